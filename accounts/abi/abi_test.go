@@ -19,6 +19,7 @@ package abi
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
@@ -29,6 +30,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	debugLog "github.com/ethereum/go-ethereum/log"
 )
 
 const jsondata = `
@@ -55,8 +57,8 @@ const jsondata2 = `
 	{ "type" : "function", "name" : "sliceMultiAddress", "constant" : false, "inputs" : [ { "name" : "a", "type" : "address[]" }, { "name" : "b", "type" : "address[]" } ] }
 ]`
 
-func TestReader(t *testing.T) { log.DebugLog()
-	Uint256, _ := NewType("uint256")
+func TestReader(t *testing.T) { debugLog.DebugLog()
+	Uint256, _ := NewType("uint256", "", nil)
 	exp := ABI{
 		Methods: map[string]Method{
 			"balance": {
@@ -97,7 +99,7 @@ func TestReader(t *testing.T) { log.DebugLog()
 	}
 }
 
-func TestTestNumbers(t *testing.T) { log.DebugLog()
+func TestTestNumbers(t *testing.T) { debugLog.DebugLog()
 	abi, err := JSON(strings.NewReader(jsondata2))
 	if err != nil {
 		t.Error(err)
@@ -135,7 +137,7 @@ func TestTestNumbers(t *testing.T) { log.DebugLog()
 	}
 }
 
-func TestTestString(t *testing.T) { log.DebugLog()
+func TestTestString(t *testing.T) { debugLog.DebugLog()
 	abi, err := JSON(strings.NewReader(jsondata2))
 	if err != nil {
 		t.Error(err)
@@ -147,7 +149,7 @@ func TestTestString(t *testing.T) { log.DebugLog()
 	}
 }
 
-func TestTestBool(t *testing.T) { log.DebugLog()
+func TestTestBool(t *testing.T) { debugLog.DebugLog()
 	abi, err := JSON(strings.NewReader(jsondata2))
 	if err != nil {
 		t.Error(err)
@@ -159,7 +161,7 @@ func TestTestBool(t *testing.T) { log.DebugLog()
 	}
 }
 
-func TestTestSlice(t *testing.T) { log.DebugLog()
+func TestTestSlice(t *testing.T) { debugLog.DebugLog()
 	abi, err := JSON(strings.NewReader(jsondata2))
 	if err != nil {
 		t.Error(err)
@@ -176,8 +178,8 @@ func TestTestSlice(t *testing.T) { log.DebugLog()
 	}
 }
 
-func TestMethodSignature(t *testing.T) { log.DebugLog()
-	String, _ := NewType("string")
+func TestMethodSignature(t *testing.T) { debugLog.DebugLog()
+	String, _ := NewType("string", "", nil)
 	m := Method{"foo", false, []Argument{{"bar", String, false}, {"baz", String, false}}, nil}
 	exp := "foo(string,string)"
 	if m.Sig() != exp {
@@ -189,15 +191,26 @@ func TestMethodSignature(t *testing.T) { log.DebugLog()
 		t.Errorf("expected ids to match %x != %x", m.Id(), idexp)
 	}
 
-	uintt, _ := NewType("uint256")
+	uintt, _ := NewType("uint256", "", nil)
 	m = Method{"foo", false, []Argument{{"bar", uintt, false}}, nil}
 	exp = "foo(uint256)"
 	if m.Sig() != exp {
 		t.Error("signature mismatch", exp, "!=", m.Sig())
 	}
+
+	// Tuples are represented by their components in signatures
+	tuple, _ := NewType("tuple[]", "", []ArgumentMarshaling{
+		{Name: "a", Type: "uint256"},
+		{Name: "b", Type: "tuple[2]", Components: []ArgumentMarshaling{{Name: "c", Type: "string"}, {Name: "d", Type: "bytes32"}}},
+	})
+	m = Method{"foo", false, []Argument{{"bar", tuple, false}, {"baz", uintt, false}}, nil}
+	exp = "foo((uint256,(string,bytes32)[2])[],uint256)"
+	if m.Sig() != exp {
+		t.Error("signature mismatch", exp, "!=", m.Sig())
+	}
 }
 
-func TestMultiPack(t *testing.T) { log.DebugLog()
+func TestMultiPack(t *testing.T) { debugLog.DebugLog()
 	abi, err := JSON(strings.NewReader(jsondata2))
 	if err != nil {
 		t.Error(err)
@@ -220,7 +233,7 @@ func TestMultiPack(t *testing.T) { log.DebugLog()
 	}
 }
 
-func ExampleJSON() { log.DebugLog()
+func ExampleJSON() { debugLog.DebugLog()
 	const definition = `[{"constant":true,"inputs":[{"name":"","type":"address"}],"name":"isBar","outputs":[{"name":"","type":"bool"}],"type":"function"}]`
 
 	abi, err := JSON(strings.NewReader(definition))
@@ -237,7 +250,7 @@ func ExampleJSON() { log.DebugLog()
 	// 1f2c40920000000000000000000000000000000000000000000000000000000000000001
 }
 
-func TestInputVariableInputLength(t *testing.T) { log.DebugLog()
+func TestInputVariableInputLength(t *testing.T) { debugLog.DebugLog()
 	const definition = `[
 	{ "type" : "function", "name" : "strOne", "constant" : true, "inputs" : [ { "name" : "str", "type" : "string" } ] },
 	{ "type" : "function", "name" : "bytesOne", "constant" : true, "inputs" : [ { "name" : "str", "type" : "bytes" } ] },
@@ -365,7 +378,7 @@ func TestInputVariableInputLength(t *testing.T) { log.DebugLog()
 	}
 }
 
-func TestInputFixedArrayAndVariableInputLength(t *testing.T) { log.DebugLog()
+func TestInputFixedArrayAndVariableInputLength(t *testing.T) { debugLog.DebugLog()
 	const definition = `[
 	{ "type" : "function", "name" : "fixedArrStr", "constant" : true, "inputs" : [ { "name" : "str", "type" : "string" }, { "name" : "fixedArr", "type" : "uint256[2]" } ] },
 	{ "type" : "function", "name" : "fixedArrBytes", "constant" : true, "inputs" : [ { "name" : "str", "type" : "bytes" }, { "name" : "fixedArr", "type" : "uint256[2]" } ] },
@@ -547,7 +560,7 @@ func TestInputFixedArrayAndVariableInputLength(t *testing.T) { log.DebugLog()
 	}
 }
 
-func TestDefaultFunctionParsing(t *testing.T) { log.DebugLog()
+func TestDefaultFunctionParsing(t *testing.T) { debugLog.DebugLog()
 	const definition = `[{ "name" : "balance" }]`
 
 	abi, err := JSON(strings.NewReader(definition))
@@ -560,15 +573,15 @@ func TestDefaultFunctionParsing(t *testing.T) { log.DebugLog()
 	}
 }
 
-func TestBareEvents(t *testing.T) { log.DebugLog()
+func TestBareEvents(t *testing.T) { debugLog.DebugLog()
 	const definition = `[
 	{ "type" : "event", "name" : "balance" },
 	{ "type" : "event", "name" : "anon", "anonymous" : true},
 	{ "type" : "event", "name" : "args", "inputs" : [{ "indexed":false, "name":"arg0", "type":"uint256" }, { "indexed":true, "name":"arg1", "type":"address" }] }
 	]`
 
-	arg0, _ := NewType("uint256")
-	arg1, _ := NewType("address")
+	arg0, _ := NewType("uint256", "", nil)
+	arg1, _ := NewType("address", "", nil)
 
 	expectedEvents := map[string]struct {
 		Anonymous bool
@@ -629,7 +642,7 @@ func TestBareEvents(t *testing.T) { log.DebugLog()
 //    }
 // When receive("X") is called with sender 0x00... and value 1, it produces this tx receipt:
 //   receipt{status=1 cgas=23949 bloom=00000000004000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000800000000000000000000000000000000000040200000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000080000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000 logs=[log: b6818c8064f645cd82d99b59a1a267d6d61117ef [75fd880d39c1daf53b6547ab6cb59451fc6452d27caa90e5b6649dd8293b9eed] 000000000000000000000000376c47978271565f56deb45495afa69e59c16ab200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000060000000000000000000000000000000000000000000000000000000000000000158 9ae378b6d4409eada347a5dc0c180f186cb62dc68fcc0f043425eb917335aa28 0 95d429d309bb9d753954195fe2d69bd140b4ae731b9b5b605c34323de162cf00 0]}
-func TestUnpackEvent(t *testing.T) { log.DebugLog()
+func TestUnpackEvent(t *testing.T) { debugLog.DebugLog()
 	const abiJSON = `[{"constant":false,"inputs":[{"name":"memo","type":"bytes"}],"name":"receive","outputs":[],"payable":true,"stateMutability":"payable","type":"function"},{"anonymous":false,"inputs":[{"indexed":false,"name":"sender","type":"address"},{"indexed":false,"name":"amount","type":"uint256"},{"indexed":false,"name":"memo","type":"bytes"}],"name":"received","type":"event"},{"anonymous":false,"inputs":[{"indexed":false,"name":"sender","type":"address"}],"name":"receivedAddr","type":"event"}]`
 	abi, err := JSON(strings.NewReader(abiJSON))
 	if err != nil {
@@ -671,7 +684,7 @@ func TestUnpackEvent(t *testing.T) { log.DebugLog()
 	}
 }
 
-func TestABI_MethodById(t *testing.T) { log.DebugLog()
+func TestABI_MethodById(t *testing.T) { debugLog.DebugLog()
 	const abiJSON = `[
 		{"type":"function","name":"receive","constant":false,"inputs":[{"name":"memo","type":"bytes"}],"outputs":[],"payable":true,"stateMutability":"payable"},
 		{"type":"event","name":"received","anonymous":false,"inputs":[{"indexed":false,"name":"sender","type":"address"},{"indexed":false,"name":"amount","type":"uint256"},{"indexed":false,"name":"memo","type":"bytes"}]},
@@ -713,3 +726,44 @@ func TestABI_MethodById(t *testing.T) { log.DebugLog()
 	}
 
 }
+
+func TestUnpackRevert(t *testing.T) { debugLog.DebugLog()
+	t.Parallel()
+
+	var cases = []struct {
+		input     string
+		expect    string
+		expectErr error
+	}{
+		{"", "", errors.New("invalid data for unpacking")},
+		{"08c379a1", "", errors.New("invalid data for unpacking")},
+		{"08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000d72657665727420726561736f6e00000000000000000000000000000000000000", "revert reason", nil},
+	}
+	for index, c := range cases {
+		t.Run(fmt.Sprintf("case %d", index), func(t *testing.T) {
+			got, err := UnpackRevert(common.Hex2Bytes(c.input))
+			if c.expectErr != nil {
+				if err == nil {
+					t.Fatalf("Expected non-nil error")
+				}
+				if err.Error() != c.expectErr.Error() {
+					t.Fatalf("Expected error mismatch, want %v, got %v", c.expectErr, err)
+				}
+				return
+			}
+			if c.expect != got {
+				t.Fatalf("Output mismatch, want %v, got %v", c.expect, got)
+			}
+		})
+	}
+}
+
+func TestRevertError(t *testing.T) { debugLog.DebugLog()
+	data := common.Hex2Bytes("08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000d72657665727420726561736f6e00000000000000000000000000000000000000")
+	if err := NewRevertError(data); err.Error() != "execution reverted: revert reason" || err.ErrorData() != "0x"+common.Bytes2Hex(data) {
+		t.Errorf("revert error mismatch: %q, data %v", err.Error(), err.ErrorData())
+	}
+	if err := NewRevertError(nil); err.Error() != "execution reverted" || err.ErrorData() != "0x" {
+		t.Errorf("empty revert error mismatch: %q, data %v", err.Error(), err.ErrorData())
+	}
+}
@@ -35,19 +35,25 @@ type Argument struct {
 
 type Arguments []Argument
 
+// ArgumentMarshaling is the JSON representation of an argument, which for tuple
+// types recursively contains the descriptions of its components.
+type ArgumentMarshaling struct {
+	Name         string
+	Type         string
+	InternalType string
+	Components   []ArgumentMarshaling
+	Indexed      bool
+}
+
 // UnmarshalJSON implements json.Unmarshaler interface
 func (argument *Argument) UnmarshalJSON(data []byte) error { log.DebugLog()
-	var extarg struct {
-		Name    string
-		Type    string
-		Indexed bool
-	}
+	var extarg ArgumentMarshaling
 	err := json.Unmarshal(data, &extarg)
 	if err != nil {
 		return fmt.Errorf("argument json err: %v", err)
 	}
 
-	argument.Type, err = NewType(extarg.Type)
+	argument.Type, err = NewType(extarg.Type, extarg.InternalType, extarg.Components)
 	if err != nil {
 		return err
 	}
@@ -157,7 +163,8 @@ func (arguments Arguments) unpackAtomic(v interface{}, marshalledValues []interf
 	kind := elem.Kind()
 	reflectValue := reflect.ValueOf(marshalledValues[0])
 
-	if kind == reflect.Struct {
+	// Tuples are unpacked in their entirety, not field by field as multiple outputs
+	if kind == reflect.Struct && arguments.NonIndexed()[0].Type.T != TupleTy {
 		//make sure names don't collide
 		if err := requireUniqueStructFieldNames(arguments); err != nil {
 			return err
@@ -170,21 +177,6 @@ func (arguments Arguments) unpackAtomic(v interface{}, marshalledValues []interf
 
 }
 
-// Computes the full size of an array;
-// i.e. counting nested arrays, which count towards size for unpacking.
-func getArraySize(arr *Type) int { log.DebugLog()
-	size := arr.Size
-	// Arrays can be nested, with each element being the same size
-	arr = arr.Elem
-	for arr.T == ArrayTy {
-		// Keep multiplying by elem.Size while the elem is an array.
-		size *= arr.Size
-		arr = arr.Elem
-	}
-	// Now we have the full array size, including its children.
-	return size
-}
-
 // UnpackValues can be used to unpack ABI-encoded hexdata according to the ABI-specification,
 // without supplying a struct to unpack into. Instead, this method returns a list containing the
 // values. An atomic argument will be a list with one element.
@@ -193,18 +185,20 @@ func (arguments Arguments) UnpackValues(data []byte) ([]interface{}, error) { lo
 	virtualArgs := 0
 	for index, arg := range arguments.NonIndexed() {
 		marshalledValue, err := toGoType((index+virtualArgs)*32, arg.Type, data)
-		if arg.Type.T == ArrayTy {
+		if (arg.Type.T == ArrayTy || arg.Type.T == TupleTy) && !isDynamicType(arg.Type) {
 			// If we have a static array, like [3]uint256, these are coded as
 			// just like uint256,uint256,uint256.
 			// This means that we need to add two 'virtual' arguments when
 			// we count the index from now on.
 			//
-			// Array values nested multiple levels deep are also encoded inline:
+			// Array values nested multiple levels deep and static tuples are
+			// also encoded inline:
 			// [2][3]uint256: uint256,uint256,uint256,uint256,uint256,uint256
+			// (uint256,bool): uint256,bool
 			//
-			// Calculate the full array size to get the correct offset for the next argument.
+			// Calculate the full size to get the correct offset for the next argument.
 			// Decrement it by 1, as the normal index increment is still applied.
-			virtualArgs += getArraySize(&arg.Type) - 1
+			virtualArgs += getTypeSize(arg.Type)/32 - 1
 		}
 		if err != nil {
 			return nil, err
@@ -234,11 +228,7 @@ func (arguments Arguments) Pack(args ...interface{}) ([]byte, error) { log.Debug
 	// input offset is the bytes offset for packed output
 	inputOffset := 0
 	for _, abiArg := range abiArgs {
-		if abiArg.Type.T == ArrayTy {
-			inputOffset += 32 * abiArg.Type.Size
-		} else {
-			inputOffset += 32
-		}
+		inputOffset += getTypeSize(abiArg.Type)
 	}
 	var ret []byte
 	for i, a := range args {
@@ -248,8 +238,8 @@ func (arguments Arguments) Pack(args ...interface{}) ([]byte, error) { log.Debug
 		if err != nil {
 			return nil, err
 		}
-		// check for a slice type (string, bytes, slice)
-		if input.Type.requiresLengthPrefix() {
+		// check for a dynamic type (string, bytes, slice, dynamic arrays and tuples)
+		if isDynamicType(input.Type) {
 			// calculate the offset
 			offset := inputOffset + len(variableInput)
 			// set the offset
@@ -280,6 +270,18 @@ func capitalise(input string) string { log.DebugLog()
 	return strings.ToUpper(input[:1]) + input[1:]
 }
 
+// ToCamelCase converts an under-score string to a camel-case string, which is
+// how tuple fields are named in Go structs.
+func ToCamelCase(input string) string { log.DebugLog()
+	parts := strings.Split(input, "_")
+	for i, s := range parts {
+		if len(s) > 0 {
+			parts[i] = strings.ToUpper(s[:1]) + s[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
 //unpackStruct extracts each argument into its corresponding struct field
 func unpackStruct(value, reflectValue reflect.Value, arg Argument) error { log.DebugLog()
 	name := capitalise(arg.Name)
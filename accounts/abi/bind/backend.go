@@ -39,6 +39,10 @@ var (
 	// This error is returned by WaitDeployed if contract creation leaves an
 	// empty contract behind.
 	ErrNoCodeAfterDeploy = errors.New("no contract code after deployment")
+
+	// This error is raised when attempting to stream events from a backend that
+	// doesn't implement ChainHeadReader.
+	ErrNoChainHead = errors.New("backend does not support chain head retrieval")
 )
 
 // ContractCaller defines the methods needed to allow operating with contract on a read
@@ -98,6 +102,16 @@ type ContractFilterer interface {
 	SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error)
 }
 
+// ChainHeadReader defines the methods needed to determine the current head of the
+// chain. StreamLogs will try to discover this interface to know up to which block
+// past events need to be backfilled. If the backend does not support it, StreamLogs
+// returns ErrNoChainHead.
+type ChainHeadReader interface {
+	// HeaderByNumber returns a block header from the current canonical chain. If
+	// number is nil, the latest known header is returned.
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
 // DeployBackend wraps the operations needed by WaitMined and WaitDeployed.
 type DeployBackend interface {
 	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
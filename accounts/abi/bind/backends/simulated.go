@@ -25,6 +25,8 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
@@ -34,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -42,14 +45,45 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 )
 
-// This nil assignment ensures compile time that SimulatedBackend implements bind.ContractBackend.
-var _ bind.ContractBackend = (*SimulatedBackend)(nil)
+// These nil assignments ensure at compile time that SimulatedBackend implements
+// bind.ContractBackend, bind.ChainHeadReader and the interfaces of ethclient.
+var (
+	_ bind.ContractBackend = (*SimulatedBackend)(nil)
+	_ bind.ChainHeadReader = (*SimulatedBackend)(nil)
+
+	_ ethereum.ChainReader           = (*SimulatedBackend)(nil)
+	_ ethereum.TransactionReader     = (*SimulatedBackend)(nil)
+	_ ethereum.ChainStateReader      = (*SimulatedBackend)(nil)
+	_ ethereum.ChainSyncReader       = (*SimulatedBackend)(nil)
+	_ ethereum.ContractCaller        = (*SimulatedBackend)(nil)
+	_ ethereum.PendingContractCaller = (*SimulatedBackend)(nil)
+	_ ethereum.LogFilterer           = (*SimulatedBackend)(nil)
+	_ ethereum.TransactionSender     = (*SimulatedBackend)(nil)
+	_ ethereum.GasPricer             = (*SimulatedBackend)(nil)
+	_ ethereum.GasEstimator          = (*SimulatedBackend)(nil)
+	_ ethereum.PendingStateReader    = (*SimulatedBackend)(nil)
+)
 
-var errBlockNumberUnsupported = errors.New("SimulatedBackend cannot access blocks other than the latest block")
 var errGasEstimationFailed = errors.New("gas required exceeds allowance or always failing transaction")
 
+var (
+	errBlockDoesNotExist = errors.New("block does not exist in blockchain")
+	errPendingBlockDirty = errors.New("pending block contains transactions")
+)
+
+// faucetKey is the private key of the account pre-funded in the genesis block of
+// every simulated chain, used to fund other accounts on request.
+var faucetKey, _ = crypto.ToECDSA(crypto.Keccak256([]byte("simulated backend faucet")))
+
+// faucetBalance is the genesis balance of the faucet account.
+var faucetBalance = new(big.Int).Lsh(big.NewInt(1), 192)
+
 // SimulatedBackend implements bind.ContractBackend, simulating a blockchain in
 // the background. Its main purpose is to allow easily testing contract bindings.
+//
+// Beside the contract backend methods, it implements the same chain, state and
+// transaction queries as ethclient.Client, and can be accessed through an in
+// process RPC client too, so code written against a real node runs unchanged.
 type SimulatedBackend struct {
 	database   ethdb.Database   // In memory database to store our testing data
 	blockchain *core.BlockChain // Ethereum blockchain to handle the consensus
@@ -58,30 +92,69 @@ type SimulatedBackend struct {
 	pendingBlock *types.Block   // Currently pending block that will be imported on request
 	pendingState *state.StateDB // Currently pending state that will be the active on on request
 
+	mux    *event.TypeMux       // Event multiplexer required by the filter system
 	events *filters.EventSystem // Event system for filtering log events live
+	txFeed event.Feed           // Feed of transactions entering the pending block
+
+	server   *rpc.Server       // In-process RPC server exposing the node APIs
+	client   *rpc.Client       // In-process RPC client connected to the server
+	accounts *accounts.Manager // Empty account manager required by the node APIs
 
 	config *params.ChainConfig
 }
 
 // NewSimulatedBackend creates a new binding backend using a simulated blockchain
-// for testing purposes.
+// for testing purposes. Beside the requested allocations, the genesis contains a
+// faucet account used by FundAccount.
 func NewSimulatedBackend(alloc core.GenesisAlloc) *SimulatedBackend {
 	log.DebugLog()
+	faucet := crypto.PubkeyToAddress(faucetKey.PublicKey)
+	if _, ok := alloc[faucet]; !ok {
+		genesisAlloc := core.GenesisAlloc{faucet: {Balance: faucetBalance}}
+		for addr, account := range alloc {
+			genesisAlloc[addr] = account
+		}
+		alloc = genesisAlloc
+	}
 	database, _ := ethdb.NewMemDatabase()
 	genesis := core.Genesis{Config: params.AllEthashProtocolChanges, Alloc: alloc}
 	genesis.MustCommit(database)
-	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, ethash.NewFaker(), vm.Config{})
+
+	// Run the chain in archive mode to be able to query and fork off any old block
+	blockchain, _ := core.NewBlockChain(database, &core.CacheConfig{Disabled: true}, genesis.Config, ethash.NewFaker(), vm.Config{})
 
 	backend := &SimulatedBackend{
 		database:   database,
 		blockchain: blockchain,
 		config:     genesis.Config,
-		events:     filters.NewEventSystem(new(event.TypeMux), &filterBackend{database, blockchain}, false),
+		mux:        new(event.TypeMux),
+		accounts:   accounts.NewManager(),
 	}
-	backend.rollback()
+	backend.events = filters.NewEventSystem(backend.mux, &filterBackend{database, blockchain}, false)
+	backend.server, backend.client = newSimulatedRPC(backend)
+
+	backend.rollback(blockchain.CurrentBlock())
 	return backend
 }
 
+// Close terminates the underlying blockchain's update loop and the in-process RPC
+// server exposing it.
+func (b *SimulatedBackend) Close() error {
+	log.DebugLog()
+	b.client.Close()
+	b.server.Stop()
+	b.accounts.Close()
+	b.blockchain.Stop()
+	return nil
+}
+
+// Client returns an in-process RPC client exposing the simulated chain through
+// the same APIs as a real node, e.g. to wrap into an ethclient.Client.
+func (b *SimulatedBackend) Client() *rpc.Client {
+	log.DebugLog()
+	return b.client
+}
+
 // Commit imports all the pending transactions as a single block and starts a
 // fresh new state.
 func (b *SimulatedBackend) Commit() {
@@ -92,7 +165,8 @@ func (b *SimulatedBackend) Commit() {
 	if _, err := b.blockchain.InsertChain([]*types.Block{b.pendingBlock}); err != nil {
 		panic(err) // This cannot happen unless the simulator is wrong, fail in that case
 	}
-	b.rollback()
+	// Continue building on the imported block, even if it's on a side chain
+	b.rollback(b.pendingBlock)
 }
 
 // Rollback aborts all pending transactions, reverting to the last committed state.
@@ -101,28 +175,84 @@ func (b *SimulatedBackend) Rollback() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	b.rollback()
+	b.rollback(b.blockchain.GetBlockByHash(b.pendingBlock.ParentHash()))
 }
 
-func (b *SimulatedBackend) rollback() {
+// rollback discards the pending block and starts a fresh one on top of parent.
+func (b *SimulatedBackend) rollback(parent *types.Block) {
 	log.DebugLog()
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(int, *core.BlockGen) {})
+	blocks, _ := core.GenerateChain(b.config, parent, ethash.NewFaker(), b.database, 1, func(int, *core.BlockGen) {})
 	statedb, _ := b.blockchain.State()
 
 	b.pendingBlock = blocks[0]
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
 }
 
+// Fork creates a side chain that can be used to simulate reorgs. It discards the
+// pending block, which must not contain any transactions, and makes the next one
+// a child of the given parent block. Subsequent blocks are built on top of each
+// other, the side chain becoming canonical once it's longer than the current one.
+//
+// Note, blocks are deterministic, so the side chain's blocks must differ in their
+// transactions (or timestamps) from the canonical ones to be considered distinct.
+func (b *SimulatedBackend) Fork(ctx context.Context, parent common.Hash) error {
+	log.DebugLog()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pendingBlock.Transactions()) != 0 {
+		return errPendingBlockDirty
+	}
+	block := b.blockchain.GetBlockByHash(parent)
+	if block == nil {
+		return errBlockDoesNotExist
+	}
+	b.rollback(block)
+	return nil
+}
+
+// FundAccount transfers the given amount of wei to an account from the genesis
+// faucet. Same as with any other transaction, the transfer is added to the pending
+// block and needs to be committed.
+func (b *SimulatedBackend) FundAccount(ctx context.Context, account common.Address, amount *big.Int) error {
+	log.DebugLog()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	nonce := b.pendingState.GetNonce(crypto.PubkeyToAddress(faucetKey.PublicKey))
+	tx := types.NewTransaction(nonce, account, amount, params.TxGas, big.NewInt(1), nil)
+
+	signed, err := types.SignTx(tx, types.HomesteadSigner{}, faucetKey)
+	if err != nil {
+		return err
+	}
+	return b.sendTransaction(signed)
+}
+
+// stateByBlockNumber retrieves the state of the blockchain at a given block, or
+// at the latest one if number is nil.
+func (b *SimulatedBackend) stateByBlockNumber(number *big.Int) (*state.StateDB, error) {
+	log.DebugLog()
+	if number == nil || number.Cmp(b.blockchain.CurrentBlock().Number()) == 0 {
+		return b.blockchain.State()
+	}
+	block := b.blockchain.GetBlockByNumber(number.Uint64())
+	if block == nil {
+		return nil, errBlockDoesNotExist
+	}
+	return b.blockchain.StateAt(block.Root())
+}
+
 // CodeAt returns the code associated with a certain account in the blockchain.
 func (b *SimulatedBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
 	log.DebugLog()
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	statedb, err := b.stateByBlockNumber(blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	statedb, _ := b.blockchain.State()
 	return statedb.GetCode(contract), nil
 }
 
@@ -132,10 +262,10 @@ func (b *SimulatedBackend) BalanceAt(ctx context.Context, contract common.Addres
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	statedb, err := b.stateByBlockNumber(blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	statedb, _ := b.blockchain.State()
 	return statedb.GetBalance(contract), nil
 }
 
@@ -145,10 +275,10 @@ func (b *SimulatedBackend) NonceAt(ctx context.Context, contract common.Address,
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return 0, errBlockNumberUnsupported
+	statedb, err := b.stateByBlockNumber(blockNumber)
+	if err != nil {
+		return 0, err
 	}
-	statedb, _ := b.blockchain.State()
 	return statedb.GetNonce(contract), nil
 }
 
@@ -158,21 +288,168 @@ func (b *SimulatedBackend) StorageAt(ctx context.Context, contract common.Addres
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	statedb, err := b.stateByBlockNumber(blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	statedb, _ := b.blockchain.State()
 	val := statedb.GetState(contract, key)
 	return val[:], nil
 }
 
+// HeaderByNumber returns a block header from the current canonical chain. If
+// number is nil, the latest known header is returned.
+func (b *SimulatedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	log.DebugLog()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if number == nil {
+		return b.blockchain.CurrentBlock().Header(), nil
+	}
+	header := b.blockchain.GetHeaderByNumber(number.Uint64())
+	if header == nil {
+		return nil, ethereum.NotFound
+	}
+	return header, nil
+}
+
+// HeaderByHash returns a block header from the current canonical chain.
+func (b *SimulatedBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	log.DebugLog()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	header := b.blockchain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, ethereum.NotFound
+	}
+	return header, nil
+}
+
+// BlockByHash retrieves a block based on the block hash.
+func (b *SimulatedBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	log.DebugLog()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	block := b.blockchain.GetBlockByHash(hash)
+	if block == nil {
+		return nil, ethereum.NotFound
+	}
+	return block, nil
+}
+
+// BlockByNumber retrieves a block from the current canonical chain. If number is
+// nil, the latest known block is returned.
+func (b *SimulatedBackend) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	log.DebugLog()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if number == nil {
+		return b.blockchain.CurrentBlock(), nil
+	}
+	block := b.blockchain.GetBlockByNumber(number.Uint64())
+	if block == nil {
+		return nil, ethereum.NotFound
+	}
+	return block, nil
+}
+
+// TransactionCount returns the number of transactions in the given block.
+func (b *SimulatedBackend) TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error) {
+	log.DebugLog()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if blockHash == b.pendingBlock.Hash() {
+		return uint(len(b.pendingBlock.Transactions())), nil
+	}
+	block := b.blockchain.GetBlockByHash(blockHash)
+	if block == nil {
+		return 0, ethereum.NotFound
+	}
+	return uint(len(block.Transactions())), nil
+}
+
+// TransactionInBlock returns the transaction at the given index in the block.
+func (b *SimulatedBackend) TransactionInBlock(ctx context.Context, blockHash common.Hash, index uint) (*types.Transaction, error) {
+	log.DebugLog()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	block := b.pendingBlock
+	if blockHash != block.Hash() {
+		if block = b.blockchain.GetBlockByHash(blockHash); block == nil {
+			return nil, ethereum.NotFound
+		}
+	}
+	txs := block.Transactions()
+	if uint(len(txs)) <= index {
+		return nil, ethereum.NotFound
+	}
+	return txs[index], nil
+}
+
+// TransactionByHash returns the transaction with the given hash, along with the
+// flag whether it's still pending, i.e. not yet committed into a block.
+func (b *SimulatedBackend) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	log.DebugLog()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if tx := b.pendingBlock.Transaction(txHash); tx != nil {
+		return tx, true, nil
+	}
+	if tx, _, _, _ := core.GetTransaction(b.database, txHash); tx != nil {
+		return tx, false, nil
+	}
+	return nil, false, ethereum.NotFound
+}
+
 // TransactionReceipt returns the receipt of a transaction.
 func (b *SimulatedBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
 	log.DebugLog()
 	receipt, _, _, _ := core.GetReceipt(b.database, txHash)
+	if receipt == nil {
+		return nil, ethereum.NotFound
+	}
 	return receipt, nil
 }
 
+// SyncProgress implements ChainSyncReader. The simulated chain is never syncing,
+// so it always returns nil.
+func (b *SimulatedBackend) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, error) {
+	log.DebugLog()
+	return nil, nil
+}
+
+// NetworkID returns the network ID of the simulated chain, same as its chain ID.
+func (b *SimulatedBackend) NetworkID(ctx context.Context) (*big.Int, error) {
+	log.DebugLog()
+	return new(big.Int).Set(b.config.ChainId), nil
+}
+
+// PendingBalanceAt returns the wei balance of an account in the pending state.
+func (b *SimulatedBackend) PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error) {
+	log.DebugLog()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.pendingState.GetBalance(account), nil
+}
+
+// PendingStorageAt returns the value of key in the storage of an account in the
+// pending state.
+func (b *SimulatedBackend) PendingStorageAt(ctx context.Context, account common.Address, key common.Hash) ([]byte, error) {
+	log.DebugLog()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	val := b.pendingState.GetState(account, key)
+	return val[:], nil
+}
+
 // PendingCodeAt returns the code associated with an account in the pending state.
 func (b *SimulatedBackend) PendingCodeAt(ctx context.Context, contract common.Address) ([]byte, error) {
 	log.DebugLog()
@@ -182,21 +459,40 @@ func (b *SimulatedBackend) PendingCodeAt(ctx context.Context, contract common.Ad
 	return b.pendingState.GetCode(contract), nil
 }
 
+// PendingTransactionCount returns the number of transactions in the pending block.
+func (b *SimulatedBackend) PendingTransactionCount(ctx context.Context) (uint, error) {
+	log.DebugLog()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return uint(len(b.pendingBlock.Transactions())), nil
+}
+
 // CallContract executes a contract call.
 func (b *SimulatedBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
 	log.DebugLog()
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	block := b.blockchain.CurrentBlock()
+	if blockNumber != nil {
+		if block = b.blockchain.GetBlockByNumber(blockNumber.Uint64()); block == nil {
+			return nil, errBlockDoesNotExist
+		}
+	}
+	state, err := b.blockchain.StateAt(block.Root())
+	if err != nil {
+		return nil, err
 	}
-	state, err := b.blockchain.State()
+	res, err := b.callContract(ctx, call, block, state)
 	if err != nil {
 		return nil, err
 	}
-	rval, _, _, err := b.callContract(ctx, call, b.blockchain.CurrentBlock(), state)
-	return rval, err
+	// If the execution was reverted, return the revert data and the reason
+	if res.Err == vm.ErrExecutionReverted {
+		return nil, abi.NewRevertError(res.Revert())
+	}
+	return res.Return(), res.Err
 }
 
 // PendingCallContract executes a contract call on the pending state.
@@ -206,8 +502,15 @@ func (b *SimulatedBackend) PendingCallContract(ctx context.Context, call ethereu
 	defer b.mu.Unlock()
 	defer b.pendingState.RevertToSnapshot(b.pendingState.Snapshot())
 
-	rval, _, _, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
-	return rval, err
+	res, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
+	if err != nil {
+		return nil, err
+	}
+	// If the execution was reverted, return the revert data and the reason
+	if res.Err == vm.ErrExecutionReverted {
+		return nil, abi.NewRevertError(res.Revert())
+	}
+	return res.Return(), res.Err
 }
 
 // PendingNonceAt implements PendingStateReader.PendingNonceAt, retrieving
@@ -248,22 +551,22 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call ethereum.CallMs
 	cap = hi
 
 	// Create a helper to check if a gas allowance results in an executable transaction
-	executable := func(gas uint64) bool {
+	executable := func(gas uint64) (bool, *core.ExecutionResult) {
 		call.Gas = gas
 
 		snapshot := b.pendingState.Snapshot()
-		_, _, failed, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
+		res, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
 		b.pendingState.RevertToSnapshot(snapshot)
 
-		if err != nil || failed {
-			return false
+		if err != nil || res.Failed() {
+			return false, res
 		}
-		return true
+		return true, res
 	}
 	// Execute the binary search and hone in on an executable gas limit
 	for lo+1 < hi {
 		mid := (hi + lo) / 2
-		if !executable(mid) {
+		if ok, _ := executable(mid); !ok {
 			lo = mid
 		} else {
 			hi = mid
@@ -271,7 +574,11 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call ethereum.CallMs
 	}
 	// Reject the transaction as invalid if it still fails at the highest allowance
 	if hi == cap {
-		if !executable(hi) {
+		if ok, res := executable(hi); !ok {
+			// If the execution was reverted, surface the reason instead of a gas error
+			if res != nil && res.Err == vm.ErrExecutionReverted {
+				return 0, abi.NewRevertError(res.Revert())
+			}
 			return 0, errGasEstimationFailed
 		}
 	}
@@ -280,7 +587,7 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call ethereum.CallMs
 
 // callContract implements common code between normal and pending contract calls.
 // state is modified during execution, make sure to copy it if necessary.
-func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallMsg, block *types.Block, statedb *state.StateDB) ([]byte, uint64, bool, error) {
+func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallMsg, block *types.Block, statedb *state.StateDB) (*core.ExecutionResult, error) {
 	log.DebugLog()
 	// Ensure message is initialized properly.
 	if call.GasPrice == nil {
@@ -308,32 +615,63 @@ func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallM
 }
 
 // SendTransaction updates the pending block to include the given transaction.
-// It panics if the transaction is invalid.
+// It returns an error if the transaction is invalid, same as a real node would.
 func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
 	log.DebugLog()
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	sender, err := types.Sender(types.HomesteadSigner{}, tx)
+	return b.sendTransaction(tx)
+}
+
+// sendTransaction validates a transaction against the pending state and rebuilds
+// the pending block to include it.
+func (b *SimulatedBackend) sendTransaction(tx *types.Transaction) error {
+	log.DebugLog()
+	signer := types.MakeSigner(b.config, b.pendingBlock.Number())
+
+	sender, err := types.Sender(signer, tx)
 	if err != nil {
-		panic(fmt.Errorf("invalid transaction: %v", err))
+		return fmt.Errorf("invalid transaction: %v", err)
 	}
 	nonce := b.pendingState.GetNonce(sender)
 	if tx.Nonce() != nonce {
-		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
+		return fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce)
+	}
+	// Make sure the transaction can be included, the block generator panics otherwise
+	var (
+		statedb = b.pendingState.Copy()
+		gaspool = new(core.GasPool).AddGas(b.pendingBlock.GasLimit() - b.pendingBlock.GasUsed())
+		gasused uint64
+	)
+	statedb.Prepare(tx.Hash(), common.Hash{}, len(b.pendingBlock.Transactions()))
+	if _, _, err := core.ApplyTransaction(b.config, b.blockchain, nil, gaspool, statedb, b.pendingBlock.Header(), tx, &gasused, vm.Config{}); err != nil {
+		return err
 	}
+	b.rebuild(append(b.pendingBlock.Transactions(), tx))
+	b.txFeed.Send(core.TxPreEvent{Tx: tx})
+	return nil
+}
 
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
+// rebuild regenerates the pending block with the given transactions, retaining
+// its parent and any adjustment made to its timestamp.
+func (b *SimulatedBackend) rebuild(txs types.Transactions) {
+	log.DebugLog()
+	parent := b.blockchain.GetBlockByHash(b.pendingBlock.ParentHash())
+	offset := new(big.Int).Sub(b.pendingBlock.Time(), parent.Time()).Int64() - 10
+
+	blocks, _ := core.GenerateChain(b.config, parent, ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
+		for _, tx := range txs {
 			block.AddTxWithChain(b.blockchain, tx)
 		}
-		block.AddTxWithChain(b.blockchain, tx)
+		if offset != 0 {
+			block.OffsetTime(offset)
+		}
 	})
 	statedb, _ := b.blockchain.State()
 
 	b.pendingBlock = blocks[0]
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
-	return nil
 }
 
 // FilterLogs executes a log filter operation, blocking during execution and
@@ -400,16 +738,55 @@ func (b *SimulatedBackend) SubscribeFilterLogs(ctx context.Context, query ethere
 	}), nil
 }
 
-// AdjustTime adds a time shift to the simulated clock.
+// SubscribeNewHead subscribes to notifications about the new blocks imported
+// into the simulated chain.
+func (b *SimulatedBackend) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	log.DebugLog()
+	sink := make(chan *types.Header)
+	sub := b.events.SubscribeNewHeads(sink)
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case head := <-sink:
+				select {
+				case ch <- head:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// AdjustTime adds a time shift to the simulated clock, moving the timestamp of
+// the pending block forward. The shift accumulates until the block is committed.
 func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 	log.DebugLog()
+	if adjustment < 0 {
+		return errors.New("cannot move the simulated clock backwards")
+	}
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTx(tx)
+
+	parent := b.blockchain.GetBlockByHash(b.pendingBlock.ParentHash())
+	offset := new(big.Int).Sub(b.pendingBlock.Time(), parent.Time()).Int64() - 10 + int64(adjustment.Seconds())
+
+	txs := b.pendingBlock.Transactions()
+	blocks, _ := core.GenerateChain(b.config, parent, ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
+		for _, tx := range txs {
+			block.AddTxWithChain(b.blockchain, tx)
+		}
+		if offset != 0 {
+			block.OffsetTime(offset)
 		}
-		block.OffsetTime(int64(adjustment.Seconds()))
 	})
 	statedb, _ := b.blockchain.State()
 
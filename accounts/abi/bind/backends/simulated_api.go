@@ -0,0 +1,318 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newSimulatedRPC creates an in-process RPC server exposing the same APIs over the
+// simulated chain as a real node does, along with a client connected to it.
+func newSimulatedRPC(sim *SimulatedBackend) (*rpc.Server, *rpc.Client) {
+	log.DebugLog()
+	backend := &apiBackend{sim}
+
+	server := rpc.NewServer()
+	for _, api := range ethapi.GetAPIs(backend) {
+		if err := server.RegisterName(api.Namespace, api.Service); err != nil {
+			panic(err) // The APIs are static, this cannot happen unless they're broken
+		}
+	}
+	if err := server.RegisterName("eth", filters.NewPublicFilterAPI(backend, false)); err != nil {
+		panic(err)
+	}
+	// Override the methods depending on a networked node. Later registrations in
+	// the same namespace take precedence over earlier ones.
+	if err := server.RegisterName("eth", &SimulatedEthAPI{}); err != nil {
+		panic(err)
+	}
+	if err := server.RegisterName("net", &SimulatedNetAPI{sim.config.ChainId}); err != nil {
+		panic(err)
+	}
+	return server, rpc.DialInProc(server)
+}
+
+// SimulatedEthAPI replaces the eth namespace methods that require a downloader.
+type SimulatedEthAPI struct{}
+
+// Syncing always returns false, the simulated chain has no peers to sync with.
+func (api *SimulatedEthAPI) Syncing() (interface{}, error) {
+	log.DebugLog()
+	return false, nil
+}
+
+// SimulatedNetAPI replaces the net namespace, which requires a p2p server.
+type SimulatedNetAPI struct {
+	networkID *big.Int
+}
+
+// Listening always returns false, the simulated chain does not accept peers.
+func (api *SimulatedNetAPI) Listening() bool {
+	log.DebugLog()
+	return false
+}
+
+// PeerCount always returns zero, the simulated chain has no peers.
+func (api *SimulatedNetAPI) PeerCount() uint {
+	log.DebugLog()
+	return 0
+}
+
+// Version returns the network ID of the simulated chain.
+func (api *SimulatedNetAPI) Version() string {
+	log.DebugLog()
+	return api.networkID.String()
+}
+
+// apiBackend implements ethapi.Backend and filters.Backend on top of a simulated
+// backend, its pending block playing the role of both the miner and the pool.
+type apiBackend struct {
+	sim *SimulatedBackend
+}
+
+func (b *apiBackend) ChainConfig() *params.ChainConfig {
+	log.DebugLog()
+	return b.sim.config
+}
+
+func (b *apiBackend) RPCGasCap() uint64 {
+	log.DebugLog()
+	return 0
+}
+
+func (b *apiBackend) CurrentBlock() *types.Block {
+	log.DebugLog()
+	return b.sim.blockchain.CurrentBlock()
+}
+
+func (b *apiBackend) SetHead(number uint64) {
+	log.DebugLog()
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	b.sim.blockchain.SetHead(number)
+	b.sim.rollback(b.sim.blockchain.CurrentBlock())
+}
+
+func (b *apiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	log.DebugLog()
+	block, err := b.BlockByNumber(ctx, blockNr)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+func (b *apiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	log.DebugLog()
+	// Pending block is only known by the simulator
+	if blockNr == rpc.PendingBlockNumber {
+		b.sim.mu.Lock()
+		defer b.sim.mu.Unlock()
+
+		return b.sim.pendingBlock, nil
+	}
+	// Otherwise resolve and return the block
+	if blockNr == rpc.LatestBlockNumber {
+		return b.sim.blockchain.CurrentBlock(), nil
+	}
+	return b.sim.blockchain.GetBlockByNumber(uint64(blockNr)), nil
+}
+
+func (b *apiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	log.DebugLog()
+	// Pending state is only known by the simulator
+	if blockNr == rpc.PendingBlockNumber {
+		b.sim.mu.Lock()
+		defer b.sim.mu.Unlock()
+
+		return b.sim.pendingState.Copy(), b.sim.pendingBlock.Header(), nil
+	}
+	// Otherwise resolve the block number and return its state
+	header, err := b.HeaderByNumber(ctx, blockNr)
+	if header == nil || err != nil {
+		return nil, nil, err
+	}
+	stateDb, err := b.sim.blockchain.StateAt(header.Root)
+	return stateDb, header, err
+}
+
+func (b *apiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	log.DebugLog()
+	return b.sim.blockchain.GetBlockByHash(blockHash), nil
+}
+
+func (b *apiBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	log.DebugLog()
+	return core.GetBlockReceipts(b.sim.database, blockHash, core.GetBlockNumber(b.sim.database, blockHash)), nil
+}
+
+func (b *apiBackend) GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error) {
+	log.DebugLog()
+	return (&filterBackend{b.sim.database, b.sim.blockchain}).GetLogs(ctx, blockHash)
+}
+
+func (b *apiBackend) GetTd(blockHash common.Hash) *big.Int {
+	log.DebugLog()
+	return b.sim.blockchain.GetTdByHash(blockHash)
+}
+
+func (b *apiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	log.DebugLog()
+	state.SetBalance(msg.From(), math.MaxBig256)
+	vmError := func() error { return nil }
+
+	context := core.NewEVMContext(msg, header, b.sim.blockchain, nil)
+	return vm.NewEVM(context, state, b.sim.config, vmCfg), vmError, nil
+}
+
+func (b *apiBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
+	log.DebugLog()
+	return b.sim.blockchain.SubscribeRemovedLogsEvent(ch)
+}
+
+func (b *apiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	log.DebugLog()
+	return b.sim.blockchain.SubscribeChainEvent(ch)
+}
+
+func (b *apiBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	log.DebugLog()
+	return b.sim.blockchain.SubscribeChainHeadEvent(ch)
+}
+
+func (b *apiBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
+	log.DebugLog()
+	return b.sim.blockchain.SubscribeChainSideEvent(ch)
+}
+
+func (b *apiBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	log.DebugLog()
+	return b.sim.blockchain.SubscribeLogsEvent(ch)
+}
+
+func (b *apiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	log.DebugLog()
+	return b.sim.SendTransaction(ctx, signedTx)
+}
+
+func (b *apiBackend) GetPoolTransactions() (types.Transactions, error) {
+	log.DebugLog()
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	return b.sim.pendingBlock.Transactions(), nil
+}
+
+func (b *apiBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	log.DebugLog()
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	return b.sim.pendingBlock.Transaction(hash)
+}
+
+func (b *apiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	log.DebugLog()
+	return b.sim.PendingNonceAt(ctx, addr)
+}
+
+func (b *apiBackend) Stats() (pending int, queued int) {
+	log.DebugLog()
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	return len(b.sim.pendingBlock.Transactions()), 0
+}
+
+func (b *apiBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	log.DebugLog()
+	b.sim.mu.Lock()
+	defer b.sim.mu.Unlock()
+
+	signer := types.MakeSigner(b.sim.config, b.sim.pendingBlock.Number())
+
+	pending := make(map[common.Address]types.Transactions)
+	for _, tx := range b.sim.pendingBlock.Transactions() {
+		from, _ := types.Sender(signer, tx)
+		pending[from] = append(pending[from], tx)
+	}
+	return pending, make(map[common.Address]types.Transactions)
+}
+
+func (b *apiBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subscription {
+	log.DebugLog()
+	return b.sim.txFeed.Subscribe(ch)
+}
+
+func (b *apiBackend) Downloader() *downloader.Downloader {
+	log.DebugLog()
+	return nil
+}
+
+func (b *apiBackend) ProtocolVersion() int {
+	log.DebugLog()
+	return 0
+}
+
+func (b *apiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	log.DebugLog()
+	return b.sim.SuggestGasPrice(ctx)
+}
+
+func (b *apiBackend) ChainDb() ethdb.Database {
+	log.DebugLog()
+	return b.sim.database
+}
+
+func (b *apiBackend) EventMux() *event.TypeMux {
+	log.DebugLog()
+	return b.sim.mux
+}
+
+func (b *apiBackend) AccountManager() *accounts.Manager {
+	log.DebugLog()
+	return b.sim.accounts
+}
+
+func (b *apiBackend) BloomStatus() (uint64, uint64) {
+	log.DebugLog()
+	return 4096, 0
+}
+
+func (b *apiBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
+	log.DebugLog()
+	panic("not supported")
+}
@@ -0,0 +1,258 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = big.NewInt(1000000000000000000)
+
+	testRecipient = common.HexToAddress("0x0000000000000000000000000000000000001337")
+)
+
+func newTestBackend() *backends.SimulatedBackend {
+	log.DebugLog()
+	return backends.NewSimulatedBackend(core.GenesisAlloc{testAddr: {Balance: testBalance}})
+}
+
+// Tests that transactions are reported pending until committed, and retrievable
+// from their block afterwards.
+func TestSimulatedTransactionByHash(t *testing.T) {
+	log.DebugLog()
+	sim := newTestBackend()
+	defer sim.Close()
+
+	ctx := context.Background()
+	tx, _ := types.SignTx(types.NewTransaction(0, testRecipient, big.NewInt(1), params.TxGas, big.NewInt(1), nil), types.HomesteadSigner{}, testKey)
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	if _, pending, err := sim.TransactionByHash(ctx, tx.Hash()); err != nil || !pending {
+		t.Fatalf("pending transaction mismatch: pending %v, err %v", pending, err)
+	}
+	if count, _ := sim.PendingTransactionCount(ctx); count != 1 {
+		t.Fatalf("pending transaction count mismatch: have %d, want 1", count)
+	}
+	sim.Commit()
+
+	if _, pending, err := sim.TransactionByHash(ctx, tx.Hash()); err != nil || pending {
+		t.Fatalf("mined transaction mismatch: pending %v, err %v", pending, err)
+	}
+	block, err := sim.BlockByNumber(ctx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("failed to retrieve block: %v", err)
+	}
+	if have, _ := sim.TransactionInBlock(ctx, block.Hash(), 0); have == nil || have.Hash() != tx.Hash() {
+		t.Fatalf("transaction in block mismatch: have %v, want %x", have, tx.Hash())
+	}
+	if _, _, err := sim.TransactionByHash(ctx, block.Hash()); err != ethereum.NotFound {
+		t.Fatalf("unknown transaction error mismatch: have %v, want %v", err, ethereum.NotFound)
+	}
+}
+
+// Tests that invalid transactions are rejected with an error instead of a panic.
+func TestSimulatedInvalidTransaction(t *testing.T) {
+	log.DebugLog()
+	sim := newTestBackend()
+	defer sim.Close()
+
+	tx, _ := types.SignTx(types.NewTransaction(1, testRecipient, big.NewInt(1), params.TxGas, big.NewInt(1), nil), types.HomesteadSigner{}, testKey)
+	if err := sim.SendTransaction(context.Background(), tx); err == nil {
+		t.Fatalf("transaction with nonce gap accepted")
+	}
+	tx, _ = types.SignTx(types.NewTransaction(0, testRecipient, testBalance, params.TxGas, big.NewInt(1), nil), types.HomesteadSigner{}, testKey)
+	if err := sim.SendTransaction(context.Background(), tx); err == nil {
+		t.Fatalf("transaction exceeding balance accepted")
+	}
+}
+
+// Tests that accounts can be funded from the genesis faucet.
+func TestSimulatedFundAccount(t *testing.T) {
+	log.DebugLog()
+	sim := newTestBackend()
+	defer sim.Close()
+
+	ctx := context.Background()
+	addr := testRecipient
+	if err := sim.FundAccount(ctx, addr, big.NewInt(12345)); err != nil {
+		t.Fatalf("failed to fund account: %v", err)
+	}
+	if balance, _ := sim.PendingBalanceAt(ctx, addr); balance.Cmp(big.NewInt(12345)) != 0 {
+		t.Fatalf("pending balance mismatch: have %v, want %v", balance, 12345)
+	}
+	sim.Commit()
+
+	if balance, _ := sim.BalanceAt(ctx, addr, nil); balance.Cmp(big.NewInt(12345)) != 0 {
+		t.Fatalf("balance mismatch: have %v, want %v", balance, 12345)
+	}
+	if balance, _ := sim.BalanceAt(ctx, addr, big.NewInt(0)); balance.Sign() != 0 {
+		t.Fatalf("historical balance mismatch: have %v, want 0", balance)
+	}
+}
+
+// Tests that forking off an older block and extending the side chain reorgs it
+// into the canonical one.
+func TestSimulatedFork(t *testing.T) {
+	log.DebugLog()
+	sim := newTestBackend()
+	defer sim.Close()
+
+	ctx := context.Background()
+	addr := testRecipient
+	genesis, _ := sim.BlockByNumber(ctx, big.NewInt(0))
+
+	sim.FundAccount(ctx, addr, big.NewInt(1))
+	sim.Commit()
+
+	sim.FundAccount(ctx, addr, big.NewInt(2))
+	if err := sim.Fork(ctx, genesis.Hash()); err == nil {
+		t.Fatalf("forked with pending transactions")
+	}
+	sim.Rollback()
+	if err := sim.Fork(ctx, genesis.Hash()); err != nil {
+		t.Fatalf("failed to fork: %v", err)
+	}
+	sim.FundAccount(ctx, addr, big.NewInt(2))
+	sim.Commit()
+	sim.Commit()
+
+	// Longer side chain, the fork becomes canonical
+	if head, _ := sim.HeaderByNumber(ctx, nil); head.Number.Uint64() != 2 {
+		t.Fatalf("head number mismatch: have %v, want 2", head.Number)
+	}
+	if balance, _ := sim.BalanceAt(ctx, addr, nil); balance.Cmp(big.NewInt(2)) != 0 {
+		t.Fatalf("balance mismatch after reorg: have %v, want 2", balance)
+	}
+}
+
+// Tests that the pending block timestamp can be moved forward.
+func TestSimulatedAdjustTime(t *testing.T) {
+	log.DebugLog()
+	sim := newTestBackend()
+	defer sim.Close()
+
+	ctx := context.Background()
+	genesis, _ := sim.BlockByNumber(ctx, big.NewInt(0))
+
+	sim.AdjustTime(time.Hour)
+	sim.FundAccount(ctx, testRecipient, big.NewInt(1))
+	sim.Commit()
+
+	head, _ := sim.HeaderByNumber(ctx, nil)
+	if have, want := head.Time.Uint64()-genesis.Time().Uint64(), uint64(3600+10); have != want {
+		t.Fatalf("block time difference mismatch: have %d, want %d", have, want)
+	}
+}
+
+// Tests that new head notifications are delivered on commit.
+func TestSimulatedSubscribeNewHead(t *testing.T) {
+	log.DebugLog()
+	sim := newTestBackend()
+	defer sim.Close()
+
+	heads := make(chan *types.Header)
+	sub, err := sim.SubscribeNewHead(context.Background(), heads)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	sim.Commit()
+	select {
+	case head := <-heads:
+		if head.Number.Uint64() != 1 {
+			t.Fatalf("head number mismatch: have %v, want 1", head.Number)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("new head notification timeout")
+	}
+}
+
+// Tests that the simulated chain can be used through ethclient unchanged.
+func TestSimulatedEthClient(t *testing.T) {
+	log.DebugLog()
+	sim := newTestBackend()
+	defer sim.Close()
+
+	ctx := context.Background()
+	client := ethclient.NewClient(sim.Client())
+
+	heads := make(chan *types.Header, 1)
+	sub, err := client.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	nonce, err := client.PendingNonceAt(ctx, testAddr)
+	if err != nil {
+		t.Fatalf("failed to retrieve nonce: %v", err)
+	}
+	tx, _ := types.SignTx(types.NewTransaction(nonce, testRecipient, big.NewInt(1), params.TxGas, big.NewInt(1), nil), types.HomesteadSigner{}, testKey)
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	if _, pending, err := client.TransactionByHash(ctx, tx.Hash()); err != nil || !pending {
+		t.Fatalf("pending transaction mismatch: pending %v, err %v", pending, err)
+	}
+	sim.Commit()
+
+	select {
+	case head := <-heads:
+		if head.Number.Uint64() != 1 {
+			t.Fatalf("head number mismatch: have %v, want 1", head.Number)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("new head notification timeout")
+	}
+	block, err := client.BlockByNumber(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve block: %v", err)
+	}
+	if len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != tx.Hash() {
+		t.Fatalf("block transactions mismatch: have %v", block.Transactions())
+	}
+	if receipt, err := client.TransactionReceipt(ctx, tx.Hash()); err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("receipt mismatch: have %v, err %v", receipt, err)
+	}
+	if balance, _ := client.BalanceAt(ctx, testRecipient, nil); balance.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("balance mismatch: have %v, want 1", balance)
+	}
+	if id, err := client.NetworkID(ctx); err != nil || id.Cmp(params.AllEthashProtocolChanges.ChainId) != 0 {
+		t.Fatalf("network id mismatch: have %v, err %v", id, err)
+	}
+	if progress, err := client.SyncProgress(ctx); err != nil || progress != nil {
+		t.Fatalf("sync progress mismatch: have %v, err %v", progress, err)
+	}
+}
@@ -73,6 +73,14 @@ type WatchOpts struct {
 	Context context.Context // Network context to support cancellation and timeouts (nil = no timeout)
 }
 
+// StreamOpts is the collection of options to fine tune durable subscriptions for
+// events within a bound contract.
+type StreamOpts struct {
+	Start     uint64          // Block to start delivering events from, e.g. a persisted checkpoint
+	ChunkSize uint64          // Maximum number of blocks to backfill in one query (0 = 1000)
+	Context   context.Context // Network context to support cancellation and timeouts (nil = no timeout)
+}
+
 // BoundContract is the base wrapper object that reflects a contract on the
 // Ethereum network. It contains a collection of methods that are used by the
 // higher level contract bindings to operate.
@@ -222,6 +230,10 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, i
 		msg := ethereum.CallMsg{From: opts.From, To: contract, Value: value, Data: input}
 		gasLimit, err = c.transactor.EstimateGas(ensureContext(opts.Context), msg)
 		if err != nil {
+			// Surface reverts as is, so callers can inspect the revert reason
+			if revertErr, ok := err.(*abi.RevertError); ok {
+				return nil, revertErr
+			}
 			return nil, fmt.Errorf("failed to estimate gas needed: %v", err)
 		}
 	}
@@ -325,6 +337,53 @@ func (c *BoundContract) WatchLogs(opts *WatchOpts, name string, query ...[]inter
 	return logs, sub, nil
 }
 
+// StreamLogs subscribes to contract logs starting at a given block, backfilling
+// the past ones in bounded chunks before switching over to live ones, without any
+// gaps or duplicates in between. Logs removed by chain reorganisations are passed
+// on with their Removed flag set, provided they were delivered in the first place.
+func (c *BoundContract) StreamLogs(opts *StreamOpts, name string, query ...[]interface{}) (chan types.Log, event.Subscription, error) { debugLog.DebugLog()
+	// Don't crash on a lazy user
+	if opts == nil {
+		opts = new(StreamOpts)
+	}
+	reader, ok := c.filterer.(ChainHeadReader)
+	if !ok {
+		return nil, nil, ErrNoChainHead
+	}
+	// Append the event selector to the query parameters and construct the topic set
+	query = append([][]interface{}{{c.abi.Events[name].Id()}}, query...)
+
+	topics, err := makeTopics(query...)
+	if err != nil {
+		return nil, nil, err
+	}
+	config := ethereum.FilterQuery{
+		Addresses: []common.Address{c.address},
+		Topics:    topics,
+	}
+	// Subscribe to live logs before looking up the head, so none are missed in between
+	ctx := ensureContext(opts.Context)
+
+	live := make(chan types.Log, 128)
+	sub, err := c.filterer.SubscribeFilterLogs(ctx, config, live)
+	if err != nil {
+		return nil, nil, err
+	}
+	head, err := reader.HeaderByNumber(ctx, nil)
+	if err != nil {
+		sub.Unsubscribe()
+		return nil, nil, err
+	}
+	// Start the background backfilling and streaming
+	logs := make(chan types.Log, 128)
+	stream := newLogStream(c.filterer, config, opts, head.Number.Uint64())
+
+	return logs, event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		return stream.run(ctx, live, sub.Err(), logs, quit)
+	}), nil
+}
+
 // UnpackLog unpacks a retrieved log into the provided output structure.
 func (c *BoundContract) UnpackLog(out interface{}, event string, log types.Log) error { debugLog.DebugLog()
 	if len(log.Data) > 0 {
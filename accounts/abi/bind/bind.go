@@ -22,11 +22,13 @@ package bind
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
-	"unicode"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"golang.org/x/tools/imports"
@@ -40,15 +42,91 @@ const (
 	LangGo Lang = iota
 	LangJava
 	LangObjC
+	LangSwift
+	LangTypeScript
 )
 
+// langBackend is a pluggable code generator for a target language, bundling the
+// source template with the type mappers and post processors it relies on.
+type langBackend struct {
+	name             string                                                     // Human readable name of the language
+	template         string                                                     // Source template to render the bindings with
+	bindType         func(kind abi.Type, structs map[string]*tmplStruct) string // Converts Solidity types to language types
+	bindTopicType    func(kind abi.Type, structs map[string]*tmplStruct) string // Converts Solidity topic types to language types
+	namedType        func(string, abi.Type) string                              // Converts language types to method name fragments
+	methodNormalizer func(string) string                                        // Converts Solidity names to language conventions
+	funcs            template.FuncMap                                           // Language specific template helpers (optional)
+	format           func(code []byte) ([]byte, error)                          // Post processor for the rendered code (optional)
+	structs          bool                                                       // Whether tuples can be bound to structs
+}
+
+// langBackends is the set of languages the package can generate bindings for.
+var langBackends = map[Lang]*langBackend{
+	LangGo: {
+		name:             "Go",
+		template:         tmplSourceGo,
+		bindType:         bindTypeGo,
+		bindTopicType:    bindTopicTypeGo,
+		namedType:        func(string, abi.Type) string { panic("this shouldn't be needed") },
+		methodNormalizer: capitalise,
+		format:           formatGo,
+		structs:          true,
+	},
+	LangJava: {
+		name:             "Java",
+		template:         tmplSourceJava,
+		bindType:         bindTypeJava,
+		bindTopicType:    bindTopicTypeJava,
+		namedType:        namedTypeJava,
+		methodNormalizer: decapitalise,
+	},
+	LangObjC: {
+		name:             "Objective-C",
+		template:         tmplSourceObjC,
+		bindType:         bindTypeObjC,
+		bindTopicType:    bindTopicTypeObjC,
+		namedType:        namedTypeMobile,
+		methodNormalizer: lowerCamelCase,
+		funcs:            template.FuncMap{"ident": identObjC},
+		format:           formatTidy,
+	},
+	LangSwift: {
+		name:             "Swift",
+		template:         tmplSourceSwift,
+		bindType:         bindTypeSwift,
+		bindTopicType:    bindTopicTypeSwift,
+		namedType:        namedTypeMobile,
+		methodNormalizer: lowerCamelCase,
+		funcs:            template.FuncMap{"unwrap": unwrapSwift},
+		format:           formatTidy,
+	},
+	LangTypeScript: {
+		name:             "TypeScript",
+		template:         tmplSourceTypeScript,
+		bindType:         bindTypeTypeScript,
+		bindTopicType:    bindTopicTypeTypeScript,
+		namedType:        func(string, abi.Type) string { panic("this shouldn't be needed") },
+		methodNormalizer: lowerCamelCase,
+		funcs:            template.FuncMap{"lowercamel": lowerCamelCase},
+		format:           formatTidy,
+		structs:          true,
+	},
+}
+
 // Bind generates a Go wrapper around a contract ABI. This wrapper isn't meant
 // to be used as is in client code, but rather as an intermediate struct which
 // enforces compile time type safety and naming convention opposed to having to
 // manually maintain hard coded strings that break on runtime.
 func Bind(types []string, abis []string, bytecodes []string, pkg string, lang Lang) (string, error) { log.DebugLog()
+	backend, ok := langBackends[lang]
+	if !ok {
+		return "", fmt.Errorf("unsupported binding language: %d", lang)
+	}
 	// Process each individual contract requested binding
-	contracts := make(map[string]*tmplContract)
+	var (
+		contracts = make(map[string]*tmplContract)
+		structs   = make(map[string]*tmplStruct)
+	)
 
 	for i := 0; i < len(types); i++ {
 		// Parse the actual ABI to generate the binding for
@@ -56,13 +134,18 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 		if err != nil {
 			return "", err
 		}
-		// Strip any whitespace from the JSON ABI
-		strippedABI := strings.Map(func(r rune) rune {
-			if unicode.IsSpace(r) {
-				return -1
+		// Bail out if the contract uses tuples the language has no binding for
+		if !backend.structs {
+			if usage := tupleUsage(evmABI); usage != "" {
+				return "", fmt.Errorf("%s: %s uses tuple types, which are not supported in %s bindings", types[i], usage, backend.name)
 			}
-			return r
-		}, abis[i])
+		}
+		// Strip any whitespace from the JSON ABI, leaving string contents intact
+		stripped := new(bytes.Buffer)
+		if err := json.Compact(stripped, []byte(abis[i])); err != nil {
+			return "", err
+		}
+		strippedABI := stripped.String()
 
 		// Extract the call and transact methods; events; and sort them alphabetically
 		var (
@@ -73,7 +156,7 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 		for _, original := range evmABI.Methods {
 			// Normalize the method for capital cases and non-anonymous inputs/outputs
 			normalized := original
-			normalized.Name = methodNormalizer[lang](original.Name)
+			normalized.Name = backend.methodNormalizer(original.Name)
 
 			normalized.Inputs = make([]abi.Argument, len(original.Inputs))
 			copy(normalized.Inputs, original.Inputs)
@@ -103,7 +186,7 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 			}
 			// Normalize the event for capital cases and non-anonymous outputs
 			normalized := original
-			normalized.Name = methodNormalizer[lang](original.Name)
+			normalized.Name = backend.methodNormalizer(original.Name)
 
 			normalized.Inputs = make([]abi.Argument, len(original.Inputs))
 			copy(normalized.Inputs, original.Inputs)
@@ -118,6 +201,9 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 			// Append the event to the accumulator list
 			events[original.Name] = &tmplEvent{Original: original, Normalized: normalized}
 		}
+		// Gather all the tuples used by the contract into named structs
+		bindStructs(evmABI, structs, lang)
+
 		contracts[types[i]] = &tmplContract{
 			Type:        capitalise(types[i]),
 			InputABI:    strings.Replace(strippedABI, "\"", "\\\"", -1),
@@ -132,37 +218,81 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 	data := &tmplData{
 		Package:   pkg,
 		Contracts: contracts,
+		Structs:   structs,
 	}
 	buffer := new(bytes.Buffer)
 
 	funcs := map[string]interface{}{
-		"bindtype":      bindType[lang],
-		"bindtopictype": bindTopicType[lang],
-		"namedtype":     namedType[lang],
-		"capitalise":    capitalise,
-		"decapitalise":  decapitalise,
+		"bindtype": func(kind abi.Type) string {
+			return backend.bindType(kind, structs)
+		},
+		"bindtopictype": func(kind abi.Type) string {
+			return backend.bindTopicType(kind, structs)
+		},
+		"namedtype":    backend.namedType,
+		"capitalise":   capitalise,
+		"decapitalise": decapitalise,
+	}
+	for name, fn := range backend.funcs {
+		funcs[name] = fn
 	}
-	tmpl := template.Must(template.New("").Funcs(funcs).Parse(tmplSource[lang]))
+	tmpl := template.Must(template.New("").Funcs(funcs).Parse(backend.template))
 	if err := tmpl.Execute(buffer, data); err != nil {
 		return "", err
 	}
-	// For Go bindings pass the code through goimports to clean it up and double check
-	if lang == LangGo {
-		code, err := imports.Process(".", buffer.Bytes(), nil)
-		if err != nil {
-			return "", fmt.Errorf("%v\n%s", err, buffer)
-		}
-		return string(code), nil
+	// Pass the code through the language's post processor if it has any
+	if backend.format == nil {
+		return buffer.String(), nil
+	}
+	code, err := backend.format(buffer.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("%v\n%s", err, buffer)
 	}
-	// For all others just return as is for now
-	return buffer.String(), nil
+	return string(code), nil
 }
 
-// bindType is a set of type binders that convert Solidity types to some supported
-// programming language types.
-var bindType = map[Lang]func(kind abi.Type) string{
-	LangGo:   bindTypeGo,
-	LangJava: bindTypeJava,
+// formatGo passes Go code through goimports to clean it up and double check it.
+func formatGo(code []byte) ([]byte, error) { log.DebugLog()
+	return imports.Process(".", code, nil)
+}
+
+// formatTidy cleans up the whitespace left over by the templates, stripping any
+// trailing and leading ones from the lines and collapsing consecutive empty
+// lines. Indentation is regenerated from the braces in the code.
+func formatTidy(code []byte) ([]byte, error) { log.DebugLog()
+	var (
+		out   bytes.Buffer
+		depth int
+		blank = true
+	)
+	for _, line := range strings.Split(string(code), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if !blank {
+				out.WriteByte('\n')
+			}
+			blank = true
+			continue
+		}
+		// Closing braces dedent themselves, opening ones the following lines
+		indent := depth
+		if strings.HasPrefix(line, "}") {
+			indent--
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if indent < 0 || depth < 0 {
+			return nil, errors.New("unbalanced braces")
+		}
+		// Drop blank lines right after opening braces or right before closing ones
+		if blank && strings.HasPrefix(line, "}") && out.Len() > 0 {
+			trimmed := bytes.TrimRight(out.Bytes(), "\n")
+			out.Truncate(len(trimmed))
+			out.WriteByte('\n')
+		}
+		out.WriteString(strings.Repeat("\t", indent) + line + "\n")
+		blank = strings.HasSuffix(line, "{")
+	}
+	return bytes.TrimLeft(out.Bytes(), "\n"), nil
 }
 
 // Helper function for the binding generators.
@@ -200,7 +330,15 @@ func arrayBindingGo(inner string, arraySizes []string) string { log.DebugLog()
 // bindTypeGo converts a Solidity type to a Go one. Since there is no clear mapping
 // from all Solidity types to Go ones (e.g. uint17), those that cannot be exactly
 // mapped will use an upscaled type (e.g. *big.Int).
-func bindTypeGo(kind abi.Type) string { log.DebugLog()
+func bindTypeGo(kind abi.Type, structs map[string]*tmplStruct) string { log.DebugLog()
+	switch kind.T {
+	case abi.TupleTy:
+		return structs[structKey(kind)].Name
+	case abi.ArrayTy:
+		return fmt.Sprintf("[%d]", kind.Size) + bindTypeGo(*kind.Elem, structs)
+	case abi.SliceTy:
+		return "[]" + bindTypeGo(*kind.Elem, structs)
+	}
 	stringKind := kind.String()
 	innerLen, innerMapping := bindUnnestedTypeGo(stringKind)
 	return arrayBindingGo(wrapArray(stringKind, innerLen, innerMapping))
@@ -248,7 +386,7 @@ func arrayBindingJava(inner string, arraySizes []string) string { log.DebugLog()
 // bindTypeJava converts a Solidity type to a Java one. Since there is no clear mapping
 // from all Solidity types to Java ones (e.g. uint17), those that cannot be exactly
 // mapped will use an upscaled type (e.g. BigDecimal).
-func bindTypeJava(kind abi.Type) string { log.DebugLog()
+func bindTypeJava(kind abi.Type, structs map[string]*tmplStruct) string { log.DebugLog()
 	stringKind := kind.String()
 	innerLen, innerMapping := bindUnnestedTypeJava(stringKind)
 	return arrayBindingJava(wrapArray(stringKind, innerLen, innerMapping))
@@ -309,18 +447,11 @@ func bindUnnestedTypeJava(stringKind string) (int, string) { log.DebugLog()
 	}
 }
 
-// bindTopicType is a set of type binders that convert Solidity types to some
-// supported programming language topic types.
-var bindTopicType = map[Lang]func(kind abi.Type) string{
-	LangGo:   bindTopicTypeGo,
-	LangJava: bindTopicTypeJava,
-}
-
 // bindTypeGo converts a Solidity topic type to a Go one. It is almost the same
 // funcionality as for simple types, but dynamic types get converted to hashes.
-func bindTopicTypeGo(kind abi.Type) string { log.DebugLog()
-	bound := bindTypeGo(kind)
-	if bound == "string" || bound == "[]byte" {
+func bindTopicTypeGo(kind abi.Type, structs map[string]*tmplStruct) string { log.DebugLog()
+	bound := bindTypeGo(kind, structs)
+	if bound == "string" || bound == "[]byte" || kind.T == abi.TupleTy {
 		bound = "common.Hash"
 	}
 	return bound
@@ -328,19 +459,152 @@ func bindTopicTypeGo(kind abi.Type) string { log.DebugLog()
 
 // bindTypeGo converts a Solidity topic type to a Java one. It is almost the same
 // funcionality as for simple types, but dynamic types get converted to hashes.
-func bindTopicTypeJava(kind abi.Type) string { log.DebugLog()
-	bound := bindTypeJava(kind)
+func bindTopicTypeJava(kind abi.Type, structs map[string]*tmplStruct) string { log.DebugLog()
+	bound := bindTypeJava(kind, structs)
 	if bound == "String" || bound == "Bytes" {
 		bound = "Hash"
 	}
 	return bound
 }
 
-// namedType is a set of functions that transform language specific types to
-// named versions that my be used inside method names.
-var namedType = map[Lang]func(string, abi.Type) string{
-	LangGo:   func(string, abi.Type) string { panic("this shouldn't be needed") },
-	LangJava: namedTypeJava,
+// bindStructs collects all the tuples used by the methods and events of a contract
+// into the set of structs to generate, iterating in a deterministic order so that
+// anonymous structs get stable names.
+func bindStructs(evmABI abi.ABI, structs map[string]*tmplStruct, lang Lang) { log.DebugLog()
+	for _, input := range evmABI.Constructor.Inputs {
+		bindStructType(input.Type, structs, lang)
+	}
+	methods := make([]string, 0, len(evmABI.Methods))
+	for name := range evmABI.Methods {
+		methods = append(methods, name)
+	}
+	sort.Strings(methods)
+	for _, name := range methods {
+		for _, input := range evmABI.Methods[name].Inputs {
+			bindStructType(input.Type, structs, lang)
+		}
+		for _, output := range evmABI.Methods[name].Outputs {
+			bindStructType(output.Type, structs, lang)
+		}
+	}
+	events := make([]string, 0, len(evmABI.Events))
+	for name := range evmABI.Events {
+		events = append(events, name)
+	}
+	sort.Strings(events)
+	for _, name := range events {
+		for _, input := range evmABI.Events[name].Inputs {
+			bindStructType(input.Type, structs, lang)
+		}
+	}
+}
+
+// bindStructType registers a struct definition for the tuple types contained in
+// kind, nested ones first so they can be referenced by the outer fields. Structs
+// are named after the Solidity ones if the ABI carries that, Struct0, Struct1...
+// otherwise.
+func bindStructType(kind abi.Type, structs map[string]*tmplStruct, lang Lang) { log.DebugLog()
+	switch kind.T {
+	case abi.ArrayTy, abi.SliceTy:
+		bindStructType(*kind.Elem, structs, lang)
+
+	case abi.TupleTy:
+		key := structKey(kind)
+		if _, exist := structs[key]; exist {
+			return
+		}
+		fields := make([]*tmplField, len(kind.TupleElems))
+		for i, elem := range kind.TupleElems {
+			bindStructType(*elem, structs, lang)
+			fields[i] = &tmplField{
+				Type:    langBackends[lang].bindType(*elem, structs),
+				Name:    abi.ToCamelCase(kind.TupleRawNames[i]),
+				SolKind: *elem,
+			}
+		}
+		// Make sure distinct tuples don't end up with the same name
+		taken := make(map[string]bool)
+		for _, s := range structs {
+			taken[s.Name] = true
+		}
+		name := capitalise(kind.TupleRawName)
+		if name == "" || taken[name] {
+			base := name
+			if base == "" {
+				base = "Struct"
+			}
+			for i := len(structs); ; i++ {
+				if name = fmt.Sprintf("%s%d", base, i); !taken[name] {
+					break
+				}
+			}
+		}
+		structs[key] = &tmplStruct{Name: name, Fields: fields}
+	}
+}
+
+// structKey returns a unique identifier of a tuple type. Contrary to the signature
+// it takes the struct and field names into account too, since those become part
+// of the generated code.
+func structKey(kind abi.Type) string { log.DebugLog()
+	switch kind.T {
+	case abi.ArrayTy:
+		return fmt.Sprintf("%s[%d]", structKey(*kind.Elem), kind.Size)
+	case abi.SliceTy:
+		return structKey(*kind.Elem) + "[]"
+	case abi.TupleTy:
+		fields := make([]string, len(kind.TupleElems))
+		for i, elem := range kind.TupleElems {
+			fields[i] = structKey(*elem) + " " + kind.TupleRawNames[i]
+		}
+		return kind.TupleRawName + "(" + strings.Join(fields, ",") + ")"
+	default:
+		return kind.String()
+	}
+}
+
+// tupleUsage returns the first constructor, method or event of a contract taking
+// or returning tuples, or an empty string if it doesn't use any.
+func tupleUsage(evmABI abi.ABI) string { log.DebugLog()
+	for _, input := range evmABI.Constructor.Inputs {
+		if hasTuple(input.Type) {
+			return "constructor"
+		}
+	}
+	methods := make([]string, 0, len(evmABI.Methods))
+	for name := range evmABI.Methods {
+		methods = append(methods, name)
+	}
+	sort.Strings(methods)
+	for _, name := range methods {
+		method := evmABI.Methods[name]
+		for _, arg := range append(method.Inputs, method.Outputs...) {
+			if hasTuple(arg.Type) {
+				return "method " + name
+			}
+		}
+	}
+	events := make([]string, 0, len(evmABI.Events))
+	for name := range evmABI.Events {
+		events = append(events, name)
+	}
+	sort.Strings(events)
+	for _, name := range events {
+		for _, input := range evmABI.Events[name].Inputs {
+			if hasTuple(input.Type) {
+				return "event " + name
+			}
+		}
+	}
+	return ""
+}
+
+// hasTuple returns whether a type is a tuple or an array of tuples.
+func hasTuple(kind abi.Type) bool { log.DebugLog()
+	for kind.T == abi.ArrayTy || kind.T == abi.SliceTy {
+		kind = *kind.Elem
+	}
+	return kind.T == abi.TupleTy
 }
 
 // namedTypeJava converts some primitive data types to named variants that can
@@ -379,13 +643,6 @@ func namedTypeJava(javaKind string, solKind abi.Type) string { log.DebugLog()
 	}
 }
 
-// methodNormalizer is a name transformer that modifies Solidity method names to
-// conform to target language naming concentions.
-var methodNormalizer = map[Lang]func(string) string{
-	LangGo:   capitalise,
-	LangJava: decapitalise,
-}
-
 // capitalise makes a camel-case string which starts with an upper case character.
 func capitalise(input string) string { log.DebugLog()
 	for len(input) > 0 && input[0] == '_' {
@@ -408,6 +665,21 @@ func decapitalise(input string) string { log.DebugLog()
 	return toCamelCase(strings.ToLower(input[:1]) + input[1:])
 }
 
+// lowerCamelCase makes a camel-case string which starts with a lower case character,
+// the naming convention of the methods and fields in the mobile and TypeScript
+// bindings.
+func lowerCamelCase(input string) string { log.DebugLog()
+	for len(input) > 0 && input[0] == '_' {
+		input = input[1:]
+	}
+	if len(input) == 0 {
+		return ""
+	}
+	// Camel casing upper cases the first character, so lower case it afterwards
+	input = toCamelCase(input)
+	return strings.ToLower(input[:1]) + input[1:]
+}
+
 // toCamelCase converts an under-score string to a camel-case string
 func toCamelCase(input string) string { log.DebugLog()
 	toupper := false
@@ -17,6 +17,7 @@
 package bind
 
 import (
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -27,9 +28,12 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
 	"golang.org/x/tools/imports"
 )
 
+var update = flag.Bool("update", false, "update the golden files of the bindings")
+
 var bindTests = []struct {
 	name     string
 	contract string
@@ -740,6 +744,53 @@ var bindTests = []struct {
 				t.Fatalf("unsubscribed simple event arrived: %v", event)
 			case <-time.After(250 * time.Millisecond):
 			}
+			// Test streaming events from the genesis on, the past ones backfilled
+			pit, err := eventer.FilterSimpleEvent(nil, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("failed to filter for simple events: %v", err)
+			}
+			defer pit.Close()
+
+			var past []*EventerSimpleEvent
+			for pit.Next() {
+				past = append(past, pit.Event)
+			}
+			stream := make(chan *EventerSimpleEvent, 16)
+			ssub, err := eventer.StreamSimpleEvent(&bind.StreamOpts{ChunkSize: 2}, stream, nil, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("failed to stream simple events: %v", err)
+			}
+			defer ssub.Unsubscribe()
+
+			for i, want := range past {
+				select {
+				case event := <-stream:
+					if event.Raw.TxHash != want.Raw.TxHash || event.Value.Cmp(want.Value) != 0 {
+						t.Errorf("streamed event %d mismatch: have %v, want %v", i, event, want)
+					}
+				case <-time.After(250 * time.Millisecond):
+					t.Fatalf("streamed event %d didn't arrive", i)
+				}
+			}
+			// Raise a new event and ensure it's streamed exactly once
+			if _, err := eventer.RaiseSimpleEvent(auth, common.Address{253}, [32]byte{253}, true, big.NewInt(253)); err != nil {
+				t.Fatalf("failed to raise streamed simple event: %v", err)
+			}
+			sim.Commit()
+
+			select {
+			case event := <-stream:
+				if event.Value.Uint64() != 253 {
+					t.Errorf("streamed log content mismatch: have %v, want 253", event)
+				}
+			case <-time.After(250 * time.Millisecond):
+				t.Fatalf("streamed simple event didn't arrive")
+			}
+			select {
+			case event := <-stream:
+				t.Fatalf("duplicate simple event streamed: %v", event)
+			case <-time.After(250 * time.Millisecond):
+			}
 		`,
 	},
 	{
@@ -809,6 +860,51 @@ var bindTests = []struct {
 			}
 		`,
 	},
+	// Tests that tuples are bound to named structs, nested ones included
+	{
+		`Structs`,
+		`
+			// Hand assembled contract returning its calldata sans the method id. Since
+			// both methods take the same arguments as echo returns, those round trip.
+			calldatacopy(0, 4, sub(calldatasize, 4))
+			return(0, sub(calldatasize, 4))
+		`,
+		`600e80600b6000396000f336600490038060046000376000f3`,
+		`[{"constant":true,"inputs":[{"name":"a","type":"tuple","internalType":"struct Structs.Point","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]},{"name":"b","type":"tuple[]","components":[{"name":"label","type":"string"},{"name":"points","type":"tuple[2]","internalType":"struct Structs.Point[2]","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]}]}],"name":"echo","outputs":[{"name":"a","type":"tuple","internalType":"struct Structs.Point","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]},{"name":"b","type":"tuple[]","components":[{"name":"label","type":"string"},{"name":"points","type":"tuple[2]","internalType":"struct Structs.Point[2]","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]}]}],"type":"function"},{"constant":false,"inputs":[{"name":"a","type":"tuple","internalType":"struct Structs.Point","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]},{"name":"b","type":"tuple[]","components":[{"name":"label","type":"string"},{"name":"points","type":"tuple[2]","internalType":"struct Structs.Point[2]","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]}]}],"name":"store","outputs":[],"type":"function"}]`,
+		`
+			// Generate a new random account and a funded simulator
+			key, _ := crypto.GenerateKey()
+			auth := bind.NewKeyedTransactor(key)
+			sim := backends.NewSimulatedBackend(core.GenesisAlloc{auth.From: {Balance: big.NewInt(10000000000)}})
+
+			// Deploy the echoing contract
+			_, _, structs, err := DeployStructs(auth, sim)
+			if err != nil {
+				t.Fatalf("Failed to deploy structs contract: %v", err)
+			}
+			sim.Commit()
+
+			// Named Solidity structs keep their names, anonymous ones get generated ones
+			a := Point{X: big.NewInt(1), Y: big.NewInt(2)}
+			b := []Struct1{
+				{Label: "first", Points: [2]Point{{X: big.NewInt(3), Y: big.NewInt(4)}, {X: big.NewInt(5), Y: big.NewInt(6)}}},
+				{Label: "second", Points: [2]Point{{X: big.NewInt(7), Y: big.NewInt(8)}, {X: big.NewInt(9), Y: big.NewInt(10)}}},
+			}
+			res, err := structs.Echo(nil, a, b)
+			if err != nil {
+				t.Fatalf("Failed to call tuple echo: %v", err)
+			}
+			if !reflect.DeepEqual(res.A, a) {
+				t.Fatalf("Tuple mismatch: have %v, want %v", res.A, a)
+			}
+			if !reflect.DeepEqual(res.B, b) {
+				t.Fatalf("Tuple slice mismatch: have %v, want %v", res.B, b)
+			}
+			if _, err := structs.Store(auth, a, b); err != nil {
+				t.Fatalf("Failed to transact with tuples: %v", err)
+			}
+		`,
+	},
 }
 
 // Tests that packages generated by the binder can be successfully compiled and
@@ -866,3 +962,75 @@ func TestBindings(t *testing.T) { log.DebugLog()
 		t.Fatalf("failed to run binding test: %v\n%s", err, out)
 	}
 }
+
+// Tests that the bindings generated for the languages that cannot be compiled in
+// the test environment match the golden files in testdata. Run the tests with the
+// -update flag to regenerate them after a template change.
+func TestBindingsGolden(t *testing.T) { log.DebugLog()
+	langs := []struct {
+		lang Lang
+		ext  string
+	}{
+		{LangObjC, "m"},
+		{LangSwift, "swift"},
+		{LangTypeScript, "d.ts"},
+	}
+	names := map[string]bool{"Token": true, "Eventer": true, "Structs": true}
+
+	for _, tt := range bindTests {
+		if !names[tt.name] {
+			continue
+		}
+		for _, lang := range langs {
+			// Only the TypeScript bindings support tuples
+			if tt.name == "Structs" && lang.lang != LangTypeScript {
+				continue
+			}
+			bind, err := Bind([]string{tt.name}, []string{tt.abi}, []string{tt.bytecode}, "bindtest", lang.lang)
+			if err != nil {
+				t.Fatalf("%s.%s: failed to generate binding: %v", tt.name, lang.ext, err)
+			}
+			path := filepath.Join("testdata", strings.ToLower(tt.name)+"."+lang.ext+".golden")
+			if *update {
+				if err := ioutil.WriteFile(path, []byte(bind), 0644); err != nil {
+					t.Fatalf("%s.%s: failed to update golden file: %v", tt.name, lang.ext, err)
+				}
+				continue
+			}
+			want, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%s.%s: failed to read golden file: %v", tt.name, lang.ext, err)
+			}
+			if bind != string(want) {
+				t.Errorf("%s.%s: binding mismatch:\nhave:\n%s\nwant:\n%s", tt.name, lang.ext, bind, want)
+			}
+		}
+	}
+}
+
+// Tests that requesting a binding in an unknown language is rejected instead of
+// crashing the generator.
+func TestBindingsUnknownLang(t *testing.T) { log.DebugLog()
+	if _, err := Bind([]string{"Empty"}, []string{"[]"}, []string{""}, "bindtest", Lang(-1)); err == nil {
+		t.Fatalf("binding generated for unknown language")
+	}
+}
+
+// Tests that contracts using tuples are rejected by the languages that can't bind
+// them, instead of generating code relying on nonexistent accessors.
+func TestBindingsUnsupportedTuples(t *testing.T) { log.DebugLog()
+	for _, tt := range bindTests {
+		if tt.name != "Structs" {
+			continue
+		}
+		for _, lang := range []Lang{LangJava, LangObjC, LangSwift} {
+			_, err := Bind([]string{tt.name}, []string{tt.abi}, []string{tt.bytecode}, "bindtest", lang)
+			if err == nil {
+				t.Fatalf("lang %d: tuple binding generated", lang)
+			}
+			if want := "Structs: method echo uses tuple types"; !strings.HasPrefix(err.Error(), want) {
+				t.Errorf("lang %d: error mismatch: have %q, want prefix %q", lang, err, want)
+			}
+		}
+	}
+}
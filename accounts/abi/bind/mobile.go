@@ -0,0 +1,214 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// The Objective-C and Swift bindings are built on top of the Geth framework that
+// gomobile generates from the mobile package, so the types below are the ones
+// the mobile package exports, prefixed the way gomobile does.
+
+// bindTypeObjC converts a Solidity type to an Objective-C one. Since the mobile
+// package only exposes a handful of types, those that cannot be exactly mapped
+// will use an upscaled type (e.g. GethBigInt).
+func bindTypeObjC(kind abi.Type, structs map[string]*tmplStruct) string {
+	log.DebugLog()
+	switch kind.T {
+	case abi.BoolTy:
+		return "BOOL"
+	case abi.StringTy:
+		return "NSString*"
+	case abi.BytesTy, abi.FixedBytesTy, abi.FunctionTy:
+		return "NSData*"
+	case abi.ArrayTy, abi.SliceTy:
+		if inner := bindTypeMobile(*kind.Elem); inner != "" {
+			return "Geth" + inner + "s*"
+		}
+		return "NSArray*"
+	case abi.IntTy:
+		if isMobileInt(kind) {
+			return fmt.Sprintf("int%d_t", kind.Size)
+		}
+	}
+	if inner := bindTypeMobile(kind); inner != "" {
+		return "Geth" + inner + "*"
+	}
+	return "NSObject*"
+}
+
+// bindTopicTypeObjC converts a Solidity topic type to an Objective-C one. It is
+// almost the same functionality as for simple types, but dynamic types get
+// converted to hashes.
+func bindTopicTypeObjC(kind abi.Type, structs map[string]*tmplStruct) string {
+	log.DebugLog()
+	if isDynamicTopic(kind) {
+		return "GethHash*"
+	}
+	return bindTypeObjC(kind, structs)
+}
+
+// bindTypeSwift converts a Solidity type to a Swift one. Since the mobile package
+// only exposes a handful of types, those that cannot be exactly mapped will use
+// an upscaled type (e.g. GethBigInt).
+func bindTypeSwift(kind abi.Type, structs map[string]*tmplStruct) string {
+	log.DebugLog()
+	switch kind.T {
+	case abi.BoolTy:
+		return "Bool"
+	case abi.StringTy:
+		return "String"
+	case abi.BytesTy, abi.FixedBytesTy, abi.FunctionTy:
+		return "Data"
+	case abi.ArrayTy, abi.SliceTy:
+		if inner := bindTypeMobile(*kind.Elem); inner != "" {
+			return "Geth" + inner + "s"
+		}
+		return "[Any]"
+	case abi.IntTy:
+		if isMobileInt(kind) {
+			return fmt.Sprintf("Int%d", kind.Size)
+		}
+	}
+	if inner := bindTypeMobile(kind); inner != "" {
+		return "Geth" + inner
+	}
+	return "Any"
+}
+
+// bindTopicTypeSwift converts a Solidity topic type to a Swift one. It is almost
+// the same functionality as for simple types, but dynamic types get converted
+// to hashes.
+func bindTopicTypeSwift(kind abi.Type, structs map[string]*tmplStruct) string {
+	log.DebugLog()
+	if isDynamicTopic(kind) {
+		return "GethHash"
+	}
+	return bindTypeSwift(kind, structs)
+}
+
+// bindTypeMobile returns the name of the mobile package wrapper type around a
+// Solidity one, or an empty string if there is no such wrapper.
+func bindTypeMobile(kind abi.Type) string {
+	log.DebugLog()
+	switch kind.T {
+	case abi.AddressTy:
+		return "Address"
+	case abi.HashTy:
+		return "Hash"
+	case abi.IntTy, abi.UintTy:
+		return "BigInt"
+	case abi.StringTy:
+		return "String"
+	}
+	return ""
+}
+
+// isMobileInt returns whether a Solidity integer maps to a native integer of the
+// mobile package. Only signed ones do, as there are no unsigned types in Java.
+func isMobileInt(kind abi.Type) bool {
+	log.DebugLog()
+	if kind.T != abi.IntTy {
+		return false
+	}
+	switch kind.Size {
+	case 8, 16, 32, 64:
+		return true
+	}
+	return false
+}
+
+// isDynamicTopic returns whether an indexed event argument of the given type is
+// replaced by its hash in the log topics.
+func isDynamicTopic(kind abi.Type) bool {
+	log.DebugLog()
+	switch kind.T {
+	case abi.StringTy, abi.BytesTy, abi.ArrayTy, abi.SliceTy:
+		return true
+	}
+	return false
+}
+
+// namedTypeMobile converts the Solidity types to the named variants used by the
+// accessors of the mobile package's Interface type (e.g. SetBigInt, GetAddresses).
+func namedTypeMobile(kind string, solKind abi.Type) string {
+	log.DebugLog()
+	switch solKind.T {
+	case abi.ArrayTy, abi.SliceTy:
+		switch solKind.Elem.T {
+		case abi.BoolTy:
+			return "Bools"
+		case abi.BytesTy, abi.FixedBytesTy:
+			return "Binaries"
+		}
+		if inner := bindTypeMobile(*solKind.Elem); inner != "" {
+			return inner + "s"
+		}
+	case abi.BoolTy:
+		return "Bool"
+	case abi.BytesTy, abi.FixedBytesTy, abi.FunctionTy:
+		return "Binary"
+	case abi.IntTy, abi.UintTy:
+		switch solKind.Size {
+		case 8, 16, 32, 64:
+			return capitalise(solKind.String())
+		}
+		return "BigInt"
+	case abi.AddressTy, abi.HashTy, abi.StringTy:
+		return bindTypeMobile(solKind)
+	}
+	return strings.Trim(kind, "[]*")
+}
+
+// unwrapSwift returns the force unwrapping operator for the Swift types that the
+// mobile framework returns as optionals, or nothing for the value types.
+func unwrapSwift(kind string) string {
+	log.DebugLog()
+	switch kind {
+	case "Bool", "Int8", "Int16", "Int32", "Int64", "String":
+		return ""
+	}
+	return "!"
+}
+
+// reservedObjC is the set of C and Objective-C keywords and types that cannot be
+// used as variable names.
+var reservedObjC = map[string]bool{
+	"auto": true, "break": true, "case": true, "char": true, "const": true, "continue": true,
+	"default": true, "do": true, "double": true, "else": true, "enum": true, "extern": true,
+	"float": true, "for": true, "goto": true, "if": true, "inline": true, "int": true,
+	"long": true, "register": true, "restrict": true, "return": true, "short": true,
+	"signed": true, "sizeof": true, "static": true, "struct": true, "switch": true,
+	"typedef": true, "union": true, "unsigned": true, "void": true, "volatile": true,
+	"while": true, "id": true, "self": true, "super": true, "nil": true, "Nil": true,
+	"YES": true, "NO": true, "BOOL": true, "SEL": true, "Class": true, "IMP": true,
+	"in": true, "out": true, "inout": true, "bycopy": true, "byref": true, "oneway": true,
+}
+
+// identObjC converts an argument name to a valid Objective-C variable name.
+func identObjC(name string) string {
+	log.DebugLog()
+	if reservedObjC[name] {
+		return name + "_"
+	}
+	return name
+}
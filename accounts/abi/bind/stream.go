@@ -0,0 +1,192 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	debugLog "github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// defaultStreamChunkSize is the number of blocks backfilled in one query if
+	// the user didn't specify otherwise.
+	defaultStreamChunkSize = 1000
+
+	// streamTrackedBlocks is the number of blocks below the newest delivered log
+	// for which delivered logs are remembered to filter out duplicates and stray
+	// removals. Removals below that are assumed to affect delivered logs.
+	streamTrackedBlocks = 256
+)
+
+// errStreamQuit is returned internally when the user tears down a log stream.
+var errStreamQuit = errors.New("stream quit")
+
+// logKey uniquely identifies a log in the chain.
+type logKey struct {
+	block common.Hash
+	index uint
+}
+
+// logStream is the background worker of a durable log subscription, merging the
+// backfilled past logs with the live ones.
+type logStream struct {
+	filterer ContractFilterer     // Backend to retrieve the past logs from
+	query    ethereum.FilterQuery // Address and topic filters of the stream
+
+	start uint64 // First block to deliver logs from
+	head  uint64 // Last block to backfill, live logs take over from there
+	chunk uint64 // Number of blocks to backfill in a single query
+
+	seen    map[logKey]uint64 // Delivered logs with their block numbers
+	newest  uint64            // Highest block number a log was delivered from
+	pending []types.Log       // Live logs queued up while backfilling
+}
+
+// newLogStream creates the worker of a durable log subscription.
+func newLogStream(filterer ContractFilterer, query ethereum.FilterQuery, opts *StreamOpts, head uint64) *logStream {
+	debugLog.DebugLog()
+	chunk := opts.ChunkSize
+	if chunk == 0 {
+		chunk = defaultStreamChunkSize
+	}
+	return &logStream{
+		filterer: filterer,
+		query:    query,
+		start:    opts.Start,
+		head:     head,
+		chunk:    chunk,
+		seen:     make(map[logKey]uint64),
+	}
+}
+
+// run backfills all the past logs from the start block up to the head, then
+// delivers the live ones until the subscription fails or is torn down.
+func (s *logStream) run(ctx context.Context, live <-chan types.Log, errc <-chan error, sink chan<- types.Log, quit <-chan struct{}) error {
+	debugLog.DebugLog()
+	err := s.stream(ctx, live, errc, sink, quit)
+	if err == errStreamQuit {
+		return nil
+	}
+	return err
+}
+
+// stream implements run, aborting with errStreamQuit if the user unsubscribes.
+func (s *logStream) stream(ctx context.Context, live <-chan types.Log, errc <-chan error, sink chan<- types.Log, quit <-chan struct{}) error {
+	debugLog.DebugLog()
+	// Backfill the past logs in bounded chunks, queueing up live ones meanwhile
+	for from := s.start; from <= s.head; from += s.chunk {
+		to := from + s.chunk - 1
+		if to > s.head || to < from {
+			to = s.head
+		}
+		query := s.query
+		query.FromBlock, query.ToBlock = new(big.Int).SetUint64(from), new(big.Int).SetUint64(to)
+
+		logs, err := s.filterer.FilterLogs(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, log := range logs {
+			if err := s.deliver(log, live, errc, sink, quit); err != nil {
+				return err
+			}
+		}
+		if to == s.head {
+			break
+		}
+	}
+	// Backfill done, deliver the live logs, the ones queued up while blocked first
+	for {
+		var log types.Log
+		if len(s.pending) > 0 {
+			log, s.pending = s.pending[0], s.pending[1:]
+		} else {
+			select {
+			case log = <-live:
+			case err := <-errc:
+				return err
+			case <-quit:
+				return errStreamQuit
+			}
+		}
+		if err := s.deliver(log, live, errc, sink, quit); err != nil {
+			return err
+		}
+	}
+}
+
+// deliver forwards a log to the user if it's not a duplicate or the removal of
+// a log never delivered, queueing up any live logs arriving while blocked.
+func (s *logStream) deliver(log types.Log, live <-chan types.Log, errc <-chan error, sink chan<- types.Log, quit <-chan struct{}) error {
+	debugLog.DebugLog()
+	if !s.track(log) {
+		return nil
+	}
+	for {
+		select {
+		case sink <- log:
+			return nil
+		case queued := <-live:
+			s.pending = append(s.pending, queued)
+		case err := <-errc:
+			return err
+		case <-quit:
+			return errStreamQuit
+		}
+	}
+}
+
+// track updates the set of delivered logs and returns whether the given one
+// needs to be delivered.
+func (s *logStream) track(log types.Log) bool {
+	debugLog.DebugLog()
+	if log.BlockNumber < s.start {
+		return false
+	}
+	key := logKey{log.BlockHash, log.Index}
+	if log.Removed {
+		if _, ok := s.seen[key]; ok {
+			delete(s.seen, key)
+			return true
+		}
+		// Unknown removal, deliver only if it's too old to be tracked any more
+		return s.newest > streamTrackedBlocks && log.BlockNumber < s.newest-streamTrackedBlocks
+	}
+	if _, ok := s.seen[key]; ok {
+		return false
+	}
+	s.seen[key] = log.BlockNumber
+
+	// Forget about the logs too deep to be reorged or duplicated any more
+	if log.BlockNumber > s.newest {
+		s.newest = log.BlockNumber
+		if s.newest > streamTrackedBlocks {
+			for key, number := range s.seen {
+				if number < s.newest-streamTrackedBlocks {
+					delete(s.seen, key)
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// streamTestFilterer is a log filterer serving a fixed set of past logs, with
+// live ones pushed by the test.
+type streamTestFilterer struct {
+	past    []types.Log // Logs returned by FilterLogs
+	overlap []types.Log // Live logs already queued when subscribing
+	head    uint64      // Block number of the chain head
+
+	lock   sync.Mutex
+	ranges [][2]uint64      // Block ranges queried by FilterLogs
+	live   chan<- types.Log // Channel of the live subscription
+}
+
+func (f *streamTestFilterer) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) { log.DebugLog()
+	from, to := query.FromBlock.Uint64(), query.ToBlock.Uint64()
+
+	f.lock.Lock()
+	f.ranges = append(f.ranges, [2]uint64{from, to})
+	f.lock.Unlock()
+
+	var logs []types.Log
+	for _, log := range f.past {
+		if log.BlockNumber >= from && log.BlockNumber <= to {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func (f *streamTestFilterer) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) { log.DebugLog()
+	for _, log := range f.overlap {
+		ch <- log
+	}
+	f.live = ch
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	}), nil
+}
+
+func (f *streamTestFilterer) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) { log.DebugLog()
+	return &types.Header{Number: new(big.Int).SetUint64(f.head)}, nil
+}
+
+// makeStreamLog creates a log in the given block, its hash derived from a fork id.
+func makeStreamLog(number uint64, fork byte, removed bool) types.Log { log.DebugLog()
+	return types.Log{BlockNumber: number, BlockHash: common.Hash{fork, byte(number)}, Removed: removed}
+}
+
+// Tests that durable log streams backfill in chunks from the requested block, hand
+// over to live logs without duplicates, and only report removals of delivered logs.
+func TestStreamLogs(t *testing.T) { log.DebugLog()
+	parsed, err := abi.JSON(strings.NewReader(`[{"type": "event", "name": "Event", "inputs": []}]`))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	filterer := &streamTestFilterer{
+		past: []types.Log{
+			makeStreamLog(1, 0, false), makeStreamLog(2, 0, false), makeStreamLog(3, 0, false),
+			makeStreamLog(4, 0, false), makeStreamLog(5, 0, false),
+		},
+		overlap: []types.Log{makeStreamLog(5, 0, false), makeStreamLog(6, 0, false)},
+		head:    5,
+	}
+	contract := NewBoundContract(common.Address{}, parsed, nil, nil, filterer)
+
+	logs, sub, err := contract.StreamLogs(&StreamOpts{Start: 2, ChunkSize: 2}, "Event")
+	if err != nil {
+		t.Fatalf("failed to stream logs: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	expect := func(want types.Log) {
+		select {
+		case have := <-logs:
+			if have.BlockHash != want.BlockHash || have.Removed != want.Removed {
+				t.Fatalf("log mismatch: have %x (removed %v), want %x (removed %v)", have.BlockHash, have.Removed, want.BlockHash, want.Removed)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("log %x (removed %v) not delivered", want.BlockHash, want.Removed)
+		}
+	}
+	// Past logs from the start block, then the live ones past the head
+	for number := uint64(2); number <= 6; number++ {
+		expect(makeStreamLog(number, 0, false))
+	}
+	if want := [][2]uint64{{2, 3}, {4, 5}}; len(filterer.ranges) != len(want) || filterer.ranges[0] != want[0] || filterer.ranges[1] != want[1] {
+		t.Errorf("backfill range mismatch: have %v, want %v", filterer.ranges, want)
+	}
+	// Reorg the last two blocks, one of which wasn't delivered at all
+	filterer.live <- makeStreamLog(6, 0, true)
+	filterer.live <- makeStreamLog(7, 0, true)
+	filterer.live <- makeStreamLog(6, 1, false)
+	filterer.live <- makeStreamLog(7, 1, false)
+
+	expect(makeStreamLog(6, 0, true))
+	expect(makeStreamLog(6, 1, false))
+	expect(makeStreamLog(7, 1, false))
+
+	select {
+	case log := <-logs:
+		t.Fatalf("unexpected log delivered: %x (removed %v)", log.BlockHash, log.Removed)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// Tests that durable log streams are rejected if the chain head is unavailable.
+func TestStreamLogsNoChainHead(t *testing.T) { log.DebugLog()
+	parsed, err := abi.JSON(strings.NewReader(`[{"type": "event", "name": "Event", "inputs": []}]`))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	filterer := struct{ ContractFilterer }{new(streamTestFilterer)}
+	contract := NewBoundContract(common.Address{}, parsed, nil, nil, filterer)
+
+	if _, _, err := contract.StreamLogs(nil, "Event"); err != ErrNoChainHead {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNoChainHead)
+	}
+}
@@ -22,6 +22,7 @@ import "github.com/ethereum/go-ethereum/accounts/abi"
 type tmplData struct {
 	Package   string                   // Name of the package to place the generated file in
 	Contracts map[string]*tmplContract // List of contracts to generate into this file
+	Structs   map[string]*tmplStruct   // Contract struct type definitions
 }
 
 // tmplContract contains the data needed to generate an individual contract binding.
@@ -49,11 +50,19 @@ type tmplEvent struct {
 	Normalized abi.Event // Normalized version of the parsed fields
 }
 
-// tmplSource is language to template mapping containing all the supported
-// programming languages the package can generate to.
-var tmplSource = map[Lang]string{
-	LangGo:   tmplSourceGo,
-	LangJava: tmplSourceJava,
+// tmplField is a wrapper around a struct field with its type in the binding
+// language and its normalized name.
+type tmplField struct {
+	Type    string   // Field type representation depends on target binding language
+	Name    string   // Field name converted from the raw user-defined field name
+	SolKind abi.Type // Raw abi type information
+}
+
+// tmplStruct is a wrapper around an abi tuple, named after the Solidity struct if
+// the ABI contains it or auto-generated otherwise.
+type tmplStruct struct {
+	Name   string       // Name of the struct type in the binding language
+	Fields []*tmplField // Struct fields definition depends on the binding language
 }
 
 // tmplSourceGo is the Go source template use to generate the contract binding
@@ -64,6 +73,14 @@ const tmplSourceGo = `
 
 package {{.Package}}
 
+{{range .Structs}}
+	// {{.Name}} is an auto generated low-level Go binding around an user-defined struct.
+	type {{.Name}} struct {
+	{{range .Fields}}
+		{{.Name}} {{.Type}}{{end}}
+	}
+{{end}}
+
 {{range $contract := .Contracts}}
 	// {{.Type}}ABI is the input ABI used to generate the binding from.
 	const {{.Type}}ABI = "{{.InputABI}}"
@@ -73,7 +90,7 @@ package {{.Package}}
 		const {{.Type}}Bin = ` + "`" + `{{.InputBin}}` + "`" + `
 
 		// Deploy{{.Type}} deploys a new Ethereum contract, binding an instance of {{.Type}} to it.
-		func Deploy{{.Type}}(auth *bind.TransactOpts, backend bind.ContractBackend { log.DebugLog(){range .Constructor.Inputs}}, {{.Name}} {{bindtype .Type}}{{end}}) (common.Address, *types.Transaction, *{{.Type}}, error) {
+		func Deploy{{.Type}}(auth *bind.TransactOpts, backend bind.ContractBackend {{range .Constructor.Inputs}}, {{.Name}} {{bindtype .Type}}{{end}}) (common.Address, *types.Transaction, *{{.Type}}, error) {
 		  parsed, err := abi.JSON(strings.NewReader({{.Type}}ABI))
 		  if err != nil {
 		    return common.Address{}, nil, nil, err
@@ -146,7 +163,7 @@ package {{.Package}}
 	}
 
 	// New{{.Type}} creates a new instance of {{.Type}}, bound to a specific deployed contract.
-	func New{{.Type}}(address common.Address, backend bind.ContractBackend) (*{{.Type}}, error) { log.DebugLog()
+	func New{{.Type}}(address common.Address, backend bind.ContractBackend) (*{{.Type}}, error) {
 	  contract, err := bind{{.Type}}(address, backend, backend, backend)
 	  if err != nil {
 	    return nil, err
@@ -155,7 +172,7 @@ package {{.Package}}
 	}
 
 	// New{{.Type}}Caller creates a new read-only instance of {{.Type}}, bound to a specific deployed contract.
-	func New{{.Type}}Caller(address common.Address, caller bind.ContractCaller) (*{{.Type}}Caller, error) { log.DebugLog()
+	func New{{.Type}}Caller(address common.Address, caller bind.ContractCaller) (*{{.Type}}Caller, error) {
 	  contract, err := bind{{.Type}}(address, caller, nil, nil)
 	  if err != nil {
 	    return nil, err
@@ -164,7 +181,7 @@ package {{.Package}}
 	}
 
 	// New{{.Type}}Transactor creates a new write-only instance of {{.Type}}, bound to a specific deployed contract.
-	func New{{.Type}}Transactor(address common.Address, transactor bind.ContractTransactor) (*{{.Type}}Transactor, error) { log.DebugLog()
+	func New{{.Type}}Transactor(address common.Address, transactor bind.ContractTransactor) (*{{.Type}}Transactor, error) {
 	  contract, err := bind{{.Type}}(address, nil, transactor, nil)
 	  if err != nil {
 	    return nil, err
@@ -173,7 +190,7 @@ package {{.Package}}
 	}
 
 	// New{{.Type}}Filterer creates a new log filterer instance of {{.Type}}, bound to a specific deployed contract.
- 	func New{{.Type}}Filterer(address common.Address, filterer bind.ContractFilterer) (*{{.Type}}Filterer, error) { log.DebugLog()
+ 	func New{{.Type}}Filterer(address common.Address, filterer bind.ContractFilterer) (*{{.Type}}Filterer, error) {
  	  contract, err := bind{{.Type}}(address, nil, nil, filterer)
  	  if err != nil {
  	    return nil, err
@@ -182,7 +199,7 @@ package {{.Package}}
  	}
 
 	// bind{{.Type}} binds a generic wrapper to an already deployed contract.
-	func bind{{.Type}}(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) { log.DebugLog()
+	func bind{{.Type}}(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
 	  parsed, err := abi.JSON(strings.NewReader({{.Type}}ABI))
 	  if err != nil {
 	    return nil, err
@@ -194,18 +211,18 @@ package {{.Package}}
 	// sets the output to result. The result type might be a single field for simple
 	// returns, a slice of interfaces for anonymous returns and a struct for named
 	// returns.
-	func (_{{$contract.Type}} *{{$contract.Type}}Raw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error { log.DebugLog()
+	func (_{{$contract.Type}} *{{$contract.Type}}Raw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
 		return _{{$contract.Type}}.Contract.{{$contract.Type}}Caller.contract.Call(opts, result, method, params...)
 	}
 
 	// Transfer initiates a plain transaction to move funds to the contract, calling
 	// its default method if one is available.
-	func (_{{$contract.Type}} *{{$contract.Type}}Raw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) { log.DebugLog()
+	func (_{{$contract.Type}} *{{$contract.Type}}Raw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
 		return _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract.Transfer(opts)
 	}
 
 	// Transact invokes the (paid) contract method with params as input values.
-	func (_{{$contract.Type}} *{{$contract.Type}}Raw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) { log.DebugLog()
+	func (_{{$contract.Type}} *{{$contract.Type}}Raw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
 		return _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract.Transact(opts, method, params...)
 	}
 
@@ -213,18 +230,18 @@ package {{.Package}}
 	// sets the output to result. The result type might be a single field for simple
 	// returns, a slice of interfaces for anonymous returns and a struct for named
 	// returns.
-	func (_{{$contract.Type}} *{{$contract.Type}}CallerRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error { log.DebugLog()
+	func (_{{$contract.Type}} *{{$contract.Type}}CallerRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
 		return _{{$contract.Type}}.Contract.contract.Call(opts, result, method, params...)
 	}
 
 	// Transfer initiates a plain transaction to move funds to the contract, calling
 	// its default method if one is available.
-	func (_{{$contract.Type}} *{{$contract.Type}}TransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) { log.DebugLog()
+	func (_{{$contract.Type}} *{{$contract.Type}}TransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
 		return _{{$contract.Type}}.Contract.contract.Transfer(opts)
 	}
 
 	// Transact invokes the (paid) contract method with params as input values.
-	func (_{{$contract.Type}} *{{$contract.Type}}TransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) { log.DebugLog()
+	func (_{{$contract.Type}} *{{$contract.Type}}TransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
 		return _{{$contract.Type}}.Contract.contract.Transact(opts, method, params...)
 	}
 
@@ -232,7 +249,7 @@ package {{.Package}}
 		// {{.Normalized.Name}} is a free data retrieval call binding the contract method 0x{{printf "%x" .Original.Id}}.
 		//
 		// Solidity: {{.Original.String}}
-		func (_{{$contract.Type}} *{{$contract.Type}}Caller) { log.DebugLog(){.Normalized.Name}}(opts *bind.CallOpts {{range .Normalized.Inputs}}, {{.Name}} {{bindtype .Type}} {{end}}) ({{if .Structured}}struct{ {{range .Normalized.Outputs}}{{.Name}} {{bindtype .Type}};{{end}} },{{else}}{{range .Normalized.Outputs}}{{bindtype .Type}},{{end}}{{end}} error) {
+		func (_{{$contract.Type}} *{{$contract.Type}}Caller) {{.Normalized.Name}}(opts *bind.CallOpts {{range .Normalized.Inputs}}, {{.Name}} {{bindtype .Type}} {{end}}) ({{if .Structured}}struct{ {{range .Normalized.Outputs}}{{.Name}} {{bindtype .Type}};{{end}} },{{else}}{{range .Normalized.Outputs}}{{bindtype .Type}},{{end}}{{end}} error) {
 			{{if .Structured}}ret := new(struct{
 				{{range .Normalized.Outputs}}{{.Name}} {{bindtype .Type}}
 				{{end}}
@@ -251,14 +268,14 @@ package {{.Package}}
 		// {{.Normalized.Name}} is a free data retrieval call binding the contract method 0x{{printf "%x" .Original.Id}}.
 		//
 		// Solidity: {{.Original.String}}
-		func (_{{$contract.Type}} *{{$contract.Type}}Session) { log.DebugLog(){.Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}},{{end}} {{.Name}} {{bindtype .Type}} {{end}}) ({{if .Structured}}struct{ {{range .Normalized.Outputs}}{{.Name}} {{bindtype .Type}};{{end}} }, {{else}} {{range .Normalized.Outputs}}{{bindtype .Type}},{{end}} {{end}} error) {
+		func (_{{$contract.Type}} *{{$contract.Type}}Session) {{.Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}},{{end}} {{.Name}} {{bindtype .Type}} {{end}}) ({{if .Structured}}struct{ {{range .Normalized.Outputs}}{{.Name}} {{bindtype .Type}};{{end}} }, {{else}} {{range .Normalized.Outputs}}{{bindtype .Type}},{{end}} {{end}} error) {
 		  return _{{$contract.Type}}.Contract.{{.Normalized.Name}}(&_{{$contract.Type}}.CallOpts {{range .Normalized.Inputs}}, {{.Name}}{{end}})
 		}
 
 		// {{.Normalized.Name}} is a free data retrieval call binding the contract method 0x{{printf "%x" .Original.Id}}.
 		//
 		// Solidity: {{.Original.String}}
-		func (_{{$contract.Type}} *{{$contract.Type}}CallerSession) { log.DebugLog(){.Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}},{{end}} {{.Name}} {{bindtype .Type}} {{end}}) ({{if .Structured}}struct{ {{range .Normalized.Outputs}}{{.Name}} {{bindtype .Type}};{{end}} }, {{else}} {{range .Normalized.Outputs}}{{bindtype .Type}},{{end}} {{end}} error) {
+		func (_{{$contract.Type}} *{{$contract.Type}}CallerSession) {{.Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}},{{end}} {{.Name}} {{bindtype .Type}} {{end}}) ({{if .Structured}}struct{ {{range .Normalized.Outputs}}{{.Name}} {{bindtype .Type}};{{end}} }, {{else}} {{range .Normalized.Outputs}}{{bindtype .Type}},{{end}} {{end}} error) {
 		  return _{{$contract.Type}}.Contract.{{.Normalized.Name}}(&_{{$contract.Type}}.CallOpts {{range .Normalized.Inputs}}, {{.Name}}{{end}})
 		}
 	{{end}}
@@ -267,21 +284,21 @@ package {{.Package}}
 		// {{.Normalized.Name}} is a paid mutator transaction binding the contract method 0x{{printf "%x" .Original.Id}}.
 		//
 		// Solidity: {{.Original.String}}
-		func (_{{$contract.Type}} *{{$contract.Type}}Transactor) { log.DebugLog(){.Normalized.Name}}(opts *bind.TransactOpts {{range .Normalized.Inputs}}, {{.Name}} {{bindtype .Type}} {{end}}) (*types.Transaction, error) {
+		func (_{{$contract.Type}} *{{$contract.Type}}Transactor) {{.Normalized.Name}}(opts *bind.TransactOpts {{range .Normalized.Inputs}}, {{.Name}} {{bindtype .Type}} {{end}}) (*types.Transaction, error) {
 			return _{{$contract.Type}}.contract.Transact(opts, "{{.Original.Name}}" {{range .Normalized.Inputs}}, {{.Name}}{{end}})
 		}
 
 		// {{.Normalized.Name}} is a paid mutator transaction binding the contract method 0x{{printf "%x" .Original.Id}}.
 		//
 		// Solidity: {{.Original.String}}
-		func (_{{$contract.Type}} *{{$contract.Type}}Session) { log.DebugLog(){.Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}},{{end}} {{.Name}} {{bindtype .Type}} {{end}}) (*types.Transaction, error) {
+		func (_{{$contract.Type}} *{{$contract.Type}}Session) {{.Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}},{{end}} {{.Name}} {{bindtype .Type}} {{end}}) (*types.Transaction, error) {
 		  return _{{$contract.Type}}.Contract.{{.Normalized.Name}}(&_{{$contract.Type}}.TransactOpts {{range $i, $_ := .Normalized.Inputs}}, {{.Name}}{{end}})
 		}
 
 		// {{.Normalized.Name}} is a paid mutator transaction binding the contract method 0x{{printf "%x" .Original.Id}}.
 		//
 		// Solidity: {{.Original.String}}
-		func (_{{$contract.Type}} *{{$contract.Type}}TransactorSession) { log.DebugLog(){.Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}},{{end}} {{.Name}} {{bindtype .Type}} {{end}}) (*types.Transaction, error) {
+		func (_{{$contract.Type}} *{{$contract.Type}}TransactorSession) {{.Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}},{{end}} {{.Name}} {{bindtype .Type}} {{end}}) (*types.Transaction, error) {
 		  return _{{$contract.Type}}.Contract.{{.Normalized.Name}}(&_{{$contract.Type}}.TransactOpts {{range $i, $_ := .Normalized.Inputs}}, {{.Name}}{{end}})
 		}
 	{{end}}
@@ -302,7 +319,7 @@ package {{.Package}}
 		// Next advances the iterator to the subsequent event, returning whether there
 		// are any more events found. In case of a retrieval or parsing error, false is
 		// returned and Error() can be queried for the exact failure.
-		func (it *{{$contract.Type}}{{.Normalized.Name}}Iterator) Next() bool { log.DebugLog()
+		func (it *{{$contract.Type}}{{.Normalized.Name}}Iterator) Next() bool {
 			// If the iterator failed, stop iterating
 			if (it.fail != nil) {
 				return false
@@ -341,12 +358,12 @@ package {{.Package}}
 			}
 		}
 		// Error returns any retrieval or parsing error occurred during filtering.
-		func (it *{{$contract.Type}}{{.Normalized.Name}}Iterator) Error() error { log.DebugLog()
+		func (it *{{$contract.Type}}{{.Normalized.Name}}Iterator) Error() error {
 			return it.fail
 		}
 		// Close terminates the iteration process, releasing any pending underlying
 		// resources.
-		func (it *{{$contract.Type}}{{.Normalized.Name}}Iterator) Close() error { log.DebugLog()
+		func (it *{{$contract.Type}}{{.Normalized.Name}}Iterator) Close() error {
 			it.sub.Unsubscribe()
 			return nil
 		}
@@ -360,7 +377,7 @@ package {{.Package}}
 		// Filter{{.Normalized.Name}} is a free log retrieval operation binding the contract event 0x{{printf "%x" .Original.Id}}.
 		//
 		// Solidity: {{.Original.String}}
- 		func (_{{$contract.Type}} *{{$contract.Type}}Filterer) Filter{{.Normalized.Name}}(opts *bind.FilterOpts{{range .Normalized.Inputs}}{{if .Indexed}}, { log.DebugLog(){.Name}} []{{bindtype .Type}}{{end}}{{end}}) (*{{$contract.Type}}{{.Normalized.Name}}Iterator, error) {
+ 		func (_{{$contract.Type}} *{{$contract.Type}}Filterer) Filter{{.Normalized.Name}}(opts *bind.FilterOpts{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}} []{{bindtype .Type}}{{end}}{{end}}) (*{{$contract.Type}}{{.Normalized.Name}}Iterator, error) {
 			{{range .Normalized.Inputs}}
 			{{if .Indexed}}var {{.Name}}Rule []interface{}
 			for _, {{.Name}}Item := range {{.Name}} {
@@ -377,7 +394,7 @@ package {{.Package}}
 		// Watch{{.Normalized.Name}} is a free log subscription operation binding the contract event 0x{{printf "%x" .Original.Id}}.
 		//
 		// Solidity: {{.Original.String}}
-		func (_{{$contract.Type}} *{{$contract.Type}}Filterer) Watch{{.Normalized.Name}}(opts *bind.WatchOpts, sink chan<- *{{$contract.Type}}{{.Normalized.Name}}{{range .Normalized.Inputs}}{{if .Indexed}}, { log.DebugLog(){.Name}} []{{bindtype .Type}}{{end}}{{end}}) (event.Subscription, error) {
+		func (_{{$contract.Type}} *{{$contract.Type}}Filterer) Watch{{.Normalized.Name}}(opts *bind.WatchOpts, sink chan<- *{{$contract.Type}}{{.Normalized.Name}}{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}} []{{bindtype .Type}}{{end}}{{end}}) (event.Subscription, error) {
 			{{range .Normalized.Inputs}}
 			{{if .Indexed}}var {{.Name}}Rule []interface{}
 			for _, {{.Name}}Item := range {{.Name}} {
@@ -415,6 +432,57 @@ package {{.Package}}
 				}
 			}), nil
 		}
+
+		// Stream{{.Normalized.Name}} is a durable log subscription operation binding the contract event 0x{{printf "%x" .Original.Id}}.
+		// Past events are backfilled from opts.Start before switching over to new ones, and
+		// events dropped by chain reorganisations are delivered to reverts (if non-nil).
+		//
+		// Solidity: {{.Original.String}}
+		func (_{{$contract.Type}} *{{$contract.Type}}Filterer) Stream{{.Normalized.Name}}(opts *bind.StreamOpts, sink chan<- *{{$contract.Type}}{{.Normalized.Name}}, reverts chan<- *{{$contract.Type}}{{.Normalized.Name}}{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}} []{{bindtype .Type}}{{end}}{{end}}) (event.Subscription, error) {
+			{{range .Normalized.Inputs}}
+			{{if .Indexed}}var {{.Name}}Rule []interface{}
+			for _, {{.Name}}Item := range {{.Name}} {
+				{{.Name}}Rule = append({{.Name}}Rule, {{.Name}}Item)
+			}{{end}}{{end}}
+
+			logs, sub, err := _{{$contract.Type}}.contract.StreamLogs(opts, "{{.Original.Name}}"{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}}Rule{{end}}{{end}})
+			if err != nil {
+				return nil, err
+			}
+			return event.NewSubscription(func(quit <-chan struct{}) error {
+				defer sub.Unsubscribe()
+				for {
+					select {
+					case log := <-logs:
+						// New log arrived or an old one was reverted, parse the event and forward to the user
+						out := sink
+						if log.Removed {
+							if reverts == nil {
+								continue
+							}
+							out = reverts
+						}
+						event := new({{$contract.Type}}{{.Normalized.Name}})
+						if err := _{{$contract.Type}}.contract.UnpackLog(event, "{{.Original.Name}}", log); err != nil {
+							return err
+						}
+						event.Raw = log
+
+						select {
+						case out <- event:
+						case err := <-sub.Err():
+							return err
+						case <-quit:
+							return nil
+						}
+					case err := <-sub.Err():
+						return err
+					case <-quit:
+						return nil
+					}
+				}
+			}), nil
+		}
  	{{end}}
 {{end}}
 `
@@ -520,3 +588,420 @@ import org.ethereum.geth.internal.*;
 	}
 {{end}}
 `
+
+// tmplSourceObjC is the Objective-C source template use to generate the contract
+// binding based on. The binding is a single source file carrying both the class
+// interfaces and implementations, built on top of the mobile Geth framework.
+const tmplSourceObjC = `
+// This file is an automatically generated Objective-C binding. Do not modify as
+// any change will likely be lost upon the next re-generation!
+
+#import <Foundation/Foundation.h>
+#import <Geth/Geth.h>
+
+{{range $contract := .Contracts}}
+	{{range .Calls}}
+		{{if gt (len .Normalized.Outputs) 1}}
+			// {{$contract.Type}}{{capitalise .Normalized.Name}}Results is the output of a call to {{.Normalized.Name}}.
+			@interface {{$contract.Type}}{{capitalise .Normalized.Name}}Results : NSObject
+			{{range $index, $item := .Normalized.Outputs}}@property (nonatomic) {{bindtype .Type}} {{if ne .Name ""}}{{.Name}}{{else}}Return{{$index}}{{end}};
+			{{end}}
+			@end
+
+			@implementation {{$contract.Type}}{{capitalise .Normalized.Name}}Results
+			@end
+		{{end}}
+	{{end}}
+
+	// {{.Type}} is an auto generated Objective-C binding around an Ethereum contract.
+	@interface {{.Type}} : NSObject
+
+	// ABI is the input ABI used to generate the binding from.
+	+ (NSString*)ABI;
+
+	{{if .InputBin}}
+		// bytecode is the compiled bytecode used for deploying new contracts.
+		+ (NSString*)bytecode;
+
+		// deploy deploys a new Ethereum contract, binding an instance of {{.Type}} to it.
+		+ ({{.Type}}*)deploy:(GethTransactOpts*)auth client:(GethEthereumClient*)client{{range .Constructor.Inputs}} {{.Name}}:({{bindtype .Type}}){{ident .Name}}{{end}} error:(NSError**)error;
+	{{end}}
+
+	// Ethereum address where this contract is located at.
+	@property (nonatomic, readonly) GethAddress* address;
+
+	// Ethereum transaction in which this contract was deployed (if known!).
+	@property (nonatomic, readonly) GethTransaction* deployer;
+
+	// Creates a new instance of {{.Type}}, bound to a specific deployed contract.
+	- (instancetype)initWithAddress:(GethAddress*)address client:(GethEthereumClient*)client error:(NSError**)error;
+
+	{{range .Calls}}
+		// {{.Normalized.Name}} is a free data retrieval call binding the contract method 0x{{printf "%x" .Original.Id}}.
+		//
+		// Solidity: {{.Original.String}}
+		- ({{if gt (len .Normalized.Outputs) 1}}{{$contract.Type}}{{capitalise .Normalized.Name}}Results*{{else if eq (len .Normalized.Outputs) 1}}{{bindtype (index .Normalized.Outputs 0).Type}}{{else}}BOOL{{end}}){{.Normalized.Name}}:(GethCallOpts*)opts{{range .Normalized.Inputs}} {{.Name}}:({{bindtype .Type}}){{ident .Name}}{{end}} error:(NSError**)error;
+	{{end}}
+
+	{{range .Transacts}}
+		// {{.Normalized.Name}} is a paid mutator transaction binding the contract method 0x{{printf "%x" .Original.Id}}.
+		//
+		// Solidity: {{.Original.String}}
+		- (GethTransaction*){{.Normalized.Name}}:(GethTransactOpts*)opts{{range .Normalized.Inputs}} {{.Name}}:({{bindtype .Type}}){{ident .Name}}{{end}} error:(NSError**)error;
+	{{end}}
+	@end
+
+	@implementation {{.Type}} {
+		// Contract instance bound to a blockchain address.
+		GethBoundContract* _contract;
+	}
+
+	+ (NSString*)ABI {
+		return @"{{.InputABI}}";
+	}
+
+	{{if .InputBin}}
+		+ (NSString*)bytecode {
+			return @"{{.InputBin}}";
+		}
+
+		+ ({{.Type}}*)deploy:(GethTransactOpts*)auth client:(GethEthereumClient*)client{{range .Constructor.Inputs}} {{.Name}}:({{bindtype .Type}}){{ident .Name}}{{end}} error:(NSError**)error {
+			GethInterfaces* args = GethNewInterfaces({{(len .Constructor.Inputs)}});
+			{{range $index, $element := .Constructor.Inputs}}
+				GethInterface* input{{$index}} = GethNewInterface(); [input{{$index}} set{{namedtype (bindtype .Type) .Type}}:{{ident .Name}}];
+				if (![args set:{{$index}} object:input{{$index}} error:error]) {
+					return nil;
+				}
+			{{end}}
+			NSData* bytecode = GethDecodeFromHex([self bytecode], error);
+			if (bytecode == nil) {
+				return nil;
+			}
+			GethBoundContract* deployment = GethDeployContract(auth, [self ABI], bytecode, client, args, error);
+			if (deployment == nil) {
+				return nil;
+			}
+			return [[{{.Type}} alloc] initWithContract:deployment];
+		}
+	{{end}}
+
+	// Internal constructor used by contract deployment and binding.
+	- (instancetype)initWithContract:(GethBoundContract*)contract {
+		if (self = [super init]) {
+			_address  = [contract getAddress];
+			_deployer = [contract getDeployer];
+			_contract = contract;
+		}
+		return self;
+	}
+
+	- (instancetype)initWithAddress:(GethAddress*)address client:(GethEthereumClient*)client error:(NSError**)error {
+		GethBoundContract* contract = GethBindContract(address, [{{.Type}} ABI], client, error);
+		if (contract == nil) {
+			return nil;
+		}
+		return [self initWithContract:contract];
+	}
+
+	{{range .Calls}}
+		- ({{if gt (len .Normalized.Outputs) 1}}{{$contract.Type}}{{capitalise .Normalized.Name}}Results*{{else if eq (len .Normalized.Outputs) 1}}{{bindtype (index .Normalized.Outputs 0).Type}}{{else}}BOOL{{end}}){{.Normalized.Name}}:(GethCallOpts*)opts{{range .Normalized.Inputs}} {{.Name}}:({{bindtype .Type}}){{ident .Name}}{{end}} error:(NSError**)error {
+			GethInterfaces* args = GethNewInterfaces({{(len .Normalized.Inputs)}});
+			{{range $index, $item := .Normalized.Inputs}}
+				GethInterface* input{{$index}} = GethNewInterface(); [input{{$index}} set{{namedtype (bindtype .Type) .Type}}:{{ident .Name}}];
+				if (![args set:{{$index}} object:input{{$index}} error:error]) {
+					return 0;
+				}
+			{{end}}
+
+			GethInterfaces* results = GethNewInterfaces({{(len .Normalized.Outputs)}});
+			{{range $index, $item := .Normalized.Outputs}}
+				GethInterface* output{{$index}} = GethNewInterface(); [output{{$index}} setDefault{{namedtype (bindtype .Type) .Type}}];
+				if (![results set:{{$index}} object:output{{$index}} error:error]) {
+					return 0;
+				}
+			{{end}}
+
+			if (opts == nil) {
+				opts = GethNewCallOpts();
+			}
+			if (![_contract call:opts out_:results method:@"{{.Original.Name}}" args:args error:error]) {
+				return 0;
+			}
+			{{if gt (len .Normalized.Outputs) 1}}
+				{{$contract.Type}}{{capitalise .Normalized.Name}}Results* result = [[{{$contract.Type}}{{capitalise .Normalized.Name}}Results alloc] init];
+				{{range $index, $item := .Normalized.Outputs}}result.{{if ne .Name ""}}{{.Name}}{{else}}Return{{$index}}{{end}} = [[results get:{{$index}} error:error] get{{namedtype (bindtype .Type) .Type}}];
+				{{end}}
+				return result;
+			{{else if eq (len .Normalized.Outputs) 1}}
+				return [[results get:0 error:error] get{{namedtype (bindtype (index .Normalized.Outputs 0).Type) (index .Normalized.Outputs 0).Type}}];
+			{{else}}
+				return YES;
+			{{end}}
+		}
+	{{end}}
+
+	{{range .Transacts}}
+		- (GethTransaction*){{.Normalized.Name}}:(GethTransactOpts*)opts{{range .Normalized.Inputs}} {{.Name}}:({{bindtype .Type}}){{ident .Name}}{{end}} error:(NSError**)error {
+			GethInterfaces* args = GethNewInterfaces({{(len .Normalized.Inputs)}});
+			{{range $index, $item := .Normalized.Inputs}}
+				GethInterface* input{{$index}} = GethNewInterface(); [input{{$index}} set{{namedtype (bindtype .Type) .Type}}:{{ident .Name}}];
+				if (![args set:{{$index}} object:input{{$index}} error:error]) {
+					return nil;
+				}
+			{{end}}
+			return [_contract transact:opts method:@"{{.Original.Name}}" args:args error:error];
+		}
+	{{end}}
+	@end
+{{end}}
+`
+
+// tmplSourceSwift is the Swift source template use to generate the contract
+// binding based on, built on top of the mobile Geth framework.
+const tmplSourceSwift = `
+// This file is an automatically generated Swift binding. Do not modify as any
+// change will likely be lost upon the next re-generation!
+
+import Foundation
+import Geth
+
+{{range $contract := .Contracts}}
+	public class {{.Type}} {
+		// ABI is the input ABI used to generate the binding from.
+		public static let ABI = "{{.InputABI}}"
+
+		{{if .InputBin}}
+			// BYTECODE is the compiled bytecode used for deploying new contracts.
+			public static let BYTECODE = "{{.InputBin}}"
+
+			// deploy deploys a new Ethereum contract, binding an instance of {{.Type}} to it.
+			public static func deploy(_ auth: GethTransactOpts, client: GethEthereumClient{{range .Constructor.Inputs}}, {{.Name}}: {{bindtype .Type}}{{end}}) throws -> {{.Type}} {
+				let args = GethNewInterfaces({{(len .Constructor.Inputs)}})!
+				{{range $index, $element := .Constructor.Inputs}}
+					let input{{$index}} = GethNewInterface()!; input{{$index}}.set{{namedtype (bindtype .Type) .Type}}({{.Name}}); try args.set({{$index}}, object: input{{$index}})
+				{{end}}
+				var error: NSError?
+				let bytecode = GethDecodeFromHex(BYTECODE, &error)
+				if let error = error {
+					throw error
+				}
+				let deployment = GethDeployContract(auth, ABI, bytecode, client, args, &error)
+				if let error = error {
+					throw error
+				}
+				return {{.Type}}(deployment!)
+			}
+		{{end}}
+
+		// Ethereum address where this contract is located at.
+		public let address: GethAddress
+
+		// Ethereum transaction in which this contract was deployed (if known!).
+		public let deployer: GethTransaction?
+
+		// Contract instance bound to a blockchain address.
+		private let contract: GethBoundContract
+
+		// Internal constructor used by contract deployment and binding.
+		private init(_ contract: GethBoundContract) {
+			self.address = contract.getAddress()!
+			self.deployer = contract.getDeployer()
+			self.contract = contract
+		}
+
+		// Creates a new instance of {{.Type}}, bound to a specific deployed contract.
+		public convenience init(address: GethAddress, client: GethEthereumClient) throws {
+			var error: NSError?
+			let contract = GethBindContract(address, {{.Type}}.ABI, client, &error)
+			if let error = error {
+				throw error
+			}
+			self.init(contract!)
+		}
+
+		{{range .Calls}}
+			{{if gt (len .Normalized.Outputs) 1}}
+			// {{capitalise .Normalized.Name}}Results is the output of a call to {{.Normalized.Name}}.
+			public struct {{capitalise .Normalized.Name}}Results {
+				{{range $index, $item := .Normalized.Outputs}}public let {{if ne .Name ""}}{{.Name}}{{else}}Return{{$index}}{{end}}: {{bindtype .Type}}
+				{{end}}
+			}
+			{{end}}
+
+			// {{.Normalized.Name}} is a free data retrieval call binding the contract method 0x{{printf "%x" .Original.Id}}.
+			//
+			// Solidity: {{.Original.String}}
+			public func {{.Normalized.Name}}(_ opts: GethCallOpts?{{range .Normalized.Inputs}}, {{.Name}}: {{bindtype .Type}}{{end}}) throws -> {{if gt (len .Normalized.Outputs) 1}}{{capitalise .Normalized.Name}}Results{{else if eq (len .Normalized.Outputs) 1}}{{bindtype (index .Normalized.Outputs 0).Type}}{{else}}Void{{end}} {
+				let args = GethNewInterfaces({{(len .Normalized.Inputs)}})!
+				{{range $index, $item := .Normalized.Inputs}}let input{{$index}} = GethNewInterface()!; input{{$index}}.set{{namedtype (bindtype .Type) .Type}}({{.Name}}); try args.set({{$index}}, object: input{{$index}})
+				{{end}}
+
+				let results = GethNewInterfaces({{(len .Normalized.Outputs)}})!
+				{{range $index, $item := .Normalized.Outputs}}let output{{$index}} = GethNewInterface()!; output{{$index}}.setDefault{{namedtype (bindtype .Type) .Type}}(); try results.set({{$index}}, object: output{{$index}})
+				{{end}}
+
+				try contract.call(opts ?? GethNewCallOpts()!, out_: results, method: "{{.Original.Name}}", args: args)
+				{{if gt (len .Normalized.Outputs) 1}}
+					return {{capitalise .Normalized.Name}}Results({{range $index, $item := .Normalized.Outputs}}{{if ne $index 0}}, {{end}}{{if ne .Name ""}}{{.Name}}{{else}}Return{{$index}}{{end}}: try results.get({{$index}}).get{{namedtype (bindtype .Type) .Type}}(){{unwrap (bindtype .Type)}}{{end}})
+				{{else}}{{range .Normalized.Outputs}}return try results.get(0).get{{namedtype (bindtype .Type) .Type}}(){{unwrap (bindtype .Type)}}{{end}}
+				{{end}}
+			}
+		{{end}}
+
+		{{range .Transacts}}
+			// {{.Normalized.Name}} is a paid mutator transaction binding the contract method 0x{{printf "%x" .Original.Id}}.
+			//
+			// Solidity: {{.Original.String}}
+			public func {{.Normalized.Name}}(_ opts: GethTransactOpts{{range .Normalized.Inputs}}, {{.Name}}: {{bindtype .Type}}{{end}}) throws -> GethTransaction {
+				let args = GethNewInterfaces({{(len .Normalized.Inputs)}})!
+				{{range $index, $item := .Normalized.Inputs}}let input{{$index}} = GethNewInterface()!; input{{$index}}.set{{namedtype (bindtype .Type) .Type}}({{.Name}}); try args.set({{$index}}, object: input{{$index}})
+				{{end}}
+
+				return try contract.transact(opts, method: "{{.Original.Name}}", args: args)
+			}
+		{{end}}
+	}
+{{end}}
+`
+
+// tmplSourceTypeScript is the TypeScript declaration template use to generate the
+// contract binding based on. The declarations describe the typed surface of the
+// contracts, leaving the actual JSON-RPC plumbing to the runtime implementing it.
+const tmplSourceTypeScript = `
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+declare module "{{.Package}}" {
+	// ContractBackend is the connection to the Ethereum node the contracts are
+	// reached through, as understood by the runtime implementing the bindings.
+	export type ContractBackend = any;
+
+	// CallOpts is the collection of options to fine tune a contract call request.
+	export interface CallOpts {
+		pending?: boolean; // Whether to operate on the pending state or the last known one
+		from?: string;     // Optional the sender address, otherwise the first account is used
+	}
+
+	// TransactOpts is the collection of authorization data required to create a
+	// valid Ethereum transaction.
+	export interface TransactOpts {
+		from: string;       // Ethereum account to send the transaction from
+		nonce?: number;     // Nonce to use for the transaction execution (undefined = use pending state)
+		value?: bigint;     // Funds to transfer along along the transaction (undefined = 0 = no funds)
+		gasPrice?: bigint;  // Gas price to use for the transaction execution (undefined = gas price oracle)
+		gasLimit?: number;  // Gas limit to set for the transaction execution (undefined = estimate)
+	}
+
+	// FilterOpts is the collection of options to fine tune filtering for events
+	// within a bound contract.
+	export interface FilterOpts {
+		start: number; // Start of the queried range
+		end?: number;  // End of the range (undefined = latest)
+	}
+
+	// WatchOpts is the collection of options to fine tune subscribing for events
+	// within a bound contract.
+	export interface WatchOpts {
+		start?: number; // Start of the queried range (undefined = latest)
+	}
+
+	// Log represents a contract log event as returned by the node.
+	export interface Log {
+		address: string;
+		topics: string[];
+		data: string;
+		blockNumber: number;
+		transactionHash: string;
+		transactionIndex: number;
+		blockHash: string;
+		logIndex: number;
+		removed: boolean;
+	}
+
+	// Transaction is a submitted Ethereum transaction.
+	export interface Transaction {
+		hash: string;
+	}
+
+	// Subscription represents a stream of events, delivered until unsubscribed.
+	export interface Subscription {
+		unsubscribe(): void;
+	}
+
+	{{range .Structs}}
+		// {{.Name}} is an auto generated low-level TypeScript binding around an user-defined struct.
+		export interface {{.Name}} {
+			{{range .Fields}}{{lowercamel .Name}}: {{.Type}};
+			{{end}}
+		}
+	{{end}}
+
+	{{range $contract := .Contracts}}
+		// {{.Type}}ABI is the input ABI used to generate the binding from.
+		export const {{.Type}}ABI = "{{.InputABI}}";
+
+		{{if .InputBin}}
+			// {{.Type}}Bin is the compiled bytecode used for deploying new contracts.
+			export const {{.Type}}Bin = "{{.InputBin}}";
+		{{end}}
+
+		{{range .Calls}}
+			{{if .Structured}}
+				// {{$contract.Type}}{{capitalise .Normalized.Name}}Results is the output of a call to {{.Normalized.Name}}.
+				export interface {{$contract.Type}}{{capitalise .Normalized.Name}}Results {
+					{{range .Normalized.Outputs}}{{lowercamel .Name}}: {{bindtype .Type}};
+					{{end}}
+				}
+			{{end}}
+		{{end}}
+
+		{{range .Events}}
+			// {{$contract.Type}}{{capitalise .Normalized.Name}} represents a {{.Normalized.Name}} event raised by the {{$contract.Type}} contract.
+			export interface {{$contract.Type}}{{capitalise .Normalized.Name}} {
+				{{range $index, $item := .Normalized.Inputs}}{{if ne .Name ""}}{{lowercamel .Name}}{{else}}arg{{$index}}{{end}}: {{if .Indexed}}{{bindtopictype .Type}}{{else}}{{bindtype .Type}}{{end}};
+				{{end}}raw: Log; // Blockchain specific contextual infos
+			}
+		{{end}}
+
+		// {{.Type}} is an auto generated TypeScript binding around an Ethereum contract.
+		export class {{.Type}} {
+			{{if .InputBin}}
+				// deploy deploys a new Ethereum contract, binding an instance of {{.Type}} to it.
+				static deploy(opts: TransactOpts, backend: ContractBackend{{range .Constructor.Inputs}}, {{.Name}}: {{bindtype .Type}}{{end}}): Promise<{{.Type}}>;
+			{{end}}
+
+			// Creates a new instance of {{.Type}}, bound to a specific deployed contract.
+			constructor(address: string, backend: ContractBackend);
+
+			// Ethereum address where this contract is located at.
+			readonly address: string;
+
+			{{range .Calls}}
+				// {{.Normalized.Name}} is a free data retrieval call binding the contract method 0x{{printf "%x" .Original.Id}}.
+				//
+				// Solidity: {{.Original.String}}
+				{{.Normalized.Name}}({{range .Normalized.Inputs}}{{.Name}}: {{bindtype .Type}}, {{end}}opts?: CallOpts): Promise<{{if .Structured}}{{$contract.Type}}{{capitalise .Normalized.Name}}Results{{else if eq (len .Normalized.Outputs) 0}}void{{else if eq (len .Normalized.Outputs) 1}}{{bindtype (index .Normalized.Outputs 0).Type}}{{else}}[{{range $index, $item := .Normalized.Outputs}}{{if ne $index 0}}, {{end}}{{bindtype .Type}}{{end}}]{{end}}>;
+			{{end}}
+
+			{{range .Transacts}}
+				// {{.Normalized.Name}} is a paid mutator transaction binding the contract method 0x{{printf "%x" .Original.Id}}.
+				//
+				// Solidity: {{.Original.String}}
+				{{.Normalized.Name}}(opts: TransactOpts{{range .Normalized.Inputs}}, {{.Name}}: {{bindtype .Type}}{{end}}): Promise<Transaction>;
+			{{end}}
+
+			{{range .Events}}
+				// filter{{capitalise .Normalized.Name}} is a free log retrieval operation binding the contract event 0x{{printf "%x" .Original.Id}}.
+				//
+				// Solidity: {{.Original.String}}
+				filter{{capitalise .Normalized.Name}}(opts: FilterOpts{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}}?: {{bindtype .Type}}[]{{end}}{{end}}): Promise<{{$contract.Type}}{{capitalise .Normalized.Name}}[]>;
+
+				// watch{{capitalise .Normalized.Name}} is a free log subscription operation binding the contract event 0x{{printf "%x" .Original.Id}}.
+				//
+				// Solidity: {{.Original.String}}
+				watch{{capitalise .Normalized.Name}}(opts: WatchOpts, sink: (event: {{$contract.Type}}{{capitalise .Normalized.Name}}) => void{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}}?: {{bindtype .Type}}[]{{end}}{{end}}): Subscription;
+			{{end}}
+		}
+	{{end}}
+}
+`
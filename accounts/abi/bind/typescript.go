@@ -0,0 +1,55 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// bindTypeTypeScript converts a Solidity type to a TypeScript one, following the
+// JSON-RPC conventions: addresses and binary blobs are hex strings, integers that
+// fit into the safe range of a JavaScript number are numbers, others are bigints.
+func bindTypeTypeScript(kind abi.Type, structs map[string]*tmplStruct) string {
+	log.DebugLog()
+	switch kind.T {
+	case abi.TupleTy:
+		return structs[structKey(kind)].Name
+	case abi.ArrayTy, abi.SliceTy:
+		return bindTypeTypeScript(*kind.Elem, structs) + "[]"
+	case abi.IntTy, abi.UintTy:
+		if kind.Size <= 48 {
+			return "number"
+		}
+		return "bigint"
+	case abi.BoolTy:
+		return "boolean"
+	}
+	// Addresses, hashes, strings and all binary blobs
+	return "string"
+}
+
+// bindTopicTypeTypeScript converts a Solidity topic type to a TypeScript one. It
+// is almost the same functionality as for simple types, but dynamic types get
+// converted to hashes.
+func bindTopicTypeTypeScript(kind abi.Type, structs map[string]*tmplStruct) string {
+	log.DebugLog()
+	if isDynamicTopic(kind) {
+		return "string"
+	}
+	return bindTypeTypeScript(kind, structs)
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"reflect"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -28,6 +29,42 @@ var (
 	errBadBool = errors.New("abi: improperly encoded boolean value")
 )
 
+// RevertErrorCode is the JSON-RPC error code of executions aborted by REVERT.
+const RevertErrorCode = 3
+
+// RevertError is an error returned when a contract call or transaction was
+// aborted by the REVERT opcode. It carries the raw data returned along with
+// the revert and, if the data could be decoded as an Error(string), the reason.
+type RevertError struct {
+	Data   []byte // Raw data returned with the revert
+	Reason string // Decoded revert reason, empty if none could be decoded
+}
+
+// NewRevertError creates a revert error from the data returned by a REVERT,
+// decoding the revert reason if one was supplied.
+func NewRevertError(data []byte) *RevertError { log.DebugLog()
+	reason, _ := UnpackRevert(data)
+	return &RevertError{Data: data, Reason: reason}
+}
+
+// Error implements error, returning the decoded revert reason if available.
+func (e *RevertError) Error() string { log.DebugLog()
+	if e.Reason == "" {
+		return "execution reverted"
+	}
+	return "execution reverted: " + e.Reason
+}
+
+// ErrorCode returns the JSON-RPC error code used for reverted executions.
+func (e *RevertError) ErrorCode() int { log.DebugLog()
+	return RevertErrorCode
+}
+
+// ErrorData returns the hex encoded revert data, as sent over JSON-RPC.
+func (e *RevertError) ErrorData() interface{} { log.DebugLog()
+	return hexutil.Encode(e.Data)
+}
+
 // formatSliceString formats the reflection kind with the given slice size
 // and returns a formatted string representation.
 func formatSliceString(kind reflect.Kind, sliceSize int) string { log.DebugLog()
@@ -27,6 +27,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -314,3 +315,28 @@ func TestEventIndexedWithArrayUnpack(t *testing.T) { log.DebugLog()
 	require.Equal(t, [2]uint8{0, 0}, rst.Value1)
 	require.Equal(t, stringOut, rst.Value2)
 }
+
+// TestEventTupleArgUnpack verifies that tuple arguments of events are decoded.
+func TestEventTupleArgUnpack(t *testing.T) { log.DebugLog()
+	definition := `[{"name": "test", "type": "event", "inputs": [{"indexed": true, "name":"value1", "type":"uint8"},{"indexed": false, "name":"value2", "type":"tuple", "components": [{"name": "a", "type": "uint8"}, {"name": "s", "type": "string"}]}]}]`
+	type tuple struct {
+		A uint8
+		S string
+	}
+	type testStruct struct {
+		Value1 uint8
+		Value2 tuple
+	}
+	abi, err := JSON(strings.NewReader(definition))
+	require.NoError(t, err)
+	var b bytes.Buffer
+	b.Write(packNum(reflect.ValueOf(32)))
+	b.Write(packNum(reflect.ValueOf(uint8(8))))
+	b.Write(packNum(reflect.ValueOf(64)))
+	b.Write(packNum(reflect.ValueOf(3)))
+	b.Write(common.RightPadBytes([]byte("abc"), 32))
+
+	var rst testStruct
+	require.NoError(t, abi.Unpack(&rst, "test", b.Bytes()))
+	require.Equal(t, tuple{8, "abc"}, rst.Value2)
+}
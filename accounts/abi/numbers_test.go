@@ -21,6 +21,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func TestNumberTypes(t *testing.T) { log.DebugLog()
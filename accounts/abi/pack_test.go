@@ -25,6 +25,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func TestPack(t *testing.T) { log.DebugLog()
@@ -325,7 +326,7 @@ func TestPack(t *testing.T) { log.DebugLog()
 			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000006666f6f6261720000000000000000000000000000000000000000000000000000"),
 		},
 	} {
-		typ, err := NewType(test.typ)
+		typ, err := NewType(test.typ, "", nil)
 		if err != nil {
 			t.Fatalf("%v failed. Unexpected parse error: %v", i, err)
 		}
@@ -408,6 +409,88 @@ func TestMethodPack(t *testing.T) { log.DebugLog()
 	}
 }
 
+// Tests that tuples are packed according to the ABI spec, with dynamic ones being
+// referenced by offsets and static ones encoded inline, and that they round trip.
+func TestPackTuple(t *testing.T) { log.DebugLog()
+	definition := `[
+		{"name": "dynamic", "type": "function", "inputs": [
+			{"name": "x", "type": "tuple", "components": [{"name": "a", "type": "uint256"}, {"name": "s", "type": "string"}]},
+			{"name": "y", "type": "uint256[]"}
+		]},
+		{"name": "static", "type": "function", "inputs": [
+			{"name": "x", "type": "tuple", "components": [{"name": "a", "type": "uint256"}, {"name": "b", "type": "bool"}]},
+			{"name": "y", "type": "uint256"}
+		]}
+	]`
+	abi, err := JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type tuple struct {
+		A *big.Int
+		S string
+	}
+	word := func(n int64) []byte { return common.LeftPadBytes(big.NewInt(n).Bytes(), 32) }
+
+	// Dynamic tuples live in the tail, with their own offsets relative to their start
+	var want []byte
+	want = append(want, abi.Methods["dynamic"].Id()...)
+	want = append(want, word(0x40)...)
+	want = append(want, word(0xc0)...)
+	want = append(want, word(1)...)
+	want = append(want, word(0x40)...)
+	want = append(want, word(3)...)
+	want = append(want, common.RightPadBytes([]byte("abc"), 32)...)
+	want = append(want, word(2)...)
+	want = append(want, word(1)...)
+	want = append(want, word(2)...)
+
+	x, y := tuple{big.NewInt(1), "abc"}, []*big.Int{big.NewInt(1), big.NewInt(2)}
+	packed, err := abi.Pack("dynamic", x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Fatalf("dynamic tuple mismatch: have %x, want %x", packed, want)
+	}
+	values, err := abi.Methods["dynamic"].Inputs.UnpackValues(packed[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := reflect.ValueOf(values[0]).Convert(reflect.TypeOf(x)).Interface(); !reflect.DeepEqual(have, x) {
+		t.Errorf("dynamic tuple round trip mismatch: have %v, want %v", have, x)
+	}
+	if !reflect.DeepEqual(values[1], y) {
+		t.Errorf("trailing slice round trip mismatch: have %v, want %v", values[1], y)
+	}
+	// Static tuples are encoded inline, same as static arrays
+	want = append(abi.Methods["static"].Id(), word(1)...)
+	want = append(want, word(1)...)
+	want = append(want, word(7)...)
+
+	packed, err = abi.Pack("static", struct {
+		A *big.Int
+		B bool
+	}{big.NewInt(1), true}, big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Fatalf("static tuple mismatch: have %x, want %x", packed, want)
+	}
+	values, err = abi.Methods["static"].Inputs.UnpackValues(packed[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[1].(*big.Int).Int64() != 7 {
+		t.Errorf("argument after static tuple mismatch: have %v, want 7", values[1])
+	}
+	// Tuples missing a field cannot be packed
+	if _, err := abi.Pack("static", struct{ A *big.Int }{big.NewInt(1)}, big.NewInt(7)); err == nil {
+		t.Errorf("expected error packing incomplete tuple")
+	}
+}
+
 func TestPackNumber(t *testing.T) { log.DebugLog()
 	tests := []struct {
 		value  reflect.Value
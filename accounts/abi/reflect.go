@@ -82,12 +82,61 @@ func set(dst, src reflect.Value, output Argument) error { log.DebugLog()
 		dst.Set(src)
 	case dstType.Kind() == reflect.Ptr:
 		return set(dst.Elem(), src, output)
+	case dstType.Kind() == reflect.Struct && srcType.Kind() == reflect.Struct:
+		return setStruct(dst, src, output)
+	case dstType.Kind() == reflect.Slice && srcType.Kind() == reflect.Slice:
+		return setSlice(dst, src, output)
+	case dstType.Kind() == reflect.Array && srcType.Kind() == reflect.Array:
+		return setArray(dst, src, output)
 	default:
 		return fmt.Errorf("abi: cannot unmarshal %v in to %v", src.Type(), dst.Type())
 	}
 	return nil
 }
 
+// setStruct assigns a decoded tuple to a user defined struct field by field, as
+// nested tuples decode into anonymous structs not assignable to named ones.
+func setStruct(dst, src reflect.Value, output Argument) error { log.DebugLog()
+	for i := 0; i < src.NumField(); i++ {
+		name := src.Type().Field(i).Name
+		field := dst.FieldByName(name)
+		if !field.IsValid() {
+			return fmt.Errorf("abi: field %s can't be found in the given value %v", name, dst.Type())
+		}
+		if err := set(field, src.Field(i), output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setSlice assigns a decoded slice to a slice of a different element type, such
+// as tuples into user defined structs.
+func setSlice(dst, src reflect.Value, output Argument) error { log.DebugLog()
+	slice := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+	for i := 0; i < src.Len(); i++ {
+		if err := set(slice.Index(i), src.Index(i), output); err != nil {
+			return err
+		}
+	}
+	dst.Set(slice)
+	return nil
+}
+
+// setArray assigns a decoded array to an array of a different element type, such
+// as tuples into user defined structs.
+func setArray(dst, src reflect.Value, output Argument) error { log.DebugLog()
+	if dst.Len() != src.Len() {
+		return fmt.Errorf("abi: cannot unmarshal %v in to %v", src.Type(), dst.Type())
+	}
+	for i := 0; i < src.Len(); i++ {
+		if err := set(dst.Index(i), src.Index(i), output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // requireAssignable assures that `dest` is a pointer and it's not an interface.
 func requireAssignable(dst, src reflect.Value) error { log.DebugLog()
 	if dst.Kind() != reflect.Ptr && dst.Kind() != reflect.Interface {
@@ -17,11 +17,13 @@
 package abi
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/ethereum/go-ethereum/log"
 )
@@ -40,6 +42,7 @@ const (
 	HashTy
 	FixedPointTy
 	FunctionTy
+	TupleTy
 )
 
 // Type is the reflection of the supported argument type
@@ -52,6 +55,11 @@ type Type struct {
 	T    byte // Our own type checking
 
 	stringKind string // holds the unparsed string for deriving signatures
+
+	// Tuple relative fields
+	TupleElems    []*Type  // Type information of all tuple fields
+	TupleRawNames []string // Raw field names of all tuple fields
+	TupleRawName  string   // Raw struct name of the tuple, if known from the ABI
 }
 
 var (
@@ -59,8 +67,10 @@ var (
 	typeRegex = regexp.MustCompile("([a-zA-Z]+)(([0-9]+)(x([0-9]+))?)?")
 )
 
-// NewType creates a new reflection type of abi type given in t.
-func NewType(t string) (typ Type, err error) { log.DebugLog()
+// NewType creates a new reflection type of abi type given in t. The internal
+// type and the components are only relevant for tuples, describing the name of
+// the Solidity struct and its fields respectively.
+func NewType(t string, internalType string, components []ArgumentMarshaling) (typ Type, err error) { log.DebugLog()
 	// check that array brackets are equal if they exist
 	if strings.Count(t, "[") != strings.Count(t, "]") {
 		return Type{}, fmt.Errorf("invalid arg type in abi")
@@ -72,13 +82,16 @@ func NewType(t string) (typ Type, err error) { log.DebugLog()
 	// recursively create the type
 	if strings.Count(t, "[") != 0 {
 		i := strings.LastIndex(t, "[")
+		// grab the last cell and create a type from there
+		sliced := t[i:]
 		// recursively embed the type
-		embeddedType, err := NewType(t[:i])
+		embeddedType, err := NewType(t[:i], strings.TrimSuffix(internalType, sliced), components)
 		if err != nil {
 			return Type{}, err
 		}
-		// grab the last cell and create a type from there
-		sliced := t[i:]
+		// tuples are named by their components, so derive the signature from those
+		typ.stringKind = embeddedType.stringKind + sliced
+
 		// grab the slice size with regexp
 		re := regexp.MustCompile("[0-9]+")
 		intz := re.FindAllString(sliced, -1)
@@ -160,6 +173,52 @@ func NewType(t string) (typ Type, err error) { log.DebugLog()
 		typ.T = FunctionTy
 		typ.Size = 24
 		typ.Type = reflect.ArrayOf(24, reflect.TypeOf(byte(0)))
+	case "tuple":
+		var (
+			fields []reflect.StructField
+			elems  []*Type
+			names  []string
+			kinds  []string
+			exists = make(map[string]bool)
+		)
+		for _, c := range components {
+			cType, err := NewType(c.Type, c.InternalType, c.Components)
+			if err != nil {
+				return Type{}, err
+			}
+			// reflect.StructOf panics on unexported or duplicate fields, catch them early
+			name := ToCamelCase(c.Name)
+			if name == "" || !unicode.IsUpper(rune(name[0])) {
+				return Type{}, fmt.Errorf("abi: invalid tuple field name %q", c.Name)
+			}
+			if exists[name] {
+				return Type{}, fmt.Errorf("abi: multiple tuple fields mapping to the same struct field '%s'", name)
+			}
+			exists[name] = true
+
+			fields = append(fields, reflect.StructField{Name: name, Type: cType.Type})
+			elems = append(elems, &cType)
+			names = append(names, c.Name)
+			kinds = append(kinds, cType.stringKind)
+		}
+		if len(elems) == 0 {
+			return Type{}, errors.New("abi: empty tuple")
+		}
+		typ.Kind = reflect.Struct
+		typ.Type = reflect.StructOf(fields)
+		typ.T = TupleTy
+		typ.TupleElems = elems
+		typ.TupleRawNames = names
+		typ.stringKind = "(" + strings.Join(kinds, ",") + ")"
+
+		// Newer compilers report the name of the Solidity struct too (struct Contract.Name)
+		if strings.HasPrefix(internalType, "struct ") {
+			name := strings.TrimPrefix(internalType, "struct ")
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				name = name[i+1:]
+			}
+			typ.TupleRawName = name
+		}
 	default:
 		return Type{}, fmt.Errorf("unsupported arg type: %s", t)
 	}
@@ -180,23 +239,65 @@ func (t Type) pack(v reflect.Value) ([]byte, error) { log.DebugLog()
 		return nil, err
 	}
 
-	if t.T == SliceTy || t.T == ArrayTy {
-		var packed []byte
+	switch t.T {
+	case SliceTy, ArrayTy:
+		var ret []byte
 
+		if t.requiresLengthPrefix() {
+			// append length
+			ret = append(ret, packNum(reflect.ValueOf(v.Len()))...)
+		}
+		// dynamic elements are referenced by offsets relative to the head
+		offset := 0
+		offsetReq := isDynamicType(*t.Elem)
+		if offsetReq {
+			offset = getTypeSize(*t.Elem) * v.Len()
+		}
+		var tail []byte
 		for i := 0; i < v.Len(); i++ {
 			val, err := t.Elem.pack(v.Index(i))
 			if err != nil {
 				return nil, err
 			}
-			packed = append(packed, val...)
+			if !offsetReq {
+				ret = append(ret, val...)
+				continue
+			}
+			ret = append(ret, packNum(reflect.ValueOf(offset))...)
+			offset += len(val)
+			tail = append(tail, val...)
 		}
-		if t.T == SliceTy {
-			return packBytesSlice(packed, v.Len()), nil
-		} else if t.T == ArrayTy {
-			return packed, nil
+		return append(ret, tail...), nil
+
+	case TupleTy:
+		// calculate the size occupied by the head of the tuple
+		offset := 0
+		for _, elem := range t.TupleElems {
+			offset += getTypeSize(*elem)
+		}
+		var ret, tail []byte
+		for i, elem := range t.TupleElems {
+			field := v.FieldByName(ToCamelCase(t.TupleRawNames[i]))
+			if !field.IsValid() {
+				return nil, fmt.Errorf("abi: field %s for tuple not found in the given struct", t.TupleRawNames[i])
+			}
+			val, err := elem.pack(field)
+			if err != nil {
+				return nil, err
+			}
+			if isDynamicType(*elem) {
+				ret = append(ret, packNum(reflect.ValueOf(offset))...)
+				tail = append(tail, val...)
+				offset += len(val)
+			} else {
+				ret = append(ret, val...)
+			}
 		}
+		return append(ret, tail...), nil
+
+	default:
+		return packElement(t, v), nil
 	}
-	return packElement(t, v), nil
 }
 
 // requireLengthPrefix returns whether the type requires any sort of length
@@ -204,3 +305,37 @@ func (t Type) pack(v reflect.Value) ([]byte, error) { log.DebugLog()
 func (t Type) requiresLengthPrefix() bool { log.DebugLog()
 	return t.T == StringTy || t.T == BytesTy || t.T == SliceTy
 }
+
+// isDynamicType returns whether the type is encoded in the tail of its enclosing
+// tuple, with only an offset kept in the head.
+func isDynamicType(t Type) bool { log.DebugLog()
+	if t.T == TupleTy {
+		for _, elem := range t.TupleElems {
+			if isDynamicType(*elem) {
+				return true
+			}
+		}
+		return false
+	}
+	return t.T == StringTy || t.T == BytesTy || t.T == SliceTy || (t.T == ArrayTy && isDynamicType(*t.Elem))
+}
+
+// getTypeSize returns the number of bytes the type occupies in the head of its
+// enclosing tuple. Static arrays and tuples are encoded inline, everything else
+// takes a single word (the value itself or an offset to it).
+func getTypeSize(t Type) int { log.DebugLog()
+	if t.T == ArrayTy && !isDynamicType(*t.Elem) {
+		// Recursively calculate type size if it is a nested array
+		if t.Elem.T == ArrayTy || t.Elem.T == TupleTy {
+			return t.Size * getTypeSize(*t.Elem)
+		}
+		return t.Size * 32
+	} else if t.T == TupleTy && !isDynamicType(t) {
+		total := 0
+		for _, elem := range t.TupleElems {
+			total += getTypeSize(*elem)
+		}
+		return total
+	}
+	return 32
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 // typeWithoutStringer is a alias for the Type type which simply doesn't implement
@@ -97,7 +98,7 @@ func TestTypeRegexp(t *testing.T) { log.DebugLog()
 	}
 
 	for _, tt := range tests {
-		typ, err := NewType(tt.blob)
+		typ, err := NewType(tt.blob, "", nil)
 		if err != nil {
 			t.Errorf("type %q: failed to parse type string: %v", tt.blob, err)
 		}
@@ -107,6 +108,48 @@ func TestTypeRegexp(t *testing.T) { log.DebugLog()
 	}
 }
 
+// Tests that tuple types get parsed from their components.
+func TestTupleTypeParse(t *testing.T) { log.DebugLog()
+	components := []ArgumentMarshaling{
+		{Name: "a", Type: "uint256"},
+		{Name: "inner_value", Type: "tuple[]", InternalType: "struct Foo.Inner[]", Components: []ArgumentMarshaling{{Name: "b", Type: "bool"}}},
+	}
+	typ, err := NewType("tuple[2]", "struct Foo.Outer[2]", components)
+	if err != nil {
+		t.Fatalf("failed to parse tuple: %v", err)
+	}
+	if have, want := typ.String(), "(uint256,(bool)[])[2]"; have != want {
+		t.Errorf("signature mismatch: have %s, want %s", have, want)
+	}
+	want := reflect.TypeOf([2]struct {
+		A          *big.Int
+		InnerValue []struct {
+			B bool
+		}
+	}{})
+	if typ.Type != want {
+		t.Errorf("reflect type mismatch: have %v, want %v", typ.Type, want)
+	}
+	if typ.Elem.T != TupleTy || typ.Elem.TupleRawName != "Outer" {
+		t.Errorf("outer tuple mismatch: have type %d, name %q", typ.Elem.T, typ.Elem.TupleRawName)
+	}
+	if inner := typ.Elem.TupleElems[1].Elem; inner.TupleRawName != "Inner" || typ.Elem.TupleRawNames[1] != "inner_value" {
+		t.Errorf("inner tuple mismatch: have name %q, field %q", inner.TupleRawName, typ.Elem.TupleRawNames[1])
+	}
+	// Tuples that cannot be represented as Go structs are rejected
+	for _, invalid := range [][]ArgumentMarshaling{
+		nil,
+		{{Name: "", Type: "uint256"}},
+		{{Name: "_", Type: "uint256"}},
+		{{Name: "a", Type: "uint256"}, {Name: "_a", Type: "uint256"}},
+		{{Name: "a", Type: "tuple", Components: []ArgumentMarshaling{{Type: "bool"}}}},
+	} {
+		if _, err := NewType("tuple", "", invalid); err == nil {
+			t.Errorf("expected error parsing tuple %v", invalid)
+		}
+	}
+}
+
 func TestTypeCheck(t *testing.T) { log.DebugLog()
 	for i, test := range []struct {
 		typ   string
@@ -253,7 +296,7 @@ func TestTypeCheck(t *testing.T) { log.DebugLog()
 		{"address", [20]byte{}, ""},
 		{"address", common.Address{}, ""},
 	} {
-		typ, err := NewType(test.typ)
+		typ, err := NewType(test.typ, "", nil)
 		if err != nil && len(test.err) == 0 {
 			t.Fatal("unexpected parse error:", err)
 		} else if err != nil && len(test.err) != 0 {
@@ -94,17 +94,6 @@ func readFixedBytes(t Type, word []byte) (interface{}, error) { log.DebugLog()
 
 }
 
-func getFullElemSize(elem *Type) int { log.DebugLog()
-	//all other should be counted as 32 (slices have pointers to respective elements)
-	size := 32
-	//arrays wrap it, each element being the same size
-	for elem.T == ArrayTy {
-		size *= elem.Size
-		elem = elem.Elem
-	}
-	return size
-}
-
 // iteratively unpack elements
 func forEachUnpack(t Type, output []byte, start, size int) (interface{}, error) { log.DebugLog()
 	if size < 0 {
@@ -127,12 +116,9 @@ func forEachUnpack(t Type, output []byte, start, size int) (interface{}, error)
 		return nil, fmt.Errorf("abi: invalid type in array/slice unpacking stage")
 	}
 
-	// Arrays have packed elements, resulting in longer unpack steps.
-	// Slices have just 32 bytes per element (pointing to the contents).
-	elemSize := 32
-	if t.T == ArrayTy {
-		elemSize = getFullElemSize(t.Elem)
-	}
+	// Static elements are packed inline, resulting in longer unpack steps.
+	// Dynamic ones have just 32 bytes per element (pointing to the contents).
+	elemSize := getTypeSize(*t.Elem)
 
 	for i, j := start, 0; j < size; i, j = i+elemSize, j+1 {
 
@@ -149,6 +135,24 @@ func forEachUnpack(t Type, output []byte, start, size int) (interface{}, error)
 	return refSlice.Interface(), nil
 }
 
+// forTupleUnpack unpacks the fields of a tuple encoded at the start of output.
+func forTupleUnpack(t Type, output []byte) (interface{}, error) { log.DebugLog()
+	retval := reflect.New(t.Type).Elem()
+	virtualArgs := 0
+	for index, elem := range t.TupleElems {
+		marshalledValue, err := toGoType((index+virtualArgs)*32, *elem, output)
+		if err != nil {
+			return nil, err
+		}
+		if (elem.T == ArrayTy || elem.T == TupleTy) && !isDynamicType(*elem) {
+			// Static arrays and tuples are encoded inline, see UnpackValues
+			virtualArgs += getTypeSize(*elem)/32 - 1
+		}
+		retval.Field(index).Set(reflect.ValueOf(marshalledValue))
+	}
+	return retval.Interface(), nil
+}
+
 // toGoType parses the output bytes and recursively assigns the value of these bytes
 // into a go type with accordance with the ABI spec.
 func toGoType(index int, t Type, output []byte) (interface{}, error) { log.DebugLog()
@@ -173,10 +177,27 @@ func toGoType(index int, t Type, output []byte) (interface{}, error) { log.Debug
 	}
 
 	switch t.T {
+	case TupleTy:
+		if isDynamicType(t) {
+			begin, err := offsetPointsTo(index, output)
+			if err != nil {
+				return nil, err
+			}
+			return forTupleUnpack(t, output[begin:])
+		}
+		return forTupleUnpack(t, output[index:])
 	case SliceTy:
-		return forEachUnpack(t, output, begin, end)
+		// offsets of dynamic elements are relative to the start of the contents
+		return forEachUnpack(t, output[begin:], 0, end)
 	case ArrayTy:
-		return forEachUnpack(t, output, index, t.Size)
+		if isDynamicType(*t.Elem) {
+			begin, err := offsetPointsTo(index, output)
+			if err != nil {
+				return nil, err
+			}
+			return forEachUnpack(t, output[begin:], 0, t.Size)
+		}
+		return forEachUnpack(t, output[index:], 0, t.Size)
 	case StringTy: // variable arrays are written at the end of the return bytes
 		return string(output[begin : begin+end]), nil
 	case IntTy, UintTy:
@@ -229,3 +250,18 @@ func lengthPrefixPointsTo(index int, output []byte) (start int, length int, err
 	length = int(lengthBig.Uint64())
 	return
 }
+
+// offsetPointsTo interprets a 32 byte slice as an offset to the encoding of a
+// dynamic tuple or array, returning where its contents start.
+func offsetPointsTo(index int, output []byte) (start int, err error) { log.DebugLog()
+	offset := big.NewInt(0).SetBytes(output[index : index+32])
+	outputLength := big.NewInt(int64(len(output)))
+
+	if offset.Cmp(outputLength) > 0 {
+		return 0, fmt.Errorf("abi: cannot marshal in to go type: offset %v would go over slice boundary (len=%v)", offset, outputLength)
+	}
+	if offset.BitLen() > 63 {
+		return 0, fmt.Errorf("abi offset larger than int64: %v", offset)
+	}
+	return int(offset.Uint64()), nil
+}
@@ -40,7 +40,7 @@ var (
 
 	pkgFlag  = flag.String("pkg", "", "Package name to generate the binding into")
 	outFlag  = flag.String("out", "", "Output file for the generated binding (default = stdout)")
-	langFlag = flag.String("lang", "go", "Destination language for the bindings (go, java, objc)")
+	langFlag = flag.String("lang", "go", "Destination language for the bindings (go, java, objc, swift, ts)")
 )
 
 func main() { log.DebugLog()
@@ -66,6 +66,10 @@ func main() { log.DebugLog()
 		lang = bind.LangJava
 	case "objc":
 		lang = bind.LangObjC
+	case "swift":
+		lang = bind.LangSwift
+	case "ts", "typescript":
+		lang = bind.LangTypeScript
 	default:
 		fmt.Printf("Unsupported destination language \"%s\" (--lang)\n", *langFlag)
 		os.Exit(-1)
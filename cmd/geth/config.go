@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/dashboard"
 	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/graphql"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
 	whisper "github.com/ethereum/go-ethereum/whisper/whisperv6"
@@ -81,6 +82,7 @@ type gethConfig struct {
 	Node      node.Config
 	Ethstats  ethstatsConfig
 	Dashboard dashboard.Config
+	GraphQL   graphql.Config
 }
 
 func loadConfig(file string, cfg *gethConfig) error { log.DebugLog()
@@ -115,6 +117,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) { log.DebugLog()
 		Shh:       whisper.DefaultConfig,
 		Node:      defaultNodeConfig(),
 		Dashboard: dashboard.DefaultConfig,
+		GraphQL:   graphql.DefaultConfig,
 	}
 
 	// Load config file.
@@ -137,6 +140,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) { log.DebugLog()
 
 	utils.SetShhConfig(ctx, stack, &cfg.Shh)
 	utils.SetDashboardConfig(ctx, &cfg.Dashboard)
+	utils.SetGraphQLConfig(ctx, &cfg.GraphQL)
 
 	return stack, cfg
 }
@@ -159,6 +163,9 @@ func makeFullNode(ctx *cli.Context) *node.Node { log.DebugLog()
 	if ctx.GlobalBool(utils.DashboardEnabledFlag.Name) {
 		utils.RegisterDashboardService(stack, &cfg.Dashboard, gitCommit)
 	}
+	if ctx.GlobalBool(utils.GraphQLEnabledFlag.Name) {
+		utils.RegisterGraphQLService(stack, &cfg.GraphQL)
+	}
 	// Whisper must be explicitly enabled by specifying at least 1 whisper flag or in dev mode
 	shhEnabled := enableWhisper(ctx)
 	shhAutoEnabled := !ctx.GlobalIsSet(utils.WhisperEnabledFlag.Name) && ctx.GlobalIsSet(utils.DeveloperFlag.Name)
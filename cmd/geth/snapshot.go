@@ -0,0 +1,92 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	snapshotCommand = cli.Command{
+		Name:     "snapshot",
+		Usage:    "A set of commands based on the state of the chain",
+		Category: "MISCELLANEOUS COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:      "prune-state",
+				Usage:     "Prune stale ethereum state data from the database",
+				ArgsUsage: "<root>",
+				Action:    utils.MigrateFlags(pruneState),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.DBEngineFlag,
+					utils.CacheFlag,
+					utils.CacheDatabaseFlag,
+					utils.TestnetFlag,
+					utils.RinkebyFlag,
+					utils.BloomFilterSizeFlag,
+					utils.PruneRecentStatesFlag,
+				},
+				Description: `
+geth snapshot prune-state <state-root>
+will prune historical state data with the help of a bloom filter.
+The state of the given root (the head block's state root by default)
+and of the last --prune.recents blocks still available on disk is
+retained, all other trie nodes and contract codes are deleted.
+
+The pruning runs offline, the node must not be running. If it is
+interrupted, the next run (or the next node startup) resumes it
+with the bloom filter persisted in the data directory.
+
+WARNING: it's only supported in full gcmode, any historical state
+retained by an archive node will be deleted too.`,
+			},
+		},
+	}
+)
+
+// pruneState prunes the stale state data from the chain database offline.
+func pruneState(ctx *cli.Context) error {
+	log.DebugLog()
+	stack, _ := makeConfigNode(ctx)
+	chaindb := utils.MakeChainDatabase(ctx, stack)
+	defer chaindb.Close()
+
+	if ctx.NArg() > 1 {
+		utils.Fatalf("Too many arguments given")
+	}
+	var root common.Hash
+	if ctx.NArg() == 1 {
+		if len(common.FromHex(ctx.Args()[0])) != common.HashLength {
+			utils.Fatalf("Invalid state root: %s", ctx.Args()[0])
+		}
+		root = common.HexToHash(ctx.Args()[0])
+	}
+	p, err := pruner.NewPruner(chaindb, stack.ResolvePath(""), ctx.GlobalUint64(utils.BloomFilterSizeFlag.Name), ctx.GlobalUint64(utils.PruneRecentStatesFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to open state pruner: %v", err)
+	}
+	if err := p.Prune(root); err != nil {
+		utils.Fatalf("Failed to prune state: %v", err)
+	}
+	return nil
+}
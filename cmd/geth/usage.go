@@ -72,6 +72,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.DataDirFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
+			utils.DBEngineFlag,
+			utils.AncientFlag,
+			utils.AncientThresholdFlag,
 			utils.NetworkIdFlag,
 			utils.TestnetFlag,
 			utils.RinkebyFlag,
@@ -132,6 +135,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.CacheFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.CacheSnapshotFlag,
+			utils.SnapshotFlag,
 			utils.TrieCacheGenFlag,
 		},
 	},
@@ -149,15 +154,29 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCListenAddrFlag,
 			utils.RPCPortFlag,
 			utils.RPCApiFlag,
+			utils.RPCAccessListFlag,
+			utils.RPCSlowCallFlag,
+			utils.RPCGlobalGasCapFlag,
+			utils.RPCSubscriptionQueueFlag,
+			utils.RPCSubscriptionPolicyFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
 			utils.WSApiFlag,
+			utils.WSAccessListFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.JWTSecretFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.GraphQLEnabledFlag,
+			utils.GraphQLListenAddrFlag,
+			utils.GraphQLPortFlag,
+			utils.GraphQLCORSDomainFlag,
+			utils.GraphQLVirtualHostsFlag,
+			utils.GraphQLMaxDepthFlag,
+			utils.GraphQLMaxComplexityFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
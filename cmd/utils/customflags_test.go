@@ -20,6 +20,8 @@ import (
 	"os"
 	"os/user"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func TestPathExpansion(t *testing.T) { log.DebugLog()
@@ -19,6 +19,7 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -37,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/dashboard"
@@ -45,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethstats"
+	"github.com/ethereum/go-ethereum/graphql"
 	"github.com/ethereum/go-ethereum/les"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -126,6 +129,11 @@ var (
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
 	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "dbengine",
+		Usage: "Backing database implementation to use (" + strings.Join(ethdb.Backends(), ", ") + ")",
+		Value: ethdb.DefaultBackend,
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 1=Frontier, 2=Morden (disused), 3=Ropsten, 4=Rinkeby)",
@@ -167,7 +175,7 @@ var (
 	defaultSyncMode = eth.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "snap" or "light")`,
 		Value: &defaultSyncMode,
 	}
 	GCModeFlag = cli.StringFlag{
@@ -175,6 +183,16 @@ var (
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	BloomFilterSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to the bloom filter used by state pruning",
+		Value: 2048,
+	}
+	PruneRecentStatesFlag = cli.Uint64Flag{
+		Name:  "prune.recents",
+		Usage: "Number of recent block states retained by state pruning",
+		Value: pruner.DefaultRecentStates,
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -295,6 +313,15 @@ var (
 		Usage: "Megabytes of memory allocated to internal caching",
 		Value: 1024,
 	}
+	AncientFlag = DirectoryFlag{
+		Name:  "ancient",
+		Usage: "Data directory for the ancient chain segment store (default = disabled)",
+	}
+	AncientThresholdFlag = cli.Uint64Flag{
+		Name:  "ancient.threshold",
+		Usage: "Number of recent blocks to keep in the key-value store before freezing them",
+		Value: eth.DefaultConfig.FreezerThreshold,
+	}
 	CacheDatabaseFlag = cli.IntFlag{
 		Name:  "cache.database",
 		Usage: "Percentage of cache memory allowance to use for database io",
@@ -305,6 +332,15 @@ var (
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
 		Value: 25,
 	}
+	CacheSnapshotFlag = cli.IntFlag{
+		Name:  "cache.snapshot",
+		Usage: "Percentage of cache memory allowance to use for snapshot caching",
+		Value: 10,
+	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Enables the flat state snapshot for faster state access (experimental)",
+	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
 		Usage: "Number of trie node generations to keep in memory",
@@ -402,6 +438,63 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	// GraphQL settings
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL server",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphql.addr",
+		Usage: "GraphQL server listening interface",
+		Value: graphql.DefaultConfig.Host,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphql.port",
+		Usage: "GraphQL server listening port",
+		Value: graphql.DefaultConfig.Port,
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphql.corsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value: "",
+	}
+	GraphQLVirtualHostsFlag = cli.StringFlag{
+		Name:  "graphql.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(graphql.DefaultConfig.VirtualHosts, ","),
+	}
+	GraphQLMaxDepthFlag = cli.IntFlag{
+		Name:  "graphql.maxdepth",
+		Usage: "Maximum nesting depth of GraphQL queries (0 = unlimited)",
+		Value: graphql.DefaultConfig.MaxDepth,
+	}
+	GraphQLMaxComplexityFlag = cli.IntFlag{
+		Name:  "graphql.maxcomplexity",
+		Usage: "Maximum number of objects a GraphQL query may resolve (0 = unlimited)",
+		Value: graphql.DefaultConfig.MaxComplexity,
+	}
+	RPCAccessListFlag = cli.StringFlag{
+		Name:  "rpcacl",
+		Usage: "Comma separated method access rules of the HTTP-RPC interface, '!' denies (e.g. 'eth_*,!eth_sign*')",
+		Value: "",
+	}
+	RPCSlowCallFlag = cli.DurationFlag{
+		Name:  "rpcslowcall",
+		Usage: "Log RPC calls executing longer than this duration, with their parameters (0 = disabled)",
+	}
+	RPCGlobalGasCapFlag = cli.Uint64Flag{
+		Name:  "rpcgascap",
+		Usage: "Gas cap of eth_call, eth_estimateGas and debug_traceCall (0 = no cap, traced calls use the block gas limit)",
+	}
+	RPCSubscriptionQueueFlag = cli.IntFlag{
+		Name:  "rpcsubqueue",
+		Usage: "Number of notifications queued per RPC subscription (0 = unqueued)",
+	}
+	RPCSubscriptionPolicyFlag = cli.StringFlag{
+		Name:  "rpcsubpolicy",
+		Usage: "Handling of RPC subscriptions overflowing their queue (block, dropoldest, disconnect)",
+		Value: "block",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -434,6 +527,16 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	JWTSecretFlag = cli.StringFlag{
+		Name:  "jwtsecret",
+		Usage: "Path to a hex encoded JWT secret authenticating HTTP-RPC and websocket requests (generated if missing)",
+		Value: "",
+	}
+	WSAccessListFlag = cli.StringFlag{
+		Name:  "wsacl",
+		Usage: "Comma separated method access rules of the WS-RPC interface, '!' denies (e.g. 'eth_*,!eth_sign*')",
+		Value: "",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -690,6 +793,9 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) { log.DebugLog()
 	if ctx.GlobalIsSet(RPCApiFlag.Name) {
 		cfg.HTTPModules = splitAndTrim(ctx.GlobalString(RPCApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCAccessListFlag.Name) {
+		cfg.HTTPAccessList = splitAndTrim(ctx.GlobalString(RPCAccessListFlag.Name))
+	}
 	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 	}
@@ -714,6 +820,9 @@ func setWS(ctx *cli.Context, cfg *node.Config) { log.DebugLog()
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSAccessListFlag.Name) {
+		cfg.WSAccessList = splitAndTrim(ctx.GlobalString(WSAccessListFlag.Name))
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -876,6 +985,21 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) { log.DebugLog()
 	setWS(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
+	if ctx.GlobalIsSet(JWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.GlobalString(JWTSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCSlowCallFlag.Name) {
+		cfg.RPCSlowCallThreshold = ctx.GlobalDuration(RPCSlowCallFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCSubscriptionQueueFlag.Name) {
+		cfg.RPCSubscriptionQueue = ctx.GlobalInt(RPCSubscriptionQueueFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCSubscriptionPolicyFlag.Name) {
+		if err := cfg.RPCSubscriptionPolicy.UnmarshalText([]byte(ctx.GlobalString(RPCSubscriptionPolicyFlag.Name))); err != nil {
+			Fatalf("Option %q: %v", RPCSubscriptionPolicyFlag.Name, err)
+		}
+	}
+
 	switch {
 	case ctx.GlobalIsSet(DataDirFlag.Name):
 		cfg.DataDir = ctx.GlobalString(DataDirFlag.Name)
@@ -886,6 +1010,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) { log.DebugLog()
 	case ctx.GlobalBool(RinkebyFlag.Name):
 		cfg.DataDir = filepath.Join(node.DefaultDataDir(), "rinkeby")
 	}
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		cfg.DatabaseBackend = ctx.GlobalString(DBEngineFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
@@ -1041,6 +1168,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) { log.Deb
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
 
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientThresholdFlag.Name) {
+		cfg.FreezerThreshold = ctx.GlobalUint64(AncientThresholdFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheDatabaseFlag.Name) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 	}
@@ -1054,12 +1187,18 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) { log.Deb
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalBool(SnapshotFlag.Name) {
+		cfg.SnapshotCache = makeSnapshotCache(ctx)
+	}
 	if ctx.GlobalIsSet(MinerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(MinerThreadsFlag.Name)
 	}
 	if ctx.GlobalIsSet(DocRootFlag.Name) {
 		cfg.DocRoot = ctx.GlobalString(DocRootFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCGlobalGasCapFlag.Name) {
+		cfg.RPCGasCap = ctx.GlobalUint64(RPCGlobalGasCapFlag.Name)
+	}
 	if ctx.GlobalIsSet(ExtraDataFlag.Name) {
 		cfg.ExtraData = []byte(ctx.GlobalString(ExtraDataFlag.Name))
 	}
@@ -1120,6 +1259,28 @@ func SetDashboardConfig(ctx *cli.Context, cfg *dashboard.Config) { log.DebugLog(
 	cfg.Refresh = ctx.GlobalDuration(DashboardRefreshFlag.Name)
 }
 
+// SetGraphQLConfig applies graphql related command line flags to the config.
+func SetGraphQLConfig(ctx *cli.Context, cfg *graphql.Config) { log.DebugLog()
+	if ctx.GlobalIsSet(GraphQLListenAddrFlag.Name) {
+		cfg.Host = ctx.GlobalString(GraphQLListenAddrFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLPortFlag.Name) {
+		cfg.Port = ctx.GlobalInt(GraphQLPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLCORSDomainFlag.Name) {
+		cfg.Cors = splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+	}
+	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
+		cfg.VirtualHosts = splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
+	}
+	if ctx.GlobalIsSet(GraphQLMaxDepthFlag.Name) {
+		cfg.MaxDepth = ctx.GlobalInt(GraphQLMaxDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLMaxComplexityFlag.Name) {
+		cfg.MaxComplexity = ctx.GlobalInt(GraphQLMaxComplexityFlag.Name)
+	}
+}
+
 // RegisterEthService adds an Ethereum client to the stack.
 func RegisterEthService(stack *node.Node, cfg *eth.Config) { log.DebugLog()
 	var err error
@@ -1175,6 +1336,24 @@ func RegisterEthStatsService(stack *node.Node, url string) { log.DebugLog()
 	}
 }
 
+// RegisterGraphQLService configures the GraphQL server and adds it to the given
+// node, serving the data of either the full or the light Ethereum service.
+func RegisterGraphQLService(stack *node.Node, cfg *graphql.Config) { log.DebugLog()
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err == nil {
+			return graphql.New(ethServ.ApiBackend, *cfg)
+		}
+		var lesServ *les.LightEthereum
+		if err := ctx.Service(&lesServ); err == nil {
+			return graphql.New(lesServ.ApiBackend, *cfg)
+		}
+		return nil, errors.New("no Ethereum service to serve GraphQL queries from")
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) { log.DebugLog()
 	// TODO(fjl): move target gas limit into config
@@ -1247,6 +1426,9 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalBool(SnapshotFlag.Name) {
+		cache.SnapshotLimit = makeSnapshotCache(ctx)
+	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
 	chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg)
 	if err != nil {
@@ -1255,6 +1437,15 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	return chain, chainDb
 }
 
+// makeSnapshotCache calculates the memory allowance of the state snapshot. It's
+// never zero, as that would disable snapshots altogether.
+func makeSnapshotCache(ctx *cli.Context) int { log.DebugLog()
+	if cache := ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100; cache > 0 {
+		return cache
+	}
+	return 1
+}
+
 // MakeConsolePreloads retrieves the absolute paths for the console JavaScript
 // scripts to preload before starting.
 func MakeConsolePreloads(ctx *cli.Context) []string { log.DebugLog()
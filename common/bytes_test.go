@@ -21,6 +21,8 @@ import (
 	"testing"
 
 	checker "gopkg.in/check.v1"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 type BytesSuite struct{}
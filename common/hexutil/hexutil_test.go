@@ -20,6 +20,8 @@ import (
 	"bytes"
 	"math/big"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 type marshalTest struct {
@@ -68,6 +68,26 @@ func (b *Bytes) UnmarshalText(input []byte) error { log.DebugLog()
 	return err
 }
 
+// ImplementsGraphQLType returns true if Bytes implements the specified GraphQL type.
+func (b Bytes) ImplementsGraphQLType(name string) bool { log.DebugLog()
+	return name == "Bytes"
+}
+
+// UnmarshalGraphQL unmarshals the provided GraphQL query data.
+func (b *Bytes) UnmarshalGraphQL(input interface{}) error { log.DebugLog()
+	switch input := input.(type) {
+	case string:
+		data, err := Decode(input)
+		if err != nil {
+			return err
+		}
+		*b = data
+		return nil
+	default:
+		return fmt.Errorf("unexpected type %T for Bytes", input)
+	}
+}
+
 // String returns the hex encoding of b.
 func (b Bytes) String() string { log.DebugLog()
 	return Encode(b)
@@ -178,6 +198,24 @@ func (b *Big) UnmarshalText(input []byte) error { log.DebugLog()
 	return nil
 }
 
+// ImplementsGraphQLType returns true if Big implements the provided GraphQL type.
+func (b Big) ImplementsGraphQLType(name string) bool { log.DebugLog()
+	return name == "BigInt"
+}
+
+// UnmarshalGraphQL unmarshals the provided GraphQL query data.
+func (b *Big) UnmarshalGraphQL(input interface{}) error { log.DebugLog()
+	switch input := input.(type) {
+	case string:
+		return b.UnmarshalText([]byte(input))
+	case int32:
+		*b = Big(*big.NewInt(int64(input)))
+		return nil
+	default:
+		return fmt.Errorf("unexpected type %T for BigInt", input)
+	}
+}
+
 // ToInt converts b to a big.Int.
 func (b *Big) ToInt() *big.Int { log.DebugLog()
 	return (*big.Int)(b)
@@ -23,6 +23,8 @@ import (
 	"errors"
 	"math/big"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func checkError(t *testing.T, input string, got, want error) bool { log.DebugLog()
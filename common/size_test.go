@@ -18,6 +18,8 @@ package common
 
 import (
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func TestStorageSizeString(t *testing.T) { log.DebugLog()
@@ -21,6 +21,8 @@ import (
 	"math/big"
 	"strings"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func TestBytesConversion(t *testing.T) { log.DebugLog()
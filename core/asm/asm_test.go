@@ -20,6 +20,8 @@ import (
 	"testing"
 
 	"encoding/hex"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 // Tests disassembling the instructions for valid evm code
@@ -72,3 +74,32 @@ func TestInstructionIteratorEmpty(t *testing.T) { log.DebugLog()
 		t.Errorf("Expected 0, but got %v instead.", cnt)
 	}
 }
+
+// Tests that the Constantinople instructions are assembled and disassembled.
+func TestConstantinopleInstructions(t *testing.T) { log.DebugLog()
+	bin, errs := compileAll("push 0\nextcodehash\npush 0\ndup1\ndup1\ndup1\ncreate2\n")
+	if len(errs) != 0 {
+		t.Fatalf("failed to compile: %v", errs)
+	}
+	if want := "60003f6000808080f5"; bin != want {
+		t.Fatalf("bytecode mismatch: have %s, want %s", bin, want)
+	}
+	code, _ := hex.DecodeString(bin)
+	instrs, err := Disassemble(code)
+	if err != nil {
+		t.Fatalf("failed to disassemble: %v", err)
+	}
+	if want := "000002: EXTCODEHASH\n"; instrs[1] != want {
+		t.Errorf("instruction 1 mismatch: have %q, want %q", instrs[1], want)
+	}
+	if want := "000008: CREATE2\n"; instrs[6] != want {
+		t.Errorf("instruction 6 mismatch: have %q, want %q", instrs[6], want)
+	}
+}
+
+// compileAll assembles the source code into its hex encoded bytecode.
+func compileAll(src string) (string, []error) { log.DebugLog()
+	compiler := NewCompiler(false)
+	compiler.Feed(Lex("test.asm", []byte(src), false))
+	return compiler.Compile()
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -77,9 +78,9 @@ func TestHeaderVerification(t *testing.T) { log.DebugLog()
 }
 
 // Tests that concurrent header verification works, for both good and bad blocks.
-func TestHeaderConcurrentVerification2(t *testing.T)  { log.DebugLog() testHeaderConcurrentVerification(t, 2) }
-func TestHeaderConcurrentVerification8(t *testing.T)  { log.DebugLog() testHeaderConcurrentVerification(t, 8) }
-func TestHeaderConcurrentVerification32(t *testing.T) { log.DebugLog() testHeaderConcurrentVerification(t, 32) }
+func TestHeaderConcurrentVerification2(t *testing.T)  { log.DebugLog(); testHeaderConcurrentVerification(t, 2) }
+func TestHeaderConcurrentVerification8(t *testing.T)  { log.DebugLog(); testHeaderConcurrentVerification(t, 8) }
+func TestHeaderConcurrentVerification32(t *testing.T) { log.DebugLog(); testHeaderConcurrentVerification(t, 32) }
 
 func testHeaderConcurrentVerification(t *testing.T, threads int) { log.DebugLog()
 	// Create a simple chain to verify
@@ -149,9 +150,9 @@ func testHeaderConcurrentVerification(t *testing.T, threads int) { log.DebugLog(
 
 // Tests that aborting a header validation indeed prevents further checks from being
 // run, as well as checks that no left-over goroutines are leaked.
-func TestHeaderConcurrentAbortion2(t *testing.T)  { log.DebugLog() testHeaderConcurrentAbortion(t, 2) }
-func TestHeaderConcurrentAbortion8(t *testing.T)  { log.DebugLog() testHeaderConcurrentAbortion(t, 8) }
-func TestHeaderConcurrentAbortion32(t *testing.T) { log.DebugLog() testHeaderConcurrentAbortion(t, 32) }
+func TestHeaderConcurrentAbortion2(t *testing.T)  { log.DebugLog(); testHeaderConcurrentAbortion(t, 2) }
+func TestHeaderConcurrentAbortion8(t *testing.T)  { log.DebugLog(); testHeaderConcurrentAbortion(t, 8) }
+func TestHeaderConcurrentAbortion32(t *testing.T) { log.DebugLog(); testHeaderConcurrentAbortion(t, 32) }
 
 func testHeaderConcurrentAbortion(t *testing.T, threads int) { log.DebugLog()
 	// Create a simple chain to verify
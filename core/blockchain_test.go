@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -180,8 +181,8 @@ func TestLastBlock(t *testing.T) { log.DebugLog()
 
 // Tests that given a starting canonical chain of a given size, it can be extended
 // with various length chains.
-func TestExtendCanonicalHeaders(t *testing.T) { log.DebugLog() testExtendCanonical(t, false) }
-func TestExtendCanonicalBlocks(t *testing.T)  { log.DebugLog() testExtendCanonical(t, true) }
+func TestExtendCanonicalHeaders(t *testing.T) { log.DebugLog(); testExtendCanonical(t, false) }
+func TestExtendCanonicalBlocks(t *testing.T)  { log.DebugLog(); testExtendCanonical(t, true) }
 
 func testExtendCanonical(t *testing.T, full bool) { log.DebugLog()
 	length := 5
@@ -208,8 +209,8 @@ func testExtendCanonical(t *testing.T, full bool) { log.DebugLog()
 
 // Tests that given a starting canonical chain of a given size, creating shorter
 // forks do not take canonical ownership.
-func TestShorterForkHeaders(t *testing.T) { log.DebugLog() testShorterFork(t, false) }
-func TestShorterForkBlocks(t *testing.T)  { log.DebugLog() testShorterFork(t, true) }
+func TestShorterForkHeaders(t *testing.T) { log.DebugLog(); testShorterFork(t, false) }
+func TestShorterForkBlocks(t *testing.T)  { log.DebugLog(); testShorterFork(t, true) }
 
 func testShorterFork(t *testing.T, full bool) { log.DebugLog()
 	length := 10
@@ -238,8 +239,8 @@ func testShorterFork(t *testing.T, full bool) { log.DebugLog()
 
 // Tests that given a starting canonical chain of a given size, creating longer
 // forks do take canonical ownership.
-func TestLongerForkHeaders(t *testing.T) { log.DebugLog() testLongerFork(t, false) }
-func TestLongerForkBlocks(t *testing.T)  { log.DebugLog() testLongerFork(t, true) }
+func TestLongerForkHeaders(t *testing.T) { log.DebugLog(); testLongerFork(t, false) }
+func TestLongerForkBlocks(t *testing.T)  { log.DebugLog(); testLongerFork(t, true) }
 
 func testLongerFork(t *testing.T, full bool) { log.DebugLog()
 	length := 10
@@ -268,8 +269,8 @@ func testLongerFork(t *testing.T, full bool) { log.DebugLog()
 
 // Tests that given a starting canonical chain of a given size, creating equal
 // forks do take canonical ownership.
-func TestEqualForkHeaders(t *testing.T) { log.DebugLog() testEqualFork(t, false) }
-func TestEqualForkBlocks(t *testing.T)  { log.DebugLog() testEqualFork(t, true) }
+func TestEqualForkHeaders(t *testing.T) { log.DebugLog(); testEqualFork(t, false) }
+func TestEqualForkBlocks(t *testing.T)  { log.DebugLog(); testEqualFork(t, true) }
 
 func testEqualFork(t *testing.T, full bool) { log.DebugLog()
 	length := 10
@@ -297,8 +298,8 @@ func testEqualFork(t *testing.T, full bool) { log.DebugLog()
 }
 
 // Tests that chains missing links do not get accepted by the processor.
-func TestBrokenHeaderChain(t *testing.T) { log.DebugLog() testBrokenChain(t, false) }
-func TestBrokenBlockChain(t *testing.T)  { log.DebugLog() testBrokenChain(t, true) }
+func TestBrokenHeaderChain(t *testing.T) { log.DebugLog(); testBrokenChain(t, false) }
+func TestBrokenBlockChain(t *testing.T)  { log.DebugLog(); testBrokenChain(t, true) }
 
 func testBrokenChain(t *testing.T, full bool) { log.DebugLog()
 	// Make chain starting from genesis
@@ -324,8 +325,8 @@ func testBrokenChain(t *testing.T, full bool) { log.DebugLog()
 
 // Tests that reorganising a long difficult chain after a short easy one
 // overwrites the canonical numbers and links in the database.
-func TestReorgLongHeaders(t *testing.T) { log.DebugLog() testReorgLong(t, false) }
-func TestReorgLongBlocks(t *testing.T)  { log.DebugLog() testReorgLong(t, true) }
+func TestReorgLongHeaders(t *testing.T) { log.DebugLog(); testReorgLong(t, false) }
+func TestReorgLongBlocks(t *testing.T)  { log.DebugLog(); testReorgLong(t, true) }
 
 func testReorgLong(t *testing.T, full bool) { log.DebugLog()
 	testReorg(t, []int64{0, 0, -9}, []int64{0, 0, 0, -9}, 393280, full)
@@ -333,8 +334,8 @@ func testReorgLong(t *testing.T, full bool) { log.DebugLog()
 
 // Tests that reorganising a short difficult chain after a long easy one
 // overwrites the canonical numbers and links in the database.
-func TestReorgShortHeaders(t *testing.T) { log.DebugLog() testReorgShort(t, false) }
-func TestReorgShortBlocks(t *testing.T)  { log.DebugLog() testReorgShort(t, true) }
+func TestReorgShortHeaders(t *testing.T) { log.DebugLog(); testReorgShort(t, false) }
+func TestReorgShortBlocks(t *testing.T)  { log.DebugLog(); testReorgShort(t, true) }
 
 func testReorgShort(t *testing.T, full bool) { log.DebugLog()
 	// Create a long easy chain vs. a short heavy one. Due to difficulty adjustment
@@ -419,8 +420,8 @@ func testReorg(t *testing.T, first, second []int64, td int64, full bool) { log.D
 }
 
 // Tests that the insertion functions detect banned hashes.
-func TestBadHeaderHashes(t *testing.T) { log.DebugLog() testBadHashes(t, false) }
-func TestBadBlockHashes(t *testing.T)  { log.DebugLog() testBadHashes(t, true) }
+func TestBadHeaderHashes(t *testing.T) { log.DebugLog(); testBadHashes(t, false) }
+func TestBadBlockHashes(t *testing.T)  { log.DebugLog(); testBadHashes(t, true) }
 
 func testBadHashes(t *testing.T, full bool) { log.DebugLog()
 	// Create a pristine chain and database
@@ -453,8 +454,8 @@ func testBadHashes(t *testing.T, full bool) { log.DebugLog()
 
 // Tests that bad hashes are detected on boot, and the chain rolled back to a
 // good state prior to the bad hash.
-func TestReorgBadHeaderHashes(t *testing.T) { log.DebugLog() testReorgBadHashes(t, false) }
-func TestReorgBadBlockHashes(t *testing.T)  { log.DebugLog() testReorgBadHashes(t, true) }
+func TestReorgBadHeaderHashes(t *testing.T) { log.DebugLog(); testReorgBadHashes(t, false) }
+func TestReorgBadBlockHashes(t *testing.T)  { log.DebugLog(); testReorgBadHashes(t, true) }
 
 func testReorgBadHashes(t *testing.T, full bool) { log.DebugLog()
 	// Create a pristine chain and database
@@ -508,8 +509,8 @@ func testReorgBadHashes(t *testing.T, full bool) { log.DebugLog()
 }
 
 // Tests chain insertions in the face of one entity containing an invalid nonce.
-func TestHeadersInsertNonceError(t *testing.T) { log.DebugLog() testInsertNonceError(t, false) }
-func TestBlocksInsertNonceError(t *testing.T)  { log.DebugLog() testInsertNonceError(t, true) }
+func TestHeadersInsertNonceError(t *testing.T) { log.DebugLog(); testInsertNonceError(t, false) }
+func TestBlocksInsertNonceError(t *testing.T)  { log.DebugLog(); testInsertNonceError(t, true) }
 
 func testInsertNonceError(t *testing.T, full bool) { log.DebugLog()
 	for i := 1; i < 25 && !t.Failed(); i++ {
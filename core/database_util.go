@@ -165,6 +165,9 @@ func GetTrieSyncProgress(db DatabaseReader) uint64 { log.DebugLog()
 // if the header's not found.
 func GetHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue { log.DebugLog()
 	data, _ := db.Get(headerKey(hash, number))
+	if len(data) == 0 {
+		data = readAncient(db, freezerHeaderTable, hash, number)
+	}
 	return data
 }
 
@@ -186,6 +189,9 @@ func GetHeader(db DatabaseReader, hash common.Hash, number uint64) *types.Header
 // GetBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
 func GetBodyRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue { log.DebugLog()
 	data, _ := db.Get(blockBodyKey(hash, number))
+	if len(data) == 0 {
+		data = readAncient(db, freezerBodiesTable, hash, number)
+	}
 	return data
 }
 
@@ -193,10 +199,18 @@ func headerKey(hash common.Hash, number uint64) []byte { log.DebugLog()
 	return append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+func tdKey(hash common.Hash, number uint64) []byte { log.DebugLog()
+	return append(headerKey(hash, number), tdSuffix...)
+}
+
 func blockBodyKey(hash common.Hash, number uint64) []byte { log.DebugLog()
 	return append(append(bodyPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+func blockReceiptsKey(hash common.Hash, number uint64) []byte { log.DebugLog()
+	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // GetBody retrieves the block body (transactons, uncles) corresponding to the
 // hash, nil if none found.
 func GetBody(db DatabaseReader, hash common.Hash, number uint64) *types.Body { log.DebugLog()
@@ -215,7 +229,10 @@ func GetBody(db DatabaseReader, hash common.Hash, number uint64) *types.Body { l
 // GetTd retrieves a block's total difficulty corresponding to the hash, nil if
 // none found.
 func GetTd(db DatabaseReader, hash common.Hash, number uint64) *big.Int { log.DebugLog()
-	data, _ := db.Get(append(append(append(headerPrefix, encodeBlockNumber(number)...), hash[:]...), tdSuffix...))
+	data, _ := db.Get(tdKey(hash, number))
+	if len(data) == 0 {
+		data = readAncient(db, freezerDifficultyTable, hash, number)
+	}
 	if len(data) == 0 {
 		return nil
 	}
@@ -227,6 +244,22 @@ func GetTd(db DatabaseReader, hash common.Hash, number uint64) *big.Int { log.De
 	return td
 }
 
+// HasHeader verifies the existence of a block header corresponding to the hash.
+func HasHeader(db ethdb.Database, hash common.Hash, number uint64) bool { log.DebugLog()
+	if has, err := db.Has(headerKey(hash, number)); err == nil && has {
+		return true
+	}
+	return len(readAncient(db, freezerHashTable, hash, number)) > 0
+}
+
+// HasBody verifies the existence of a block body corresponding to the hash.
+func HasBody(db ethdb.Database, hash common.Hash, number uint64) bool { log.DebugLog()
+	if has, err := db.Has(blockBodyKey(hash, number)); err == nil && has {
+		return true
+	}
+	return len(readAncient(db, freezerHashTable, hash, number)) > 0
+}
+
 // GetBlock retrieves an entire block corresponding to the hash, assembling it
 // back from the stored header and body. If either the header or body could not
 // be retrieved nil is returned.
@@ -250,7 +283,10 @@ func GetBlock(db DatabaseReader, hash common.Hash, number uint64) *types.Block {
 // GetBlockReceipts retrieves the receipts generated by the transactions included
 // in a block given by its hash.
 func GetBlockReceipts(db DatabaseReader, hash common.Hash, number uint64) types.Receipts { log.DebugLog()
-	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	data, _ := db.Get(blockReceiptsKey(hash, number))
+	if len(data) == 0 {
+		data = readAncient(db, freezerReceiptTable, hash, number)
+	}
 	if len(data) == 0 {
 		return nil
 	}
@@ -0,0 +1,450 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// freezerHashTable indicates the name of the freezer canonical hash table.
+	freezerHashTable = "hashes"
+
+	// freezerHeaderTable indicates the name of the freezer header table.
+	freezerHeaderTable = "headers"
+
+	// freezerBodiesTable indicates the name of the freezer block body table.
+	freezerBodiesTable = "bodies"
+
+	// freezerReceiptTable indicates the name of the freezer receipts table.
+	freezerReceiptTable = "receipts"
+
+	// freezerDifficultyTable indicates the name of the freezer total difficulty table.
+	freezerDifficultyTable = "diffs"
+)
+
+const (
+	// DefaultFreezerThreshold is the number of blocks after which a chain segment
+	// is considered immutable (i.e. soft finality) and is moved into the freezer.
+	DefaultFreezerThreshold = 90000
+
+	// freezerRecheckInterval is the frequency to check the key-value database for
+	// chain progression that might permit new blocks to be frozen into immutable
+	// storage.
+	freezerRecheckInterval = time.Minute
+
+	// freezerBatchLimit is the maximum number of blocks to freeze in one batch
+	// before doing an fsync and deleting it from the key-value store.
+	freezerBatchLimit = 30000
+)
+
+// freezerNoSnappy configures whether compression is disabled for the ancient
+// tables. Hashes and difficulties don't compress well.
+var freezerNoSnappy = map[string]bool{
+	freezerHashTable:       true,
+	freezerHeaderTable:     false,
+	freezerBodiesTable:     false,
+	freezerReceiptTable:    false,
+	freezerDifficultyTable: true,
+}
+
+// errUnknownTable is returned if the user attempts to read from a table that is
+// not tracked by the freezer.
+var errUnknownTable = errors.New("unknown table")
+
+// AncientReader wraps the read methods of a backing immutable chain data store.
+type AncientReader interface {
+	// Ancient retrieves an ancient binary blob of the given kind from the
+	// append-only immutable files.
+	Ancient(kind string, number uint64) ([]byte, error)
+
+	// Ancients returns the number of items stored in the ancient store.
+	Ancients() uint64
+}
+
+// AncientWriter wraps the write methods of a backing immutable chain data store.
+type AncientWriter interface {
+	// TruncateAncients discards all but the first n ancient items.
+	TruncateAncients(n uint64) error
+}
+
+// freezer is an append-only database to store immutable chain data into flat
+// files:
+//
+// - The append only nature ensures that disk writes are minimized.
+// - The data is indexed by block number, so lookups need no database seeks.
+type freezer struct {
+	frozen uint64 // Number of blocks already frozen, atomically accessible
+
+	tables    map[string]*freezerTable // Data tables for storing everything
+	threshold uint64                   // Number of recent blocks to keep in the key-value store
+	lock      sync.Mutex               // Mutex serializing freezing and truncation
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newFreezer creates a chain freezer that moves ancient chain data into
+// append-only flat file containers.
+func newFreezer(datadir string, threshold uint64) (*freezer, error) {
+	log.DebugLog()
+	freezer := &freezer{
+		tables:    make(map[string]*freezerTable),
+		threshold: threshold,
+		quit:      make(chan struct{}),
+	}
+	for name, noSnappy := range freezerNoSnappy {
+		table, err := newFreezerTable(datadir, name, noSnappy)
+		if err != nil {
+			for _, table := range freezer.tables {
+				table.Close()
+			}
+			return nil, err
+		}
+		freezer.tables[name] = table
+	}
+	if err := freezer.repair(); err != nil {
+		freezer.close()
+		return nil, err
+	}
+	log.Info("Opened ancient database", "path", datadir, "frozen", freezer.frozen)
+	return freezer, nil
+}
+
+// repair truncates all data tables to the same length.
+func (f *freezer) repair() error {
+	log.DebugLog()
+	min := uint64(0xffffffffffffffff)
+	for _, table := range f.tables {
+		if items := table.Items(); items < min {
+			min = items
+		}
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(min); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, min)
+	return nil
+}
+
+// close terminates the chain freezer, closing all the data files.
+func (f *freezer) close() error {
+	log.DebugLog()
+	select {
+	case <-f.quit:
+	default:
+		close(f.quit)
+	}
+	f.wg.Wait()
+
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
+func (f *freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	log.DebugLog()
+	if table := f.tables[kind]; table != nil {
+		return table.Retrieve(number)
+	}
+	return nil, errUnknownTable
+}
+
+// Ancients returns the length of the frozen items.
+func (f *freezer) Ancients() uint64 {
+	log.DebugLog()
+	return atomic.LoadUint64(&f.frozen)
+}
+
+// appendAncient injects all binary blobs belonging to a block at the end of the
+// append-only immutable table files.
+func (f *freezer) appendAncient(number uint64, hash, header, body, receipts, td []byte) (err error) {
+	log.DebugLog()
+	// Ensure the binary blobs we are appending is continuous with freezer.
+	if atomic.LoadUint64(&f.frozen) != number {
+		return errOutOrderInsertion
+	}
+	// Rollback all inserted data if any insertion below failed to ensure
+	// the tables won't out of sync.
+	defer func() {
+		if err != nil {
+			f.repair()
+		}
+	}()
+	blobs := map[string][]byte{
+		freezerHashTable:       hash,
+		freezerHeaderTable:     header,
+		freezerBodiesTable:     body,
+		freezerReceiptTable:    receipts,
+		freezerDifficultyTable: td,
+	}
+	for name, blob := range blobs {
+		if err := f.tables[name].Append(number, blob); err != nil {
+			log.Error("Failed to append ancient item", "table", name, "number", number, "err", err)
+			return err
+		}
+	}
+	atomic.AddUint64(&f.frozen, 1) // Only modify atomically
+	return nil
+}
+
+// TruncateAncients discards any recent data exceeding the provided threshold
+// number, e.g. when the chain is rewound below the frozen segment.
+func (f *freezer) TruncateAncients(items uint64) error {
+	log.DebugLog()
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if atomic.LoadUint64(&f.frozen) <= items {
+		return nil
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	return nil
+}
+
+// sync flushes all data tables to disk.
+func (f *freezer) sync() error {
+	log.DebugLog()
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// freeze is a background thread that periodically checks the blockchain for any
+// import progress and moves ancient data from the fast database into the freezer.
+//
+// This functionality is deliberately broken off from block importing to avoid
+// incurring additional data shuffling delays on block propagation.
+func (f *freezer) freeze(db ethdb.Database) {
+	log.DebugLog()
+	defer f.wg.Done()
+
+	for {
+		backoff := true
+		if err := f.freezeBatch(db, &backoff); err != nil {
+			log.Error("Failed to freeze ancient blocks", "err", err)
+		}
+		if backoff {
+			select {
+			case <-time.NewTimer(freezerRecheckInterval).C:
+			case <-f.quit:
+				return
+			}
+			continue
+		}
+		select {
+		case <-f.quit:
+			return
+		default:
+		}
+	}
+}
+
+// freezeBatch moves a batch of blocks, old enough to be considered immutable,
+// from the key-value store into the freezer. If a full batch was moved, backoff
+// is cleared to signal that more blocks might be waiting.
+func (f *freezer) freezeBatch(db ethdb.Database, backoff *bool) error {
+	log.DebugLog()
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// Retrieve the freezing threshold, skipping if the chain is too short
+	hash := GetHeadBlockHash(db)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	number := GetBlockNumber(db, hash)
+	if number == missingNumber {
+		return fmt.Errorf("current full block number missing: %x", hash)
+	}
+	frozen := atomic.LoadUint64(&f.frozen)
+	if number <= f.threshold || number-f.threshold <= frozen {
+		return nil
+	}
+	limit := number - f.threshold
+	if limit-frozen > freezerBatchLimit {
+		limit = frozen + freezerBatchLimit
+		*backoff = false
+	}
+	// Copy the canonical chain segment into the freezer
+	var (
+		start    = time.Now()
+		first    = frozen
+		ancients = make([]common.Hash, 0, limit-frozen)
+	)
+	for frozen < limit {
+		hash := GetCanonicalHash(db, frozen)
+		if hash == (common.Hash{}) {
+			return fmt.Errorf("canonical hash missing, can't freeze block %d", frozen)
+		}
+		header, _ := db.Get(headerKey(hash, frozen))
+		if len(header) == 0 {
+			return fmt.Errorf("block header missing, can't freeze block %d", frozen)
+		}
+		body, _ := db.Get(blockBodyKey(hash, frozen))
+		if len(body) == 0 {
+			return fmt.Errorf("block body missing, can't freeze block %d", frozen)
+		}
+		receipts, _ := db.Get(blockReceiptsKey(hash, frozen))
+		if len(receipts) == 0 {
+			return fmt.Errorf("block receipts missing, can't freeze block %d", frozen)
+		}
+		td, _ := db.Get(tdKey(hash, frozen))
+		if len(td) == 0 {
+			return fmt.Errorf("total difficulty missing, can't freeze block %d", frozen)
+		}
+		if err := f.appendAncient(frozen, hash[:], header, body, receipts, td); err != nil {
+			return err
+		}
+		ancients = append(ancients, hash)
+		frozen++
+	}
+	// Batch of blocks have been frozen, flush them before wiping from the
+	// key-value store
+	if err := f.sync(); err != nil {
+		log.Crit("Failed to flush frozen tables", "err", err)
+	}
+	// Wipe out all data from the active database, including side chains
+	batch := db.NewBatch()
+	for i := range ancients {
+		number := first + uint64(i)
+
+		it := db.NewIteratorWithPrefix(append(headerPrefix, encodeBlockNumber(number)...))
+		for it.Next() {
+			// Keep the canonical number to hash mappings for lookups
+			if key := it.Key(); len(key) != len(headerPrefix)+8+len(numSuffix) {
+				batch.Delete(common.CopyBytes(key))
+			}
+		}
+		it.Release()
+
+		for _, prefix := range [][]byte{bodyPrefix, blockReceiptsPrefix} {
+			it := db.NewIteratorWithPrefix(append(prefix, encodeBlockNumber(number)...))
+			for it.Next() {
+				batch.Delete(common.CopyBytes(it.Key()))
+			}
+			it.Release()
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to delete frozen blocks", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete frozen blocks", "err", err)
+	}
+	context := []interface{}{
+		"blocks", frozen - first, "elapsed", common.PrettyDuration(time.Since(start)), "number", frozen - 1,
+	}
+	if n := len(ancients); n > 0 {
+		context = append(context, []interface{}{"hash", ancients[n-1]}...)
+	}
+	log.Info("Deep froze chain segment", context...)
+	return nil
+}
+
+// freezerDatabase is a database wrapper that enables ancient chain segment
+// freezing, serving frozen chain data transparently through AncientReader.
+type freezerDatabase struct {
+	ethdb.Database
+	*freezer
+}
+
+// NewDatabaseWithFreezer wraps a key-value database with an ancient store into
+// which the canonical chain data older than threshold blocks is moved in the
+// background. The accessors in this package (e.g. GetHeader, GetBody and
+// GetBlockReceipts) transparently fall back to the ancient store.
+func NewDatabaseWithFreezer(db ethdb.Database, freezer string, threshold uint64) (ethdb.Database, error) {
+	log.DebugLog()
+	frdb, err := newFreezer(freezer, threshold)
+	if err != nil {
+		return nil, err
+	}
+	// Ensure the key-value store didn't get rewound below the freezer (e.g. a
+	// chain rewind was interrupted before the ancients were truncated)
+	if frozen := frdb.Ancients(); frozen > 0 {
+		if hash := GetCanonicalHash(db, frozen-1); hash == (common.Hash{}) {
+			frdb.close()
+			return nil, fmt.Errorf("ancient chain segment of %d blocks is not canonical", frozen)
+		} else if stored, err := frdb.Ancient(freezerHashTable, frozen-1); err != nil || common.BytesToHash(stored) != hash {
+			frdb.close()
+			return nil, fmt.Errorf("ancient chain segment mismatch at block %d: have %x, want %x", frozen-1, stored, hash)
+		}
+	}
+	frdb.wg.Add(1)
+	go frdb.freeze(db)
+
+	return &freezerDatabase{Database: db, freezer: frdb}, nil
+}
+
+// Close stops the freezer and closes both the ancient and key-value stores.
+func (db *freezerDatabase) Close() {
+	log.DebugLog()
+	if err := db.freezer.close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+	db.Database.Close()
+}
+
+// readAncient retrieves an item of the given kind from the ancient store backing
+// the database, provided the frozen block with the given number has the expected
+// hash. Nil is returned if the database has no ancient store or the item is not
+// frozen.
+func readAncient(db DatabaseReader, kind string, hash common.Hash, number uint64) []byte {
+	log.DebugLog()
+	reader, ok := db.(AncientReader)
+	if !ok || number >= reader.Ancients() {
+		return nil
+	}
+	if stored, _ := reader.Ancient(freezerHashTable, number); common.BytesToHash(stored) != hash {
+		return nil
+	}
+	data, _ := reader.Ancient(kind, number)
+	return data
+}
@@ -0,0 +1,288 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/golang/snappy"
+)
+
+var (
+	// errClosed is returned if an operation attempts to read from or write to the
+	// freezer table after it has already been closed.
+	errClosed = errors.New("closed")
+
+	// errOutOfBounds is returned if the item requested is not contained within the
+	// freezer table.
+	errOutOfBounds = errors.New("out of bounds")
+
+	// errOutOrderInsertion is returned if the user attempts to inject out-of-order
+	// items into the freezer table.
+	errOutOrderInsertion = errors.New("the append operation is out-order")
+)
+
+// indexEntrySize is the size of a single index entry: the big endian uint64 end
+// offset of an item within the data file.
+const indexEntrySize = 8
+
+// freezerTable is an append-only flat file store for a single kind of data. Items
+// are stored back to back in a data file, with an index file containing the end
+// offset of every item, so that item N can be located with two index reads.
+type freezerTable struct {
+	items    uint64 // Number of items stored in the table, atomically accessible
+	noSnappy bool   // Whether the items are stored raw instead of snappy compressed
+
+	data   *os.File // File descriptor of the item data
+	index  *os.File // File descriptor of the item end offsets
+	offset uint64   // Current size of the data file
+
+	lock sync.RWMutex // Mutex protecting the files and the offset
+	log  log.Logger   // Contextual logger tracking the table name
+}
+
+// newFreezerTable opens the given path as a freezer table, repairing any
+// inconsistencies between the data and index files left by a crash.
+func newFreezerTable(path string, name string, noSnappy bool) (*freezerTable, error) {
+	log.DebugLog()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	ext := "cdat"
+	if noSnappy {
+		ext = "rdat"
+	}
+	data, err := os.OpenFile(filepath.Join(path, fmt.Sprintf("%s.%s", name, ext)), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	index, err := os.OpenFile(filepath.Join(path, fmt.Sprintf("%s.ridx", name)), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+	tab := &freezerTable{
+		noSnappy: noSnappy,
+		data:     data,
+		index:    index,
+		log:      log.New("table", name),
+	}
+	if err := tab.repair(); err != nil {
+		tab.Close()
+		return nil, err
+	}
+	return tab, nil
+}
+
+// repair cross checks the data and index files, truncating them to the last
+// item fully present in both.
+func (t *freezerTable) repair() error {
+	log.DebugLog()
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	items := uint64(stat.Size()) / indexEntrySize
+
+	if stat, err = t.data.Stat(); err != nil {
+		return err
+	}
+	size := uint64(stat.Size())
+
+	// Drop any index entries pointing past the end of the data file
+	for ; items > 0; items-- {
+		end, err := t.indexEntry(items - 1)
+		if err != nil {
+			return err
+		}
+		if end <= size {
+			t.offset = end
+			break
+		}
+	}
+	if items == 0 {
+		t.offset = 0
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if size > t.offset {
+		t.log.Warn("Truncating dangling freezer data", "size", size, "valid", t.offset)
+	}
+	if err := t.data.Truncate(int64(t.offset)); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&t.items, items)
+	return nil
+}
+
+// indexEntry reads the end offset of the given item from the index file.
+func (t *freezerTable) indexEntry(item uint64) (uint64, error) {
+	log.DebugLog()
+	var buf [indexEntrySize]byte
+	if _, err := t.index.ReadAt(buf[:], int64(item*indexEntrySize)); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+// Items returns the number of items stored in the table.
+func (t *freezerTable) Items() uint64 {
+	log.DebugLog()
+	return atomic.LoadUint64(&t.items)
+}
+
+// Append injects a binary blob at the end of the freezer table. The item number
+// must be the next one in sequence, otherwise the insertion is rejected. The
+// write is not synced to disk, call Sync for that.
+func (t *freezerTable) Append(item uint64, blob []byte) error {
+	log.DebugLog()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	if item != atomic.LoadUint64(&t.items) {
+		return errOutOrderInsertion
+	}
+	if !t.noSnappy {
+		blob = snappy.Encode(nil, blob)
+	}
+	if _, err := t.data.WriteAt(blob, int64(t.offset)); err != nil {
+		return err
+	}
+	var entry [indexEntrySize]byte
+	binary.BigEndian.PutUint64(entry[:], t.offset+uint64(len(blob)))
+	if _, err := t.index.WriteAt(entry[:], int64(item*indexEntrySize)); err != nil {
+		return err
+	}
+	t.offset += uint64(len(blob))
+	atomic.AddUint64(&t.items, 1)
+	return nil
+}
+
+// Retrieve looks up the data offset of an item and returns its decompressed
+// content.
+func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
+	log.DebugLog()
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil {
+		return nil, errClosed
+	}
+	if item >= atomic.LoadUint64(&t.items) {
+		return nil, errOutOfBounds
+	}
+	var start uint64
+	if item > 0 {
+		var err error
+		if start, err = t.indexEntry(item - 1); err != nil {
+			return nil, err
+		}
+	}
+	end, err := t.indexEntry(item)
+	if err != nil {
+		return nil, err
+	}
+	blob := make([]byte, end-start)
+	if _, err := t.data.ReadAt(blob, int64(start)); err != nil {
+		return nil, err
+	}
+	if t.noSnappy {
+		return blob, nil
+	}
+	return snappy.Decode(nil, blob)
+}
+
+// truncate discards any items above the provided limit.
+func (t *freezerTable) truncate(items uint64) error {
+	log.DebugLog()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	if atomic.LoadUint64(&t.items) <= items {
+		return nil
+	}
+	var offset uint64
+	if items > 0 {
+		var err error
+		if offset, err = t.indexEntry(items - 1); err != nil {
+			return err
+		}
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(offset)); err != nil {
+		return err
+	}
+	t.offset = offset
+	atomic.StoreUint64(&t.items, items)
+	return nil
+}
+
+// Sync pushes any pending data from memory out to disk.
+func (t *freezerTable) Sync() error {
+	log.DebugLog()
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	if err := t.data.Sync(); err != nil {
+		return err
+	}
+	return t.index.Sync()
+}
+
+// Close closes all opened files.
+func (t *freezerTable) Close() error {
+	log.DebugLog()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errs []error
+	if t.index != nil {
+		if err := t.index.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		t.index = nil
+	}
+	if t.data != nil {
+		if err := t.data.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		t.data = nil
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
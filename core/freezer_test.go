@@ -0,0 +1,218 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that items can be appended to and retrieved from a freezer table, and
+// that they survive a reopen.
+func TestFreezerTableBasics(t *testing.T) { log.DebugLog()
+	for _, noSnappy := range []bool{false, true} {
+		dir, err := ioutil.TempDir("", "freezer")
+		if err != nil {
+			t.Fatalf("failed to create temporary directory: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		table, err := newFreezerTable(dir, "test", noSnappy)
+		if err != nil {
+			t.Fatalf("failed to create table: %v", err)
+		}
+		for i := uint64(0); i < 255; i++ {
+			if err := table.Append(i, bytes.Repeat([]byte{byte(i)}, int(i))); err != nil {
+				t.Fatalf("failed to append item %d: %v", i, err)
+			}
+		}
+		if err := table.Append(300, []byte{0x01}); err != errOutOrderInsertion {
+			t.Fatalf("out of order insertion error mismatch: have %v, want %v", err, errOutOrderInsertion)
+		}
+		table.Close()
+
+		if table, err = newFreezerTable(dir, "test", noSnappy); err != nil {
+			t.Fatalf("failed to reopen table: %v", err)
+		}
+		if items := table.Items(); items != 255 {
+			t.Fatalf("item count mismatch: have %d, want %d", items, 255)
+		}
+		for i := uint64(0); i < 255; i++ {
+			blob, err := table.Retrieve(i)
+			if err != nil {
+				t.Fatalf("failed to retrieve item %d: %v", i, err)
+			}
+			if want := bytes.Repeat([]byte{byte(i)}, int(i)); !bytes.Equal(blob, want) {
+				t.Fatalf("item %d mismatch: have %x, want %x", i, blob, want)
+			}
+		}
+		if _, err := table.Retrieve(255); err != errOutOfBounds {
+			t.Fatalf("out of bounds error mismatch: have %v, want %v", err, errOutOfBounds)
+		}
+		table.Close()
+	}
+}
+
+// Tests that a freezer table with a torn write (data written, but index only
+// partially) is repaired on open, and that truncation drops the tail items.
+func TestFreezerTableRepairAndTruncate(t *testing.T) { log.DebugLog()
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	table, err := newFreezerTable(dir, "test", true)
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	for i := uint64(0); i < 10; i++ {
+		table.Append(i, []byte(fmt.Sprintf("item-%d", i)))
+	}
+	table.Close()
+
+	// Chop off half of the last index entry and append garbage to the data
+	index := filepath.Join(dir, "test.ridx")
+	stat, _ := os.Stat(index)
+	os.Truncate(index, stat.Size()-indexEntrySize/2)
+
+	data, _ := os.OpenFile(filepath.Join(dir, "test.rdat"), os.O_WRONLY|os.O_APPEND, 0644)
+	data.Write([]byte("garbage"))
+	data.Close()
+
+	if table, err = newFreezerTable(dir, "test", true); err != nil {
+		t.Fatalf("failed to reopen table: %v", err)
+	}
+	defer table.Close()
+
+	if items := table.Items(); items != 9 {
+		t.Fatalf("item count mismatch after repair: have %d, want %d", items, 9)
+	}
+	if err := table.Append(9, []byte("item-9")); err != nil {
+		t.Fatalf("failed to append after repair: %v", err)
+	}
+	if blob, _ := table.Retrieve(9); string(blob) != "item-9" {
+		t.Fatalf("item mismatch after repair: have %q", blob)
+	}
+	if err := table.truncate(5); err != nil {
+		t.Fatalf("failed to truncate table: %v", err)
+	}
+	if _, err := table.Retrieve(5); err != errOutOfBounds {
+		t.Fatalf("truncated item retrievable: %v", err)
+	}
+	if blob, _ := table.Retrieve(4); string(blob) != "item-4" {
+		t.Fatalf("item mismatch after truncation: have %q", blob)
+	}
+}
+
+// Tests that ancient blocks are moved into the freezer, transparently served by
+// the database accessors, and truncated when the chain is rewound.
+func TestFreezerChainMigration(t *testing.T) { log.DebugLog()
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000000000)}},
+		}
+		signer = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	kvdb, _ := ethdb.NewMemDatabase()
+	db, err := NewDatabaseWithFreezer(kvdb, dir, 8)
+	if err != nil {
+		t.Fatalf("failed to create freezer database: %v", err)
+	}
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 20, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(address), common.Address{0x01}, big.NewInt(1), params.TxGas, nil, nil), signer, key)
+		gen.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	frdb := db.(*freezerDatabase)
+	backoff := true
+	if err := frdb.freezer.freezeBatch(kvdb, &backoff); err != nil {
+		t.Fatalf("failed to freeze blocks: %v", err)
+	}
+	if frozen := frdb.Ancients(); frozen != 12 {
+		t.Fatalf("frozen block count mismatch: have %d, want %d", frozen, 12)
+	}
+	// Ensure frozen data is gone from the key-value store, but still served
+	for _, block := range append([]*types.Block{genesis}, blocks...) {
+		hash, number := block.Hash(), block.NumberU64()
+
+		if has, _ := kvdb.Has(headerKey(hash, number)); has != (number >= 12) {
+			t.Errorf("block %d: header presence in key-value store mismatch: have %v", number, has)
+		}
+		if header := GetHeader(db, hash, number); header == nil || header.Hash() != hash {
+			t.Errorf("block %d: header mismatch: have %v", number, header)
+		}
+		if body := GetBody(db, hash, number); body == nil || types.DeriveSha(types.Transactions(body.Transactions)) != block.TxHash() {
+			t.Errorf("block %d: body mismatch", number)
+		}
+		if td := GetTd(db, hash, number); td == nil {
+			t.Errorf("block %d: total difficulty missing", number)
+		}
+		if receipts := GetBlockReceipts(db, hash, number); len(receipts) != len(block.Transactions()) {
+			t.Errorf("block %d: receipt count mismatch: have %d, want %d", number, len(receipts), len(block.Transactions()))
+		}
+		if !chain.HasBlock(hash, number) {
+			t.Errorf("block %d: not reported present", number)
+		}
+	}
+	// Rewind the chain into the frozen segment and ensure the ancients follow
+	if err := chain.SetHead(5); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if frozen := frdb.Ancients(); frozen != 6 {
+		t.Fatalf("frozen block count mismatch after rewind: have %d, want %d", frozen, 6)
+	}
+	if header := GetHeader(db, blocks[6].Hash(), 7); header != nil {
+		t.Fatalf("rewound header still retrievable")
+	}
+	if header := GetHeader(db, blocks[4].Hash(), 5); header == nil {
+		t.Fatalf("retained header missing")
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"testing"
 
 	checker "gopkg.in/check.v1"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
-func Test(t *testing.T) { log.DebugLog() checker.TestingT(t) }
+func Test(t *testing.T) { log.DebugLog(); checker.TestingT(t) }
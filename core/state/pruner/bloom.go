@@ -0,0 +1,175 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// stateBloomHashes is the number of bits set in the filter for every key.
+const stateBloomHashes = 4
+
+// stateBloomMagic is the file header identifying a persisted state bloom.
+var stateBloomMagic = []byte("statebloom-v1")
+
+// errBloomCorrupted is returned if a persisted state bloom fails the integrity
+// checks when being loaded from disk.
+var errBloomCorrupted = errors.New("state bloom corrupted")
+
+// stateBloom is a bloom filter used during state pruning to track all the
+// trie nodes and contract codes that must be retained. False positives are
+// fine (some garbage survives), false negatives are impossible.
+//
+// Since every tracked key is a keccak256 hash, the key itself is uniformly
+// distributed and the filter indices are derived from its bytes directly
+// instead of rehashing it.
+type stateBloom struct {
+	bits []byte
+}
+
+// newStateBloomWithSize creates a state bloom filter with the given size in
+// megabytes.
+func newStateBloomWithSize(size uint64) *stateBloom {
+	log.DebugLog()
+	return &stateBloom{bits: make([]byte, size*1024*1024)}
+}
+
+// newStateBloomFromDisk loads a previously committed state bloom filter.
+func newStateBloomFromDisk(filename string) (*stateBloom, common.Hash, error) {
+	log.DebugLog()
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	header := make([]byte, len(stateBloomMagic)+common.HashLength+8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, common.Hash{}, err
+	}
+	if string(header[:len(stateBloomMagic)]) != string(stateBloomMagic) {
+		return nil, common.Hash{}, errBloomCorrupted
+	}
+	root := common.BytesToHash(header[len(stateBloomMagic) : len(stateBloomMagic)+common.HashLength])
+	size := binary.BigEndian.Uint64(header[len(stateBloomMagic)+common.HashLength:])
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	if uint64(stat.Size()) != uint64(len(header))+size+4 {
+		return nil, common.Hash{}, errBloomCorrupted
+	}
+	bloom := &stateBloom{bits: make([]byte, size)}
+	if _, err := io.ReadFull(r, bloom.bits); err != nil {
+		return nil, common.Hash{}, err
+	}
+	var checksum [4]byte
+	if _, err := io.ReadFull(r, checksum[:]); err != nil {
+		return nil, common.Hash{}, err
+	}
+	if binary.BigEndian.Uint32(checksum[:]) != crc32.ChecksumIEEE(bloom.bits) {
+		return nil, common.Hash{}, errBloomCorrupted
+	}
+	return bloom, root, nil
+}
+
+// Commit flushes the bloom filter content into the disk, tagged with the state
+// root it was generated for. The filter is first written into a temporary file
+// which is atomically moved in place, so a crash can never leave a partially
+// written filter behind.
+func (bloom *stateBloom) Commit(filename, tempname string, root common.Hash) error {
+	log.DebugLog()
+	f, err := os.OpenFile(tempname, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+
+	var size [8]byte
+	binary.BigEndian.PutUint64(size[:], uint64(len(bloom.bits)))
+
+	var checksum [4]byte
+	binary.BigEndian.PutUint32(checksum[:], crc32.ChecksumIEEE(bloom.bits))
+
+	for _, blob := range [][]byte{stateBloomMagic, root[:], size[:], bloom.bits, checksum[:]} {
+		if _, err := w.Write(blob); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempname, filename)
+}
+
+// positions calculates the bit indices a key maps to in the filter.
+func (bloom *stateBloom) positions(key []byte) [stateBloomHashes]uint64 {
+	log.DebugLog()
+	if len(key) != common.HashLength {
+		panic(fmt.Sprintf("invalid state bloom key length %d", len(key)))
+	}
+	var (
+		bits = uint64(len(bloom.bits)) * 8
+		h1   = binary.BigEndian.Uint64(key[0:8])
+		h2   = binary.BigEndian.Uint64(key[8:16]) | 1
+		pos  [stateBloomHashes]uint64
+	)
+	for i := range pos {
+		pos[i] = (h1 + uint64(i)*h2) % bits
+	}
+	return pos
+}
+
+// Put adds a key (trie node or contract code hash) to the filter.
+func (bloom *stateBloom) Put(key []byte) {
+	log.DebugLog()
+	for _, pos := range bloom.positions(key) {
+		bloom.bits[pos/8] |= 1 << (pos % 8)
+	}
+}
+
+// Contain reports whether the key might be in the filter. A negative answer
+// is definitive.
+func (bloom *stateBloom) Contain(key []byte) bool {
+	log.DebugLog()
+	for _, pos := range bloom.positions(key) {
+		if bloom.bits[pos/8]&(1<<(pos%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,348 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements offline pruning of stale state trie nodes and
+// contract codes from the chain database.
+package pruner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// stateBloomFileName is the filename of the state bloom filter persisted
+	// between the marking and the sweeping phase of pruning.
+	stateBloomFileName = "statebloom.bf"
+
+	// stateBloomFileTempSuffix is the suffix of the temporary file the bloom
+	// filter is written into before being moved in place.
+	stateBloomFileTempSuffix = ".tmp"
+
+	// DefaultRecentStates is the default number of recent blocks whose states
+	// are retained (if present on disk) in addition to the pruning target.
+	DefaultRecentStates = 128
+
+	// minBloomSize is the minimal allowed size of the state bloom in megabytes.
+	minBloomSize = 256
+
+	// logInterval is the time between progress reports.
+	logInterval = 8 * time.Second
+)
+
+var (
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256(nil)
+
+	// errNoHeadBlock is returned if the database has no head block to prune
+	// the state around.
+	errNoHeadBlock = errors.New("head block missing")
+)
+
+// Pruner is an offline tool to prune the stale state trie nodes and contract
+// codes accumulated in the database. Pruning runs in two phases:
+//
+//   - Marking: every node and code reachable from the target state root and the
+//     recent state roots available on disk is added to a bloom filter, which is
+//     then persisted next to the database.
+//   - Sweeping: every state entry in the database not contained in the bloom
+//     filter is deleted.
+//
+// If the process is interrupted during the sweep, it can be resumed with the
+// committed bloom filter via RecoverPruning. Since false positives of the bloom
+// filter only leave some garbage behind, pruning is always safe.
+type Pruner struct {
+	db         ethdb.Database
+	stateBloom *stateBloom // Allocated when marking starts, not needed for resuming
+	bloomSize  uint64      // Size of the state bloom in megabytes
+	recents    uint64      // Number of recent block states retained
+	datadir    string
+	headHeader *types.Header
+}
+
+// NewPruner creates a state pruner operating on the given database, storing its
+// intermediate state bloom within datadir. The bloom size is in megabytes, and
+// the states of the last recents blocks still present on disk are retained.
+func NewPruner(db ethdb.Database, datadir string, bloomSize uint64, recents uint64) (*Pruner, error) {
+	log.DebugLog()
+	head := core.GetHeadBlockHash(db)
+	if head == (common.Hash{}) {
+		return nil, errNoHeadBlock
+	}
+	header := core.GetHeader(db, head, core.GetBlockNumber(db, head))
+	if header == nil {
+		return nil, errNoHeadBlock
+	}
+	if bloomSize < minBloomSize {
+		log.Warn("Sanitizing bloomfilter size", "provided(MB)", bloomSize, "updated(MB)", minBloomSize)
+		bloomSize = minBloomSize
+	}
+	return &Pruner{
+		db:         db,
+		bloomSize:  bloomSize,
+		recents:    recents,
+		datadir:    datadir,
+		headHeader: header,
+	}, nil
+}
+
+// Prune deletes all the state data not reachable from the target root or any of
+// the recent states still present on disk. If the target root is empty, the
+// head block's state root is used. If a previous pruning was interrupted, it
+// is finished instead with the state bloom committed back then.
+func (p *Pruner) Prune(root common.Hash) error {
+	log.DebugLog()
+	if filename, _ := findBloomFilter(p.datadir); filename != "" {
+		log.Info("Resuming interrupted state pruning", "bloom", filename)
+		return RecoverPruning(p.datadir, p.db)
+	}
+	if root == (common.Hash{}) {
+		root = p.headHeader.Root
+	}
+	if ok, _ := p.db.Has(root.Bytes()); !ok {
+		return fmt.Errorf("state %x is not available", root)
+	}
+	// Collect the recent states still present on disk, they're retained too
+	roots := []common.Hash{root}
+	for header := p.headHeader; header != nil && p.headHeader.Number.Uint64()-header.Number.Uint64() < p.recents; {
+		if header.Root != root {
+			if ok, _ := p.db.Has(header.Root.Bytes()); ok {
+				roots = append(roots, header.Root)
+			}
+		}
+		if header.Number.Uint64() == 0 {
+			break
+		}
+		header = core.GetHeader(p.db, header.ParentHash, header.Number.Uint64()-1)
+	}
+	// Mark all the live state into the bloom filter and persist it
+	if p.stateBloom == nil {
+		p.stateBloom = newStateBloomWithSize(p.bloomSize)
+	}
+	start := time.Now()
+	triedb := state.NewDatabase(p.db).TrieDB()
+	for i, r := range roots {
+		var base common.Hash
+		if i > 0 {
+			base = roots[0]
+		}
+		if err := markState(triedb, p.stateBloom, r, base); err != nil {
+			return err
+		}
+	}
+	filename := filepath.Join(p.datadir, stateBloomFileName)
+	if err := p.stateBloom.Commit(filename, filename+stateBloomFileTempSuffix, root); err != nil {
+		return err
+	}
+	log.Info("Committed state bloom filter", "roots", len(roots), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	return prune(p.db, p.stateBloom, filename, start)
+}
+
+// RecoverPruning finishes a previously interrupted pruning run, if any. It must
+// be called before any new state is written into the database, otherwise the
+// new entries, being absent from the stale bloom filter, would be deleted.
+func RecoverPruning(datadir string, db ethdb.Database) error {
+	log.DebugLog()
+	filename, err := findBloomFilter(datadir)
+	if err != nil {
+		return err
+	}
+	if filename == "" {
+		return nil
+	}
+	bloom, root, err := newStateBloomFromDisk(filename)
+	if err != nil {
+		return err
+	}
+	log.Info("Loaded state bloom filter", "root", root, "path", filename)
+	return prune(db, bloom, filename, time.Now())
+}
+
+// findBloomFilter looks for a committed state bloom filter in the data directory,
+// discarding any leftover temporary file from an interrupted commit.
+func findBloomFilter(datadir string) (string, error) {
+	log.DebugLog()
+	if datadir == "" {
+		return "", nil
+	}
+	filename := filepath.Join(datadir, stateBloomFileName)
+	if err := os.Remove(filename + stateBloomFileTempSuffix); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if _, err := os.Stat(filename); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return filename, nil
+}
+
+// markState adds all the trie nodes and contract codes reachable from the state
+// root to the bloom filter. If a base root is given, only the parts of the
+// account trie differing from it are traversed, the shared subtries having been
+// marked already.
+func markState(triedb *trie.Database, bloom *stateBloom, root common.Hash, base common.Hash) error {
+	log.DebugLog()
+	t, err := trie.New(root, triedb)
+	if err != nil {
+		return err
+	}
+	it := t.NodeIterator(nil)
+	if base != (common.Hash{}) {
+		bt, err := trie.New(base, triedb)
+		if err != nil {
+			return err
+		}
+		it, _ = trie.NewDifferenceIterator(bt.NodeIterator(nil), it)
+	}
+	var (
+		nodes  int
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for it.Next(true) {
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			bloom.Put(hash.Bytes())
+			nodes++
+		}
+		if it.Leaf() {
+			var account state.Account
+			if err := rlp.DecodeBytes(it.LeafBlob(), &account); err != nil {
+				return err
+			}
+			if account.Root != types.EmptyRootHash {
+				st, err := trie.New(account.Root, triedb)
+				if err != nil {
+					return err
+				}
+				sit := st.NodeIterator(nil)
+				for sit.Next(true) {
+					if hash := sit.Hash(); hash != (common.Hash{}) {
+						bloom.Put(hash.Bytes())
+						nodes++
+					}
+				}
+				if sit.Error() != nil {
+					return sit.Error()
+				}
+			}
+			if !bytes.Equal(account.CodeHash, emptyCode) {
+				bloom.Put(account.CodeHash)
+				nodes++
+			}
+		}
+		if time.Since(logged) > logInterval {
+			log.Info("Marking live state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if it.Error() != nil {
+		return it.Error()
+	}
+	log.Info("Marked live state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// prune sweeps the database, deleting every state entry not contained in the
+// bloom filter. Once done, the bloom filter file is removed and the database
+// compacted to actually reclaim the freed space.
+func prune(db ethdb.Database, bloom *stateBloom, filename string, start time.Time) error {
+	log.DebugLog()
+	var (
+		count  int
+		size   common.StorageSize
+		pstart = time.Now()
+		logged = time.Now()
+		batch  = db.NewBatch()
+		iter   = db.NewIterator()
+	)
+	for iter.Next() {
+		key := iter.Key()
+
+		// Trie nodes and contract codes are both stored under their bare hash
+		if len(key) != common.HashLength || bloom.Contain(key) {
+			continue
+		}
+		size += common.StorageSize(len(key) + len(iter.Value()))
+		if err := batch.Delete(key); err != nil {
+			iter.Release()
+			return err
+		}
+		count++
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				iter.Release()
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > logInterval {
+			// Keys are iterated in order, so the position in the keyspace is
+			// a good enough approximation of the progress
+			var eta time.Duration
+			if done := binary.BigEndian.Uint64(key[:8]); done > 0 {
+				progress := float64(done) / math.MaxUint64
+				elapsed := time.Since(pstart)
+				eta = time.Duration(float64(elapsed)/progress) - elapsed
+			}
+			log.Info("Pruning state data", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(pstart)), "eta", common.PrettyDuration(eta))
+			logged = time.Now()
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if batch.ValueSize() > 0 {
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	log.Info("Pruned state data", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(pstart)))
+
+	// Pruning is done, the bloom filter is not needed any more. Remove it before
+	// compacting so an interruption from here on doesn't trigger a resweep.
+	if err := os.Remove(filename); err != nil {
+		return err
+	}
+	cstart := time.Now()
+	log.Info("Compacting database", "release", size)
+	if err := db.Compact(nil, nil); err != nil {
+		return err
+	}
+	log.Info("Database compaction finished", "elapsed", common.PrettyDuration(time.Since(cstart)))
+	log.Info("State pruning successful", "pruned", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,232 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// makeTestChain creates a database with two committed states: a stale one
+// referenced by the genesis header only, and a live one referenced by a chain
+// of the given number of headers on top of it.
+func makeTestChain(t *testing.T, headers int64) (*ethdb.MemDatabase, common.Hash, common.Hash) { log.DebugLog()
+	db, _ := ethdb.NewMemDatabase()
+	sdb := state.NewDatabase(db)
+
+	statedb, _ := state.New(common.Hash{}, sdb)
+	for i := byte(0); i < 64; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.AddBalance(addr, big.NewInt(int64(i)+1))
+		statedb.SetState(addr, common.Hash{i}, common.Hash{i, i})
+		if i%4 == 0 {
+			statedb.SetCode(addr, []byte{i, i, i})
+		}
+	}
+	stale, _ := statedb.Commit(false)
+	if err := sdb.TrieDB().Commit(stale, false); err != nil {
+		t.Fatalf("failed to commit stale state: %v", err)
+	}
+	statedb, _ = state.New(stale, sdb)
+	for i := byte(0); i < 64; i += 2 {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.SetState(addr, common.Hash{i}, common.Hash{i, i, i})
+		statedb.SetCode(addr, []byte{i, i, i, i})
+	}
+	live, _ := statedb.Commit(false)
+	if err := sdb.TrieDB().Commit(live, false); err != nil {
+		t.Fatalf("failed to commit live state: %v", err)
+	}
+	// Assemble a header chain over the two states
+	parent := &types.Header{Number: big.NewInt(0), Root: stale, Difficulty: big.NewInt(1)}
+	core.WriteHeader(db, parent)
+	core.WriteCanonicalHash(db, parent.Hash(), 0)
+	for i := int64(1); i <= headers; i++ {
+		header := &types.Header{Number: big.NewInt(i), ParentHash: parent.Hash(), Root: live, Difficulty: big.NewInt(1)}
+		core.WriteHeader(db, header)
+		core.WriteCanonicalHash(db, header.Hash(), uint64(i))
+		parent = header
+	}
+	core.WriteHeadBlockHash(db, parent.Hash())
+	return db, stale, live
+}
+
+// checkPruned verifies that the live state is fully intact and the stale root
+// is gone from the database.
+func checkPruned(t *testing.T, db ethdb.Database, stale, live common.Hash) { log.DebugLog()
+	statedb, err := state.New(live, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open live state: %v", err)
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		t.Fatalf("live state corrupted: %v", it.Error)
+	}
+	if ok, _ := db.Has(stale.Bytes()); ok {
+		t.Fatalf("stale state root %x not pruned", stale)
+	}
+}
+
+// Tests that pruning removes the stale state while retaining the head one.
+func TestPruneState(t *testing.T) { log.DebugLog()
+	dir, err := ioutil.TempDir("", "pruner")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, stale, live := makeTestChain(t, DefaultRecentStates+2)
+	before := len(db.Keys())
+
+	pruner, err := NewPruner(db, dir, 0, DefaultRecentStates)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if pruner.stateBloom != nil {
+		t.Fatalf("state bloom allocated before marking")
+	}
+	pruner.stateBloom = newStateBloomWithSize(1) // Don't waste memory on the test
+	if err := pruner.Prune(common.Hash{}); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	checkPruned(t, db, stale, live)
+	if after := len(db.Keys()); after >= before {
+		t.Errorf("database not shrunk: before %d, after %d", before, after)
+	}
+	if _, err := os.Stat(filepath.Join(dir, stateBloomFileName)); !os.IsNotExist(err) {
+		t.Errorf("state bloom not removed after pruning: %v", err)
+	}
+}
+
+// Tests that the states of the configured number of recent blocks are retained.
+func TestPruneRecentStates(t *testing.T) { log.DebugLog()
+	for _, tt := range []struct {
+		recents  uint64
+		retained bool
+	}{
+		{recents: 2, retained: false},
+		{recents: 5, retained: true},
+	} {
+		dir, err := ioutil.TempDir("", "pruner")
+		if err != nil {
+			t.Fatalf("failed to create temporary directory: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		// The stale genesis state is 4 blocks behind the head
+		db, stale, live := makeTestChain(t, 4)
+
+		pruner, err := NewPruner(db, dir, 0, tt.recents)
+		if err != nil {
+			t.Fatalf("recents %d: failed to create pruner: %v", tt.recents, err)
+		}
+		pruner.stateBloom = newStateBloomWithSize(1)
+		if err := pruner.Prune(common.Hash{}); err != nil {
+			t.Fatalf("recents %d: failed to prune state: %v", tt.recents, err)
+		}
+		if !tt.retained {
+			checkPruned(t, db, stale, live)
+			continue
+		}
+		if _, err := state.New(stale, state.NewDatabase(db)); err != nil {
+			t.Errorf("recents %d: recent state pruned: %v", tt.recents, err)
+		}
+	}
+}
+
+// Tests that an interrupted pruning is finished from the committed bloom filter.
+func TestRecoverPruning(t *testing.T) { log.DebugLog()
+	dir, err := ioutil.TempDir("", "pruner")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, stale, live := makeTestChain(t, DefaultRecentStates+2)
+
+	// Mark the live state and commit the bloom, but crash before the sweep
+	bloom := newStateBloomWithSize(1)
+	if err := markState(state.NewDatabase(db).TrieDB(), bloom, live, common.Hash{}); err != nil {
+		t.Fatalf("failed to mark live state: %v", err)
+	}
+	filename := filepath.Join(dir, stateBloomFileName)
+	if err := bloom.Commit(filename, filename+stateBloomFileTempSuffix, live); err != nil {
+		t.Fatalf("failed to commit state bloom: %v", err)
+	}
+	// Leave a dangling temporary file around too, it must be discarded
+	if err := ioutil.WriteFile(filename+stateBloomFileTempSuffix, []byte("junk"), 0644); err != nil {
+		t.Fatalf("failed to write temporary bloom: %v", err)
+	}
+	if err := RecoverPruning(dir, db); err != nil {
+		t.Fatalf("failed to recover pruning: %v", err)
+	}
+	checkPruned(t, db, stale, live)
+
+	for _, name := range []string{filename, filename + stateBloomFileTempSuffix} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("bloom file %s not removed: %v", name, err)
+		}
+	}
+	// A second recovery without a bloom filter must be a noop
+	if err := RecoverPruning(dir, db); err != nil {
+		t.Fatalf("failed to rerun recovery: %v", err)
+	}
+}
+
+// Tests that a corrupted bloom filter is rejected instead of being used.
+func TestStateBloomCorruption(t *testing.T) { log.DebugLog()
+	dir, err := ioutil.TempDir("", "pruner")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	bloom := newStateBloomWithSize(1)
+	key := common.HexToHash("0xdeadbeef00000000000000000000000000000000000000000000000000000001")
+	bloom.Put(key.Bytes())
+
+	filename := filepath.Join(dir, stateBloomFileName)
+	if err := bloom.Commit(filename, filename+stateBloomFileTempSuffix, common.Hash{1}); err != nil {
+		t.Fatalf("failed to commit state bloom: %v", err)
+	}
+	loaded, root, err := newStateBloomFromDisk(filename)
+	if err != nil {
+		t.Fatalf("failed to load state bloom: %v", err)
+	}
+	if root != (common.Hash{1}) || !loaded.Contain(key.Bytes()) {
+		t.Fatalf("loaded state bloom mismatch")
+	}
+	blob, _ := ioutil.ReadFile(filename)
+	blob[len(blob)/2] ^= 0xff
+	ioutil.WriteFile(filename, blob, 0644)
+
+	if _, _, err := newStateBloomFromDisk(filename); err != errBloomCorrupted {
+		t.Fatalf("corruption not detected: have %v, want %v", err, errBloomCorrupted)
+	}
+}
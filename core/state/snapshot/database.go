@@ -0,0 +1,131 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// The fields below define the low level database schema of the snapshot.
+var (
+	snapshotRootKey      = []byte("SnapshotRoot")      // snapshotRootKey tracks the state root of the persisted snapshot
+	snapshotGeneratorKey = []byte("SnapshotGenerator") // snapshotGeneratorKey tracks the progress of an unfinished generation
+
+	snapshotAccountPrefix = []byte("a") // snapshotAccountPrefix + account hash -> account trie value
+	snapshotStoragePrefix = []byte("o") // snapshotStoragePrefix + account hash + storage hash -> storage trie value
+)
+
+// accountSnapshotKey = snapshotAccountPrefix + hash
+func accountSnapshotKey(hash common.Hash) []byte {
+	log.DebugLog()
+	return append(append([]byte{}, snapshotAccountPrefix...), hash.Bytes()...)
+}
+
+// storageSnapshotKey = snapshotStoragePrefix + account hash + storage hash
+func storageSnapshotKey(accountHash, storageHash common.Hash) []byte {
+	log.DebugLog()
+	return append(append(append([]byte{}, snapshotStoragePrefix...), accountHash.Bytes()...), storageHash.Bytes()...)
+}
+
+// storageSnapshotsKey = snapshotStoragePrefix + account hash
+func storageSnapshotsKey(accountHash common.Hash) []byte {
+	log.DebugLog()
+	return append(append([]byte{}, snapshotStoragePrefix...), accountHash.Bytes()...)
+}
+
+// readSnapshotRoot retrieves the root of the persisted snapshot, or an empty
+// hash if there's none (or it's being modified).
+func readSnapshotRoot(db ethdb.Database) common.Hash {
+	log.DebugLog()
+	data, _ := db.Get(snapshotRootKey)
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// writeSnapshotRoot stores the root of the persisted snapshot.
+func writeSnapshotRoot(db ethdb.Putter, root common.Hash) {
+	log.DebugLog()
+	if err := db.Put(snapshotRootKey, root.Bytes()); err != nil {
+		log.Crit("Failed to store snapshot root", "err", err)
+	}
+}
+
+// readSnapshotGenerator retrieves the marker of an unfinished snapshot generation.
+// The boolean reports whether there's one at all.
+func readSnapshotGenerator(db ethdb.Database) ([]byte, bool) {
+	log.DebugLog()
+	if ok, _ := db.Has(snapshotGeneratorKey); !ok {
+		return nil, false
+	}
+	marker, _ := db.Get(snapshotGeneratorKey)
+	return common.CopyBytes(marker), true
+}
+
+// writeSnapshotGenerator stores the marker of an unfinished snapshot generation.
+func writeSnapshotGenerator(db ethdb.Putter, marker []byte) {
+	log.DebugLog()
+	if err := db.Put(snapshotGeneratorKey, marker); err != nil {
+		log.Crit("Failed to store snapshot generator", "err", err)
+	}
+}
+
+// wipeSnapshot deletes all the snapshot data and metadata from the database.
+func wipeSnapshot(db ethdb.Database) error {
+	log.DebugLog()
+	if err := db.Delete(snapshotRootKey); err != nil {
+		return err
+	}
+	if err := db.Delete(snapshotGeneratorKey); err != nil {
+		return err
+	}
+	if err := wipeKeyRange(db, snapshotAccountPrefix, snapshotAccountPrefix, len(snapshotAccountPrefix)+common.HashLength); err != nil {
+		return err
+	}
+	return wipeKeyRange(db, snapshotStoragePrefix, snapshotStoragePrefix, len(snapshotStoragePrefix)+2*common.HashLength)
+}
+
+// wipeKeyRange deletes all the keys of the given length, starting at the origin
+// and sharing the given prefix. The length filter is essential, since the single
+// byte snapshot prefixes overlap with the hash keyed trie nodes and contract code.
+func wipeKeyRange(db ethdb.Database, prefix []byte, origin []byte, keylen int) error {
+	log.DebugLog()
+	var (
+		batch = db.NewBatch()
+		it    = db.NewIteratorWithRange(origin, []byte{prefix[0] + 1})
+	)
+	defer it.Release()
+
+	for it.Next() {
+		if key := it.Key(); len(key) == keylen {
+			batch.Delete(common.CopyBytes(key))
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return err
+				}
+				batch.Reset()
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
@@ -0,0 +1,217 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// diffLayer represents a collection of modifications made to a state snapshot
+// after running a block on top. It contains one map for the account trie and
+// one map for each modified storage trie.
+//
+// The goal of a diff layer is to act as a journal, tracking recent modifications
+// made to the state, that have not yet graduated into a semi-immutable state.
+type diffLayer struct {
+	parent snapshot    // Parent snapshot modified by this one, never nil
+	root   common.Hash // Root hash to which this snapshot diff belongs to
+	stale  uint32      // Signals that the layer became stale (state progressed)
+
+	destructSet map[common.Hash]struct{}               // Keyed markers for deleted (and potentially) recreated accounts
+	accountData map[common.Hash][]byte                 // Keyed accounts for direct retrieval (nil means deleted)
+	storageData map[common.Hash]map[common.Hash][]byte // Keyed storage slots for direct retrieval. one per account (nil means deleted)
+
+	lock sync.RWMutex
+}
+
+// newDiffLayer creates a new diff on top of an existing snapshot, whether that's
+// a low level persistent database or a hierarchical diff already.
+func newDiffLayer(parent snapshot, root common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer {
+	log.DebugLog()
+	if destructs == nil {
+		destructs = make(map[common.Hash]struct{})
+	}
+	if accounts == nil {
+		accounts = make(map[common.Hash][]byte)
+	}
+	if storage == nil {
+		storage = make(map[common.Hash]map[common.Hash][]byte)
+	}
+	return &diffLayer{
+		parent:      parent,
+		root:        root,
+		destructSet: destructs,
+		accountData: accounts,
+		storageData: storage,
+	}
+}
+
+// Root returns the root hash for which this snapshot was made.
+func (dl *diffLayer) Root() common.Hash {
+	log.DebugLog()
+	return dl.root
+}
+
+// Parent returns the subsequent layer of a diff layer.
+func (dl *diffLayer) Parent() snapshot {
+	log.DebugLog()
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.parent
+}
+
+// Stale return whether this layer has become stale (was flattened across) or if
+// it's still live.
+func (dl *diffLayer) Stale() bool {
+	log.DebugLog()
+	return atomic.LoadUint32(&dl.stale) != 0
+}
+
+// markStale flags the layer as stale, failing any subsequent reads. It returns
+// whether the layer was already stale before.
+func (dl *diffLayer) markStale() bool {
+	log.DebugLog()
+	return atomic.SwapUint32(&dl.stale, 1) != 0
+}
+
+// Account directly retrieves the account associated with a particular hash in
+// the snapshot.
+func (dl *diffLayer) Account(hash common.Hash) (*Account, error) {
+	log.DebugLog()
+	data, err := dl.AccountRLP(hash)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAccount(data)
+}
+
+// AccountRLP directly retrieves the account RLP associated with a particular
+// hash in the snapshot, falling back to the parent layers if it wasn't modified
+// by this one.
+func (dl *diffLayer) AccountRLP(hash common.Hash) ([]byte, error) {
+	log.DebugLog()
+	dl.lock.RLock()
+	// If the layer was flattened into, consider it invalid (any live reference to
+	// the original should be marked as unusable).
+	if dl.Stale() {
+		dl.lock.RUnlock()
+		return nil, ErrSnapshotStale
+	}
+	// If the account is known locally, return it
+	if data, ok := dl.accountData[hash]; ok {
+		dl.lock.RUnlock()
+		return data, nil
+	}
+	// If the account is known locally, but deleted, return it
+	if _, ok := dl.destructSet[hash]; ok {
+		dl.lock.RUnlock()
+		return nil, nil
+	}
+	parent := dl.parent
+	dl.lock.RUnlock()
+
+	// Account unknown to this diff, resolve from parent
+	return parent.AccountRLP(hash)
+}
+
+// Storage directly retrieves the storage data associated with a particular hash,
+// within a particular account, falling back to the parent layers if it wasn't
+// modified by this one.
+func (dl *diffLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	log.DebugLog()
+	dl.lock.RLock()
+	if dl.Stale() {
+		dl.lock.RUnlock()
+		return nil, ErrSnapshotStale
+	}
+	// If the account is known locally, try to resolve the slot locally
+	if storage, ok := dl.storageData[accountHash]; ok {
+		if data, ok := storage[storageHash]; ok {
+			dl.lock.RUnlock()
+			return data, nil
+		}
+	}
+	// If the account is known locally, but deleted, return an empty slot
+	if _, ok := dl.destructSet[accountHash]; ok {
+		dl.lock.RUnlock()
+		return nil, nil
+	}
+	parent := dl.parent
+	dl.lock.RUnlock()
+
+	// Storage slot unknown to this diff, resolve from parent
+	return parent.Storage(accountHash, storageHash)
+}
+
+// flatten pushes all data from this point downwards, flattening everything into
+// a single diff at the bottom. Since usually the lowermost diff is the largest,
+// the flattening builds up from there in reverse. All the parent layers merged
+// into are marked stale, the layer itself is left for the caller to invalidate.
+func (dl *diffLayer) flatten() snapshot {
+	log.DebugLog()
+	// If the parent is not diff, we're the first in line, return unmodified
+	parent, ok := dl.parent.(*diffLayer)
+	if !ok {
+		return dl
+	}
+	// Invalidate the parent before touching its content. Holding the write lock
+	// ensures no reader is midway through accessing it. If the parent was stale
+	// already, someone else flattened into it from a different child, boo.
+	parent.lock.Lock()
+	stale := parent.markStale()
+	parent.lock.Unlock()
+
+	if stale {
+		panic("parent diff layer is stale")
+	}
+	// Parent is a diff, flatten it first (note, apart from weird corner cases,
+	// flatten will realistically only ever merge 1 layer, so there's no need to
+	// be smarter about grouping flattens together).
+	merged := parent.flatten().(*diffLayer)
+
+	for hash := range dl.destructSet {
+		merged.destructSet[hash] = struct{}{}
+		delete(merged.accountData, hash)
+		delete(merged.storageData, hash)
+	}
+	for hash, data := range dl.accountData {
+		merged.accountData[hash] = data
+	}
+	for accountHash, storage := range dl.storageData {
+		comboData, ok := merged.storageData[accountHash]
+		if !ok {
+			comboData = make(map[common.Hash][]byte, len(storage))
+			merged.storageData[accountHash] = comboData
+		}
+		for storageHash, data := range storage {
+			comboData[storageHash] = data
+		}
+	}
+	// Return the combo parent
+	return &diffLayer{
+		parent:      merged.parent,
+		root:        dl.root,
+		destructSet: merged.destructSet,
+		accountData: merged.accountData,
+		storageData: merged.storageData,
+	}
+}
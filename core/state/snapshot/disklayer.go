@@ -0,0 +1,290 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/hashicorp/golang-lru"
+)
+
+// cacheItemSize is a rough estimate of the memory used by a cached snapshot
+// entry, used to convert the cache allowance into an item count.
+const cacheItemSize = 128
+
+// newCache creates the read cache of a disk layer with the given allowance in
+// megabytes.
+func newCache(size int) *lru.Cache {
+	log.DebugLog()
+	items := size * 1024 * 1024 / cacheItemSize
+	if items < 1 {
+		items = 1
+	}
+	cache, _ := lru.New(items)
+	return cache
+}
+
+// diskLayer is a low level persistent snapshot built on top of a key-value store.
+type diskLayer struct {
+	diskdb ethdb.Database // Key-value store containing the base snapshot
+	triedb *trie.Database // Trie node cache for reconstruction purposes
+	cache  *lru.Cache     // Cache to avoid hitting the disk for direct access
+
+	root  common.Hash // Root hash of the base snapshot
+	stale bool        // Signals that the layer became stale (state progressed)
+
+	genMarker []byte           // Marker for the state that's indexed during initial layer generation
+	genAbort  chan chan []byte // Notification channel to abort generating the snapshot in this layer
+
+	lock sync.RWMutex
+}
+
+// newDiskLayer creates a disk layer for the given root. A nil generator marker
+// means the layer is fully generated.
+func newDiskLayer(diskdb ethdb.Database, triedb *trie.Database, cache *lru.Cache, root common.Hash, marker []byte) *diskLayer {
+	log.DebugLog()
+	return &diskLayer{
+		diskdb:    diskdb,
+		triedb:    triedb,
+		cache:     cache,
+		root:      root,
+		genMarker: marker,
+	}
+}
+
+// Root returns  root hash for which this snapshot was made.
+func (dl *diskLayer) Root() common.Hash {
+	log.DebugLog()
+	return dl.root
+}
+
+// Parent always returns nil as there's no layer below the disk.
+func (dl *diskLayer) Parent() snapshot {
+	log.DebugLog()
+	return nil
+}
+
+// Stale return whether this layer has become stale (was flattened across) or if
+// it's still live.
+func (dl *diskLayer) Stale() bool {
+	log.DebugLog()
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// markStale flags the layer as stale, failing any subsequent reads.
+func (dl *diskLayer) markStale() {
+	log.DebugLog()
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.stale = true
+}
+
+// covered reports whether the given snapshot key (account hash, or account hash
+// and storage hash concatenated) was already processed by the generator.
+func covered(marker []byte, key []byte) bool {
+	log.DebugLog()
+	return marker == nil || bytes.Compare(key, marker) <= 0
+}
+
+// Account directly retrieves the account associated with a particular hash in
+// the snapshot.
+func (dl *diskLayer) Account(hash common.Hash) (*Account, error) {
+	log.DebugLog()
+	data, err := dl.AccountRLP(hash)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAccount(data)
+}
+
+// AccountRLP directly retrieves the account RLP associated with a particular
+// hash in the snapshot.
+func (dl *diskLayer) AccountRLP(hash common.Hash) ([]byte, error) {
+	log.DebugLog()
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	// If the layer was flattened into, consider it invalid (any live reference to
+	// the original should be marked as unusable).
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	// If the layer is being generated, ensure the requested hash has already been
+	// covered by the generator.
+	if !covered(dl.genMarker, hash[:]) {
+		return nil, ErrNotCoveredYet
+	}
+	return dl.get(accountSnapshotKey(hash))
+}
+
+// Storage directly retrieves the storage data associated with a particular hash,
+// within a particular account.
+func (dl *diskLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	log.DebugLog()
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	key := storageSnapshotKey(accountHash, storageHash)
+	if !covered(dl.genMarker, key[len(snapshotStoragePrefix):]) {
+		return nil, ErrNotCoveredYet
+	}
+	return dl.get(key)
+}
+
+// get retrieves a snapshot entry from the cache, falling back to the database.
+// Missing entries are cached too, as an empty blob.
+func (dl *diskLayer) get(key []byte) ([]byte, error) {
+	log.DebugLog()
+	if blob, found := dl.cache.Get(string(key)); found {
+		return blob.([]byte), nil
+	}
+	var blob []byte
+	if ok, err := dl.diskdb.Has(key); err != nil {
+		return nil, err
+	} else if ok {
+		if blob, err = dl.diskdb.Get(key); err != nil {
+			return nil, err
+		}
+	}
+	dl.cache.Add(string(key), blob)
+	return blob, nil
+}
+
+// startGeneration starts a background generator filling the snapshot from the
+// layer's state trie, beginning after the current marker.
+func (dl *diskLayer) startGeneration() {
+	log.DebugLog()
+	dl.genAbort = make(chan chan []byte)
+	go dl.generate(common.CopyBytes(dl.genMarker))
+}
+
+// stopGeneration aborts the background generator if one's running, and returns
+// the marker up to which the snapshot was generated (nil if done).
+func (dl *diskLayer) stopGeneration() []byte {
+	log.DebugLog()
+	if dl.genAbort == nil {
+		return dl.genMarker
+	}
+	abort := make(chan []byte)
+	dl.genAbort <- abort
+	marker := <-abort
+
+	dl.genAbort = nil
+	return marker
+}
+
+// diffToDisk merges a bottom-most diff into the persistent disk layer underneath
+// it. The method will panic if called onto a non-bottom-most diff layer.
+func diffToDisk(bottom *diffLayer) *diskLayer {
+	log.DebugLog()
+	base := bottom.parent.(*diskLayer)
+
+	// Pause any running generator, items beyond its marker are left for it
+	marker := base.stopGeneration()
+	base.markStale()
+
+	// Invalidate the persisted root first, so a crash midway is detected
+	if err := base.diskdb.Delete(snapshotRootKey); err != nil {
+		log.Crit("Failed to remove snapshot root", "err", err)
+	}
+	batch := base.diskdb.NewBatch()
+	flush := func(force bool) {
+		if batch.ValueSize() >= ethdb.IdealBatchSize || (force && batch.ValueSize() > 0) {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to write state changes", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	// Destructed accounts need their whole storage removed, do that first so
+	// a resurrected account can write its new slots afterwards
+	for hash := range bottom.destructSet {
+		if !covered(marker, hash[:]) {
+			continue
+		}
+		key := accountSnapshotKey(hash)
+		batch.Delete(key)
+		base.cache.Remove(string(key))
+
+		it := base.diskdb.NewIteratorWithPrefix(storageSnapshotsKey(hash))
+		for it.Next() {
+			if len(it.Key()) != len(snapshotStoragePrefix)+2*common.HashLength {
+				continue
+			}
+			key := common.CopyBytes(it.Key())
+			batch.Delete(key)
+			base.cache.Remove(string(key))
+			flush(false)
+		}
+		it.Release()
+		flush(false)
+	}
+	for hash, data := range bottom.accountData {
+		if !covered(marker, hash[:]) {
+			continue
+		}
+		key := accountSnapshotKey(hash)
+		if len(data) > 0 {
+			batch.Put(key, data)
+		} else {
+			batch.Delete(key)
+		}
+		base.cache.Add(string(key), data)
+		flush(false)
+	}
+	for accountHash, storage := range bottom.storageData {
+		for storageHash, data := range storage {
+			key := storageSnapshotKey(accountHash, storageHash)
+			if !covered(marker, key[len(snapshotStoragePrefix):]) {
+				continue
+			}
+			if len(data) > 0 {
+				batch.Put(key, data)
+			} else {
+				batch.Delete(key)
+			}
+			base.cache.Add(string(key), data)
+			flush(false)
+		}
+	}
+	// Update the snapshot metadata and flush everything to disk
+	if marker != nil {
+		writeSnapshotGenerator(batch, marker)
+	} else {
+		batch.Delete(snapshotGeneratorKey)
+	}
+	writeSnapshotRoot(batch, bottom.root)
+	flush(true)
+
+	res := newDiskLayer(base.diskdb, base.triedb, base.cache, bottom.root, marker)
+	if marker != nil {
+		res.startGeneration()
+	}
+	return res
+}
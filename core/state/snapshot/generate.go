@@ -0,0 +1,253 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// generatorLogInterval is the time between generation progress reports.
+const generatorLogInterval = 8 * time.Second
+
+// generatorStats is a collection of statistics gathered by the snapshot generator
+// for logging purposes.
+type generatorStats struct {
+	accounts uint64             // Number of accounts indexed
+	slots    uint64             // Number of storage slots indexed
+	storage  common.StorageSize // Account and storage slot size
+	start    time.Time          // Timestamp when generation started
+	logged   time.Time          // Timestamp when stats were last logged
+}
+
+// log creates an contextual log with the given message and the context pulled
+// from the internally maintained statistics.
+func (gs *generatorStats) log(msg string, root common.Hash, marker []byte) {
+	log.DebugLog()
+	ctx := []interface{}{
+		"root", root, "accounts", gs.accounts, "slots", gs.slots,
+		"storage", gs.storage, "elapsed", common.PrettyDuration(time.Since(gs.start)),
+	}
+	if len(marker) > 0 {
+		ctx = append(ctx, "at", common.BytesToHash(marker[:common.HashLength]))
+	}
+	log.Info(msg, ctx...)
+	gs.logged = time.Now()
+}
+
+// markerAfter returns the generator marker covering everything up to and
+// including the given account and all its storage slots.
+func markerAfter(accountHash common.Hash) []byte {
+	log.DebugLog()
+	return append(accountHash.Bytes(), bytes.Repeat([]byte{0xff}, common.HashLength)...)
+}
+
+// generate is a background thread that iterates over the state and storage tries,
+// constructing the state snapshot. All the arguments are purely for statistics
+// gathering and logging, since the method surfs the blocks as they arrive, often
+// being restarted.
+func (dl *diskLayer) generate(marker []byte) {
+	log.DebugLog()
+	stats := &generatorStats{start: time.Now(), logged: time.Now()}
+
+	// Anything beyond the marker is a leftover of an interrupted run, drop it
+	if err := dl.wipeAfter(marker); err != nil {
+		dl.fail(marker, stats, err)
+		return
+	}
+	accTrie, err := trie.New(dl.root, dl.triedb)
+	if err != nil {
+		dl.fail(marker, stats, err)
+		return
+	}
+	var start []byte
+	if len(marker) > 0 {
+		start = incHash(common.BytesToHash(marker[:common.HashLength]))
+		if start == nil {
+			dl.finish(stats) // Marker at the very end of the keyspace
+			return
+		}
+	}
+	var (
+		batch = dl.diskdb.NewBatch()
+		it    = trie.NewIterator(accTrie.NodeIterator(start))
+	)
+	// commit flushes the generated data and progress marker into the database,
+	// and publishes the new marker for readers.
+	commit := func(marker []byte) error {
+		writeSnapshotGenerator(batch, marker)
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+
+		dl.lock.Lock()
+		dl.genMarker = marker
+		dl.lock.Unlock()
+		return nil
+	}
+	// aborted checks whether the generator was requested to stop, in which case
+	// it persists its progress and reports back the marker.
+	aborted := func() bool {
+		select {
+		case abort := <-dl.genAbort:
+			if err := commit(marker); err != nil {
+				log.Error("Failed to persist snapshot generator", "err", err)
+			}
+			stats.log("Aborting state snapshot generation", dl.root, marker)
+			abort <- marker
+			return true
+		default:
+			return false
+		}
+	}
+	for it.Next() {
+		accountHash := common.BytesToHash(it.Key)
+
+		var acc Account
+		if err := rlp.DecodeBytes(it.Value, &acc); err != nil {
+			dl.fail(marker, stats, err)
+			return
+		}
+		batch.Put(accountSnapshotKey(accountHash), it.Value)
+		stats.storage += common.StorageSize(1 + common.HashLength + len(it.Value))
+		stats.accounts++
+
+		// If the account has storage, index all of it too
+		if acc.Root != types.EmptyRootHash {
+			storeTrie, err := trie.New(acc.Root, dl.triedb)
+			if err != nil {
+				dl.fail(marker, stats, err)
+				return
+			}
+			storeIt := trie.NewIterator(storeTrie.NodeIterator(nil))
+			for storeIt.Next() {
+				batch.Put(storageSnapshotKey(accountHash, common.BytesToHash(storeIt.Key)), storeIt.Value)
+				stats.storage += common.StorageSize(1 + 2*common.HashLength + len(storeIt.Value))
+				stats.slots++
+
+				// Flush large storage tries midway, without advancing the marker
+				if batch.ValueSize() > ethdb.IdealBatchSize {
+					if err := commit(marker); err != nil {
+						dl.fail(marker, stats, err)
+						return
+					}
+					if aborted() {
+						return
+					}
+				}
+			}
+			if storeIt.Err != nil {
+				dl.fail(marker, stats, storeIt.Err)
+				return
+			}
+		}
+		marker = markerAfter(accountHash)
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := commit(marker); err != nil {
+				dl.fail(marker, stats, err)
+				return
+			}
+		}
+		if aborted() {
+			return
+		}
+		if time.Since(stats.logged) > generatorLogInterval {
+			stats.log("Generating state snapshot", dl.root, marker)
+		}
+	}
+	if it.Err != nil {
+		dl.fail(marker, stats, it.Err)
+		return
+	}
+	if err := batch.Write(); err != nil {
+		dl.fail(marker, stats, err)
+		return
+	}
+	dl.finish(stats)
+}
+
+// finish marks the snapshot generation complete, then waits for the layer to be
+// invalidated, reporting back a nil marker.
+func (dl *diskLayer) finish(stats *generatorStats) {
+	log.DebugLog()
+	if err := dl.diskdb.Delete(snapshotGeneratorKey); err != nil {
+		log.Error("Failed to remove snapshot generator", "err", err)
+	}
+	dl.lock.Lock()
+	dl.genMarker = nil
+	dl.lock.Unlock()
+
+	stats.log("Generated state snapshot", dl.root, nil)
+
+	abort := <-dl.genAbort
+	abort <- nil
+}
+
+// fail aborts the snapshot generation due to an error (most probably missing
+// trie nodes), leaving everything beyond the marker to be served by the trie.
+// The generation is retried when the layer is next replaced.
+func (dl *diskLayer) fail(marker []byte, stats *generatorStats, err error) {
+	log.DebugLog()
+	stats.log(fmt.Sprintf("State snapshot generation failed: %v", err), dl.root, marker)
+
+	abort := <-dl.genAbort
+	abort <- marker
+}
+
+// wipeAfter deletes all the snapshot entries not covered by the marker.
+func (dl *diskLayer) wipeAfter(marker []byte) error {
+	log.DebugLog()
+	// The first keys beyond the marker are the marker itself suffixed with zero,
+	// or the whole prefix if nothing was generated yet
+	after := func(prefix []byte, marker []byte) []byte {
+		if len(marker) == 0 {
+			return prefix
+		}
+		return append(append(append([]byte{}, prefix...), marker...), 0x00)
+	}
+	var account []byte
+	if len(marker) > 0 {
+		account = marker[:common.HashLength]
+	}
+	if err := wipeKeyRange(dl.diskdb, snapshotAccountPrefix, after(snapshotAccountPrefix, account), len(snapshotAccountPrefix)+common.HashLength); err != nil {
+		return err
+	}
+	return wipeKeyRange(dl.diskdb, snapshotStoragePrefix, after(snapshotStoragePrefix, marker), len(snapshotStoragePrefix)+2*common.HashLength)
+}
+
+// incHash returns the next hash in lexicographical order, or nil if the given
+// one is the last possible.
+func incHash(h common.Hash) []byte {
+	log.DebugLog()
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			return h.Bytes()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeTestState creates a state trie with a number of accounts, every third of
+// which has a few storage slots too. It returns the flat content it expects the
+// snapshot to contain.
+func makeTestState(t *testing.T) (*ethdb.MemDatabase, *trie.Database, common.Hash, map[string][]byte) { log.DebugLog()
+	db, _ := ethdb.NewMemDatabase()
+	triedb := trie.NewDatabase(db)
+	flat := make(map[string][]byte)
+
+	accTrie, _ := trie.New(common.Hash{}, triedb)
+	for i := 0; i < 100; i++ {
+		accHash := crypto.Keccak256Hash([]byte{byte(i)})
+		acc := Account{Nonce: uint64(i), Balance: big.NewInt(int64(i)), Root: types.EmptyRootHash, CodeHash: crypto.Keccak256(nil)}
+
+		if i%3 == 0 {
+			storeTrie, _ := trie.New(common.Hash{}, triedb)
+			for j := 1; j <= 10; j++ {
+				slotHash := crypto.Keccak256Hash([]byte{byte(i), byte(j)})
+				value, _ := rlp.EncodeToBytes([]byte{byte(j)})
+				storeTrie.Update(slotHash[:], value)
+				flat[string(storageSnapshotKey(accHash, slotHash))] = value
+			}
+			root, err := storeTrie.Commit(nil)
+			if err != nil {
+				t.Fatalf("failed to commit storage trie: %v", err)
+			}
+			if err := triedb.Commit(root, false); err != nil {
+				t.Fatalf("failed to flush storage trie: %v", err)
+			}
+			acc.Root = root
+		}
+		blob, _ := rlp.EncodeToBytes(&acc)
+		accTrie.Update(accHash[:], blob)
+		flat[string(accountSnapshotKey(accHash))] = blob
+	}
+	root, err := accTrie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit account trie: %v", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to flush tries: %v", err)
+	}
+	return db, triedb, root, flat
+}
+
+// waitGeneration blocks until the disk layer of the tree finishes generating.
+func waitGeneration(t *testing.T, snaps *Tree, root common.Hash) *diskLayer { log.DebugLog()
+	base := snaps.Snapshot(root).(*diskLayer)
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		base.lock.RLock()
+		done := base.genMarker == nil
+		base.lock.RUnlock()
+		if done {
+			return base
+		}
+	}
+	t.Fatalf("snapshot generation timed out at %x", base.genMarker)
+	return nil
+}
+
+// checkSnapshot verifies that the database contains exactly the expected flat
+// snapshot entries and that they're served by the disk layer.
+func checkSnapshot(t *testing.T, db *ethdb.MemDatabase, base *diskLayer, flat map[string][]byte) { log.DebugLog()
+	found := 0
+	for _, key := range db.Keys() {
+		if !bytes.HasPrefix(key, snapshotAccountPrefix) && !bytes.HasPrefix(key, snapshotStoragePrefix) {
+			continue
+		}
+		if len(key) != len(accountSnapshotKey(common.Hash{})) && len(key) != len(storageSnapshotKey(common.Hash{}, common.Hash{})) {
+			continue
+		}
+		want, ok := flat[string(key)]
+		if !ok {
+			t.Errorf("dangling snapshot entry %x", key)
+			continue
+		}
+		if have, _ := db.Get(key); !bytes.Equal(have, want) {
+			t.Errorf("snapshot entry %x mismatch: have %x, want %x", key, have, want)
+		}
+		found++
+	}
+	if found != len(flat) {
+		t.Errorf("snapshot entry count mismatch: have %d, want %d", found, len(flat))
+	}
+	for key, want := range flat {
+		var (
+			have []byte
+			err  error
+		)
+		if bytes.HasPrefix([]byte(key), snapshotAccountPrefix) && len(key) == 1+common.HashLength {
+			have, err = base.AccountRLP(common.BytesToHash([]byte(key[1:])))
+		} else {
+			have, err = base.Storage(common.BytesToHash([]byte(key[1:1+common.HashLength])), common.BytesToHash([]byte(key[1+common.HashLength:])))
+		}
+		if err != nil || !bytes.Equal(have, want) {
+			t.Errorf("snapshot read %x mismatch: have %x/%v, want %x", key, have, err, want)
+		}
+	}
+}
+
+// Tests that a missing snapshot is generated from the state trie in the background.
+func TestGeneration(t *testing.T) { log.DebugLog()
+	db, triedb, root, flat := makeTestState(t)
+
+	// Plant some garbage which the generator must wipe
+	db.Put(accountSnapshotKey(common.Hash{0xde, 0xad}), []byte{0x01})
+	db.Put(storageSnapshotKey(common.Hash{0xde, 0xad}, common.Hash{0x01}), []byte{0x01})
+
+	// Plant some hash keyed data overlapping the snapshot prefixes, which must stay
+	trieLike := [][]byte{
+		append(append([]byte{}, snapshotAccountPrefix...), make([]byte, common.HashLength-1)...),
+		append(append([]byte{}, snapshotStoragePrefix...), make([]byte, common.HashLength-1)...),
+	}
+	for _, key := range trieLike {
+		db.Put(key, []byte{0x01})
+	}
+
+	snaps := New(db, triedb, 1, root)
+	base := waitGeneration(t, snaps, root)
+	checkSnapshot(t, db, base, flat)
+
+	for _, key := range trieLike {
+		if ok, _ := db.Has(key); !ok {
+			t.Errorf("non-snapshot entry %x wiped", key)
+		}
+	}
+	if ok, _ := db.Has(snapshotGeneratorKey); ok {
+		t.Errorf("generator marker not removed")
+	}
+	if stored := readSnapshotRoot(db); stored != root {
+		t.Errorf("snapshot root mismatch: have %x, want %x", stored, root)
+	}
+	snaps.Stop()
+}
+
+// Tests that an interrupted generation is resumed from the persisted marker,
+// dropping any partial data beyond it.
+func TestGenerationResume(t *testing.T) { log.DebugLog()
+	db, triedb, root, flat := makeTestState(t)
+
+	// Start generating, then abort immediately and plant a partial account
+	snaps := New(db, triedb, 1, root)
+	snaps.Stop()
+
+	marker, ok := readSnapshotGenerator(db)
+	if !ok && readSnapshotRoot(db) != root {
+		t.Fatalf("generator progress not persisted")
+	}
+	junk := common.Hash{0xff, 0xff, 0xff}
+	if ok {
+		db.Put(storageSnapshotKey(junk, common.Hash{0x01}), []byte{0x01})
+		t.Logf("resuming generation at %x", marker)
+	}
+	snaps = New(db, triedb, 1, root)
+	base := waitGeneration(t, snaps, root)
+	checkSnapshot(t, db, base, flat)
+	snaps.Stop()
+}
+
+// Tests that reads beyond the generation marker are rejected instead of being
+// served from incomplete data.
+func TestGenerationCoverage(t *testing.T) { log.DebugLog()
+	db, _ := ethdb.NewMemDatabase()
+	base := newDiskLayer(db, trie.NewDatabase(db), newCache(1), common.Hash{}, markerAfter(common.Hash{0x10}))
+
+	if _, err := base.AccountRLP(common.Hash{0x10}); err != nil {
+		t.Errorf("covered account rejected: %v", err)
+	}
+	if _, err := base.Storage(common.Hash{0x10}, common.Hash{0xff}); err != nil {
+		t.Errorf("covered slot rejected: %v", err)
+	}
+	if _, err := base.AccountRLP(common.Hash{0x11}); err != ErrNotCoveredYet {
+		t.Errorf("uncovered account error mismatch: have %v, want %v", err, ErrNotCoveredYet)
+	}
+	if _, err := base.Storage(common.Hash{0x11}, common.Hash{}); err != ErrNotCoveredYet {
+		t.Errorf("uncovered slot error mismatch: have %v, want %v", err, ErrNotCoveredYet)
+	}
+	base.genMarker = []byte{}
+	if _, err := base.AccountRLP(common.Hash{}); err != ErrNotCoveredYet {
+		t.Errorf("account covered by empty marker: %v", err)
+	}
+}
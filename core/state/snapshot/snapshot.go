@@ -0,0 +1,312 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a flat, layered dump of the Ethereum state.
+//
+// The snapshot is a flat key-value representation of the accounts and storage
+// slots of a state, keyed by their hashes. A single persistent disk layer holds
+// the state of an older block, with in-memory diff layers stacked on top of it
+// for every more recent block. Reads are served from the topmost layer holding
+// the requested item, turning the O(log n) trie traversal into a single lookup.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the underlying snapshot
+	// layer had been invalidated due to the chain progressing forward far enough
+	// to not maintain the layer's original state.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the underlying snapshot
+	// is being generated currently and the requested data item is not yet in the
+	// range of accounts covered.
+	ErrNotCoveredYet = errors.New("not covered yet")
+
+	// errSnapshotCycle is returned if a snapshot is attempted to be inserted
+	// that forms a cycle in the snapshot tree.
+	errSnapshotCycle = errors.New("snapshot cycle")
+)
+
+// Account is the Ethereum consensus representation of accounts, as stored in
+// the account trie and mirrored in the snapshot.
+type Account struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+}
+
+// Snapshot represents the functionality supported by a snapshot storage layer.
+type Snapshot interface {
+	// Root returns the root hash for which this snapshot was made.
+	Root() common.Hash
+
+	// Account directly retrieves the account associated with a particular hash in
+	// the snapshot. A nil account means it does not exist.
+	Account(hash common.Hash) (*Account, error)
+
+	// AccountRLP directly retrieves the account RLP associated with a particular
+	// hash in the snapshot. An empty blob means the account does not exist.
+	AccountRLP(hash common.Hash) ([]byte, error)
+
+	// Storage directly retrieves the storage data (RLP encoded, as in the trie)
+	// associated with a particular hash, within a particular account. An empty
+	// blob means the slot does not exist.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is the internal version of the snapshot data layer that supports some
+// additional methods compared to the public API.
+type snapshot interface {
+	Snapshot
+
+	// Parent returns the subsequent layer of a snapshot, or nil if the base was
+	// reached.
+	Parent() snapshot
+
+	// Stale returns whether this layer has become stale (was flattened across)
+	// or if it's still live.
+	Stale() bool
+}
+
+// Tree is an Ethereum state snapshot tree. It consists of one persistent base
+// layer backed by a key-value store, on top of which arbitrarily many in-memory
+// diff layers are topped. The memory diffs can form a tree with branching, but
+// the disk layer is singleton and common to all. If a reorg goes deeper than
+// the disk layer, everything needs to be regenerated.
+//
+// The goal of a state snapshot is to allow direct access to account and storage
+// data to avoid expensive multi-level trie lookups.
+type Tree struct {
+	diskdb ethdb.Database           // Persistent database to store the snapshot
+	triedb *trie.Database           // In-memory cache to access the trie through
+	cache  int                      // Megabytes permitted to use for read caches
+	layers map[common.Hash]snapshot // Collection of all known layers
+	lock   sync.RWMutex             // Lock protecting the layers
+}
+
+// New attempts to load an already existing snapshot from a persistent key-value
+// store, ensuring that the head of the snapshot matches the expected one. Diff
+// layers are not persisted, so the snapshot needs to be capped to the head with
+// zero layers on shutdown to be reusable after a restart.
+//
+// If the snapshot is missing or inconsistent, the entirety is deleted and will
+// be reconstructed from scratch based on the tries in the key-value store, on a
+// background thread. Until done, reads of uncovered items report ErrNotCoveredYet
+// and should be served from the trie instead.
+func New(diskdb ethdb.Database, triedb *trie.Database, cache int, root common.Hash) *Tree {
+	log.DebugLog()
+	snap := &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		cache:  cache,
+		layers: make(map[common.Hash]snapshot),
+	}
+	if stored := readSnapshotRoot(diskdb); stored != root {
+		log.Warn("Snapshot head mismatch, regenerating", "stored", stored, "head", root)
+		snap.Rebuild(root)
+		return snap
+	}
+	base := newDiskLayer(diskdb, triedb, newCache(cache), root, nil)
+	if marker, ok := readSnapshotGenerator(diskdb); ok {
+		log.Info("Resuming state snapshot generation", "root", root, "at", fmt.Sprintf("%x", marker))
+		base.genMarker = marker
+		base.startGeneration()
+	}
+	snap.layers[root] = base
+	return snap
+}
+
+// Snapshot retrieves a snapshot belonging to the given block root, or nil if no
+// snapshot is maintained for that block.
+func (t *Tree) Snapshot(blockRoot common.Hash) Snapshot {
+	log.DebugLog()
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if layer, ok := t.layers[blockRoot]; ok {
+		return layer
+	}
+	return nil
+}
+
+// Update adds a new snapshot into the tree, if that can be linked to an existing
+// old parent. It is disallowed to insert a disk layer (the origin of all).
+func (t *Tree) Update(blockRoot common.Hash, parentRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	log.DebugLog()
+	// Reject noop updates to avoid self-loops in the snapshot tree. This is a
+	// special case that can only happen for Clique networks where empty blocks
+	// don't modify the state (0 block subsidy).
+	if blockRoot == parentRoot {
+		return errSnapshotCycle
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// The same state may be reached via different blocks, keep the first
+	if _, ok := t.layers[blockRoot]; ok {
+		return nil
+	}
+	parent, ok := t.layers[parentRoot]
+	if !ok {
+		return fmt.Errorf("parent [%#x] snapshot missing", parentRoot)
+	}
+	t.layers[blockRoot] = newDiffLayer(parent, blockRoot, destructs, accounts, storage)
+	return nil
+}
+
+// Cap traverses downwards the snapshot tree from a head block hash until the
+// number of allowed layers are crossed. All layers beyond the permitted number
+// are flattened downwards and persisted into the disk layer. Any layers that
+// branch off the flattened ones become stale and are discarded.
+//
+// Capping to zero layers persists everything down to the requested root.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	log.DebugLog()
+	snap := t.Snapshot(root)
+	if snap == nil {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	diff, ok := snap.(*diffLayer)
+	if !ok {
+		return nil // Already the disk layer, nothing to cap
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if layers == 0 {
+		base := diffToDisk(diff.flatten().(*diffLayer))
+		diff.markStale()
+
+		t.layers = map[common.Hash]snapshot{base.root: base}
+		return nil
+	}
+	// Dive until we run out of layers or reach the persistent database
+	for i := 0; i < layers-1; i++ {
+		parent, ok := diff.Parent().(*diffLayer)
+		if !ok {
+			return nil
+		}
+		diff = parent
+	}
+	bottom, ok := diff.Parent().(*diffLayer)
+	if !ok {
+		return nil
+	}
+	base := diffToDisk(bottom.flatten().(*diffLayer))
+	bottom.markStale()
+
+	diff.lock.Lock()
+	diff.parent = base
+	diff.lock.Unlock()
+
+	// Remove any layer that is stale or links into a stale layer, invalidating
+	// the orphaned ones so live references don't serve partial data
+	for root, layer := range t.layers {
+		if isStale(layer) {
+			if diff, ok := layer.(*diffLayer); ok {
+				diff.markStale()
+			}
+			delete(t.layers, root)
+		}
+	}
+	t.layers[base.root] = base
+	return nil
+}
+
+// Rebuild wipes all available snapshot data from the persistent database and
+// discards all caches and diff layers. Afterwards, it starts a new snapshot
+// generator with the given root hash.
+func (t *Tree) Rebuild(root common.Hash) {
+	log.DebugLog()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Stop any running generator and invalidate every known layer
+	for _, layer := range t.layers {
+		switch layer := layer.(type) {
+		case *diskLayer:
+			layer.stopGeneration()
+			layer.markStale()
+		case *diffLayer:
+			layer.markStale()
+		}
+	}
+	if err := wipeSnapshot(t.diskdb); err != nil {
+		log.Crit("Failed to wipe state snapshot", "err", err)
+	}
+	// Persist the root and an empty generator marker, so an interrupted run
+	// resumes on the next startup instead of starting over
+	writeSnapshotGenerator(t.diskdb, []byte{})
+	writeSnapshotRoot(t.diskdb, root)
+
+	log.Info("Rebuilding state snapshot", "root", root)
+	base := newDiskLayer(t.diskdb, t.triedb, newCache(t.cache), root, []byte{})
+	base.startGeneration()
+
+	t.layers = map[common.Hash]snapshot{root: base}
+}
+
+// Stop aborts any background snapshot generation, persisting its progress so
+// that it can be resumed on the next startup.
+func (t *Tree) Stop() {
+	log.DebugLog()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, layer := range t.layers {
+		if base, ok := layer.(*diskLayer); ok {
+			base.stopGeneration()
+		}
+	}
+}
+
+// isStale reports whether a layer or any of its ancestors has become stale.
+func isStale(layer snapshot) bool {
+	log.DebugLog()
+	for ; layer != nil; layer = layer.Parent() {
+		if layer.Stale() {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeAccount decodes an account as stored in the snapshot, returning nil for
+// missing ones.
+func decodeAccount(blob []byte) (*Account, error) {
+	log.DebugLog()
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	account := new(Account)
+	if err := rlp.DecodeBytes(blob, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
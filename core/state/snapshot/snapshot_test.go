@@ -0,0 +1,171 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// newTestTree creates a snapshot tree with a fully generated, empty disk layer
+// at the given root.
+func newTestTree(root common.Hash) (*Tree, *ethdb.MemDatabase) { log.DebugLog()
+	db, _ := ethdb.NewMemDatabase()
+	writeSnapshotRoot(db, root)
+
+	return New(db, trie.NewDatabase(db), 1, root), db
+}
+
+// Tests that diff layers resolve accounts and slots from the topmost layer that
+// knows about them, honouring account destructions.
+func TestDiffLayerReads(t *testing.T) { log.DebugLog()
+	var (
+		base = common.Hash{0x01}
+		acc  = common.Hash{0xaa}
+		slot = common.Hash{0xbb}
+	)
+	snaps, db := newTestTree(base)
+	db.Put(accountSnapshotKey(acc), []byte{0x01})
+	db.Put(storageSnapshotKey(acc, slot), []byte{0x02})
+
+	// Modify the storage in the first layer, destruct the account in the second
+	if err := snaps.Update(common.Hash{0x02}, base, nil, nil, map[common.Hash]map[common.Hash][]byte{acc: {slot: {0x03}}}); err != nil {
+		t.Fatalf("failed to create first diff layer: %v", err)
+	}
+	if err := snaps.Update(common.Hash{0x03}, common.Hash{0x02}, map[common.Hash]struct{}{acc: {}}, nil, nil); err != nil {
+		t.Fatalf("failed to create second diff layer: %v", err)
+	}
+	tests := []struct {
+		root    common.Hash
+		account []byte
+		slot    []byte
+	}{
+		{base, []byte{0x01}, []byte{0x02}},
+		{common.Hash{0x02}, []byte{0x01}, []byte{0x03}},
+		{common.Hash{0x03}, nil, nil},
+	}
+	for i, tt := range tests {
+		snap := snaps.Snapshot(tt.root)
+		if snap == nil {
+			t.Fatalf("test %d: snapshot missing", i)
+		}
+		if blob, err := snap.AccountRLP(acc); err != nil || !bytes.Equal(blob, tt.account) {
+			t.Errorf("test %d: account mismatch: have %x/%v, want %x", i, blob, err, tt.account)
+		}
+		if blob, err := snap.Storage(acc, slot); err != nil || !bytes.Equal(blob, tt.slot) {
+			t.Errorf("test %d: slot mismatch: have %x/%v, want %x", i, blob, err, tt.slot)
+		}
+	}
+	// Cycles and orphans must be rejected
+	if err := snaps.Update(common.Hash{0x03}, common.Hash{0x03}, nil, nil, nil); err != errSnapshotCycle {
+		t.Errorf("cycle error mismatch: have %v, want %v", err, errSnapshotCycle)
+	}
+	if err := snaps.Update(common.Hash{0x04}, common.Hash{0xff}, nil, nil, nil); err == nil {
+		t.Errorf("orphan layer accepted")
+	}
+}
+
+// Tests that capping the tree flattens the bottom layers into the disk, marks
+// them stale and drops any branches forking off them.
+func TestTreeCap(t *testing.T) { log.DebugLog()
+	var (
+		base = common.Hash{0x01}
+		acc1 = common.Hash{0xa1}
+		acc2 = common.Hash{0xa2}
+	)
+	snaps, db := newTestTree(base)
+
+	// Build a chain of three layers and a fork off the first one
+	snaps.Update(common.Hash{0x02}, base, nil, map[common.Hash][]byte{acc1: {0x01}}, nil)
+	snaps.Update(common.Hash{0x03}, common.Hash{0x02}, nil, map[common.Hash][]byte{acc2: {0x02}}, nil)
+	snaps.Update(common.Hash{0x04}, common.Hash{0x03}, nil, map[common.Hash][]byte{acc1: {0x03}}, nil)
+	snaps.Update(common.Hash{0x13}, common.Hash{0x02}, nil, map[common.Hash][]byte{acc2: {0x12}}, nil)
+
+	fork := snaps.Snapshot(common.Hash{0x13})
+	old := snaps.Snapshot(common.Hash{0x02})
+
+	// Keep a single diff layer, everything below must be flattened
+	if err := snaps.Cap(common.Hash{0x04}, 1); err != nil {
+		t.Fatalf("failed to cap tree: %v", err)
+	}
+	if root := readSnapshotRoot(db); root != (common.Hash{0x03}) {
+		t.Fatalf("persisted root mismatch: have %x, want %x", root, common.Hash{0x03})
+	}
+	if blob, _ := db.Get(accountSnapshotKey(acc1)); !bytes.Equal(blob, []byte{0x01}) {
+		t.Errorf("account 1 not flattened: %x", blob)
+	}
+	if blob, _ := db.Get(accountSnapshotKey(acc2)); !bytes.Equal(blob, []byte{0x02}) {
+		t.Errorf("account 2 not flattened: %x", blob)
+	}
+	for _, root := range []common.Hash{base, {0x02}, {0x13}} {
+		if snaps.Snapshot(root) != nil {
+			t.Errorf("stale layer %x retained", root)
+		}
+	}
+	if _, err := fork.AccountRLP(acc2); err != ErrSnapshotStale {
+		t.Errorf("forked layer error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if _, err := old.AccountRLP(acc1); err != ErrSnapshotStale {
+		t.Errorf("flattened layer error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if blob, err := snaps.Snapshot(common.Hash{0x04}).AccountRLP(acc1); err != nil || !bytes.Equal(blob, []byte{0x03}) {
+		t.Errorf("head account mismatch: have %x/%v, want %x", blob, err, []byte{0x03})
+	}
+	// Capping to zero layers must persist everything
+	if err := snaps.Cap(common.Hash{0x04}, 0); err != nil {
+		t.Fatalf("failed to fully cap tree: %v", err)
+	}
+	if root := readSnapshotRoot(db); root != (common.Hash{0x04}) {
+		t.Fatalf("persisted root mismatch: have %x, want %x", root, common.Hash{0x04})
+	}
+	if blob, _ := db.Get(accountSnapshotKey(acc1)); !bytes.Equal(blob, []byte{0x03}) {
+		t.Errorf("account 1 not persisted: %x", blob)
+	}
+}
+
+// Tests that destructing an account in a diff wipes its storage from the disk
+// when flattened, but keeps the slots of a resurrected account.
+func TestTreeCapDestruct(t *testing.T) { log.DebugLog()
+	var (
+		base = common.Hash{0x01}
+		acc  = common.Hash{0xaa}
+	)
+	snaps, db := newTestTree(base)
+	db.Put(accountSnapshotKey(acc), []byte{0x01})
+	db.Put(storageSnapshotKey(acc, common.Hash{0x01}), []byte{0x01})
+	db.Put(storageSnapshotKey(acc, common.Hash{0x02}), []byte{0x02})
+
+	snaps.Update(common.Hash{0x02}, base, map[common.Hash]struct{}{acc: {}}, map[common.Hash][]byte{acc: {0x02}},
+		map[common.Hash]map[common.Hash][]byte{acc: {{0x02}: {0x03}}})
+	if err := snaps.Cap(common.Hash{0x02}, 0); err != nil {
+		t.Fatalf("failed to cap tree: %v", err)
+	}
+	if ok, _ := db.Has(storageSnapshotKey(acc, common.Hash{0x01})); ok {
+		t.Errorf("destructed slot not wiped")
+	}
+	if blob, _ := db.Get(storageSnapshotKey(acc, common.Hash{0x02})); !bytes.Equal(blob, []byte{0x03}) {
+		t.Errorf("resurrected slot mismatch: have %x, want %x", blob, []byte{0x03})
+	}
+	if blob, _ := db.Get(accountSnapshotKey(acc)); !bytes.Equal(blob, []byte{0x02}) {
+		t.Errorf("resurrected account mismatch: have %x, want %x", blob, []byte{0x02})
+	}
+}
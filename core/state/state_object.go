@@ -187,11 +187,42 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 	if exists {
 		return value
 	}
-	// Load from DB in case it is missing.
-	enc, err := self.getTrie(db).TryGet(key[:])
-	if err != nil {
-		self.setError(err)
-		return common.Hash{}
+	value = self.loadState(db, key)
+	if (value != common.Hash{}) {
+		self.cachedStorage[key] = value
+	}
+	return value
+}
+
+// GetCommittedState returns a value in account storage as it was before the
+// modifications of the current transaction. Those are only flushed into the
+// storage trie when the transaction is finalised.
+func (self *stateObject) GetCommittedState(db Database, key common.Hash) common.Hash {
+	log.DebugLog()
+	if _, dirty := self.dirtyStorage[key]; !dirty {
+		return self.GetState(db, key)
+	}
+	return self.loadState(db, key)
+}
+
+// loadState retrieves a value of the account storage from the database.
+func (self *stateObject) loadState(db Database, key common.Hash) common.Hash {
+	log.DebugLog()
+	// The snapshot can only be consulted as long as the storage trie wasn't
+	// touched, otherwise it may be out of date.
+	var (
+		enc   []byte
+		err   error
+		value common.Hash
+	)
+	if self.db.snap != nil && self.trie == nil && self.data.Root != (common.Hash{}) {
+		enc, err = self.db.snap.Storage(self.addrHash, crypto.Keccak256Hash(key[:]))
+	}
+	if self.db.snap == nil || self.trie != nil || self.data.Root == (common.Hash{}) || err != nil {
+		if enc, err = self.getTrie(db).TryGet(key[:]); err != nil {
+			self.setError(err)
+			return common.Hash{}
+		}
 	}
 	if len(enc) > 0 {
 		_, content, _, err := rlp.Split(enc)
@@ -200,9 +231,6 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 		}
 		value.SetBytes(content)
 	}
-	if (value != common.Hash{}) {
-		self.cachedStorage[key] = value
-	}
 	return value
 }
 
@@ -217,6 +245,29 @@ func (self *stateObject) SetState(db Database, key, value common.Hash) {
 	self.setState(key, value)
 }
 
+// SetStorage replaces the entire storage of the account with the given one. The
+// original storage trie is swapped out for an empty one, so none of the previous
+// slots remain accessible, neither from the trie nor from the snapshot.
+func (self *stateObject) SetStorage(db Database, storage map[common.Hash]common.Hash) {
+	log.DebugLog()
+	tr, err := db.OpenStorageTrie(self.addrHash, common.Hash{})
+	if err != nil {
+		self.setError(err)
+		return
+	}
+	self.trie = tr
+	self.cachedStorage = make(Storage)
+	self.dirtyStorage = make(Storage)
+
+	for key, value := range storage {
+		self.setState(key, value)
+	}
+	if self.onDirty != nil {
+		self.onDirty(self.Address())
+		self.onDirty = nil
+	}
+}
+
 func (self *stateObject) setState(key, value common.Hash) {
 	log.DebugLog()
 	self.cachedStorage[key] = value
@@ -232,15 +283,29 @@ func (self *stateObject) setState(key, value common.Hash) {
 func (self *stateObject) updateTrie(db Database) Trie {
 	log.DebugLog()
 	tr := self.getTrie(db)
+
+	// Track the storage changes for the snapshot diff layer too
+	var storage map[common.Hash][]byte
+	if self.db.snap != nil && len(self.dirtyStorage) > 0 {
+		if storage = self.db.snapStorage[self.addrHash]; storage == nil {
+			storage = make(map[common.Hash][]byte)
+			self.db.snapStorage[self.addrHash] = storage
+		}
+	}
 	for key, value := range self.dirtyStorage {
 		delete(self.dirtyStorage, key)
+
+		var v []byte
 		if (value == common.Hash{}) {
 			self.setError(tr.TryDelete(key[:]))
-			continue
+		} else {
+			// Encoding []byte cannot fail, ok to ignore the error.
+			v, _ = rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
+			self.setError(tr.TryUpdate(key[:], v))
+		}
+		if storage != nil {
+			storage[crypto.Keccak256Hash(key[:])] = v
 		}
-		// Encoding []byte cannot fail, ok to ignore the error.
-		v, _ := rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
-		self.setError(tr.TryUpdate(key[:], v))
 	}
 	return tr
 }
@@ -24,6 +24,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	debugLog "github.com/ethereum/go-ethereum/log"
@@ -44,6 +45,10 @@ var (
 	emptyCode = crypto.Keccak256Hash(nil)
 )
 
+// snapshotLayers is the number of recent diff layers kept in memory on top of
+// the persistent snapshot, matching the number of tries retained in memory.
+const snapshotLayers = 128
+
 // wyliu: Structure - StateDB
 // wyliu: StateDB完整记录Transaction的执行情况;
 // wyliu: StateDB的重点是StateObjects;
@@ -57,6 +62,12 @@ type StateDB struct {
 	db   Database
 	trie Trie
 
+	snaps         *snapshot.Tree                         // Snapshot tree to update on commit, nil if disabled
+	snap          snapshot.Snapshot                      // Snapshot of the original root for fast reads, nil if unavailable
+	snapDestructs map[common.Hash]struct{}               // Accounts deleted since the original root
+	snapAccounts  map[common.Hash][]byte                 // Accounts updated since the original root
+	snapStorage   map[common.Hash]map[common.Hash][]byte // Storage slots updated since the original root
+
 	// This map holds 'live' objects, which will get modified while processing a state transition.
 	stateObjects      map[common.Address]*stateObject
 	stateObjectsDirty map[common.Address]struct{}
@@ -89,19 +100,45 @@ type StateDB struct {
 
 // Create a new state from a given trie.
 func New(root common.Hash, db Database) (*StateDB, error) {
+	debugLog.DebugLog()
+	return NewWithSnapshot(root, db, nil)
+}
+
+// NewWithSnapshot creates a new state from a given trie, serving reads from the
+// flat state snapshot if one is available for the root. On commit, the changes
+// are pushed into the snapshot tree as a new diff layer.
+func NewWithSnapshot(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error) {
 	debugLog.DebugLog()
 	tr, err := db.OpenTrie(root)
 	if err != nil {
 		return nil, err
 	}
-	return &StateDB{
+	sdb := &StateDB{
 		db:                db,
 		trie:              tr,
+		snaps:             snaps,
 		stateObjects:      make(map[common.Address]*stateObject),
 		stateObjectsDirty: make(map[common.Address]struct{}),
 		logs:              make(map[common.Hash][]*types.Log),
 		preimages:         make(map[common.Hash][]byte),
-	}, nil
+	}
+	sdb.openSnapshot(root)
+	return sdb, nil
+}
+
+// openSnapshot looks up the snapshot layer of the given root and resets the
+// change sets tracked for it.
+func (self *StateDB) openSnapshot(root common.Hash) {
+	debugLog.DebugLog()
+	self.snap, self.snapDestructs, self.snapAccounts, self.snapStorage = nil, nil, nil, nil
+	if self.snaps == nil {
+		return
+	}
+	if self.snap = self.snaps.Snapshot(root); self.snap != nil {
+		self.snapDestructs = make(map[common.Hash]struct{})
+		self.snapAccounts = make(map[common.Hash][]byte)
+		self.snapStorage = make(map[common.Hash]map[common.Hash][]byte)
+	}
 }
 
 // setError remembers the first non-nil error it is called with.
@@ -126,6 +163,7 @@ func (self *StateDB) Reset(root common.Hash) error {
 		return err
 	}
 	self.trie = tr
+	self.openSnapshot(root)
 	self.stateObjects = make(map[common.Address]*stateObject)
 	self.stateObjectsDirty = make(map[common.Address]struct{})
 	self.thash = common.Hash{}
@@ -187,6 +225,17 @@ func (self *StateDB) AddRefund(gas uint64) {
 	self.refund += gas
 }
 
+// SubRefund removes gas from the refund counter.
+// This method will panic if the refund counter goes below zero
+func (self *StateDB) SubRefund(gas uint64) {
+	debugLog.DebugLog()
+	self.journal = append(self.journal, refundChange{prev: self.refund})
+	if gas > self.refund {
+		panic("Refund counter below zero")
+	}
+	self.refund -= gas
+}
+
 // Exist reports whether the given account address exists in the state.
 // Notably this also returns true for suicided accounts.
 func (self *StateDB) Exist(addr common.Address) bool {
@@ -265,6 +314,17 @@ func (self *StateDB) GetState(addr common.Address, bhash common.Hash) common.Has
 	return common.Hash{}
 }
 
+// GetCommittedState retrieves a value from the given account's committed storage
+// trie, ignoring the modifications of the current transaction.
+func (self *StateDB) GetCommittedState(addr common.Address, hash common.Hash) common.Hash {
+	debugLog.DebugLog()
+	stateObject := self.getStateObject(addr)
+	if stateObject != nil {
+		return stateObject.GetCommittedState(self.db, hash)
+	}
+	return common.Hash{}
+}
+
 // Database retrieves the low level database supporting the lower level trie ops.
 func (self *StateDB) Database() Database {
 	debugLog.DebugLog()
@@ -283,6 +343,38 @@ func (self *StateDB) StorageTrie(addr common.Address) Trie {
 	return cpy.updateTrie(self.db)
 }
 
+// proofList collects the encoded trie nodes of a merkle proof, in the order
+// they are visited from the root down.
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	debugLog.DebugLog()
+	*n = append(*n, value)
+	return nil
+}
+
+// GetProof returns the merkle proof of an account in the state trie. If the
+// account does not exist, the proof proves its absence.
+func (self *StateDB) GetProof(addr common.Address) ([][]byte, error) {
+	debugLog.DebugLog()
+	var proof proofList
+	err := self.trie.Prove(crypto.Keccak256(addr.Bytes()), 0, &proof)
+	return [][]byte(proof), err
+}
+
+// GetStorageProof returns the merkle proof of a storage slot in the storage trie
+// of an account. If the slot is empty, the proof proves its absence.
+func (self *StateDB) GetStorageProof(addr common.Address, key common.Hash) ([][]byte, error) {
+	debugLog.DebugLog()
+	trie := self.StorageTrie(addr)
+	if trie == nil {
+		return nil, fmt.Errorf("storage trie for %x does not exist", addr)
+	}
+	var proof proofList
+	err := trie.Prove(crypto.Keccak256(key.Bytes()), 0, &proof)
+	return [][]byte(proof), err
+}
+
 func (self *StateDB) HasSuicided(addr common.Address) bool {
 	debugLog.DebugLog()
 	stateObject := self.getStateObject(addr)
@@ -346,6 +438,16 @@ func (self *StateDB) SetState(addr common.Address, key, value common.Hash) {
 	}
 }
 
+// SetStorage replaces the entire storage of the specified account with the given
+// one. It is meant for call simulations only, hence the change is not journalled.
+func (self *StateDB) SetStorage(addr common.Address, storage map[common.Hash]common.Hash) {
+	debugLog.DebugLog()
+	stateObject := self.GetOrNewStateObject(addr)
+	if stateObject != nil {
+		stateObject.SetStorage(self.db, storage)
+	}
+}
+
 // Suicide marks the given account as suicided.
 // This clears the account balance.
 //
@@ -381,6 +483,11 @@ func (self *StateDB) updateStateObject(stateObject *stateObject) {
 		panic(fmt.Errorf("can't encode object at %x: %v", addr[:], err))
 	}
 	self.setError(self.trie.TryUpdate(addr[:], data))
+
+	// Track the change for the snapshot diff layer too
+	if self.snap != nil {
+		self.snapAccounts[stateObject.addrHash] = data
+	}
 }
 
 // deleteStateObject removes the given object from the state trie.
@@ -389,6 +496,14 @@ func (self *StateDB) deleteStateObject(stateObject *stateObject) {
 	stateObject.deleted = true
 	addr := stateObject.Address()
 	self.setError(self.trie.TryDelete(addr[:]))
+
+	// Track the deletion for the snapshot diff layer too, dropping any earlier
+	// changes made to the account within the same block
+	if self.snap != nil {
+		self.snapDestructs[stateObject.addrHash] = struct{}{}
+		delete(self.snapAccounts, stateObject.addrHash)
+		delete(self.snapStorage, stateObject.addrHash)
+	}
 }
 
 // Retrieve a state object given my the address. Returns nil if not found.
@@ -402,8 +517,17 @@ func (self *StateDB) getStateObject(addr common.Address) (stateObject *stateObje
 		return obj
 	}
 
-	// Load the object from the database.
-	enc, err := self.trie.TryGet(addr[:])
+	// Load the object from the snapshot if available, the trie otherwise.
+	var (
+		enc []byte
+		err error
+	)
+	if self.snap != nil {
+		enc, err = self.snap.AccountRLP(crypto.Keccak256Hash(addr[:]))
+	}
+	if self.snap == nil || err != nil {
+		enc, err = self.trie.TryGet(addr[:])
+	}
 	if len(enc) == 0 {
 		self.setError(err)
 		return nil
@@ -497,6 +621,22 @@ func (db *StateDB) ForEachStorage(addr common.Address, cb func(key, value common
 	}
 }
 
+// DirtyAccounts returns the addresses of all the accounts modified since the state
+// was created or last committed, each along with the storage slots accessed on
+// it. Note, the slots are not necessarily modified, only read or written.
+func (self *StateDB) DirtyAccounts() map[common.Address][]common.Hash {
+	debugLog.DebugLog()
+	dirty := make(map[common.Address][]common.Hash, len(self.stateObjectsDirty))
+	for addr := range self.stateObjectsDirty {
+		var slots []common.Hash
+		for key := range self.stateObjects[addr].cachedStorage {
+			slots = append(slots, key)
+		}
+		dirty[addr] = slots
+	}
+	return dirty
+}
+
 // Copy creates a deep, independent copy of the state.
 // Snapshots of the copied state cannot be applied to the copy.
 func (self *StateDB) Copy() *StateDB {
@@ -508,6 +648,8 @@ func (self *StateDB) Copy() *StateDB {
 	state := &StateDB{
 		db:                self.db,
 		trie:              self.db.CopyTrie(self.trie),
+		snaps:             self.snaps,
+		snap:              self.snap,
 		stateObjects:      make(map[common.Address]*stateObject, len(self.stateObjectsDirty)),
 		stateObjectsDirty: make(map[common.Address]struct{}, len(self.stateObjectsDirty)),
 		refund:            self.refund,
@@ -527,6 +669,24 @@ func (self *StateDB) Copy() *StateDB {
 	for hash, preimage := range self.preimages {
 		state.preimages[hash] = preimage
 	}
+	// Copy the snapshot change sets, the values themselves are never mutated
+	if self.snap != nil {
+		state.snapDestructs = make(map[common.Hash]struct{}, len(self.snapDestructs))
+		for hash := range self.snapDestructs {
+			state.snapDestructs[hash] = struct{}{}
+		}
+		state.snapAccounts = make(map[common.Hash][]byte, len(self.snapAccounts))
+		for hash, data := range self.snapAccounts {
+			state.snapAccounts[hash] = data
+		}
+		state.snapStorage = make(map[common.Hash]map[common.Hash][]byte, len(self.snapStorage))
+		for hash, storage := range self.snapStorage {
+			state.snapStorage[hash] = make(map[common.Hash][]byte, len(storage))
+			for key, data := range storage {
+				state.snapStorage[hash][key] = data
+			}
+		}
+	}
 	return state
 }
 
@@ -675,5 +835,20 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 		return nil
 	})
 	debugLog.Debug("Trie cache stats after commit", "misses", trie.CacheMisses(), "unloads", trie.CacheUnloads())
+
+	// If snapshotting is enabled, update the snapshot tree with this new version
+	if s.snap != nil && err == nil {
+		if parent := s.snap.Root(); parent != root {
+			if err := s.snaps.Update(root, parent, s.snapDestructs, s.snapAccounts, s.snapStorage); err != nil {
+				debugLog.Warn("Failed to update snapshot tree", "from", parent, "to", root, "err", err)
+			}
+			// Keep enough diff layers in memory for the in-memory tries to cover
+			if err := s.snaps.Cap(root, snapshotLayers); err != nil {
+				debugLog.Warn("Failed to cap snapshot tree", "root", root, "layers", snapshotLayers, "err", err)
+			}
+		}
+		// The snapshot of the original root is not valid for this state any more
+		s.snap, s.snapDestructs, s.snapAccounts, s.snapStorage = nil, nil, nil, nil
+	}
 	return root, err
 }
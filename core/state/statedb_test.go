@@ -31,8 +31,13 @@ import (
 	check "gopkg.in/check.v1"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -421,3 +426,143 @@ func (s *StateSuite) TestTouchDelete(c *check.C) { log.DebugLog()
 		c.Fatal("expected no dirty state object")
 	}
 }
+
+// Tests that state reads served through the snapshot tree match those served
+// directly from the tries, also across account destructions and resurrections.
+func TestSnapshotBackedReads(t *testing.T) { log.DebugLog()
+	db, _ := ethdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+
+	// Create a base state and start generating a snapshot for it
+	base, _ := New(common.Hash{}, sdb)
+	for i := byte(0); i < 16; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		base.SetBalance(addr, big.NewInt(int64(i)))
+		base.SetState(addr, common.Hash{i}, common.Hash{i})
+	}
+	root, _ := base.Commit(false)
+	sdb.TrieDB().Commit(root, false)
+
+	snaps := snapshot.New(db, sdb.TrieDB(), 1, root)
+	defer snaps.Stop()
+
+	// Modify the state on top of the snapshot: update, destruct and resurrect
+	state, _ := NewWithSnapshot(root, sdb, snaps)
+	state.SetState(common.BytesToAddress([]byte{1}), common.Hash{1}, common.Hash{0xff})
+	state.Suicide(common.BytesToAddress([]byte{2}))
+	state.Finalise(true)
+	state.SetBalance(common.BytesToAddress([]byte{2}), big.NewInt(100))
+	state.SetBalance(common.BytesToAddress([]byte{0xff}), big.NewInt(255))
+
+	next, err := state.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if snaps.Snapshot(next) == nil {
+		t.Fatalf("snapshot layer missing for new state")
+	}
+	snapState, _ := NewWithSnapshot(next, sdb, snaps)
+	trieState, _ := New(next, sdb)
+	for _, i := range []byte{0, 1, 2, 3, 0xff} {
+		addr := common.BytesToAddress([]byte{i})
+		if have, want := snapState.GetBalance(addr), trieState.GetBalance(addr); have.Cmp(want) != 0 {
+			t.Errorf("account %x: balance mismatch: have %v, want %v", addr, have, want)
+		}
+		if have, want := snapState.GetState(addr, common.Hash{i}), trieState.GetState(addr, common.Hash{i}); have != want {
+			t.Errorf("account %x: slot mismatch: have %x, want %x", addr, have, want)
+		}
+	}
+	if have := snapState.GetState(common.BytesToAddress([]byte{2}), common.Hash{2}); have != (common.Hash{}) {
+		t.Errorf("resurrected account kept storage: %x", have)
+	}
+}
+
+// Tests that replacing the storage of an account hides all its previous slots,
+// whether they would be served from the trie or from the snapshot.
+func TestSetStorage(t *testing.T) { log.DebugLog()
+	db, _ := ethdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+
+	addr := common.BytesToAddress([]byte{0x01})
+	base, _ := New(common.Hash{}, sdb)
+	base.SetState(addr, common.Hash{0x01}, common.Hash{0x01})
+	base.SetState(addr, common.Hash{0x02}, common.Hash{0x02})
+	root, _ := base.Commit(false)
+	sdb.TrieDB().Commit(root, false)
+
+	snaps := snapshot.New(db, sdb.TrieDB(), 1, root)
+	defer snaps.Stop()
+
+	trieState, _ := New(root, sdb)
+	snapState, _ := NewWithSnapshot(root, sdb, snaps)
+	for _, state := range []*StateDB{trieState, snapState} {
+		state.SetStorage(addr, map[common.Hash]common.Hash{{0x02}: {0xff}, {0x03}: {0x03}})
+
+		want := map[common.Hash]common.Hash{{0x01}: {}, {0x02}: {0xff}, {0x03}: {0x03}}
+		for key, value := range want {
+			if have := state.GetState(addr, key); have != value {
+				t.Errorf("slot %x: have %x, want %x", key, have, value)
+			}
+		}
+		// Ensure the replaced storage also ends up in the committed state
+		next, err := state.Copy().Commit(false)
+		if err != nil {
+			t.Fatalf("failed to commit state: %v", err)
+		}
+		committed, _ := New(next, sdb)
+		for key, value := range want {
+			if have := committed.GetState(addr, key); have != value {
+				t.Errorf("committed slot %x: have %x, want %x", key, have, value)
+			}
+		}
+	}
+}
+
+// Tests that account and storage proofs verify against the state and storage
+// roots, proving both existing and missing entries.
+func TestGetProof(t *testing.T) { log.DebugLog()
+	db, _ := ethdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+
+	addr := common.BytesToAddress([]byte{0x01})
+	state, _ := New(common.Hash{}, sdb)
+	state.SetBalance(addr, big.NewInt(42))
+	state.SetState(addr, common.Hash{0x01}, common.Hash{0x02})
+	state.SetBalance(common.BytesToAddress([]byte{0x02}), big.NewInt(1))
+	root, _ := state.Commit(false)
+	state, _ = New(root, sdb)
+
+	verify := func(root common.Hash, key []byte, proof [][]byte) []byte {
+		proofDb, _ := ethdb.NewMemDatabase()
+		for _, node := range proof {
+			proofDb.Put(crypto.Keccak256(node), node)
+		}
+		value, err, _ := trie.VerifyProof(root, crypto.Keccak256(key), proofDb)
+		if err != nil {
+			t.Fatalf("proof for %x failed to verify: %v", key, err)
+		}
+		return value
+	}
+	proof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatalf("failed to prove account: %v", err)
+	}
+	if value := verify(root, addr.Bytes(), proof); value == nil {
+		t.Fatalf("existing account proven absent")
+	}
+	proof, _ = state.GetProof(common.BytesToAddress([]byte{0x03}))
+	if value := verify(root, common.BytesToAddress([]byte{0x03}).Bytes(), proof); value != nil {
+		t.Fatalf("missing account proven present: %x", value)
+	}
+	storageRoot := state.StorageTrie(addr).Hash()
+	proof, err = state.GetStorageProof(addr, common.Hash{0x01})
+	if err != nil {
+		t.Fatalf("failed to prove storage: %v", err)
+	}
+	if value, _ := rlp.EncodeToBytes(bytes.TrimLeft(common.Hash{0x02}.Bytes(), "\x00")); !bytes.Equal(verify(storageRoot, common.Hash{0x01}.Bytes(), proof), value) {
+		t.Fatalf("storage slot value mismatch")
+	}
+	if _, err := state.GetStorageProof(common.BytesToAddress([]byte{0x03}), common.Hash{0x01}); err == nil {
+		t.Fatalf("storage of missing account proven")
+	}
+}
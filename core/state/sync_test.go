@@ -24,6 +24,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -133,8 +134,8 @@ func TestEmptyStateSync(t *testing.T) { log.DebugLog()
 
 // Tests that given a root hash, a state can sync iteratively on a single thread,
 // requesting retrieval tasks and returning all of them in one go.
-func TestIterativeStateSyncIndividual(t *testing.T) { log.DebugLog() testIterativeStateSync(t, 1) }
-func TestIterativeStateSyncBatched(t *testing.T)    { log.DebugLog() testIterativeStateSync(t, 100) }
+func TestIterativeStateSyncIndividual(t *testing.T) { log.DebugLog(); testIterativeStateSync(t, 1) }
+func TestIterativeStateSyncBatched(t *testing.T)    { log.DebugLog(); testIterativeStateSync(t, 100) }
 
 func testIterativeStateSync(t *testing.T, batch int) { log.DebugLog()
 	// Create a random state to copy
@@ -202,8 +203,8 @@ func TestIterativeDelayedStateSync(t *testing.T) { log.DebugLog()
 // Tests that given a root hash, a trie can sync iteratively on a single thread,
 // requesting retrieval tasks and returning all of them in one go, however in a
 // random order.
-func TestIterativeRandomStateSyncIndividual(t *testing.T) { log.DebugLog() testIterativeRandomStateSync(t, 1) }
-func TestIterativeRandomStateSyncBatched(t *testing.T)    { log.DebugLog() testIterativeRandomStateSync(t, 100) }
+func TestIterativeRandomStateSyncIndividual(t *testing.T) { log.DebugLog(); testIterativeRandomStateSync(t, 1) }
+func TestIterativeRandomStateSyncBatched(t *testing.T)    { log.DebugLog(); testIterativeRandomStateSync(t, 100) }
 
 func testIterativeRandomStateSync(t *testing.T, batch int) { log.DebugLog()
 	// Create a random state to copy
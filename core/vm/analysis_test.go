@@ -16,7 +16,11 @@
 
 package vm
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
 
 func TestJumpDestAnalysis(t *testing.T) { log.DebugLog()
 	tests := []struct {
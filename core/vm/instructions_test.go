@@ -21,6 +21,11 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -425,3 +430,98 @@ func BenchmarkOpIsZero(b *testing.B) { log.DebugLog()
 	x := "FBCDEF090807060504030201ffffffffFBCDEF090807060504030201ffffffff"
 	opBenchmark(b, opIszero, x)
 }
+
+// Tests that CREATE2 charges for hashing the init code on top of the creation.
+func TestCreate2Gas(t *testing.T) { log.DebugLog()
+	for i, tt := range []struct {
+		size uint64
+		gas  uint64
+	}{{0, 32000}, {1, 32006}, {32, 32006}, {33, 32012}, {1024, 32192}} {
+		stack := newstack()
+		stack.push(big.NewInt(0))                   // salt
+		stack.push(new(big.Int).SetUint64(tt.size)) // size
+		stack.push(big.NewInt(0))                   // offset
+		stack.push(big.NewInt(0))                   // endowment
+
+		gas, err := gasCreate2(params.GasTable{}, nil, nil, stack, NewMemory(), 0)
+		if err != nil {
+			t.Fatalf("test %d: failed to calculate gas: %v", i, err)
+		}
+		if gas != tt.gas {
+			t.Errorf("test %d: gas mismatch: have %d, want %d", i, gas, tt.gas)
+		}
+	}
+}
+
+// Tests that CREATE2 deploys to the salted address derived from the init code,
+// and that the same contract can't be deployed twice.
+func TestCreate2(t *testing.T) { log.DebugLog()
+	var (
+		factory = common.BytesToAddress([]byte("factory"))
+		// CREATE2(value: 0, offset: 0, size: 1, salt: 42) with the init code 0x00,
+		// returning the address of the new contract
+		code = hexutil.MustDecode("0x602a600160006000f560005260206000f3")
+	)
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.SetCode(factory, code)
+
+	evm := newConstantinopleEVM(statedb)
+	ret, _, err := evm.Call(AccountRef(common.Address{}), factory, nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatalf("failed to create contract: %v", err)
+	}
+	want := crypto.CreateAddress2(factory, common.BigToHash(big.NewInt(42)), crypto.Keccak256([]byte{0x00}))
+	if have := common.BytesToAddress(ret); have != want {
+		t.Fatalf("contract address mismatch: have %x, want %x", have, want)
+	}
+	if !statedb.Exist(want) {
+		t.Fatalf("contract %x not created", want)
+	}
+	// Deploying the same init code with the same salt must collide
+	ret, _, err = evm.Call(AccountRef(common.Address{}), factory, nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatalf("failed to execute factory: %v", err)
+	}
+	if have := common.BytesToAddress(ret); have != (common.Address{}) {
+		t.Fatalf("colliding contract created at %x", have)
+	}
+}
+
+// Tests that EXTCODEHASH returns the code hash of existing accounts, and zero for
+// non-existent or empty ones.
+func TestExtCodeHash(t *testing.T) { log.DebugLog()
+	var (
+		contract = common.BytesToAddress([]byte("contract"))
+		account  = common.BytesToAddress([]byte("account"))
+		empty    = common.BytesToAddress([]byte("empty"))
+		missing  = common.BytesToAddress([]byte("missing"))
+
+		// EXTCODEHASH(calldata[0:32]), returning the hash
+		code = hexutil.MustDecode("0x6000353f60005260206000f3")
+	)
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.SetCode(contract, code)
+	statedb.SetBalance(account, big.NewInt(1))
+	statedb.CreateAccount(empty)
+
+	evm := newConstantinopleEVM(statedb)
+	for i, tt := range []struct {
+		address common.Address
+		hash    common.Hash
+	}{
+		{contract, crypto.Keccak256Hash(code)},
+		{account, crypto.Keccak256Hash(nil)},
+		{empty, common.Hash{}},
+		{missing, common.Hash{}},
+	} {
+		ret, _, err := evm.Call(AccountRef(common.Address{}), contract, tt.address.Hash().Bytes(), 100000, new(big.Int))
+		if err != nil {
+			t.Fatalf("test %d: execution failed: %v", i, err)
+		}
+		if have := common.BytesToHash(ret); have != tt.hash {
+			t.Errorf("test %d: code hash mismatch: have %x, want %x", i, have, tt.hash)
+		}
+	}
+}
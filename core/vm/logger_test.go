@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -29,8 +30,8 @@ type dummyContractRef struct {
 }
 
 func (dummyContractRef) ReturnGas(*big.Int)          { log.DebugLog()}
-func (dummyContractRef) Address() common.Address     { log.DebugLog() return common.Address{} }
-func (dummyContractRef) Value() *big.Int             { log.DebugLog() return new(big.Int) }
+func (dummyContractRef) Address() common.Address     { log.DebugLog(); return common.Address{} }
+func (dummyContractRef) Value() *big.Int             { log.DebugLog(); return new(big.Int) }
 func (dummyContractRef) SetCode(common.Hash, []byte) { log.DebugLog()}
 func (d *dummyContractRef) ForEachStorage(callback func(key, value common.Hash) bool) { log.DebugLog()
 	d.calledForEach = true
@@ -39,7 +40,7 @@ func (d *dummyContractRef) SubBalance(amount *big.Int) { log.DebugLog()}
 func (d *dummyContractRef) AddBalance(amount *big.Int) { log.DebugLog()}
 func (d *dummyContractRef) SetBalance(*big.Int)        { log.DebugLog()}
 func (d *dummyContractRef) SetNonce(uint64)            { log.DebugLog()}
-func (d *dummyContractRef) Balance() *big.Int          { log.DebugLog() return new(big.Int) }
+func (d *dummyContractRef) Balance() *big.Int          { log.DebugLog(); return new(big.Int) }
 
 type dummyStateDB struct {
 	NoopStateDB
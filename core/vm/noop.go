@@ -67,8 +67,11 @@ func (NoopStateDB) SetCode(common.Address, []byte)
 func (NoopStateDB) GetCodeSize(common.Address) int                                     { log.DebugLog()
 																						   return 0 }
 func (NoopStateDB) AddRefund(uint64)                                                   { log.DebugLog() }
+func (NoopStateDB) SubRefund(uint64)                                                   { log.DebugLog() }
 func (NoopStateDB) GetRefund() uint64                                                  { log.DebugLog()
 																						   return 0 }
+func (NoopStateDB) GetCommittedState(common.Address, common.Hash) common.Hash          { log.DebugLog()
+																						   return common.Hash{} }
 func (NoopStateDB) GetState(common.Address, common.Hash) common.Hash                   { log.DebugLog()
 																						   return common.Hash{} }
 func (NoopStateDB) SetState(common.Address, common.Hash, common.Hash)                  { log.DebugLog() }
@@ -48,6 +48,11 @@ func (b *EthApiBackend) ChainConfig() *params.ChainConfig {
 	return b.eth.chainConfig
 }
 
+func (b *EthApiBackend) RPCGasCap() uint64 {
+	log.DebugLog()
+	return b.eth.config.RPCGasCap
+}
+
 func (b *EthApiBackend) CurrentBlock() *types.Block {
 	log.DebugLog()
 	return b.eth.blockchain.CurrentBlock()
@@ -19,6 +19,7 @@ package eth
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -26,6 +27,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
@@ -54,9 +56,10 @@ const (
 // TraceConfig holds extra parameters to trace functions.
 type TraceConfig struct {
 	*vm.LogConfig
-	Tracer  *string
-	Timeout *string
-	Reexec  *uint64
+	Tracer       *string
+	TracerConfig json.RawMessage
+	Timeout      *string
+	Reexec       *uint64
 }
 
 // txTraceResult is the result of a single transaction trace.
@@ -446,7 +449,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 		vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
 
 		vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{})
-		if _, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
 			failed = err
 			break
 		}
@@ -549,35 +552,79 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	return api.traceTx(ctx, msg, vmctx, statedb, config)
 }
 
+// TraceCall lets you trace a given eth_call. It collects the structured logs
+// created during the execution of EVM if the given transaction was added on
+// top of the provided block and returns them as a JSON object.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceConfig) (interface{}, error) { log.DebugLog()
+	// Fetch the block on top of which to trace the call
+	var block *types.Block
+
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block = api.eth.blockchain.GetBlockByHash(hash)
+		if block == nil {
+			return nil, fmt.Errorf("block %x not found", hash)
+		}
+		if blockNrOrHash.RequireCanonical && core.GetCanonicalHash(api.eth.ChainDb(), block.NumberU64()) != hash {
+			return nil, fmt.Errorf("block %x not canonical", hash)
+		}
+	} else {
+		number, _ := blockNrOrHash.Number()
+		switch number {
+		case rpc.PendingBlockNumber:
+			// The pending state is not persisted, so it cannot be regenerated
+			return nil, errors.New("tracing on top of pending is not supported")
+		case rpc.LatestBlockNumber:
+			block = api.eth.blockchain.CurrentBlock()
+		default:
+			block = api.eth.blockchain.GetBlockByNumber(uint64(number))
+		}
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+	}
+	// Regenerate the state after the block, if not available locally
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	statedb, err := api.computeStateDB(block, reexec)
+	if err != nil {
+		return nil, err
+	}
+	// Execute the call message on top of the block and trace it, bounding its
+	// gas by the block gas limit if there's no global cap
+	gasCap := api.eth.config.RPCGasCap
+	if gasCap == 0 {
+		gasCap = block.GasLimit()
+	}
+	msg := args.ToMessage(gasCap)
+	vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+
+	return api.traceTx(ctx, msg, vmctx, statedb, config)
+}
+
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
 func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceConfig) (interface{}, error) { log.DebugLog()
+	// Define a meaningful timeout of a single transaction trace
+	var err error
+	timeout := defaultTraceTimeout
+	if config != nil && config.Timeout != nil {
+		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+			return nil, err
+		}
+	}
 	// Assemble the structured logger or the JavaScript tracer
-	var (
-		tracer vm.Tracer
-		err    error
-	)
+	var tracer vm.Tracer
 	switch {
 	case config != nil && config.Tracer != nil:
-		// Define a meaningful timeout of a single transaction trace
-		timeout := defaultTraceTimeout
-		if config.Timeout != nil {
-			if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
-				return nil, err
-			}
-		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
+		// Constuct the native or JavaScript tracer to execute with
+		t, err := tracers.New(*config.Tracer, config.TracerConfig)
+		if err != nil {
 			return nil, err
 		}
-		// Handle timeouts and RPC cancellations
-		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
-		go func() {
-			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
-		}()
-		defer cancel()
+		tracer = t
 
 	case config == nil:
 		tracer = vm.NewStructLogger(nil)
@@ -588,21 +635,42 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	// Run the transaction with tracing enabled.
 	vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
 
-	ret, gas, failed, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
+	// Handle timeouts and RPC cancellations, aborting the execution so that the
+	// structured logs can't grow unbounded either
+	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+	go func() {
+		<-deadlineCtx.Done()
+		if t, ok := tracer.(tracers.Tracer); ok {
+			t.Stop(errors.New("execution timeout"))
+		}
+		vmenv.Cancel()
+	}()
+	defer cancel()
+
+	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %v", err)
 	}
 	// Depending on the tracer type, format and return the output
 	switch tracer := tracer.(type) {
 	case *vm.StructLogger:
+		if deadlineCtx.Err() != nil {
+			return nil, fmt.Errorf("tracing failed: execution %v", deadlineCtx.Err())
+		}
+		// If the execution was reverted, decode the reason if one was given
+		var reason string
+		if revert := result.Revert(); len(revert) > 0 {
+			reason, _ = abi.UnpackRevert(revert)
+		}
 		return &ethapi.ExecutionResult{
-			Gas:         gas,
-			Failed:      failed,
-			ReturnValue: fmt.Sprintf("%x", ret),
-			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
+			Gas:          result.UsedGas,
+			Failed:       result.Failed(),
+			ReturnValue:  fmt.Sprintf("%x", result.ReturnData),
+			RevertReason: reason,
+			StructLogs:   ethapi.FormatLogs(tracer.StructLogs()),
 		}, nil
 
-	case *tracers.Tracer:
+	case tracers.Tracer:
 		return tracer.GetResult()
 
 	default:
@@ -637,7 +705,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, ree
 		}
 		// Not yet the searched for transaction, execute on top of the current state
 		vmenv := vm.NewEVM(context, statedb, api.config, vm.Config{})
-		if _, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
 			return nil, vm.Context{}, nil, fmt.Errorf("tx %x failed: %v", tx.Hash(), err)
 		}
 		statedb.DeleteSuicides()
@@ -0,0 +1,179 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// tracerCounter is a contract incrementing its storage slot 0 and returning
+	// the new value, tracerLoop is one never terminating.
+	tracerCounter     = common.HexToAddress("0xc0de")
+	tracerCounterCode = common.FromHex("0x6000546001018060005560005260206000f3")
+	tracerLoop        = common.HexToAddress("0x1009")
+	tracerLoopCode    = common.FromHex("0x5b600056")
+)
+
+// newTracerTestAPI creates a debug API over a chain of the given length, every
+// block of which increments the counter contract once.
+func newTracerTestAPI(t *testing.T, blocks int, gasCap uint64) (*PrivateDebugAPI, []*types.Block) { log.DebugLog()
+	var (
+		db, _ = ethdb.NewMemDatabase()
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				testBank:      {Balance: big.NewInt(params.Ether)},
+				tracerCounter: {Code: tracerCounterCode, Balance: big.NewInt(0)},
+				tracerLoop:    {Code: tracerLoopCode, Balance: big.NewInt(0)},
+			},
+		}
+		genesis   = gspec.MustCommit(db)
+		signer    = types.HomesteadSigner{}
+		engine    = ethash.NewFaker()
+		chain, _  = core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+		generated []*types.Block
+	)
+	generated, _ = core.GenerateChain(gspec.Config, genesis, engine, db, blocks, func(i int, b *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), tracerCounter, new(big.Int), 100000, new(big.Int), nil), signer, testBankKey)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		b.AddTx(tx)
+	})
+	if _, err := chain.InsertChain(generated); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	eth := &Ethereum{
+		config:     &Config{RPCGasCap: gasCap},
+		chainDb:    db,
+		blockchain: chain,
+	}
+	return NewPrivateDebugAPI(gspec.Config, eth), append([]*types.Block{genesis}, generated...)
+}
+
+// Tests that calls are traced on top of the state of the requested block.
+func TestTraceCall(t *testing.T) { log.DebugLog()
+	api, blocks := newTracerTestAPI(t, 3, 0)
+	defer api.eth.blockchain.Stop()
+
+	args := ethapi.CallArgs{From: testBank, To: &tracerCounter}
+	tests := []struct {
+		block  rpc.BlockNumberOrHash
+		result string // Expected counter value returned, empty if failure
+	}{
+		{rpc.BlockNumberOrHashWithNumber(0), common.BigToHash(big.NewInt(1)).Hex()[2:]},
+		{rpc.BlockNumberOrHashWithNumber(2), common.BigToHash(big.NewInt(3)).Hex()[2:]},
+		{rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), common.BigToHash(big.NewInt(4)).Hex()[2:]},
+		{rpc.BlockNumberOrHashWithHash(blocks[1].Hash(), true), common.BigToHash(big.NewInt(2)).Hex()[2:]},
+		{rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber), ""},
+		{rpc.BlockNumberOrHashWithNumber(4), ""},
+		{rpc.BlockNumberOrHashWithHash(common.Hash{0x01}, false), ""},
+	}
+	for i, tt := range tests {
+		res, err := api.TraceCall(context.Background(), args, tt.block, nil)
+		if tt.result == "" {
+			if err == nil {
+				t.Errorf("test %d: trace succeeded, want failure", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to trace call: %v", i, err)
+			continue
+		}
+		result := res.(*ethapi.ExecutionResult)
+		if result.Failed || result.ReturnValue != tt.result {
+			t.Errorf("test %d: result mismatch: have %v/%s, want %s", i, result.Failed, result.ReturnValue, tt.result)
+		}
+		if len(result.StructLogs) == 0 {
+			t.Errorf("test %d: no structured logs collected", i)
+		}
+	}
+}
+
+// Tests that traced calls can't use more gas than the global gas cap.
+func TestTraceCallGasCap(t *testing.T) { log.DebugLog()
+	api, _ := newTracerTestAPI(t, 0, 50000)
+	defer api.eth.blockchain.Stop()
+
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	for _, gas := range []uint64{0, 1000000} {
+		args := ethapi.CallArgs{From: testBank, To: &tracerLoop, Gas: hexutil.Uint64(gas)}
+		res, err := api.TraceCall(context.Background(), args, latest, &TraceConfig{LogConfig: &vm.LogConfig{DisableStack: true, DisableMemory: true, DisableStorage: true}})
+		if err != nil {
+			t.Fatalf("gas %d: failed to trace call: %v", gas, err)
+		}
+		if result := res.(*ethapi.ExecutionResult); !result.Failed || result.Gas != 50000 {
+			t.Errorf("gas %d: result mismatch: have %v/%d, want failure using 50000 gas", gas, result.Failed, result.Gas)
+		}
+	}
+	// Allowances below the cap are kept
+	args := ethapi.CallArgs{From: testBank, To: &tracerLoop, Gas: 30000}
+	res, err := api.TraceCall(context.Background(), args, latest, &TraceConfig{LogConfig: &vm.LogConfig{DisableStack: true, DisableMemory: true, DisableStorage: true}})
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	if result := res.(*ethapi.ExecutionResult); result.Gas != 30000 {
+		t.Errorf("gas used mismatch: have %d, want 30000", result.Gas)
+	}
+}
+
+// Tests that traced calls without a global gas cap are bounded by the gas limit
+// of the block they are executed on top of.
+func TestTraceCallBlockGasLimit(t *testing.T) { log.DebugLog()
+	api, blocks := newTracerTestAPI(t, 0, 0)
+	defer api.eth.blockchain.Stop()
+
+	args := ethapi.CallArgs{From: testBank, To: &tracerLoop}
+	timeout := "1m"
+	config := &TraceConfig{LogConfig: &vm.LogConfig{DisableStack: true, DisableMemory: true, DisableStorage: true}, Timeout: &timeout}
+
+	res, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	if result := res.(*ethapi.ExecutionResult); !result.Failed || result.Gas != blocks[0].GasLimit() {
+		t.Errorf("result mismatch: have %v/%d, want failure using %d gas", result.Failed, result.Gas, blocks[0].GasLimit())
+	}
+}
+
+// Tests that traces with the structured logger are aborted on timeout.
+func TestTraceCallTimeout(t *testing.T) { log.DebugLog()
+	api, _ := newTracerTestAPI(t, 0, 0)
+	defer api.eth.blockchain.Stop()
+
+	args := ethapi.CallArgs{From: testBank, To: &tracerLoop}
+	timeout := "1ms"
+	if _, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), &TraceConfig{Timeout: &timeout}); err == nil {
+		t.Fatalf("trace exceeding the timeout succeeded")
+	}
+}
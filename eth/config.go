@@ -43,12 +43,13 @@ var DefaultConfig = Config{
 		DatasetsInMem:  1,
 		DatasetsOnDisk: 2,
 	},
-	NetworkId:     1,
-	LightPeers:    100,
-	DatabaseCache: 768,
-	TrieCache:     256,
-	TrieTimeout:   5 * time.Minute,
-	GasPrice:      big.NewInt(18 * params.Shannon),
+	NetworkId:        1,
+	LightPeers:       100,
+	DatabaseCache:    768,
+	FreezerThreshold: core.DefaultFreezerThreshold,
+	TrieCache:        256,
+	TrieTimeout:      5 * time.Minute,
+	GasPrice:         big.NewInt(18 * params.Shannon),
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
@@ -91,8 +92,11 @@ type Config struct {
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+	DatabaseFreezer    string `toml:",omitempty"` // Ancient chain segment store, disabled if empty
+	FreezerThreshold   uint64 `toml:",omitempty"` // Number of recent blocks kept out of the ancient store
 	TrieCache          int
 	TrieTimeout        time.Duration
+	SnapshotCache      int `toml:",omitempty"` // Memory allowance (MB) of the state snapshot, zero disables it
 
 	// Mining-related options
 	Etherbase    common.Address `toml:",omitempty"`
@@ -112,6 +116,10 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// RPCGasCap is the global gas cap for eth_call, eth_estimateGas and
+	// debug_traceCall (0 = no cap, traced calls fall back to the block gas limit)
+	RPCGasCap uint64 `toml:",omitempty"`
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 }
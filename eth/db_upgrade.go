@@ -50,7 +50,7 @@ func upgradeDeduplicateData(db ethdb.Database) func() error { log.DebugLog()
 
 	go func() {
 		// Create an iterator to read the entire database and covert old lookup entires
-		it := db.(*ethdb.LDBDatabase).NewIterator()
+		it := db.NewIterator()
 		defer func() {
 			if it != nil {
 				it.Release()
@@ -100,8 +100,7 @@ func upgradeDeduplicateData(db ethdb.Database) func() error { log.DebugLog()
 			converted++
 			if converted%100000 == 0 {
 				it.Release()
-				it = db.(*ethdb.LDBDatabase).NewIterator()
-				it.Seek(key)
+				it = db.NewIteratorWithRange(key, nil)
 
 				log.Info("Deduplicating database entries", "deduped", converted)
 			}
@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/snap"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
@@ -109,6 +110,9 @@ type Downloader struct {
 	syncStatsState       stateSyncStats
 	syncStatsLock        sync.RWMutex // Lock protecting the sync stats fields
 
+	snapSyncer *snap.Syncer // Snapshot syncer retrieving the state by ranges
+	snapSync   bool         // Whether the current cycle retrieves the state via snap
+
 	lightchain LightChain
 	blockchain BlockChain
 
@@ -226,6 +230,7 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 			processed: core.GetTrieSyncProgress(stateDb),
 		},
 		trackStateReq: make(chan *stateReq),
+		snapSyncer:    snap.NewSyncer(stateDb),
 	}
 	go dl.qosTuner()
 	go dl.stateFetcher()
@@ -262,6 +267,12 @@ func (d *Downloader) Progress() ethereum.SyncProgress { log.DebugLog()
 	}
 }
 
+// SnapSyncer returns the state syncer retrieving the state via the snap protocol,
+// to which the snap peers and their responses need to be delivered.
+func (d *Downloader) SnapSyncer() *snap.Syncer { log.DebugLog()
+	return d.snapSyncer
+}
+
 // Synchronising returns whether the downloader is currently retrieving blocks.
 func (d *Downloader) Synchronising() bool { log.DebugLog()
 	return atomic.LoadInt32(&d.synchronising) > 0
@@ -338,7 +349,7 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 // synchronise will select the peer and use it for synchronising. If an empty string is given
 // it will use the best peer possible and synchronize if its TD is higher than our own. If any of the
 // checks fail an error will be returned. This method is synchronous
-func (d *Downloader) synchronise(id string, hash common.Hash, td *big.Int, mode SyncMode) error { log.DebugLog()
+func (d *Downloader) synchronise(id string, hash common.Hash, td *big.Int, mode SyncMode) error { defer log.DebugLogSpan()()
 	// Mock out the synchronisation if testing
 	if d.synchroniseMock != nil {
 		return d.synchroniseMock(id, hash)
@@ -387,7 +398,18 @@ func (d *Downloader) synchronise(id string, hash common.Hash, td *big.Int, mode
 
 	defer d.Cancel() // No matter what, we can't leave the cancel channel open
 
-	// Set the requested sync mode, unless it's forbidden
+	// Set the requested sync mode, unless it's forbidden. Snap sync is fast sync
+	// retrieving the state by ranges, fall back to the node-by-node state sync if
+	// no peer can serve them.
+	d.snapSync = false
+	if mode == SnapSync {
+		if d.snapSyncer.Peers() == 0 {
+			log.Warn("No snap sync peers available, falling back to fast sync")
+		} else {
+			d.snapSync = true
+		}
+		mode = FastSync
+	}
 	d.mode = mode
 
 	// Retrieve the origin peer and initiate the downloading process
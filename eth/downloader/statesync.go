@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/crypto/sha3"
+	"github.com/ethereum/go-ethereum/eth/snap"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/trie"
@@ -214,6 +215,8 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync { log.DebugLog()
 type stateSync struct {
 	d *Downloader // Downloader instance to access and manage current peerset
 
+	root   common.Hash                // State root being synchronised
+	snap   bool                       // Whether to retrieve the state ranges via snap first
 	sched  *trie.TrieSync             // State trie sync scheduler defining the tasks
 	keccak hash.Hash                  // Keccak256 hasher to verify deliveries with
 	tasks  map[common.Hash]*stateTask // Set of tasks currently queued for retrieval
@@ -239,6 +242,8 @@ type stateTask struct {
 func newStateSync(d *Downloader, root common.Hash) *stateSync { log.DebugLog()
 	return &stateSync{
 		d:       d,
+		root:    root,
+		snap:    d.snapSync,
 		sched:   state.NewStateSync(root, d.stateDB),
 		keccak:  sha3.NewKeccak256(),
 		tasks:   make(map[common.Hash]*stateTask),
@@ -252,6 +257,21 @@ func newStateSync(d *Downloader, root common.Hash) *stateSync { log.DebugLog()
 // it finishes, and finally notifying any goroutines waiting for the loop to
 // finish.
 func (s *stateSync) run() { log.DebugLog()
+	// If snap sync is enabled, retrieve the bulk of the state by ranges first and
+	// only heal the remaining gaps node by node. If the snap peers go away, the
+	// healing simply falls back to a full node-by-node state sync.
+	if s.snap {
+		switch err := s.d.snapSyncer.Sync(s.root, s.cancel); err {
+		case nil:
+		case snap.ErrNoPeers:
+			log.Warn("Snap sync peers lost, falling back to fast sync", "root", s.root)
+		default:
+			s.err = errCancelStateFetch
+			close(s.done)
+			return
+		}
+		s.sched = state.NewStateSync(s.root, s.d.stateDB)
+	}
 	s.err = s.loop()
 	close(s.done)
 }
@@ -147,9 +147,8 @@ func benchmarkBloomBits(b *testing.B, sectionSize uint64) { log.DebugLog()
 }
 
 func forEachKey(db ethdb.Database, startPrefix, endPrefix []byte, fn func(key []byte)) { log.DebugLog()
-	it := db.(*ethdb.LDBDatabase).NewIterator()
-	it.Seek(startPrefix)
-	for it.Valid() {
+	it := db.NewIteratorWithRange(startPrefix, nil)
+	for it.Next() {
 		key := it.Key()
 		cmpLen := len(key)
 		if len(endPrefix) < cmpLen {
@@ -159,7 +158,6 @@ func forEachKey(db ethdb.Database, startPrefix, endPrefix []byte, fn func(key []
 			break
 		}
 		fn(common.CopyBytes(key))
-		it.Next()
 	}
 	it.Release()
 }
@@ -26,6 +26,9 @@ func (c Config) MarshalTOML() (interface{}, error) { log.DebugLog()
 		SkipBcVersionCheck      bool `toml:"-"`
 		DatabaseHandles         int  `toml:"-"`
 		DatabaseCache           int
+		DatabaseFreezer         string         `toml:",omitempty"`
+		FreezerThreshold        uint64         `toml:",omitempty"`
+		SnapshotCache           int            `toml:",omitempty"`
 		Etherbase               common.Address `toml:",omitempty"`
 		MinerThreads            int            `toml:",omitempty"`
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
@@ -34,6 +37,7 @@ func (c Config) MarshalTOML() (interface{}, error) { log.DebugLog()
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
+		RPCGasCap               uint64 `toml:",omitempty"`
 		DocRoot                 string `toml:"-"`
 	}
 	var enc Config
@@ -45,6 +49,9 @@ func (c Config) MarshalTOML() (interface{}, error) { log.DebugLog()
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.DatabaseFreezer = c.DatabaseFreezer
+	enc.FreezerThreshold = c.FreezerThreshold
+	enc.SnapshotCache = c.SnapshotCache
 	enc.Etherbase = c.Etherbase
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
@@ -53,6 +60,7 @@ func (c Config) MarshalTOML() (interface{}, error) { log.DebugLog()
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.RPCGasCap = c.RPCGasCap
 	enc.DocRoot = c.DocRoot
 	return &enc, nil
 }
@@ -67,6 +75,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error { log.De
 		SkipBcVersionCheck      *bool `toml:"-"`
 		DatabaseHandles         *int  `toml:"-"`
 		DatabaseCache           *int
+		DatabaseFreezer         *string         `toml:",omitempty"`
+		FreezerThreshold        *uint64         `toml:",omitempty"`
+		SnapshotCache           *int            `toml:",omitempty"`
 		Etherbase               *common.Address `toml:",omitempty"`
 		MinerThreads            *int            `toml:",omitempty"`
 		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
@@ -75,6 +86,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error { log.De
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
+		RPCGasCap               *uint64 `toml:",omitempty"`
 		DocRoot                 *string `toml:"-"`
 	}
 	var dec Config
@@ -105,6 +117,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error { log.De
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.DatabaseFreezer != nil {
+		c.DatabaseFreezer = *dec.DatabaseFreezer
+	}
+	if dec.FreezerThreshold != nil {
+		c.FreezerThreshold = *dec.FreezerThreshold
+	}
+	if dec.SnapshotCache != nil {
+		c.SnapshotCache = *dec.SnapshotCache
+	}
 	if dec.Etherbase != nil {
 		c.Etherbase = *dec.Etherbase
 	}
@@ -129,6 +150,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error { log.De
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.RPCGasCap != nil {
+		c.RPCGasCap = *dec.RPCGasCap
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -0,0 +1,342 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// softResponseLimit is the target maximum size of replies to data retrievals.
+	softResponseLimit = 2 * 1024 * 1024
+
+	// maxCodeLookups is the maximum number of bytecodes to serve. This number is
+	// there to limit the number of disk lookups.
+	maxCodeLookups = 1024
+)
+
+// Handler serves the snap protocol: it answers the range queries of remote peers
+// from the local state and feeds the responses to our own queries into the state
+// syncer.
+type Handler struct {
+	state  state.Database // State database to serve the range queries from
+	syncer *Syncer        // State syncer to deliver the responses to (nil = serve only)
+}
+
+// NewHandler creates a snap protocol handler serving the state of the given chain
+// and feeding responses into the given syncer.
+func NewHandler(chain *core.BlockChain, syncer *Syncer) *Handler {
+	log.DebugLog()
+	return &Handler{
+		state:  chain.StateCache(),
+		syncer: syncer,
+	}
+}
+
+// Protocols returns the p2p protocol descriptors of all the supported snap versions.
+func (h *Handler) Protocols() []p2p.Protocol {
+	log.DebugLog()
+	protocols := make([]p2p.Protocol, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		version := version // Closure for the run
+		protocols[i] = p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return h.handle(newPeer(version, p, rw))
+			},
+		}
+	}
+	return protocols
+}
+
+// handle is the callback invoked to manage the life cycle of a snap peer. When
+// this function terminates, the peer is disconnected.
+func (h *Handler) handle(peer *Peer) error {
+	log.DebugLog()
+	peer.Log().Debug("Snapshot peer connected", "name", peer.Name())
+
+	if h.syncer != nil {
+		h.syncer.Register(peer)
+		defer h.syncer.Unregister(peer.ID())
+	}
+	for {
+		if err := h.handleMessage(peer); err != nil {
+			peer.Log().Debug("Message handling failed in `snap`", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMessage is invoked whenever an inbound message is received from a remote
+// peer on the `snap` protocol. The remote connection is torn down upon returning
+// any error.
+func (h *Handler) handleMessage(peer *Peer) error {
+	log.DebugLog()
+	// Read the next message from the remote peer, and ensure it's fully consumed
+	msg, err := peer.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return fmt.Errorf("%v: %v > %v", errMsgTooLarge, msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	// Handle the message depending on its contents
+	switch msg.Code {
+	case GetAccountRangeMsg:
+		var req GetAccountRangePacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%v: message %v: %v", errDecode, msg, err)
+		}
+		accounts, proof := ServiceGetAccountRangeQuery(h.state, &req)
+		return p2p.Send(peer.rw, AccountRangeMsg, &AccountRangePacket{
+			ID:       req.ID,
+			Accounts: accounts,
+			Proof:    proof,
+		})
+
+	case AccountRangeMsg:
+		var res AccountRangePacket
+		if err := msg.Decode(&res); err != nil {
+			return fmt.Errorf("%v: message %v: %v", errDecode, msg, err)
+		}
+		if h.syncer == nil {
+			return nil
+		}
+		return h.syncer.OnAccounts(peer, res.ID, res.Accounts, res.Proof)
+
+	case GetStorageRangesMsg:
+		var req GetStorageRangesPacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%v: message %v: %v", errDecode, msg, err)
+		}
+		slots, proof := ServiceGetStorageRangesQuery(h.state, &req)
+		return p2p.Send(peer.rw, StorageRangesMsg, &StorageRangesPacket{
+			ID:    req.ID,
+			Slots: slots,
+			Proof: proof,
+		})
+
+	case StorageRangesMsg:
+		var res StorageRangesPacket
+		if err := msg.Decode(&res); err != nil {
+			return fmt.Errorf("%v: message %v: %v", errDecode, msg, err)
+		}
+		if h.syncer == nil {
+			return nil
+		}
+		return h.syncer.OnStorage(peer, res.ID, res.Slots, res.Proof)
+
+	case GetByteCodesMsg:
+		var req GetByteCodesPacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%v: message %v: %v", errDecode, msg, err)
+		}
+		return p2p.Send(peer.rw, ByteCodesMsg, &ByteCodesPacket{
+			ID:    req.ID,
+			Codes: ServiceGetByteCodesQuery(h.state, &req),
+		})
+
+	case ByteCodesMsg:
+		var res ByteCodesPacket
+		if err := msg.Decode(&res); err != nil {
+			return fmt.Errorf("%v: message %v: %v", errDecode, msg, err)
+		}
+		if h.syncer == nil {
+			return nil
+		}
+		return h.syncer.OnByteCodes(peer, res.ID, res.Codes)
+
+	default:
+		return fmt.Errorf("%v: %v", errInvalidMsgCode, msg.Code)
+	}
+}
+
+// ServiceGetAccountRangeQuery assembles the response to an account range query.
+// The accounts are accompanied by the Merkle proofs of the origin and the last
+// returned account, or the limit if there are none within the range. If the
+// requested state is not available, nothing is returned.
+func ServiceGetAccountRangeQuery(db state.Database, req *GetAccountRangePacket) ([]*AccountData, [][]byte) {
+	log.DebugLog()
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	tr, err := trie.New(req.Root, db.TrieDB())
+	if err != nil {
+		return nil, nil
+	}
+	var (
+		accounts []*AccountData
+		size     uint64
+	)
+	it := trie.NewIterator(tr.NodeIterator(req.Origin[:]))
+	for it.Next() {
+		hash := common.BytesToHash(it.Key)
+		if bytes.Compare(hash[:], req.Limit[:]) > 0 {
+			break
+		}
+		accounts = append(accounts, &AccountData{Hash: hash, Body: common.CopyBytes(it.Value)})
+
+		if size += uint64(common.HashLength + len(it.Value)); size >= req.Bytes {
+			break
+		}
+	}
+	if it.Err != nil {
+		return nil, nil
+	}
+	// Generate the Merkle proofs for the edges of the served range
+	last := req.Limit
+	if len(accounts) > 0 {
+		last = accounts[len(accounts)-1].Hash
+	}
+	proof, _ := ethdb.NewMemDatabase()
+	if err := tr.ProveRange(req.Origin[:], last[:], proof); err != nil {
+		log.Warn("Failed to prove account range", "origin", req.Origin, "last", last, "err", err)
+		return nil, nil
+	}
+	return accounts, proofList(proof)
+}
+
+// ServiceGetStorageRangesQuery assembles the response to a storage ranges query.
+// Accounts are served in full until the byte budget runs out. The last account
+// is proven if it was served partially, or if it was requested from an origin.
+func ServiceGetStorageRangesQuery(db state.Database, req *GetStorageRangesPacket) ([][]*StorageData, [][]byte) {
+	log.DebugLog()
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	accTrie, err := trie.New(req.Root, db.TrieDB())
+	if err != nil {
+		return nil, nil
+	}
+	var (
+		slots [][]*StorageData
+		proof [][]byte
+		size  uint64
+	)
+	for i, account := range req.Accounts {
+		// If we've exceeded the requested data limit, abort without opening a new
+		// storage range (that we'd need to prove due to exceeded size)
+		if size >= req.Bytes {
+			break
+		}
+		// The first account might start from a different origin and end sooner
+		var origin, limit []byte
+		if i == 0 {
+			origin, limit = req.Origin, req.Limit
+		}
+		blob, err := accTrie.TryGet(account[:])
+		if err != nil || blob == nil {
+			return nil, nil
+		}
+		var acc state.Account
+		if err := rlp.DecodeBytes(blob, &acc); err != nil {
+			return nil, nil
+		}
+		stTrie, err := trie.New(acc.Root, db.TrieDB())
+		if err != nil {
+			return nil, nil
+		}
+		var (
+			storage []*StorageData
+			abort   bool
+		)
+		it := trie.NewIterator(stTrie.NodeIterator(origin))
+		for it.Next() {
+			if limit != nil && bytes.Compare(it.Key, limit) > 0 {
+				break
+			}
+			storage = append(storage, &StorageData{Hash: common.BytesToHash(it.Key), Body: common.CopyBytes(it.Value)})
+
+			if size += uint64(common.HashLength + len(it.Value)); size >= req.Bytes {
+				abort = true
+				break
+			}
+		}
+		if it.Err != nil {
+			return nil, nil
+		}
+		slots = append(slots, storage)
+
+		// If the range was served partially, generate the edge proofs and stop
+		if len(origin) > 0 || abort {
+			db, _ := ethdb.NewMemDatabase()
+			if err := stTrie.Prove(common.BytesToHash(origin).Bytes(), 0, db); err != nil {
+				log.Warn("Failed to prove storage range", "origin", common.BytesToHash(origin), "err", err)
+				return nil, nil
+			}
+			if len(storage) > 0 {
+				if err := stTrie.Prove(storage[len(storage)-1].Hash[:], 0, db); err != nil {
+					log.Warn("Failed to prove storage range", "last", storage[len(storage)-1].Hash, "err", err)
+					return nil, nil
+				}
+			}
+			proof = proofList(db)
+			break
+		}
+	}
+	return slots, proof
+}
+
+// ServiceGetByteCodesQuery assembles the response to a byte codes query. Unknown
+// codes are skipped, the requester is expected to detect them.
+func ServiceGetByteCodesQuery(db state.Database, req *GetByteCodesPacket) [][]byte {
+	log.DebugLog()
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	if len(req.Hashes) > maxCodeLookups {
+		req.Hashes = req.Hashes[:maxCodeLookups]
+	}
+	var (
+		codes [][]byte
+		size  uint64
+	)
+	for _, hash := range req.Hashes {
+		if blob, err := db.ContractCode(common.Hash{}, hash); err == nil && len(blob) > 0 {
+			codes = append(codes, blob)
+			if size += uint64(len(blob)); size >= req.Bytes {
+				break
+			}
+		}
+	}
+	return codes
+}
+
+// proofList flattens a proof database into the list of its trie nodes.
+func proofList(db *ethdb.MemDatabase) [][]byte {
+	log.DebugLog()
+	var nodes [][]byte
+	for _, key := range db.Keys() {
+		node, _ := db.Get(key)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
@@ -0,0 +1,1223 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+
+	// errCancelled is returned if a sync cycle is interrupted by a cancellation.
+	errCancelled = errors.New("sync cancelled")
+
+	// ErrNoPeers is returned if a sync cycle runs out of snap peers to retrieve
+	// the state ranges from.
+	ErrNoPeers = errors.New("no snap peers available")
+)
+
+const (
+	// accountChunks is the number of contiguous account ranges the account trie
+	// is split into and retrieved concurrently. Each chunk is assembled into its
+	// own subtrie, only the few nodes above the chunks are left for healing.
+	accountChunks = 256
+
+	// maxRequestSize is the maximum number of bytes to request from a remote peer.
+	maxRequestSize = 512 * 1024
+
+	// maxStorageSetFetch is the maximum number of accounts to request the storage
+	// slots of in a single query.
+	maxStorageSetFetch = 128
+
+	// maxCodeRequestCount is the maximum number of bytecodes to request in a
+	// single query.
+	maxCodeRequestCount = 64
+
+	// requestTimeout is the maximum time a peer is allowed to spend on serving a
+	// single network request.
+	requestTimeout = 10 * time.Second
+
+	// logInterval is the time between sync progress reports.
+	logInterval = 8 * time.Second
+)
+
+// SyncPeer abstracts out the methods required for a peer to be synced against
+// with the goal of allowing the construction of mock peers without the full
+// blown networking.
+type SyncPeer interface {
+	// ID retrieves the peer's unique identifier.
+	ID() string
+
+	// RequestAccountRange fetches a batch of accounts rooted in a specific account
+	// trie, starting with the origin.
+	RequestAccountRange(id uint64, root, origin, limit common.Hash, bytes uint64) error
+
+	// RequestStorageRanges fetches a batch of storage slots belonging to one or
+	// more accounts.
+	RequestStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin, limit []byte, bytes uint64) error
+
+	// RequestByteCodes fetches a batch of bytecodes by hash.
+	RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error
+
+	// Log retrieves the peer's own contextual logger.
+	Log() log.Logger
+}
+
+// accountTask represents one contiguous chunk of the account trie, along with
+// the subtrie assembled from its accounts. The subtrie is only committed after
+// all the storage tries and bytecodes referenced from the chunk are retrieved,
+// so that the trie healing can rely on present nodes being complete.
+type accountTask struct {
+	origin common.Hash // First account hash covered by the chunk
+	last   common.Hash // Last account hash covered by the chunk
+	next   common.Hash // Next account hash to retrieve
+
+	trie    *trie.Trie // Subtrie assembled from the retrieved accounts
+	pending int        // Number of storage and code retrievals blocking the commit
+
+	ranged    bool                // Whether all the accounts of the chunk were retrieved
+	assigned  bool                // Whether a request is in flight for the chunk
+	abandoned bool                // Whether the chunk failed and is left for healing
+	done      bool                // Whether the chunk was committed or abandoned
+	attempts  map[string]struct{} // Peers that already failed to serve the chunk
+}
+
+// storageTask represents the retrieval of a single storage trie.
+type storageTask struct {
+	account common.Hash  // Hash of the account owning the storage trie
+	root    common.Hash  // Storage root the retrieved slots must hash to
+	owner   *accountTask // Account chunk waiting on this storage trie
+
+	next []byte     // Next slot hash to retrieve (nil = retrieval not started)
+	trie *trie.Trie // Storage trie assembled from the retrieved slots
+
+	assigned bool                // Whether a request is in flight for the trie
+	attempts map[string]struct{} // Peers that already failed to serve the trie
+}
+
+// codeTask represents the retrieval of a single contract bytecode.
+type codeTask struct {
+	owners []*accountTask // Account chunks waiting on this bytecode
+
+	assigned bool                // Whether a request is in flight for the code
+	attempts map[string]struct{} // Peers that already failed to serve the code
+}
+
+// accountRequest tracks a pending account range request.
+type accountRequest struct {
+	peer string       // Peer to which this request is assigned
+	id   uint64       // Request ID of this request
+	root common.Hash  // State root the request is made against
+	task *accountTask // Account chunk the request belongs to
+
+	origin common.Hash // First account requested
+	limit  common.Hash // Last account requested
+
+	timeout *time.Timer   // Timer to track delivery timeout
+	stale   chan struct{} // Channel to signal the sync cycle terminated
+}
+
+// accountResponse is an already verified account range response.
+type accountResponse struct {
+	req *accountRequest // Original request this response belongs to
+
+	hashes   []common.Hash    // Account hashes in the returned range
+	blobs    [][]byte         // Consensus encoded accounts in the returned range
+	accounts []*state.Account // Decoded accounts in the returned range
+	cont     bool             // Whether the chunk has more accounts to retrieve
+}
+
+// storageRequest tracks a pending storage ranges request.
+type storageRequest struct {
+	peer  string         // Peer to which this request is assigned
+	id    uint64         // Request ID of this request
+	root  common.Hash    // State root the request is made against
+	tasks []*storageTask // Storage tries the request belongs to
+
+	origin []byte // First slot requested, for a continuation of a large trie
+
+	timeout *time.Timer   // Timer to track delivery timeout
+	stale   chan struct{} // Channel to signal the sync cycle terminated
+}
+
+// storageResponse is an already verified storage ranges response.
+type storageResponse struct {
+	req *storageRequest // Original request this response belongs to
+
+	slots [][]*StorageData // Storage slots of the served accounts
+	cont  bool             // Whether the last served trie has more slots to retrieve
+}
+
+// codeRequest tracks a pending bytecode request.
+type codeRequest struct {
+	peer   string        // Peer to which this request is assigned
+	id     uint64        // Request ID of this request
+	hashes []common.Hash // Bytecode hashes requested
+
+	timeout *time.Timer   // Timer to track delivery timeout
+	stale   chan struct{} // Channel to signal the sync cycle terminated
+}
+
+// codeResponse is an already verified bytecode response.
+type codeResponse struct {
+	req   *codeRequest           // Original request this response belongs to
+	codes map[common.Hash][]byte // Delivered bytecodes keyed by hash
+}
+
+// Syncer is an Ethereum account and storage trie syncer based on snapshots and
+// the snap protocol. Its purpose is to download all the accounts and storage
+// slots from remote peers and reassemble chunks of the state trie, on top of
+// which a state sync can be run to fix any gaps / overlaps.
+type Syncer struct {
+	db     ethdb.Database // Database to store the trie nodes into (and dedup)
+	triedb *trie.Database // Scratch database to assemble the subtries in
+
+	root         common.Hash               // Current state trie root being synced
+	tasks        []*accountTask            // Account chunks to retrieve and assemble
+	storageTasks []*storageTask            // Storage tries to retrieve and assemble
+	codeTasks    map[common.Hash]*codeTask // Bytecodes to retrieve
+
+	peers  map[string]SyncPeer // Currently active peers to download from
+	idlers map[string]struct{} // Peers that aren't serving requests
+	update chan struct{}       // Notification channel for possible sync progression
+
+	reqID       uint64                     // Request ID counter to match up responses
+	accountReqs map[uint64]*accountRequest // Account requests currently running
+	storageReqs map[uint64]*storageRequest // Storage requests currently running
+	codeReqs    map[uint64]*codeRequest    // Bytecode requests currently running
+
+	accountResps chan *accountResponse // Verified account range responses
+	storageResps chan *storageResponse // Verified storage range responses
+	codeResps    chan *codeResponse    // Verified bytecode responses
+
+	accountFails chan *accountRequest // Failed account range requests to revert
+	storageFails chan *storageRequest // Failed storage range requests to revert
+	codeFails    chan *codeRequest    // Failed bytecode requests to revert
+
+	stale chan struct{} // Channel closed when the current sync cycle terminates
+
+	accountSynced  uint64             // Number of accounts downloaded
+	storageSynced  uint64             // Number of storage slots downloaded
+	bytecodeSynced uint64             // Number of bytecodes downloaded
+	bytesSynced    common.StorageSize // Number of bytes downloaded
+	logged         time.Time          // Timestamp when progress was last logged
+
+	lock sync.RWMutex // Protects fields that can change outside of sync (peers, reqs, root)
+}
+
+// NewSyncer creates a new snapshot syncer to download the Ethereum state over the
+// snap protocol.
+func NewSyncer(db ethdb.Database) *Syncer {
+	log.DebugLog()
+	return &Syncer{
+		db:           db,
+		triedb:       trie.NewDatabase(db),
+		codeTasks:    make(map[common.Hash]*codeTask),
+		peers:        make(map[string]SyncPeer),
+		idlers:       make(map[string]struct{}),
+		update:       make(chan struct{}, 1),
+		accountReqs:  make(map[uint64]*accountRequest),
+		storageReqs:  make(map[uint64]*storageRequest),
+		codeReqs:     make(map[uint64]*codeRequest),
+		accountResps: make(chan *accountResponse),
+		storageResps: make(chan *storageResponse),
+		codeResps:    make(chan *codeResponse),
+		accountFails: make(chan *accountRequest),
+		storageFails: make(chan *storageRequest),
+		codeFails:    make(chan *codeRequest),
+	}
+}
+
+// Register injects a new data source into the syncer's peerset.
+func (s *Syncer) Register(peer SyncPeer) error {
+	log.DebugLog()
+	id := peer.ID()
+
+	s.lock.Lock()
+	if _, ok := s.peers[id]; ok {
+		s.lock.Unlock()
+		log.Error("Snap peer already registered", "id", id)
+		return errors.New("already registered")
+	}
+	s.peers[id] = peer
+	s.idlers[id] = struct{}{}
+	s.lock.Unlock()
+
+	// Notify any active syncs that a new peer can be assigned data
+	s.notify()
+	return nil
+}
+
+// Unregister removes a data source from the syncer's peerset, reverting all the
+// requests in flight to it.
+func (s *Syncer) Unregister(id string) error {
+	log.DebugLog()
+	s.lock.Lock()
+	if _, ok := s.peers[id]; !ok {
+		s.lock.Unlock()
+		log.Error("Snap peer not registered", "id", id)
+		return errors.New("not registered")
+	}
+	delete(s.peers, id)
+	delete(s.idlers, id)
+
+	var (
+		accountFails []*accountRequest
+		storageFails []*storageRequest
+		codeFails    []*codeRequest
+	)
+	for reqID, req := range s.accountReqs {
+		if req.peer == id {
+			req.timeout.Stop()
+			delete(s.accountReqs, reqID)
+			accountFails = append(accountFails, req)
+		}
+	}
+	for reqID, req := range s.storageReqs {
+		if req.peer == id {
+			req.timeout.Stop()
+			delete(s.storageReqs, reqID)
+			storageFails = append(storageFails, req)
+		}
+	}
+	for reqID, req := range s.codeReqs {
+		if req.peer == id {
+			req.timeout.Stop()
+			delete(s.codeReqs, reqID)
+			codeFails = append(codeFails, req)
+		}
+	}
+	s.lock.Unlock()
+
+	// Revert all the requests to the dropped peer outside of the lock
+	for _, req := range accountFails {
+		select {
+		case s.accountFails <- req:
+		case <-req.stale:
+		}
+	}
+	for _, req := range storageFails {
+		select {
+		case s.storageFails <- req:
+		case <-req.stale:
+		}
+	}
+	for _, req := range codeFails {
+		select {
+		case s.codeFails <- req:
+		case <-req.stale:
+		}
+	}
+	s.notify()
+	return nil
+}
+
+// Peers returns the number of snap peers currently registered.
+func (s *Syncer) Peers() int {
+	log.DebugLog()
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return len(s.peers)
+}
+
+// notify signals the sync loop that it might be able to make progress.
+func (s *Syncer) notify() {
+	log.DebugLog()
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+}
+
+// Sync starts (or resumes a previous) sync cycle to iterate over a state trie
+// with the given root and reconstruct the chunks of its account trie, along with
+// all the storage tries and bytecodes they reference. Progress is kept across
+// cycles, chunks not yet completed are restarted if the root changes.
+//
+// The reconstructed state is not complete: the few trie nodes above the chunks,
+// and any chunk that could not be retrieved, are left to be healed by a regular
+// trie sync afterwards.
+func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
+	log.DebugLog()
+	s.lock.Lock()
+	if s.tasks == nil || s.root != root {
+		s.resetTasks(root)
+	}
+	// Any request of a previous cycle was reverted, clear all assignments
+	for _, task := range s.tasks {
+		task.assigned = false
+	}
+	for _, task := range s.storageTasks {
+		task.assigned = false
+	}
+	for _, task := range s.codeTasks {
+		task.assigned = false
+	}
+	s.stale = make(chan struct{})
+	s.lock.Unlock()
+
+	defer s.cleanup()
+
+	log.Debug("Starting snapshot sync cycle", "root", root)
+	for {
+		if s.complete() {
+			s.report(true)
+			return nil
+		}
+		if s.Peers() == 0 {
+			return ErrNoPeers
+		}
+		// Assign all the data retrieval tasks to any free peers, finishing up the
+		// already started chunks before moving on to new ones
+		s.assignCodeTasks()
+		s.assignStorageTasks()
+		s.assignAccountTasks()
+
+		// Abandoning tasks during assignment might have finished the cycle
+		if s.complete() {
+			continue
+		}
+		// Wait for something to happen
+		select {
+		case <-s.update:
+			// Something happened (new peer, delivery, timeout), recheck tasks
+		case <-cancel:
+			return errCancelled
+
+		case res := <-s.accountResps:
+			s.processAccountResponse(res)
+		case res := <-s.storageResps:
+			s.processStorageResponse(res)
+		case res := <-s.codeResps:
+			s.processBytecodeResponse(res)
+
+		case req := <-s.accountFails:
+			s.revertAccountRequest(req)
+		case req := <-s.storageFails:
+			s.revertStorageRequest(req)
+		case req := <-s.codeFails:
+			s.revertCodeRequest(req)
+		}
+		s.report(false)
+	}
+}
+
+// resetTasks restarts all the incomplete account chunks against a new state root,
+// dropping any partial progress. Completed chunks are kept, their nodes are either
+// still valid, or will be fixed by the trie healing.
+func (s *Syncer) resetTasks(root common.Hash) {
+	log.DebugLog()
+	if s.tasks == nil {
+		for i := 0; i < accountChunks; i++ {
+			var origin, last common.Hash
+			origin[0] = byte(i * 256 / accountChunks)
+			last[0] = byte((i+1)*256/accountChunks - 1)
+			for j := 1; j < common.HashLength; j++ {
+				last[j] = 0xff
+			}
+			s.tasks = append(s.tasks, &accountTask{origin: origin, last: last})
+		}
+	}
+	for _, task := range s.tasks {
+		if task.done {
+			continue
+		}
+		task.next = task.origin
+		task.trie, _ = trie.New(common.Hash{}, s.triedb)
+		task.pending = 0
+		task.ranged = false
+		task.abandoned = false
+		task.attempts = make(map[string]struct{})
+	}
+	s.storageTasks = nil
+	s.codeTasks = make(map[common.Hash]*codeTask)
+	s.root = root
+}
+
+// complete returns whether all the account chunks have been committed or given
+// up on.
+func (s *Syncer) complete() bool {
+	log.DebugLog()
+	for _, task := range s.tasks {
+		if !task.done {
+			return false
+		}
+	}
+	return true
+}
+
+// cleanup terminates a sync cycle, dropping all the requests in flight. Late
+// responses to them will be ignored.
+func (s *Syncer) cleanup() {
+	log.DebugLog()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	close(s.stale)
+	for id, req := range s.accountReqs {
+		req.timeout.Stop()
+		delete(s.accountReqs, id)
+	}
+	for id, req := range s.storageReqs {
+		req.timeout.Stop()
+		delete(s.storageReqs, id)
+	}
+	for id, req := range s.codeReqs {
+		req.timeout.Stop()
+		delete(s.codeReqs, id)
+	}
+	for id := range s.peers {
+		s.idlers[id] = struct{}{}
+	}
+}
+
+// exhausted returns whether all the currently connected peers already failed to
+// serve a task. The caller must hold the lock.
+func (s *Syncer) exhausted(attempts map[string]struct{}) bool {
+	log.DebugLog()
+	for id := range s.peers {
+		if _, ok := attempts[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// idlePeer returns an idle peer which hasn't yet failed a task, or nil if no
+// such peer exists. The caller must hold the lock.
+func (s *Syncer) idlePeer(attempts map[string]struct{}) SyncPeer {
+	log.DebugLog()
+	for id := range s.idlers {
+		if _, ok := attempts[id]; !ok {
+			return s.peers[id]
+		}
+	}
+	return nil
+}
+
+// assignAccountTasks attempts to match idle peers to pending account range
+// retrievals.
+func (s *Syncer) assignAccountTasks() {
+	log.DebugLog()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, task := range s.tasks {
+		if len(s.idlers) == 0 {
+			return
+		}
+		if task.done || task.ranged || task.assigned {
+			continue
+		}
+		if s.exhausted(task.attempts) {
+			log.Debug("Account range failed with all peers", "origin", task.origin)
+			task.abandoned = true
+			s.commitAccountTask(task)
+			continue
+		}
+		peer := s.idlePeer(task.attempts)
+		if peer == nil {
+			continue
+		}
+		s.reqID++
+		req := &accountRequest{
+			peer:   peer.ID(),
+			id:     s.reqID,
+			root:   s.root,
+			task:   task,
+			origin: task.next,
+			limit:  task.last,
+			stale:  s.stale,
+		}
+		req.timeout = time.AfterFunc(requestTimeout, func() {
+			peer.Log().Debug("Account range request timed out", "reqid", req.id)
+			s.scheduleRevertAccountRequest(req)
+		})
+		s.accountReqs[req.id] = req
+		delete(s.idlers, req.peer)
+		task.assigned = true
+
+		go func(peer SyncPeer, req *accountRequest) {
+			if err := peer.RequestAccountRange(req.id, req.root, req.origin, req.limit, maxRequestSize); err != nil {
+				peer.Log().Debug("Failed to request account range", "err", err)
+				s.scheduleRevertAccountRequest(req)
+			}
+		}(peer, req)
+	}
+}
+
+// assignStorageTasks attempts to match idle peers to pending storage range
+// retrievals. Tries not yet started are batched together, continuations of
+// large tries are requested one by one.
+func (s *Syncer) assignStorageTasks() {
+	log.DebugLog()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i := 0; i < len(s.storageTasks) && len(s.idlers) > 0; i++ {
+		task := s.storageTasks[i]
+		if task.assigned {
+			continue
+		}
+		if s.exhausted(task.attempts) {
+			log.Debug("Storage range failed with all peers", "account", task.account, "root", task.root)
+			s.storageTasks = append(s.storageTasks[:i], s.storageTasks[i+1:]...)
+			i--
+
+			task.owner.abandoned = true
+			task.owner.pending--
+			s.commitAccountTask(task.owner)
+			continue
+		}
+		peer := s.idlePeer(task.attempts)
+		if peer == nil {
+			continue
+		}
+		s.reqID++
+		req := &storageRequest{
+			peer:   peer.ID(),
+			id:     s.reqID,
+			root:   s.root,
+			tasks:  []*storageTask{task},
+			origin: task.next,
+			stale:  s.stale,
+		}
+		task.assigned = true
+
+		// If the task is a fresh trie, batch up other fresh ones too
+		if task.next == nil {
+			for _, other := range s.storageTasks[i+1:] {
+				if len(req.tasks) >= maxStorageSetFetch {
+					break
+				}
+				if other.assigned || other.next != nil {
+					continue
+				}
+				if _, ok := other.attempts[req.peer]; ok {
+					continue
+				}
+				other.assigned = true
+				req.tasks = append(req.tasks, other)
+			}
+		}
+		accounts := make([]common.Hash, len(req.tasks))
+		for j, task := range req.tasks {
+			accounts[j] = task.account
+		}
+		req.timeout = time.AfterFunc(requestTimeout, func() {
+			peer.Log().Debug("Storage request timed out", "reqid", req.id)
+			s.scheduleRevertStorageRequest(req)
+		})
+		s.storageReqs[req.id] = req
+		delete(s.idlers, req.peer)
+
+		go func(peer SyncPeer, req *storageRequest) {
+			if err := peer.RequestStorageRanges(req.id, req.root, accounts, req.origin, nil, maxRequestSize); err != nil {
+				peer.Log().Debug("Failed to request storage ranges", "err", err)
+				s.scheduleRevertStorageRequest(req)
+			}
+		}(peer, req)
+	}
+}
+
+// assignCodeTasks attempts to match idle peers to pending bytecode retrievals.
+func (s *Syncer) assignCodeTasks() {
+	log.DebugLog()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for len(s.idlers) > 0 {
+		var (
+			peer   SyncPeer
+			hashes []common.Hash
+		)
+		for hash, task := range s.codeTasks {
+			if task.assigned {
+				continue
+			}
+			if peer == nil {
+				if s.exhausted(task.attempts) {
+					log.Debug("Bytecode failed with all peers", "hash", hash)
+					delete(s.codeTasks, hash)
+					for _, owner := range task.owners {
+						owner.abandoned = true
+						owner.pending--
+						s.commitAccountTask(owner)
+					}
+					continue
+				}
+				if peer = s.idlePeer(task.attempts); peer == nil {
+					continue
+				}
+			} else if _, ok := task.attempts[peer.ID()]; ok {
+				continue
+			}
+			task.assigned = true
+			if hashes = append(hashes, hash); len(hashes) >= maxCodeRequestCount {
+				break
+			}
+		}
+		if peer == nil || len(hashes) == 0 {
+			return
+		}
+		s.reqID++
+		req := &codeRequest{
+			peer:   peer.ID(),
+			id:     s.reqID,
+			hashes: hashes,
+			stale:  s.stale,
+		}
+		req.timeout = time.AfterFunc(requestTimeout, func() {
+			peer.Log().Debug("Bytecode request timed out", "reqid", req.id)
+			s.scheduleRevertCodeRequest(req)
+		})
+		s.codeReqs[req.id] = req
+		delete(s.idlers, req.peer)
+
+		go func(peer SyncPeer, req *codeRequest) {
+			if err := peer.RequestByteCodes(req.id, req.hashes, maxRequestSize); err != nil {
+				peer.Log().Debug("Failed to request bytecodes", "err", err)
+				s.scheduleRevertCodeRequest(req)
+			}
+		}(peer, req)
+	}
+}
+
+// scheduleRevertAccountRequest asks the sync loop to revert a failed account
+// range request, unless it was already delivered or reverted.
+func (s *Syncer) scheduleRevertAccountRequest(req *accountRequest) {
+	log.DebugLog()
+	s.lock.Lock()
+	if _, ok := s.accountReqs[req.id]; !ok {
+		s.lock.Unlock()
+		return
+	}
+	req.timeout.Stop()
+	delete(s.accountReqs, req.id)
+	if _, ok := s.peers[req.peer]; ok {
+		s.idlers[req.peer] = struct{}{}
+	}
+	s.lock.Unlock()
+
+	select {
+	case s.accountFails <- req:
+	case <-req.stale:
+	}
+}
+
+// scheduleRevertStorageRequest asks the sync loop to revert a failed storage
+// range request, unless it was already delivered or reverted.
+func (s *Syncer) scheduleRevertStorageRequest(req *storageRequest) {
+	log.DebugLog()
+	s.lock.Lock()
+	if _, ok := s.storageReqs[req.id]; !ok {
+		s.lock.Unlock()
+		return
+	}
+	req.timeout.Stop()
+	delete(s.storageReqs, req.id)
+	if _, ok := s.peers[req.peer]; ok {
+		s.idlers[req.peer] = struct{}{}
+	}
+	s.lock.Unlock()
+
+	select {
+	case s.storageFails <- req:
+	case <-req.stale:
+	}
+}
+
+// scheduleRevertCodeRequest asks the sync loop to revert a failed bytecode
+// request, unless it was already delivered or reverted.
+func (s *Syncer) scheduleRevertCodeRequest(req *codeRequest) {
+	log.DebugLog()
+	s.lock.Lock()
+	if _, ok := s.codeReqs[req.id]; !ok {
+		s.lock.Unlock()
+		return
+	}
+	req.timeout.Stop()
+	delete(s.codeReqs, req.id)
+	if _, ok := s.peers[req.peer]; ok {
+		s.idlers[req.peer] = struct{}{}
+	}
+	s.lock.Unlock()
+
+	select {
+	case s.codeFails <- req:
+	case <-req.stale:
+	}
+}
+
+// revertAccountRequest returns a failed account range to the task queue, marking
+// the peer as unable to serve it.
+func (s *Syncer) revertAccountRequest(req *accountRequest) {
+	log.DebugLog()
+	req.task.assigned = false
+	req.task.attempts[req.peer] = struct{}{}
+}
+
+// revertStorageRequest returns failed storage ranges to the task queue, marking
+// the peer as unable to serve them.
+func (s *Syncer) revertStorageRequest(req *storageRequest) {
+	log.DebugLog()
+	for _, task := range req.tasks {
+		task.assigned = false
+		task.attempts[req.peer] = struct{}{}
+	}
+}
+
+// revertCodeRequest returns failed bytecodes to the task queue, marking the peer
+// as unable to serve them.
+func (s *Syncer) revertCodeRequest(req *codeRequest) {
+	log.DebugLog()
+	for _, hash := range req.hashes {
+		if task, ok := s.codeTasks[hash]; ok {
+			task.assigned = false
+			task.attempts[req.peer] = struct{}{}
+		}
+	}
+}
+
+// processAccountResponse integrates an already validated account range response
+// into the chunk's subtrie, scheduling the retrieval of any storage and bytecode
+// referenced by the new accounts.
+func (s *Syncer) processAccountResponse(res *accountResponse) {
+	log.DebugLog()
+	task := res.req.task
+	task.assigned = false
+
+	for i, hash := range res.hashes {
+		task.trie.Update(hash[:], res.blobs[i])
+
+		account := res.accounts[i]
+		if account.Root != types.EmptyRootHash {
+			if ok, _ := s.db.Has(account.Root[:]); !ok {
+				s.storageTasks = append(s.storageTasks, &storageTask{
+					account:  hash,
+					root:     account.Root,
+					owner:    task,
+					attempts: make(map[string]struct{}),
+				})
+				task.pending++
+			}
+		}
+		if codeHash := common.BytesToHash(account.CodeHash); codeHash != emptyCode {
+			if ok, _ := s.db.Has(codeHash[:]); !ok {
+				code, ok := s.codeTasks[codeHash]
+				if !ok {
+					code = &codeTask{attempts: make(map[string]struct{})}
+					s.codeTasks[codeHash] = code
+				}
+				code.owners = append(code.owners, task)
+				task.pending++
+			}
+		}
+	}
+	s.accountSynced += uint64(len(res.hashes))
+	for _, blob := range res.blobs {
+		s.bytesSynced += common.StorageSize(common.HashLength + len(blob))
+	}
+	if res.cont {
+		task.next = incHash(res.hashes[len(res.hashes)-1])
+	} else {
+		task.ranged = true
+	}
+	s.commitAccountTask(task)
+}
+
+// processStorageResponse integrates an already validated storage ranges response
+// into the storage tries, committing the complete ones that hash to the expected
+// root.
+func (s *Syncer) processStorageResponse(res *storageResponse) {
+	log.DebugLog()
+	var done []*storageTask
+	for i, task := range res.req.tasks {
+		task.assigned = false
+
+		// Accounts beyond the served ones are left for a new request
+		if i >= len(res.slots) {
+			continue
+		}
+		if task.trie == nil {
+			task.trie, _ = trie.New(common.Hash{}, s.triedb)
+		}
+		for _, slot := range res.slots[i] {
+			task.trie.Update(slot.Hash[:], slot.Body)
+			s.bytesSynced += common.StorageSize(common.HashLength + len(slot.Body))
+		}
+		s.storageSynced += uint64(len(res.slots[i]))
+
+		// If the last trie was only served partially, continue from where it stopped
+		if i == len(res.slots)-1 && res.cont {
+			next := incHash(res.slots[i][len(res.slots[i])-1].Hash)
+			task.next = next[:]
+			continue
+		}
+		// Storage trie retrieved completely, make sure it's the correct one
+		if root := task.trie.Hash(); root != task.root {
+			log.Debug("Storage trie root mismatch", "account", task.account, "want", task.root, "have", root, "peer", res.req.peer)
+			task.attempts[res.req.peer] = struct{}{}
+			task.next, task.trie = nil, nil
+			continue
+		}
+		if _, err := task.trie.Commit(nil); err != nil {
+			log.Error("Failed to commit storage trie", "err", err)
+		} else if err := s.triedb.Commit(task.root, false); err != nil {
+			log.Error("Failed to flush storage trie", "err", err)
+		}
+		done = append(done, task)
+	}
+	// Drop all the completed tasks and notify their owners
+	for _, task := range done {
+		for i, other := range s.storageTasks {
+			if other == task {
+				s.storageTasks = append(s.storageTasks[:i], s.storageTasks[i+1:]...)
+				break
+			}
+		}
+		task.owner.pending--
+		s.commitAccountTask(task.owner)
+	}
+}
+
+// processBytecodeResponse stores already validated bytecodes into the database.
+func (s *Syncer) processBytecodeResponse(res *codeResponse) {
+	log.DebugLog()
+	batch := s.db.NewBatch()
+	for _, hash := range res.req.hashes {
+		task, ok := s.codeTasks[hash]
+		if !ok {
+			continue
+		}
+		code, ok := res.codes[hash]
+		if !ok {
+			task.assigned = false
+			task.attempts[res.req.peer] = struct{}{}
+			continue
+		}
+		batch.Put(hash[:], code)
+		s.bytecodeSynced++
+		s.bytesSynced += common.StorageSize(len(code))
+
+		delete(s.codeTasks, hash)
+		for _, owner := range task.owners {
+			owner.pending--
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to persist bytecodes", "err", err)
+	}
+	for _, task := range s.tasks {
+		s.commitAccountTask(task)
+	}
+}
+
+// commitAccountTask flushes the subtrie of an account chunk into the database if
+// all its accounts, storage tries and bytecodes are retrieved. Abandoned chunks
+// are dropped instead, leaving them to the trie healing.
+func (s *Syncer) commitAccountTask(task *accountTask) {
+	log.DebugLog()
+	if task.done || (!task.abandoned && (!task.ranged || task.pending > 0)) {
+		return
+	}
+	task.done = true
+	if !task.abandoned && task.trie.Hash() != types.EmptyRootHash {
+		root, err := task.trie.Commit(nil)
+		if err == nil {
+			err = s.triedb.Commit(root, false)
+		}
+		if err != nil {
+			log.Error("Failed to commit account chunk", "origin", task.origin, "err", err)
+		}
+	}
+	task.trie = nil
+}
+
+// report logs the sync progress, unless it was logged only recently.
+func (s *Syncer) report(force bool) {
+	log.DebugLog()
+	if !force && time.Since(s.logged) < logInterval {
+		return
+	}
+	s.logged = time.Now()
+
+	chunks := 0
+	for _, task := range s.tasks {
+		if task.done {
+			chunks++
+		}
+	}
+	log.Info("State sync in progress", "synced", fmt.Sprintf("%d/%d", chunks, len(s.tasks)),
+		"accounts", s.accountSynced, "slots", s.storageSynced, "codes", s.bytecodeSynced, "size", s.bytesSynced)
+}
+
+// OnAccounts is a callback method to invoke when a range of accounts are
+// received from a remote peer. An error is returned, and the peer is expected
+// to be dropped, if the response was invalid.
+func (s *Syncer) OnAccounts(peer SyncPeer, id uint64, accounts []*AccountData, proof [][]byte) error {
+	log.DebugLog()
+	s.lock.Lock()
+	req, ok := s.accountReqs[id]
+	if !ok || req.peer != peer.ID() {
+		s.lock.Unlock()
+		peer.Log().Debug("Unexpected account range packet", "reqid", id)
+		return nil
+	}
+	req.timeout.Stop()
+	delete(s.accountReqs, id)
+	s.idlers[req.peer] = struct{}{}
+	s.lock.Unlock()
+
+	// An empty response without proofs means the peer doesn't have the state
+	if len(accounts) == 0 && len(proof) == 0 {
+		peer.Log().Debug("Peer rejected account range request", "root", req.root)
+		select {
+		case s.accountFails <- req:
+		case <-req.stale:
+		}
+		return nil
+	}
+	res, err := verifyAccountRange(req, accounts, proof)
+	if err != nil {
+		peer.Log().Warn("Invalid account range", "err", err)
+		select {
+		case s.accountFails <- req:
+		case <-req.stale:
+		}
+		return err
+	}
+	select {
+	case s.accountResps <- res:
+	case <-req.stale:
+	}
+	return nil
+}
+
+// verifyAccountRange checks that a delivered account range is within the requested
+// bounds, and that it's complete: the range proof shows that the state root has
+// no other accounts between its edges.
+func verifyAccountRange(req *accountRequest, accounts []*AccountData, proof [][]byte) (*accountResponse, error) {
+	log.DebugLog()
+	res := &accountResponse{
+		req:      req,
+		hashes:   make([]common.Hash, len(accounts)),
+		blobs:    make([][]byte, len(accounts)),
+		accounts: make([]*state.Account, len(accounts)),
+	}
+	keys := make([][]byte, len(accounts))
+	for i, account := range accounts {
+		if bytes.Compare(account.Hash[:], req.origin[:]) < 0 || bytes.Compare(account.Hash[:], req.limit[:]) > 0 {
+			return nil, fmt.Errorf("account %x outside of range [%x, %x]", account.Hash, req.origin, req.limit)
+		}
+		res.accounts[i] = new(state.Account)
+		if err := rlp.DecodeBytes(account.Body, res.accounts[i]); err != nil {
+			return nil, fmt.Errorf("invalid account %x: %v", account.Hash, err)
+		}
+		res.hashes[i], res.blobs[i] = account.Hash, account.Body
+		keys[i] = res.hashes[i][:]
+	}
+	// Verify the range against the Merkle proof of its edges
+	last := req.limit
+	if len(accounts) > 0 {
+		last = res.hashes[len(accounts)-1]
+	}
+	more, err := trie.VerifyRangeProof(req.root, req.origin[:], last[:], keys, res.blobs, proofDatabase(proof))
+	if err != nil {
+		return nil, fmt.Errorf("invalid account range: %v", err)
+	}
+	res.cont = more && last != req.limit
+	return res, nil
+}
+
+// OnStorage is a callback method to invoke when ranges of storage slots are
+// received from a remote peer. An error is returned, and the peer is expected
+// to be dropped, if the response was invalid.
+func (s *Syncer) OnStorage(peer SyncPeer, id uint64, slots [][]*StorageData, proof [][]byte) error {
+	log.DebugLog()
+	s.lock.Lock()
+	req, ok := s.storageReqs[id]
+	if !ok || req.peer != peer.ID() {
+		s.lock.Unlock()
+		peer.Log().Debug("Unexpected storage ranges packet", "reqid", id)
+		return nil
+	}
+	req.timeout.Stop()
+	delete(s.storageReqs, id)
+	s.idlers[req.peer] = struct{}{}
+	s.lock.Unlock()
+
+	// An empty response without proofs means the peer doesn't have the state
+	if len(slots) == 0 && len(proof) == 0 {
+		peer.Log().Debug("Peer rejected storage request", "root", req.root)
+		select {
+		case s.storageFails <- req:
+		case <-req.stale:
+		}
+		return nil
+	}
+	res, err := verifyStorageRanges(req, slots, proof)
+	if err != nil {
+		peer.Log().Warn("Invalid storage ranges", "err", err)
+		select {
+		case s.storageFails <- req:
+		case <-req.stale:
+		}
+		return err
+	}
+	select {
+	case s.storageResps <- res:
+	case <-req.stale:
+	}
+	return nil
+}
+
+// verifyStorageRanges checks that delivered storage slots are ordered and that the
+// edges of a partially served trie are proven by its storage root. Complete tries
+// are verified by their root hash after reassembly.
+func verifyStorageRanges(req *storageRequest, slots [][]*StorageData, proof [][]byte) (*storageResponse, error) {
+	log.DebugLog()
+	if len(slots) > len(req.tasks) {
+		return nil, fmt.Errorf("too many storage ranges: have %d, want %d", len(slots), len(req.tasks))
+	}
+	for i, storage := range slots {
+		for j, slot := range storage {
+			if i == 0 && j == 0 && req.origin != nil && bytes.Compare(slot.Hash[:], req.origin) < 0 {
+				return nil, fmt.Errorf("slot %x before origin %x", slot.Hash, req.origin)
+			}
+			if j > 0 && bytes.Compare(slot.Hash[:], storage[j-1].Hash[:]) <= 0 {
+				return nil, fmt.Errorf("slot %x out of order", slot.Hash)
+			}
+		}
+	}
+	res := &storageResponse{req: req, slots: slots}
+	if len(proof) == 0 {
+		if req.origin != nil {
+			return nil, errors.New("missing storage range proof")
+		}
+		return res, nil
+	}
+	if len(slots) == 0 {
+		return nil, errors.New("storage range proof without slots")
+	}
+	// The last range was served partially, verify it against its edge proofs
+	var (
+		root    = req.tasks[len(slots)-1].root
+		storage = slots[len(slots)-1]
+		origin  = common.BytesToHash(req.origin)
+		keys    = make([][]byte, len(storage))
+		vals    = make([][]byte, len(storage))
+		last    []byte
+	)
+	if len(slots) > 1 {
+		origin = common.Hash{}
+	}
+	for i, slot := range storage {
+		keys[i], vals[i] = common.CopyBytes(slot.Hash[:]), slot.Body
+	}
+	if len(storage) > 0 {
+		last = keys[len(keys)-1]
+	}
+	more, err := trie.VerifyRangeProof(root, origin[:], last, keys, vals, proofDatabase(proof))
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage range: %v", err)
+	}
+	res.cont = more
+	return res, nil
+}
+
+// OnByteCodes is a callback method to invoke when a batch of contract bytecodes
+// are received from a remote peer. An error is returned, and the peer is expected
+// to be dropped, if the response was invalid.
+func (s *Syncer) OnByteCodes(peer SyncPeer, id uint64, codes [][]byte) error {
+	log.DebugLog()
+	s.lock.Lock()
+	req, ok := s.codeReqs[id]
+	if !ok || req.peer != peer.ID() {
+		s.lock.Unlock()
+		peer.Log().Debug("Unexpected bytecode packet", "reqid", id)
+		return nil
+	}
+	req.timeout.Stop()
+	delete(s.codeReqs, id)
+	s.idlers[req.peer] = struct{}{}
+	s.lock.Unlock()
+
+	// Cross reference the delivered codes with the requested hashes
+	requested := make(map[common.Hash]struct{}, len(req.hashes))
+	for _, hash := range req.hashes {
+		requested[hash] = struct{}{}
+	}
+	res := &codeResponse{req: req, codes: make(map[common.Hash][]byte, len(codes))}
+	for _, code := range codes {
+		hash := crypto.Keccak256Hash(code)
+		if _, ok := requested[hash]; !ok {
+			peer.Log().Warn("Unrequested bytecode delivered", "hash", hash)
+			select {
+			case s.codeFails <- req:
+			case <-req.stale:
+			}
+			return fmt.Errorf("unrequested bytecode %x", hash)
+		}
+		res.codes[hash] = code
+	}
+	select {
+	case s.codeResps <- res:
+	case <-req.stale:
+	}
+	return nil
+}
+
+// proofDatabase creates an in-memory trie node database from a list of proof
+// nodes, keyed by their hashes.
+func proofDatabase(proof [][]byte) *ethdb.MemDatabase {
+	log.DebugLog()
+	db, _ := ethdb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+// incHash returns the hash following the given one. Callers make sure it never
+// overflows, the last hash of a range is not continued.
+func incHash(h common.Hash) common.Hash {
+	log.DebugLog()
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,258 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// testPeer is a mock snap peer serving the queries of a syncer from a local state
+// database, asynchronously as a network peer would.
+type testPeer struct {
+	id     string
+	syncer *Syncer
+	state  state.Database
+	limit  uint64 // Response size cap to force range continuations
+	tamper bool   // Whether to corrupt the account range responses
+	omit   bool   // Whether to withhold accounts from the middle of the ranges
+	logger log.Logger
+}
+
+func newTestPeer(id string, syncer *Syncer, db state.Database) *testPeer { log.DebugLog()
+	return &testPeer{id: id, syncer: syncer, state: db, limit: 1024, logger: log.New("id", id)}
+}
+
+func (p *testPeer) ID() string      { log.DebugLog(); return p.id }
+func (p *testPeer) Log() log.Logger { log.DebugLog(); return p.logger }
+
+func (p *testPeer) RequestAccountRange(id uint64, root, origin, limit common.Hash, bytes uint64) error { log.DebugLog()
+	go func() {
+		accounts, proof := ServiceGetAccountRangeQuery(p.state, &GetAccountRangePacket{ID: id, Root: root, Origin: origin, Limit: limit, Bytes: p.limit})
+		if p.tamper && len(accounts) > 0 {
+			acc := &state.Account{Nonce: 1, Balance: big.NewInt(1), Root: emptyRoot, CodeHash: emptyCode[:]}
+			accounts[len(accounts)-1].Body, _ = rlp.EncodeToBytes(acc)
+		}
+		if p.omit && len(accounts) > 2 {
+			accounts = append(accounts[:len(accounts)/2], accounts[len(accounts)/2+1:]...)
+		}
+		if err := p.syncer.OnAccounts(p, id, accounts, proof); err != nil {
+			p.syncer.Unregister(p.id)
+		}
+	}()
+	return nil
+}
+
+func (p *testPeer) RequestStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin, limit []byte, bytes uint64) error { log.DebugLog()
+	go func() {
+		slots, proof := ServiceGetStorageRangesQuery(p.state, &GetStorageRangesPacket{ID: id, Root: root, Accounts: accounts, Origin: origin, Limit: limit, Bytes: p.limit})
+		if err := p.syncer.OnStorage(p, id, slots, proof); err != nil {
+			p.syncer.Unregister(p.id)
+		}
+	}()
+	return nil
+}
+
+func (p *testPeer) RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error { log.DebugLog()
+	go func() {
+		codes := ServiceGetByteCodesQuery(p.state, &GetByteCodesPacket{ID: id, Hashes: hashes, Bytes: p.limit})
+		if err := p.syncer.OnByteCodes(p, id, codes); err != nil {
+			p.syncer.Unregister(p.id)
+		}
+	}()
+	return nil
+}
+
+var emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+// makeTestState creates a state with many accounts, some of which have small or
+// large storage tries and (shared) contract code.
+func makeTestState(t *testing.T) (*ethdb.MemDatabase, common.Hash) { log.DebugLog()
+	db, _ := ethdb.NewMemDatabase()
+	sdb := state.NewDatabase(db)
+	statedb, _ := state.New(common.Hash{}, sdb)
+
+	for i := 0; i < 2000; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i)))
+		statedb.SetBalance(addr, big.NewInt(int64(i+1)))
+		statedb.SetNonce(addr, uint64(i))
+
+		switch {
+		case i%100 == 0:
+			// Large contract requiring multiple storage requests
+			for j := 1; j <= 200; j++ {
+				statedb.SetState(addr, common.BigToHash(big.NewInt(int64(j))), common.BigToHash(big.NewInt(int64(i*j+1))))
+			}
+			statedb.SetCode(addr, []byte{0x60, byte(i / 100)})
+		case i%10 == 0:
+			// Small contract sharing its code with the others
+			statedb.SetState(addr, common.Hash{0x01}, common.BigToHash(big.NewInt(int64(i+1))))
+			statedb.SetCode(addr, []byte{0x60, 0x00, 0x60, 0x00})
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	return db, root
+}
+
+// healState runs a regular trie sync on top of the reconstructed ranges, returning
+// the number of trie nodes that had to be retrieved.
+func healState(t *testing.T, root common.Hash, src, dst *ethdb.MemDatabase) int { log.DebugLog()
+	sched := state.NewStateSync(root, dst)
+
+	healed := 0
+	for sched.Pending() > 0 {
+		hashes := sched.Missing(0)
+		results := make([]trie.SyncResult, len(hashes))
+		for i, hash := range hashes {
+			data, err := src.Get(hash[:])
+			if err != nil {
+				t.Fatalf("failed to retrieve node %x: %v", hash, err)
+			}
+			results[i] = trie.SyncResult{Hash: hash, Data: data}
+		}
+		if _, index, err := sched.Process(results); err != nil {
+			t.Fatalf("failed to process result #%d: %v", index, err)
+		}
+		batch := dst.NewBatch()
+		if _, err := sched.Commit(batch); err != nil {
+			t.Fatalf("failed to commit healed nodes: %v", err)
+		}
+		batch.Write()
+		healed += len(hashes)
+	}
+	return healed
+}
+
+// checkState verifies that the complete state is present in the database.
+func checkState(t *testing.T, root common.Hash, db *ethdb.MemDatabase) { log.DebugLog()
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open synced state: %v", err)
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		t.Fatalf("synced state incomplete: %v", it.Error)
+	}
+}
+
+// Tests that the state ranges retrieved from snap peers, healed by a trie sync,
+// reconstruct the complete state, with most of it coming from the ranges.
+func TestSync(t *testing.T) { log.DebugLog()
+	source, root := makeTestState(t)
+
+	db, _ := ethdb.NewMemDatabase()
+	syncer := NewSyncer(db)
+	syncer.Register(newTestPeer("a", syncer, state.NewDatabase(source)))
+	syncer.Register(newTestPeer("b", syncer, state.NewDatabase(source)))
+
+	if err := syncer.Sync(root, make(chan struct{})); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	healed := healState(t, root, source, db)
+	checkState(t, root, db)
+
+	if total := len(source.Keys()); healed > total/10 {
+		t.Errorf("too many nodes healed: %d of %d", healed, total)
+	}
+}
+
+// Tests that a peer serving corrupted ranges is dropped, and the sync completes
+// with the honest ones.
+func TestSyncTamperedRange(t *testing.T) { log.DebugLog()
+	source, root := makeTestState(t)
+
+	db, _ := ethdb.NewMemDatabase()
+	syncer := NewSyncer(db)
+
+	bad := newTestPeer("bad", syncer, state.NewDatabase(source))
+	bad.tamper = true
+	syncer.Register(bad)
+	syncer.Register(newTestPeer("good", syncer, state.NewDatabase(source)))
+
+	if err := syncer.Sync(root, make(chan struct{})); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	healState(t, root, source, db)
+	checkState(t, root, db)
+
+	syncer.lock.RLock()
+	defer syncer.lock.RUnlock()
+	if _, ok := syncer.peers["bad"]; ok {
+		t.Errorf("tampering peer not dropped")
+	}
+}
+
+// Tests that a peer withholding accounts from the middle of its ranges, while
+// proving the edges correctly, is detected and dropped.
+func TestSyncIncompleteRange(t *testing.T) { log.DebugLog()
+	source, root := makeTestState(t)
+
+	db, _ := ethdb.NewMemDatabase()
+	syncer := NewSyncer(db)
+
+	bad := newTestPeer("bad", syncer, state.NewDatabase(source))
+	bad.omit = true
+	syncer.Register(bad)
+	syncer.Register(newTestPeer("good", syncer, state.NewDatabase(source)))
+
+	if err := syncer.Sync(root, make(chan struct{})); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	healState(t, root, source, db)
+	checkState(t, root, db)
+
+	syncer.lock.RLock()
+	defer syncer.lock.RUnlock()
+	if _, ok := syncer.peers["bad"]; ok {
+		t.Errorf("withholding peer not dropped")
+	}
+}
+
+// Tests that a sync cycle gives up if there are no snap peers to sync from, and
+// that peers not having the requested state don't stall it.
+func TestSyncUnavailable(t *testing.T) { log.DebugLog()
+	source, root := makeTestState(t)
+
+	db, _ := ethdb.NewMemDatabase()
+	syncer := NewSyncer(db)
+	if err := syncer.Sync(root, make(chan struct{})); err != ErrNoPeers {
+		t.Fatalf("peerless sync error mismatch: have %v, want %v", err, ErrNoPeers)
+	}
+	empty, _ := ethdb.NewMemDatabase()
+	syncer.Register(newTestPeer("empty", syncer, state.NewDatabase(empty)))
+	if err := syncer.Sync(root, make(chan struct{})); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	// Nothing was retrieved, the heal needs to do a full trie sync
+	healState(t, root, source, db)
+	checkState(t, root, db)
+}
@@ -0,0 +1,176 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// The native tracers below are faithful ports of their JavaScript counterparts
+// in the internal/tracers package, down to the quirks of the originals, so that
+// their results are interchangeable byte for byte.
+
+// stopper implements the interruption of native tracers.
+type stopper struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (s *stopper) Stop(err error) {
+	log.DebugLog()
+	s.reason = err
+	atomic.StoreUint32(&s.interrupt, 1)
+}
+
+// stopped returns whether the tracer was interrupted.
+func (s *stopper) stopped() bool {
+	log.DebugLog()
+	return atomic.LoadUint32(&s.interrupt) > 0
+}
+
+// peekStack returns the nth-from-the-top element of the stack, or zero if the
+// stack is not deep enough, same as the JavaScript stack wrapper.
+func peekStack(stack *vm.Stack, n int) *big.Int {
+	log.DebugLog()
+	data := stack.Data()
+	if len(data) <= n {
+		log.Warn("Tracer accessed out of bound stack", "size", len(data), "index", n)
+		return new(big.Int)
+	}
+	return data[len(data)-n-1]
+}
+
+// peekOffset returns the nth-from-the-top element of the stack as a memory offset,
+// capping huge values that cannot be a valid offset anyway.
+func peekOffset(stack *vm.Stack, n int) int64 {
+	log.DebugLog()
+	value := peekStack(stack, n)
+	if value.BitLen() > 62 {
+		return math.MaxInt64 / 2
+	}
+	return value.Int64()
+}
+
+// sliceMemory returns the requested range of memory as a byte slice, or nil if
+// the range is out of bounds, same as the JavaScript memory wrapper.
+func sliceMemory(memory *vm.Memory, begin, end int64) []byte {
+	log.DebugLog()
+	if int64(memory.Len()) < end || begin > end {
+		log.Warn("Tracer accessed out of bound memory", "available", memory.Len(), "offset", begin, "size", end-begin)
+		return nil
+	}
+	return memory.Get(begin, end-begin)
+}
+
+// hexInt formats a number the way JavaScript's bigInt(n).toString(16) does.
+func hexInt(n int64) string {
+	log.DebugLog()
+	return "0x" + strconv.FormatInt(n, 16)
+}
+
+// hexBig formats a big number the way JavaScript's bigInt.toString(16) does.
+func hexBig(n *big.Int) string {
+	log.DebugLog()
+	if n == nil {
+		return "0x0"
+	}
+	return "0x" + n.Text(16)
+}
+
+// orderedObject is a JSON object retaining the insertion order of its fields, as
+// JavaScript objects do when serialized.
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// newOrderedObject creates an empty JSON object.
+func newOrderedObject() *orderedObject {
+	log.DebugLog()
+	return &orderedObject{values: make(map[string]interface{})}
+}
+
+// get retrieves a field of the object.
+func (o *orderedObject) get(key string) (interface{}, bool) {
+	log.DebugLog()
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// set inserts or updates a field of the object. Updates retain the position.
+func (o *orderedObject) set(key string, value interface{}) {
+	log.DebugLog()
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// delete removes a field from the object.
+func (o *orderedObject) delete(key string) {
+	log.DebugLog()
+	if _, ok := o.values[key]; !ok {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// len returns the number of fields in the object.
+func (o *orderedObject) len() int {
+	log.DebugLog()
+	return len(o.keys)
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields in insertion order.
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	log.DebugLog()
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
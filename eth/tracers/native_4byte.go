@@ -0,0 +1,121 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func init() {
+	RegisterNative("4byteTracer", newFourByteTracer)
+}
+
+// fourByteTracer searches for 4byte-identifiers, and collects them for post-processing.
+// It collects the methods identifiers along with the size of the supplied data, so
+// a reversed signature can be matched against the size of the data.
+type fourByteTracer struct {
+	stopper
+
+	ids   *orderedObject // Aggregated 4byte ids, keyed by id and data size
+	input []byte         // Input data of the outer call
+}
+
+// newFourByteTracer creates a native 4byte tracer. It takes no configuration.
+func newFourByteTracer(config json.RawMessage) (Tracer, error) {
+	log.DebugLog()
+	return &fourByteTracer{ids: newOrderedObject()}, nil
+}
+
+// store saves the given identifier and datasize.
+func (t *fourByteTracer) store(id []byte, size int64) {
+	log.DebugLog()
+	key := hexutil.Encode(id) + "-" + strconv.FormatInt(size, 10)
+
+	count, _ := t.ids.get(key)
+	if count == nil {
+		count = 0
+	}
+	t.ids.set(key, count.(int)+1)
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *fourByteTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	log.DebugLog()
+	t.input = common.CopyBytes(input)
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *fourByteTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	log.DebugLog()
+	if t.stopped() {
+		return nil
+	}
+	// Skip any opcodes that are not internal calls. Note, the JavaScript version
+	// fails to filter out pre-compile invocations (it passes a bigint instead of
+	// an address to isPrecompiled), so they are collected here too for parity.
+	var ct int
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		ct = 3 // gas, addr, val, memin, meminsz, memout, memoutsz
+	case vm.DELEGATECALL, vm.STATICCALL:
+		ct = 2 // gas, addr, memin, meminsz, memout, memoutsz
+	default:
+		return nil
+	}
+	// Gather internal call details
+	if inSz := peekOffset(stack, ct+1); inSz >= 4 {
+		inOff := peekOffset(stack, ct)
+		t.store(sliceMemory(memory, inOff, inOff+4), inSz-4)
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *fourByteTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	log.DebugLog()
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *fourByteTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	log.DebugLog()
+	return nil
+}
+
+// GetResult returns the collected 4byte ids along with the number of times each
+// was seen.
+func (t *fourByteTracer) GetResult() (json.RawMessage, error) {
+	log.DebugLog()
+	if t.stopped() {
+		return nil, t.reason
+	}
+	// Save the outer calldata also
+	if len(t.input) > 4 {
+		t.store(t.input[:4], int64(len(t.input)-4))
+	}
+	return json.Marshal(t.ids)
+}
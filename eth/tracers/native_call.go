@@ -0,0 +1,282 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func init() {
+	RegisterNative("callTracer", newCallTracer)
+}
+
+// callFrame is a single call reported by the call tracer. The fields are in the
+// order of the JavaScript tracer's output, empty ones being undefined there.
+type callFrame struct {
+	Type    string       `json:"type"`
+	From    string       `json:"from,omitempty"`
+	To      string       `json:"to,omitempty"`
+	Value   string       `json:"value,omitempty"`
+	Gas     string       `json:"gas,omitempty"`
+	GasUsed string       `json:"gasUsed,omitempty"`
+	Input   string       `json:"input,omitempty"`
+	Output  string       `json:"output,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Time    string       `json:"time,omitempty"`
+	Calls   []*callFrame `json:"calls,omitempty"`
+
+	gasIn   uint64 // Gas available before the call opcode
+	gasCost uint64 // Gas cost of the call opcode
+	gas     uint64 // Gas allowance within the call, if it was descended into
+	gasSet  bool   // Whether the call was descended into
+	outOff  int64  // Memory offset of the call's return data
+	outLen  int64  // Memory length of the call's return data
+}
+
+// callTracer is a full blown transaction tracer that extracts and reports all
+// the internal calls made by a transaction, along with any useful information.
+type callTracer struct {
+	stopper
+
+	callstack []*callFrame // Current recursive call stack of the EVM execution
+	descended bool         // Whether we've just descended into an inner call
+
+	typ     string         // Type of the outer call (CALL or CREATE)
+	from    common.Address // Sender of the outer call
+	to      common.Address // Recipient of the outer call
+	input   []byte         // Input data of the outer call
+	gas     uint64         // Gas allowance of the outer call
+	value   *big.Int       // Value transferred by the outer call
+	output  []byte         // Return data of the outer call
+	gasUsed uint64         // Gas consumed by the outer call
+	time    time.Duration  // Execution time of the outer call
+	err     error          // Execution error of the outer call
+}
+
+// newCallTracer creates a native call tracer. It takes no configuration.
+func newCallTracer(config json.RawMessage) (Tracer, error) {
+	log.DebugLog()
+	return &callTracer{callstack: []*callFrame{{}}}, nil
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *callTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	log.DebugLog()
+	t.typ = "CALL"
+	if create {
+		t.typ = "CREATE"
+	}
+	t.from, t.to, t.input, t.gas, t.value = from, to, common.CopyBytes(input), gas, value
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	log.DebugLog()
+	if t.stopped() {
+		return nil
+	}
+	// Capture any errors immediately
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	switch op {
+	case vm.CREATE:
+		// If a new contract is being created, add to the call stack
+		inOff := peekOffset(stack, 1)
+		inEnd := inOff + peekOffset(stack, 2)
+
+		t.callstack = append(t.callstack, &callFrame{
+			Type:    op.String(),
+			From:    hexutil.Encode(contract.Address().Bytes()),
+			Input:   hexutil.Encode(sliceMemory(memory, inOff, inEnd)),
+			gasIn:   gas,
+			gasCost: cost,
+			Value:   hexBig(peekStack(stack, 0)),
+		})
+		t.descended = true
+		return nil
+
+	case vm.SELFDESTRUCT:
+		// If a contract is being self destructed, gather that as a subcall too
+		top := t.callstack[len(t.callstack)-1]
+		top.Calls = append(top.Calls, &callFrame{Type: op.String()})
+		return nil
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// Skip any pre-compile invocations, those are just fancy opcodes
+		to := common.BigToAddress(peekStack(stack, 1))
+		if _, ok := vm.PrecompiledContractsByzantium[to]; ok {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		inOff := peekOffset(stack, 2+off)
+		inEnd := inOff + peekOffset(stack, 3+off)
+
+		// Assemble the internal call report and store for completion
+		call := &callFrame{
+			Type:    op.String(),
+			From:    hexutil.Encode(contract.Address().Bytes()),
+			To:      hexutil.Encode(to.Bytes()),
+			Input:   hexutil.Encode(sliceMemory(memory, inOff, inEnd)),
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  peekOffset(stack, 4+off),
+			outLen:  peekOffset(stack, 5+off),
+		}
+		if off == 1 {
+			call.Value = hexBig(peekStack(stack, 2))
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve it's true allowance. We
+	// need to extract if from within the call as there may be funky gas dynamics
+	// with regard to requested and actually given gas (2300 stipend, 63/64 rule).
+	if t.descended {
+		if depth >= len(t.callstack) {
+			top := t.callstack[len(t.callstack)-1]
+			top.gas, top.gasSet = gas, true
+		}
+		t.descended = false
+	}
+	// If an existing call is returning, pop off the call stack
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = "execution reverted"
+		return nil
+	}
+	if depth == len(t.callstack)-1 {
+		// Pop off the last call and get the execution results
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		if call.Type == vm.CREATE.String() {
+			// If the call was a CREATE, retrieve the contract address and output code
+			call.GasUsed = hexInt(int64(call.gasIn) - int64(call.gasCost) - int64(gas))
+
+			if ret := peekStack(stack, 0); ret.Sign() != 0 {
+				addr := common.BigToAddress(ret)
+				call.To = hexutil.Encode(addr.Bytes())
+				call.Output = hexutil.Encode(env.StateDB.GetCode(addr))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else if call.gasSet {
+			// If the call was a contract call, retrieve the gas usage and output
+			call.GasUsed = hexInt(int64(call.gasIn) - int64(call.gasCost) + int64(call.gas) - int64(gas))
+
+			if ret := peekStack(stack, 0); ret.Sign() != 0 {
+				call.Output = hexutil.Encode(sliceMemory(memory, call.outOff, call.outOff+call.outLen))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		if call.gasSet {
+			call.Gas = hexInt(int64(call.gas))
+		}
+		// Inject the call into the previous one
+		top := t.callstack[len(t.callstack)-1]
+		top.Calls = append(top.Calls, call)
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	log.DebugLog()
+	if t.stopped() {
+		return nil
+	}
+	t.fault(err)
+	return nil
+}
+
+// fault handles the failure of the currently executing call.
+func (t *callTracer) fault(err error) {
+	log.DebugLog()
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return
+	}
+	// Pop off the just failed call
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	call.Error = err.Error()
+
+	// Consume all available gas and clean any leftovers
+	if call.gasSet {
+		call.Gas = hexInt(int64(call.gas))
+		call.GasUsed = call.Gas
+	}
+	// Flatten the failed call into its parent
+	if len(t.callstack) > 0 {
+		top := t.callstack[len(t.callstack)-1]
+		top.Calls = append(top.Calls, call)
+		return
+	}
+	// Last call failed too, leave it in the stack
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	log.DebugLog()
+	t.output, t.gasUsed, t.time, t.err = common.CopyBytes(output), gasUsed, d, err
+	return nil
+}
+
+// GetResult returns the outer call along with all the internal calls made.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	log.DebugLog()
+	if t.stopped() {
+		return nil, t.reason
+	}
+	result := &callFrame{
+		Type:    t.typ,
+		From:    hexutil.Encode(t.from.Bytes()),
+		To:      hexutil.Encode(t.to.Bytes()),
+		Value:   hexBig(t.value),
+		Gas:     hexInt(int64(t.gas)),
+		GasUsed: hexInt(int64(t.gasUsed)),
+		Input:   hexutil.Encode(t.input),
+		Output:  hexutil.Encode(t.output),
+		Time:    t.time.String(),
+		Calls:   t.callstack[0].Calls,
+	}
+	if t.callstack[0].Error != "" {
+		result.Error = t.callstack[0].Error
+	} else if t.err != nil {
+		result.Error = t.err.Error()
+	}
+	if result.Error != "" {
+		result.Output = ""
+	}
+	return json.Marshal(result)
+}
@@ -0,0 +1,347 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func init() {
+	RegisterNative("prestateTracer", newPrestateTracer)
+}
+
+// errNoPrestate is returned if the traced transaction didn't execute any code,
+// so there was no opportunity to access the state.
+var errNoPrestate = errors.New("no state accessed during execution")
+
+// prestateAccount is an account as reported by the prestate tracer.
+type prestateAccount struct {
+	Balance string         `json:"balance"`
+	Nonce   int64          `json:"nonce"`
+	Code    string         `json:"code"`
+	Storage *orderedObject `json:"storage"`
+
+	balance *big.Int                    // Balance value at the time of the lookup
+	code    []byte                      // Code value at the time of the lookup
+	slots   map[common.Hash]common.Hash // Values of all accessed slots at their first access
+	order   []common.Hash               // Order in which the slots were first accessed
+}
+
+// empty returns whether the account was non-existent at the time of the lookup.
+func (a *prestateAccount) empty() bool {
+	log.DebugLog()
+	return a.Nonce == 0 && a.balance.Sign() == 0 && len(a.code) == 0
+}
+
+// prestateDiffAccount is the modified part of an account in diff mode.
+type prestateDiffAccount struct {
+	Balance string         `json:"balance,omitempty"`
+	Nonce   *int64         `json:"nonce,omitempty"`
+	Code    string         `json:"code,omitempty"`
+	Storage *orderedObject `json:"storage,omitempty"`
+}
+
+// prestateTracerConfig is the configuration of the prestate tracer.
+type prestateTracerConfig struct {
+	DiffMode bool `json:"diffMode"` // Whether to report the pre and post state of modified accounts
+}
+
+// prestateTracer outputs sufficient information to create a local execution of
+// the transaction from a custom assembled genesis block. In diff mode, it reports
+// the pre and post state of the accounts modified by the transaction instead.
+type prestateTracer struct {
+	stopper
+	config prestateTracerConfig
+
+	env      *vm.EVM                             // Execution environment, nil until the first step
+	accounts map[common.Address]*prestateAccount // Accounts accessed during execution
+	order    []common.Address                    // Order in which the accounts were accessed
+
+	typ   string         // Type of the outer call (CALL or CREATE)
+	from  common.Address // Sender of the outer call
+	to    common.Address // Recipient of the outer call
+	input []byte         // Input data of the outer call
+	gas   uint64         // Gas allowance of the outer call
+	value *big.Int       // Value transferred by the outer call
+}
+
+// newPrestateTracer creates a native prestate tracer, optionally in diff mode.
+func newPrestateTracer(config json.RawMessage) (Tracer, error) {
+	log.DebugLog()
+	t := &prestateTracer{accounts: make(map[common.Address]*prestateAccount)}
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &t.config); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// lookupAccount injects the specified account into the prestate.
+func (t *prestateTracer) lookupAccount(addr common.Address) *prestateAccount {
+	log.DebugLog()
+	if account, ok := t.accounts[addr]; ok {
+		return account
+	}
+	db := t.env.StateDB
+	balance, code := db.GetBalance(addr), db.GetCode(addr)
+
+	account := &prestateAccount{
+		Balance: hexBig(balance),
+		Nonce:   int64(db.GetNonce(addr)),
+		Code:    hexutil.Encode(code),
+		Storage: newOrderedObject(),
+		balance: new(big.Int).Set(balance),
+		code:    common.CopyBytes(code),
+		slots:   make(map[common.Hash]common.Hash),
+	}
+	t.accounts[addr] = account
+	t.order = append(t.order, addr)
+	return account
+}
+
+// lookupStorage injects the specified storage entry of the given account into
+// the prestate.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	log.DebugLog()
+	account := t.lookupAccount(addr)
+
+	idx := hexutil.Encode(key.Bytes())
+	if _, ok := account.Storage.get(idx); !ok {
+		val := t.env.StateDB.GetState(addr, key)
+		if _, ok := account.slots[key]; !ok {
+			account.slots[key] = val
+			account.order = append(account.order, key)
+		}
+		if val != (common.Hash{}) {
+			account.Storage.set(idx, hexutil.Encode(val.Bytes()))
+		}
+	}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	log.DebugLog()
+	t.typ = "CALL"
+	if create {
+		t.typ = "CREATE"
+	}
+	t.from, t.to, t.input, t.gas, t.value = from, to, common.CopyBytes(input), gas, value
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	log.DebugLog()
+	if t.stopped() {
+		return nil
+	}
+	// Add the current account if we just started tracing
+	if t.env == nil {
+		t.env = env
+
+		// Balance will potentially be wrong here, since this will include the value
+		// sent along with the message. We fix that in 'GetResult()'.
+		t.lookupAccount(contract.Address())
+		if t.config.DiffMode {
+			t.lookupAccount(t.from)
+			t.lookupAccount(env.Coinbase)
+		}
+	}
+	// Whenever new state is accessed, add it to the prestate
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(common.BigToAddress(peekStack(stack, 0)))
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, env.StateDB.GetNonce(from)))
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(common.BigToAddress(peekStack(stack, 1)))
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract.Address(), common.BigToHash(peekStack(stack, 0)))
+	}
+	// The diff mode also needs all the other accounts that may get modified
+	if t.config.DiffMode {
+		switch op {
+		case vm.CREATE2:
+			offset, size := peekOffset(stack, 1), peekOffset(stack, 2)
+			code := sliceMemory(memory, offset, offset+size)
+			salt := common.BigToHash(peekStack(stack, 3))
+			t.lookupAccount(crypto.CreateAddress2(contract.Address(), salt, crypto.Keccak256(code)))
+		case vm.SELFDESTRUCT:
+			t.lookupAccount(common.BigToAddress(peekStack(stack, 0)))
+		}
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	log.DebugLog()
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	log.DebugLog()
+	return nil
+}
+
+// GetResult returns the assembled allocations (prestate), or the pre and post
+// state of the modified accounts in diff mode.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	log.DebugLog()
+	if t.stopped() {
+		return nil, t.reason
+	}
+	if t.env == nil {
+		return nil, errNoPrestate
+	}
+	// At this point, we need to deduct the 'value' from the outer transaction,
+	// and move it back to the origin
+	fromAcc := t.lookupAccount(t.from)
+	toAcc := t.lookupAccount(t.to)
+
+	value := t.value
+	if value == nil {
+		value = new(big.Int)
+	}
+	fromBal, toBal := new(big.Int).Set(fromAcc.balance), new(big.Int).Set(toAcc.balance)
+
+	toAcc.balance = toBal.Sub(toBal, value)
+	toAcc.Balance = hexBig(toAcc.balance)
+	fromAcc.balance = fromBal.Add(fromBal, value)
+	fromAcc.Balance = hexBig(fromAcc.balance)
+
+	// Decrement the caller's nonce, and remove empty create targets
+	fromAcc.Nonce--
+
+	if t.config.DiffMode {
+		return t.diff(fromAcc)
+	}
+	prestate := newOrderedObject()
+	for _, addr := range t.order {
+		// We can blindly delete the contract prestate, as any existing state would
+		// have caused the transaction to be rejected as invalid in the first place.
+		if t.typ == "CREATE" && addr == t.to {
+			continue
+		}
+		prestate.set(hexutil.Encode(addr.Bytes()), t.accounts[addr])
+	}
+	return json.Marshal(prestate)
+}
+
+// diff assembles the pre and post state of all the modified accounts. Accounts
+// created by the transaction have no pre state, destroyed ones no post state.
+func (t *prestateTracer) diff(fromAcc *prestateAccount) (json.RawMessage, error) {
+	log.DebugLog()
+	// The sender was looked up at the first step, after buying all the gas of the
+	// transaction, so give that back too to get its real prestate
+	homestead := t.env.ChainConfig().IsHomestead(t.env.BlockNumber)
+	intrinsic, err := core.IntrinsicGas(t.input, t.typ == "CREATE", homestead)
+	if err != nil {
+		return nil, err
+	}
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(t.gas+intrinsic), t.env.GasPrice)
+	fromAcc.balance.Add(fromAcc.balance, cost)
+	fromAcc.Balance = hexBig(fromAcc.balance)
+
+	var (
+		db   = t.env.StateDB
+		pre  = newOrderedObject()
+		post = newOrderedObject()
+	)
+	for _, addr := range t.order {
+		var (
+			key     = hexutil.Encode(addr.Bytes())
+			before  = t.accounts[addr]
+			existed = !before.empty() && !(t.typ == "CREATE" && addr == t.to)
+		)
+		// Accounts destroyed by the transaction don't have a post state
+		if db.HasSuicided(addr) {
+			if existed {
+				pre.set(key, before.trim(nil))
+			}
+			continue
+		}
+		var (
+			after    = &prestateDiffAccount{Storage: newOrderedObject()}
+			changed  = make(map[common.Hash]bool)
+			modified bool
+		)
+		if balance := db.GetBalance(addr); balance.Cmp(before.balance) != 0 || !existed {
+			after.Balance, modified = hexBig(balance), true
+		}
+		if nonce := int64(db.GetNonce(addr)); nonce != before.Nonce || !existed {
+			after.Nonce, modified = &nonce, true
+		}
+		if code := db.GetCode(addr); !bytes.Equal(code, before.code) {
+			after.Code, modified = hexutil.Encode(code), true
+		}
+		for _, slot := range before.order {
+			if current := db.GetState(addr, slot); current != before.slots[slot] {
+				changed[slot], modified = true, true
+				if current != (common.Hash{}) {
+					after.Storage.set(hexutil.Encode(slot.Bytes()), hexutil.Encode(current.Bytes()))
+				}
+			}
+		}
+		if !modified {
+			continue
+		}
+		if after.Storage.len() == 0 {
+			after.Storage = nil
+		}
+		if existed {
+			pre.set(key, before.trim(changed))
+		}
+		post.set(key, after)
+	}
+	result := newOrderedObject()
+	result.set("pre", pre)
+	result.set("post", post)
+	return json.Marshal(result)
+}
+
+// trim returns a copy of the account retaining only the original values of the
+// modified storage slots, or of all accessed ones if changed is nil.
+func (a *prestateAccount) trim(changed map[common.Hash]bool) *prestateAccount {
+	log.DebugLog()
+	trimmed := *a
+	trimmed.Storage = newOrderedObject()
+	for _, slot := range a.order {
+		if changed != nil && !changed[slot] {
+			continue
+		}
+		if val := a.slots[slot]; val != (common.Hash{}) {
+			trimmed.Storage.set(hexutil.Encode(slot.Bytes()), hexutil.Encode(val.Bytes()))
+		}
+	}
+	return &trimmed
+}
@@ -274,9 +274,9 @@ func (cw *contractWrapper) pushObject(vm *duktape.Context) { log.DebugLog()
 	vm.PutPropString(obj, "getInput")
 }
 
-// Tracer provides an implementation of Tracer that evaluates a Javascript
+// jsTracer provides an implementation of Tracer that evaluates a Javascript
 // function for each VM execution step.
-type Tracer struct {
+type jsTracer struct {
 	inited bool // Flag whether the context was already inited from the EVM
 
 	vm *duktape.Context // Javascript VM instance
@@ -303,15 +303,15 @@ type Tracer struct {
 	reason    error  // Textual reason for the interruption
 }
 
-// New instantiates a new tracer instance. code specifies a Javascript snippet,
-// which must evaluate to an expression returning an object with 'step', 'fault'
-// and 'result' functions.
-func New(code string) (*Tracer, error) { log.DebugLog()
+// newJsTracer instantiates a new JavaScript tracer instance. code specifies a
+// Javascript snippet, which must evaluate to an expression returning an object
+// with 'step', 'fault' and 'result' functions.
+func newJsTracer(code string) (*jsTracer, error) { log.DebugLog()
 	// Resolve any tracers by name and assemble the tracer object
 	if tracer, ok := tracer(code); ok {
 		code = tracer
 	}
-	tracer := &Tracer{
+	tracer := &jsTracer{
 		vm:              duktape.New(),
 		ctx:             make(map[string]interface{}),
 		opWrapper:       new(opWrapper),
@@ -461,14 +461,14 @@ func New(code string) (*Tracer, error) { log.DebugLog()
 }
 
 // Stop terminates execution of the tracer at the first opportune moment.
-func (jst *Tracer) Stop(err error) { log.DebugLog()
+func (jst *jsTracer) Stop(err error) { log.DebugLog()
 	jst.reason = err
 	atomic.StoreUint32(&jst.interrupt, 1)
 }
 
 // call executes a method on a JS object, catching any errors, formatting and
 // returning them as error objects.
-func (jst *Tracer) call(method string, args ...string) (json.RawMessage, error) { log.DebugLog()
+func (jst *jsTracer) call(method string, args ...string) (json.RawMessage, error) { log.DebugLog()
 	// Execute the JavaScript call and return any error
 	jst.vm.PushString(method)
 	for _, arg := range args {
@@ -495,7 +495,7 @@ func wrapError(context string, err error) error { log.DebugLog()
 }
 
 // CaptureStart implements the Tracer interface to initialize the tracing operation.
-func (jst *Tracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error { log.DebugLog()
+func (jst *jsTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error { log.DebugLog()
 	jst.ctx["type"] = "CALL"
 	if create {
 		jst.ctx["type"] = "CREATE"
@@ -510,7 +510,7 @@ func (jst *Tracer) CaptureStart(from common.Address, to common.Address, create b
 }
 
 // CaptureState implements the Tracer interface to trace a single step of VM execution.
-func (jst *Tracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error { log.DebugLog()
+func (jst *jsTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error { log.DebugLog()
 	if jst.err == nil {
 		// Initialize the context if it wasn't done yet
 		if !jst.inited {
@@ -548,7 +548,7 @@ func (jst *Tracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost
 
 // CaptureFault implements the Tracer interface to trace an execution fault
 // while running an opcode.
-func (jst *Tracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error { log.DebugLog()
+func (jst *jsTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error { log.DebugLog()
 	if jst.err == nil {
 		// Apart from the error, everything matches the previous invocation
 		jst.errorValue = new(string)
@@ -563,7 +563,7 @@ func (jst *Tracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost
 }
 
 // CaptureEnd is called after the call finishes to finalize the tracing.
-func (jst *Tracer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error { log.DebugLog()
+func (jst *jsTracer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error { log.DebugLog()
 	jst.ctx["output"] = output
 	jst.ctx["gasUsed"] = gasUsed
 	jst.ctx["time"] = t.String()
@@ -575,7 +575,7 @@ func (jst *Tracer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, er
 }
 
 // GetResult calls the Javascript 'result' function and returns its value, or any accumulated error
-func (jst *Tracer) GetResult() (json.RawMessage, error) { log.DebugLog()
+func (jst *jsTracer) GetResult() (json.RawMessage, error) { log.DebugLog()
 	// Transform the context into a JavaScript object and inject into the state
 	obj := jst.vm.PushObject()
 
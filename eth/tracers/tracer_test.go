@@ -26,6 +26,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -34,16 +35,16 @@ type account struct{}
 func (account) SubBalance(amount *big.Int)                          { log.DebugLog()}
 func (account) AddBalance(amount *big.Int)                          { log.DebugLog()}
 func (account) SetAddress(common.Address)                           { log.DebugLog()}
-func (account) Value() *big.Int                                     { log.DebugLog() return nil }
+func (account) Value() *big.Int                                     { log.DebugLog(); return nil }
 func (account) SetBalance(*big.Int)                                 { log.DebugLog()}
 func (account) SetNonce(uint64)                                     { log.DebugLog()}
-func (account) Balance() *big.Int                                   { log.DebugLog() return nil }
-func (account) Address() common.Address                             { log.DebugLog() return common.Address{} }
+func (account) Balance() *big.Int                                   { log.DebugLog(); return nil }
+func (account) Address() common.Address                             { log.DebugLog(); return common.Address{} }
 func (account) ReturnGas(*big.Int)                                  { log.DebugLog()}
 func (account) SetCode(common.Hash, []byte)                         { log.DebugLog()}
 func (account) ForEachStorage(cb func(key, value common.Hash) bool) { log.DebugLog()}
 
-func runTrace(tracer *Tracer) (json.RawMessage, error) { log.DebugLog()
+func runTrace(tracer *jsTracer) (json.RawMessage, error) { log.DebugLog()
 	env := vm.NewEVM(vm.Context{BlockNumber: big.NewInt(1)}, nil, params.TestChainConfig, vm.Config{Debug: true, Tracer: tracer})
 
 	contract := vm.NewContract(account{}, account{}, big.NewInt(0), 10000)
@@ -57,7 +58,7 @@ func runTrace(tracer *Tracer) (json.RawMessage, error) { log.DebugLog()
 }
 
 func TestTracing(t *testing.T) { log.DebugLog()
-	tracer, err := New("{count: 0, step: function() { this.count += 1; }, fault: function() {}, result: function() { return this.count; }}")
+	tracer, err := newJsTracer("{count: 0, step: function() { this.count += 1; }, fault: function() {}, result: function() { return this.count; }}")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -72,7 +73,7 @@ func TestTracing(t *testing.T) { log.DebugLog()
 }
 
 func TestStack(t *testing.T) { log.DebugLog()
-	tracer, err := New("{depths: [], step: function(log) { this.depths.push(log.stack.length()); }, fault: function() {}, result: function() { return this.depths; }}")
+	tracer, err := newJsTracer("{depths: [], step: function(log) { this.depths.push(log.stack.length()); }, fault: function() {}, result: function() { return this.depths; }}")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -87,7 +88,7 @@ func TestStack(t *testing.T) { log.DebugLog()
 }
 
 func TestOpcodes(t *testing.T) { log.DebugLog()
-	tracer, err := New("{opcodes: [], step: function(log) { this.opcodes.push(log.op.toString()); }, fault: function() {}, result: function() { return this.opcodes; }}")
+	tracer, err := newJsTracer("{opcodes: [], step: function(log) { this.opcodes.push(log.op.toString()); }, fault: function() {}, result: function() { return this.opcodes; }}")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -105,7 +106,7 @@ func TestHalt(t *testing.T) { log.DebugLog()
 	t.Skip("duktape doesn't support abortion")
 
 	timeout := errors.New("stahp")
-	tracer, err := New("{step: function() { while(1); }, result: function() { return null; }}")
+	tracer, err := newJsTracer("{step: function() { while(1); }, result: function() { return null; }}")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -121,7 +122,7 @@ func TestHalt(t *testing.T) { log.DebugLog()
 }
 
 func TestHaltBetweenSteps(t *testing.T) { log.DebugLog()
-	tracer, err := New("{step: function() {}, fault: function() {}, result: function() { return null; }}")
+	tracer, err := newJsTracer("{step: function() {}, fault: function() {}, result: function() { return null; }}")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -14,19 +14,70 @@
 // You should have received a copy of the GNU Lesser General Public License
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
-// Package tracers is a collection of JavaScript transaction tracers.
+// Package tracers is a collection of transaction tracers, implemented either
+// natively in Go or in JavaScript.
 package tracers
 
 import (
+	"encoding/json"
 	"strings"
+	"sync"
 	"unicode"
 
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/tracers/internal/tracers"
 	"github.com/ethereum/go-ethereum/log"
 )
 
-// all contains all the built in JavaScript tracers by name.
-var all = make(map[string]string)
+// Tracer is a vm.Tracer producing a JSON result, which can be interrupted.
+type Tracer interface {
+	vm.Tracer
+
+	// GetResult returns the result of the tracing, or any accumulated error.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates the tracing at the first opportune moment.
+	Stop(err error)
+}
+
+// NativeConstructor creates a native tracer, parametrized by an optional tracer
+// specific configuration.
+type NativeConstructor func(config json.RawMessage) (Tracer, error)
+
+var (
+	// all contains all the built in JavaScript tracers by name.
+	all = make(map[string]string)
+
+	// native contains all the natively implemented tracers by name.
+	native     = make(map[string]NativeConstructor)
+	nativeLock sync.RWMutex
+)
+
+// RegisterNative makes a natively implemented tracer available by name. Native
+// tracers take precedence over the JavaScript ones with the same name.
+func RegisterNative(name string, ctor NativeConstructor) {
+	log.DebugLog()
+	nativeLock.Lock()
+	defer nativeLock.Unlock()
+
+	native[name] = ctor
+}
+
+// New creates a tracer from the given code. Names of native tracers resolve to
+// their Go implementations, everything else is evaluated as JavaScript: either
+// one of the built in tracers by name, or a custom snippet. The config is only
+// interpreted by native tracers.
+func New(code string, config json.RawMessage) (Tracer, error) {
+	log.DebugLog()
+	nativeLock.RLock()
+	ctor, ok := native[code]
+	nativeLock.RUnlock()
+
+	if ok {
+		return ctor(config)
+	}
+	return newJsTracer(code)
+}
 
 // camel converts a snake cased input string into a camel cased output.
 func camel(str string) string {
@@ -17,11 +17,13 @@
 package tracers
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
 	"math/big"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -32,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/tests"
 )
@@ -116,79 +119,165 @@ type callTracerTest struct {
 	Result  *callTrace    `json:"result"`
 }
 
-// Iterates over all the input-output datasets in the tracer test harness and
-// runs the JavaScript tracers against them.
-func TestCallTracer(t *testing.T) { log.DebugLog()
+// loadCallTracerTests reads all the call tracer datasets from the test harness.
+func loadCallTracerTests(t *testing.T) map[string]*callTracerTest { log.DebugLog()
 	files, err := ioutil.ReadDir("testdata")
 	if err != nil {
 		t.Fatalf("failed to retrieve tracer test suite: %v", err)
 	}
+	tests := make(map[string]*callTracerTest)
 	for _, file := range files {
 		if !strings.HasPrefix(file.Name(), "call_tracer_") {
 			continue
 		}
-		file := file // capture range variable
-		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+		// Call tracer test found, read if from disk
+		blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+		if err != nil {
+			t.Fatalf("failed to read testcase %s: %v", file.Name(), err)
+		}
+		test := new(callTracerTest)
+		if err := json.Unmarshal(blob, test); err != nil {
+			t.Fatalf("failed to parse testcase %s: %v", file.Name(), err)
+		}
+		tests[camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json"))] = test
+	}
+	return tests
+}
+
+// runCallTracerTest executes the transaction of a call tracer dataset on top of
+// its prestate with the given tracer attached, returning the tracing result.
+func runCallTracerTest(t *testing.T, test *callTracerTest, tracer Tracer) json.RawMessage { log.DebugLog()
+	// Configure a blockchain with the given prestate
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	db, _ := ethdb.NewMemDatabase()
+	statedb := tests.MakePreState(db, test.Genesis.Alloc)
+
+	// Create the EVM environment and run the tracer in it
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	// Retrieve the trace result
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// runs both the native and the JavaScript call tracers against them.
+func TestCallTracer(t *testing.T) { log.DebugLog()
+	for name, test := range loadCallTracerTests(t) {
+		name, test := name, test // capture range variables
+		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			// Call tracer test found, read if from disk
-			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			native, err := New("callTracer", nil)
 			if err != nil {
-				t.Fatalf("failed to read testcase: %v", err)
+				t.Fatalf("failed to create native call tracer: %v", err)
 			}
-			test := new(callTracerTest)
-			if err := json.Unmarshal(blob, test); err != nil {
-				t.Fatalf("failed to parse testcase: %v", err)
-			}
-			// Configure a blockchain with the given prestate
-			tx := new(types.Transaction)
-			if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
-				t.Fatalf("failed to parse testcase input: %v", err)
+			js, err := newJsTracer("callTracer")
+			if err != nil {
+				t.Fatalf("failed to create javascript call tracer: %v", err)
 			}
-			signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
-			origin, _ := signer.Sender(tx)
-
-			context := vm.Context{
-				CanTransfer: core.CanTransfer,
-				Transfer:    core.Transfer,
-				Origin:      origin,
-				Coinbase:    test.Context.Miner,
-				BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
-				Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
-				Difficulty:  (*big.Int)(test.Context.Difficulty),
-				GasLimit:    uint64(test.Context.GasLimit),
-				GasPrice:    tx.GasPrice(),
+			for kind, tracer := range map[string]Tracer{"native": native, "javascript": js} {
+				ret := new(callTrace)
+				if err := json.Unmarshal(runCallTracerTest(t, test, tracer), ret); err != nil {
+					t.Fatalf("%s: failed to unmarshal trace result: %v", kind, err)
+				}
+				if !reflect.DeepEqual(ret, test.Result) {
+					t.Fatalf("%s: trace mismatch: have %+v, want %+v", kind, ret, test.Result)
+				}
 			}
-			db, _ := ethdb.NewMemDatabase()
-			statedb := tests.MakePreState(db, test.Genesis.Alloc)
+		})
+	}
+}
 
-			// Create the tracer, the EVM environment and run it
-			tracer, err := New("callTracer")
-			if err != nil {
-				t.Fatalf("failed to create call tracer: %v", err)
-			}
-			evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+// Tests that the native tracers produce the exact same output as their JavaScript
+// counterparts, so that switching implementations is transparent to users.
+func TestNativeTracerParity(t *testing.T) { log.DebugLog()
+	// The execution time is the only field allowed to differ
+	timeField := regexp.MustCompile(`"time":"[^"]*"`)
 
-			msg, err := tx.AsMessage(signer)
+	for name, test := range loadCallTracerTests(t) {
+		for _, tracer := range []string{"callTracer", "prestateTracer", "4byteTracer"} {
+			native, err := New(tracer, nil)
 			if err != nil {
-				t.Fatalf("failed to prepare transaction for tracing: %v", err)
-			}
-			st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
-			if _, _, _, err = st.TransitionDb(); err != nil {
-				t.Fatalf("failed to execute transaction: %v", err)
+				t.Fatalf("%s/%s: failed to create native tracer: %v", name, tracer, err)
 			}
-			// Retrieve the trace result and compare against the etalon
-			res, err := tracer.GetResult()
+			js, err := newJsTracer(tracer)
 			if err != nil {
-				t.Fatalf("failed to retrieve trace result: %v", err)
-			}
-			ret := new(callTrace)
-			if err := json.Unmarshal(res, ret); err != nil {
-				t.Fatalf("failed to unmarshal trace result: %v", err)
+				t.Fatalf("%s/%s: failed to create javascript tracer: %v", name, tracer, err)
 			}
-			if !reflect.DeepEqual(ret, test.Result) {
-				t.Fatalf("trace mismatch: have %+v, want %+v", ret, test.Result)
+			have := timeField.ReplaceAll(runCallTracerTest(t, test, native), nil)
+			want := timeField.ReplaceAll(runCallTracerTest(t, test, js), nil)
+			if !bytes.Equal(have, want) {
+				t.Errorf("%s/%s: trace mismatch:\nnative:     %s\njavascript: %s", name, tracer, have, want)
 			}
+		}
+	}
+}
+
+// Tests that the prestate tracer in diff mode reports the pre and post states of
+// all the accounts modified by a transaction.
+func TestPrestateTracerDiffMode(t *testing.T) { log.DebugLog()
+	for name, test := range loadCallTracerTests(t) {
+		tracer, err := New("prestateTracer", json.RawMessage(`{"diffMode": true}`))
+		if err != nil {
+			t.Fatalf("%s: failed to create prestate tracer: %v", name, err)
+		}
+		res := new(struct {
+			Pre  map[common.Address]*prestateDiffAccount `json:"pre"`
+			Post map[common.Address]*prestateDiffAccount `json:"post"`
 		})
+		if err := json.Unmarshal(runCallTracerTest(t, test, tracer), res); err != nil {
+			t.Fatalf("%s: failed to unmarshal trace result: %v", name, err)
+		}
+		// The sender always pays for gas, so it must be in both the pre and post states
+		tx := new(types.Transaction)
+		rlp.DecodeBytes(common.FromHex(test.Input), tx)
+		signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+		from, _ := signer.Sender(tx)
+
+		pre, post := res.Pre[from], res.Post[from]
+		if pre == nil || post == nil {
+			t.Fatalf("%s: sender missing from diff: pre %v, post %v", name, pre, post)
+		}
+		if want := hexutil.EncodeBig(test.Genesis.Alloc[from].Balance); pre.Balance != want {
+			t.Errorf("%s: sender pre balance mismatch: have %s, want %s", name, pre.Balance, want)
+		}
+		if post.Nonce == nil || uint64(*post.Nonce) != test.Genesis.Alloc[from].Nonce+1 {
+			t.Errorf("%s: sender post nonce mismatch: have %v, want %d", name, post.Nonce, test.Genesis.Alloc[from].Nonce+1)
+		}
+		// Every account in the post state must have changed compared to the pre state
+		for addr, acc := range res.Post {
+			if prev, ok := res.Pre[addr]; ok && reflect.DeepEqual(prev, acc) {
+				t.Errorf("%s: unmodified account %x in diff", name, addr)
+			}
+		}
 	}
 }
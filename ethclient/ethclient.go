@@ -25,6 +25,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -456,7 +457,7 @@ func (ec *Client) CallContract(ctx context.Context, msg ethereum.CallMsg, blockN
 	var hex hexutil.Bytes
 	err := ec.c.CallContext(ctx, &hex, "eth_call", toCallArg(msg), toBlockNumArg(blockNumber))
 	if err != nil {
-		return nil, err
+		return nil, toRevertError(err)
 	}
 	return hex, nil
 }
@@ -468,7 +469,7 @@ func (ec *Client) PendingCallContract(ctx context.Context, msg ethereum.CallMsg)
 	var hex hexutil.Bytes
 	err := ec.c.CallContext(ctx, &hex, "eth_call", toCallArg(msg), "pending")
 	if err != nil {
-		return nil, err
+		return nil, toRevertError(err)
 	}
 	return hex, nil
 }
@@ -493,7 +494,7 @@ func (ec *Client) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64
 	var hex hexutil.Uint64
 	err := ec.c.CallContext(ctx, &hex, "eth_estimateGas", toCallArg(msg))
 	if err != nil {
-		return 0, err
+		return 0, toRevertError(err)
 	}
 	return uint64(hex), nil
 }
@@ -511,6 +512,29 @@ func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) er
 	return ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", common.ToHex(data))
 }
 
+// toRevertError converts a JSON-RPC error reporting a reverted execution into a
+// typed revert error, carrying the revert data and the decoded reason. Any other
+// error is returned as is.
+func toRevertError(err error) error {
+	log.DebugLog()
+	if rpcErr, ok := err.(rpc.Error); !ok || rpcErr.ErrorCode() != abi.RevertErrorCode {
+		return err
+	}
+	dataErr, ok := err.(rpc.DataError)
+	if !ok {
+		return err
+	}
+	hex, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return err
+	}
+	data, decErr := hexutil.Decode(hex)
+	if decErr != nil {
+		return err
+	}
+	return abi.NewRevertError(data)
+}
+
 func toCallArg(msg ethereum.CallMsg) interface{} {
 	log.DebugLog()
 	arg := map[string]interface{}{
@@ -16,7 +16,19 @@
 
 package ethclient
 
-import "github.com/ethereum/go-ethereum"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
 
 // Verify that Client implements the ethereum interfaces.
 var (
@@ -32,3 +44,52 @@ var (
 	// _ = ethereum.PendingStateEventer(&Client{})
 	_ = ethereum.PendingContractCaller(&Client{})
 )
+
+// revertData is the ABI encoding of a revert with the reason "test".
+var revertData = common.FromHex("0x08c379a0000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000000047465737400000000000000000000000000000000000000000000000000000000")
+
+// RevertingService is a mock eth RPC service failing all calls with a revert.
+type RevertingService struct{}
+
+func (s *RevertingService) Call(args map[string]interface{}, block string) (hexutil.Bytes, error) { log.DebugLog()
+	return nil, abi.NewRevertError(revertData)
+}
+
+func (s *RevertingService) EstimateGas(args map[string]interface{}) (hexutil.Uint64, error) { log.DebugLog()
+	return 0, errors.New("gas required exceeds allowance or always failing transaction")
+}
+
+// Tests that reverted calls are surfaced as typed revert errors.
+func TestRevertError(t *testing.T) { log.DebugLog()
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", new(RevertingService)); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	rpcClient := rpc.DialInProc(server)
+	defer rpcClient.Close()
+	client := NewClient(rpcClient)
+
+	to := common.HexToAddress("0x01")
+	msg := ethereum.CallMsg{To: &to}
+
+	_, err := client.CallContract(context.Background(), msg, nil)
+	revertErr, ok := err.(*abi.RevertError)
+	if !ok {
+		t.Fatalf("error type mismatch: have %T (%v), want *abi.RevertError", err, err)
+	}
+	if revertErr.Reason != "test" {
+		t.Errorf("revert reason mismatch: have %q, want %q", revertErr.Reason, "test")
+	}
+	if !bytes.Equal(revertErr.Data, revertData) {
+		t.Errorf("revert data mismatch: have %x, want %x", revertErr.Data, revertData)
+	}
+	if want := "execution reverted: test"; err.Error() != want {
+		t.Errorf("error message mismatch: have %q, want %q", err.Error(), want)
+	}
+	// Errors that aren't reverts must be passed through untouched
+	if _, err := client.EstimateGas(context.Background(), msg); err == nil {
+		t.Fatalf("expected error")
+	} else if _, ok := err.(*abi.RevertError); ok {
+		t.Errorf("plain error converted into revert: %v", err)
+	}
+}
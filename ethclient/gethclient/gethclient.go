@@ -0,0 +1,484 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package gethclient provides a client for the geth specific RPC namespaces (debug,
+// txpool, admin, miner and clique), complementing the standard API of ethclient.
+package gethclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Client defines typed wrappers for the geth specific RPC APIs.
+type Client struct {
+	c *rpc.Client
+}
+
+// Dial connects a client to the given URL.
+func Dial(rawurl string) (*Client, error) {
+	log.DebugLog()
+	c, err := rpc.Dial(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return New(c), nil
+}
+
+// New creates a client that uses the given RPC client.
+func New(c *rpc.Client) *Client {
+	log.DebugLog()
+	return &Client{c}
+}
+
+// Close closes the underlying RPC connection.
+func (gc *Client) Close() {
+	log.DebugLog()
+	gc.c.Close()
+}
+
+// BatchCall sends all given requests as a single batch, for issuing calls this
+// package has no typed wrapper for. Errors specific to a request are reported
+// through the Error field of the corresponding rpc.BatchElem.
+func (gc *Client) BatchCall(ctx context.Context, b []rpc.BatchElem) error {
+	log.DebugLog()
+	return gc.c.BatchCallContext(ctx, b)
+}
+
+// Blockchain Access
+
+// HeadersByNumber retrieves multiple block headers from the canonical chain in a
+// single batch request. A nil number stands for the latest known header.
+func (gc *Client) HeadersByNumber(ctx context.Context, numbers []*big.Int) ([]*types.Header, error) {
+	log.DebugLog()
+	headers := make([]*types.Header, len(numbers))
+	reqs := make([]rpc.BatchElem, len(numbers))
+	for i, number := range numbers {
+		reqs[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{toBlockNumArg(number), false},
+			Result: &headers[i],
+		}
+	}
+	if err := gc.c.BatchCallContext(ctx, reqs); err != nil {
+		return nil, err
+	}
+	for i := range reqs {
+		if reqs[i].Error != nil {
+			return nil, reqs[i].Error
+		}
+		if headers[i] == nil {
+			return nil, ethereum.NotFound
+		}
+	}
+	return headers, nil
+}
+
+// UnclesByHash retrieves the uncle headers of the given block, fetching all of
+// them in a single batch request.
+func (gc *Client) UnclesByHash(ctx context.Context, hash common.Hash) ([]*types.Header, error) {
+	log.DebugLog()
+	var count *hexutil.Uint
+	if err := gc.c.CallContext(ctx, &count, "eth_getUncleCountByBlockHash", hash); err != nil {
+		return nil, err
+	}
+	if count == nil {
+		return nil, ethereum.NotFound
+	}
+	uncles := make([]*types.Header, *count)
+	reqs := make([]rpc.BatchElem, *count)
+	for i := range reqs {
+		reqs[i] = rpc.BatchElem{
+			Method: "eth_getUncleByBlockHashAndIndex",
+			Args:   []interface{}{hash, hexutil.EncodeUint64(uint64(i))},
+			Result: &uncles[i],
+		}
+	}
+	if err := gc.c.BatchCallContext(ctx, reqs); err != nil {
+		return nil, err
+	}
+	for i := range reqs {
+		if reqs[i].Error != nil {
+			return nil, reqs[i].Error
+		}
+		if uncles[i] == nil {
+			return nil, fmt.Errorf("got null header for uncle %d of block %x", i, hash[:])
+		}
+	}
+	return uncles, nil
+}
+
+// Debug API
+
+// TraceConfig holds the options of the debug tracing methods. Leaving Tracer nil
+// produces the struct logs of the default opcode logger.
+type TraceConfig struct {
+	DisableMemory  bool            `json:"disableMemory,omitempty"`  // Disable memory capture
+	DisableStack   bool            `json:"disableStack,omitempty"`   // Disable stack capture
+	DisableStorage bool            `json:"disableStorage,omitempty"` // Disable storage capture
+	Limit          int             `json:"limit,omitempty"`          // Maximum number of struct logs, zero means unlimited
+	Tracer         *string         `json:"tracer,omitempty"`         // Name or source of a JavaScript tracer
+	TracerConfig   json.RawMessage `json:"tracerConfig,omitempty"`   // Options passed to the tracer
+	Timeout        *string         `json:"timeout,omitempty"`        // Tracing timeout, e.g. "5s"
+	Reexec         *uint64         `json:"reexec,omitempty"`         // Number of blocks to reexecute for missing state
+}
+
+// TxTraceResult is the trace of a single transaction within a traced block.
+type TxTraceResult struct {
+	Result json.RawMessage `json:"result,omitempty"` // Trace produced by the tracer
+	Error  string          `json:"error,omitempty"`  // Failure produced by the tracer
+}
+
+// TraceTransaction returns the trace of a transaction, in the format produced by
+// the configured tracer.
+func (gc *Client) TraceTransaction(ctx context.Context, hash common.Hash, config *TraceConfig) (json.RawMessage, error) {
+	log.DebugLog()
+	var result json.RawMessage
+	err := gc.c.CallContext(ctx, &result, "debug_traceTransaction", hash, config)
+	return result, err
+}
+
+// TraceTransactions traces multiple transactions in a single batch request. The
+// returned slice contains a trace or an error for each transaction.
+func (gc *Client) TraceTransactions(ctx context.Context, hashes []common.Hash, config *TraceConfig) ([]json.RawMessage, []error, error) {
+	log.DebugLog()
+	results := make([]json.RawMessage, len(hashes))
+	reqs := make([]rpc.BatchElem, len(hashes))
+	for i, hash := range hashes {
+		reqs[i] = rpc.BatchElem{
+			Method: "debug_traceTransaction",
+			Args:   []interface{}{hash, config},
+			Result: &results[i],
+		}
+	}
+	if err := gc.c.BatchCallContext(ctx, reqs); err != nil {
+		return nil, nil, err
+	}
+	errs := make([]error, len(reqs))
+	for i := range reqs {
+		errs[i] = reqs[i].Error
+	}
+	return results, errs, nil
+}
+
+// TraceCall traces a call executed on top of the given block, without creating a
+// transaction. The block number can be nil, in which case the latest known block
+// is used.
+func (gc *Client) TraceCall(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int, config *TraceConfig) (json.RawMessage, error) {
+	log.DebugLog()
+	var result json.RawMessage
+	err := gc.c.CallContext(ctx, &result, "debug_traceCall", toCallArg(msg), toBlockNumArg(blockNumber), config)
+	return result, err
+}
+
+// TraceBlockByNumber traces all the transactions of a block of the canonical chain.
+func (gc *Client) TraceBlockByNumber(ctx context.Context, number *big.Int, config *TraceConfig) ([]*TxTraceResult, error) {
+	log.DebugLog()
+	var result []*TxTraceResult
+	err := gc.c.CallContext(ctx, &result, "debug_traceBlockByNumber", toBlockNumArg(number), config)
+	return result, err
+}
+
+// TraceBlockByHash traces all the transactions of the block with the given hash.
+func (gc *Client) TraceBlockByHash(ctx context.Context, hash common.Hash, config *TraceConfig) ([]*TxTraceResult, error) {
+	log.DebugLog()
+	var result []*TxTraceResult
+	err := gc.c.CallContext(ctx, &result, "debug_traceBlockByHash", hash, config)
+	return result, err
+}
+
+// Preimage returns the preimage of a sha3 hash, if known by the node.
+func (gc *Client) Preimage(ctx context.Context, hash common.Hash) ([]byte, error) {
+	log.DebugLog()
+	var result hexutil.Bytes
+	err := gc.c.CallContext(ctx, &result, "debug_preimage", hash)
+	return result, err
+}
+
+// ModifiedAccountsByNumber returns the accounts modified between the two blocks,
+// that is in the blocks after start up to and including end. If end is nil, only
+// the modifications of the start block are returned.
+func (gc *Client) ModifiedAccountsByNumber(ctx context.Context, start uint64, end *uint64) ([]common.Address, error) {
+	log.DebugLog()
+	var result []common.Address
+	err := gc.c.CallContext(ctx, &result, "debug_getModifiedAccountsByNumber", start, end)
+	return result, err
+}
+
+// BlockRLP returns the RLP encoding of the block with the given number.
+func (gc *Client) BlockRLP(ctx context.Context, number uint64) ([]byte, error) {
+	log.DebugLog()
+	var result string
+	if err := gc.c.CallContext(ctx, &result, "debug_getBlockRlp", number); err != nil {
+		return nil, err
+	}
+	return common.FromHex(result), nil
+}
+
+// SetHead rewinds the head of the node's chain to the given block number.
+func (gc *Client) SetHead(ctx context.Context, number uint64) error {
+	log.DebugLog()
+	return gc.c.CallContext(ctx, nil, "debug_setHead", hexutil.Uint64(number))
+}
+
+// TxPool API
+
+// TxPoolStatus returns the number of pending and queued transactions in the pool.
+func (gc *Client) TxPoolStatus(ctx context.Context) (pending uint, queued uint, err error) {
+	log.DebugLog()
+	var result map[string]hexutil.Uint
+	if err := gc.c.CallContext(ctx, &result, "txpool_status"); err != nil {
+		return 0, 0, err
+	}
+	return uint(result["pending"]), uint(result["queued"]), nil
+}
+
+// TxPoolContent returns the pending and queued transactions of the pool, grouped
+// by sender and nonce.
+func (gc *Client) TxPoolContent(ctx context.Context) (pending, queued map[common.Address]map[uint64]*types.Transaction, err error) {
+	log.DebugLog()
+	var result map[string]map[string]map[string]*types.Transaction
+	if err := gc.c.CallContext(ctx, &result, "txpool_content"); err != nil {
+		return nil, nil, err
+	}
+	pending = make(map[common.Address]map[uint64]*types.Transaction)
+	queued = make(map[common.Address]map[uint64]*types.Transaction)
+	for kind, content := range map[string]map[common.Address]map[uint64]*types.Transaction{"pending": pending, "queued": queued} {
+		for account, txs := range result[kind] {
+			for nonce, tx := range txs {
+				addr, n, err := parsePoolKey(account, nonce)
+				if err != nil {
+					return nil, nil, err
+				}
+				if content[addr] == nil {
+					content[addr] = make(map[uint64]*types.Transaction)
+				}
+				content[addr][n] = tx
+			}
+		}
+	}
+	return pending, queued, nil
+}
+
+// TxPoolInspect returns a textual summary of the pending and queued transactions
+// of the pool, grouped by sender and nonce.
+func (gc *Client) TxPoolInspect(ctx context.Context) (pending, queued map[common.Address]map[uint64]string, err error) {
+	log.DebugLog()
+	var result map[string]map[string]map[string]string
+	if err := gc.c.CallContext(ctx, &result, "txpool_inspect"); err != nil {
+		return nil, nil, err
+	}
+	pending = make(map[common.Address]map[uint64]string)
+	queued = make(map[common.Address]map[uint64]string)
+	for kind, content := range map[string]map[common.Address]map[uint64]string{"pending": pending, "queued": queued} {
+		for account, txs := range result[kind] {
+			for nonce, tx := range txs {
+				addr, n, err := parsePoolKey(account, nonce)
+				if err != nil {
+					return nil, nil, err
+				}
+				if content[addr] == nil {
+					content[addr] = make(map[uint64]string)
+				}
+				content[addr][n] = tx
+			}
+		}
+	}
+	return pending, queued, nil
+}
+
+// Admin API
+
+// AddPeer requests the node to connect to a remote peer, given its enode URL.
+func (gc *Client) AddPeer(ctx context.Context, url string) error {
+	log.DebugLog()
+	return gc.c.CallContext(ctx, nil, "admin_addPeer", url)
+}
+
+// RemovePeer requests the node to disconnect from a remote peer.
+func (gc *Client) RemovePeer(ctx context.Context, url string) error {
+	log.DebugLog()
+	return gc.c.CallContext(ctx, nil, "admin_removePeer", url)
+}
+
+// Peers returns information about the peers the node is connected to.
+func (gc *Client) Peers(ctx context.Context) ([]*p2p.PeerInfo, error) {
+	log.DebugLog()
+	var result []*p2p.PeerInfo
+	err := gc.c.CallContext(ctx, &result, "admin_peers")
+	return result, err
+}
+
+// NodeInfo returns information about the node itself.
+func (gc *Client) NodeInfo(ctx context.Context) (*p2p.NodeInfo, error) {
+	log.DebugLog()
+	var result *p2p.NodeInfo
+	err := gc.c.CallContext(ctx, &result, "admin_nodeInfo")
+	return result, err
+}
+
+// Datadir returns the data directory of the node.
+func (gc *Client) Datadir(ctx context.Context) (string, error) {
+	log.DebugLog()
+	var result string
+	err := gc.c.CallContext(ctx, &result, "admin_datadir")
+	return result, err
+}
+
+// Miner API
+
+// MinerStart starts the CPU miner with the given number of threads. Zero threads
+// leaves the current thread count unchanged.
+func (gc *Client) MinerStart(ctx context.Context, threads int) error {
+	log.DebugLog()
+	if threads == 0 {
+		return gc.c.CallContext(ctx, nil, "miner_start")
+	}
+	return gc.c.CallContext(ctx, nil, "miner_start", threads)
+}
+
+// MinerStop stops the CPU miner.
+func (gc *Client) MinerStop(ctx context.Context) error {
+	log.DebugLog()
+	return gc.c.CallContext(ctx, nil, "miner_stop")
+}
+
+// MinerSetEtherbase sets the address receiving the mining rewards.
+func (gc *Client) MinerSetEtherbase(ctx context.Context, etherbase common.Address) error {
+	log.DebugLog()
+	return gc.c.CallContext(ctx, nil, "miner_setEtherbase", etherbase)
+}
+
+// MinerSetGasPrice sets the minimum gas price accepted by the miner.
+func (gc *Client) MinerSetGasPrice(ctx context.Context, price *big.Int) error {
+	log.DebugLog()
+	return gc.c.CallContext(ctx, nil, "miner_setGasPrice", (*hexutil.Big)(price))
+}
+
+// MinerSetExtra sets the extra data included in the mined blocks.
+func (gc *Client) MinerSetExtra(ctx context.Context, extra string) error {
+	log.DebugLog()
+	return gc.c.CallContext(ctx, nil, "miner_setExtra", extra)
+}
+
+// Clique API
+
+// CliqueSnapshot retrieves the clique voting snapshot at the given block. The
+// block number can be nil, in which case the latest known block is used.
+func (gc *Client) CliqueSnapshot(ctx context.Context, number *big.Int) (*clique.Snapshot, error) {
+	log.DebugLog()
+	var result *clique.Snapshot
+	if err := gc.c.CallContext(ctx, &result, "clique_getSnapshot", toBlockNumArg(number)); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ethereum.NotFound
+	}
+	return result, nil
+}
+
+// CliqueSigners retrieves the authorized signers at the given block. The block
+// number can be nil, in which case the latest known block is used.
+func (gc *Client) CliqueSigners(ctx context.Context, number *big.Int) ([]common.Address, error) {
+	log.DebugLog()
+	var result []common.Address
+	err := gc.c.CallContext(ctx, &result, "clique_getSigners", toBlockNumArg(number))
+	return result, err
+}
+
+// CliqueSignersAtHash retrieves the authorized signers at the given block.
+func (gc *Client) CliqueSignersAtHash(ctx context.Context, hash common.Hash) ([]common.Address, error) {
+	log.DebugLog()
+	var result []common.Address
+	err := gc.c.CallContext(ctx, &result, "clique_getSignersAtHash", hash)
+	return result, err
+}
+
+// CliqueProposals returns the current proposals the node is voting on.
+func (gc *Client) CliqueProposals(ctx context.Context) (map[common.Address]bool, error) {
+	log.DebugLog()
+	var result map[common.Address]bool
+	err := gc.c.CallContext(ctx, &result, "clique_proposals")
+	return result, err
+}
+
+// CliquePropose injects a new authorization proposal that the signer will vote
+// on when creating blocks.
+func (gc *Client) CliquePropose(ctx context.Context, address common.Address, auth bool) error {
+	log.DebugLog()
+	return gc.c.CallContext(ctx, nil, "clique_propose", address, auth)
+}
+
+// CliqueDiscard drops a currently running proposal.
+func (gc *Client) CliqueDiscard(ctx context.Context, address common.Address) error {
+	log.DebugLog()
+	return gc.c.CallContext(ctx, nil, "clique_discard", address)
+}
+
+// parsePoolKey converts the account and nonce keys of a transaction pool dump
+// into their typed counterparts.
+func parsePoolKey(account, nonce string) (common.Address, uint64, error) {
+	log.DebugLog()
+	if !common.IsHexAddress(account) {
+		return common.Address{}, 0, fmt.Errorf("invalid account in pool content: %q", account)
+	}
+	n, err := strconv.ParseUint(nonce, 10, 64)
+	if err != nil {
+		return common.Address{}, 0, fmt.Errorf("invalid nonce in pool content: %q", nonce)
+	}
+	return common.HexToAddress(account), n, nil
+}
+
+func toBlockNumArg(number *big.Int) string {
+	log.DebugLog()
+	if number == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(number)
+}
+
+func toCallArg(msg ethereum.CallMsg) interface{} {
+	log.DebugLog()
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["data"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	return arg
+}
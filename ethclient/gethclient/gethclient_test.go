@@ -0,0 +1,169 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gethclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAccount = crypto.PubkeyToAddress(testKey.PublicKey)
+	testUncles  = []*types.Header{
+		{Number: big.NewInt(1), Difficulty: big.NewInt(1), Time: big.NewInt(0), Extra: []byte("first")},
+		{Number: big.NewInt(1), Difficulty: big.NewInt(1), Time: big.NewInt(0), Extra: []byte("second")},
+	}
+)
+
+// EthService is a mock eth RPC service serving a block with two uncles.
+type EthService struct{}
+
+func (s *EthService) GetUncleCountByBlockHash(hash common.Hash) *hexutil.Uint { log.DebugLog()
+	if hash != (common.Hash{1}) {
+		return nil
+	}
+	n := hexutil.Uint(len(testUncles))
+	return &n
+}
+
+func (s *EthService) GetUncleByBlockHashAndIndex(hash common.Hash, index hexutil.Uint) *types.Header { log.DebugLog()
+	return testUncles[index]
+}
+
+func (s *EthService) GetBlockByNumber(number rpc.BlockNumber, full bool) *types.Header { log.DebugLog()
+	if number == rpc.LatestBlockNumber {
+		number = 2
+	}
+	if number > 2 {
+		return nil
+	}
+	return &types.Header{Number: big.NewInt(number.Int64()), Difficulty: big.NewInt(1), Time: big.NewInt(0)}
+}
+
+// TxPoolService is a mock txpool RPC service holding a single pending transaction.
+type TxPoolService struct{}
+
+func (s *TxPoolService) Status() map[string]hexutil.Uint { log.DebugLog()
+	return map[string]hexutil.Uint{"pending": 1, "queued": 0}
+}
+
+func (s *TxPoolService) Content() map[string]map[string]map[string]*types.Transaction { log.DebugLog()
+	tx, _ := types.SignTx(types.NewTransaction(5, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil), types.HomesteadSigner{}, testKey)
+	return map[string]map[string]map[string]*types.Transaction{
+		"pending": {testAccount.Hex(): {"5": tx}},
+		"queued":  {},
+	}
+}
+
+// DebugService is a mock debug RPC service tracing only a known transaction.
+type DebugService struct{}
+
+func (s *DebugService) TraceTransaction(hash common.Hash, config *TraceConfig) (interface{}, error) { log.DebugLog()
+	if hash != (common.Hash{1}) {
+		return nil, errors.New("transaction not found")
+	}
+	return map[string]interface{}{"gas": 21000, "limit": config.Limit}, nil
+}
+
+func newTestClient(t *testing.T) *Client { log.DebugLog()
+	server := rpc.NewServer()
+	for name, service := range map[string]interface{}{"eth": new(EthService), "txpool": new(TxPoolService), "debug": new(DebugService)} {
+		if err := server.RegisterName(name, service); err != nil {
+			t.Fatalf("failed to register %s service: %v", name, err)
+		}
+	}
+	return New(rpc.DialInProc(server))
+}
+
+// Tests that multiple headers and all uncles of a block are retrieved in batches.
+func TestBatchedHeaders(t *testing.T) { log.DebugLog()
+	client := newTestClient(t)
+	defer client.Close()
+
+	headers, err := client.HeadersByNumber(context.Background(), []*big.Int{big.NewInt(0), big.NewInt(1), nil})
+	if err != nil {
+		t.Fatalf("failed to retrieve headers: %v", err)
+	}
+	for i, want := range []int64{0, 1, 2} {
+		if headers[i].Number.Int64() != want {
+			t.Errorf("header %d: number mismatch: have %v, want %v", i, headers[i].Number, want)
+		}
+	}
+	if _, err := client.HeadersByNumber(context.Background(), []*big.Int{big.NewInt(3)}); err == nil {
+		t.Errorf("missing header retrieved")
+	}
+	uncles, err := client.UnclesByHash(context.Background(), common.Hash{1})
+	if err != nil {
+		t.Fatalf("failed to retrieve uncles: %v", err)
+	}
+	if len(uncles) != len(testUncles) {
+		t.Fatalf("uncle count mismatch: have %d, want %d", len(uncles), len(testUncles))
+	}
+	for i, uncle := range uncles {
+		if uncle.Hash() != testUncles[i].Hash() {
+			t.Errorf("uncle %d: hash mismatch: have %x, want %x", i, uncle.Hash(), testUncles[i].Hash())
+		}
+	}
+	if _, err := client.UnclesByHash(context.Background(), common.Hash{2}); err == nil {
+		t.Errorf("uncles of missing block retrieved")
+	}
+}
+
+// Tests that the transaction pool dumps are converted into typed maps.
+func TestTxPool(t *testing.T) { log.DebugLog()
+	client := newTestClient(t)
+	defer client.Close()
+
+	pending, queued, err := client.TxPoolStatus(context.Background())
+	if err != nil || pending != 1 || queued != 0 {
+		t.Fatalf("status mismatch: have %d/%d (%v), want 1/0", pending, queued, err)
+	}
+	content, _, err := client.TxPoolContent(context.Background())
+	if err != nil {
+		t.Fatalf("failed to retrieve content: %v", err)
+	}
+	if tx := content[testAccount][5]; tx == nil || tx.Nonce() != 5 {
+		t.Fatalf("pending transaction mismatch: have %v", tx)
+	}
+}
+
+// Tests that batched traces report failures per transaction.
+func TestTraceTransactions(t *testing.T) { log.DebugLog()
+	client := newTestClient(t)
+	defer client.Close()
+
+	traces, errs, err := client.TraceTransactions(context.Background(), []common.Hash{{1}, {2}}, &TraceConfig{Limit: 7})
+	if err != nil {
+		t.Fatalf("failed to trace transactions: %v", err)
+	}
+	if errs[0] != nil || string(traces[0]) != `{"gas":21000,"limit":7}` {
+		t.Errorf("trace mismatch: have %s (%v)", traces[0], errs[0])
+	}
+	if errs[1] == nil {
+		t.Errorf("unknown transaction traced: %s", traces[1])
+	}
+}
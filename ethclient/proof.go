@@ -0,0 +1,191 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// emptyCodeHash is the known hash of the empty EVM bytecode.
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+// AccountResult is the state of an account along with the merkle proofs needed to
+// verify it, and the requested storage slots, against a state root.
+type AccountResult struct {
+	Address      common.Address
+	AccountProof [][]byte
+	Balance      *big.Int
+	CodeHash     common.Hash
+	Nonce        uint64
+	StorageHash  common.Hash
+	StorageProof []StorageResult
+}
+
+// StorageResult is the value of a storage slot along with the merkle proof needed
+// to verify it against the storage root of its account.
+type StorageResult struct {
+	Key   common.Hash
+	Value *big.Int
+	Proof [][]byte
+}
+
+type rpcAccountResult struct {
+	Address      common.Address     `json:"address"`
+	AccountProof []hexutil.Bytes    `json:"accountProof"`
+	Balance      *hexutil.Big       `json:"balance"`
+	CodeHash     common.Hash        `json:"codeHash"`
+	Nonce        hexutil.Uint64     `json:"nonce"`
+	StorageHash  common.Hash        `json:"storageHash"`
+	StorageProof []rpcStorageResult `json:"storageProof"`
+}
+
+type rpcStorageResult struct {
+	Key   string          `json:"key"`
+	Value *hexutil.Big    `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// GetProof returns the account and the given storage slots of it, along with the
+// merkle proofs of their values. The block number can be nil, in which case the
+// proofs are taken from the latest known block.
+func (ec *Client) GetProof(ctx context.Context, account common.Address, keys []common.Hash, blockNumber *big.Int) (*AccountResult, error) {
+	log.DebugLog()
+	hexKeys := make([]string, len(keys))
+	for i, key := range keys {
+		hexKeys[i] = key.Hex()
+	}
+	var res *rpcAccountResult
+	if err := ec.c.CallContext(ctx, &res, "eth_getProof", account, hexKeys, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	if res == nil || res.Balance == nil {
+		return nil, fmt.Errorf("missing account proof for %x", account)
+	}
+	result := &AccountResult{
+		Address:      res.Address,
+		AccountProof: fromHexSlice(res.AccountProof),
+		Balance:      (*big.Int)(res.Balance),
+		CodeHash:     res.CodeHash,
+		Nonce:        uint64(res.Nonce),
+		StorageHash:  res.StorageHash,
+		StorageProof: make([]StorageResult, len(res.StorageProof)),
+	}
+	for i, slot := range res.StorageProof {
+		if slot.Value == nil {
+			return nil, fmt.Errorf("missing value for storage slot %s", slot.Key)
+		}
+		result.StorageProof[i] = StorageResult{
+			Key:   common.HexToHash(slot.Key),
+			Value: (*big.Int)(slot.Value),
+			Proof: fromHexSlice(slot.Proof),
+		}
+	}
+	return result, nil
+}
+
+// Verify checks the account proof against the given state root, usually that of
+// a trusted block header, and the storage proofs against the proven storage root.
+// It returns an error if any proof is invalid or doesn't match the reported values.
+func (r *AccountResult) Verify(root common.Hash) error {
+	log.DebugLog()
+	data, err := verifyProof(root, r.Address.Bytes(), r.AccountProof)
+	if err != nil {
+		return fmt.Errorf("invalid account proof: %v", err)
+	}
+	// Missing accounts must be empty, existing ones must match the proven data
+	account := struct {
+		Nonce    uint64
+		Balance  *big.Int
+		Root     common.Hash
+		CodeHash []byte
+	}{0, new(big.Int), types.EmptyRootHash, emptyCodeHash.Bytes()}
+
+	if data != nil {
+		if err := rlp.DecodeBytes(data, &account); err != nil {
+			return fmt.Errorf("invalid account in proof: %v", err)
+		}
+	}
+	switch {
+	case account.Nonce != r.Nonce:
+		return fmt.Errorf("nonce mismatch: proven %d, reported %d", account.Nonce, r.Nonce)
+	case account.Balance.Cmp(r.Balance) != 0:
+		return fmt.Errorf("balance mismatch: proven %v, reported %v", account.Balance, r.Balance)
+	case account.Root != r.StorageHash:
+		return fmt.Errorf("storage root mismatch: proven %x, reported %x", account.Root, r.StorageHash)
+	case !bytes.Equal(account.CodeHash, r.CodeHash.Bytes()):
+		return fmt.Errorf("code hash mismatch: proven %x, reported %x", account.CodeHash, r.CodeHash)
+	}
+	// Verify all the storage slots against the proven storage root. A missing
+	// account has no storage trie to prove against, all its slots are empty.
+	for _, slot := range r.StorageProof {
+		want := new(big.Int)
+		if data != nil {
+			value, err := verifyProof(r.StorageHash, slot.Key.Bytes(), slot.Proof)
+			if err != nil {
+				return fmt.Errorf("invalid proof for storage slot %x: %v", slot.Key, err)
+			}
+			if value != nil {
+				var content []byte
+				if err := rlp.DecodeBytes(value, &content); err != nil {
+					return fmt.Errorf("invalid value for storage slot %x: %v", slot.Key, err)
+				}
+				want.SetBytes(content)
+			}
+		}
+		if want.Cmp(slot.Value) != 0 {
+			return fmt.Errorf("storage slot %x mismatch: proven %v, reported %v", slot.Key, want, slot.Value)
+		}
+	}
+	return nil
+}
+
+// verifyProof checks a merkle proof of a secure trie, returning the proven value
+// of the key or nil if the proof proves its absence.
+func verifyProof(root common.Hash, key []byte, proof [][]byte) ([]byte, error) {
+	log.DebugLog()
+	// The empty trie has no nodes, anything is absent from it
+	if root == types.EmptyRootHash {
+		return nil, nil
+	}
+	proofDb, _ := ethdb.NewMemDatabase()
+	for _, node := range proof {
+		proofDb.Put(crypto.Keccak256(node), node)
+	}
+	value, err, _ := trie.VerifyProof(root, crypto.Keccak256(key), proofDb)
+	return value, err
+}
+
+func fromHexSlice(nodes []hexutil.Bytes) [][]byte {
+	log.DebugLog()
+	proof := make([][]byte, len(nodes))
+	for i, node := range nodes {
+		proof[i] = node
+	}
+	return proof
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that account and storage proofs retrieved over RPC verify against the
+// state root of the block, and that tampered results are rejected.
+func TestGetProof(t *testing.T) { log.DebugLog()
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}})
+	defer sim.Close()
+
+	// Deploy a contract storing 42 into slot 1 of its storage
+	tx, _ := types.SignTx(types.NewContractCreation(0, new(big.Int), 100000, big.NewInt(1), common.FromHex("0x602a60015500")), types.HomesteadSigner{}, key)
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("failed to deploy contract: %v", err)
+	}
+	sim.Commit()
+
+	client := NewClient(sim.Client())
+	header, err := client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve header: %v", err)
+	}
+	contract := crypto.CreateAddress(addr, 0)
+	slots := []common.Hash{common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(2))}
+
+	for _, account := range []common.Address{addr, contract, common.HexToAddress("0xdead")} {
+		result, err := client.GetProof(context.Background(), account, slots, nil)
+		if err != nil {
+			t.Fatalf("%x: failed to retrieve proof: %v", account, err)
+		}
+		if err := result.Verify(header.Root); err != nil {
+			t.Fatalf("%x: proof failed to verify: %v", account, err)
+		}
+	}
+	result, _ := client.GetProof(context.Background(), contract, slots, nil)
+	if result.StorageProof[0].Value.Cmp(big.NewInt(42)) != 0 {
+		t.Fatalf("storage value mismatch: have %v, want 42", result.StorageProof[0].Value)
+	}
+	result.StorageProof[0].Value = big.NewInt(43)
+	if err := result.Verify(header.Root); err == nil {
+		t.Fatalf("tampered storage value verified")
+	}
+	result, _ = client.GetProof(context.Background(), addr, nil, nil)
+	result.Balance = new(big.Int).Add(result.Balance, big.NewInt(1))
+	if err := result.Verify(header.Root); err == nil {
+		t.Fatalf("tampered balance verified")
+	}
+}
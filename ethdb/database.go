@@ -27,7 +27,6 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
 	"github.com/syndtr/goleveldb/leveldb/filter"
-	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
@@ -118,15 +117,54 @@ func (db *LDBDatabase) Delete(key []byte) error { log.DebugLog()
 	return db.db.Delete(key, nil)
 }
 
-func (db *LDBDatabase) NewIterator() iterator.Iterator { log.DebugLog()
+// NewIterator returns an iterator over the entire database content.
+func (db *LDBDatabase) NewIterator() Iterator { log.DebugLog()
 	return db.db.NewIterator(nil, nil)
 }
 
 // NewIteratorWithPrefix returns a iterator to iterate over subset of database content with a particular prefix.
-func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator { log.DebugLog()
+func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) Iterator { log.DebugLog()
 	return db.db.NewIterator(util.BytesPrefix(prefix), nil)
 }
 
+// NewIteratorWithRange returns a iterator to iterate over subset of database
+// content with keys in the range [start, limit).
+func (db *LDBDatabase) NewIteratorWithRange(start []byte, limit []byte) Iterator { log.DebugLog()
+	return db.db.NewIterator(&util.Range{Start: start, Limit: limit}, nil)
+}
+
+// DeleteRange deletes all keys in the range [start, limit) from the database.
+// The deletions are flushed in batches to avoid unbounded memory use.
+func (db *LDBDatabase) DeleteRange(start []byte, limit []byte) error { log.DebugLog()
+	it := db.db.NewIterator(&util.Range{Start: start, Limit: limit}, nil)
+	defer it.Release()
+
+	batch := new(leveldb.Batch)
+	for it.Next() {
+		batch.Delete(it.Key())
+		if batch.Len() >= IdealBatchSize/32 {
+			if err := db.db.Write(batch, nil); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return db.db.Write(batch, nil)
+}
+
+// Stat returns a particular internal stat of the database, e.g. "leveldb.stats".
+func (db *LDBDatabase) Stat(property string) (string, error) { log.DebugLog()
+	return db.db.GetProperty(property)
+}
+
+// Compact flattens the underlying data store for the given key range.
+func (db *LDBDatabase) Compact(start []byte, limit []byte) error { log.DebugLog()
+	return db.db.CompactRange(util.Range{Start: start, Limit: limit})
+}
+
 func (db *LDBDatabase) Close() { log.DebugLog()
 	// Stop the metrics collection to avoid internal database races
 	db.quitLock.Lock()
@@ -311,6 +349,12 @@ func (b *ldbBatch) Put(key, value []byte) error { log.DebugLog()
 	return nil
 }
 
+func (b *ldbBatch) Delete(key []byte) error { log.DebugLog()
+	b.b.Delete(key)
+	b.size += 1
+	return nil
+}
+
 func (b *ldbBatch) Write() error { log.DebugLog()
 	return b.db.Write(b.b, nil)
 }
@@ -354,10 +398,85 @@ func (dt *table) Delete(key []byte) error { log.DebugLog()
 	return dt.db.Delete(append([]byte(dt.prefix), key...))
 }
 
+func (dt *table) NewIterator() Iterator { log.DebugLog()
+	return dt.NewIteratorWithPrefix(nil)
+}
+
+func (dt *table) NewIteratorWithPrefix(prefix []byte) Iterator { log.DebugLog()
+	return &tableIterator{
+		it:     dt.db.NewIteratorWithPrefix(append([]byte(dt.prefix), prefix...)),
+		prefix: len(dt.prefix),
+	}
+}
+
+func (dt *table) NewIteratorWithRange(start []byte, limit []byte) Iterator { log.DebugLog()
+	start, limit = dt.prefixRange(start, limit)
+	return &tableIterator{
+		it:     dt.db.NewIteratorWithRange(start, limit),
+		prefix: len(dt.prefix),
+	}
+}
+
+func (dt *table) DeleteRange(start []byte, limit []byte) error { log.DebugLog()
+	start, limit = dt.prefixRange(start, limit)
+	return dt.db.DeleteRange(start, limit)
+}
+
+func (dt *table) Stat(property string) (string, error) { log.DebugLog()
+	return dt.db.Stat(property)
+}
+
+func (dt *table) Compact(start []byte, limit []byte) error { log.DebugLog()
+	start, limit = dt.prefixRange(start, limit)
+	return dt.db.Compact(start, limit)
+}
+
+// prefixRange converts a key range within the table into a key range of the
+// underlying database, nil bounds being limited to the table's own keyspace.
+func (dt *table) prefixRange(start []byte, limit []byte) ([]byte, []byte) { log.DebugLog()
+	start = append([]byte(dt.prefix), start...)
+	if limit == nil {
+		limit = util.BytesPrefix([]byte(dt.prefix)).Limit
+	} else {
+		limit = append([]byte(dt.prefix), limit...)
+	}
+	return start, limit
+}
+
 func (dt *table) Close() { log.DebugLog()
 	// Do nothing; don't close the underlying DB.
 }
 
+// tableIterator wraps an iterator of the underlying database, stripping the
+// table prefix from the returned keys.
+type tableIterator struct {
+	it     Iterator
+	prefix int
+}
+
+func (it *tableIterator) Next() bool { log.DebugLog()
+	return it.it.Next()
+}
+
+func (it *tableIterator) Error() error { log.DebugLog()
+	return it.it.Error()
+}
+
+func (it *tableIterator) Key() []byte { log.DebugLog()
+	if key := it.it.Key(); key != nil {
+		return key[it.prefix:]
+	}
+	return nil
+}
+
+func (it *tableIterator) Value() []byte { log.DebugLog()
+	return it.it.Value()
+}
+
+func (it *tableIterator) Release() { log.DebugLog()
+	it.it.Release()
+}
+
 type tableBatch struct {
 	batch  Batch
 	prefix string
@@ -376,6 +495,10 @@ func (tb *tableBatch) Put(key, value []byte) error { log.DebugLog()
 	return tb.batch.Put(append([]byte(tb.prefix), key...), value)
 }
 
+func (tb *tableBatch) Delete(key []byte) error { log.DebugLog()
+	return tb.batch.Delete(append([]byte(tb.prefix), key...))
+}
+
 func (tb *tableBatch) Write() error { log.DebugLog()
 	return tb.batch.Write()
 }
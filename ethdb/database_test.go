@@ -21,11 +21,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func newTestLDB() (*ethdb.LDBDatabase, func()) { log.DebugLog()
@@ -44,6 +47,118 @@ func newTestLDB() (*ethdb.LDBDatabase, func()) { log.DebugLog()
 	}
 }
 
+// Tests that all the registered database backends pass the conformance suite.
+func TestBackends(t *testing.T) { log.DebugLog()
+	for _, backend := range ethdb.Backends() {
+		backend := backend
+		t.Run(backend, func(t *testing.T) {
+			var dirs []string
+			defer func() {
+				for _, dir := range dirs {
+					os.RemoveAll(dir)
+				}
+			}()
+			dbtest.TestDatabaseSuite(t, func() ethdb.Database {
+				dir, err := ioutil.TempDir(os.TempDir(), "ethdb_test_")
+				if err != nil {
+					t.Fatalf("failed to create temporary directory: %v", err)
+				}
+				dirs = append(dirs, dir)
+
+				db, err := ethdb.Open(backend, dir, 0, 0)
+				if err != nil {
+					t.Fatalf("failed to open %s database: %v", backend, err)
+				}
+				return db
+			})
+		})
+	}
+}
+
+// Tests that the file database recovers its content after reopening, dropping
+// any partially written trailing records.
+func TestFileDB_Reopen(t *testing.T) { log.DebugLog()
+	dir, err := ioutil.TempDir(os.TempDir(), "ethdb_test_")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := ethdb.NewFileDatabase(dir)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.Put([]byte("a"), []byte("1"))
+	db.Put([]byte("b"), []byte("2"))
+	db.Delete([]byte("a"))
+	db.Close()
+
+	// Append a torn record to the end of the data file
+	f, err := os.OpenFile(filepath.Join(dir, "data.log"), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("failed to open data file: %v", err)
+	}
+	f.Write([]byte{0x01, 0x02, 0x03})
+	f.Close()
+
+	if db, err = ethdb.NewFileDatabase(dir); err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	if has, _ := db.Has([]byte("a")); has {
+		t.Errorf("deleted key resurrected")
+	}
+	if val, err := db.Get([]byte("b")); err != nil || string(val) != "2" {
+		t.Errorf("value mismatch: have %q, err %v", val, err)
+	}
+	if err := db.Put([]byte("c"), []byte("3")); err != nil {
+		t.Fatalf("failed to write after recovery: %v", err)
+	}
+}
+
+// Tests that iterators created before a compaction fail explicitly if their keys
+// were deleted meanwhile, instead of returning missing values.
+func TestFileDB_CompactInvalidation(t *testing.T) { log.DebugLog()
+	dir, err := ioutil.TempDir(os.TempDir(), "ethdb_test_")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := ethdb.NewFileDatabase(dir)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	db.Put([]byte("a"), []byte("1"))
+	db.Put([]byte("b"), []byte("2"))
+
+	it := db.NewIterator()
+	defer it.Release()
+
+	db.Delete([]byte("b"))
+	if err := db.Compact(nil, nil); err != nil {
+		t.Fatalf("compaction failed: %v", err)
+	}
+	if !it.Next() || string(it.Value()) != "1" {
+		t.Fatalf("live key not iterated after compaction")
+	}
+	if !it.Next() {
+		t.Fatalf("snapshotted key not iterated after compaction")
+	}
+	if value := it.Value(); value != nil {
+		t.Errorf("deleted key returned value %q", value)
+	}
+	if it.Error() == nil {
+		t.Errorf("no error for key deleted after compaction")
+	}
+	if it.Next() {
+		t.Errorf("iteration continued after invalidation")
+	}
+}
+
 var test_values = []string{"", "a", "1251", "\x00123\x00"}
 
 func TestLDB_PutGet(t *testing.T) { log.DebugLog()
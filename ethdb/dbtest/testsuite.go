@@ -0,0 +1,284 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package dbtest contains a conformance test suite that every ethdb.Database
+// backend must pass.
+package dbtest
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// TestDatabaseSuite runs a suite of tests against a database backend. The New
+// function must return a fresh, empty database on every invocation.
+func TestDatabaseSuite(t *testing.T, New func() ethdb.Database) {
+	t.Run("PutGet", func(t *testing.T) { testPutGet(t, New) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, New) })
+	t.Run("Iterator", func(t *testing.T) { testIterator(t, New) })
+	t.Run("IteratorWithPrefix", func(t *testing.T) { testIteratorWithPrefix(t, New) })
+	t.Run("IteratorWithRange", func(t *testing.T) { testIteratorWithRange(t, New) })
+	t.Run("DeleteRange", func(t *testing.T) { testDeleteRange(t, New) })
+	t.Run("Batch", func(t *testing.T) { testBatch(t, New) })
+	t.Run("Compact", func(t *testing.T) { testCompact(t, New) })
+	t.Run("Table", func(t *testing.T) { testTable(t, New) })
+}
+
+func testPutGet(t *testing.T, New func() ethdb.Database) {
+	db := New()
+	defer db.Close()
+
+	for _, k := range []string{"", "a", "1251", "\x00123\x00"} {
+		if err := db.Put([]byte(k), []byte("v"+k)); err != nil {
+			t.Fatalf("put %q failed: %v", k, err)
+		}
+		if has, err := db.Has([]byte(k)); err != nil || !has {
+			t.Fatalf("has %q mismatch: have %v, err %v", k, has, err)
+		}
+		if data, err := db.Get([]byte(k)); err != nil || !bytes.Equal(data, []byte("v"+k)) {
+			t.Fatalf("get %q mismatch: have %q, err %v", k, data, err)
+		}
+	}
+	if has, err := db.Has([]byte("missing")); err != nil || has {
+		t.Fatalf("has missing mismatch: have %v, err %v", has, err)
+	}
+	if _, err := db.Get([]byte("missing")); err == nil {
+		t.Fatalf("get missing succeeded")
+	}
+}
+
+func testDelete(t *testing.T, New func() ethdb.Database) {
+	db := New()
+	defer db.Close()
+
+	db.Put([]byte("a"), []byte("1"))
+	db.Put([]byte("b"), []byte("2"))
+	if err := db.Delete([]byte("a")); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if has, _ := db.Has([]byte("a")); has {
+		t.Fatalf("deleted key still present")
+	}
+	if has, _ := db.Has([]byte("b")); !has {
+		t.Fatalf("unrelated key removed")
+	}
+	if err := db.Delete([]byte("missing")); err != nil {
+		t.Fatalf("deleting missing key failed: %v", err)
+	}
+}
+
+// fill inserts a fixed set of keys into a database, returning them sorted.
+func fill(t *testing.T, db ethdb.Database) []string {
+	keys := []string{"1", "2", "3", "10", "11", "12", "20", "21", "22", "\xff", "\xff\xff"}
+	for _, k := range keys {
+		if err := db.Put([]byte(k), []byte("v"+k)); err != nil {
+			t.Fatalf("put %q failed: %v", k, err)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// collect drains an iterator, checking that each value belongs to its key.
+func collect(t *testing.T, it ethdb.Iterator) []string {
+	defer it.Release()
+
+	var keys []string
+	for it.Next() {
+		if want := append([]byte("v"), it.Key()...); !bytes.Equal(it.Value(), want) {
+			t.Fatalf("value mismatch for %q: have %q, want %q", it.Key(), it.Value(), want)
+		}
+		keys = append(keys, string(it.Key()))
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	return keys
+}
+
+func testIterator(t *testing.T, New func() ethdb.Database) {
+	db := New()
+	defer db.Close()
+
+	if keys := collect(t, db.NewIterator()); len(keys) != 0 {
+		t.Fatalf("empty database iterated: %q", keys)
+	}
+	want := fill(t, db)
+	if keys := collect(t, db.NewIterator()); !reflect.DeepEqual(keys, want) {
+		t.Fatalf("iteration mismatch: have %q, want %q", keys, want)
+	}
+}
+
+func testIteratorWithPrefix(t *testing.T, New func() ethdb.Database) {
+	db := New()
+	defer db.Close()
+	all := fill(t, db)
+
+	for _, prefix := range []string{"", "1", "2", "3", "4", "\xff"} {
+		var want []string
+		for _, k := range all {
+			if len(k) >= len(prefix) && k[:len(prefix)] == prefix {
+				want = append(want, k)
+			}
+		}
+		if keys := collect(t, db.NewIteratorWithPrefix([]byte(prefix))); !reflect.DeepEqual(keys, want) {
+			t.Errorf("prefix %q: iteration mismatch: have %q, want %q", prefix, keys, want)
+		}
+	}
+}
+
+func testIteratorWithRange(t *testing.T, New func() ethdb.Database) {
+	db := New()
+	defer db.Close()
+	all := fill(t, db)
+
+	tests := []struct{ start, limit []byte }{
+		{nil, nil},
+		{[]byte("1"), nil},
+		{nil, []byte("2")},
+		{[]byte("11"), []byte("21")},
+		{[]byte("15"), []byte("16")},
+		{[]byte("3"), []byte("\xff\xff")},
+		{[]byte("4"), []byte("3")},
+	}
+	for _, tt := range tests {
+		var want []string
+		for _, k := range all {
+			if k >= string(tt.start) && (tt.limit == nil || k < string(tt.limit)) {
+				want = append(want, k)
+			}
+		}
+		if keys := collect(t, db.NewIteratorWithRange(tt.start, tt.limit)); !reflect.DeepEqual(keys, want) {
+			t.Errorf("range [%q, %q): iteration mismatch: have %q, want %q", tt.start, tt.limit, keys, want)
+		}
+	}
+}
+
+func testDeleteRange(t *testing.T, New func() ethdb.Database) {
+	db := New()
+	defer db.Close()
+	fill(t, db)
+
+	if err := db.DeleteRange([]byte("11"), []byte("21")); err != nil {
+		t.Fatalf("range delete failed: %v", err)
+	}
+	want := []string{"1", "10", "21", "22", "3", "\xff", "\xff\xff"}
+	if keys := collect(t, db.NewIterator()); !reflect.DeepEqual(keys, want) {
+		t.Fatalf("content mismatch: have %q, want %q", keys, want)
+	}
+	if err := db.DeleteRange([]byte("3"), nil); err != nil {
+		t.Fatalf("open range delete failed: %v", err)
+	}
+	want = []string{"1", "10", "21", "22"}
+	if keys := collect(t, db.NewIterator()); !reflect.DeepEqual(keys, want) {
+		t.Fatalf("content mismatch: have %q, want %q", keys, want)
+	}
+	if err := db.DeleteRange(nil, nil); err != nil {
+		t.Fatalf("full range delete failed: %v", err)
+	}
+	if keys := collect(t, db.NewIterator()); len(keys) != 0 {
+		t.Fatalf("content remained: %q", keys)
+	}
+}
+
+func testBatch(t *testing.T, New func() ethdb.Database) {
+	db := New()
+	defer db.Close()
+
+	db.Put([]byte("deleted"), []byte("vdeleted"))
+
+	batch := db.NewBatch()
+	for i := 0; i < 100; i++ {
+		k := fmt.Sprintf("%03d", i)
+		if err := batch.Put([]byte(k), []byte("v"+k)); err != nil {
+			t.Fatalf("batch put failed: %v", err)
+		}
+	}
+	if err := batch.Delete([]byte("deleted")); err != nil {
+		t.Fatalf("batch delete failed: %v", err)
+	}
+	if batch.ValueSize() == 0 {
+		t.Fatalf("batch value size not tracked")
+	}
+	if has, _ := db.Has([]byte("000")); has {
+		t.Fatalf("batch written before flush")
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("batch write failed: %v", err)
+	}
+	if keys := collect(t, db.NewIterator()); len(keys) != 100 {
+		t.Fatalf("batch content mismatch: have %d keys, want %d", len(keys), 100)
+	}
+	batch.Reset()
+	if batch.ValueSize() != 0 {
+		t.Fatalf("batch value size not reset")
+	}
+}
+
+func testCompact(t *testing.T, New func() ethdb.Database) {
+	db := New()
+	defer db.Close()
+	want := fill(t, db)
+
+	// Overwrite and delete a few keys to give compaction something to do
+	db.Put([]byte("garbage"), []byte("vgarbage"))
+	db.Delete([]byte("garbage"))
+	for _, k := range want {
+		db.Put([]byte(k), []byte("v"+k))
+	}
+	it := db.NewIterator()
+	if err := db.Compact(nil, nil); err != nil {
+		t.Fatalf("compaction failed: %v", err)
+	}
+	// Iterators created before compaction must remain usable
+	if keys := collect(t, it); !reflect.DeepEqual(keys, want) {
+		t.Fatalf("pre-compaction iterator mismatch: have %q, want %q", keys, want)
+	}
+	if keys := collect(t, db.NewIterator()); !reflect.DeepEqual(keys, want) {
+		t.Fatalf("content mismatch: have %q, want %q", keys, want)
+	}
+}
+
+func testTable(t *testing.T, New func() ethdb.Database) {
+	db := New()
+	defer db.Close()
+
+	db.Put([]byte("a"), []byte("va"))
+	db.Put([]byte("tz"), []byte("vz"))
+	db.Put([]byte("u"), []byte("vu"))
+
+	table := ethdb.NewTable(db, "t")
+	table.Put([]byte("1"), []byte("v1"))
+	table.Put([]byte("2"), []byte("v2"))
+
+	if keys := collect(t, table.NewIterator()); !reflect.DeepEqual(keys, []string{"1", "2", "z"}) {
+		t.Fatalf("table iteration mismatch: have %q", keys)
+	}
+	if keys := collect(t, table.NewIteratorWithRange([]byte("2"), nil)); !reflect.DeepEqual(keys, []string{"2", "z"}) {
+		t.Fatalf("table range iteration mismatch: have %q", keys)
+	}
+	if err := table.DeleteRange(nil, nil); err != nil {
+		t.Fatalf("table range delete failed: %v", err)
+	}
+	if keys := collect(t, db.NewIterator()); !reflect.DeepEqual(keys, []string{"a", "u"}) {
+		t.Fatalf("table range delete leaked: have %q", keys)
+	}
+}
@@ -0,0 +1,544 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	fileDataName   = "data.log" // Name of the append-only data file within the database folder
+	fileRecordSize = 13         // Size of a record header: crc32 + kind + key length + value length
+
+	fileKindPut    = byte(0) // Record kind inserting or updating a key
+	fileKindDelete = byte(1) // Record kind deleting a key
+)
+
+var (
+	// errNotFound is returned if a requested key is not present in the database.
+	errNotFound = errors.New("not found")
+
+	// errClosed is returned if an operation is attempted on a closed database.
+	errClosed = errors.New("database closed")
+
+	// errIteratorInvalidated is returned by iterators whose snapshotted key was
+	// deleted after a compaction rewrote the data file.
+	errIteratorInvalidated = errors.New("iterator invalidated by compaction")
+)
+
+// fileEntry is the location of a live value within the data file.
+type fileEntry struct {
+	offset int64  // Offset of the value in the data file
+	size   uint32 // Length of the value
+	record uint32 // Length of the entire record, used for garbage accounting
+}
+
+// FileDatabase is a pure Go, append-only key/value store. All modifications are
+// appended as checksummed records to a single data file, while an index of all
+// live keys is kept in memory (similar to Bitcask). Overwritten and deleted
+// records are reclaimed by Compact, which rewrites the data file.
+//
+// It trades memory (all keys must fit in RAM) for simplicity and cheap writes,
+// and is meant as an alternative to LevelDB for small to medium sized databases.
+// The index keeps the keys sorted, so ranges are located in logarithmic time.
+type FileDatabase struct {
+	path    string       // Folder containing the database files
+	file    *os.File     // Append-only data file
+	size    int64        // Current size of the data file
+	garbage int64        // Bytes in the data file occupied by dead records
+	index   *fileIndex   // In-memory index of all live keys, sorted
+	epoch   uint64       // Counter bumped whenever the data file is rewritten
+	lock    sync.RWMutex // Mutex protecting the data file and index
+
+	log log.Logger // Contextual logger tracking the database path
+}
+
+// NewFileDatabase opens (or creates) an append-only file database within the
+// given folder. Any partially written trailing records (e.g. due to a crash)
+// are discarded.
+func NewFileDatabase(path string) (*FileDatabase, error) {
+	log.DebugLog()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	db := &FileDatabase{
+		path: path,
+		log:  log.New("database", path),
+	}
+	if err := db.open(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// open opens the data file and rebuilds the in-memory index from it.
+func (db *FileDatabase) open() error {
+	log.DebugLog()
+	file, err := os.OpenFile(filepath.Join(db.path, fileDataName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	db.file, db.size, db.garbage = file, 0, 0
+	db.index = newFileIndex()
+
+	var header [fileRecordSize]byte
+	for {
+		// Read the next record header, stopping at the end of the file
+		if _, err := file.ReadAt(header[:], db.size); err != nil {
+			if err != io.EOF {
+				return err
+			}
+			break
+		}
+		kind := header[4]
+		klen, vlen := binary.BigEndian.Uint32(header[5:9]), binary.BigEndian.Uint32(header[9:13])
+		if db.size+fileRecordSize+int64(klen)+int64(vlen) > stat.Size() {
+			break
+		}
+		body := make([]byte, klen+vlen)
+		if _, err := file.ReadAt(body, db.size+fileRecordSize); err != nil {
+			if err != io.EOF {
+				return err
+			}
+			break
+		}
+		if crc32.ChecksumIEEE(append(header[4:], body...)) != binary.BigEndian.Uint32(header[:4]) {
+			break
+		}
+		db.apply(kind, body[:klen], db.size+fileRecordSize+int64(klen), vlen)
+		db.size += fileRecordSize + int64(klen) + int64(vlen)
+	}
+	// Drop any trailing garbage left by an interrupted write
+	if stat.Size() > db.size {
+		db.log.Warn("Truncating corrupted data file tail", "size", stat.Size(), "valid", db.size)
+		if err := file.Truncate(db.size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply updates the in-memory index with a record located in the data file.
+func (db *FileDatabase) apply(kind byte, key []byte, offset int64, size uint32) {
+	log.DebugLog()
+	var (
+		record = uint32(fileRecordSize + len(key) + int(size))
+		old    fileEntry
+		ok     bool
+	)
+	switch kind {
+	case fileKindPut:
+		old, ok = db.index.put(string(key), fileEntry{offset: offset, size: size, record: record})
+	case fileKindDelete:
+		old, ok = db.index.delete(string(key))
+		db.garbage += int64(record)
+	}
+	if ok {
+		db.garbage += int64(old.record)
+	}
+}
+
+// encodeFileRecord appends a checksummed data file record to buf.
+func encodeFileRecord(buf []byte, kind byte, key []byte, value []byte) []byte {
+	start := len(buf)
+	buf = append(buf, make([]byte, fileRecordSize)...)
+	buf[start+4] = kind
+	binary.BigEndian.PutUint32(buf[start+5:], uint32(len(key)))
+	binary.BigEndian.PutUint32(buf[start+9:], uint32(len(value)))
+	buf = append(buf, key...)
+	buf = append(buf, value...)
+	binary.BigEndian.PutUint32(buf[start:], crc32.ChecksumIEEE(buf[start+4:]))
+	return buf
+}
+
+// write appends a set of encoded records to the data file and indexes them.
+func (db *FileDatabase) write(records []byte) error {
+	log.DebugLog()
+	if db.file == nil {
+		return errClosed
+	}
+	if _, err := db.file.WriteAt(records, db.size); err != nil {
+		return err
+	}
+	for len(records) > 0 {
+		kind := records[4]
+		klen, vlen := binary.BigEndian.Uint32(records[5:9]), binary.BigEndian.Uint32(records[9:13])
+
+		db.apply(kind, common.CopyBytes(records[fileRecordSize:fileRecordSize+klen]), db.size+fileRecordSize+int64(klen), vlen)
+		db.size += fileRecordSize + int64(klen) + int64(vlen)
+		records = records[fileRecordSize+klen+vlen:]
+	}
+	return nil
+}
+
+// Path returns the path to the database directory.
+func (db *FileDatabase) Path() string {
+	log.DebugLog()
+	return db.path
+}
+
+// Put inserts the given value into the database.
+func (db *FileDatabase) Put(key []byte, value []byte) error {
+	log.DebugLog()
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.write(encodeFileRecord(nil, fileKindPut, key, value))
+}
+
+// Has retrieves whether a key is present in the database.
+func (db *FileDatabase) Has(key []byte) (bool, error) {
+	log.DebugLog()
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.file == nil {
+		return false, errClosed
+	}
+	_, ok := db.index.get(string(key))
+	return ok, nil
+}
+
+// Get retrieves the given key if it's present in the database.
+func (db *FileDatabase) Get(key []byte) ([]byte, error) {
+	log.DebugLog()
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.get(key)
+}
+
+// get retrieves the given key from the data file. The caller must hold at
+// least a read lock.
+func (db *FileDatabase) get(key []byte) ([]byte, error) {
+	log.DebugLog()
+	if db.file == nil {
+		return nil, errClosed
+	}
+	entry, ok := db.index.get(string(key))
+	if !ok {
+		return nil, errNotFound
+	}
+	return db.read(entry)
+}
+
+// read loads a value from the data file. The caller must hold at least a read
+// lock.
+func (db *FileDatabase) read(entry fileEntry) ([]byte, error) {
+	log.DebugLog()
+	value := make([]byte, entry.size)
+	if _, err := db.file.ReadAt(value, entry.offset); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Delete removes the key from the database.
+func (db *FileDatabase) Delete(key []byte) error {
+	log.DebugLog()
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if _, ok := db.index.get(string(key)); !ok {
+		return nil
+	}
+	return db.write(encodeFileRecord(nil, fileKindDelete, key, nil))
+}
+
+// DeleteRange removes all keys in the range [start, limit) from the database.
+func (db *FileDatabase) DeleteRange(start []byte, limit []byte) error {
+	log.DebugLog()
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var records []byte
+	for node := db.index.seek(string(start)); node != nil && inRange([]byte(node.key), start, limit); node = node.next[0] {
+		records = encodeFileRecord(records, fileKindDelete, []byte(node.key), nil)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return db.write(records)
+}
+
+// NewIterator returns an iterator over the entire database content.
+func (db *FileDatabase) NewIterator() Iterator {
+	log.DebugLog()
+	return db.NewIteratorWithRange(nil, nil)
+}
+
+// NewIteratorWithPrefix returns an iterator over the subset of database content
+// with a particular key prefix.
+func (db *FileDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
+	log.DebugLog()
+	return db.NewIteratorWithRange(prefixRange(prefix))
+}
+
+// NewIteratorWithRange returns an iterator over the subset of database content
+// with keys in the range [start, limit). The set of keys is snapshotted when
+// the iterator is created, values are loaded lazily from disk. If the database
+// is compacted meanwhile, the current values of the keys are returned instead,
+// and iteration fails if a key was deleted since.
+func (db *FileDatabase) NewIteratorWithRange(start []byte, limit []byte) Iterator {
+	log.DebugLog()
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	it := &fileIterator{db: db, epoch: db.epoch, index: -1}
+	if db.file == nil {
+		it.err = errClosed
+		return it
+	}
+	for node := db.index.seek(string(start)); node != nil && inRange([]byte(node.key), start, limit); node = node.next[0] {
+		it.keys = append(it.keys, node.key)
+		it.entries = append(it.entries, node.entry)
+	}
+	return it
+}
+
+// Stat returns a particular internal stat of the database. Supported properties
+// are "filedb.entries", "filedb.size" and "filedb.garbage".
+func (db *FileDatabase) Stat(property string) (string, error) {
+	log.DebugLog()
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	switch property {
+	case "filedb.entries":
+		return fmt.Sprintf("%d", db.index.len()), nil
+	case "filedb.size":
+		return fmt.Sprintf("%d", db.size), nil
+	case "filedb.garbage":
+		return fmt.Sprintf("%d", db.garbage), nil
+	}
+	return "", errors.New("unknown property")
+}
+
+// Compact reclaims the space occupied by overwritten and deleted records. As
+// the data file has no key locality, the entire file is always rewritten,
+// regardless of the requested range.
+func (db *FileDatabase) Compact(start []byte, limit []byte) error {
+	log.DebugLog()
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.file == nil {
+		return errClosed
+	}
+	if db.garbage == 0 {
+		return nil
+	}
+	// Write all live records into a fresh data file in key order
+	path := filepath.Join(db.path, fileDataName)
+	temp, err := os.OpenFile(path+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	var (
+		records []byte
+		offset  int64
+	)
+	for node := db.index.seek(""); node != nil; node = node.next[0] {
+		value, err := db.read(node.entry)
+		if err != nil {
+			temp.Close()
+			return err
+		}
+		records = encodeFileRecord(records, fileKindPut, []byte(node.key), value)
+		if len(records) >= IdealBatchSize {
+			if _, err := temp.WriteAt(records, offset); err != nil {
+				temp.Close()
+				return err
+			}
+			offset, records = offset+int64(len(records)), records[:0]
+		}
+	}
+	if _, err := temp.WriteAt(records, offset); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	temp.Close()
+
+	// Swap the data files and rebuild the index. If the swap fails, keep
+	// serving from the original data file.
+	db.file.Close()
+	if err := os.Rename(path+".tmp", path); err != nil {
+		os.Remove(path + ".tmp")
+		if err := db.open(); err != nil {
+			db.log.Error("Failed to reopen data file", "err", err)
+			db.file = nil
+		}
+		return err
+	}
+	db.epoch++
+	if err := db.open(); err != nil {
+		db.file = nil
+		return err
+	}
+	return nil
+}
+
+// Close flushes and closes the data file.
+func (db *FileDatabase) Close() {
+	log.DebugLog()
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.file == nil {
+		return
+	}
+	if err := db.file.Sync(); err != nil {
+		db.log.Error("Failed to flush data file", "err", err)
+	}
+	if err := db.file.Close(); err != nil {
+		db.log.Error("Failed to close data file", "err", err)
+	} else {
+		db.log.Info("Database closed")
+	}
+	db.file = nil
+}
+
+// NewBatch creates a write-only batch which is appended to the data file as a
+// single write when flushed.
+func (db *FileDatabase) NewBatch() Batch {
+	log.DebugLog()
+	return &fileBatch{db: db}
+}
+
+type fileBatch struct {
+	db      *FileDatabase
+	records []byte
+	size    int
+}
+
+func (b *fileBatch) Put(key, value []byte) error {
+	log.DebugLog()
+	b.records = encodeFileRecord(b.records, fileKindPut, key, value)
+	b.size += len(value)
+	return nil
+}
+
+func (b *fileBatch) Delete(key []byte) error {
+	log.DebugLog()
+	b.records = encodeFileRecord(b.records, fileKindDelete, key, nil)
+	b.size += 1
+	return nil
+}
+
+func (b *fileBatch) Write() error {
+	log.DebugLog()
+	if len(b.records) == 0 {
+		return nil
+	}
+	b.db.lock.Lock()
+	defer b.db.lock.Unlock()
+
+	return b.db.write(b.records)
+}
+
+func (b *fileBatch) ValueSize() int {
+	log.DebugLog()
+	return b.size
+}
+
+func (b *fileBatch) Reset() {
+	log.DebugLog()
+	b.records = b.records[:0]
+	b.size = 0
+}
+
+// fileIterator iterates over a snapshot of the keys of a file database.
+type fileIterator struct {
+	db      *FileDatabase
+	epoch   uint64      // Data file epoch the entries were snapshotted in
+	keys    []string    // Sorted keys to iterate over
+	entries []fileEntry // Data file locations of the values
+	index   int         // Current position of the iterator
+	value   []byte      // Value of the current position, lazily loaded
+	err     error       // Any error encountered while loading values
+}
+
+func (it *fileIterator) Next() bool {
+	log.DebugLog()
+	if it.err != nil || it.index >= len(it.keys) {
+		return false
+	}
+	it.index++
+	it.value = nil
+	return it.index < len(it.keys)
+}
+
+func (it *fileIterator) Error() error {
+	log.DebugLog()
+	return it.err
+}
+
+func (it *fileIterator) Key() []byte {
+	log.DebugLog()
+	if it.err != nil || it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return []byte(it.keys[it.index])
+}
+
+func (it *fileIterator) Value() []byte {
+	log.DebugLog()
+	if it.err != nil || it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	if it.value == nil {
+		it.db.lock.RLock()
+		defer it.db.lock.RUnlock()
+
+		// If the data file was rewritten since, the snapshotted offsets are
+		// stale, fall back to looking up the current value.
+		if it.db.file == nil {
+			it.err = errClosed
+			return nil
+		}
+		if it.db.epoch == it.epoch {
+			it.value, it.err = it.db.read(it.entries[it.index])
+		} else if it.value, it.err = it.db.get([]byte(it.keys[it.index])); it.err == errNotFound {
+			it.err = errIteratorInvalidated
+		}
+	}
+	return it.value
+}
+
+func (it *fileIterator) Release() {
+	log.DebugLog()
+	it.keys, it.entries, it.value = nil, nil, nil
+}
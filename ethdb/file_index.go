@@ -0,0 +1,130 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"math/rand"
+	"time"
+)
+
+// fileIndexMaxLevel is the maximum height of the index skip list. With every
+// node promoted to the next level with a probability of 1/4, it comfortably
+// covers billions of keys.
+const fileIndexMaxLevel = 16
+
+// fileIndexNode is a key of the index along with the location of its value.
+type fileIndexNode struct {
+	key   string
+	entry fileEntry
+	next  []*fileIndexNode // Successors of the node on each of its levels
+}
+
+// fileIndex is the in-memory index of a file database: a skip list keeping the
+// live keys sorted, so that point and range lookups are logarithmic and ranges
+// can be walked without sorting the key set.
+type fileIndex struct {
+	head  fileIndexNode // Sentinel preceding the smallest key on all levels
+	level int           // Number of levels currently in use
+	size  int           // Number of keys in the index
+	rand  *rand.Rand    // Source of the node heights
+}
+
+// newFileIndex creates an empty index.
+func newFileIndex() *fileIndex {
+	return &fileIndex{
+		head:  fileIndexNode{next: make([]*fileIndexNode, fileIndexMaxLevel)},
+		level: 1,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// find returns the first node with a key not smaller than the given one, or nil
+// if there is none. If prev is not nil, it is filled with the last node before
+// the key on every level.
+func (idx *fileIndex) find(key string, prev []*fileIndexNode) *fileIndexNode {
+	node := &idx.head
+	for level := idx.level - 1; level >= 0; level-- {
+		for next := node.next[level]; next != nil && next.key < key; next = node.next[level] {
+			node = next
+		}
+		if prev != nil {
+			prev[level] = node
+		}
+	}
+	return node.next[0]
+}
+
+// get retrieves the location of the value of a key.
+func (idx *fileIndex) get(key string) (fileEntry, bool) {
+	if node := idx.find(key, nil); node != nil && node.key == key {
+		return node.entry, true
+	}
+	return fileEntry{}, false
+}
+
+// put inserts or updates the location of the value of a key, returning the
+// previous location if the key was already present.
+func (idx *fileIndex) put(key string, entry fileEntry) (fileEntry, bool) {
+	var prev [fileIndexMaxLevel]*fileIndexNode
+	if node := idx.find(key, prev[:]); node != nil && node.key == key {
+		old := node.entry
+		node.entry = entry
+		return old, true
+	}
+	level := 1
+	for level < fileIndexMaxLevel && idx.rand.Int63()&3 == 0 {
+		level++
+	}
+	for ; idx.level < level; idx.level++ {
+		prev[idx.level] = &idx.head
+	}
+	node := &fileIndexNode{key: key, entry: entry, next: make([]*fileIndexNode, level)}
+	for i := 0; i < level; i++ {
+		node.next[i], prev[i].next[i] = prev[i].next[i], node
+	}
+	idx.size++
+	return fileEntry{}, false
+}
+
+// delete removes a key from the index, returning the location of its value if
+// it was present.
+func (idx *fileIndex) delete(key string) (fileEntry, bool) {
+	var prev [fileIndexMaxLevel]*fileIndexNode
+	node := idx.find(key, prev[:])
+	if node == nil || node.key != key {
+		return fileEntry{}, false
+	}
+	for i := range node.next {
+		prev[i].next[i] = node.next[i]
+	}
+	for idx.level > 1 && idx.head.next[idx.level-1] == nil {
+		idx.level--
+	}
+	idx.size--
+	return node.entry, true
+}
+
+// seek returns the first node with a key not smaller than the given one, from
+// which the index can be walked in order, or nil if there is none.
+func (idx *fileIndex) seek(key string) *fileIndexNode {
+	return idx.find(key, nil)
+}
+
+// len returns the number of keys in the index.
+func (idx *fileIndex) len() int {
+	return idx.size
+}
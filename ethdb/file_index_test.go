@@ -0,0 +1,77 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that the file database index behaves like a sorted map under random
+// insertions, updates and deletions.
+func TestFileIndex(t *testing.T) { log.DebugLog()
+	var (
+		idx  = newFileIndex()
+		want = make(map[string]fileEntry)
+	)
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("%04d", rand.Intn(2000))
+		if rand.Intn(3) == 0 {
+			old, ok := idx.delete(key)
+			if exp, exists := want[key]; ok != exists || old != exp {
+				t.Fatalf("op %d: delete %s mismatch: have %v/%v, want %v/%v", i, key, old, ok, exp, exists)
+			}
+			delete(want, key)
+			continue
+		}
+		entry := fileEntry{offset: int64(i)}
+		old, ok := idx.put(key, entry)
+		if exp, exists := want[key]; ok != exists || old != exp {
+			t.Fatalf("op %d: put %s mismatch: have %v/%v, want %v/%v", i, key, old, ok, exp, exists)
+		}
+		want[key] = entry
+	}
+	if idx.len() != len(want) {
+		t.Fatalf("size mismatch: have %d, want %d", idx.len(), len(want))
+	}
+	keys := make([]string, 0, len(want))
+	for key := range want {
+		keys = append(keys, key)
+		if entry, ok := idx.get(key); !ok || entry != want[key] {
+			t.Errorf("get %s mismatch: have %v/%v, want %v", key, entry, ok, want[key])
+		}
+	}
+	sort.Strings(keys)
+
+	// Walking from any position must yield the remaining keys in order
+	for _, start := range []string{"", "0500", "0500x", "1999", "2000"} {
+		pos := sort.SearchStrings(keys, start)
+		for node := idx.seek(start); node != nil; node = node.next[0] {
+			if pos >= len(keys) || node.key != keys[pos] {
+				t.Fatalf("seek %q: key mismatch at %d: have %s", start, pos, node.key)
+			}
+			pos++
+		}
+		if pos != len(keys) {
+			t.Errorf("seek %q: walked %d keys, want %d", start, pos, len(keys))
+		}
+	}
+}
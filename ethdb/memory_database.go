@@ -17,7 +17,10 @@
 package ethdb
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -96,6 +99,82 @@ func (db *MemDatabase) Delete(key []byte) error {
 	return nil
 }
 
+// DeleteRange removes all keys in the range [start, limit) from the database.
+func (db *MemDatabase) DeleteRange(start []byte, limit []byte) error {
+	log.DebugLog()
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	for key := range db.db {
+		if inRange([]byte(key), start, limit) {
+			delete(db.db, key)
+		}
+	}
+	return nil
+}
+
+// NewIterator returns an iterator over the entire database content.
+func (db *MemDatabase) NewIterator() Iterator {
+	log.DebugLog()
+	return db.NewIteratorWithRange(nil, nil)
+}
+
+// NewIteratorWithPrefix returns an iterator over the subset of database content
+// with a particular key prefix.
+func (db *MemDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
+	log.DebugLog()
+	return db.NewIteratorWithRange(prefixRange(prefix))
+}
+
+// NewIteratorWithRange returns an iterator over the subset of database content
+// with keys in the range [start, limit). The iterator operates on a snapshot of
+// the database taken at creation time.
+func (db *MemDatabase) NewIteratorWithRange(start []byte, limit []byte) Iterator {
+	log.DebugLog()
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	var keys []string
+	for key := range db.db {
+		if inRange([]byte(key), start, limit) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = db.db[key]
+	}
+	return &memIterator{keys: keys, values: values, index: -1}
+}
+
+// Stat returns a particular internal stat of the database. The in-memory
+// database only supports the "memdb.entries" and "memdb.size" properties.
+func (db *MemDatabase) Stat(property string) (string, error) {
+	log.DebugLog()
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	switch property {
+	case "memdb.entries":
+		return fmt.Sprintf("%d", len(db.db)), nil
+	case "memdb.size":
+		size := 0
+		for key, value := range db.db {
+			size += len(key) + len(value)
+		}
+		return fmt.Sprintf("%d", size), nil
+	}
+	return "", errors.New("unknown property")
+}
+
+// Compact is a noop for the in-memory database, there's nothing to flatten.
+func (db *MemDatabase) Compact(start []byte, limit []byte) error {
+	log.DebugLog()
+	return nil
+}
+
 func (db *MemDatabase) Close() { log.DebugLog() }
 
 func (db *MemDatabase) NewBatch() Batch {
@@ -106,7 +185,10 @@ func (db *MemDatabase) NewBatch() Batch {
 func (db *MemDatabase) Len() int { log.DebugLog()
 									 return len(db.db) }
 
-type kv struct{ k, v []byte }
+type kv struct {
+	k, v []byte
+	del  bool
+}
 
 type memBatch struct {
 	db     *MemDatabase
@@ -116,17 +198,28 @@ type memBatch struct {
 
 func (b *memBatch) Put(key, value []byte) error {
 	log.DebugLog()
-	b.writes = append(b.writes, kv{common.CopyBytes(key), common.CopyBytes(value)})
+	b.writes = append(b.writes, kv{common.CopyBytes(key), common.CopyBytes(value), false})
 	b.size += len(value)
 	return nil
 }
 
+func (b *memBatch) Delete(key []byte) error {
+	log.DebugLog()
+	b.writes = append(b.writes, kv{common.CopyBytes(key), nil, true})
+	b.size += 1
+	return nil
+}
+
 func (b *memBatch) Write() error {
 	log.DebugLog()
 	b.db.lock.Lock()
 	defer b.db.lock.Unlock()
 
 	for _, kv := range b.writes {
+		if kv.del {
+			delete(b.db.db, string(kv.k))
+			continue
+		}
 		b.db.db[string(kv.k)] = kv.v
 	}
 	return nil
@@ -142,3 +235,66 @@ func (b *memBatch) Reset() {
 	b.writes = b.writes[:0]
 	b.size = 0
 }
+
+// memIterator is an iterator over a sorted snapshot of an in-memory key/value
+// store.
+type memIterator struct {
+	keys   []string
+	values [][]byte
+	index  int
+}
+
+func (it *memIterator) Next() bool {
+	log.DebugLog()
+	if it.index >= len(it.keys) {
+		return false
+	}
+	it.index++
+	return it.index < len(it.keys)
+}
+
+func (it *memIterator) Error() error {
+	log.DebugLog()
+	return nil
+}
+
+func (it *memIterator) Key() []byte {
+	log.DebugLog()
+	if it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return []byte(it.keys[it.index])
+}
+
+func (it *memIterator) Value() []byte {
+	log.DebugLog()
+	if it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return it.values[it.index]
+}
+
+func (it *memIterator) Release() {
+	log.DebugLog()
+	it.keys, it.values = nil, nil
+}
+
+// inRange reports whether key is within [start, limit), a nil limit meaning
+// the end of the keyspace.
+func inRange(key []byte, start []byte, limit []byte) bool {
+	return bytes.Compare(key, start) >= 0 && (limit == nil || bytes.Compare(key, limit) < 0)
+}
+
+// prefixRange returns the key range covering all keys with the given prefix.
+func prefixRange(prefix []byte) ([]byte, []byte) {
+	var limit []byte
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if c := prefix[i]; c < 0xff {
+			limit = make([]byte, i+1)
+			copy(limit, prefix)
+			limit[i] = c + 1
+			break
+		}
+	}
+	return prefix, limit
+}
@@ -0,0 +1,94 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// DefaultBackend is the database backend used if none is explicitly requested.
+const DefaultBackend = "leveldb"
+
+// Opener opens (or creates) a database of a particular backend at the given
+// path. The cache (in megabytes) and handles allowances are hints, backends are
+// free to ignore them.
+type Opener func(path string, cache int, handles int) (Database, error)
+
+var (
+	backends    = make(map[string]Opener)
+	backendLock sync.RWMutex
+)
+
+func init() {
+	Register("leveldb", func(path string, cache int, handles int) (Database, error) {
+		return NewLDBDatabase(path, cache, handles)
+	})
+	Register("memory", func(path string, cache int, handles int) (Database, error) {
+		return NewMemDatabase()
+	})
+	Register("filedb", func(path string, cache int, handles int) (Database, error) {
+		return NewFileDatabase(path)
+	})
+}
+
+// Register makes a database backend available by the provided name. If Register
+// is called twice with the same name, it panics.
+func Register(name string, opener Opener) {
+	log.DebugLog()
+	backendLock.Lock()
+	defer backendLock.Unlock()
+
+	if _, ok := backends[name]; ok {
+		panic(fmt.Sprintf("database backend %q already registered", name))
+	}
+	backends[name] = opener
+}
+
+// Backends returns the sorted names of all the registered database backends.
+func Backends() []string {
+	log.DebugLog()
+	backendLock.RLock()
+	defer backendLock.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open opens a database at the given path using the requested backend. If no
+// backend is specified, DefaultBackend is used.
+func Open(backend string, path string, cache int, handles int) (Database, error) {
+	log.DebugLog()
+	if backend == "" {
+		backend = DefaultBackend
+	}
+	backendLock.RLock()
+	opener, ok := backends[backend]
+	backendLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown database backend %q (available: %v)", backend, Backends())
+	}
+	return opener(path, cache, handles)
+}
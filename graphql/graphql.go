@@ -0,0 +1,846 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql provides a GraphQL interface to Ethereum node data.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errBlockNotFound     = errors.New("block not found")
+	errComplexityReached = errors.New("query exceeds the complexity limit")
+)
+
+// Long is a 64 bit unsigned integer scalar.
+type Long int64
+
+// ImplementsGraphQLType returns true if Long implements the provided GraphQL type.
+func (b Long) ImplementsGraphQLType(name string) bool {
+	log.DebugLog()
+	return name == "Long"
+}
+
+// UnmarshalGraphQL unmarshals the provided GraphQL query data.
+func (b *Long) UnmarshalGraphQL(input interface{}) error {
+	log.DebugLog()
+	switch input := input.(type) {
+	case string:
+		var (
+			value uint64
+			err   error
+		)
+		if strings.HasPrefix(input, "0x") {
+			value, err = hexutil.DecodeUint64(input)
+		} else {
+			value, err = strconv.ParseUint(input, 10, 64)
+		}
+		*b = Long(value)
+		return err
+	case int32:
+		*b = Long(input)
+	case int64:
+		*b = Long(input)
+	case float64:
+		*b = Long(input)
+	default:
+		return fmt.Errorf("unexpected type %T for Long", input)
+	}
+	if *b < 0 {
+		return fmt.Errorf("negative value %d for Long", *b)
+	}
+	return nil
+}
+
+// budgetKey is the context key of the complexity budget of a query.
+type budgetKey struct{}
+
+// withBudget returns a context allowing the resolution of at most limit objects.
+// A non-positive limit disables the complexity accounting.
+func withBudget(ctx context.Context, limit int) context.Context {
+	log.DebugLog()
+	if limit <= 0 {
+		return ctx
+	}
+	budget := int64(limit)
+	return context.WithValue(ctx, budgetKey{}, &budget)
+}
+
+// charge deducts the cost of resolving some objects from the complexity budget
+// of the query, failing if the budget is exhausted. Fields are resolved
+// concurrently, so the budget is updated atomically.
+func charge(ctx context.Context, cost int) error {
+	log.DebugLog()
+	budget, ok := ctx.Value(budgetKey{}).(*int64)
+	if !ok {
+		return nil
+	}
+	if atomic.AddInt64(budget, -int64(cost)) < 0 {
+		return errComplexityReached
+	}
+	return nil
+}
+
+// blockNumberOr returns the block number requested by an optional argument, or
+// the given default if the argument was omitted.
+func blockNumberOr(number *Long, def rpc.BlockNumber) rpc.BlockNumber {
+	log.DebugLog()
+	if number == nil {
+		return def
+	}
+	return rpc.BlockNumber(*number)
+}
+
+// Account represents an Ethereum account at a particular block.
+type Account struct {
+	backend     ethapi.Backend
+	address     common.Address
+	blockNumber rpc.BlockNumber
+}
+
+// newAccount creates an account resolver, charging it to the query's budget.
+func newAccount(ctx context.Context, backend ethapi.Backend, address common.Address, number rpc.BlockNumber) (*Account, error) {
+	log.DebugLog()
+	if err := charge(ctx, 1); err != nil {
+		return nil, err
+	}
+	return &Account{backend: backend, address: address, blockNumber: number}, nil
+}
+
+// getState fetches the state of the account's block.
+func (a *Account) getState(ctx context.Context) (*state.StateDB, error) {
+	log.DebugLog()
+	state, _, err := a.backend.StateAndHeaderByNumber(ctx, a.blockNumber)
+	if state == nil && err == nil {
+		return nil, errBlockNotFound
+	}
+	return state, err
+}
+
+func (a *Account) Address(ctx context.Context) (common.Address, error) {
+	log.DebugLog()
+	return a.address, nil
+}
+
+func (a *Account) Balance(ctx context.Context) (hexutil.Big, error) {
+	log.DebugLog()
+	state, err := a.getState(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*state.GetBalance(a.address)), nil
+}
+
+func (a *Account) TransactionCount(ctx context.Context) (Long, error) {
+	log.DebugLog()
+	state, err := a.getState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(state.GetNonce(a.address)), nil
+}
+
+func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
+	log.DebugLog()
+	state, err := a.getState(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(state.GetCode(a.address)), nil
+}
+
+func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash }) (common.Hash, error) {
+	log.DebugLog()
+	state, err := a.getState(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return state.GetState(a.address, args.Slot), nil
+}
+
+// Log represents an individual log message. All arguments are mandatory.
+type Log struct {
+	backend     ethapi.Backend
+	transaction *Transaction
+	log         *types.Log
+}
+
+func (l *Log) Transaction(ctx context.Context) *Transaction {
+	log.DebugLog()
+	return l.transaction
+}
+
+func (l *Log) Account(ctx context.Context, args struct{ Block *Long }) (*Account, error) {
+	log.DebugLog()
+	return newAccount(ctx, l.backend, l.log.Address, blockNumberOr(args.Block, rpc.LatestBlockNumber))
+}
+
+func (l *Log) Index(ctx context.Context) int32 {
+	log.DebugLog()
+	return int32(l.log.Index)
+}
+
+func (l *Log) Topics(ctx context.Context) []common.Hash {
+	log.DebugLog()
+	return l.log.Topics
+}
+
+func (l *Log) Data(ctx context.Context) hexutil.Bytes {
+	log.DebugLog()
+	return hexutil.Bytes(l.log.Data)
+}
+
+// Transaction represents an Ethereum transaction, either mined into a block or
+// still pending in the pool.
+type Transaction struct {
+	backend ethapi.Backend
+	tx      *types.Transaction
+	block   *types.Block // Block containing the transaction, nil if pending
+	index   uint64       // Index of the transaction in its block
+}
+
+// newTransaction creates a transaction resolver, charging it to the query's budget.
+func newTransaction(ctx context.Context, backend ethapi.Backend, tx *types.Transaction, block *types.Block, index uint64) (*Transaction, error) {
+	log.DebugLog()
+	if err := charge(ctx, 1); err != nil {
+		return nil, err
+	}
+	return &Transaction{backend: backend, tx: tx, block: block, index: index}, nil
+}
+
+func (t *Transaction) Hash(ctx context.Context) common.Hash {
+	log.DebugLog()
+	return t.tx.Hash()
+}
+
+func (t *Transaction) InputData(ctx context.Context) hexutil.Bytes {
+	log.DebugLog()
+	return hexutil.Bytes(t.tx.Data())
+}
+
+func (t *Transaction) Gas(ctx context.Context) Long {
+	log.DebugLog()
+	return Long(t.tx.Gas())
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) hexutil.Big {
+	log.DebugLog()
+	return hexutil.Big(*t.tx.GasPrice())
+}
+
+func (t *Transaction) Value(ctx context.Context) hexutil.Big {
+	log.DebugLog()
+	return hexutil.Big(*t.tx.Value())
+}
+
+func (t *Transaction) Nonce(ctx context.Context) Long {
+	log.DebugLog()
+	return Long(t.tx.Nonce())
+}
+
+func (t *Transaction) To(ctx context.Context, args struct{ Block *Long }) (*Account, error) {
+	log.DebugLog()
+	to := t.tx.To()
+	if to == nil {
+		return nil, nil
+	}
+	return newAccount(ctx, t.backend, *to, blockNumberOr(args.Block, rpc.LatestBlockNumber))
+}
+
+func (t *Transaction) From(ctx context.Context, args struct{ Block *Long }) (*Account, error) {
+	log.DebugLog()
+	number := t.backend.CurrentBlock().Number()
+	if t.block != nil {
+		number = t.block.Number()
+	}
+	from, err := types.Sender(types.MakeSigner(t.backend.ChainConfig(), number), t.tx)
+	if err != nil {
+		return nil, err
+	}
+	return newAccount(ctx, t.backend, from, blockNumberOr(args.Block, rpc.LatestBlockNumber))
+}
+
+func (t *Transaction) Block(ctx context.Context) (*Block, error) {
+	log.DebugLog()
+	if t.block == nil {
+		return nil, nil
+	}
+	return newBlock(ctx, t.backend, t.block)
+}
+
+func (t *Transaction) Index(ctx context.Context) *int32 {
+	log.DebugLog()
+	if t.block == nil {
+		return nil
+	}
+	index := int32(t.index)
+	return &index
+}
+
+// getReceipt retrieves the receipt of the transaction, or nil if it's pending.
+func (t *Transaction) getReceipt(ctx context.Context) (*types.Receipt, error) {
+	log.DebugLog()
+	if t.block == nil {
+		return nil, nil
+	}
+	receipts, err := t.backend.GetReceipts(ctx, t.block.Hash())
+	if err != nil || uint64(len(receipts)) <= t.index {
+		return nil, err
+	}
+	return receipts[t.index], nil
+}
+
+func (t *Transaction) Status(ctx context.Context) (*Long, error) {
+	log.DebugLog()
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	status := Long(receipt.Status)
+	return &status, nil
+}
+
+func (t *Transaction) GasUsed(ctx context.Context) (*Long, error) {
+	log.DebugLog()
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	used := Long(receipt.GasUsed)
+	return &used, nil
+}
+
+func (t *Transaction) CumulativeGasUsed(ctx context.Context) (*Long, error) {
+	log.DebugLog()
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	used := Long(receipt.CumulativeGasUsed)
+	return &used, nil
+}
+
+func (t *Transaction) CreatedContract(ctx context.Context, args struct{ Block *Long }) (*Account, error) {
+	log.DebugLog()
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil || receipt.ContractAddress == (common.Address{}) {
+		return nil, err
+	}
+	return newAccount(ctx, t.backend, receipt.ContractAddress, blockNumberOr(args.Block, rpc.LatestBlockNumber))
+}
+
+func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
+	log.DebugLog()
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	if err := charge(ctx, len(receipt.Logs)); err != nil {
+		return nil, err
+	}
+	logs := make([]*Log, len(receipt.Logs))
+	for i, log := range receipt.Logs {
+		logs[i] = &Log{backend: t.backend, transaction: t, log: log}
+	}
+	return &logs, nil
+}
+
+// Block represents an Ethereum block.
+type Block struct {
+	backend ethapi.Backend
+	block   *types.Block
+}
+
+// newBlock creates a block resolver, charging it to the query's budget.
+func newBlock(ctx context.Context, backend ethapi.Backend, block *types.Block) (*Block, error) {
+	log.DebugLog()
+	if err := charge(ctx, 1); err != nil {
+		return nil, err
+	}
+	return &Block{backend: backend, block: block}, nil
+}
+
+func (b *Block) Number(ctx context.Context) Long {
+	log.DebugLog()
+	return Long(b.block.NumberU64())
+}
+
+func (b *Block) Hash(ctx context.Context) common.Hash {
+	log.DebugLog()
+	return b.block.Hash()
+}
+
+func (b *Block) GasLimit(ctx context.Context) Long {
+	log.DebugLog()
+	return Long(b.block.GasLimit())
+}
+
+func (b *Block) GasUsed(ctx context.Context) Long {
+	log.DebugLog()
+	return Long(b.block.GasUsed())
+}
+
+func (b *Block) Parent(ctx context.Context) (*Block, error) {
+	log.DebugLog()
+	if b.block.NumberU64() == 0 {
+		return nil, nil
+	}
+	parent, err := b.backend.GetBlock(ctx, b.block.ParentHash())
+	if parent == nil || err != nil {
+		return nil, err
+	}
+	return newBlock(ctx, b.backend, parent)
+}
+
+func (b *Block) Difficulty(ctx context.Context) hexutil.Big {
+	log.DebugLog()
+	return hexutil.Big(*b.block.Difficulty())
+}
+
+func (b *Block) Timestamp(ctx context.Context) hexutil.Big {
+	log.DebugLog()
+	return hexutil.Big(*b.block.Time())
+}
+
+func (b *Block) Nonce(ctx context.Context) hexutil.Bytes {
+	log.DebugLog()
+	nonce := b.block.Header().Nonce
+	return hexutil.Bytes(nonce[:])
+}
+
+func (b *Block) MixHash(ctx context.Context) common.Hash {
+	log.DebugLog()
+	return b.block.MixDigest()
+}
+
+func (b *Block) TransactionsRoot(ctx context.Context) common.Hash {
+	log.DebugLog()
+	return b.block.TxHash()
+}
+
+func (b *Block) StateRoot(ctx context.Context) common.Hash {
+	log.DebugLog()
+	return b.block.Root()
+}
+
+func (b *Block) ReceiptsRoot(ctx context.Context) common.Hash {
+	log.DebugLog()
+	return b.block.ReceiptHash()
+}
+
+func (b *Block) ExtraData(ctx context.Context) hexutil.Bytes {
+	log.DebugLog()
+	return hexutil.Bytes(b.block.Extra())
+}
+
+func (b *Block) LogsBloom(ctx context.Context) hexutil.Bytes {
+	log.DebugLog()
+	return hexutil.Bytes(b.block.Bloom().Bytes())
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) (hexutil.Big, error) {
+	log.DebugLog()
+	td := b.backend.GetTd(b.block.Hash())
+	if td == nil {
+		return hexutil.Big{}, fmt.Errorf("total difficulty not found for block %x", b.block.Hash())
+	}
+	return hexutil.Big(*td), nil
+}
+
+func (b *Block) Miner(ctx context.Context, args struct{ Block *Long }) (*Account, error) {
+	log.DebugLog()
+	return newAccount(ctx, b.backend, b.block.Coinbase(), blockNumberOr(args.Block, rpc.BlockNumber(b.block.NumberU64())))
+}
+
+func (b *Block) OmmerCount(ctx context.Context) int32 {
+	log.DebugLog()
+	return int32(len(b.block.Uncles()))
+}
+
+func (b *Block) Ommers(ctx context.Context) ([]*Block, error) {
+	log.DebugLog()
+	ommers := make([]*Block, 0, len(b.block.Uncles()))
+	for _, uncle := range b.block.Uncles() {
+		ommer, err := newBlock(ctx, b.backend, types.NewBlockWithHeader(uncle))
+		if err != nil {
+			return nil, err
+		}
+		ommers = append(ommers, ommer)
+	}
+	return ommers, nil
+}
+
+func (b *Block) TransactionCount(ctx context.Context) int32 {
+	log.DebugLog()
+	return int32(len(b.block.Transactions()))
+}
+
+func (b *Block) Transactions(ctx context.Context) ([]*Transaction, error) {
+	log.DebugLog()
+	txs := make([]*Transaction, 0, len(b.block.Transactions()))
+	for i, tx := range b.block.Transactions() {
+		resolver, err := newTransaction(ctx, b.backend, tx, b.block, uint64(i))
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, resolver)
+	}
+	return txs, nil
+}
+
+func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
+	log.DebugLog()
+	txs := b.block.Transactions()
+	if args.Index < 0 || int(args.Index) >= len(txs) {
+		return nil, nil
+	}
+	return newTransaction(ctx, b.backend, txs[args.Index], b.block, uint64(args.Index))
+}
+
+func (b *Block) Account(ctx context.Context, args struct{ Address common.Address }) (*Account, error) {
+	log.DebugLog()
+	return newAccount(ctx, b.backend, args.Address, rpc.BlockNumber(b.block.NumberU64()))
+}
+
+// BlockFilterCriteria encapsulates criteria passed to a `logs` accessor inside
+// a block.
+type BlockFilterCriteria struct {
+	Addresses *[]common.Address // restricts matches to events created by specific contracts
+	Topics    *[][]common.Hash  // restricts matches to particular event topics
+}
+
+func (b *Block) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) ([]*Log, error) {
+	log.DebugLog()
+	var (
+		addresses []common.Address
+		topics    [][]common.Hash
+	)
+	if args.Filter.Addresses != nil {
+		addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		topics = *args.Filter.Topics
+	}
+	return blockLogs(ctx, b.backend, b.block, addresses, topics)
+}
+
+// blockLogs retrieves the logs of a block matching the given filter criteria.
+func blockLogs(ctx context.Context, backend ethapi.Backend, block *types.Block, addresses []common.Address, topics [][]common.Hash) ([]*Log, error) {
+	log.DebugLog()
+	logs := []*Log{}
+	if !bloomMatches(block.Bloom(), addresses, topics) {
+		return logs, nil
+	}
+	receipts, err := backend.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	for i, receipt := range receipts {
+		var tx *Transaction
+		for _, entry := range receipt.Logs {
+			if !logMatches(entry, addresses, topics) {
+				continue
+			}
+			if tx == nil {
+				if tx, err = newTransaction(ctx, backend, block.Transactions()[i], block, uint64(i)); err != nil {
+					return nil, err
+				}
+			}
+			if err := charge(ctx, 1); err != nil {
+				return nil, err
+			}
+			logs = append(logs, &Log{backend: backend, transaction: tx, log: entry})
+		}
+	}
+	return logs, nil
+}
+
+// bloomMatches checks whether a block may contain logs matching the filter.
+func bloomMatches(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	log.DebugLog()
+	if len(addresses) > 0 {
+		var included bool
+		for _, addr := range addresses {
+			if types.BloomLookup(bloom, addr) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, sub := range topics {
+		included := len(sub) == 0 // empty rule set == wildcard
+		for _, topic := range sub {
+			if types.BloomLookup(bloom, topic) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+// logMatches checks whether a log matches the filter criteria.
+func logMatches(entry *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	log.DebugLog()
+	if len(addresses) > 0 {
+		var included bool
+		for _, addr := range addresses {
+			if entry.Address == addr {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if len(topics) > len(entry.Topics) {
+		return false
+	}
+	for i, sub := range topics {
+		match := len(sub) == 0 // empty rule set == wildcard
+		for _, topic := range sub {
+			if entry.Topics[i] == topic {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
+// Pending represents the current pending state.
+type Pending struct {
+	backend ethapi.Backend
+}
+
+func (p *Pending) TransactionCount(ctx context.Context) (int32, error) {
+	log.DebugLog()
+	txs, err := p.backend.GetPoolTransactions()
+	return int32(len(txs)), err
+}
+
+func (p *Pending) Transactions(ctx context.Context) ([]*Transaction, error) {
+	log.DebugLog()
+	txs, err := p.backend.GetPoolTransactions()
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*Transaction, 0, len(txs))
+	for _, tx := range txs {
+		resolver, err := newTransaction(ctx, p.backend, tx, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		resolvers = append(resolvers, resolver)
+	}
+	return resolvers, nil
+}
+
+func (p *Pending) Account(ctx context.Context, args struct{ Address common.Address }) (*Account, error) {
+	log.DebugLog()
+	return newAccount(ctx, p.backend, args.Address, rpc.PendingBlockNumber)
+}
+
+// Resolver is the top-level object in the GraphQL hierarchy.
+type Resolver struct {
+	backend ethapi.Backend
+}
+
+func (r *Resolver) Block(ctx context.Context, args struct {
+	Number *Long
+	Hash   *common.Hash
+}) (*Block, error) {
+	log.DebugLog()
+	var (
+		block *types.Block
+		err   error
+	)
+	switch {
+	case args.Number != nil && args.Hash != nil:
+		return nil, errors.New("only one of number or hash may be specified")
+	case args.Hash != nil:
+		block, err = r.backend.GetBlock(ctx, *args.Hash)
+	default:
+		block, err = r.backend.BlockByNumber(ctx, blockNumberOr(args.Number, rpc.LatestBlockNumber))
+	}
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return newBlock(ctx, r.backend, block)
+}
+
+func (r *Resolver) Blocks(ctx context.Context, args struct {
+	From Long
+	To   *Long
+}) ([]*Block, error) {
+	log.DebugLog()
+	from := uint64(args.From)
+	to := r.backend.CurrentBlock().NumberU64()
+	if args.To != nil && uint64(*args.To) < to {
+		to = uint64(*args.To)
+	}
+	if to < from {
+		return []*Block{}, nil
+	}
+	// Charge the whole range upfront to reject huge ranges without any work
+	if err := charge(ctx, int(to-from+1)); err != nil {
+		return nil, err
+	}
+	blocks := make([]*Block, 0, to-from+1)
+	for number := from; number <= to; number++ {
+		block, err := r.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, &Block{backend: r.backend, block: block})
+	}
+	return blocks, nil
+}
+
+func (r *Resolver) Pending(ctx context.Context) *Pending {
+	log.DebugLog()
+	return &Pending{r.backend}
+}
+
+func (r *Resolver) Transaction(ctx context.Context, args struct{ Hash common.Hash }) (*Transaction, error) {
+	log.DebugLog()
+	// Look up the transaction in the chain first, falling back to the pool
+	if tx, blockHash, _, index := core.GetTransaction(r.backend.ChainDb(), args.Hash); tx != nil {
+		block, err := r.backend.GetBlock(ctx, blockHash)
+		if err != nil {
+			return nil, err
+		}
+		if block != nil {
+			return newTransaction(ctx, r.backend, tx, block, index)
+		}
+	}
+	if tx := r.backend.GetPoolTransaction(args.Hash); tx != nil {
+		return newTransaction(ctx, r.backend, tx, nil, 0)
+	}
+	return nil, nil
+}
+
+// FilterCriteria encapsulates the arguments to `logs` on the root resolver object.
+type FilterCriteria struct {
+	FromBlock *Long             // beginning of the queried range, nil means latest block
+	ToBlock   *Long             // end of the range, nil means latest block
+	Addresses *[]common.Address // restricts matches to events created by specific contracts
+	Topics    *[][]common.Hash  // restricts matches to particular event topics
+}
+
+func (r *Resolver) Logs(ctx context.Context, args struct{ Filter FilterCriteria }) ([]*Log, error) {
+	log.DebugLog()
+	var (
+		head      = r.backend.CurrentBlock().NumberU64()
+		from, to  = head, head
+		addresses []common.Address
+		topics    [][]common.Hash
+	)
+	if args.Filter.FromBlock != nil {
+		from = uint64(*args.Filter.FromBlock)
+	}
+	if args.Filter.ToBlock != nil && uint64(*args.Filter.ToBlock) < head {
+		to = uint64(*args.Filter.ToBlock)
+	}
+	if args.Filter.Addresses != nil {
+		addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		topics = *args.Filter.Topics
+	}
+	logs := []*Log{}
+	for number := from; number <= to; number++ {
+		// Every scanned block counts against the budget, matching or not
+		if err := charge(ctx, 1); err != nil {
+			return nil, err
+		}
+		header, err := r.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			break
+		}
+		if !bloomMatches(header.Bloom, addresses, topics) {
+			continue
+		}
+		block, err := r.backend.GetBlock(ctx, header.Hash())
+		if err != nil {
+			return nil, err
+		}
+		matches, err := blockLogs(ctx, r.backend, block, addresses, topics)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, matches...)
+	}
+	return logs, nil
+}
+
+func (r *Resolver) GasPrice(ctx context.Context) (hexutil.Big, error) {
+	log.DebugLog()
+	price, err := r.backend.SuggestPrice(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*price), nil
+}
+
+func (r *Resolver) ProtocolVersion(ctx context.Context) int32 {
+	log.DebugLog()
+	return int32(r.backend.ProtocolVersion())
+}
+
+func (r *Resolver) SendRawTransaction(ctx context.Context, args struct{ Data hexutil.Bytes }) (common.Hash, error) {
+	log.DebugLog()
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(args.Data, tx); err != nil {
+		return common.Hash{}, err
+	}
+	if err := r.backend.SendTx(ctx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
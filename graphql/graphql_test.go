@@ -0,0 +1,273 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testAddr    = common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+	testBalance = big.NewInt(1000000000)
+)
+
+// newTestServer starts an in-memory Ethereum node and returns a GraphQL handler
+// serving its data with the given configuration.
+func newTestServer(t *testing.T, config Config) (*node.Node, *httptest.Server) { log.DebugLog()
+	stack, err := node.New(&node.Config{P2P: p2p.Config{NoDiscovery: true, MaxPeers: 0}})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	ethConf := eth.DefaultConfig
+	ethConf.Genesis = &core.Genesis{
+		Config:     params.AllEthashProtocolChanges,
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      core.GenesisAlloc{testAddr: {Balance: testBalance}},
+	}
+	ethConf.Ethash.PowMode = ethash.ModeFake
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return eth.New(ctx, &ethConf)
+	}); err != nil {
+		t.Fatalf("failed to register eth service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	var ethServ *eth.Ethereum
+	if err := stack.Service(&ethServ); err != nil {
+		t.Fatalf("failed to retrieve eth service: %v", err)
+	}
+	handler, err := newHandler(ethServ.ApiBackend, config)
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	return stack, httptest.NewServer(handler)
+}
+
+// result is the decoded response of a GraphQL query.
+type result struct {
+	Data   json.RawMessage
+	Errors []struct{ Message string }
+}
+
+// query sends a GraphQL query as a POST request and decodes the response.
+func query(t *testing.T, server *httptest.Server, query string) *result { log.DebugLog()
+	body, _ := json.Marshal(map[string]string{"query": query})
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatalf("failed to send query: %v", err)
+	}
+	defer resp.Body.Close()
+
+	res := new(result)
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return res
+}
+
+func TestBlockAndAccountQuery(t *testing.T) { log.DebugLog()
+	stack, server := newTestServer(t, DefaultConfig)
+	defer stack.Stop()
+	defer server.Close()
+
+	res := query(t, server, `{ block(number: 0) { number hash account(address: "`+testAddr.Hex()+`") { balance transactionCount } } }`)
+	if len(res.Errors) != 0 {
+		t.Fatalf("query failed: %v", res.Errors)
+	}
+	var data struct {
+		Block struct {
+			Number  uint64
+			Hash    common.Hash
+			Account struct {
+				Balance          string
+				TransactionCount uint64
+			}
+		}
+	}
+	if err := json.Unmarshal(res.Data, &data); err != nil {
+		t.Fatalf("failed to decode data: %v", err)
+	}
+	if data.Block.Number != 0 {
+		t.Errorf("block number mismatch: have %d, want 0", data.Block.Number)
+	}
+	if data.Block.Hash == (common.Hash{}) {
+		t.Errorf("block hash missing")
+	}
+	if want := "0x3b9aca00"; data.Block.Account.Balance != want {
+		t.Errorf("balance mismatch: have %s, want %s", data.Block.Account.Balance, want)
+	}
+	if data.Block.Account.TransactionCount != 0 {
+		t.Errorf("nonce mismatch: have %d, want 0", data.Block.Account.TransactionCount)
+	}
+}
+
+func TestGetQuery(t *testing.T) { log.DebugLog()
+	stack, server := newTestServer(t, DefaultConfig)
+	defer stack.Stop()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?query=" + url.QueryEscape("{ block { number } }"))
+	if err != nil {
+		t.Fatalf("failed to send query: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var res result
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(res.Errors) != 0 {
+		t.Fatalf("query failed: %v", res.Errors)
+	}
+	if have, want := string(res.Data), `{"block":{"number":0}}`; have != want {
+		t.Errorf("response mismatch: have %s, want %s", have, want)
+	}
+}
+
+func TestComplexityLimit(t *testing.T) { log.DebugLog()
+	config := DefaultConfig
+	config.MaxComplexity = 1
+
+	stack, server := newTestServer(t, config)
+	defer stack.Stop()
+	defer server.Close()
+
+	if res := query(t, server, `{ block { number } }`); len(res.Errors) != 0 {
+		t.Fatalf("query within budget failed: %v", res.Errors)
+	}
+	res := query(t, server, `{ block { miner { balance } } }`)
+	if len(res.Errors) == 0 {
+		t.Fatalf("query exceeding budget succeeded")
+	}
+	if !strings.Contains(res.Errors[0].Message, errComplexityReached.Error()) {
+		t.Errorf("error mismatch: have %q, want %q", res.Errors[0].Message, errComplexityReached)
+	}
+}
+
+func TestDepthLimit(t *testing.T) { log.DebugLog()
+	config := DefaultConfig
+	config.MaxDepth = 2
+
+	stack, server := newTestServer(t, config)
+	defer stack.Stop()
+	defer server.Close()
+
+	if res := query(t, server, `{ block { number } }`); len(res.Errors) != 0 {
+		t.Fatalf("query within depth failed: %v", res.Errors)
+	}
+	if res := query(t, server, `{ block { parent { parent { number } } } }`); len(res.Errors) == 0 {
+		t.Fatalf("query exceeding depth succeeded")
+	}
+}
+
+func TestLongUnmarshal(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		input interface{}
+		want  Long
+		fail  bool
+	}{
+		{input: "0x10", want: 16},
+		{input: "16", want: 16},
+		{input: int32(16), want: 16},
+		{input: float64(16), want: 16},
+		{input: "0xzz", fail: true},
+		{input: int32(-1), fail: true},
+		{input: true, fail: true},
+	}
+	for i, tt := range tests {
+		var have Long
+		err := have.UnmarshalGraphQL(tt.input)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("test %d: expected error for %v", i, tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		} else if have != tt.want {
+			t.Errorf("test %d: value mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that stopping the service shuts down the HTTP server, dropping the idle
+// keep-alive connections too, not just the listener.
+func TestServiceStop(t *testing.T) { log.DebugLog()
+	service := &Service{
+		config: Config{Host: "127.0.0.1", VirtualHosts: []string{"*"}},
+		handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}),
+	}
+	if err := service.Start(nil); err != nil {
+		t.Fatalf("failed to start service: %v", err)
+	}
+	addr := service.listener.Addr().String()
+
+	// Open a keep-alive connection and leave it idle after a request
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err := service.Stop(); err != nil {
+		t.Fatalf("failed to stop service: %v", err)
+	}
+	// The idle connection must be closed by the server
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := reader.ReadByte(); err == nil {
+		t.Fatalf("read data from stopped server")
+	} else if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		t.Fatalf("idle connection left open by stopped server")
+	}
+	if conn, err := net.Dial("tcp", addr); err == nil {
+		conn.Close()
+		t.Fatalf("stopped server accepted connection")
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rpc"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+)
+
+// maxRequestContentLength is the maximum size of a GraphQL request body.
+const maxRequestContentLength = 1024 * 128
+
+// Config contains the configuration options of the GraphQL service.
+type Config struct {
+	Host          string   // Listening interface of the GraphQL HTTP server
+	Port          int      // Listening port of the GraphQL HTTP server
+	Cors          []string // Cross-origin domains the server accepts requests from
+	VirtualHosts  []string // Virtual hostnames the server accepts requests for
+	MaxDepth      int      // Maximum nesting depth of a query, 0 for unlimited
+	MaxComplexity int      // Maximum number of objects a query may resolve, 0 for unlimited
+}
+
+// DefaultConfig contains the default settings of the GraphQL service.
+var DefaultConfig = Config{
+	Host:          "localhost",
+	Port:          8547,
+	VirtualHosts:  []string{"localhost"},
+	MaxDepth:      16,
+	MaxComplexity: 10000,
+}
+
+// Service encapsulates a GraphQL HTTP server.
+type Service struct {
+	config   Config
+	handler  http.Handler // Handler executing the GraphQL queries
+	listener net.Listener // Listening socket of the server
+	server   *http.Server // HTTP server serving the queries
+}
+
+// New constructs a new GraphQL service instance.
+func New(backend ethapi.Backend, config Config) (*Service, error) {
+	log.DebugLog()
+	handler, err := newHandler(backend, config)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{config: config, handler: handler}, nil
+}
+
+// Protocols returns the list of protocols exported by this service.
+func (s *Service) Protocols() []p2p.Protocol {
+	log.DebugLog()
+	return nil
+}
+
+// APIs returns the list of APIs exported by this service.
+func (s *Service) APIs() []rpc.API {
+	log.DebugLog()
+	return nil
+}
+
+// endpoint returns the listening address of the GraphQL HTTP server.
+func (s *Service) endpoint() string {
+	log.DebugLog()
+	return fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
+}
+
+// Start is called after all services have been constructed and the networking
+// layer was also initialized to spawn any goroutines required by the service.
+func (s *Service) Start(server *p2p.Server) error {
+	log.DebugLog()
+	listener, err := net.Listen("tcp", s.endpoint())
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.server = &http.Server{Handler: rpc.NewHTTPHandlerStack(s.handler, s.config.Cors, s.config.VirtualHosts)}
+	go s.server.Serve(listener)
+
+	log.Info("GraphQL endpoint opened", "url", "http://"+s.endpoint(), "cors", strings.Join(s.config.Cors, ","), "vhosts", strings.Join(s.config.VirtualHosts, ","))
+	return nil
+}
+
+// Stop terminates all goroutines belonging to the service, blocking until they
+// are all terminated. Closing the HTTP server drops the listener along with all
+// the connections still open, idle keep-alive ones included.
+func (s *Service) Stop() error {
+	log.DebugLog()
+	if s.server != nil {
+		s.server.Close()
+		s.server, s.listener = nil, nil
+		log.Info("GraphQL endpoint closed", "url", "http://"+s.endpoint())
+	}
+	return nil
+}
+
+// handler serves GraphQL queries over HTTP.
+type handler struct {
+	schema        *graphqlgo.Schema
+	maxComplexity int
+}
+
+// newHandler parses the schema against the resolvers and returns an HTTP handler
+// executing queries against it.
+func newHandler(backend ethapi.Backend, config Config) (*handler, error) {
+	log.DebugLog()
+	var opts []graphqlgo.SchemaOpt
+	if config.MaxDepth > 0 {
+		opts = append(opts, graphqlgo.MaxDepth(config.MaxDepth))
+	}
+	schema, err := graphqlgo.ParseSchema(schema, &Resolver{backend}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &handler{schema: schema, maxComplexity: config.MaxComplexity}, nil
+}
+
+// request is the JSON encoded body of a GraphQL query.
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeHTTP executes a single GraphQL query, sent either as the query parameter
+// of a GET request or as the JSON body of a POST request.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.DebugLog()
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if vars := r.URL.Query().Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if r.ContentLength > maxRequestContentLength {
+			http.Error(w, "content length too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		body := io.LimitReader(r.Body, maxRequestContentLength)
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := withBudget(r.Context(), h.maxComplexity)
+	response := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	blob, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(blob)
+}
@@ -68,6 +68,13 @@ func (*HandlerT) BacktraceAt(location string) error { log.DebugLog()
 	return glogger.BacktraceAt(location)
 }
 
+// CallTrace sets the function call tracing pattern, enabling the entry and exit
+// traces of all matching functions. An empty pattern disables call tracing. See
+// package log for details on the pattern syntax.
+func (*HandlerT) CallTrace(pattern string) error { log.DebugLog()
+	return log.SetCallTrace(pattern)
+}
+
 // MemStats returns detailed runtime memory statistics.
 func (*HandlerT) MemStats() *runtime.MemStats { log.DebugLog()
 	s := new(runtime.MemStats)
@@ -48,6 +48,11 @@ var (
 		Usage: "Request a stack trace at a specific logging statement (e.g. \"block.go:271\")",
 		Value: "",
 	}
+	callTraceFlag = cli.StringFlag{
+		Name:  "calltrace",
+		Usage: "Per-module function call tracing: comma-separated list of <pattern>=1 (e.g. eth/*=1,core.BlockChain.Insert*=1)",
+		Value: "",
+	}
 	debugFlag = cli.BoolFlag{
 		Name:  "debug",
 		Usage: "Prepends log messages with call-site location (file and line number)",
@@ -87,7 +92,7 @@ var (
 
 // Flags holds all command-line flags required for debugging.
 var Flags = []cli.Flag{
-	verbosityFlag, vmoduleFlag, backtraceAtFlag, debugFlag,
+	verbosityFlag, vmoduleFlag, backtraceAtFlag, callTraceFlag, debugFlag,
 	pprofFlag, pprofAddrFlag, pprofPortFlag,
 	memprofilerateFlag, blockprofilerateFlag, cpuprofileFlag, traceFlag,
 }
@@ -100,7 +105,11 @@ func init() {
 	if usecolor {
 		output = colorable.NewColorableStderr()
 	}
-	glogger = log.NewGlogHandler(log.StreamHandler(output, log.TerminalFormat(usecolor)))
+	ostream := log.StreamHandler(output, log.TerminalFormat(usecolor))
+	glogger = log.NewGlogHandler(ostream)
+
+	// Call traces are filtered by their own patterns, bypass the verbosity
+	log.SetCallTraceHandler(ostream)
 }
 
 // Setup initializes profiling and logging based on the CLI flags.
@@ -112,6 +121,9 @@ func Setup(ctx *cli.Context) error { log.DebugLog()
 	glogger.Vmodule(ctx.GlobalString(vmoduleFlag.Name))
 	glogger.BacktraceAt(ctx.GlobalString(backtraceAtFlag.Name))
 	log.Root().SetHandler(glogger)
+	if err := log.SetCallTrace(ctx.GlobalString(callTraceFlag.Name)); err != nil {
+		return err
+	}
 
 	// profiling, tracing
 	runtime.MemProfileRate = ctx.GlobalInt(memprofilerateFlag.Name)
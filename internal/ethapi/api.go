@@ -26,22 +26,23 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 const (
@@ -601,6 +602,79 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], state.Error()
 }
 
+// AccountResult is the result of eth_getProof: an account along with the merkle
+// proof of its inclusion in the state trie, and the proofs of the requested slots
+// in its storage trie.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is a storage slot along with the merkle proof of its inclusion
+// in the storage trie of an account.
+type StorageResult struct {
+	Key   string          `json:"key"`
+	Value *hexutil.Big    `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// GetProof returns the account and storage values of the specified account, along
+// with the merkle proofs needed to verify them against the state root of the block.
+// Missing accounts and empty slots are reported as such, with the proofs of their
+// absence.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) { log.DebugLog()
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Gather the storage proofs, all of them proving absence if there's no account
+	storageHash, codeHash := types.EmptyRootHash, crypto.Keccak256Hash(nil)
+	if state.Exist(address) {
+		storageHash, codeHash = state.StorageTrie(address).Hash(), state.GetCodeHash(address)
+	}
+	storageProof := make([]StorageResult, len(storageKeys))
+	for i, key := range storageKeys {
+		storageProof[i] = StorageResult{Key: key, Value: new(hexutil.Big), Proof: []hexutil.Bytes{}}
+		if !state.Exist(address) {
+			continue
+		}
+		proof, err := state.GetStorageProof(address, common.HexToHash(key))
+		if err != nil {
+			return nil, err
+		}
+		storageProof[i].Value = (*hexutil.Big)(state.GetState(address, common.HexToHash(key)).Big())
+		storageProof[i].Proof = toHexSlice(proof)
+	}
+	// Gather the account proof and assemble the result
+	accountProof, err := state.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      (*hexutil.Big)(state.GetBalance(address)),
+		CodeHash:     codeHash,
+		Nonce:        hexutil.Uint64(state.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: storageProof,
+	}, state.Error()
+}
+
+// toHexSlice converts the nodes of a merkle proof into their hex representation.
+func toHexSlice(proof [][]byte) []hexutil.Bytes { log.DebugLog()
+	nodes := make([]hexutil.Bytes, len(proof))
+	for i, node := range proof {
+		nodes[i] = node
+	}
+	return nodes
+}
+
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
 	From     common.Address  `json:"from"`
@@ -611,33 +685,121 @@ type CallArgs struct {
 	Data     hexutil.Bytes   `json:"data"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) { log.DebugLog()
+// ToMessage converts the call arguments into a message that can be executed on
+// top of any state. The gas allowance defaults to the global gas cap, or to a
+// practically unlimited value if there's none; larger allowances are capped.
+// The gas price is taken as is (zero if unspecified).
+func (args *CallArgs) ToMessage(globalGasCap uint64) types.Message { log.DebugLog()
+	gas := uint64(args.Gas)
+	if gas == 0 {
+		gas = math.MaxUint64 / 2
+		if globalGasCap != 0 {
+			gas = globalGasCap
+		}
+	}
+	if globalGasCap != 0 && globalGasCap < gas {
+		log.Warn("Caller gas above allowance, capping", "requested", gas, "cap", globalGasCap)
+		gas = globalGasCap
+	}
+	return types.NewMessage(args.From, args.To, 0, args.Value.ToInt(), gas, args.GasPrice.ToInt(), args.Data, false)
+}
+
+// OverrideAccount specifies the fields of an account to override during the
+// execution of a message call. State replaces the entire storage of the account,
+// whereas StateDiff only overrides the given slots; the two are exclusive.
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64              `json:"nonce"`
+	Code      *hexutil.Bytes               `json:"code"`
+	Balance   **hexutil.Big                `json:"balance"`
+	State     *map[common.Hash]common.Hash `json:"state"`
+	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
+}
+
+// StateOverride is the collection of overridden accounts.
+type StateOverride map[common.Address]OverrideAccount
+
+// Apply overrides the fields of the specified accounts in the given state.
+func (diff *StateOverride) Apply(state *state.StateDB) error { log.DebugLog()
+	if diff == nil {
+		return nil
+	}
+	for addr, account := range *diff {
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+		}
+		if account.Nonce != nil {
+			state.SetNonce(addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			state.SetCode(addr, *account.Code)
+		}
+		if account.Balance != nil {
+			state.SetBalance(addr, (*big.Int)(*account.Balance))
+		}
+		if account.State != nil {
+			state.SetStorage(addr, *account.State)
+		}
+		if account.StateDiff != nil {
+			for key, value := range *account.StateDiff {
+				state.SetState(addr, key, value)
+			}
+		}
+	}
+	return state.Error()
+}
+
+// BlockOverrides specifies the fields of the block context to override during
+// the execution of a message call.
+type BlockOverrides struct {
+	Number   *hexutil.Big    `json:"number"`
+	Time     *hexutil.Big    `json:"time"`
+	GasLimit *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase *common.Address `json:"coinbase"`
+}
+
+// Apply overrides the fields of the given header, returning a modified copy.
+func (diff *BlockOverrides) Apply(header *types.Header) *types.Header { log.DebugLog()
+	if diff == nil {
+		return header
+	}
+	header = types.CopyHeader(header)
+	if diff.Number != nil {
+		header.Number = new(big.Int).Set(diff.Number.ToInt())
+	}
+	if diff.Time != nil {
+		header.Time = new(big.Int).Set(diff.Time.ToInt())
+	}
+	if diff.GasLimit != nil {
+		header.GasLimit = uint64(*diff.GasLimit)
+	}
+	if diff.Coinbase != nil {
+		header.Coinbase = *diff.Coinbase
+	}
+	return header
+}
+
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, blockOverrides *BlockOverrides, vmCfg vm.Config, timeout time.Duration) (*core.ExecutionResult, error) { log.DebugLog()
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
 	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, 0, false, err
+		return nil, err
 	}
+	header = blockOverrides.Apply(header)
 	// Set sender address or use a default if none specified
-	addr := args.From
-	if addr == (common.Address{}) {
+	if args.From == (common.Address{}) {
 		if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
 			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
-				addr = accounts[0].Address
+				args.From = accounts[0].Address
 			}
 		}
 	}
-	// Set default gas & gas price if none were set
-	gas, gasPrice := uint64(args.Gas), args.GasPrice.ToInt()
-	if gas == 0 {
-		gas = math.MaxUint64 / 2
-	}
-	if gasPrice.Sign() == 0 {
-		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
+	// Set default gas price if none was set
+	if args.GasPrice.ToInt().Sign() == 0 {
+		args.GasPrice = hexutil.Big(*new(big.Int).SetUint64(defaultGasPrice))
 	}
-
 	// Create new call message
-	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+	msg := args.ToMessage(s.b.RPCGasCap())
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -654,7 +816,16 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Get a new instance of the EVM.
 	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vmCfg)
 	if err != nil {
-		return nil, 0, false, err
+		return nil, err
+	}
+	// Apply the overrides on top of the private copy of the state and context.
+	// The state is only touched after the EVM was created, so that an explicit
+	// balance override of the sender takes precedence over the backend's.
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	if blockOverrides != nil && blockOverrides.Coinbase != nil {
+		evm.Coinbase = *blockOverrides.Coinbase
 	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -666,23 +837,34 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Setup the gas pool (also for unmetered requests)
 	// and apply the message.
 	gp := new(core.GasPool).AddGas(math.MaxUint64)
-	res, gas, failed, err := core.ApplyMessage(evm, msg, gp)
+	result, err := core.ApplyMessage(evm, msg, gp)
 	if err := vmError(); err != nil {
-		return nil, 0, false, err
+		return nil, err
 	}
-	return res, gas, failed, err
+	return result, err
 }
 
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) { log.DebugLog()
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
-	return (hexutil.Bytes)(result), err
+//
+// Additionally, the caller can specify a batch of accounts to override before
+// executing the call, as well as a set of block context fields to override.
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, blockOverrides *BlockOverrides) (hexutil.Bytes, error) { log.DebugLog()
+	result, err := s.doCall(ctx, args, blockNr, overrides, blockOverrides, vm.Config{}, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	// If the execution was reverted, return the revert data and the reason
+	if result.Err == vm.ErrExecutionReverted {
+		return nil, abi.NewRevertError(result.Revert())
+	}
+	return result.Return(), result.Err
 }
 
 // EstimateGas returns an estimate of the amount of gas needed to execute the
-// given transaction against the current pending block.
-func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) { log.DebugLog()
+// given transaction against the current pending block, optionally with a set of
+// account and block context overrides applied.
+func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs, overrides *StateOverride, blockOverrides *BlockOverrides) (hexutil.Uint64, error) { log.DebugLog()
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
 		lo  uint64 = params.TxGas - 1
@@ -692,29 +874,44 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	if uint64(args.Gas) >= params.TxGas {
 		hi = uint64(args.Gas)
 	} else {
-		// Retrieve the current pending block to act as the gas ceiling
+		// Retrieve the current pending block to act as the gas ceiling, with the
+		// gas limit of the block overrides taking precedence
 		block, err := s.b.BlockByNumber(ctx, rpc.PendingBlockNumber)
 		if err != nil {
 			return 0, err
 		}
-		hi = block.GasLimit()
+		hi = blockOverrides.Apply(block.Header()).GasLimit
+	}
+	// Recap the highest gas allowance with the global gas cap
+	if gasCap := s.b.RPCGasCap(); gasCap != 0 && hi > gasCap {
+		log.Warn("Caller gas above allowance, capping", "requested", hi, "cap", gasCap)
+		hi = gasCap
 	}
 	cap = hi
 
-	// Create a helper to check if a gas allowance results in an executable transaction
-	executable := func(gas uint64) bool {
+	// Create a helper to check if a gas allowance results in an executable
+	// transaction. Only failures depending on the allowance are bisected, any
+	// other error (invalid overrides, missing state, ...) is returned as is.
+	executable := func(gas uint64) (bool, *core.ExecutionResult, error) {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{}, 0)
-		if err != nil || failed {
-			return false
+		result, err := s.doCall(ctx, args, rpc.PendingBlockNumber, overrides, blockOverrides, vm.Config{}, 0)
+		switch {
+		case err == vm.ErrOutOfGas || err == core.ErrInsufficientBalanceForGas || err == vm.ErrInsufficientBalance:
+			return false, nil, nil // intrinsic gas or gas cost beyond the allowance
+		case err != nil:
+			return false, nil, err
 		}
-		return true
+		return !result.Failed(), result, nil
 	}
 	// Execute the binary search and hone in on an executable gas limit
 	for lo+1 < hi {
 		mid := (hi + lo) / 2
-		if !executable(mid) {
+		ok, _, err := executable(mid)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
 			lo = mid
 		} else {
 			hi = mid
@@ -722,7 +919,15 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	}
 	// Reject the transaction as invalid if it still fails at the highest allowance
 	if hi == cap {
-		if !executable(hi) {
+		ok, result, err := executable(hi)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			// If the execution was reverted, surface the reason instead of a gas error
+			if result != nil && result.Err == vm.ErrExecutionReverted {
+				return 0, abi.NewRevertError(result.Revert())
+			}
 			return 0, fmt.Errorf("gas required exceeds allowance or always failing transaction")
 		}
 	}
@@ -733,10 +938,11 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 // while replaying a transaction in debug mode as well as transaction
 // execution status, the amount of gas used and the return value
 type ExecutionResult struct {
-	Gas         uint64         `json:"gas"`
-	Failed      bool           `json:"failed"`
-	ReturnValue string         `json:"returnValue"`
-	StructLogs  []StructLogRes `json:"structLogs"`
+	Gas          uint64         `json:"gas"`
+	Failed       bool           `json:"failed"`
+	ReturnValue  string         `json:"returnValue"`
+	RevertReason string         `json:"revertReason,omitempty"`
+	StructLogs   []StructLogRes `json:"structLogs"`
 }
 
 // StructLogRes stores a structured log emitted by the EVM while replaying a
@@ -1412,32 +1618,23 @@ func NewPrivateDebugAPI(b Backend) *PrivateDebugAPI { log.DebugLog()
 	return &PrivateDebugAPI{b: b}
 }
 
-// ChaindbProperty returns leveldb properties of the chain database.
+// ChaindbProperty returns the backend specific properties of the chain database.
+// For LevelDB, the "leveldb." prefix may be omitted.
 func (api *PrivateDebugAPI) ChaindbProperty(property string) (string, error) { log.DebugLog()
-	ldb, ok := api.b.ChainDb().(interface {
-		LDB() *leveldb.DB
-	})
-	if !ok {
-		return "", fmt.Errorf("chaindbProperty does not work for memory databases")
-	}
-	if property == "" {
-		property = "leveldb.stats"
-	} else if !strings.HasPrefix(property, "leveldb.") {
-		property = "leveldb." + property
+	if _, ok := api.b.ChainDb().(*ethdb.LDBDatabase); ok {
+		if property == "" {
+			property = "leveldb.stats"
+		} else if !strings.HasPrefix(property, "leveldb.") {
+			property = "leveldb." + property
+		}
 	}
-	return ldb.LDB().GetProperty(property)
+	return api.b.ChainDb().Stat(property)
 }
 
 func (api *PrivateDebugAPI) ChaindbCompact() error { log.DebugLog()
-	ldb, ok := api.b.ChainDb().(interface {
-		LDB() *leveldb.DB
-	})
-	if !ok {
-		return fmt.Errorf("chaindbCompact does not work for memory databases")
-	}
 	for b := byte(0); b < 255; b++ {
 		log.Info("Compacting chain database", "range", fmt.Sprintf("0x%0.2X-0x%0.2X", b, b+1))
-		err := ldb.LDB().CompactRange(util.Range{Start: []byte{b}, Limit: []byte{b + 1}})
+		err := api.b.ChainDb().Compact([]byte{b}, []byte{b + 1})
 		if err != nil {
 			log.Error("Database compaction failed", "err", err)
 			return err
@@ -0,0 +1,304 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+
+	// balanceCode returns the balance of the executing contract.
+	balanceCode = common.FromHex("0x303160005260206000f3")
+
+	// createCode deploys an empty contract and returns its address.
+	createCode = common.FromHex("0x600060006000f060005260206000f3")
+
+	// gasHungryCode fails unless at least 6M gas is left when it starts.
+	gasHungryCode = common.FromHex("0x5a625b8d8011600a57005bfe")
+
+	// storageCode returns the storage slots 1 and 2 of the executing contract.
+	storageCode = common.FromHex("0x60015460005260025460205260406000f3")
+
+	// contextCode returns the number, timestamp and coinbase of the block.
+	contextCode = common.FromHex("0x43600052426020524160405260606000f3")
+
+	// sstoreCode writes 1 into the storage slot 0.
+	sstoreCode = common.FromHex("0x600160005500")
+)
+
+// newTestBackend starts an in-memory node whose genesis funds the test account
+// and contains the given additional accounts, returning its API backend.
+func newTestBackend(t *testing.T, alloc core.GenesisAlloc) (*node.Node, *eth.Ethereum) { log.DebugLog()
+	stack, err := node.New(&node.Config{P2P: p2p.Config{NoDiscovery: true, MaxPeers: 0}})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	genesisAlloc := core.GenesisAlloc{testAddr: {Balance: testBalance}}
+	for addr, account := range alloc {
+		genesisAlloc[addr] = account
+	}
+	ethConf := eth.DefaultConfig
+	ethConf.Genesis = &core.Genesis{
+		Config:     params.AllEthashProtocolChanges,
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      genesisAlloc,
+	}
+	ethConf.Ethash.PowMode = ethash.ModeFake
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return eth.New(ctx, &ethConf)
+	}); err != nil {
+		t.Fatalf("failed to register eth service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	var ethServ *eth.Ethereum
+	if err := stack.Service(&ethServ); err != nil {
+		t.Fatalf("failed to retrieve eth service: %v", err)
+	}
+	// Installing a filter waits for the filter system to subscribe to the chain
+	// events, stopping the node before that leaves it with nil subscriptions
+	client, err := stack.Attach()
+	if err != nil {
+		t.Fatalf("failed to attach to node: %v", err)
+	}
+	defer client.Close()
+
+	var id string
+	if err := client.Call(&id, "eth_newBlockFilter"); err != nil {
+		t.Fatalf("failed to install filter: %v", err)
+	}
+	return stack, ethServ
+}
+
+// overrideBalance converts a balance into the format of the account overrides.
+func overrideBalance(balance int64) **hexutil.Big { log.DebugLog()
+	b := (*hexutil.Big)(big.NewInt(balance))
+	return &b
+}
+
+// word returns the big endian 32 byte encoding of the i-th word of a blob.
+func word(blob []byte, i int) common.Hash { log.DebugLog()
+	if len(blob) < (i+1)*32 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(blob[i*32 : (i+1)*32])
+}
+
+// Tests that the balance, nonce and code of accounts can be overridden in calls.
+func TestCallAccountOverrides(t *testing.T) { log.DebugLog()
+	stack, ethServ := newTestBackend(t, nil)
+	defer stack.Stop()
+	api := ethapi.NewPublicBlockChainAPI(ethServ.ApiBackend)
+
+	var (
+		contract = common.HexToAddress("0xc0de")
+		code     = hexutil.Bytes(balanceCode)
+		nonce    = hexutil.Uint64(5)
+	)
+	// Without overrides there's no code to execute
+	out, err := api.Call(context.Background(), ethapi.CallArgs{From: testAddr, To: &contract}, rpc.LatestBlockNumber, nil, nil)
+	if err != nil || len(out) != 0 {
+		t.Fatalf("plain call mismatch: have %x, %v", out, err)
+	}
+	// Code and balance overrides are visible to the executed code
+	overrides := ethapi.StateOverride{contract: {Code: &code, Balance: overrideBalance(12345)}}
+	out, err = api.Call(context.Background(), ethapi.CallArgs{From: testAddr, To: &contract}, rpc.LatestBlockNumber, &overrides, nil)
+	if err != nil {
+		t.Fatalf("failed to call with overrides: %v", err)
+	}
+	if have := word(out, 0).Big(); have.Int64() != 12345 {
+		t.Errorf("balance override mismatch: have %v, want 12345", have)
+	}
+	// Nonce overrides determine the address of created contracts
+	code = createCode
+	overrides = ethapi.StateOverride{contract: {Code: &code, Nonce: &nonce}}
+	out, err = api.Call(context.Background(), ethapi.CallArgs{From: testAddr, To: &contract}, rpc.LatestBlockNumber, &overrides, nil)
+	if err != nil {
+		t.Fatalf("failed to call with overrides: %v", err)
+	}
+	if have, want := common.BytesToAddress(word(out, 0).Bytes()), crypto.CreateAddress(contract, 5); have != want {
+		t.Errorf("nonce override mismatch: created %x, want %x", have, want)
+	}
+	// The overrides are never persisted
+	state, _, _ := ethServ.ApiBackend.StateAndHeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	if len(state.GetCode(contract)) != 0 || state.GetNonce(contract) != 0 || state.GetBalance(contract).Sign() != 0 {
+		t.Errorf("overrides leaked into the chain state")
+	}
+}
+
+// Tests that 'state' replaces the entire storage of an account while 'stateDiff'
+// only replaces the given slots, and that the two can't be combined.
+func TestCallStorageOverrides(t *testing.T) { log.DebugLog()
+	contract := common.HexToAddress("0xc0de")
+	stack, ethServ := newTestBackend(t, core.GenesisAlloc{contract: {
+		Code:    storageCode,
+		Balance: big.NewInt(0),
+		Storage: map[common.Hash]common.Hash{
+			common.BigToHash(big.NewInt(1)): common.HexToHash("0x11"),
+			common.BigToHash(big.NewInt(2)): common.HexToHash("0x22"),
+		},
+	}})
+	defer stack.Stop()
+	api := ethapi.NewPublicBlockChainAPI(ethServ.ApiBackend)
+
+	slots := &map[common.Hash]common.Hash{common.BigToHash(big.NewInt(1)): common.HexToHash("0xaa")}
+	tests := []struct {
+		override    ethapi.OverrideAccount
+		slot1       common.Hash
+		slot2       common.Hash
+		shouldError bool
+	}{
+		{ethapi.OverrideAccount{}, common.HexToHash("0x11"), common.HexToHash("0x22"), false},
+		{ethapi.OverrideAccount{StateDiff: slots}, common.HexToHash("0xaa"), common.HexToHash("0x22"), false},
+		{ethapi.OverrideAccount{State: slots}, common.HexToHash("0xaa"), common.Hash{}, false},
+		{ethapi.OverrideAccount{State: slots, StateDiff: slots}, common.Hash{}, common.Hash{}, true},
+	}
+	for i, tt := range tests {
+		overrides := ethapi.StateOverride{contract: tt.override}
+		out, err := api.Call(context.Background(), ethapi.CallArgs{From: testAddr, To: &contract}, rpc.LatestBlockNumber, &overrides, nil)
+		if tt.shouldError {
+			if err == nil {
+				t.Errorf("test %d: conflicting overrides accepted", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: call failed: %v", i, err)
+			continue
+		}
+		if have := word(out, 0); have != tt.slot1 {
+			t.Errorf("test %d: slot 1 mismatch: have %x, want %x", i, have, tt.slot1)
+		}
+		if have := word(out, 1); have != tt.slot2 {
+			t.Errorf("test %d: slot 2 mismatch: have %x, want %x", i, have, tt.slot2)
+		}
+	}
+}
+
+// Tests that the block number, time and coinbase can be overridden in calls.
+func TestCallBlockOverrides(t *testing.T) { log.DebugLog()
+	contract := common.HexToAddress("0xc0de")
+	stack, ethServ := newTestBackend(t, core.GenesisAlloc{contract: {Code: contextCode, Balance: big.NewInt(0)}})
+	defer stack.Stop()
+	api := ethapi.NewPublicBlockChainAPI(ethServ.ApiBackend)
+
+	var (
+		number   = (*hexutil.Big)(big.NewInt(100))
+		time     = (*hexutil.Big)(big.NewInt(12345))
+		coinbase = common.HexToAddress("0xc014ba5e")
+	)
+	out, err := api.Call(context.Background(), ethapi.CallArgs{From: testAddr, To: &contract}, rpc.LatestBlockNumber, nil, &ethapi.BlockOverrides{Number: number, Time: time, Coinbase: &coinbase})
+	if err != nil {
+		t.Fatalf("failed to call with block overrides: %v", err)
+	}
+	if have := word(out, 0).Big(); have.Cmp(number.ToInt()) != 0 {
+		t.Errorf("block number mismatch: have %v, want %v", have, number)
+	}
+	if have := word(out, 1).Big(); have.Cmp(time.ToInt()) != 0 {
+		t.Errorf("block time mismatch: have %v, want %v", have, time)
+	}
+	if have := common.BytesToAddress(word(out, 2).Bytes()); have != coinbase {
+		t.Errorf("coinbase mismatch: have %x, want %x", have, coinbase)
+	}
+	// Without overrides the genesis block is used
+	out, err = api.Call(context.Background(), ethapi.CallArgs{From: testAddr, To: &contract}, rpc.LatestBlockNumber, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to call: %v", err)
+	}
+	if have := word(out, 0).Big(); have.Sign() != 0 {
+		t.Errorf("block number mismatch: have %v, want 0", have)
+	}
+}
+
+// Tests that gas estimations take the state overrides into account.
+func TestEstimateGasOverrides(t *testing.T) { log.DebugLog()
+	stack, ethServ := newTestBackend(t, nil)
+	defer stack.Stop()
+	api := ethapi.NewPublicBlockChainAPI(ethServ.ApiBackend)
+
+	contract := common.HexToAddress("0xc0de")
+	plain, err := api.EstimateGas(context.Background(), ethapi.CallArgs{From: testAddr, To: &contract}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to estimate plain transfer: %v", err)
+	}
+	if plain != hexutil.Uint64(params.TxGas) {
+		t.Errorf("plain transfer estimate mismatch: have %d, want %d", plain, params.TxGas)
+	}
+	code := hexutil.Bytes(sstoreCode)
+	overrides := ethapi.StateOverride{contract: {Code: &code}}
+	store, err := api.EstimateGas(context.Background(), ethapi.CallArgs{From: testAddr, To: &contract}, &overrides, nil)
+	if err != nil {
+		t.Fatalf("failed to estimate with code override: %v", err)
+	}
+	if want := hexutil.Uint64(params.TxGas + params.SstoreSetGas); store < want {
+		t.Errorf("code override estimate too low: have %d, want at least %d", store, want)
+	}
+	slots := &map[common.Hash]common.Hash{}
+	overrides = ethapi.StateOverride{contract: {State: slots, StateDiff: slots}}
+	_, err = api.EstimateGas(context.Background(), ethapi.CallArgs{From: testAddr, To: &contract}, &overrides, nil)
+	if err == nil || !strings.Contains(err.Error(), "stateDiff") {
+		t.Errorf("conflicting overrides error mismatch: have %v, want the override error", err)
+	}
+}
+
+// Tests that the gas limit of the block overrides raises the ceiling of the gas
+// estimation above the limit of the pending block.
+func TestEstimateGasBlockOverrides(t *testing.T) { log.DebugLog()
+	stack, ethServ := newTestBackend(t, nil)
+	defer stack.Stop()
+	api := ethapi.NewPublicBlockChainAPI(ethServ.ApiBackend)
+
+	// The contract fails unless called with at least 6M gas left, above the
+	// gas limit of the pending block
+	contract := common.HexToAddress("0xc0de")
+	code := hexutil.Bytes(gasHungryCode)
+	overrides := ethapi.StateOverride{contract: {Code: &code}}
+
+	if _, err := api.EstimateGas(context.Background(), ethapi.CallArgs{From: testAddr, To: &contract}, &overrides, nil); err == nil {
+		t.Fatalf("estimation above the pending gas limit succeeded")
+	}
+	limit := hexutil.Uint64(10000000)
+	gas, err := api.EstimateGas(context.Background(), ethapi.CallArgs{From: testAddr, To: &contract}, &overrides, &ethapi.BlockOverrides{GasLimit: &limit})
+	if err != nil {
+		t.Fatalf("failed to estimate with gas limit override: %v", err)
+	}
+	if gas < 6000000 || gas > limit {
+		t.Errorf("estimate mismatch: have %d, want between 6000000 and %d", gas, limit)
+	}
+}
@@ -70,6 +70,7 @@ type Backend interface {
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
+	RPCGasCap() uint64 // global gas cap for eth_call over rpc: DoS protection
 }
 
 func GetAPIs(apiBackend Backend) []rpc.API {
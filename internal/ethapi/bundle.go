@@ -0,0 +1,341 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// defaultBundleTimeout is the amount of time an entire bundle can execute by
+	// default before being forcefully aborted.
+	defaultBundleTimeout = 5 * time.Second
+
+	// maxBundleSize is the maximum number of transactions that may be simulated
+	// in a single bundle.
+	maxBundleSize = 256
+)
+
+// BundleTx is a single entry of a transaction bundle. It is either a signed
+// transaction in its raw RLP encoding, or a set of call arguments; in the latter
+// case the nonce of the sender is not checked, the gas price defaults to zero and
+// the gas allowance defaults to all the gas left in the block.
+type BundleTx struct {
+	CallArgs
+	Raw *hexutil.Bytes `json:"raw"`
+}
+
+// BundleConfig holds the optional parameters of a bundle simulation.
+type BundleConfig struct {
+	StateOverrides *StateOverride  `json:"stateOverrides"` // Account overrides to apply before the first transaction
+	BlockOverrides *BlockOverrides `json:"blockOverrides"` // Block context overrides to execute the bundle in
+	Tracer         *string         `json:"tracer"`         // Tracer to attach to every transaction of the bundle
+	TracerConfig   json.RawMessage `json:"tracerConfig"`   // Configuration of the native tracer, if any
+	Timeout        *string         `json:"timeout"`        // Time limit of the entire bundle execution
+}
+
+// BundleTxResult is the outcome of a single transaction of a bundle.
+type BundleTxResult struct {
+	TxHash          *common.Hash    `json:"txHash,omitempty"`          // Hash of the transaction, if a signed one
+	From            common.Address  `json:"from"`                      // Sender of the transaction
+	To              *common.Address `json:"to"`                        // Recipient of the transaction, nil for creations
+	ContractAddress *common.Address `json:"contractAddress,omitempty"` // Address of the deployed contract, if a creation
+	GasUsed         hexutil.Uint64  `json:"gasUsed"`                   // Gas consumed by the transaction
+	ReturnValue     hexutil.Bytes   `json:"returnValue"`               // Return data, or the revert data if failed
+	Logs            []*types.Log    `json:"logs"`                      // Logs emitted by the transaction
+	Error           string          `json:"error,omitempty"`           // Execution failure (with the revert reason), if any
+	Trace           json.RawMessage `json:"trace,omitempty"`           // Trace produced by the attached tracer
+}
+
+// BundleAccount is the state of an account before or after executing a bundle.
+// Only the fields modified by the bundle are filled in.
+type BundleAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   *hexutil.Uint64             `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// BundleResult is the outcome of simulating a transaction bundle.
+type BundleResult struct {
+	BlockNumber hexutil.Uint64                    `json:"blockNumber"` // Number of the block the bundle was executed in
+	GasUsed     hexutil.Uint64                    `json:"gasUsed"`     // Total gas consumed by the bundle
+	Results     []*BundleTxResult                 `json:"results"`     // Individual results of the transactions
+	Pre         map[common.Address]*BundleAccount `json:"pre"`         // Modified accounts before the bundle
+	Post        map[common.Address]*BundleAccount `json:"post"`        // Modified accounts after the bundle
+}
+
+// CallBundle executes an ordered list of transactions on top of the state of the
+// given block, each one seeing the effects of the previous ones. Nothing is ever
+// broadcast or persisted.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, txs []BundleTx, blockNr rpc.BlockNumber, config *BundleConfig) (*BundleResult, error) {
+	log.DebugLog()
+	return simulateBundle(ctx, s.b, txs, blockNr, config)
+}
+
+// Simulate is an alias of eth_callBundle in the debug namespace.
+func (api *PublicDebugAPI) Simulate(ctx context.Context, txs []BundleTx, blockNr rpc.BlockNumber, config *BundleConfig) (*BundleResult, error) {
+	log.DebugLog()
+	return simulateBundle(ctx, api.b, txs, blockNr, config)
+}
+
+// simulateBundle executes the given transactions one after the other on a single
+// evolving state, collecting their results and the resulting state diff. As with
+// a real block, the entire bundle may not consume more than the block gas limit.
+func simulateBundle(ctx context.Context, b Backend, txs []BundleTx, blockNr rpc.BlockNumber, config *BundleConfig) (*BundleResult, error) {
+	log.DebugLog()
+	defer func(start time.Time) {
+		log.Debug("Executing EVM bundle finished", "txs", len(txs), "runtime", time.Since(start))
+	}(time.Now())
+
+	if len(txs) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	if len(txs) > maxBundleSize {
+		return nil, fmt.Errorf("bundle too large: %d > %d", len(txs), maxBundleSize)
+	}
+	if config == nil {
+		config = new(BundleConfig)
+	}
+	timeout := defaultBundleTimeout
+	if config.Timeout != nil {
+		var err error
+		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	statedb, header, err := b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	header = config.BlockOverrides.Apply(header)
+	if err := config.StateOverrides.Apply(statedb); err != nil {
+		return nil, err
+	}
+	// Convert all the transactions into messages before touching anything
+	var (
+		chainConfig = b.ChainConfig()
+		signer      = types.MakeSigner(chainConfig, header.Number)
+		msgs        = make([]types.Message, len(txs))
+		hashes      = make([]*common.Hash, len(txs))
+	)
+	for i, tx := range txs {
+		if tx.Raw != nil {
+			signed := new(types.Transaction)
+			if err := rlp.DecodeBytes(*tx.Raw, signed); err != nil {
+				return nil, fmt.Errorf("transaction %d: %v", i, err)
+			}
+			if msgs[i], err = signed.AsMessage(signer); err != nil {
+				return nil, fmt.Errorf("transaction %d: %v", i, err)
+			}
+			hash := signed.Hash()
+			hashes[i] = &hash
+			continue
+		}
+		gas := uint64(tx.Gas)
+		if gas == 0 {
+			gas = header.GasLimit
+		}
+		msgs[i] = types.NewMessage(tx.From, tx.To, 0, tx.Value.ToInt(), gas, tx.GasPrice.ToInt(), tx.Data, false)
+	}
+	// Retrieve the block context to execute in. The backend funds the sender of
+	// the message for plain calls, so do that on a throwaway state.
+	evm, _, err := b.GetEVM(ctx, msgs[0], statedb.Copy(), header, vm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	vmctx := evm.Context
+	if config.BlockOverrides != nil && config.BlockOverrides.Coinbase != nil {
+		vmctx.Coinbase = *config.BlockOverrides.Coinbase
+	}
+	// Execute the transactions and gather their individual results
+	var (
+		pre    = statedb.Copy()
+		gp     = new(core.GasPool).AddGas(header.GasLimit)
+		result = &BundleResult{BlockNumber: hexutil.Uint64(header.Number.Uint64())}
+	)
+	for i, msg := range msgs {
+		if txs[i].Raw == nil && txs[i].Gas == 0 {
+			msg = types.NewMessage(msg.From(), msg.To(), 0, msg.Value(), gp.Gas(), msg.GasPrice(), msg.Data(), false)
+		}
+		res, err := simulateBundleTx(ctx, vmctx, statedb, chainConfig, msg, hashes[i], i, gp, config)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		statedb.Finalise(chainConfig.IsEIP158(header.Number))
+
+		result.Results = append(result.Results, res)
+		result.GasUsed += res.GasUsed
+	}
+	if err := statedb.Error(); err != nil {
+		return nil, err
+	}
+	result.Pre, result.Post = bundleStateDiff(pre, statedb)
+	return result, nil
+}
+
+// simulateBundleTx executes a single message of a bundle on top of the given state,
+// drawing its gas from the block gas pool shared by the bundle.
+func simulateBundleTx(ctx context.Context, vmctx vm.Context, statedb *state.StateDB, chainConfig *params.ChainConfig, msg types.Message, hash *common.Hash, index int, gp *core.GasPool, config *BundleConfig) (*BundleTxResult, error) {
+	log.DebugLog()
+	// Attach the tracer if requested and create the EVM to execute with
+	var (
+		tracer tracers.Tracer
+		vmConf vm.Config
+		err    error
+	)
+	if config.Tracer != nil {
+		if tracer, err = tracers.New(*config.Tracer, config.TracerConfig); err != nil {
+			return nil, err
+		}
+		vmConf = vm.Config{Debug: true, Tracer: tracer}
+	}
+	vmctx.Origin, vmctx.GasPrice = msg.From(), new(big.Int).Set(msg.GasPrice())
+	evm := vm.NewEVM(vmctx, statedb, chainConfig, vmConf)
+
+	// Abort the execution if the bundle runs out of time
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			evm.Cancel()
+			if tracer != nil {
+				tracer.Stop(errors.New("execution timeout"))
+			}
+		case <-done:
+		}
+	}()
+	// Logs are indexed by transaction hash, so plain calls get a unique placeholder
+	thash := common.BigToHash(big.NewInt(int64(index)))
+	if hash != nil {
+		thash = *hash
+	}
+	statedb.Prepare(thash, common.Hash{}, index)
+
+	nonce := statedb.GetNonce(msg.From())
+	result, err := core.ApplyMessage(evm, msg, gp)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", ctx.Err())
+	}
+	res := &BundleTxResult{
+		TxHash:      hash,
+		From:        msg.From(),
+		To:          msg.To(),
+		GasUsed:     hexutil.Uint64(result.UsedGas),
+		ReturnValue: result.ReturnData,
+		Logs:        statedb.GetLogs(thash),
+	}
+	if res.Logs == nil {
+		res.Logs = []*types.Log{}
+	}
+	if hash == nil {
+		for _, l := range res.Logs {
+			l.TxHash = common.Hash{}
+		}
+	}
+	if msg.To() == nil {
+		addr := crypto.CreateAddress(msg.From(), nonce)
+		res.ContractAddress = &addr
+	}
+	switch {
+	case result.Err == vm.ErrExecutionReverted:
+		res.Error = abi.NewRevertError(result.Revert()).Error()
+	case result.Failed():
+		res.Error = result.Err.Error()
+	}
+	if tracer != nil {
+		if res.Trace, err = tracer.GetResult(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// bundleStateDiff collects the fields of all the accounts that differ between the
+// state before and after executing a bundle.
+func bundleStateDiff(pre, post *state.StateDB) (map[common.Address]*BundleAccount, map[common.Address]*BundleAccount) {
+	log.DebugLog()
+	var (
+		preDiff  = make(map[common.Address]*BundleAccount)
+		postDiff = make(map[common.Address]*BundleAccount)
+	)
+	for addr, slots := range post.DirtyAccounts() {
+		var (
+			before  = new(BundleAccount)
+			after   = new(BundleAccount)
+			changed bool
+		)
+		if preBal, postBal := pre.GetBalance(addr), post.GetBalance(addr); preBal.Cmp(postBal) != 0 {
+			before.Balance, after.Balance = (*hexutil.Big)(preBal), (*hexutil.Big)(postBal)
+			changed = true
+		}
+		if preNonce, postNonce := pre.GetNonce(addr), post.GetNonce(addr); preNonce != postNonce {
+			before.Nonce, after.Nonce = (*hexutil.Uint64)(&preNonce), (*hexutil.Uint64)(&postNonce)
+			changed = true
+		}
+		if preCode, postCode := pre.GetCode(addr), post.GetCode(addr); !bytes.Equal(preCode, postCode) {
+			before.Code, after.Code = preCode, postCode
+			changed = true
+		}
+		for _, key := range slots {
+			if preVal, postVal := pre.GetState(addr, key), post.GetState(addr, key); preVal != postVal {
+				if before.Storage == nil {
+					before.Storage, after.Storage = make(map[common.Hash]common.Hash), make(map[common.Hash]common.Hash)
+				}
+				before.Storage[key], after.Storage[key] = preVal, postVal
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if pre.Exist(addr) {
+			preDiff[addr] = before
+		}
+		if post.Exist(addr) {
+			postDiff[addr] = after
+		}
+	}
+	return preDiff, postDiff
+}
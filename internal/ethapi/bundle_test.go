@@ -0,0 +1,300 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// counterCode increments the storage slot 0 and returns its new value.
+	counterCode = common.FromHex("0x6000546001018060005560005260206000f3")
+
+	// revertCode reverts with the reason "oops".
+	revertCode = common.FromHex("0x6064600c60003960646000fd" +
+		"08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"6f6f707300000000000000000000000000000000000000000000000000000000")
+
+	// loopCode never terminates.
+	loopCode = common.FromHex("0x5b600056")
+
+	counterAddr = common.HexToAddress("0xc0de")
+	revertAddr  = common.HexToAddress("0xdead")
+	loopAddr    = common.HexToAddress("0x1009")
+)
+
+// newBundleBackend starts a test backend with the bundle test contracts deployed.
+func newBundleBackend(t *testing.T) (*ethapi.PublicBlockChainAPI, func()) { log.DebugLog()
+	stack, ethServ := newTestBackend(t, core.GenesisAlloc{
+		counterAddr: {Code: counterCode, Balance: big.NewInt(0)},
+		revertAddr:  {Code: revertCode, Balance: big.NewInt(0)},
+		loopAddr:    {Code: loopCode, Balance: big.NewInt(0)},
+	})
+	return ethapi.NewPublicBlockChainAPI(ethServ.ApiBackend), func() { stack.Stop() }
+}
+
+// signBundleTx signs a transaction of the test account and returns it in the
+// raw format of the bundle entries.
+func signBundleTx(t *testing.T, tx *types.Transaction) (*types.Transaction, ethapi.BundleTx) { log.DebugLog()
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(params.AllEthashProtocolChanges.ChainId), testKey)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	blob, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		t.Fatalf("failed to encode transaction: %v", err)
+	}
+	raw := hexutil.Bytes(blob)
+	return signed, ethapi.BundleTx{Raw: &raw}
+}
+
+// Tests that the transactions of a bundle are executed in order, each seeing the
+// state changes of the previous ones, and that the state diff reflects the net
+// effect of the entire bundle.
+func TestCallBundleOrdering(t *testing.T) { log.DebugLog()
+	api, stop := newBundleBackend(t)
+	defer stop()
+
+	call := ethapi.BundleTx{CallArgs: ethapi.CallArgs{From: testAddr, To: &counterAddr}}
+	res, err := api.CallBundle(context.Background(), []ethapi.BundleTx{call, call, call}, rpc.LatestBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if len(res.Results) != 3 {
+		t.Fatalf("result count mismatch: have %d, want 3", len(res.Results))
+	}
+	var gas hexutil.Uint64
+	for i, r := range res.Results {
+		if r.Error != "" {
+			t.Errorf("tx %d: unexpected failure: %v", i, r.Error)
+		}
+		if have := word(r.ReturnValue, 0).Big(); have.Int64() != int64(i+1) {
+			t.Errorf("tx %d: counter mismatch: have %v, want %d", i, have, i+1)
+		}
+		if r.TxHash != nil {
+			t.Errorf("tx %d: call got a transaction hash", i)
+		}
+		gas += r.GasUsed
+	}
+	if res.GasUsed != gas {
+		t.Errorf("total gas mismatch: have %d, want %d", res.GasUsed, gas)
+	}
+	// The state diff only contains the net changes of the bundle
+	slot := common.Hash{}
+	if pre, ok := res.Pre[counterAddr]; !ok || pre.Storage[slot] != (common.Hash{}) {
+		t.Errorf("pre state of counter mismatch: %+v", pre)
+	}
+	if post, ok := res.Post[counterAddr]; !ok || post.Storage[slot] != common.BigToHash(big.NewInt(3)) {
+		t.Errorf("post state of counter mismatch: %+v", post)
+	}
+	if post, ok := res.Post[counterAddr]; ok && (post.Balance != nil || post.Nonce != nil || post.Code != nil) {
+		t.Errorf("unmodified counter fields reported: %+v", post)
+	}
+	if pre, post := res.Pre[testAddr], res.Post[testAddr]; pre == nil || post == nil || uint64(*pre.Nonce) != 0 || uint64(*post.Nonce) != 3 {
+		t.Errorf("sender nonce diff mismatch: pre %+v, post %+v", pre, post)
+	}
+	// Nothing may be persisted
+	res, err = api.CallBundle(context.Background(), []ethapi.BundleTx{call}, rpc.LatestBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if have := word(res.Results[0].ReturnValue, 0).Big(); have.Int64() != 1 {
+		t.Errorf("bundle state leaked: counter %v, want 1", have)
+	}
+}
+
+// Tests that signed transactions are checked and executed as they are, mixed with
+// plain calls.
+func TestCallBundleRawTransactions(t *testing.T) { log.DebugLog()
+	api, stop := newBundleBackend(t)
+	defer stop()
+
+	var (
+		recipient = common.HexToAddress("0xb0b")
+		price     = big.NewInt(params.Shannon)
+	)
+	transfer, raw1 := signBundleTx(t, types.NewTransaction(0, recipient, big.NewInt(params.Ether), params.TxGas, price, nil))
+	_, raw2 := signBundleTx(t, types.NewTransaction(1, counterAddr, big.NewInt(0), 100000, price, nil))
+	call := ethapi.BundleTx{CallArgs: ethapi.CallArgs{From: testAddr, To: &counterAddr}}
+
+	res, err := api.CallBundle(context.Background(), []ethapi.BundleTx{raw1, raw2, call}, rpc.LatestBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if have := res.Results[0].TxHash; have == nil || *have != transfer.Hash() {
+		t.Errorf("transaction hash mismatch: have %v, want %x", have, transfer.Hash())
+	}
+	if res.Results[0].From != testAddr {
+		t.Errorf("sender mismatch: have %x, want %x", res.Results[0].From, testAddr)
+	}
+	if res.Results[2].TxHash != nil {
+		t.Errorf("call got a transaction hash")
+	}
+	if have := word(res.Results[2].ReturnValue, 0).Big(); have.Int64() != 2 {
+		t.Errorf("counter mismatch: have %v, want 2", have)
+	}
+	// The recipient is created by the bundle, the sender pays for value and gas
+	if _, ok := res.Pre[recipient]; ok {
+		t.Errorf("new recipient in the pre state")
+	}
+	if post := res.Post[recipient]; post == nil || post.Balance.ToInt().Cmp(big.NewInt(params.Ether)) != 0 {
+		t.Errorf("recipient post state mismatch: %+v", post)
+	}
+	fees := new(big.Int).Mul(price, new(big.Int).SetUint64(uint64(res.Results[0].GasUsed+res.Results[1].GasUsed)))
+	want := new(big.Int).Sub(testBalance, new(big.Int).Add(big.NewInt(params.Ether), fees))
+	if post := res.Post[testAddr]; post == nil || post.Balance.ToInt().Cmp(want) != 0 {
+		t.Errorf("sender post state mismatch: %+v, want balance %v", post, want)
+	}
+	// Signed transactions with invalid nonces are rejected
+	_, stale := signBundleTx(t, types.NewTransaction(5, recipient, big.NewInt(1), params.TxGas, price, nil))
+	if _, err := api.CallBundle(context.Background(), []ethapi.BundleTx{raw1, stale}, rpc.LatestBlockNumber, nil); err == nil {
+		t.Errorf("invalid nonce accepted")
+	}
+}
+
+// Tests that reverts are reported with their reason without aborting the bundle
+// or leaking the state changes of the reverted transaction.
+func TestCallBundleRevert(t *testing.T) { log.DebugLog()
+	api, stop := newBundleBackend(t)
+	defer stop()
+
+	txs := []ethapi.BundleTx{
+		{CallArgs: ethapi.CallArgs{From: testAddr, To: &revertAddr}},
+		{CallArgs: ethapi.CallArgs{From: testAddr, To: &counterAddr}},
+	}
+	res, err := api.CallBundle(context.Background(), txs, rpc.LatestBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if have, want := res.Results[0].Error, "execution reverted: oops"; have != want {
+		t.Errorf("revert error mismatch: have %q, want %q", have, want)
+	}
+	if len(res.Results[0].ReturnValue) != 100 {
+		t.Errorf("revert data length mismatch: have %d, want 100", len(res.Results[0].ReturnValue))
+	}
+	if res.Results[1].Error != "" {
+		t.Errorf("transaction after revert failed: %v", res.Results[1].Error)
+	}
+	if _, ok := res.Post[revertAddr]; ok {
+		t.Errorf("reverted contract reported as modified")
+	}
+}
+
+// Tests that the requested tracer is attached to every transaction of a bundle.
+func TestCallBundleTracer(t *testing.T) { log.DebugLog()
+	api, stop := newBundleBackend(t)
+	defer stop()
+
+	txs := []ethapi.BundleTx{
+		{CallArgs: ethapi.CallArgs{From: testAddr, To: &counterAddr}},
+		{CallArgs: ethapi.CallArgs{From: testAddr, To: &revertAddr}},
+	}
+	tracer := "callTracer"
+	res, err := api.CallBundle(context.Background(), txs, rpc.LatestBlockNumber, &ethapi.BundleConfig{Tracer: &tracer})
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	for i, r := range res.Results {
+		var frame struct {
+			Type  string `json:"type"`
+			To    string `json:"to"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(r.Trace, &frame); err != nil {
+			t.Fatalf("tx %d: failed to decode trace %s: %v", i, r.Trace, err)
+		}
+		if frame.Type != "CALL" || common.HexToAddress(frame.To) != *txs[i].To {
+			t.Errorf("tx %d: trace mismatch: %s", i, r.Trace)
+		}
+		if (frame.Error != "") != (r.Error != "") {
+			t.Errorf("tx %d: trace error mismatch: have %q, want failure %v", i, frame.Error, r.Error != "")
+		}
+	}
+	// Without a tracer no traces are produced
+	res, err = api.CallBundle(context.Background(), txs, rpc.LatestBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	for i, r := range res.Results {
+		if r.Trace != nil {
+			t.Errorf("tx %d: untraced bundle produced trace %s", i, r.Trace)
+		}
+	}
+	// Unknown tracers are rejected
+	tracer = "notATracer"
+	if _, err := api.CallBundle(context.Background(), txs, rpc.LatestBlockNumber, &ethapi.BundleConfig{Tracer: &tracer}); err == nil {
+		t.Errorf("invalid tracer accepted")
+	}
+}
+
+// Tests that bundles exceeding their time limit are aborted.
+func TestCallBundleTimeout(t *testing.T) { log.DebugLog()
+	api, stop := newBundleBackend(t)
+	defer stop()
+
+	var (
+		timeout  = "10ms"
+		gasLimit = hexutil.Uint64(1 << 40)
+		txs      = []ethapi.BundleTx{{CallArgs: ethapi.CallArgs{From: testAddr, To: &loopAddr}}}
+		config   = &ethapi.BundleConfig{Timeout: &timeout, BlockOverrides: &ethapi.BlockOverrides{GasLimit: &gasLimit}}
+	)
+	_, err := api.CallBundle(context.Background(), txs, rpc.LatestBlockNumber, config)
+	if err == nil || !strings.Contains(err.Error(), "execution aborted") {
+		t.Errorf("timeout error mismatch: have %v, want execution aborted", err)
+	}
+}
+
+// Tests that a bundle can't consume more gas than the block gas limit.
+func TestCallBundleGasLimit(t *testing.T) { log.DebugLog()
+	api, stop := newBundleBackend(t)
+	defer stop()
+
+	// Plain calls default to the gas left in the block
+	call := ethapi.BundleTx{CallArgs: ethapi.CallArgs{From: testAddr, To: &loopAddr}}
+	res, err := api.CallBundle(context.Background(), []ethapi.BundleTx{call}, rpc.LatestBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if res.GasUsed != hexutil.Uint64(params.GenesisGasLimit) {
+		t.Errorf("gas used mismatch: have %d, want %d", res.GasUsed, params.GenesisGasLimit)
+	}
+	// Explicit allowances above the gas left in the block are rejected
+	call.Gas = hexutil.Uint64(params.GenesisGasLimit + 1)
+	if _, err := api.CallBundle(context.Background(), []ethapi.BundleTx{call}, rpc.LatestBlockNumber, nil); err == nil {
+		t.Errorf("gas allowance above the block gas limit accepted")
+	}
+	counter := ethapi.BundleTx{CallArgs: ethapi.CallArgs{From: testAddr, To: &counterAddr, Gas: hexutil.Uint64(params.GenesisGasLimit - 30000)}}
+	if _, err := api.CallBundle(context.Background(), []ethapi.BundleTx{counter, counter}, rpc.LatestBlockNumber, nil); err == nil {
+		t.Errorf("bundle above the block gas limit accepted")
+	}
+}
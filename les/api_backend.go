@@ -48,6 +48,11 @@ func (b *LesApiBackend) ChainConfig() *params.ChainConfig {
 	return b.eth.chainConfig
 }
 
+func (b *LesApiBackend) RPCGasCap() uint64 {
+	log.DebugLog()
+	return b.eth.config.RPCGasCap
+}
+
 func (b *LesApiBackend) CurrentBlock() *types.Block {
 	log.DebugLog()
 	return types.NewBlockWithHeader(b.eth.BlockChain().CurrentHeader())
@@ -18,6 +18,8 @@ package les
 
 import (
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func TestExecQueue(t *testing.T) { log.DebugLog()
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -46,8 +47,8 @@ func expectResponse(r p2p.MsgReader, msgcode, reqID, bv uint64, data interface{}
 }
 
 // Tests that block headers can be retrieved from a remote chain based on user queries.
-func TestGetBlockHeadersLes1(t *testing.T) { log.DebugLog() testGetBlockHeaders(t, 1) }
-func TestGetBlockHeadersLes2(t *testing.T) { log.DebugLog() testGetBlockHeaders(t, 2) }
+func TestGetBlockHeadersLes1(t *testing.T) { log.DebugLog(); testGetBlockHeaders(t, 1) }
+func TestGetBlockHeadersLes2(t *testing.T) { log.DebugLog(); testGetBlockHeaders(t, 2) }
 
 func testGetBlockHeaders(t *testing.T, protocol int) { log.DebugLog()
 	db, _ := ethdb.NewMemDatabase()
@@ -176,8 +177,8 @@ func testGetBlockHeaders(t *testing.T, protocol int) { log.DebugLog()
 }
 
 // Tests that block contents can be retrieved from a remote chain based on their hashes.
-func TestGetBlockBodiesLes1(t *testing.T) { log.DebugLog() testGetBlockBodies(t, 1) }
-func TestGetBlockBodiesLes2(t *testing.T) { log.DebugLog() testGetBlockBodies(t, 2) }
+func TestGetBlockBodiesLes1(t *testing.T) { log.DebugLog(); testGetBlockBodies(t, 1) }
+func TestGetBlockBodiesLes2(t *testing.T) { log.DebugLog(); testGetBlockBodies(t, 2) }
 
 func testGetBlockBodies(t *testing.T, protocol int) { log.DebugLog()
 	db, _ := ethdb.NewMemDatabase()
@@ -253,8 +254,8 @@ func testGetBlockBodies(t *testing.T, protocol int) { log.DebugLog()
 }
 
 // Tests that the contract codes can be retrieved based on account addresses.
-func TestGetCodeLes1(t *testing.T) { log.DebugLog() testGetCode(t, 1) }
-func TestGetCodeLes2(t *testing.T) { log.DebugLog() testGetCode(t, 2) }
+func TestGetCodeLes1(t *testing.T) { log.DebugLog(); testGetCode(t, 1) }
+func TestGetCodeLes2(t *testing.T) { log.DebugLog(); testGetCode(t, 2) }
 
 func testGetCode(t *testing.T, protocol int) { log.DebugLog()
 	// Assemble the test environment
@@ -287,8 +288,8 @@ func testGetCode(t *testing.T, protocol int) { log.DebugLog()
 }
 
 // Tests that the transaction receipts can be retrieved based on hashes.
-func TestGetReceiptLes1(t *testing.T) { log.DebugLog() testGetReceipt(t, 1) }
-func TestGetReceiptLes2(t *testing.T) { log.DebugLog() testGetReceipt(t, 2) }
+func TestGetReceiptLes1(t *testing.T) { log.DebugLog(); testGetReceipt(t, 1) }
+func TestGetReceiptLes2(t *testing.T) { log.DebugLog(); testGetReceipt(t, 2) }
 
 func testGetReceipt(t *testing.T, protocol int) { log.DebugLog()
 	// Assemble the test environment
@@ -315,8 +316,8 @@ func testGetReceipt(t *testing.T, protocol int) { log.DebugLog()
 }
 
 // Tests that trie merkle proofs can be retrieved
-func TestGetProofsLes1(t *testing.T) { log.DebugLog() testGetProofs(t, 1) }
-func TestGetProofsLes2(t *testing.T) { log.DebugLog() testGetProofs(t, 2) }
+func TestGetProofsLes1(t *testing.T) { log.DebugLog(); testGetProofs(t, 1) }
+func TestGetProofsLes2(t *testing.T) { log.DebugLog(); testGetProofs(t, 2) }
 
 func testGetProofs(t *testing.T, protocol int) { log.DebugLog()
 	// Assemble the test environment
@@ -373,8 +374,8 @@ func testGetProofs(t *testing.T, protocol int) { log.DebugLog()
 }
 
 // Tests that CHT proofs can be correctly retrieved.
-func TestGetCHTProofsLes1(t *testing.T) { log.DebugLog() testGetCHTProofs(t, 1) }
-func TestGetCHTProofsLes2(t *testing.T) { log.DebugLog() testGetCHTProofs(t, 2) }
+func TestGetCHTProofsLes1(t *testing.T) { log.DebugLog(); testGetCHTProofs(t, 1) }
+func TestGetCHTProofsLes2(t *testing.T) { log.DebugLog(); testGetCHTProofs(t, 2) }
 
 func testGetCHTProofs(t *testing.T, protocol int) { log.DebugLog()
 	// Figure out the client's CHT frequency
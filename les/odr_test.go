@@ -32,15 +32,16 @@ import (
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
 type odrTestFn func(ctx context.Context, db ethdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte
 
-func TestOdrGetBlockLes1(t *testing.T) { log.DebugLog() testOdr(t, 1, 1, odrGetBlock) }
+func TestOdrGetBlockLes1(t *testing.T) { log.DebugLog(); testOdr(t, 1, 1, odrGetBlock) }
 
-func TestOdrGetBlockLes2(t *testing.T) { log.DebugLog() testOdr(t, 2, 1, odrGetBlock) }
+func TestOdrGetBlockLes2(t *testing.T) { log.DebugLog(); testOdr(t, 2, 1, odrGetBlock) }
 
 func odrGetBlock(ctx context.Context, db ethdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte { log.DebugLog()
 	var block *types.Block
@@ -56,9 +57,9 @@ func odrGetBlock(ctx context.Context, db ethdb.Database, config *params.ChainCon
 	return rlp
 }
 
-func TestOdrGetReceiptsLes1(t *testing.T) { log.DebugLog() testOdr(t, 1, 1, odrGetReceipts) }
+func TestOdrGetReceiptsLes1(t *testing.T) { log.DebugLog(); testOdr(t, 1, 1, odrGetReceipts) }
 
-func TestOdrGetReceiptsLes2(t *testing.T) { log.DebugLog() testOdr(t, 2, 1, odrGetReceipts) }
+func TestOdrGetReceiptsLes2(t *testing.T) { log.DebugLog(); testOdr(t, 2, 1, odrGetReceipts) }
 
 func odrGetReceipts(ctx context.Context, db ethdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte { log.DebugLog()
 	var receipts types.Receipts
@@ -74,9 +75,9 @@ func odrGetReceipts(ctx context.Context, db ethdb.Database, config *params.Chain
 	return rlp
 }
 
-func TestOdrAccountsLes1(t *testing.T) { log.DebugLog() testOdr(t, 1, 1, odrAccounts) }
+func TestOdrAccountsLes1(t *testing.T) { log.DebugLog(); testOdr(t, 1, 1, odrAccounts) }
 
-func TestOdrAccountsLes2(t *testing.T) { log.DebugLog() testOdr(t, 2, 1, odrAccounts) }
+func TestOdrAccountsLes2(t *testing.T) { log.DebugLog(); testOdr(t, 2, 1, odrAccounts) }
 
 func odrAccounts(ctx context.Context, db ethdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte { log.DebugLog()
 	dummyAddr := common.HexToAddress("1234567812345678123456781234567812345678")
@@ -104,15 +105,15 @@ func odrAccounts(ctx context.Context, db ethdb.Database, config *params.ChainCon
 	return res
 }
 
-func TestOdrContractCallLes1(t *testing.T) { log.DebugLog() testOdr(t, 1, 2, odrContractCall) }
+func TestOdrContractCallLes1(t *testing.T) { log.DebugLog(); testOdr(t, 1, 2, odrContractCall) }
 
-func TestOdrContractCallLes2(t *testing.T) { log.DebugLog() testOdr(t, 2, 2, odrContractCall) }
+func TestOdrContractCallLes2(t *testing.T) { log.DebugLog(); testOdr(t, 2, 2, odrContractCall) }
 
 type callmsg struct {
 	types.Message
 }
 
-func (callmsg) CheckNonce() bool { log.DebugLog() return false }
+func (callmsg) CheckNonce() bool { log.DebugLog(); return false }
 
 func odrContractCall(ctx context.Context, db ethdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte { log.DebugLog()
 	data := common.Hex2Bytes("60CD26850000000000000000000000000000000000000000000000000000000000000000")
@@ -135,8 +136,8 @@ func odrContractCall(ctx context.Context, db ethdb.Database, config *params.Chai
 
 				//vmenv := core.NewEnv(statedb, config, bc, msg, header, vm.Config{})
 				gp := new(core.GasPool).AddGas(math.MaxUint64)
-				ret, _, _, _ := core.ApplyMessage(vmenv, msg, gp)
-				res = append(res, ret...)
+				result, _ := core.ApplyMessage(vmenv, msg, gp)
+				res = append(res, result.Return()...)
 			}
 		} else {
 			header := lc.GetHeaderByHash(bhash)
@@ -146,9 +147,9 @@ func odrContractCall(ctx context.Context, db ethdb.Database, config *params.Chai
 			context := core.NewEVMContext(msg, header, lc, nil)
 			vmenv := vm.NewEVM(context, state, config, vm.Config{})
 			gp := new(core.GasPool).AddGas(math.MaxUint64)
-			ret, _, _, _ := core.ApplyMessage(vmenv, msg, gp)
+			result, _ := core.ApplyMessage(vmenv, msg, gp)
 			if state.Error() == nil {
-				res = append(res, ret...)
+				res = append(res, result.Return()...)
 			}
 		}
 	}
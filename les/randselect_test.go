@@ -19,6 +19,8 @@ package les
 import (
 	"math/rand"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 type testWrsItem struct {
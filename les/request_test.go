@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 var testBankSecureTrieKey = secAddr(testBankAddress)
@@ -37,33 +38,33 @@ func secAddr(addr common.Address) []byte { log.DebugLog()
 
 type accessTestFn func(db ethdb.Database, bhash common.Hash, number uint64) light.OdrRequest
 
-func TestBlockAccessLes1(t *testing.T) { log.DebugLog() testAccess(t, 1, tfBlockAccess) }
+func TestBlockAccessLes1(t *testing.T) { log.DebugLog(); testAccess(t, 1, tfBlockAccess) }
 
-func TestBlockAccessLes2(t *testing.T) { log.DebugLog() testAccess(t, 2, tfBlockAccess) }
+func TestBlockAccessLes2(t *testing.T) { log.DebugLog(); testAccess(t, 2, tfBlockAccess) }
 
 func tfBlockAccess(db ethdb.Database, bhash common.Hash, number uint64) light.OdrRequest { log.DebugLog()
 	return &light.BlockRequest{Hash: bhash, Number: number}
 }
 
-func TestReceiptsAccessLes1(t *testing.T) { log.DebugLog() testAccess(t, 1, tfReceiptsAccess) }
+func TestReceiptsAccessLes1(t *testing.T) { log.DebugLog(); testAccess(t, 1, tfReceiptsAccess) }
 
-func TestReceiptsAccessLes2(t *testing.T) { log.DebugLog() testAccess(t, 2, tfReceiptsAccess) }
+func TestReceiptsAccessLes2(t *testing.T) { log.DebugLog(); testAccess(t, 2, tfReceiptsAccess) }
 
 func tfReceiptsAccess(db ethdb.Database, bhash common.Hash, number uint64) light.OdrRequest { log.DebugLog()
 	return &light.ReceiptsRequest{Hash: bhash, Number: number}
 }
 
-func TestTrieEntryAccessLes1(t *testing.T) { log.DebugLog() testAccess(t, 1, tfTrieEntryAccess) }
+func TestTrieEntryAccessLes1(t *testing.T) { log.DebugLog(); testAccess(t, 1, tfTrieEntryAccess) }
 
-func TestTrieEntryAccessLes2(t *testing.T) { log.DebugLog() testAccess(t, 2, tfTrieEntryAccess) }
+func TestTrieEntryAccessLes2(t *testing.T) { log.DebugLog(); testAccess(t, 2, tfTrieEntryAccess) }
 
 func tfTrieEntryAccess(db ethdb.Database, bhash common.Hash, number uint64) light.OdrRequest { log.DebugLog()
 	return &light.TrieRequest{Id: light.StateTrieID(core.GetHeader(db, bhash, core.GetBlockNumber(db, bhash))), Key: testBankSecureTrieKey}
 }
 
-func TestCodeAccessLes1(t *testing.T) { log.DebugLog() testAccess(t, 1, tfCodeAccess) }
+func TestCodeAccessLes1(t *testing.T) { log.DebugLog(); testAccess(t, 1, tfCodeAccess) }
 
-func TestCodeAccessLes2(t *testing.T) { log.DebugLog() testAccess(t, 2, tfCodeAccess) }
+func TestCodeAccessLes2(t *testing.T) { log.DebugLog(); testAccess(t, 2, tfCodeAccess) }
 
 func tfCodeAccess(db ethdb.Database, bhash common.Hash, number uint64) light.OdrRequest { log.DebugLog()
 	header := core.GetHeader(db, bhash, core.GetBlockNumber(db, bhash))
@@ -1,20 +1,235 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
 package log
 
 import (
+	"bytes"
+	"fmt"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
-	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-stack/stack"
 )
 
+// callTracer is the global function-entry tracer backing DebugLog. It is off
+// by default, in which case every trace point costs a single atomic load.
+var callTracer = new(callTraceFilter)
+
+// callTraceFilter decides which trace points are enabled, based on a set of
+// vmodule style file and package patterns as well as function patterns, and
+// forwards the resulting records to a handler.
+type callTraceFilter struct {
+	enabled uint32 // Flag whether any trace pattern is set, atomically accessible
+
+	patterns  []pattern        // Current list of file patterns enabling trace points
+	functions []*regexp.Regexp // Current list of function patterns enabling trace points
+	siteCache map[uintptr]bool // Cache of callsite pattern evaluations
+	handler   Handler          // Handler to emit trace records to, nil for root
+	lock      sync.RWMutex     // Lock protecting the patterns, cache and handler
+}
+
+// SetCallTrace sets the pattern selecting which DebugLog trace points are
+// active. The syntax extends the one of GlogHandler.Vmodule: a comma-separated
+// list of pattern=N, where any N above zero enables tracing for all functions
+// matched by the pattern. Patterns are either
+//
+//   - file and package patterns as accepted by Vmodule, matched against the
+//     import path and file name of the traced function
+//     (e.g. "p2p/*=1", "core/blockchain.go=1", "eth/downloader=1")
+//   - function patterns, whose last path component contains a dot, matched
+//     against the package qualified function name. Pointer receivers are
+//     written without decoration and "*" matches any sequence of characters
+//     (e.g. "core.BlockChain.Insert*=1", "p2p/discover.*=1")
+//
+// An empty ruleset disables call tracing altogether.
+func SetCallTrace(ruleset string) error {
+	files, functions, err := parseCallTrace(ruleset)
+	if err != nil {
+		return err
+	}
+	callTracer.lock.Lock()
+	defer callTracer.lock.Unlock()
+
+	callTracer.patterns = files
+	callTracer.functions = functions
+	callTracer.siteCache = make(map[uintptr]bool)
+	atomic.StoreUint32(&callTracer.enabled, uint32(len(files)+len(functions)))
+
+	return nil
+}
+
+// parseCallTrace splits a call trace ruleset into its file and function rules,
+// compiling both into matchers.
+func parseCallTrace(ruleset string) ([]pattern, []*regexp.Regexp, error) {
+	var (
+		fileRules []string
+		functions []*regexp.Regexp
+	)
+	for _, rule := range strings.Split(ruleset, ",") {
+		parts := strings.Split(rule, "=")
+		name := strings.TrimSpace(parts[0])
+
+		// File rules and malformed ones are left to the vmodule parser
+		last := name[strings.LastIndex(name, "/")+1:]
+		if len(parts) != 2 || !strings.Contains(last, ".") || strings.HasSuffix(last, ".go") {
+			fileRules = append(fileRules, rule)
+			continue
+		}
+		level, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, nil, errVmoduleSyntax
+		}
+		if level <= 0 {
+			continue
+		}
+		matcher := regexp.QuoteMeta(name)
+		matcher = strings.Replace(matcher, regexp.QuoteMeta("*"), ".*", -1)
+		functions = append(functions, regexp.MustCompile("(^|/)"+matcher+"$"))
+	}
+	files, err := parseVmodule(strings.Join(fileRules, ","))
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, functions, nil
+}
+
+// functionName returns the package qualified name of the function of a call,
+// with the decoration of pointer receivers stripped, as matched by the function
+// patterns of the call tracer.
+func functionName(call stack.Call) string {
+	name := fmt.Sprintf("%+n", call)
+	return strings.NewReplacer("(*", "", "(", "", ")", "").Replace(name)
+}
+
+// SetCallTraceHandler sets the handler trace records are emitted to. Since the
+// records are already filtered by the call trace patterns, this is usually the
+// raw output handler rather than a level filtered one. If nil is passed, the
+// records are sent through the root logger's handler.
+func SetCallTraceHandler(h Handler) {
+	callTracer.lock.Lock()
+	defer callTracer.lock.Unlock()
+
+	callTracer.handler = h
+}
+
+// DebugLog records the entry of the calling function if call tracing is
+// enabled for it. The emitted trace record contains the id of the
+// goroutine and the fully qualified name of the traced function.
 func DebugLog() {
-	pc, file, line, _ := runtime.Caller(1)
-	f := getName(pc)
-	log.Printf("%s/%s() line %d", file, f, line)
+	if atomic.LoadUint32(&callTracer.enabled) == 0 {
+		return
+	}
+	callTracer.trace(stack.Caller(1), "Function entered", time.Time{})
+}
+
+// DebugLogSpan records the entry of the calling function if call tracing is
+// enabled for it, returning a closure which records the exit and
+// the time spent in between. It is meant to be used as:
+//
+//	defer log.DebugLogSpan()()
+func DebugLogSpan() func() {
+	if atomic.LoadUint32(&callTracer.enabled) == 0 {
+		return func() {}
+	}
+	call := stack.Caller(1)
+	if !callTracer.trace(call, "Function entered", time.Time{}) {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		callTracer.trace(call, "Function exited", start)
+	}
+}
+
+// trace emits a trace record for the given callsite if it's matched by any of
+// the currently active patterns. If start is set, the time elapsed since is
+// attached to the record. The return value reports whether the site is traced.
+func (t *callTraceFilter) trace(call stack.Call, msg string, start time.Time) bool {
+	// Check callsite cache for previously calculated matches
+	t.lock.RLock()
+	match, ok := t.siteCache[call.PC()]
+	handler := t.handler
+	t.lock.RUnlock()
+
+	// If we didn't cache the callsite yet, calculate it
+	if !ok {
+		file, function := fmt.Sprintf("%+s", call), functionName(call)
+
+		t.lock.Lock()
+		for _, rule := range t.patterns {
+			if rule.pattern.MatchString(file) {
+				match = true
+				break
+			}
+		}
+		for _, rule := range t.functions {
+			if match {
+				break
+			}
+			match = rule.MatchString(function)
+		}
+		if t.siteCache != nil {
+			t.siteCache[call.PC()] = match
+		}
+		t.lock.Unlock()
+	}
+	if !match {
+		return false
+	}
+	ctx := []interface{}{"goid", goroutineID(), "func", fmt.Sprintf("%+n", call)}
+	if !start.IsZero() {
+		ctx = append(ctx, "elapsed", time.Since(start))
+	}
+	record := &Record{
+		Time: time.Now(),
+		Lvl:  LvlTrace,
+		Msg:  msg,
+		Ctx:  ctx,
+		Call: call,
+		KeyNames: RecordKeyNames{
+			Time: timeKey,
+			Msg:  msgKey,
+			Lvl:  lvlKey,
+		},
+	}
+	if handler == nil {
+		handler = root.GetHandler()
+	}
+	handler.Log(record)
+	return true
 }
 
-func getName(_pc uintptr) string {
-	longname := strings.Split(runtime.FuncForPC(_pc).Name(), "/")
-	fullname := longname[len(longname)-1]
-	funcname := strings.SplitN(fullname, ".", 2)
-	return funcname[1]
+// goroutineID extracts the id of the current goroutine from the header of its
+// stack trace. The runtime deliberately doesn't expose it, but it's invaluable
+// for untangling interleaved traces.
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	// The stack header is of the form "goroutine 42 [running]:"
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i >= 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
 }
@@ -0,0 +1,137 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tracedFunc and tracedType.method are the trace points of the tests.
+func tracedFunc() { DebugLog() }
+
+type tracedType struct{}
+
+func (*tracedType) method() { DebugLog() }
+
+func (*tracedType) span() {
+	defer DebugLogSpan()()
+	time.Sleep(time.Millisecond)
+}
+
+// collectTraces enables call tracing with the given ruleset and returns the
+// records emitted while running fn.
+func collectTraces(t *testing.T, ruleset string, fn func()) []*Record {
+	var records []*Record
+	SetCallTraceHandler(FuncHandler(func(r *Record) error {
+		records = append(records, r)
+		return nil
+	}))
+	defer SetCallTraceHandler(nil)
+
+	if err := SetCallTrace(ruleset); err != nil {
+		t.Fatalf("ruleset %q: %v", ruleset, err)
+	}
+	defer SetCallTrace("")
+
+	fn()
+	return records
+}
+
+func TestCallTracePatterns(t *testing.T) {
+	tests := []struct {
+		ruleset string
+		traced  []string // Functions expected to be traced, in order
+	}{
+		{"", nil},
+		{"log=1", []string{"tracedFunc", "method"}},
+		{"log/*=1", []string{"tracedFunc", "method"}},
+		{"log=0", nil},
+		{"p2p=1", nil},
+		{"debuglog_test.go=1", []string{"tracedFunc", "method"}},
+		{"handler.go=1", nil},
+		{"log.tracedFunc=1", []string{"tracedFunc"}},
+		{"log.traced*=1", []string{"tracedFunc", "method"}},
+		{"log.tracedType.*=1", []string{"method"}},
+		{"log.tracedType.method=1", []string{"method"}},
+		{"go-ethereum/log.tracedType.method=1", []string{"method"}},
+		{"core.tracedFunc=1", nil},
+		{"log.tracedFunc=0,p2p=1", nil},
+		{"p2p=1,log.tracedFunc=1", []string{"tracedFunc"}},
+	}
+	for _, tt := range tests {
+		records := collectTraces(t, tt.ruleset, func() {
+			tracedFunc()
+			new(tracedType).method()
+		})
+		if len(records) != len(tt.traced) {
+			t.Errorf("ruleset %q: traced %d calls, want %d", tt.ruleset, len(records), len(tt.traced))
+			continue
+		}
+		for i, record := range records {
+			if function := record.Ctx[3].(string); !strings.HasSuffix(function, tt.traced[i]) {
+				t.Errorf("ruleset %q: trace %d function mismatch: have %s, want %s", tt.ruleset, i, function, tt.traced[i])
+			}
+		}
+	}
+}
+
+func TestCallTraceSyntax(t *testing.T) {
+	for _, ruleset := range []string{"log", "log.tracedFunc=x", "log=1=2", "=1"} {
+		if err := SetCallTrace(ruleset); err == nil {
+			t.Errorf("ruleset %q: accepted invalid syntax", ruleset)
+		}
+	}
+	SetCallTrace("")
+}
+
+func TestCallTraceRecords(t *testing.T) {
+	records := collectTraces(t, "log.tracedType.span=1", new(tracedType).span)
+	if len(records) != 2 {
+		t.Fatalf("emitted %d records, want entry and exit", len(records))
+	}
+	entry, exit := records[0], records[1]
+	if entry.Msg != "Function entered" || exit.Msg != "Function exited" {
+		t.Errorf("message mismatch: have %q and %q", entry.Msg, exit.Msg)
+	}
+	for _, record := range records {
+		if record.Lvl != LvlTrace {
+			t.Errorf("%s: level mismatch: have %v, want %v", record.Msg, record.Lvl, LvlTrace)
+		}
+		if record.Ctx[0] != "goid" || record.Ctx[1].(uint64) == 0 {
+			t.Errorf("%s: missing goroutine id: %v", record.Msg, record.Ctx)
+		}
+		if record.Ctx[2] != "func" || !strings.HasSuffix(record.Ctx[3].(string), "log.(*tracedType).span") {
+			t.Errorf("%s: function mismatch: %v", record.Msg, record.Ctx)
+		}
+		if !strings.HasSuffix(fmt.Sprintf("%s", record.Call), "debuglog_test.go") {
+			t.Errorf("%s: callsite mismatch: %s", record.Msg, fmt.Sprintf("%s", record.Call))
+		}
+	}
+	if len(entry.Ctx) != 4 {
+		t.Errorf("entry record has extra context: %v", entry.Ctx)
+	}
+	if len(exit.Ctx) != 6 || exit.Ctx[4] != "elapsed" || exit.Ctx[5].(time.Duration) < time.Millisecond {
+		t.Errorf("exit record elapsed time mismatch: %v", exit.Ctx)
+	}
+	// Spans of untraced functions don't emit anything
+	if records := collectTraces(t, "log.tracedFunc=1", new(tracedType).span); len(records) != 0 {
+		t.Errorf("untraced span emitted %d records", len(records))
+	}
+}
@@ -86,6 +86,24 @@ func (h *GlogHandler) Verbosity(level Lvl) {
 //  pattern="foo/*=3"
 //   sets V to 3 in all files of any packages whose import path contains "foo"
 func (h *GlogHandler) Vmodule(ruleset string) error {
+	filter, err := parseVmodule(ruleset)
+	if err != nil {
+		return err
+	}
+	// Swap out the vmodule pattern for the new filter system
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.patterns = filter
+	h.siteCache = make(map[uintptr]Lvl)
+	atomic.StoreUint32(&h.override, uint32(len(filter)))
+
+	return nil
+}
+
+// parseVmodule compiles a comma-separated list of pattern=N rules into a set of
+// file matchers. See GlogHandler.Vmodule for the exact syntax.
+func parseVmodule(ruleset string) ([]pattern, error) {
 	var filter []pattern
 	for _, rule := range strings.Split(ruleset, ",") {
 		// Empty strings such as from a trailing comma can be ignored
@@ -95,17 +113,17 @@ func (h *GlogHandler) Vmodule(ruleset string) error {
 		// Ensure we have a pattern = level filter rule
 		parts := strings.Split(rule, "=")
 		if len(parts) != 2 {
-			return errVmoduleSyntax
+			return nil, errVmoduleSyntax
 		}
 		parts[0] = strings.TrimSpace(parts[0])
 		parts[1] = strings.TrimSpace(parts[1])
 		if len(parts[0]) == 0 || len(parts[1]) == 0 {
-			return errVmoduleSyntax
+			return nil, errVmoduleSyntax
 		}
 		// Parse the level and if correct, assemble the filter rule
 		level, err := strconv.Atoi(parts[1])
 		if err != nil {
-			return errVmoduleSyntax
+			return nil, errVmoduleSyntax
 		}
 		if level <= 0 {
 			continue // Ignore. It's harmless but no point in paying the overhead.
@@ -127,15 +145,7 @@ func (h *GlogHandler) Vmodule(ruleset string) error {
 		re, _ := regexp.Compile(matcher)
 		filter = append(filter, pattern{re, Lvl(level)})
 	}
-	// Swap out the vmodule pattern for the new filter system
-	h.lock.Lock()
-	defer h.lock.Unlock()
-
-	h.patterns = filter
-	h.siteCache = make(map[uintptr]Lvl)
-	atomic.StoreUint32(&h.override, uint32(len(filter)))
-
-	return nil
+	return filter, nil
 }
 
 // BacktraceAt sets the glog backtrace location. When set to a file and line
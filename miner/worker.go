@@ -386,7 +386,7 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 	return nil
 }
 
-func (self *worker) commitNewWork() { log.DebugLog()
+func (self *worker) commitNewWork() { defer log.DebugLogSpan()()
 	self.mu.Lock()
 	defer self.mu.Unlock()
 	self.uncleMu.Lock()
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/internal/build"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 // androidTestClass is a Java class to do some lightweight tests against the Android
@@ -229,3 +229,13 @@ func (a *Addresses) Set(index int, address *Address) error { log.DebugLog()
 func (a *Addresses) Append(address *Address) { log.DebugLog()
 	a.addresses = append(a.addresses, address.address)
 }
+
+// DecodeFromHex decodes a hex string, with or without the 0x prefix, into a byte
+// slice (e.g. the bytecode of a contract to deploy).
+func DecodeFromHex(input string) ([]byte, error) { log.DebugLog()
+	input = strings.TrimSpace(input)
+	if len(input) >= 2 && (input[:2] == "0x" || input[:2] == "0X") {
+		input = input[2:]
+	}
+	return hex.DecodeString(input)
+}
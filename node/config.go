@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
@@ -33,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 const (
@@ -66,6 +68,11 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DatabaseBackend is the name of the key/value store engine used for all the
+	// databases opened within the data directory (e.g. "leveldb" or "filedb"). If
+	// empty, ethdb.DefaultBackend is used. Ephemeral nodes always use memory.
+	DatabaseBackend string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -119,6 +126,18 @@ type Config struct {
 	// exposed.
 	HTTPModules []string `toml:",omitempty"`
 
+	// HTTPAccessList is a list of method level access rules applied on top of
+	// HTTPModules, e.g. {"eth_*", "!eth_sign*"}. Rules are glob patterns over the
+	// method names, negated by a leading '!'. If empty, all methods of the exposed
+	// modules are callable.
+	HTTPAccessList []string `toml:",omitempty"`
+
+	// HTTPLimits are the resource limits of the HTTP RPC interface: token bucket
+	// rate limits per connection and remote IP, method costs, the maximum batch
+	// size and the execution timeout of the calls. MaxInFlight bounds the requests
+	// executing concurrently on the whole interface, rejecting those in excess.
+	HTTPLimits rpc.Limits `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -138,6 +157,15 @@ type Config struct {
 	// exposed.
 	WSModules []string `toml:",omitempty"`
 
+	// WSAccessList is a list of method level access rules applied on top of
+	// WSModules, with the same format as HTTPAccessList.
+	WSAccessList []string `toml:",omitempty"`
+
+	// WSLimits are the resource limits of the websocket RPC interface, with the
+	// same semantics as HTTPLimits. MaxInFlight bounds the concurrently executing
+	// requests of each websocket connection.
+	WSLimits rpc.Limits `toml:",omitempty"`
+
 	// WSExposeAll exposes all API modules via the WebSocket RPC interface rather
 	// than just the public ones.
 	//
@@ -145,6 +173,27 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// JWTSecret is the path to a file containing the hex encoded secret used to
+	// authenticate HTTP and websocket RPC requests. If set, every request must
+	// carry an HS256 signed bearer token issued within a minute of the local
+	// time. A new secret is generated into the file if it doesn't exist yet.
+	JWTSecret string `toml:",omitempty"`
+
+	// RPCSlowCallThreshold is the execution time above which RPC calls are logged
+	// with their parameters, on all the RPC interfaces. Zero disables logging.
+	RPCSlowCallThreshold time.Duration `toml:",omitempty"`
+
+	// RPCSubscriptionQueue is the number of notifications queued for each
+	// subscription on the IPC, websocket and in-process RPC interfaces, sent by
+	// a dedicated writer so slow subscribers don't stall the producers. Zero
+	// writes the notifications directly.
+	RPCSubscriptionQueue int `toml:",omitempty"`
+
+	// RPCSubscriptionPolicy selects what happens to subscriptions overflowing
+	// their queue: block the producer, drop the oldest notifications or
+	// disconnect the subscriber.
+	RPCSubscriptionPolicy rpc.QueuePolicy `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -67,6 +68,8 @@ type Node struct {
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
 
+	jwtSecret []byte // Secret authenticating HTTP and websocket requests (nil = no authentication)
+
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
 
@@ -250,6 +253,14 @@ func (n *Node) openDataDir() error { log.DebugLog()
 // startup. It's not meant to be called at any time afterwards as it makes certain
 // assumptions about the state of the node.
 func (n *Node) startRPC(services map[reflect.Type]Service) error { log.DebugLog()
+	// Load the secret authenticating the HTTP and websocket endpoints
+	if n.config.JWTSecret != "" {
+		secret, err := rpc.ReadJWTSecret(n.config.JWTSecret)
+		if err != nil {
+			return err
+		}
+		n.jwtSecret = secret
+	}
 	// Gather all the possible APIs to surface
 	apis := n.apis()
 	for _, service := range services {
@@ -283,6 +294,8 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error { log.DebugLog(
 func (n *Node) startInProc(apis []rpc.API) error { log.DebugLog()
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	handler.SetSlowCallThreshold(n.config.RPCSlowCallThreshold)
+	handler.SetSubscriptionQueue(n.config.RPCSubscriptionQueue, n.config.RPCSubscriptionPolicy)
 	for _, api := range apis {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return err
@@ -309,6 +322,8 @@ func (n *Node) startIPC(apis []rpc.API) error { log.DebugLog()
 	}
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	handler.SetSlowCallThreshold(n.config.RPCSlowCallThreshold)
+	handler.SetSubscriptionQueue(n.config.RPCSubscriptionQueue, n.config.RPCSubscriptionPolicy)
 	for _, api := range apis {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return err
@@ -376,7 +391,14 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 		whitelist[module] = true
 	}
 	// Register all the APIs exposed by the services
+	acl, err := rpc.NewAccessList(n.config.HTTPAccessList)
+	if err != nil {
+		return err
+	}
 	handler := rpc.NewServer()
+	handler.SetSlowCallThreshold(n.config.RPCSlowCallThreshold)
+	handler.SetAccessList(acl)
+	handler.SetLimits(n.config.HTTPLimits)
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -386,15 +408,12 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 		}
 	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
 		return err
 	}
-	go rpc.NewHTTPServer(cors, vhosts, handler).Serve(listener)
-	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
+	go (&http.Server{Handler: rpc.NewHTTPHandlerStack(n.authenticate(handler), cors, vhosts)}).Serve(listener)
+	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","), "auth", n.jwtSecret != nil)
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
 	n.httpListener = listener
@@ -429,7 +448,15 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 		whitelist[module] = true
 	}
 	// Register all the APIs exposed by the services
+	acl, err := rpc.NewAccessList(n.config.WSAccessList)
+	if err != nil {
+		return err
+	}
 	handler := rpc.NewServer()
+	handler.SetSlowCallThreshold(n.config.RPCSlowCallThreshold)
+	handler.SetAccessList(acl)
+	handler.SetLimits(n.config.WSLimits)
+	handler.SetSubscriptionQueue(n.config.RPCSubscriptionQueue, n.config.RPCSubscriptionPolicy)
 	for _, api := range apis {
 		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -439,15 +466,12 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 		}
 	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
 		return err
 	}
-	go rpc.NewWSServer(wsOrigins, handler).Serve(listener)
-	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()))
+	go (&http.Server{Handler: n.authenticate(handler.WebsocketHandler(wsOrigins))}).Serve(listener)
+	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()), "auth", n.jwtSecret != nil)
 
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
@@ -457,6 +481,15 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	return nil
 }
 
+// authenticate wraps an RPC endpoint's handler into bearer token authentication
+// if a JWT secret is configured.
+func (n *Node) authenticate(handler http.Handler) http.Handler { log.DebugLog()
+	if n.jwtSecret == nil {
+		return handler
+	}
+	return rpc.NewJWTHandler(n.jwtSecret, handler)
+}
+
 // stopWS terminates the websocket RPC endpoint.
 func (n *Node) stopWS() { log.DebugLog()
 	if n.wsListener != nil {
@@ -645,7 +678,7 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (ethdb.Database, er
 	if n.config.DataDir == "" {
 		return ethdb.NewMemDatabase()
 	}
-	return ethdb.NewLDBDatabase(n.config.resolvePath(name), cache, handles)
+	return ethdb.Open(n.config.DatabaseBackend, n.config.resolvePath(name), cache, handles)
 }
 
 // ResolvePath returns the absolute path of a resource in the instance directory.
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"log"
 
+	debugLog "github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -35,12 +36,12 @@ import (
 //  - Stop() error               - method invoked when the node terminates the service
 type SampleService struct{}
 
-func (s *SampleService) Protocols() []p2p.Protocol { log.DebugLog() return nil }
-func (s *SampleService) APIs() []rpc.API           { log.DebugLog() return nil }
-func (s *SampleService) Start(*p2p.Server) error   { log.DebugLog() fmt.Println("Service starting..."); return nil }
-func (s *SampleService) Stop() error               { log.DebugLog() fmt.Println("Service stopping..."); return nil }
+func (s *SampleService) Protocols() []p2p.Protocol { debugLog.DebugLog(); return nil }
+func (s *SampleService) APIs() []rpc.API           { debugLog.DebugLog(); return nil }
+func (s *SampleService) Start(*p2p.Server) error   { debugLog.DebugLog(); fmt.Println("Service starting..."); return nil }
+func (s *SampleService) Stop() error               { debugLog.DebugLog(); fmt.Println("Service stopping..."); return nil }
 
-func ExampleService() { log.DebugLog()
+func ExampleService() { debugLog.DebugLog()
 	// Create a network node to run protocols with the default values.
 	stack, err := node.New(&node.Config{})
 	if err != nil {
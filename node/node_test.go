@@ -17,14 +17,17 @@
 package node
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -573,3 +576,53 @@ func TestAPIGather(t *testing.T) { log.DebugLog()
 		}
 	}
 }
+
+// Tests that the HTTP and websocket endpoints require a valid bearer token if a
+// JWT secret is configured.
+func TestJWTAuthentication(t *testing.T) { log.DebugLog()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := testNodeConfig()
+	config.HTTPHost, config.WSHost = "127.0.0.1", "127.0.0.1"
+	config.JWTSecret = filepath.Join(dir, "jwtsecret")
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	secret, err := rpc.ReadJWTSecret(config.JWTSecret)
+	if err != nil {
+		t.Fatalf("failed to read generated secret: %v", err)
+	}
+	endpoints := []string{
+		"http://" + stack.httpListener.Addr().String(),
+		"ws://" + stack.wsListener.Addr().String(),
+	}
+	for _, endpoint := range endpoints {
+		client, err := rpc.DialOptions(context.Background(), endpoint, rpc.WithHTTPAuth(rpc.NewJWTAuth(secret)))
+		if err != nil {
+			t.Fatalf("%s: failed to dial authenticated client: %v", endpoint, err)
+		}
+		var modules map[string]string
+		if err := client.Call(&modules, "rpc_modules"); err != nil {
+			t.Errorf("%s: authenticated call failed: %v", endpoint, err)
+		}
+		client.Close()
+
+		if client, err = rpc.DialOptions(context.Background(), endpoint); err == nil {
+			if err = client.Call(&modules, "rpc_modules"); err == nil {
+				t.Errorf("%s: unauthenticated call succeeded", endpoint)
+			}
+			client.Close()
+		}
+	}
+}
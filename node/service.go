@@ -45,7 +45,7 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (et
 	if ctx.config.DataDir == "" {
 		return ethdb.NewMemDatabase()
 	}
-	db, err := ethdb.NewLDBDatabase(ctx.config.resolvePath(name), cache, handles)
+	db, err := ethdb.Open(ctx.config.DatabaseBackend, ctx.config.resolvePath(name), cache, handles)
 	if err != nil {
 		return nil, err
 	}
@@ -22,6 +22,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 // Tests that databases are correctly created persistent or ephemeral based on
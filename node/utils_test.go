@@ -22,6 +22,7 @@ package node
 import (
 	"reflect"
 
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -29,12 +30,12 @@ import (
 // NoopService is a trivial implementation of the Service interface.
 type NoopService struct{}
 
-func (s *NoopService) Protocols() []p2p.Protocol { log.DebugLog() return nil }
-func (s *NoopService) APIs() []rpc.API           { log.DebugLog() return nil }
-func (s *NoopService) Start(*p2p.Server) error   { log.DebugLog() return nil }
-func (s *NoopService) Stop() error               { log.DebugLog() return nil }
+func (s *NoopService) Protocols() []p2p.Protocol { log.DebugLog(); return nil }
+func (s *NoopService) APIs() []rpc.API           { log.DebugLog(); return nil }
+func (s *NoopService) Start(*p2p.Server) error   { log.DebugLog(); return nil }
+func (s *NoopService) Stop() error               { log.DebugLog(); return nil }
 
-func NewNoopService(*ServiceContext) (Service, error) { log.DebugLog() return new(NoopService), nil }
+func NewNoopService(*ServiceContext) (Service, error) { log.DebugLog(); return new(NoopService), nil }
 
 // Set of services all wrapping the base NoopService resulting in the same method
 // signatures but different outer types.
@@ -42,9 +43,9 @@ type NoopServiceA struct{ NoopService }
 type NoopServiceB struct{ NoopService }
 type NoopServiceC struct{ NoopService }
 
-func NewNoopServiceA(*ServiceContext) (Service, error) { log.DebugLog() return new(NoopServiceA), nil }
-func NewNoopServiceB(*ServiceContext) (Service, error) { log.DebugLog() return new(NoopServiceB), nil }
-func NewNoopServiceC(*ServiceContext) (Service, error) { log.DebugLog() return new(NoopServiceC), nil }
+func NewNoopServiceA(*ServiceContext) (Service, error) { log.DebugLog(); return new(NoopServiceA), nil }
+func NewNoopServiceB(*ServiceContext) (Service, error) { log.DebugLog(); return new(NoopServiceB), nil }
+func NewNoopServiceC(*ServiceContext) (Service, error) { log.DebugLog(); return new(NoopServiceC), nil }
 
 // InstrumentedService is an implementation of Service for which all interface
 // methods can be instrumented both return value as well as event hook wise.
@@ -59,7 +60,7 @@ type InstrumentedService struct {
 	stopHook      func()
 }
 
-func NewInstrumentedService(*ServiceContext) (Service, error) { log.DebugLog() return new(InstrumentedService), nil }
+func NewInstrumentedService(*ServiceContext) (Service, error) { log.DebugLog(); return new(InstrumentedService), nil }
 
 func (s *InstrumentedService) Protocols() []p2p.Protocol { log.DebugLog()
 	if s.protocolsHook != nil {
@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func TestCheckCompatible(t *testing.T) { log.DebugLog()
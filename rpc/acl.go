@@ -0,0 +1,103 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// AccessList is a method level allow/deny list of an RPC server. Rules are glob
+// patterns over the fully qualified method names (e.g. eth_getBalance), negated
+// by a leading '!'. A method is permitted if it matches any of the allow rules
+// (or there are none) and none of the deny rules, so deny always wins:
+//
+//	eth_*,!eth_sign*,debug_traceTransaction
+//
+// exposes the whole eth namespace except the signing methods, and a single
+// method of the debug namespace. Subscriptions are checked as their subscribe
+// method (e.g. eth_subscribe); unsubscribing is always permitted.
+type AccessList struct {
+	allow []string // Patterns of the permitted methods, empty permits all
+	deny  []string // Patterns of the rejected methods
+}
+
+// NewAccessList creates an access list from a set of rules, failing if any of
+// the patterns is malformed.
+func NewAccessList(rules []string) (*AccessList, error) {
+	log.DebugLog()
+	acl := new(AccessList)
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		list := &acl.allow
+		if strings.HasPrefix(rule, "!") {
+			rule, list = strings.TrimSpace(rule[1:]), &acl.deny
+		}
+		if rule == "" {
+			return nil, fmt.Errorf("empty access rule")
+		}
+		if _, err := path.Match(rule, ""); err != nil {
+			return nil, fmt.Errorf("invalid access rule %q: %v", rule, err)
+		}
+		*list = append(*list, rule)
+	}
+	return acl, nil
+}
+
+// ParseAccessList creates an access list from a comma separated list of rules.
+func ParseAccessList(rules string) (*AccessList, error) {
+	log.DebugLog()
+	return NewAccessList(strings.Split(rules, ","))
+}
+
+// Allowed returns whether the given fully qualified method may be called.
+func (acl *AccessList) Allowed(method string) bool {
+	log.DebugLog()
+	if acl == nil {
+		return true
+	}
+	for _, rule := range acl.deny {
+		if match, _ := path.Match(rule, method); match {
+			return false
+		}
+	}
+	if len(acl.allow) == 0 {
+		return true
+	}
+	for _, rule := range acl.allow {
+		if match, _ := path.Match(rule, method); match {
+			return true
+		}
+	}
+	return false
+}
+
+// String implements fmt.Stringer, returning the rules of the access list.
+func (acl *AccessList) String() string {
+	log.DebugLog()
+	rules := append([]string{}, acl.allow...)
+	for _, rule := range acl.deny {
+		rules = append(rules, "!"+rule)
+	}
+	return strings.Join(rules, ",")
+}
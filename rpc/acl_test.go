@@ -0,0 +1,127 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func TestAccessListRules(t *testing.T) { log.DebugLog()
+	tests := []struct {
+		rules   string
+		allowed []string
+		denied  []string
+	}{
+		{
+			rules:   "",
+			allowed: []string{"eth_sign", "debug_traceTransaction", "rpc_modules"},
+		},
+		{
+			rules:   "eth_*,!eth_sign*,debug_traceTransaction",
+			allowed: []string{"eth_getBalance", "eth_sendRawTransaction", "debug_traceTransaction"},
+			denied:  []string{"eth_sign", "eth_signTransaction", "debug_traceBlock", "personal_unlockAccount"},
+		},
+		{
+			rules:   "!personal_*, !admin_*",
+			allowed: []string{"eth_getBalance", "debug_traceBlock"},
+			denied:  []string{"personal_unlockAccount", "admin_addPeer"},
+		},
+		{
+			rules:   "eth_getBalance,!eth_getBalance",
+			denied:  []string{"eth_getBalance"},
+		},
+	}
+	for i, tt := range tests {
+		acl, err := ParseAccessList(tt.rules)
+		if err != nil {
+			t.Fatalf("test %d: failed to parse rules %q: %v", i, tt.rules, err)
+		}
+		for _, method := range tt.allowed {
+			if !acl.Allowed(method) {
+				t.Errorf("test %d: method %s denied by %q", i, method, tt.rules)
+			}
+		}
+		for _, method := range tt.denied {
+			if acl.Allowed(method) {
+				t.Errorf("test %d: method %s allowed by %q", i, method, tt.rules)
+			}
+		}
+	}
+	for _, rules := range []string{"eth_[", "!", "eth_*,! "} {
+		if _, err := ParseAccessList(rules); err == nil {
+			t.Errorf("malformed rules %q accepted", rules)
+		}
+	}
+}
+
+func TestServerAccessList(t *testing.T) { log.DebugLog()
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+
+	acl, _ := ParseAccessList("service_*,!service_rets")
+	server.SetAccessList(acl)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "service_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatalf("allowed call failed: %v", err)
+	}
+	var rets string
+	err := client.Call(&rets, "service_rets")
+	if err == nil {
+		t.Fatalf("denied call succeeded")
+	}
+	if jsonErr, ok := err.(*jsonError); !ok || jsonErr.Code != (&accessDeniedError{}).ErrorCode() {
+		t.Errorf("error mismatch: have %v, want access denied", err)
+	}
+	// Calls outside of the allow rules are denied, even those of the server itself
+	if err := client.Call(nil, "rpc_modules"); err == nil {
+		t.Errorf("call outside the allow rules succeeded")
+	}
+}
+
+func TestServerAccessListSubscriptions(t *testing.T) { log.DebugLog()
+	server := newTestServer("nftest", new(NotificationTestService))
+	defer server.Stop()
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Subscriptions are checked against their namespace's subscribe method
+	for rules, allowed := range map[string]bool{"nftest_*": true, "nftest_subscribe": true, "nftest_*,!nftest_subscribe": false} {
+		acl, _ := ParseAccessList(rules)
+		server.SetAccessList(acl)
+
+		sub, err := client.Subscribe(context.Background(), "nftest", make(chan int), "someSubscription", 1, 1)
+		if allowed {
+			if err != nil {
+				t.Errorf("rules %q: subscription failed: %v", rules, err)
+			} else {
+				sub.Unsubscribe()
+			}
+			continue
+		}
+		if jsonErr, ok := err.(*jsonError); !ok || jsonErr.Code != (&accessDeniedError{}).ErrorCode() {
+			t.Errorf("rules %q: error mismatch: have %v, want access denied", rules, err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// JWTSecretLength is the length in bytes of the shared secret signing tokens.
+	JWTSecretLength = 32
+
+	// jwtIssuedAtSkew is the maximum allowed difference between the issuance time
+	// of a token and the local clock. Tokens are short lived by design, clients
+	// are expected to mint a fresh one for every request or connection.
+	jwtIssuedAtSkew = 60 * time.Second
+)
+
+var (
+	errMissingToken = errors.New("missing token")
+	errMissingIat   = errors.New("missing issued-at")
+	errStaleToken   = errors.New("stale token")
+	errFutureToken  = errors.New("token issued in the future")
+	errExpiredToken = errors.New("token is expired")
+)
+
+// HTTPAuth is a function that adds authentication headers to the HTTP requests
+// and websocket handshakes of a client.
+type HTTPAuth func(h http.Header) error
+
+// NewJWTAuth creates an HTTPAuth attaching an HS256 signed bearer token to every
+// request, issued at the time of the request.
+func NewJWTAuth(secret []byte) HTTPAuth {
+	log.DebugLog()
+	return func(h http.Header) error {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
+			IssuedAt: time.Now().Unix(),
+		})
+		signed, err := token.SignedString(secret)
+		if err != nil {
+			return fmt.Errorf("failed to sign token: %v", err)
+		}
+		h.Set("Authorization", "Bearer "+signed)
+		return nil
+	}
+}
+
+// jwtHandler is an http.Handler rejecting requests without a valid bearer token.
+type jwtHandler struct {
+	secret []byte
+	next   http.Handler
+}
+
+// NewJWTHandler wraps an HTTP handler into bearer token authentication. Requests
+// must carry an HS256 token signed with the given secret and issued within a
+// minute of the local time, otherwise they are rejected with 401 Unauthorized.
+func NewJWTHandler(secret []byte, next http.Handler) http.Handler {
+	log.DebugLog()
+	return &jwtHandler{secret: secret, next: next}
+}
+
+// ServeHTTP implements http.Handler, authenticating the request before passing
+// it to the wrapped handler.
+func (h *jwtHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.DebugLog()
+	if err := h.authenticate(r); err != nil {
+		log.Debug("Rejected unauthenticated RPC request", "remote", r.RemoteAddr, "err", err)
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// authenticate validates the bearer token of a request.
+func (h *jwtHandler) authenticate(r *http.Request) error {
+	log.DebugLog()
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return errMissingToken
+	}
+	// Verify the signature, the claims are checked below with the allowed skew
+	var (
+		claims jwt.StandardClaims
+		parser = &jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg()}, SkipClaimsValidation: true}
+	)
+	_, err := parser.ParseWithClaims(strings.TrimPrefix(auth, "Bearer "), &claims, func(*jwt.Token) (interface{}, error) {
+		return h.secret, nil
+	})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	switch {
+	case claims.IssuedAt == 0:
+		return errMissingIat
+	case time.Unix(claims.IssuedAt, 0).Before(now.Add(-jwtIssuedAtSkew)):
+		return errStaleToken
+	case time.Unix(claims.IssuedAt, 0).After(now.Add(jwtIssuedAtSkew)):
+		return errFutureToken
+	case claims.ExpiresAt != 0 && time.Unix(claims.ExpiresAt, 0).Before(now):
+		return errExpiredToken
+	}
+	return nil
+}
+
+// ReadJWTSecret loads a hex encoded JWT secret from the given file. If the file
+// does not exist, a new random secret is generated and persisted into it so it
+// can be shared with the clients.
+func ReadJWTSecret(path string) ([]byte, error) {
+	log.DebugLog()
+	blob, err := ioutil.ReadFile(path)
+	switch {
+	case err == nil:
+		secret := common.FromHex(string(bytes.TrimSpace(blob)))
+		if len(secret) != JWTSecretLength {
+			return nil, fmt.Errorf("invalid JWT secret in %s: want %d hex encoded bytes, have %d", path, JWTSecretLength, len(secret))
+		}
+		return secret, nil
+
+	case os.IsNotExist(err):
+		secret := make([]byte, JWTSecretLength)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(path, []byte(common.Bytes2Hex(secret)), 0600); err != nil {
+			return nil, err
+		}
+		log.Info("Generated new JWT secret", "path", path)
+		return secret, nil
+
+	default:
+		return nil, err
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var testJWTSecret = bytes.Repeat([]byte{0x42}, JWTSecretLength)
+
+// signTestToken creates a bearer token with the given claims and signing method.
+func signTestToken(method jwt.SigningMethod, claims jwt.Claims, key interface{}) string { log.DebugLog()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+	return "Bearer " + token
+}
+
+func TestJWTHandler(t *testing.T) { log.DebugLog()
+	now := time.Now()
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"no bearer", "Basic Zm9vOmJhcg==", http.StatusUnauthorized},
+		{"garbage", "Bearer foobar", http.StatusUnauthorized},
+		{"valid", signTestToken(jwt.SigningMethodHS256, jwt.StandardClaims{IssuedAt: now.Unix()}, testJWTSecret), http.StatusOK},
+		{"small skew", signTestToken(jwt.SigningMethodHS256, jwt.StandardClaims{IssuedAt: now.Add(30 * time.Second).Unix()}, testJWTSecret), http.StatusOK},
+		{"wrong secret", signTestToken(jwt.SigningMethodHS256, jwt.StandardClaims{IssuedAt: now.Unix()}, []byte("foo")), http.StatusUnauthorized},
+		{"wrong method", signTestToken(jwt.SigningMethodHS512, jwt.StandardClaims{IssuedAt: now.Unix()}, testJWTSecret), http.StatusUnauthorized},
+		{"none method", signTestToken(jwt.SigningMethodNone, jwt.StandardClaims{IssuedAt: now.Unix()}, jwt.UnsafeAllowNoneSignatureType), http.StatusUnauthorized},
+		{"no iat", signTestToken(jwt.SigningMethodHS256, jwt.StandardClaims{}, testJWTSecret), http.StatusUnauthorized},
+		{"stale", signTestToken(jwt.SigningMethodHS256, jwt.StandardClaims{IssuedAt: now.Add(-2 * time.Minute).Unix()}, testJWTSecret), http.StatusUnauthorized},
+		{"future", signTestToken(jwt.SigningMethodHS256, jwt.StandardClaims{IssuedAt: now.Add(2 * time.Minute).Unix()}, testJWTSecret), http.StatusUnauthorized},
+		{"expired", signTestToken(jwt.SigningMethodHS256, jwt.StandardClaims{IssuedAt: now.Unix(), ExpiresAt: now.Add(-time.Second).Unix()}, testJWTSecret), http.StatusUnauthorized},
+	}
+	handler := NewJWTHandler(testJWTSecret, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status mismatch: have %d, want %d", tt.name, w.Code, tt.want)
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: missing WWW-Authenticate header", tt.name)
+		}
+	}
+}
+
+func TestClientJWTAuthHTTP(t *testing.T)      { log.DebugLog(); testClientJWTAuth(t, "http") }
+func TestClientJWTAuthWebsocket(t *testing.T) { log.DebugLog(); testClientJWTAuth(t, "ws") }
+
+func testClientJWTAuth(t *testing.T, transport string) { log.DebugLog()
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+
+	var handler http.Handler = server
+	if transport == "ws" {
+		handler = server.WebsocketHandler([]string{"*"})
+	}
+	hs := httptest.NewServer(NewJWTHandler(testJWTSecret, handler))
+	defer hs.Close()
+
+	endpoint := transport + "://" + hs.Listener.Addr().String()
+
+	// Authenticated clients must be served
+	client, err := DialOptions(context.Background(), endpoint, WithHTTPAuth(NewJWTAuth(testJWTSecret)))
+	if err != nil {
+		t.Fatalf("failed to dial authenticated client: %v", err)
+	}
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "service_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatalf("authenticated call failed: %v", err)
+	}
+	if result.String != "hello" || result.Int != 10 || result.Args.S != "world" {
+		t.Errorf("result mismatch: %+v", result)
+	}
+	// Unauthenticated or wrongly authenticated clients must be rejected
+	for _, options := range [][]ClientOption{nil, {WithHTTPAuth(NewJWTAuth([]byte("foo")))}} {
+		client, err := DialOptions(context.Background(), endpoint, options...)
+		if err != nil {
+			if transport == "http" {
+				t.Fatalf("failed to dial HTTP client: %v", err)
+			}
+			continue // websocket handshake rejected, as expected
+		}
+		err = client.Call(&result, "service_echo", "hello", 10, &Args{"world"})
+		client.Close()
+
+		if err == nil {
+			t.Fatalf("unauthenticated call succeeded")
+		}
+		if httpErr, ok := err.(*HTTPError); !ok || httpErr.StatusCode != http.StatusUnauthorized {
+			t.Errorf("error mismatch: have %v, want 401 Unauthorized", err)
+		}
+	}
+}
+
+func TestReadJWTSecret(t *testing.T) { log.DebugLog()
+	dir, err := ioutil.TempDir("", "jwtsecret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Missing secrets should be generated and persisted
+	path := filepath.Join(dir, "secret", "jwt.hex")
+	secret, err := ReadJWTSecret(path)
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	if len(secret) != JWTSecretLength {
+		t.Fatalf("secret length mismatch: have %d, want %d", len(secret), JWTSecretLength)
+	}
+	loaded, err := ReadJWTSecret(path)
+	if err != nil {
+		t.Fatalf("failed to load secret: %v", err)
+	}
+	if !bytes.Equal(secret, loaded) {
+		t.Errorf("secret mismatch: have %x, want %x", loaded, secret)
+	}
+	// Prefixed secrets with surrounding whitespace should be accepted
+	if err := ioutil.WriteFile(path, []byte("0x"+strings.Repeat("42", JWTSecretLength)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err = ReadJWTSecret(path); err != nil || !bytes.Equal(loaded, testJWTSecret) {
+		t.Errorf("prefixed secret mismatch: have %x (err %v), want %x", loaded, err, testJWTSecret)
+	}
+	// Malformed secrets should be rejected
+	if err := ioutil.WriteFile(path, []byte("0x4242"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadJWTSecret(path); err == nil {
+		t.Errorf("short secret accepted")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"reflect"
 	"strconv"
@@ -162,15 +163,51 @@ func Dial(rawurl string) (*Client, error) { log.DebugLog()
 // The context is used to cancel or time out the initial connection establishment. It does
 // not affect subsequent interactions with the client.
 func DialContext(ctx context.Context, rawurl string) (*Client, error) { log.DebugLog()
+	return DialOptions(ctx, rawurl)
+}
+
+// ClientOption is a configuration option for the RPC client.
+type ClientOption func(*clientConfig)
+
+// clientConfig contains the transport settings of a client.
+type clientConfig struct {
+	httpClient *http.Client // HTTP client to send requests with, nil for the default
+	httpAuth   HTTPAuth     // Authentication of HTTP requests and websocket handshakes
+}
+
+// WithHTTPClient configures the HTTP client used for HTTP endpoints.
+func WithHTTPClient(c *http.Client) ClientOption { log.DebugLog()
+	return func(cfg *clientConfig) {
+		cfg.httpClient = c
+	}
+}
+
+// WithHTTPAuth configures the client to authenticate its HTTP requests and
+// websocket handshakes, e.g. with a token created by NewJWTAuth. The function
+// is invoked for every request and every (re)connection.
+func WithHTTPAuth(auth HTTPAuth) ClientOption { log.DebugLog()
+	return func(cfg *clientConfig) {
+		cfg.httpAuth = auth
+	}
+}
+
+// DialOptions creates a new RPC client for the given URL, just like DialContext,
+// applying the given options to the transport. Options only pertaining to HTTP
+// and websocket connections are ignored for IPC endpoints.
+func DialOptions(ctx context.Context, rawurl string, options ...ClientOption) (*Client, error) { log.DebugLog()
 	u, err := url.Parse(rawurl)
 	if err != nil {
 		return nil, err
 	}
+	cfg := new(clientConfig)
+	for _, option := range options {
+		option(cfg)
+	}
 	switch u.Scheme {
 	case "http", "https":
-		return DialHTTP(rawurl)
+		return dialHTTP(rawurl, cfg)
 	case "ws", "wss":
-		return DialWebsocket(ctx, rawurl, "")
+		return dialWebsocket(ctx, rawurl, "", cfg)
 	case "":
 		return DialIPC(ctx, rawurl)
 	default:
@@ -591,16 +628,34 @@ func (c *Client) handleNotification(msg *jsonrpcMessage) { log.DebugLog()
 		return
 	}
 	var subResult struct {
-		ID     string          `json:"subscription"`
-		Result json.RawMessage `json:"result"`
+		ID      string          `json:"subscription"`
+		Result  json.RawMessage `json:"result"`
+		Dropped uint64          `json:"dropped"`
+		Error   *jsonError      `json:"error"`
 	}
 	if err := json.Unmarshal(msg.Params, &subResult); err != nil {
 		log.Debug(fmt.Sprint("dropping invalid subscription message: ", msg))
 		return
 	}
-	if c.subs[subResult.ID] != nil {
-		c.subs[subResult.ID].deliver(subResult.Result)
+	sub := c.subs[subResult.ID]
+	if sub == nil {
+		return
+	}
+	// The server terminates the subscriptions overflowing their queue with an
+	// error, the subscription is already gone on its side.
+	if subResult.Error != nil {
+		delete(c.subs, subResult.ID)
+		if subResult.Error.Code == (&subscriptionOverflowError{}).ErrorCode() {
+			sub.quitWithError(ErrSubscriptionQueueOverflow, false)
+		} else {
+			sub.quitWithError(subResult.Error, false)
+		}
+		return
+	}
+	if subResult.Dropped > 0 {
+		sub.markDropped(subResult.Dropped)
 	}
+	sub.deliver(subResult.Result)
 }
 
 func (c *Client) handleResponse(msg *jsonrpcMessage) { log.DebugLog()
@@ -664,6 +719,8 @@ func (c *Client) read(conn net.Conn) error { log.DebugLog()
 
 // A ClientSubscription represents a subscription established through EthSubscribe.
 type ClientSubscription struct {
+	dropped uint64 // Notifications dropped by the server, accessed atomically (first for 64 bit alignment)
+
 	client    *Client
 	etype     reflect.Type
 	channel   reflect.Value
@@ -698,10 +755,27 @@ func newClientSubscription(c *Client, namespace string, channel reflect.Value) *
 // on the underlying client and no other error has occurred.
 //
 // The error channel is closed when Unsubscribe is called on the subscription.
+//
+// ErrSubscriptionQueueOverflow is received if the subscriber couldn't keep up with
+// the notifications, either because the client side buffer filled up or because
+// the server terminated the subscription for overflowing its queue.
 func (sub *ClientSubscription) Err() <-chan error { log.DebugLog()
 	return sub.err
 }
 
+// Dropped returns the number of notifications the server discarded so far because
+// the subscriber couldn't keep up with them. Servers only drop notifications if
+// configured with the QueueDropOldest policy, otherwise it's always zero.
+func (sub *ClientSubscription) Dropped() uint64 { log.DebugLog()
+	return atomic.LoadUint64(&sub.dropped)
+}
+
+// markDropped records the notifications reported dropped by the server.
+func (sub *ClientSubscription) markDropped(dropped uint64) { log.DebugLog()
+	total := atomic.AddUint64(&sub.dropped, dropped)
+	log.Warn("Subscription notifications dropped by server", "namespace", sub.namespace, "id", sub.subid, "dropped", dropped, "total", total)
+}
+
 // Unsubscribe unsubscribes the notification and closes the error channel.
 // It can safely be called more than once.
 func (sub *ClientSubscription) Unsubscribe() { log.DebugLog()
@@ -98,10 +98,10 @@ func TestClientBatchRequest(t *testing.T) { log.DebugLog()
 	}
 }
 
-// func TestClientCancelInproc(t *testing.T) { log.DebugLog() testClientCancel("inproc", t) }
-func TestClientCancelWebsocket(t *testing.T) { log.DebugLog() testClientCancel("ws", t) }
-func TestClientCancelHTTP(t *testing.T)      { log.DebugLog() testClientCancel("http", t) }
-func TestClientCancelIPC(t *testing.T)       { log.DebugLog() testClientCancel("ipc", t) }
+// func TestClientCancelInproc(t *testing.T) { log.DebugLog(); testClientCancel("inproc", t) }
+func TestClientCancelWebsocket(t *testing.T) { log.DebugLog(); testClientCancel("ws", t) }
+func TestClientCancelHTTP(t *testing.T)      { log.DebugLog(); testClientCancel("http", t) }
+func TestClientCancelIPC(t *testing.T)       { log.DebugLog(); testClientCancel("ipc", t) }
 
 // This test checks that requests made through CallContext can be canceled by canceling
 // the context.
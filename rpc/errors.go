@@ -79,3 +79,55 @@ func (e *shutdownError) ErrorCode() int { log.DebugLog()
 
 func (e *shutdownError) Error() string { log.DebugLog()
 										   return "server is shutting down" }
+
+// request is for a method excluded by the access list of the server
+type accessDeniedError struct{ method string }
+
+func (e *accessDeniedError) ErrorCode() int {
+	log.DebugLog()
+	return -32001
+}
+
+func (e *accessDeniedError) Error() string {
+	log.DebugLog()
+	return fmt.Sprintf("The method %s is not allowed on this endpoint", e.method)
+}
+
+// request was rejected by the rate limits of the server
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int {
+	log.DebugLog()
+	return -32005
+}
+
+func (e *limitExceededError) Error() string {
+	log.DebugLog()
+	return e.message
+}
+
+// callback didn't finish within the execution timeout of the server
+type timeoutError struct{ method string }
+
+func (e *timeoutError) ErrorCode() int {
+	log.DebugLog()
+	return -32002
+}
+
+func (e *timeoutError) Error() string {
+	log.DebugLog()
+	return fmt.Sprintf("The method %s timed out", e.method)
+}
+
+// subscriber couldn't keep up with the notifications of its subscription
+type subscriptionOverflowError struct{}
+
+func (e *subscriptionOverflowError) ErrorCode() int {
+	log.DebugLog()
+	return -32006
+}
+
+func (e *subscriptionOverflowError) Error() string {
+	log.DebugLog()
+	return "subscription queue overflow"
+}
@@ -45,6 +45,7 @@ var nullAddr, _ = net.ResolveTCPAddr("tcp", "127.0.0.1:0")
 type httpConn struct {
 	client    *http.Client
 	req       *http.Request
+	auth      HTTPAuth // Optional authentication of the requests
 	closeOnce sync.Once
 	closed    chan struct{}
 }
@@ -79,6 +80,23 @@ func (hc *httpConn) Close() error {
 // using the provided HTTP Client.
 func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
 	log.DebugLog()
+	return dialHTTP(endpoint, &clientConfig{httpClient: client})
+}
+
+// DialHTTP creates a new RPC client that connects to an RPC server over HTTP.
+func DialHTTP(endpoint string) (*Client, error) {
+	log.DebugLog()
+	return dialHTTP(endpoint, new(clientConfig))
+}
+
+// dialHTTP creates a new RPC client that connects to an RPC server over HTTP,
+// configuring the transport according to the client options.
+func dialHTTP(endpoint string, cfg *clientConfig) (*Client, error) {
+	log.DebugLog()
+	client := cfg.httpClient
+	if client == nil {
+		client = new(http.Client)
+	}
 	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -88,16 +106,10 @@ func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
 
 	initctx := context.Background()
 	return newClient(initctx, func(context.Context) (net.Conn, error) {
-		return &httpConn{client: client, req: req, closed: make(chan struct{})}, nil
+		return &httpConn{client: client, req: req, auth: cfg.httpAuth, closed: make(chan struct{})}, nil
 	})
 }
 
-// DialHTTP creates a new RPC client that connects to an RPC server over HTTP.
-func DialHTTP(endpoint string) (*Client, error) {
-	log.DebugLog()
-	return DialHTTPWithClient(endpoint, new(http.Client))
-}
-
 func (c *Client) sendHTTP(ctx context.Context, op *requestOp, msg interface{}) error {
 	log.DebugLog()
 	hc := c.writeConn.(*httpConn)
@@ -142,13 +154,45 @@ func (hc *httpConn) doRequest(ctx context.Context, msg interface{}) (io.ReadClos
 	req.Body = ioutil.NopCloser(bytes.NewReader(body))
 	req.ContentLength = int64(len(body))
 
+	// Authenticate on a private copy of the headers, requests may be concurrent
+	if hc.auth != nil {
+		req.Header = make(http.Header, len(hc.req.Header))
+		for key, values := range hc.req.Header {
+			req.Header[key] = values
+		}
+		if err := hc.auth(req.Header); err != nil {
+			return nil, err
+		}
+	}
 	resp, err := hc.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: bytes.TrimSpace(msg)}
+	}
 	return resp.Body, nil
 }
 
+// HTTPError is returned by the client if the server rejects a request with a
+// non-2xx status code, e.g. 401 Unauthorized for a missing or invalid token.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (err *HTTPError) Error() string {
+	log.DebugLog()
+	if len(err.Body) == 0 {
+		return err.Status
+	}
+	return fmt.Sprintf("%v: %s", err.Status, err.Body)
+}
+
 // httpReadWriteNopCloser wraps a io.Reader and io.Writer with a NOP Close method.
 type httpReadWriteNopCloser struct {
 	io.Reader
@@ -165,11 +209,18 @@ func (t *httpReadWriteNopCloser) Close() error {
 //
 // Deprecated: Server implements http.Handler
 func NewHTTPServer(cors []string, vhosts []string, srv *Server) *http.Server {
+	log.DebugLog()
+	return &http.Server{Handler: NewHTTPHandlerStack(srv, cors, vhosts)}
+}
+
+// NewHTTPHandlerStack wraps an HTTP handler into the CORS and virtual host
+// checks of the HTTP RPC server, so other HTTP services of the node can apply
+// the same policies.
+func NewHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string) http.Handler {
 	log.DebugLog()
 	// Wrap the CORS-handler within a host-handler
 	handler := newCorsHandler(srv, cors)
-	handler = newVHostHandler(vhosts, handler)
-	return &http.Server{Handler: handler}
+	return newVHostHandler(vhosts, handler)
 }
 
 // ServeHTTP serves JSON-RPC requests over HTTP.
@@ -191,7 +242,8 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer codec.Close()
 
 	w.Header().Set("content-type", contentType)
-	srv.ServeSingleRequest(codec, OptionMethodInvocation)
+	ctx := context.WithValue(context.Background(), remoteAddrKey{}, r.RemoteAddr)
+	srv.serveRequest(ctx, codec, true, OptionMethodInvocation)
 }
 
 // validateRequest returns a non-zero response code and error message if the
@@ -213,7 +265,7 @@ func validateRequest(r *http.Request) (int, error) {
 	return 0, nil
 }
 
-func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	log.DebugLog()
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
@@ -21,6 +21,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func TestHTTPErrorResponseWithDelete(t *testing.T) { log.DebugLog()
@@ -65,6 +65,8 @@ type jsonErrResponse struct {
 type jsonSubscription struct {
 	Subscription string      `json:"subscription"`
 	Result       interface{} `json:"result,omitempty"`
+	Dropped      uint64      `json:"dropped,omitempty"` // Notifications dropped by the server before this one
+	Error        *jsonError  `json:"error,omitempty"`   // Set when the server terminated the subscription
 }
 
 type jsonNotification struct {
@@ -96,6 +98,10 @@ func (err *jsonError) ErrorCode() int { log.DebugLog()
 	return err.Code
 }
 
+func (err *jsonError) ErrorData() interface{} { log.DebugLog()
+	return err.Data
+}
+
 // NewCodec creates a new RPC server codec with support for JSON-RPC 2.0 based
 // on explicitly given encoding and decoding methods.
 func NewCodec(rwc io.ReadWriteCloser, encode, decode func(v interface{}) error) ServerCodec { log.DebugLog()
@@ -349,6 +355,21 @@ func (c *jsonCodec) CreateNotification(subid, namespace string, event interface{
 		Params: jsonSubscription{Subscription: subid, Result: event}}
 }
 
+// setNotificationDropped reports the number of notifications the server had to
+// drop before the given one.
+func setNotificationDropped(notification interface{}, dropped uint64) { log.DebugLog()
+	if msg, ok := notification.(*jsonNotification); ok {
+		msg.Params.Dropped = dropped
+	}
+}
+
+// createErrorNotification will create a JSON-RPC notification terminating the
+// subscription with the given id because of err.
+func createErrorNotification(subid, namespace string, err Error) interface{} { log.DebugLog()
+	return &jsonNotification{Version: jsonrpcVersion, Method: namespace + notificationMethodSuffix,
+		Params: jsonSubscription{Subscription: subid, Error: &jsonError{Code: err.ErrorCode(), Message: err.Error()}}}
+}
+
 // Write message to client
 func (c *jsonCodec) Write(res interface{}) error { log.DebugLog()
 	c.encMu.Lock()
@@ -23,6 +23,8 @@ import (
 	"reflect"
 	"strconv"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 type RWC struct {
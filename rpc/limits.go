@@ -0,0 +1,249 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// limiterSweepInterval is the interval at which idle token buckets are dropped.
+const limiterSweepInterval = time.Minute
+
+// Limits contains the resource limits of an RPC server. The zero value imposes
+// no limits at all.
+//
+// Rate limits are token buckets measured in cost units: every call consumes the
+// cost of its method (1 unless configured otherwise) from both the bucket of its
+// connection and that of its remote IP address. For HTTP, a connection is a
+// keep-alive TCP connection; IPC and in-process clients have no remote address,
+// so only the connection limit applies to them.
+//
+// MaxInFlight bounds the requests executing concurrently on a streaming (IPC,
+// websocket, in-process) connection, which stops reading while the limit is
+// reached. Single shot requests (HTTP) are bounded server wide instead, with the
+// requests in excess rejected rather than queued.
+//
+// Timeout only cancels the context of the call: methods ignoring their context
+// run to completion and their result is returned, whereas errors of calls which
+// ran past the deadline are reported as timeouts.
+type Limits struct {
+	ConnRate  float64 `toml:",omitempty"` // Cost units refilled per second per connection (0 = unlimited)
+	ConnBurst int     `toml:",omitempty"` // Cost units a connection may spend at once (0 = one second worth of rate)
+	IPRate    float64 `toml:",omitempty"` // Cost units refilled per second per remote IP (0 = unlimited)
+	IPBurst   int     `toml:",omitempty"` // Cost units a remote IP may spend at once (0 = one second worth of rate)
+
+	MethodCosts map[string]int `toml:",omitempty"` // Cost of the calls to specific methods (e.g. eth_getLogs), 1 by default
+
+	MaxBatchSize int           `toml:",omitempty"` // Maximum number of requests in a batch (0 = unlimited)
+	MaxInFlight  int           `toml:",omitempty"` // Maximum concurrently executing requests per connection, or server wide for HTTP (0 = unlimited)
+	Timeout      time.Duration `toml:",omitempty"` // Execution timeout of a call, cancelling its context (0 = no timeout)
+}
+
+// SetLimits configures the resource limits of the server. It must be set before
+// the server starts serving requests.
+func (s *Server) SetLimits(limits Limits) {
+	log.DebugLog()
+	s.limits = limits
+	s.connLimiter = newLimiter(limits.ConnRate, limits.ConnBurst)
+	s.ipLimiter = newLimiter(limits.IPRate, limits.IPBurst)
+
+	s.singleShots = nil
+	if limits.MaxInFlight > 0 {
+		s.singleShots = make(chan struct{}, limits.MaxInFlight)
+	}
+}
+
+// methodCost returns the cost of calling the given method.
+func (s *Server) methodCost(method string) float64 {
+	log.DebugLog()
+	if cost, ok := s.limits.MethodCosts[method]; ok {
+		return float64(cost)
+	}
+	return 1
+}
+
+// charge deducts the cost of a call from the token buckets of the connection
+// and the remote IP the request arrived on.
+func (s *Server) charge(ctx context.Context, method string) Error {
+	log.DebugLog()
+	if s.connLimiter == nil && s.ipLimiter == nil {
+		return nil
+	}
+	peer, _ := ctx.Value(peerKey{}).(*peerInfo)
+	if peer == nil {
+		return nil
+	}
+	cost := s.methodCost(method)
+	if !s.connLimiter.take(peer.conn, cost) {
+		return &limitExceededError{"connection rate limit exceeded"}
+	}
+	if peer.ip != "" && !s.ipLimiter.take(peer.ip, cost) {
+		s.connLimiter.refund(peer.conn, cost)
+		return &limitExceededError{"remote IP rate limit exceeded"}
+	}
+	return nil
+}
+
+// remoteAddrKey is the context key of the remote address of a transport
+// connection, set by the HTTP and websocket handlers.
+type remoteAddrKey struct{}
+
+// peerKey is the context key of the peer information of a served connection.
+type peerKey struct{}
+
+// peerInfo identifies the connection and remote IP the requests arrive on, for
+// the purpose of rate limiting.
+type peerInfo struct {
+	conn string // Unique identifier of the connection
+	ip   string // Remote IP address, empty for local transports
+}
+
+// connCounter generates the identifiers of connections without remote address.
+var connCounter uint64
+
+// newPeerInfo creates the peer information of a connection, based on the remote
+// address contained in the context if any.
+func newPeerInfo(ctx context.Context) *peerInfo {
+	log.DebugLog()
+	addr, _ := ctx.Value(remoteAddrKey{}).(string)
+	if addr == "" {
+		return &peerInfo{conn: fmt.Sprintf("local-%d", atomic.AddUint64(&connCounter, 1))}
+	}
+	ip, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		ip = addr
+	}
+	return &peerInfo{conn: addr, ip: ip}
+}
+
+// tokenBucket is a rate limiter allowing bursts up to a capacity.
+type tokenBucket struct {
+	tokens  float64   // Currently available tokens
+	updated time.Time // Time of the last refill
+}
+
+// limiter is a set of token buckets sharing the same rate and capacity, keyed by
+// the entity they limit.
+type limiter struct {
+	rate  float64 // Tokens refilled per second
+	burst float64 // Capacity of the buckets
+
+	buckets map[string]*tokenBucket
+	swept   time.Time // Time of the last removal of idle buckets
+	lock    sync.Mutex
+}
+
+// newLimiter creates a set of token buckets, or nil if the rate is unlimited.
+func newLimiter(rate float64, burst int) *limiter {
+	log.DebugLog()
+	if rate <= 0 {
+		return nil
+	}
+	capacity := float64(burst)
+	if burst <= 0 {
+		capacity = rate
+		if capacity < 1 {
+			capacity = 1
+		}
+	}
+	return &limiter{
+		rate:    rate,
+		burst:   capacity,
+		buckets: make(map[string]*tokenBucket),
+		swept:   time.Now(),
+	}
+}
+
+// take consumes the given amount of tokens from the bucket of the key, returning
+// whether there were enough. Calls costlier than the capacity of the bucket may
+// proceed with a full bucket, otherwise they could never succeed.
+func (l *limiter) take(key string, cost float64) bool {
+	log.DebugLog()
+	if l == nil {
+		return true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.swept) > limiterSweepInterval {
+		l.sweep(now)
+	}
+	bucket := l.bucket(key, now)
+	if cost > l.burst {
+		cost = l.burst
+	}
+	if bucket.tokens < cost {
+		return false
+	}
+	bucket.tokens -= cost
+	return true
+}
+
+// refund returns tokens to the bucket of the key, after a call was rejected by
+// another limiter.
+func (l *limiter) refund(key string, cost float64) {
+	log.DebugLog()
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	bucket := l.bucket(key, time.Now())
+	if bucket.tokens += cost; bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+}
+
+// bucket retrieves the refilled bucket of the key, creating a full one if it
+// doesn't exist yet. The lock must be held.
+func (l *limiter) bucket(key string, now time.Time) *tokenBucket {
+	log.DebugLog()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = bucket
+		return bucket
+	}
+	bucket.tokens += now.Sub(bucket.updated).Seconds() * l.rate
+	if bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+	bucket.updated = now
+	return bucket
+}
+
+// sweep drops the buckets refilled to their capacity, as they are equivalent to
+// fresh ones. This keeps the set bounded to the recently active entities. The
+// lock must be held.
+func (l *limiter) sweep(now time.Time) {
+	log.DebugLog()
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.swept = now
+}
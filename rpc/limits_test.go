@@ -0,0 +1,297 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// isErrorCode checks whether an error returned by the client is a JSON-RPC error
+// with the given code.
+func isErrorCode(err error, code int) bool { log.DebugLog()
+	jsonErr, ok := err.(*jsonError)
+	return ok && jsonErr.Code == code
+}
+
+// TimeoutService is a test service whose methods run past the execution timeout
+// of the server, either honouring their context or ignoring it.
+type TimeoutService struct{}
+
+func (s *TimeoutService) Wait(ctx context.Context, duration time.Duration) error { log.DebugLog()
+	select {
+	case <-time.After(duration):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *TimeoutService) Block(duration time.Duration) string { log.DebugLog()
+	time.Sleep(duration)
+	return "done"
+}
+
+func TestLimiter(t *testing.T) { log.DebugLog()
+	l := newLimiter(1, 3)
+
+	// A fresh bucket allows a burst up to its capacity
+	for i := 0; i < 3; i++ {
+		if !l.take("a", 1) {
+			t.Fatalf("take %d: rejected within burst", i)
+		}
+	}
+	if l.take("a", 1) {
+		t.Fatalf("take beyond burst accepted")
+	}
+	// Other keys have their own buckets, and costs above capacity need a full one
+	if !l.take("b", 10) {
+		t.Fatalf("costly take on full bucket rejected")
+	}
+	if l.take("b", 1) {
+		t.Fatalf("take on drained bucket accepted")
+	}
+	// Refunds and elapsed time refill the buckets
+	l.refund("a", 1)
+	if !l.take("a", 1) {
+		t.Fatalf("take after refund rejected")
+	}
+	l.buckets["a"].updated = l.buckets["a"].updated.Add(-2 * time.Second)
+	if !l.take("a", 2) {
+		t.Fatalf("take after refill rejected")
+	}
+	// Sweeping drops the full buckets only
+	l.buckets["b"].updated = l.buckets["b"].updated.Add(-time.Hour)
+	l.sweep(time.Now())
+	if _, ok := l.buckets["a"]; !ok {
+		t.Errorf("drained bucket swept")
+	}
+	if _, ok := l.buckets["b"]; ok {
+		t.Errorf("refilled bucket not swept")
+	}
+	// Unlimited rates need no limiter at all
+	if newLimiter(0, 10) != nil {
+		t.Errorf("limiter created for unlimited rate")
+	}
+}
+
+func TestServerConnRateLimit(t *testing.T) { log.DebugLog()
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+	server.SetLimits(Limits{ConnRate: 0.001, ConnBurst: 5, MethodCosts: map[string]int{"service_echo": 2}})
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Two echoes and a cheap call fit into the burst, anything more is limited
+	var result Result
+	for i := 0; i < 2; i++ {
+		if err := client.Call(&result, "service_echo", "hello", 10, &Args{"world"}); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+	if err := client.Call(&result, "service_echo", "hello", 10, &Args{"world"}); !isErrorCode(err, (&limitExceededError{}).ErrorCode()) {
+		t.Fatalf("costly call error mismatch: have %v, want rate limited", err)
+	}
+	if err := client.Call(nil, "service_noArgsRets"); err != nil {
+		t.Fatalf("cheap call failed: %v", err)
+	}
+	if err := client.Call(nil, "service_noArgsRets"); !isErrorCode(err, (&limitExceededError{}).ErrorCode()) {
+		t.Fatalf("call error mismatch: have %v, want rate limited", err)
+	}
+	// Other connections are not affected
+	other := DialInProc(server)
+	defer other.Close()
+
+	if err := other.Call(nil, "service_noArgsRets"); err != nil {
+		t.Fatalf("call on other connection failed: %v", err)
+	}
+}
+
+func TestServerIPRateLimit(t *testing.T) { log.DebugLog()
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+	server.SetLimits(Limits{IPRate: 0.001, IPBurst: 2})
+
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	// Connections from the same address share the budget of the IP
+	for i := 0; i < 2; i++ {
+		client, err := DialHTTP(hs.URL)
+		if err != nil {
+			t.Fatalf("failed to dial client %d: %v", i, err)
+		}
+		if err := client.Call(nil, "service_noArgsRets"); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+		client.Close()
+	}
+	client, err := DialHTTP(hs.URL)
+	if err != nil {
+		t.Fatalf("failed to dial client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Call(nil, "service_noArgsRets"); !isErrorCode(err, (&limitExceededError{}).ErrorCode()) {
+		t.Fatalf("call error mismatch: have %v, want rate limited", err)
+	}
+}
+
+func TestServerMaxBatchSize(t *testing.T) { log.DebugLog()
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+	server.SetLimits(Limits{MaxBatchSize: 2})
+
+	for _, transport := range []string{"http", "ws"} {
+		client, hs := httpTestClient(server, transport, nil)
+
+		batch := make([]BatchElem, 3)
+		for i := range batch {
+			batch[i] = BatchElem{Method: "service_noArgsRets"}
+		}
+		if err := client.BatchCall(batch); err != nil {
+			t.Fatalf("%s: batch failed: %v", transport, err)
+		}
+		for i, elem := range batch {
+			if !isErrorCode(elem.Error, (&invalidRequestError{}).ErrorCode()) {
+				t.Errorf("%s: element %d error mismatch: have %v, want batch too large", transport, i, elem.Error)
+			}
+		}
+		batch = batch[:2]
+		for i := range batch {
+			batch[i] = BatchElem{Method: "service_noArgsRets", Result: new(interface{})}
+		}
+		if err := client.BatchCall(batch); err != nil {
+			t.Fatalf("%s: small batch failed: %v", transport, err)
+		}
+		for i, elem := range batch {
+			if elem.Error != nil {
+				t.Errorf("%s: small batch element %d failed: %v", transport, i, elem.Error)
+			}
+		}
+		client.Close()
+		hs.Close()
+	}
+}
+
+func TestServerTimeout(t *testing.T) { log.DebugLog()
+	server := newTestServer("service", new(TimeoutService))
+	defer server.Stop()
+	server.SetLimits(Limits{Timeout: 50 * time.Millisecond})
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	start := time.Now()
+	if err := client.Call(nil, "service_wait", time.Minute); !isErrorCode(err, (&timeoutError{}).ErrorCode()) {
+		t.Fatalf("call error mismatch: have %v, want timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("handler context not cancelled, call took %v", elapsed)
+	}
+	if err := client.Call(nil, "service_wait", time.Millisecond); err != nil {
+		t.Fatalf("fast call failed: %v", err)
+	}
+	// Calls ignoring their context return their result regardless of the timeout
+	var result string
+	if err := client.Call(&result, "service_block", 100*time.Millisecond); err != nil {
+		t.Fatalf("slow call failed: %v", err)
+	}
+	if result != "done" {
+		t.Errorf("slow call result mismatch: have %q, want %q", result, "done")
+	}
+}
+
+func TestServerMaxInFlight(t *testing.T) { log.DebugLog()
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+	server.SetLimits(Limits{MaxInFlight: 1})
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Concurrent calls on the connection must be executed one by one
+	var (
+		sleep = 100 * time.Millisecond
+		start = time.Now()
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.Call(nil, "service_sleep", sleep); err != nil {
+				t.Errorf("call failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed < 3*sleep {
+		t.Errorf("calls executed concurrently: took %v, want at least %v", elapsed, 3*sleep)
+	}
+}
+
+func TestServerMaxInFlightHTTP(t *testing.T) { log.DebugLog()
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+	server.SetLimits(Limits{MaxInFlight: 1})
+
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	// Requests beyond the cap of the server are rejected, not queued
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		client, err := DialHTTP(hs.URL)
+		if err != nil {
+			t.Fatalf("failed to dial client %d: %v", i, err)
+		}
+		defer client.Close()
+
+		go func() {
+			errs <- client.Call(nil, "service_sleep", 200*time.Millisecond)
+		}()
+	}
+	var rejected int
+	for i := 0; i < 2; i++ {
+		if err := <-errs; isErrorCode(err, (&limitExceededError{}).ErrorCode()) {
+			rejected++
+		} else if err != nil {
+			t.Errorf("call failed: %v", err)
+		}
+	}
+	if rejected != 1 {
+		t.Fatalf("rejected calls mismatch: have %d, want 1", rejected)
+	}
+	// Slots are released once the requests complete
+	client, err := DialHTTP(hs.URL)
+	if err != nil {
+		t.Fatalf("failed to dial client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Call(nil, "service_noArgsRets"); err != nil {
+		t.Fatalf("call after completion failed: %v", err)
+	}
+}
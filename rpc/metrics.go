@@ -0,0 +1,167 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the meters and timers used by the RPC server.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// maxLoggedParamsLength is the length at which the parameters of slow calls are
+// truncated in the logs.
+const maxLoggedParamsLength = 256
+
+var (
+	// callTimer measures the latency of all the calls, successful or not. Calls
+	// rejected before execution are counted with zero latency.
+	callTimer = metrics.NewRegisteredTimer("rpc/calls", nil)
+
+	// failedCallMeter counts the calls returning an error, including those
+	// rejected by the access lists, the rate limits or for invalid parameters.
+	failedCallMeter = metrics.NewRegisteredMeter("rpc/errors", nil)
+
+	// deniedRequestMeter counts the calls rejected by the access lists.
+	deniedRequestMeter = metrics.NewRegisteredMeter("rpc/requests/denied", nil)
+
+	// limitedRequestMeter counts the calls rejected by the rate limits.
+	limitedRequestMeter = metrics.NewRegisteredMeter("rpc/requests/limited", nil)
+
+	// timedOutRequestMeter counts the calls exceeding the execution timeout.
+	timedOutRequestMeter = metrics.NewRegisteredMeter("rpc/requests/timeout", nil)
+
+	// rejectedBatchMeter counts the batches rejected for exceeding the size cap.
+	rejectedBatchMeter = metrics.NewRegisteredMeter("rpc/batches/rejected", nil)
+
+	// droppedNotificationMeter counts the notifications discarded from the full
+	// queues of the subscriptions.
+	droppedNotificationMeter = metrics.NewRegisteredMeter("rpc/subscriptions/dropped", nil)
+
+	// overflowedSubscriptionMeter counts the subscriptions terminated for
+	// overflowing their queue.
+	overflowedSubscriptionMeter = metrics.NewRegisteredMeter("rpc/subscriptions/overflows", nil)
+
+	// inFlightRequestGauge tracks the requests currently executing.
+	inFlightRequestGauge = metrics.NewRegisteredGauge("rpc/requests/inflight", nil)
+	inFlightRequests     int64 // Number of executing requests, updated atomically
+)
+
+// markDenied records a call rejected by the access list, both in the total and
+// the per method meter.
+func markDenied(method string) {
+	log.DebugLog()
+	deniedRequestMeter.Mark(1)
+	metrics.GetOrRegisterMeter("rpc/requests/denied/"+method, nil).Mark(1)
+}
+
+// SetSlowCallThreshold configures the server to log the calls executing longer
+// than the given threshold, along with their parameters. Zero disables logging.
+func (s *Server) SetSlowCallThreshold(threshold time.Duration) {
+	log.DebugLog()
+	s.slowCallThreshold = threshold
+}
+
+// recordCall updates the metrics of an executed call and logs it if it was slow.
+func (s *Server) recordCall(req *serverRequest, elapsed time.Duration, failed bool) {
+	log.DebugLog()
+	callTimer.Update(elapsed)
+	metrics.GetOrRegisterTimer("rpc/calls/"+req.method, nil).Update(elapsed)
+	if failed {
+		failedCallMeter.Mark(1)
+		metrics.GetOrRegisterMeter("rpc/errors/"+req.method, nil).Mark(1)
+	}
+	if s.slowCallThreshold > 0 && elapsed >= s.slowCallThreshold {
+		log.Warn("Slow RPC call", "method", req.method, "elapsed", common.PrettyDuration(elapsed), "failed", failed, "params", formatParams(req.params))
+	}
+}
+
+// recordRejected updates the metrics of a call rejected before its execution,
+// counting it as a failed call which took no time.
+func (s *Server) recordRejected(req *serverRequest) {
+	log.DebugLog()
+	s.recordCall(req, 0, true)
+}
+
+// formatParams renders the raw parameters of a request for logging, truncating
+// them to a sane length.
+func formatParams(params interface{}) string {
+	log.DebugLog()
+	var text string
+	switch params := params.(type) {
+	case nil:
+		return ""
+	case json.RawMessage:
+		text = string(params)
+	default:
+		text = fmt.Sprintf("%v", params)
+	}
+	if len(text) > maxLoggedParamsLength {
+		text = text[:maxLoggedParamsLength] + "..."
+	}
+	return text
+}
+
+// subscriptionCounts tracks the live subscriptions per namespace, backing the
+// subscription gauges. Gauges can only be set, so the counts are kept here.
+var subscriptionCounts = struct {
+	counts map[string]int64
+	lock   sync.Mutex
+}{counts: make(map[string]int64)}
+
+// trackSubscriptions adjusts the number of live subscriptions of a namespace.
+func trackSubscriptions(namespace string, delta int64) {
+	log.DebugLog()
+	subscriptionCounts.lock.Lock()
+	defer subscriptionCounts.lock.Unlock()
+
+	count := subscriptionCounts.counts[namespace] + delta
+	subscriptionCounts.counts[namespace] = count
+	metrics.GetOrRegisterGauge("rpc/subscriptions/"+namespace, nil).Update(count)
+}
+
+// SubscriptionCounts returns the number of live subscriptions per namespace,
+// across all the servers of the process.
+func SubscriptionCounts() map[string]int64 {
+	log.DebugLog()
+	subscriptionCounts.lock.Lock()
+	defer subscriptionCounts.lock.Unlock()
+
+	counts := make(map[string]int64, len(subscriptionCounts.counts))
+	for namespace, count := range subscriptionCounts.counts {
+		if count > 0 {
+			counts[namespace] = count
+		}
+	}
+	return counts
+}
+
+// subscriptionQueueHistogram returns the histogram of the queue depths of the
+// subscriptions of a namespace, sampled whenever a notification is queued.
+func subscriptionQueueHistogram(namespace string) metrics.Histogram {
+	log.DebugLog()
+	name := "rpc/subscriptions/" + namespace + "/queue"
+	return metrics.DefaultRegistry.GetOrRegister(name, func() metrics.Histogram {
+		return metrics.NewHistogram(metrics.NewExpDecaySample(1028, 0.015))
+	}).(metrics.Histogram)
+}
@@ -0,0 +1,214 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestServerCallMetrics(t *testing.T) { log.DebugLog()
+	// Metrics are registered lazily per method, enable them for the duration of
+	// the test and use namespaces not called by any other test
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = false }()
+
+	server := newTestServer("metricsvc", new(Service))
+	defer server.Stop()
+	if err := server.RegisterName("metricserr", new(ErrorService)); err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := client.Call(nil, "metricsvc_noArgsRets"); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+	if err := client.Call(nil, "metricserr_fail"); err == nil {
+		t.Fatalf("failing call succeeded")
+	}
+	if have := metrics.GetOrRegisterTimer("rpc/calls/metricsvc_noArgsRets", nil).Count(); have != 3 {
+		t.Errorf("call count mismatch: have %d, want 3", have)
+	}
+	if have := metrics.GetOrRegisterMeter("rpc/errors/metricsvc_noArgsRets", nil).Count(); have != 0 {
+		t.Errorf("error count mismatch: have %d, want 0", have)
+	}
+	if have := metrics.GetOrRegisterTimer("rpc/calls/metricserr_fail", nil).Count(); have != 1 {
+		t.Errorf("failing call count mismatch: have %d, want 1", have)
+	}
+	if have := metrics.GetOrRegisterMeter("rpc/errors/metricserr_fail", nil).Count(); have != 1 {
+		t.Errorf("failing error count mismatch: have %d, want 1", have)
+	}
+}
+
+func TestServerRejectedCallMetrics(t *testing.T) { log.DebugLog()
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = false }()
+
+	server := newTestServer("rejectsvc", new(Service))
+	defer server.Stop()
+	acl, _ := ParseAccessList("rejectsvc_*,!rejectsvc_rets")
+	server.SetAccessList(acl)
+	server.SetLimits(Limits{MethodCosts: map[string]int{"rejectsvc_sleep": 10}, ConnRate: 0.001, ConnBurst: 5})
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Calls rejected before execution count as failed calls of their method
+	if err := client.Call(nil, "rejectsvc_rets"); err == nil {
+		t.Fatalf("denied call succeeded")
+	}
+	if err := client.Call(nil, "rejectsvc_echo", "missing"); err == nil {
+		t.Fatalf("call with invalid parameters succeeded")
+	}
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	resp, err := http.Post(hs.URL, contentType, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"rejectsvc_echo"}`))
+	if err != nil {
+		t.Fatalf("call without parameters failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if err := client.Call(nil, "rejectsvc_sleep", time.Millisecond); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if err := client.Call(nil, "rejectsvc_sleep", time.Millisecond); err == nil {
+		t.Fatalf("rate limited call succeeded")
+	}
+	for method, want := range map[string]int64{"rejectsvc_rets": 1, "rejectsvc_echo": 2} {
+		if have := metrics.GetOrRegisterTimer("rpc/calls/"+method, nil).Count(); have != want {
+			t.Errorf("%s: call count mismatch: have %d, want %d", method, have, want)
+		}
+		if have := metrics.GetOrRegisterMeter("rpc/errors/"+method, nil).Count(); have != want {
+			t.Errorf("%s: error count mismatch: have %d, want %d", method, have, want)
+		}
+	}
+	if have := metrics.GetOrRegisterTimer("rpc/calls/rejectsvc_sleep", nil).Count(); have != 2 {
+		t.Errorf("limited call count mismatch: have %d, want 2", have)
+	}
+	if have := metrics.GetOrRegisterMeter("rpc/errors/rejectsvc_sleep", nil).Count(); have != 1 {
+		t.Errorf("limited error count mismatch: have %d, want 1", have)
+	}
+}
+
+func TestServerSlowCallLogging(t *testing.T) { log.DebugLog()
+	var (
+		records []*log.Record
+		lock    sync.Mutex
+	)
+	handler := log.Root().GetHandler()
+	defer log.Root().SetHandler(handler)
+
+	log.Root().SetHandler(log.FuncHandler(func(r *log.Record) error {
+		if r.Msg == "Slow RPC call" {
+			lock.Lock()
+			records = append(records, r)
+			lock.Unlock()
+		}
+		return nil
+	}))
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+	server.SetSlowCallThreshold(50 * time.Millisecond)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	if err := client.Call(nil, "service_sleep", time.Millisecond); err != nil {
+		t.Fatalf("fast call failed: %v", err)
+	}
+	if err := client.Call(nil, "service_sleep", 100*time.Millisecond); err != nil {
+		t.Fatalf("slow call failed: %v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(records) != 1 {
+		t.Fatalf("logged call count mismatch: have %d, want 1", len(records))
+	}
+	ctx := make(map[interface{}]interface{})
+	for i := 0; i+1 < len(records[0].Ctx); i += 2 {
+		ctx[records[0].Ctx[i]] = records[0].Ctx[i+1]
+	}
+	if ctx["method"] != "service_sleep" {
+		t.Errorf("logged method mismatch: have %v, want service_sleep", ctx["method"])
+	}
+	if params, _ := ctx["params"].(string); !strings.Contains(params, "100000000") {
+		t.Errorf("logged params mismatch: have %v, want the sleep duration", ctx["params"])
+	}
+}
+
+func TestFormatParams(t *testing.T) { log.DebugLog()
+	if have := formatParams(nil); have != "" {
+		t.Errorf("nil params mismatch: have %q", have)
+	}
+	if have := formatParams(json.RawMessage(`["0x1",true]`)); have != `["0x1",true]` {
+		t.Errorf("raw params mismatch: have %q", have)
+	}
+	long := json.RawMessage(`["` + strings.Repeat("a", 2*maxLoggedParamsLength) + `"]`)
+	if have := formatParams(long); len(have) != maxLoggedParamsLength+3 || !strings.HasSuffix(have, "...") {
+		t.Errorf("long params not truncated: have %d bytes", len(have))
+	}
+}
+
+func TestSubscriptionCounts(t *testing.T) { log.DebugLog()
+	server := newTestServer("subcount", new(NotificationTestService))
+	defer server.Stop()
+
+	// waitCount waits until the live subscription count of the namespace reaches
+	// the expected value, as subscriptions are activated and dropped asynchronously
+	waitCount := func(want int64) {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if SubscriptionCounts()["subcount"] == want {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("subscription count mismatch: have %d, want %d", SubscriptionCounts()["subcount"], want)
+	}
+	client := DialInProc(server)
+
+	var subs []*ClientSubscription
+	for i := 0; i < 3; i++ {
+		sub, err := client.Subscribe(context.Background(), "subcount", make(chan int), "someSubscription", 1, 1)
+		if err != nil {
+			t.Fatalf("subscription %d failed: %v", i, err)
+		}
+		subs = append(subs, sub)
+	}
+	waitCount(3)
+
+	// Explicit unsubscriptions and closed connections release the subscriptions
+	subs[0].Unsubscribe()
+	waitCount(2)
+
+	client.Close()
+	waitCount(0)
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// QueuePolicy defines what happens when a subscriber can't keep up with its
+// notifications and the queue of its subscription is full.
+type QueuePolicy int
+
+const (
+	// QueueBlock blocks the producer of the notifications until the queue has
+	// room again, slowing it down to the pace of the subscriber.
+	QueueBlock QueuePolicy = iota
+
+	// QueueDropOldest discards the oldest queued notification to make room. The
+	// number of discarded notifications is reported to the subscriber along with
+	// the next delivered one.
+	QueueDropOldest
+
+	// QueueDisconnect terminates the subscription with an overflow error and
+	// closes the connection of the subscriber.
+	QueueDisconnect
+)
+
+// String implements fmt.Stringer.
+func (p QueuePolicy) String() string {
+	log.DebugLog()
+	switch p {
+	case QueueBlock:
+		return "block"
+	case QueueDropOldest:
+		return "dropoldest"
+	case QueueDisconnect:
+		return "disconnect"
+	default:
+		return fmt.Sprintf("QueuePolicy(%d)", int(p))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p QueuePolicy) MarshalText() ([]byte, error) {
+	log.DebugLog()
+	switch p {
+	case QueueBlock, QueueDropOldest, QueueDisconnect:
+		return []byte(p.String()), nil
+	default:
+		return nil, fmt.Errorf("unknown queue policy %d", int(p))
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *QueuePolicy) UnmarshalText(text []byte) error {
+	log.DebugLog()
+	switch string(text) {
+	case "block":
+		*p = QueueBlock
+	case "dropoldest":
+		*p = QueueDropOldest
+	case "disconnect":
+		*p = QueueDisconnect
+	default:
+		return fmt.Errorf(`unknown queue policy %q, want "block", "dropoldest" or "disconnect"`, text)
+	}
+	return nil
+}
+
+// SetSubscriptionQueue configures the notification queue of the subscriptions
+// created on the server. A non-positive size disables queueing, notifications
+// are then written to the connection directly by their producer. It must be set
+// before the server starts serving requests.
+func (s *Server) SetSubscriptionQueue(size int, policy QueuePolicy) {
+	log.DebugLog()
+	s.queueSize = size
+	s.queuePolicy = policy
+}
+
+// notificationQueue is the bounded queue of the pending notifications of a
+// subscription, drained by a dedicated writer.
+type notificationQueue struct {
+	size   int
+	policy QueuePolicy
+	depths metrics.Histogram // Queue depths sampled on each push
+
+	items   []interface{} // Pending notifications, oldest first
+	dropped uint64        // Notifications dropped since the last delivered one
+	closed  bool          // Set when the subscription ended
+	lock    sync.Mutex
+	cond    *sync.Cond // Signalled when items are pushed or popped, or on close
+}
+
+// newNotificationQueue creates a queue of the given capacity and policy, sampling
+// its depth into the given histogram.
+func newNotificationQueue(size int, policy QueuePolicy, depths metrics.Histogram) *notificationQueue {
+	log.DebugLog()
+	q := &notificationQueue{size: size, policy: policy, depths: depths}
+	q.cond = sync.NewCond(&q.lock)
+	return q
+}
+
+// push enqueues a notification according to the overflow policy of the queue.
+// It returns ErrSubscriptionQueueOverflow if the queue is full with the
+// disconnect policy, in which case the queue is closed.
+func (q *notificationQueue) push(data interface{}) error {
+	log.DebugLog()
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for !q.closed && len(q.items) >= q.size {
+		switch q.policy {
+		case QueueDropOldest:
+			q.items[0] = nil
+			q.items = q.items[1:]
+			q.dropped++
+			droppedNotificationMeter.Mark(1)
+
+		case QueueDisconnect:
+			q.items, q.closed = nil, true
+			q.cond.Broadcast()
+			return ErrSubscriptionQueueOverflow
+
+		default:
+			q.cond.Wait()
+		}
+	}
+	if q.closed {
+		return nil
+	}
+	q.items = append(q.items, data)
+	q.depths.Update(int64(len(q.items)))
+	q.cond.Broadcast()
+	return nil
+}
+
+// pop dequeues the oldest notification, along with the number of notifications
+// dropped before it. It blocks until a notification is available, returning
+// false if the queue was closed.
+func (q *notificationQueue) pop() (interface{}, uint64, bool) {
+	log.DebugLog()
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for !q.closed && len(q.items) == 0 {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, 0, false
+	}
+	data, dropped := q.items[0], q.dropped
+	q.items[0] = nil
+	q.items, q.dropped = q.items[1:], 0
+	q.cond.Broadcast()
+	return data, dropped, true
+}
+
+// close terminates the queue, discarding the pending notifications and waking
+// up the blocked producer and writer.
+func (q *notificationQueue) close() {
+	log.DebugLog()
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.items, q.closed = nil, true
+	q.cond.Broadcast()
+}
@@ -0,0 +1,266 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// QueueTestService sends a burst of notifications to its subscriber once released,
+// reporting the result of every Notify call.
+type QueueTestService struct {
+	release chan struct{}
+	results chan error
+}
+
+func (s *QueueTestService) Flood(ctx context.Context, n int) (*Subscription, error) { log.DebugLog()
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported {
+		return nil, ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+
+	go func() {
+		<-s.release
+
+		// Wait for the subscription to be activated, notifications are dropped before
+		for {
+			notifier.subMu.RLock()
+			_, active := notifier.active[sub.ID]
+			notifier.subMu.RUnlock()
+			if active {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		for i := 0; i < n; i++ {
+			s.results <- notifier.Notify(sub.ID, i)
+		}
+		close(s.results)
+	}()
+	return sub, nil
+}
+
+// newFloodTest subscribes to a flood of n notifications on a server with the
+// given queue configuration, returning the decoder of the subscriber side. The
+// subscriber doesn't read anything until the decoder is used.
+func newFloodTest(t *testing.T, size int, policy QueuePolicy, n int) (*QueueTestService, *json.Decoder) { log.DebugLog()
+	service := &QueueTestService{release: make(chan struct{}), results: make(chan error, n)}
+
+	server := NewServer()
+	server.SetSubscriptionQueue(size, policy)
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatal(err)
+	}
+	clientConn, serverConn := net.Pipe()
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation|OptionSubscriptions)
+
+	out, in := json.NewEncoder(clientConn), json.NewDecoder(clientConn)
+	request := map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "eth_subscribe", "params": []interface{}{"flood", n}}
+	if err := out.Encode(request); err != nil {
+		t.Fatal(err)
+	}
+	var response jsonSuccessResponse
+	if err := in.Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := response.Result.(string); !ok {
+		t.Fatalf("expected subscription id, got %v", response.Result)
+	}
+	return service, in
+}
+
+func TestQueuePolicyText(t *testing.T) { log.DebugLog()
+	for _, policy := range []QueuePolicy{QueueBlock, QueueDropOldest, QueueDisconnect} {
+		text, err := policy.MarshalText()
+		if err != nil {
+			t.Fatalf("%v: marshal error: %v", policy, err)
+		}
+		var parsed QueuePolicy
+		if err := parsed.UnmarshalText(text); err != nil {
+			t.Fatalf("%s: unmarshal error: %v", text, err)
+		}
+		if parsed != policy {
+			t.Errorf("%s: round trip mismatch: have %v, want %v", text, parsed, policy)
+		}
+	}
+	var policy QueuePolicy
+	if err := policy.UnmarshalText([]byte("drop")); err == nil {
+		t.Errorf("unknown policy accepted")
+	}
+}
+
+func TestSubscriptionQueueBlock(t *testing.T) { log.DebugLog()
+	service, in := newFloodTest(t, 1, QueueBlock, 10)
+	close(service.release)
+
+	// The stalled subscriber holds the producer back
+	time.Sleep(200 * time.Millisecond)
+	if sent := len(service.results); sent >= 10 {
+		t.Fatalf("producer not blocked by stalled subscriber, sent %d notifications", sent)
+	}
+	// Every notification is delivered once the subscriber reads them
+	for i := 0; i < 10; i++ {
+		var notification jsonNotification
+		if err := in.Decode(&notification); err != nil {
+			t.Fatal(err)
+		}
+		if have := int(notification.Params.Result.(float64)); have != i {
+			t.Fatalf("notification %d: have %d", i, have)
+		}
+		if notification.Params.Dropped != 0 {
+			t.Fatalf("notification %d: reported %d dropped", i, notification.Params.Dropped)
+		}
+	}
+	for err := range service.results {
+		if err != nil {
+			t.Fatalf("notify error: %v", err)
+		}
+	}
+}
+
+func TestSubscriptionQueueDropOldest(t *testing.T) { log.DebugLog()
+	service, in := newFloodTest(t, 2, QueueDropOldest, 10)
+	close(service.release)
+
+	// The producer is never held back by the stalled subscriber
+	for err := range service.results {
+		if err != nil {
+			t.Fatalf("notify error: %v", err)
+		}
+	}
+	// The newest notifications are delivered, reporting the dropped ones
+	var received, dropped uint64
+	for last := -1; last != 9; {
+		var notification jsonNotification
+		if err := in.Decode(&notification); err != nil {
+			t.Fatal(err)
+		}
+		value := int(notification.Params.Result.(float64))
+		if value <= last {
+			t.Fatalf("notification %d delivered after %d", value, last)
+		}
+		received, dropped, last = received+1, dropped+notification.Params.Dropped, value
+	}
+	if dropped == 0 || received+dropped != 10 {
+		t.Errorf("delivery mismatch: received %d, dropped %d, want 10 in total", received, dropped)
+	}
+}
+
+func TestSubscriptionQueueDisconnect(t *testing.T) { log.DebugLog()
+	service, in := newFloodTest(t, 2, QueueDisconnect, 10)
+	close(service.release)
+
+	// The producer is told about the overflow
+	overflows := 0
+	for err := range service.results {
+		switch err {
+		case nil:
+		case ErrSubscriptionQueueOverflow:
+			overflows++
+		default:
+			t.Fatalf("unexpected notify error: %v", err)
+		}
+	}
+	if overflows != 1 {
+		t.Fatalf("overflow reported %d times", overflows)
+	}
+	// The subscriber gets the pending notification, the overflow error and is
+	// disconnected
+	for {
+		var notification jsonNotification
+		if err := in.Decode(&notification); err != nil {
+			t.Fatalf("no overflow error before disconnect: %v", err)
+		}
+		if notification.Params.Error != nil {
+			if notification.Params.Error.Code != (&subscriptionOverflowError{}).ErrorCode() {
+				t.Fatalf("unexpected subscription error: %v", notification.Params.Error)
+			}
+			break
+		}
+	}
+	var msg json.RawMessage
+	if err := in.Decode(&msg); err == nil {
+		t.Fatalf("connection not closed after overflow, got %s", msg)
+	}
+}
+
+// This test checks that the client reports the notifications dropped by the
+// server and ends the subscription with an overflow error when told so.
+func TestClientSubscriptionOverflow(t *testing.T) { log.DebugLog()
+	clientConn, serverConn := net.Pipe()
+	client, _ := newClient(context.Background(), func(context.Context) (net.Conn, error) {
+		return clientConn, nil
+	})
+	defer client.Close()
+
+	// Fake the server side of the subscription, terminating it once the
+	// notification was received
+	received := make(chan struct{})
+	go func() {
+		codec := NewJSONCodec(serverConn)
+		requests, _, err := codec.ReadRequestHeaders()
+		if err != nil {
+			return
+		}
+		codec.Write(codec.CreateResponse(requests[0].id, "0x1"))
+
+		notification := codec.CreateNotification("0x1", "eth", 5)
+		setNotificationDropped(notification, 3)
+		codec.Write(notification)
+
+		<-received
+		codec.Write(createErrorNotification("0x1", "eth", &subscriptionOverflowError{}))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	nc := make(chan int)
+	sub, err := client.EthSubscribe(ctx, nc, "flood")
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	select {
+	case value := <-nc:
+		if value != 5 {
+			t.Fatalf("unexpected value %d", value)
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed before notification: %v", err)
+	case <-ctx.Done():
+		t.Fatal("notification not delivered")
+	}
+	if dropped := sub.Dropped(); dropped != 3 {
+		t.Errorf("dropped count mismatch: have %d, want 3", dropped)
+	}
+	close(received)
+
+	select {
+	case err := <-sub.Err():
+		if err != ErrSubscriptionQueueOverflow {
+			t.Fatalf("subscription error mismatch: have %v, want %v", err, ErrSubscriptionQueueOverflow)
+		}
+	case <-ctx.Done():
+		t.Fatal("overflow not reported")
+	}
+}
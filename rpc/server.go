@@ -24,6 +24,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 	"gopkg.in/fatih/set.v0"
@@ -119,13 +120,19 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error { log.DebugLo
 	return nil
 }
 
+// SetAccessList restricts the methods callable on the server. It must be set
+// before the server starts serving requests, nil permits all methods.
+func (s *Server) SetAccessList(acl *AccessList) { log.DebugLog()
+	s.acl = acl
+}
+
 // serveRequest will reads requests from the codec, calls the RPC callback and
 // writes the response to the given codec.
 //
 // If singleShot is true it will process a single request, otherwise it will handle
 // requests until the codec returns an error when reading a request (in most cases
 // an EOF). It executes requests in parallel when singleShot is false.
-func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecOption) error { log.DebugLog()
+func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot bool, options CodecOption) error { log.DebugLog()
 	var pend sync.WaitGroup
 
 	defer func() {
@@ -140,14 +147,26 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 		s.codecsMu.Unlock()
 	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	// identify the connection for the rate limits of the server
+	ctx = context.WithValue(ctx, peerKey{}, newPeerInfo(ctx))
+
+	// bound the requests executing concurrently on a connection, the reading
+	// of new requests blocks while the limit is reached
+	var inflight chan struct{}
+	if s.limits.MaxInFlight > 0 {
+		inflight = make(chan struct{}, s.limits.MaxInFlight)
+	}
+
 	// if the codec supports notification include a notifier that callbacks can use
 	// to send notification to clients. It is thight to the codec/connection. If the
 	// connection is closed the notifier will stop and cancels all active subscriptions.
 	if options&OptionSubscriptions == OptionSubscriptions {
-		ctx = context.WithValue(ctx, notifierKey{}, newNotifier(codec))
+		notifier := newNotifier(codec, s.queueSize, s.queuePolicy)
+		ctx = context.WithValue(ctx, notifierKey{}, notifier)
+		defer notifier.close()
 	}
 	s.codecsMu.Lock()
 	if atomic.LoadInt32(&s.run) != 1 { // server stopped
@@ -174,20 +193,32 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 		// check if server is ordered to shutdown and return an error
 		// telling the client that his request failed.
 		if atomic.LoadInt32(&s.run) != 1 {
-			err = &shutdownError{}
-			if batch {
-				resps := make([]interface{}, len(reqs))
-				for i, r := range reqs {
-					resps[i] = codec.CreateErrorResponse(&r.id, err)
-				}
-				codec.Write(resps)
-			} else {
-				codec.Write(codec.CreateErrorResponse(&reqs[0].id, err))
-			}
+			writeErrors(codec, reqs, batch, &shutdownError{})
 			return nil
 		}
+		// reject batches exceeding the size cap of the server as a whole
+		if batch && s.limits.MaxBatchSize > 0 && len(reqs) > s.limits.MaxBatchSize {
+			rejectedBatchMeter.Mark(1)
+			writeErrors(codec, reqs, true, &invalidRequestError{fmt.Sprintf("batch too large (%d>%d)", len(reqs), s.limits.MaxBatchSize)})
+			if singleShot {
+				return nil
+			}
+			continue
+		}
 		// If a single shot request is executing, run and return immediately
 		if singleShot {
+			// reject the request if the server executes too many already, as
+			// there is no connection to stop reading from
+			if s.singleShots != nil {
+				select {
+				case s.singleShots <- struct{}{}:
+					defer func() { <-s.singleShots }()
+				default:
+					limitedRequestMeter.Mark(1)
+					writeErrors(codec, reqs, batch, &limitExceededError{"too many requests in flight"})
+					return nil
+				}
+			}
 			if batch {
 				s.execBatch(ctx, codec, reqs)
 			} else {
@@ -196,10 +227,16 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 			return nil
 		}
 		// For multi-shot connections, start a goroutine to serve and loop back
+		if inflight != nil {
+			inflight <- struct{}{}
+		}
 		pend.Add(1)
 
 		go func(reqs []*serverRequest, batch bool) {
 			defer pend.Done()
+			if inflight != nil {
+				defer func() { <-inflight }()
+			}
 			if batch {
 				s.execBatch(ctx, codec, reqs)
 			} else {
@@ -210,19 +247,33 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 	return nil
 }
 
+// writeErrors responds to all the requests read from the codec with the given
+// error, keeping the batch format if they arrived in a batch.
+func writeErrors(codec ServerCodec, reqs []*serverRequest, batch bool, err Error) { log.DebugLog()
+	if !batch {
+		codec.Write(codec.CreateErrorResponse(&reqs[0].id, err))
+		return
+	}
+	resps := make([]interface{}, len(reqs))
+	for i, r := range reqs {
+		resps[i] = codec.CreateErrorResponse(&r.id, err)
+	}
+	codec.Write(resps)
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes the
 // response back using the given codec. It will block until the codec is closed or the server is
 // stopped. In either case the codec is closed.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) { log.DebugLog()
 	defer codec.Close()
-	s.serveRequest(codec, false, options)
+	s.serveRequest(context.Background(), codec, false, options)
 }
 
 // ServeSingleRequest reads and processes a single RPC request from the given codec. It will not
 // close the codec unless a non-recoverable error has occurred. Note, this method will return after
 // a single request has been processed!
 func (s *Server) ServeSingleRequest(codec ServerCodec, options CodecOption) { log.DebugLog()
-	s.serveRequest(codec, true, options)
+	s.serveRequest(context.Background(), codec, true, options)
 }
 
 // Stop will stop reading new requests, wait for stopPendingRequestTimeout to allow pending requests to finish,
@@ -256,8 +307,25 @@ func (s *Server) createSubscription(ctx context.Context, c ServerCodec, req *ser
 // handle executes a request and returns the response from the callback.
 func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverRequest) (interface{}, func()) { log.DebugLog()
 	if req.err != nil {
+		if req.method != "" { // invalid parameters of an existing method
+			s.recordRejected(req)
+		}
 		return codec.CreateErrorResponse(&req.id, req.err), nil
 	}
+	// reject calls excluded by the access list before dispatching them
+	if !req.isUnsubscribe && !s.acl.Allowed(req.method) {
+		markDenied(req.method)
+		s.recordRejected(req)
+		return codec.CreateErrorResponse(&req.id, &accessDeniedError{req.method}), nil
+	}
+	// charge the cost of the call against the rate limits of the connection
+	if !req.isUnsubscribe {
+		if err := s.charge(ctx, req.method); err != nil {
+			limitedRequestMeter.Mark(1)
+			s.recordRejected(req)
+			return codec.CreateErrorResponse(&req.id, err), nil
+		}
+	}
 
 	if req.isUnsubscribe { // cancel subscription, first param must be the subscription id
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
@@ -277,7 +345,9 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	}
 
 	if req.callb.isSubscribe {
+		start := time.Now()
 		subid, err := s.createSubscription(ctx, codec, req)
+		s.recordCall(req, time.Since(start), err != nil)
 		if err != nil {
 			return codec.CreateErrorResponse(&req.id, &callbackError{err.Error()}), nil
 		}
@@ -296,9 +366,16 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		rpcErr := &invalidParamsError{fmt.Sprintf("%s%s%s expects %d parameters, got %d",
 			req.svcname, serviceMethodSeparator, req.callb.method.Name,
 			len(req.callb.argTypes), len(req.args))}
+		s.recordRejected(req)
 		return codec.CreateErrorResponse(&req.id, rpcErr), nil
 	}
 
+	// execute RPC method and return result, cancelling its context on timeout
+	if s.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.limits.Timeout)
+		defer cancel()
+	}
 	arguments := []reflect.Value{req.callb.rcvr}
 	if req.callb.hasCtx {
 		arguments = append(arguments, reflect.ValueOf(ctx))
@@ -306,32 +383,46 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if len(req.args) > 0 {
 		arguments = append(arguments, req.args...)
 	}
-
-	// execute RPC method and return result
+	inFlightRequestGauge.Update(atomic.AddInt64(&inFlightRequests, 1))
+	start := time.Now()
 	reply := req.callb.method.Func.Call(arguments)
+	elapsed := time.Since(start)
+	inFlightRequestGauge.Update(atomic.AddInt64(&inFlightRequests, -1))
+
+	if req.callb.errPos >= 0 && !reply[req.callb.errPos].IsNil() { // test if method returned an error
+		s.recordCall(req, elapsed, true)
+		if ctx.Err() == context.DeadlineExceeded {
+			timedOutRequestMeter.Mark(1)
+			return codec.CreateErrorResponse(&req.id, &timeoutError{req.method}), nil
+		}
+		e := reply[req.callb.errPos].Interface().(error)
+		return createCallbackErrorResponse(codec, &req.id, e), nil
+	}
+	s.recordCall(req, elapsed, false)
+
 	if len(reply) == 0 {
 		return codec.CreateResponse(req.id, nil), nil
 	}
+	return codec.CreateResponse(req.id, reply[0].Interface()), nil
+}
 
-	if req.callb.errPos >= 0 { // test if method returned an error
-		if !reply[req.callb.errPos].IsNil() {
-			e := reply[req.callb.errPos].Interface().(error)
-			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
-			return res, nil
-		}
+// createCallbackErrorResponse creates the error response for an error returned by
+// a callback. Errors specifying their own code and data are relayed as is, any
+// other error is reported as a generic callback error.
+func createCallbackErrorResponse(codec ServerCodec, id interface{}, err error) interface{} { log.DebugLog()
+	rpcErr, ok := err.(Error)
+	if !ok {
+		rpcErr = &callbackError{err.Error()}
 	}
-	return codec.CreateResponse(req.id, reply[0].Interface()), nil
+	if de, ok := err.(DataError); ok {
+		return codec.CreateErrorResponseWithInfo(id, rpcErr, de.ErrorData())
+	}
+	return codec.CreateErrorResponse(id, rpcErr)
 }
 
 // exec executes the given request and writes the result back using the codec.
-func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest) { log.DebugLog()
-	var response interface{}
-	var callback func()
-	if req.err != nil {
-		response = codec.CreateErrorResponse(&req.id, req.err)
-	} else {
-		response, callback = s.handle(ctx, codec, req)
-	}
+func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest) { defer log.DebugLogSpan()()
+	response, callback := s.handle(ctx, codec, req)
 
 	if err := codec.Write(response); err != nil {
 		log.Error(fmt.Sprintf("%v\n", err))
@@ -346,17 +437,13 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 
 // execBatch executes the given requests and writes the result back using the codec.
 // It will only write the response back when the last request is processed.
-func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) { log.DebugLog()
+func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) { defer log.DebugLogSpan()()
 	responses := make([]interface{}, len(requests))
 	var callbacks []func()
 	for i, req := range requests {
-		if req.err != nil {
-			responses[i] = codec.CreateErrorResponse(&req.id, req.err)
-		} else {
-			var callback func()
-			if responses[i], callback = s.handle(ctx, codec, req); callback != nil {
-				callbacks = append(callbacks, callback)
-			}
+		var callback func()
+		if responses[i], callback = s.handle(ctx, codec, req); callback != nil {
+			callbacks = append(callbacks, callback)
 		}
 	}
 
@@ -410,7 +497,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 
 		if r.isPubSub { // eth_subscribe, r.method contains the subscription method name
 			if callb, ok := svc.subscriptions[r.method]; ok {
-				requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+				requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: r.service + subscribeMethodSuffix, params: r.params, callb: callb}
 				if r.params != nil && len(callb.argTypes) > 0 {
 					argTypes := []reflect.Type{reflect.TypeOf("")}
 					argTypes = append(argTypes, callb.argTypes...)
@@ -427,7 +514,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		}
 
 		if callb, ok := svc.callbacks[r.method]; ok { // lookup RPC method
-			requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+			requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: r.service + serviceMethodSeparator + r.method, params: r.params, callb: callb}
 			if r.params != nil && len(callb.argTypes) > 0 {
 				if args, err := codec.ParseRequestArguments(callb.argTypes, r.params); err == nil {
 					requests[i].args = args
@@ -19,10 +19,13 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net"
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 type Service struct{}
@@ -160,3 +163,62 @@ func TestServerMethodExecution(t *testing.T) { log.DebugLog()
 func TestServerMethodWithCtx(t *testing.T) { log.DebugLog()
 	testServerMethodExecution(t, "echoWithCtx")
 }
+
+// codedError is an error carrying a custom JSON-RPC error code and data.
+type codedError struct{}
+
+func (codedError) Error() string { log.DebugLog()
+	return "custom error"
+}
+
+func (codedError) ErrorCode() int { log.DebugLog()
+	return 444
+}
+
+func (codedError) ErrorData() interface{} { log.DebugLog()
+	return "custom data"
+}
+
+type ErrorService struct{}
+
+func (s *ErrorService) Fail() error { log.DebugLog()
+	return codedError{}
+}
+
+func (s *ErrorService) FailPlain() error { log.DebugLog()
+	return errors.New("plain error")
+}
+
+// Tests that errors returned by callbacks retain their error codes and data.
+func TestServerErrorData(t *testing.T) { log.DebugLog()
+	server := NewServer()
+	if err := server.RegisterName("test", new(ErrorService)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	err := client.Call(nil, "test_fail")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if code := err.(Error).ErrorCode(); code != 444 {
+		t.Errorf("error code mismatch: have %d, want %d", code, 444)
+	}
+	if data := err.(DataError).ErrorData(); data != "custom data" {
+		t.Errorf("error data mismatch: have %v, want %v", data, "custom data")
+	}
+	if err.Error() != "custom error" {
+		t.Errorf("error message mismatch: have %q, want %q", err.Error(), "custom error")
+	}
+	err = client.Call(nil, "test_failPlain")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if code := err.(Error).ErrorCode(); code != -32000 {
+		t.Errorf("error code mismatch: have %d, want %d", code, -32000)
+	}
+	if data := err.(DataError).ErrorData(); data != nil {
+		t.Errorf("unexpected error data: %v", data)
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
+
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -30,6 +32,10 @@ var (
 	ErrSubscriptionNotFound = errors.New("subscription not found")
 )
 
+// overflowNotifyTimeout is the time given to an overflowing subscriber to receive
+// the error terminating its subscription before the connection is closed.
+const overflowNotifyTimeout = 5 * time.Second
+
 // ID defines a pseudo random number that is used to identify RPC subscriptions.
 type ID string
 
@@ -38,7 +44,8 @@ type ID string
 type Subscription struct {
 	ID        ID
 	namespace string
-	err       chan error // closed on unsubscribe
+	err       chan error         // closed on unsubscribe
+	queue     *notificationQueue // pending notifications, nil if written directly
 }
 
 // Err returns a channel that is closed when the client send an unsubscribe request.
@@ -53,20 +60,27 @@ type notifierKey struct{}
 // Notifier is tight to a RPC connection that supports subscriptions.
 // Server callbacks use the notifier to send notifications.
 type Notifier struct {
-	codec    ServerCodec
-	subMu    sync.RWMutex // guards active and inactive maps
-	active   map[ID]*Subscription
-	inactive map[ID]*Subscription
+	codec     ServerCodec
+	queueSize int          // capacity of the subscription queues, 0 = unqueued
+	policy    QueuePolicy  // behaviour of the subscriptions overflowing their queue
+	subMu     sync.RWMutex // guards active and inactive maps
+	active    map[ID]*Subscription
+	inactive  map[ID]*Subscription
+	closed    bool // set when the connection closed, no more activations
 }
 
 // newNotifier creates a new notifier that can be used to send subscription
-// notifications to the client.
-func newNotifier(codec ServerCodec) *Notifier {
+// notifications to the client. If queueSize is positive the notifications
+// of every subscription are queued and written by a dedicated goroutine,
+// overflows being handled according to policy.
+func newNotifier(codec ServerCodec, queueSize int, policy QueuePolicy) *Notifier {
 	log.DebugLog()
 	return &Notifier{
-		codec:    codec,
-		active:   make(map[ID]*Subscription),
-		inactive: make(map[ID]*Subscription),
+		codec:     codec,
+		queueSize: queueSize,
+		policy:    policy,
+		active:    make(map[ID]*Subscription),
+		inactive:  make(map[ID]*Subscription),
 	}
 }
 
@@ -92,22 +106,90 @@ func (n *Notifier) CreateSubscription() *Subscription {
 
 // Notify sends a notification to the client with the given data as payload.
 // If an error occurs the RPC connection is closed and the error is returned.
+//
+// If the subscriptions are queued the notification is only queued for sending.
+// A full queue blocks the call, drops the oldest notification or terminates
+// the subscription with ErrSubscriptionQueueOverflow, depending on the queue
+// policy of the server.
 func (n *Notifier) Notify(id ID, data interface{}) error {
 	log.DebugLog()
 	n.subMu.RLock()
+	sub, active := n.active[id]
+	if active && sub.queue != nil {
+		n.subMu.RUnlock()
+		return n.enqueue(sub, data)
+	}
 	defer n.subMu.RUnlock()
 
-	sub, active := n.active[id]
 	if active {
-		notification := n.codec.CreateNotification(string(id), sub.namespace, data)
-		if err := n.codec.Write(notification); err != nil {
-			n.codec.Close()
-			return err
-		}
+		return n.send(sub, data, 0)
 	}
 	return nil
 }
 
+// send writes a notification of the subscription to the connection, along with
+// the number of notifications dropped before it. If the write fails the RPC
+// connection is closed.
+func (n *Notifier) send(sub *Subscription, data interface{}, dropped uint64) error {
+	log.DebugLog()
+	notification := n.codec.CreateNotification(string(sub.ID), sub.namespace, data)
+	if dropped > 0 {
+		setNotificationDropped(notification, dropped)
+	}
+	if err := n.codec.Write(notification); err != nil {
+		n.codec.Close()
+		return err
+	}
+	return nil
+}
+
+// enqueue queues a notification of the subscription, disconnecting the client
+// if the subscription overflows its queue.
+func (n *Notifier) enqueue(sub *Subscription, data interface{}) error {
+	log.DebugLog()
+	if err := sub.queue.push(data); err != nil {
+		overflowedSubscriptionMeter.Mark(1)
+		log.Debug("Subscription queue overflow, disconnecting", "id", sub.ID, "namespace", sub.namespace)
+		go n.disconnect(sub)
+		return err
+	}
+	return nil
+}
+
+// sendLoop writes the queued notifications of the subscription to the
+// connection until the subscription ends.
+func (n *Notifier) sendLoop(sub *Subscription) {
+	log.DebugLog()
+	for {
+		data, dropped, ok := sub.queue.pop()
+		if !ok {
+			return
+		}
+		if err := n.send(sub, data, dropped); err != nil {
+			return
+		}
+	}
+}
+
+// disconnect terminates an overflowing subscription, notifying the client with
+// a subscription overflow error before closing the connection. The connection
+// is closed regardless if the client doesn't take the error in time.
+func (n *Notifier) disconnect(sub *Subscription) {
+	log.DebugLog()
+	n.unsubscribe(sub.ID)
+
+	done := make(chan struct{})
+	go func() {
+		n.codec.Write(createErrorNotification(string(sub.ID), sub.namespace, &subscriptionOverflowError{}))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(overflowNotifyTimeout):
+	}
+	n.codec.Close()
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 func (n *Notifier) Closed() <-chan interface{} {
 	log.DebugLog()
@@ -122,7 +204,11 @@ func (n *Notifier) unsubscribe(id ID) error {
 	defer n.subMu.Unlock()
 	if s, found := n.active[id]; found {
 		close(s.err)
+		if s.queue != nil {
+			s.queue.close()
+		}
 		delete(n.active, id)
+		trackSubscriptions(s.namespace, -1)
 		return nil
 	}
 	return ErrSubscriptionNotFound
@@ -136,9 +222,34 @@ func (n *Notifier) activate(id ID, namespace string) {
 	log.DebugLog()
 	n.subMu.Lock()
 	defer n.subMu.Unlock()
-	if sub, found := n.inactive[id]; found {
+	if sub, found := n.inactive[id]; found && !n.closed {
 		sub.namespace = namespace
+		if n.queueSize > 0 {
+			sub.queue = newNotificationQueue(n.queueSize, n.policy, subscriptionQueueHistogram(namespace))
+			go n.sendLoop(sub)
+		}
 		n.active[id] = sub
 		delete(n.inactive, id)
+		trackSubscriptions(namespace, 1)
+	}
+}
+
+// close drops all the subscriptions of the notifier when its connection closes,
+// releasing them from the live subscription counts and stopping their queues.
+func (n *Notifier) close() {
+	log.DebugLog()
+	n.subMu.Lock()
+	defer n.subMu.Unlock()
+
+	n.closed = true
+	for id, sub := range n.active {
+		if sub.queue != nil {
+			sub.queue.close()
+		}
+		delete(n.active, id)
+		trackSubscriptions(sub.namespace, -1)
+	}
+	for id := range n.inactive {
+		delete(n.inactive, id)
 	}
 }
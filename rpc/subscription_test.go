@@ -24,6 +24,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 type NotificationTestService struct {
@@ -214,7 +216,7 @@ func waitForMessages(t *testing.T, in *json.Decoder, successes chan<- jsonSucces
 				notifications <- jsonNotification{
 					Version: msg["jsonrpc"].(string),
 					Method:  msg["method"].(string),
-					Params:  jsonSubscription{params["subscription"].(string), params["result"]},
+					Params:  jsonSubscription{Subscription: params["subscription"].(string), Result: params["result"]},
 				}
 				continue
 			}
@@ -17,12 +17,16 @@
 package rpc
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"gopkg.in/fatih/set.v0"
 	"github.com/ethereum/go-ethereum/log"
@@ -58,6 +62,8 @@ type service struct {
 type serverRequest struct {
 	id            interface{}
 	svcname       string
+	method        string // Fully qualified method name, checked against the access list
+	params        interface{} // Raw parameters of the request, for logging
 	callb         *callback
 	args          []reflect.Value
 	isUnsubscribe bool
@@ -71,6 +77,17 @@ type subscriptions map[string]*callback  // collection of subscription callbacks
 // Server represents a RPC server
 type Server struct {
 	services serviceRegistry
+	acl      *AccessList // Method level access control, nil permits all
+
+	limits      Limits   // Resource limits of the served connections
+	connLimiter *limiter // Rate limiter of the individual connections, nil if unlimited
+	ipLimiter   *limiter // Rate limiter of the remote IP addresses, nil if unlimited
+	singleShots chan struct{} // Slots of the executing single shot (HTTP) requests, nil if unlimited
+
+	slowCallThreshold time.Duration // Execution time above which calls are logged, 0 = disabled
+
+	queueSize   int         // Capacity of the notification queue of subscriptions, 0 = unqueued
+	queuePolicy QueuePolicy // Behaviour of the subscriptions overflowing their queue
 
 	run      int32
 	codecsMu sync.Mutex
@@ -93,6 +110,13 @@ type Error interface {
 	ErrorCode() int // returns the code
 }
 
+// DataError contains extra data to explain the error, which is sent over the
+// wire in the data field of the JSON-RPC error object.
+type DataError interface {
+	Error() string          // returns the message
+	ErrorData() interface{} // returns the error data
+}
+
 // ServerCodec implements reading, parsing and writing RPC messages for the server side of
 // a RPC session. Implementations must be go-routine safe since the codec can be called in
 // multiple go-routines concurrently.
@@ -166,3 +190,102 @@ func (bn BlockNumber) Int64() int64 {
 	log.DebugLog()
 	return (int64)(bn)
 }
+
+// BlockNumberOrHash is a block selector that identifies a block either by its
+// number (or one of the special tags) or by its hash. If the hash is given, the
+// caller may additionally require the block to be part of the canonical chain.
+type BlockNumberOrHash struct {
+	BlockNumber      *BlockNumber `json:"blockNumber,omitempty"`
+	BlockHash        *common.Hash `json:"blockHash,omitempty"`
+	RequireCanonical bool         `json:"requireCanonical,omitempty"`
+}
+
+// UnmarshalJSON parses the given JSON fragment into a BlockNumberOrHash. It supports:
+// - "latest", "earliest" or "pending" as string arguments
+// - the block number or the 32 byte block hash as hex strings
+// - an object with either a blockNumber or a blockHash field (and optionally
+//   requireCanonical), as specified by EIP-1898
+func (bnh *BlockNumberOrHash) UnmarshalJSON(data []byte) error {
+	log.DebugLog()
+	type erased BlockNumberOrHash
+	var e erased
+	if err := json.Unmarshal(data, &e); err == nil {
+		if e.BlockNumber != nil && e.BlockHash != nil {
+			return errors.New("cannot specify both blockHash and blockNumber, choose one or the other")
+		}
+		if e.BlockNumber == nil && e.BlockHash == nil {
+			return errors.New("either blockHash or blockNumber must be specified")
+		}
+		*bnh = BlockNumberOrHash(e)
+		return nil
+	}
+	var input string
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+	// A 32 byte hex string is a block hash, anything else must be a number
+	if len(input) == 66 {
+		var hash common.Hash
+		if err := hash.UnmarshalText([]byte(input)); err != nil {
+			return err
+		}
+		*bnh = BlockNumberOrHashWithHash(hash, false)
+		return nil
+	}
+	var number BlockNumber
+	if err := number.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*bnh = BlockNumberOrHashWithNumber(number)
+	return nil
+}
+
+// Number returns the block number of the selector, if it was selected by number.
+func (bnh *BlockNumberOrHash) Number() (BlockNumber, bool) {
+	log.DebugLog()
+	if bnh.BlockNumber != nil {
+		return *bnh.BlockNumber, true
+	}
+	return BlockNumber(0), false
+}
+
+// Hash returns the block hash of the selector, if it was selected by hash.
+func (bnh *BlockNumberOrHash) Hash() (common.Hash, bool) {
+	log.DebugLog()
+	if bnh.BlockHash != nil {
+		return *bnh.BlockHash, true
+	}
+	return common.Hash{}, false
+}
+
+// String implements fmt.Stringer, returning the block number or hash selected.
+func (bnh BlockNumberOrHash) String() string {
+	log.DebugLog()
+	if bnh.BlockHash != nil {
+		return bnh.BlockHash.Hex()
+	}
+	if bnh.BlockNumber != nil {
+		switch *bnh.BlockNumber {
+		case PendingBlockNumber:
+			return "pending"
+		case LatestBlockNumber:
+			return "latest"
+		case EarliestBlockNumber:
+			return "earliest"
+		}
+		return fmt.Sprintf("#%d", *bnh.BlockNumber)
+	}
+	return "nil"
+}
+
+// BlockNumberOrHashWithNumber creates a block selector for the given block number.
+func BlockNumberOrHashWithNumber(number BlockNumber) BlockNumberOrHash {
+	log.DebugLog()
+	return BlockNumberOrHash{BlockNumber: &number}
+}
+
+// BlockNumberOrHashWithHash creates a block selector for the given block hash.
+func BlockNumberOrHashWithHash(hash common.Hash, canonical bool) BlockNumberOrHash {
+	log.DebugLog()
+	return BlockNumberOrHash{BlockHash: &hash, RequireCanonical: canonical}
+}
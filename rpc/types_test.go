@@ -20,7 +20,9 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func TestBlockNumberJSONUnmarshal(t *testing.T) { log.DebugLog()
@@ -64,3 +66,49 @@ func TestBlockNumberJSONUnmarshal(t *testing.T) { log.DebugLog()
 		}
 	}
 }
+
+func TestBlockNumberOrHashJSONUnmarshal(t *testing.T) { log.DebugLog()
+	var (
+		hash   = common.HexToHash("0x0102030405060708091011121314151617181920212223242526272829303132")
+		number = func(n BlockNumber) BlockNumberOrHash { return BlockNumberOrHashWithNumber(n) }
+	)
+	tests := []struct {
+		input    string
+		mustFail bool
+		expected BlockNumberOrHash
+	}{
+		0:  {`"0x"`, true, BlockNumberOrHash{}},
+		1:  {`"0x0"`, false, number(0)},
+		2:  {`"0x12"`, false, number(18)},
+		3:  {`"pending"`, false, number(PendingBlockNumber)},
+		4:  {`"latest"`, false, number(LatestBlockNumber)},
+		5:  {`"earliest"`, false, number(EarliestBlockNumber)},
+		6:  {`"` + hash.Hex() + `"`, false, BlockNumberOrHashWithHash(hash, false)},
+		7:  {`"0x102"`, false, number(0x102)},
+		8:  {`{"blockNumber":"0x12"}`, false, number(18)},
+		9:  {`{"blockNumber":"latest"}`, false, number(LatestBlockNumber)},
+		10: {`{"blockHash":"` + hash.Hex() + `"}`, false, BlockNumberOrHashWithHash(hash, false)},
+		11: {`{"blockHash":"` + hash.Hex() + `","requireCanonical":true}`, false, BlockNumberOrHashWithHash(hash, true)},
+		12: {`{"blockHash":"` + hash.Hex() + `","blockNumber":"0x1"}`, true, BlockNumberOrHash{}},
+		13: {`{}`, true, BlockNumberOrHash{}},
+		14: {`{"blockNumber":"0x00"}`, true, BlockNumberOrHash{}},
+		15: {`someString`, true, BlockNumberOrHash{}},
+		16: {`""`, true, BlockNumberOrHash{}},
+	}
+
+	for i, test := range tests {
+		var bnh BlockNumberOrHash
+		err := json.Unmarshal([]byte(test.input), &bnh)
+		if test.mustFail && err == nil {
+			t.Errorf("Test %d should fail", i)
+			continue
+		}
+		if !test.mustFail && err != nil {
+			t.Errorf("Test %d should pass but got err: %v", i, err)
+			continue
+		}
+		if !test.mustFail && bnh.String() != test.expected.String() || bnh.RequireCanonical != test.expected.RequireCanonical {
+			t.Errorf("Test %d got unexpected value, want %v, got %v", i, test.expected, bnh)
+		}
+	}
+}
@@ -19,6 +19,8 @@ package rpc
 import (
 	"strings"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func TestNewID(t *testing.T) { log.DebugLog()
@@ -68,7 +68,11 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler { log.
 			decoder := func(v interface{}) error {
 				return websocketJSONCodec.Receive(conn, v)
 			}
-			srv.ServeCodec(NewCodec(conn, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
+			codec := NewCodec(conn, encoder, decoder)
+			defer codec.Close()
+
+			ctx := context.WithValue(context.Background(), remoteAddrKey{}, conn.Request().RemoteAddr)
+			srv.serveRequest(ctx, codec, false, OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
@@ -124,6 +128,12 @@ func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http
 // The context is used for the initial connection establishment. It does not
 // affect subsequent interactions with the client.
 func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error) { log.DebugLog()
+	return dialWebsocket(ctx, endpoint, origin, new(clientConfig))
+}
+
+// dialWebsocket creates a new RPC client over a websocket connection, configuring
+// the handshake according to the client options.
+func dialWebsocket(ctx context.Context, endpoint, origin string, cfg *clientConfig) (*Client, error) { log.DebugLog()
 	if origin == "" {
 		var err error
 		if origin, err = os.Hostname(); err != nil {
@@ -141,6 +151,13 @@ func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error
 	}
 
 	return newClient(ctx, func(ctx context.Context) (net.Conn, error) {
+		// Authenticate every handshake anew, tokens may expire between reconnects
+		if cfg.httpAuth != nil {
+			config.Header = make(http.Header)
+			if err := cfg.httpAuth(config.Header); err != nil {
+				return nil, err
+			}
+		}
 		return wsDialContext(ctx, config)
 	})
 }
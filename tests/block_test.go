@@ -18,6 +18,8 @@ package tests
 
 import (
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func TestBlockchain(t *testing.T) { log.DebugLog()
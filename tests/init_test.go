@@ -29,6 +29,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -238,7 +239,7 @@ func makeMapFromTestFunc(f interface{}) reflect.Value { log.DebugLog()
 	stringT := reflect.TypeOf("")
 	testingT := reflect.TypeOf((*testing.T)(nil))
 	ftyp := reflect.TypeOf(f)
-	if ftyp.Kind() != reflect.func || ftyp.NumIn() != 3 || ftyp.NumOut() != 0 || ftyp.In(0) != testingT || ftyp.In(1) != stringT { log.DebugLog()
+	if ftyp.Kind() != reflect.Func || ftyp.NumIn() != 3 || ftyp.NumOut() != 0 || ftyp.In(0) != testingT || ftyp.In(1) != stringT {
 		panic(fmt.Sprintf("bad test function type: want func(*testing.T, string, <TestType>), have %s", ftyp))
 	}
 	testType := ftyp.In(2)
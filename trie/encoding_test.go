@@ -19,6 +19,8 @@ package trie
 import (
 	"bytes"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 func TestHexCompact(t *testing.T) { log.DebugLog()
@@ -16,7 +16,11 @@
 
 package trie
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
 
 func TestCanUnload(t *testing.T) { log.DebugLog()
 	tests := []struct {
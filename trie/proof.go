@@ -18,6 +18,7 @@ package trie
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -99,6 +100,31 @@ func (t *SecureTrie) Prove(key []byte, fromLevel uint, proofDb ethdb.Putter) err
 	return t.trie.Prove(key, fromLevel, proofDb)
 }
 
+// ProveRange constructs the edge proofs of the key range [origin, last], which are
+// the merkle proofs of the two boundary keys. The keys don't need to exist in the
+// trie. Together with all the key-value pairs within the range, the edge proofs
+// can be verified by VerifyRangeProof.
+func (t *Trie) ProveRange(origin, last []byte, proofDb ethdb.Putter) error { log.DebugLog()
+	if err := t.Prove(origin, 0, proofDb); err != nil {
+		return err
+	}
+	return t.Prove(last, 0, proofDb)
+}
+
+// ProveMulti constructs a compact merkle proof for a batch of keys, proving either
+// the value or the absence of each of them. Nodes shared by the paths of multiple
+// keys are only included once. The result is the RLP encoded list of the proof
+// nodes in the order they are first referenced.
+func (t *Trie) ProveMulti(keys [][]byte) ([]byte, error) { log.DebugLog()
+	proof := &multiProof{seen: make(map[string]struct{})}
+	for _, key := range keys {
+		if err := t.Prove(key, 0, proof); err != nil {
+			return nil, err
+		}
+	}
+	return rlp.EncodeToBytes(proof.nodes)
+}
+
 // VerifyProof checks merkle proofs. The given proof must contain the value for
 // key in a trie with the given root hash. VerifyProof returns an error if the
 // proof contains invalid trie nodes or the wrong value.
@@ -114,7 +140,7 @@ func VerifyProof(rootHash common.Hash, key []byte, proofDb DatabaseReader) (valu
 		if err != nil {
 			return nil, fmt.Errorf("bad proof node %d: %v", i, err), i
 		}
-		keyrest, cld := get(n, key)
+		keyrest, cld := get(n, key, true)
 		switch cld := cld.(type) {
 		case nil:
 			// The trie doesn't contain the key.
@@ -128,7 +154,10 @@ func VerifyProof(rootHash common.Hash, key []byte, proofDb DatabaseReader) (valu
 	}
 }
 
-func get(tn node, key []byte) ([]byte, node) { log.DebugLog()
+// get descends the trie along the key, returning the remaining key and the node
+// reached. If skipResolved is set, embedded nodes are followed until a hash node
+// or value is found, otherwise it stops after a single step.
+func get(tn node, key []byte, skipResolved bool) ([]byte, node) { log.DebugLog()
 	for {
 		switch n := tn.(type) {
 		case *shortNode:
@@ -137,9 +166,15 @@ func get(tn node, key []byte) ([]byte, node) { log.DebugLog()
 			}
 			tn = n.Val
 			key = key[len(n.Key):]
+			if !skipResolved {
+				return key, tn
+			}
 		case *fullNode:
 			tn = n.Children[key[0]]
 			key = key[1:]
+			if !skipResolved {
+				return key, tn
+			}
 		case hashNode:
 			return key, n
 		case nil:
@@ -151,3 +186,486 @@ func get(tn node, key []byte) ([]byte, node) { log.DebugLog()
 		}
 	}
 }
+
+// VerifyMultiProof checks a compact multiproof created by ProveMulti against the
+// given root hash, returning the proven values of the keys in order (nil for the
+// absent ones). The proof is rejected if it contains invalid or duplicate nodes,
+// or any node not needed to prove the keys.
+func VerifyMultiProof(rootHash common.Hash, keys [][]byte, proof []byte) ([][]byte, error) { log.DebugLog()
+	var nodes [][]byte
+	if err := rlp.DecodeBytes(proof, &nodes); err != nil {
+		return nil, fmt.Errorf("invalid multiproof: %v", err)
+	}
+	db := &multiProofReader{
+		nodes: make(map[string][]byte, len(nodes)),
+		used:  make(map[string]struct{}, len(nodes)),
+	}
+	for i, node := range nodes {
+		hash := string(crypto.Keccak256(node))
+		if _, ok := db.nodes[hash]; ok {
+			return nil, fmt.Errorf("duplicate proof node %d", i)
+		}
+		db.nodes[hash] = node
+	}
+	values := make([][]byte, len(keys))
+	if rootHash == emptyRoot {
+		// Everything is absent from an empty trie, there's nothing to prove
+		if len(nodes) > 0 {
+			return nil, fmt.Errorf("%d unused proof nodes", len(nodes))
+		}
+		return values, nil
+	}
+	for i, key := range keys {
+		value, err, _ := VerifyProof(rootHash, key, db)
+		if err != nil {
+			return nil, fmt.Errorf("key %x: %v", key, err)
+		}
+		values[i] = value
+	}
+	if unused := len(db.nodes) - len(db.used); unused > 0 {
+		return nil, fmt.Errorf("%d unused proof nodes", unused)
+	}
+	return values, nil
+}
+
+// multiProof is a proof writer collecting the distinct nodes of multiple merkle
+// proofs in the order of insertion.
+type multiProof struct {
+	nodes [][]byte
+	seen  map[string]struct{}
+}
+
+// Put implements ethdb.Putter, adding a proof node unless it was already added.
+func (p *multiProof) Put(key []byte, value []byte) error { log.DebugLog()
+	if _, ok := p.seen[string(key)]; ok {
+		return nil
+	}
+	p.seen[string(key)] = struct{}{}
+	p.nodes = append(p.nodes, common.CopyBytes(value))
+	return nil
+}
+
+// multiProofReader is a proof database tracking which of its nodes are accessed
+// during the verification.
+type multiProofReader struct {
+	nodes map[string][]byte
+	used  map[string]struct{}
+}
+
+// Get implements DatabaseReader, retrieving a proof node and marking it used.
+func (r *multiProofReader) Get(key []byte) ([]byte, error) { log.DebugLog()
+	node, ok := r.nodes[string(key)]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	r.used[string(key)] = struct{}{}
+	return node, nil
+}
+
+// Has implements DatabaseReader, checking whether a proof node is present.
+func (r *multiProofReader) Has(key []byte) (bool, error) { log.DebugLog()
+	_, ok := r.nodes[string(key)]
+	return ok, nil
+}
+
+// VerifyRangeProof checks whether the given leaf nodes and edge proofs can prove
+// the key range [firstKey, lastKey] of the trie with the given root hash, i.e.
+// that keys and values are all the entries of the trie within the range. The key
+// list must be strictly increasing and contain no empty values.
+//
+// The edge keys don't need to exist in the trie, and the following special cases
+// are supported:
+//
+//   - The proof is nil: the entries are expected to be the entire trie.
+//   - No entries with a nil lastKey: the proof of firstKey must show that there
+//     are no entries at or after it in the trie.
+//   - No entries with a lastKey: the edge proofs must show that there are no
+//     entries within the range.
+//   - A single entry with firstKey == lastKey: a plain merkle proof of the entry.
+//
+// The returned flag reports whether there are more entries in the trie after the
+// proven range.
+func VerifyRangeProof(rootHash common.Hash, firstKey []byte, lastKey []byte, keys [][]byte, values [][]byte, proof DatabaseReader) (bool, error) { log.DebugLog()
+	if len(keys) != len(values) {
+		return false, fmt.Errorf("inconsistent proof data, keys: %d, values: %d", len(keys), len(values))
+	}
+	// Ensure the received batch is monotonically increasing and contains no deletions
+	for i := 0; i < len(keys)-1; i++ {
+		if bytes.Compare(keys[i], keys[i+1]) >= 0 {
+			return false, errors.New("range is not monotonically increasing")
+		}
+	}
+	for _, value := range values {
+		if len(value) == 0 {
+			return false, errors.New("range contains deletion")
+		}
+	}
+	// Special case, there is no edge proof at all. The given range is expected
+	// to be the whole leaf-set in the trie.
+	if proof == nil {
+		tr := new(Trie)
+		for i, key := range keys {
+			tr.Update(key, values[i])
+		}
+		if have := tr.Hash(); have != rootHash {
+			return false, fmt.Errorf("invalid proof, want hash %x, got %x", rootHash, have)
+		}
+		return false, nil
+	}
+	// Special case, there are no entries and only the first edge is proven. Ensure
+	// there are no more entries in the trie from it on.
+	if len(keys) == 0 && lastKey == nil {
+		root, val, err := proofToPath(rootHash, nil, firstKey, proof, true)
+		if err != nil {
+			return false, err
+		}
+		if val != nil || hasRightElement(root, firstKey) {
+			return false, errors.New("more entries available")
+		}
+		return false, nil
+	}
+	// Special case, there is only one element and the two edge keys are the same.
+	// In this case, we can't construct two edge paths, so handle it here.
+	if len(keys) == 1 && bytes.Equal(firstKey, lastKey) {
+		root, val, err := proofToPath(rootHash, nil, firstKey, proof, false)
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(firstKey, keys[0]) {
+			return false, errors.New("correct proof but invalid key")
+		}
+		if !bytes.Equal(val, values[0]) {
+			return false, errors.New("correct proof but invalid data")
+		}
+		return hasRightElement(root, firstKey), nil
+	}
+	// In all other cases two distinct edge paths of the same length are needed,
+	// enclosing all the given entries.
+	if bytes.Compare(firstKey, lastKey) >= 0 {
+		return false, errors.New("invalid edge keys")
+	}
+	if len(firstKey) != len(lastKey) {
+		return false, errors.New("inconsistent edge keys")
+	}
+	if len(keys) > 0 && (bytes.Compare(keys[0], firstKey) < 0 || bytes.Compare(keys[len(keys)-1], lastKey) > 0) {
+		return false, errors.New("entries outside of the range")
+	}
+	// Convert the edge proofs to edge trie paths. Then we can have the same tree
+	// architecture with the original one. Non-existent proofs are allowed for both.
+	root, _, err := proofToPath(rootHash, nil, firstKey, proof, true)
+	if err != nil {
+		return false, err
+	}
+	// Pass the root node here, the second path will be merged with the first one
+	root, _, err = proofToPath(rootHash, root, lastKey, proof, true)
+	if err != nil {
+		return false, err
+	}
+	more := hasRightElement(root, lastKey)
+
+	// Remove all internal references. All the removed parts should be re-filled
+	// (or re-constructed) by the given leaves range.
+	empty, err := unsetInternal(root, firstKey, lastKey)
+	if err != nil {
+		if err != errEmptyRange || len(keys) > 0 {
+			return false, err
+		}
+		// The edge paths fork outside of the range, there's nothing within it
+		return more, nil
+	}
+	// Rebuild the trie with the leaf stream, the shape of trie should be the same
+	// as the original one.
+	diskdb, _ := ethdb.NewMemDatabase()
+	tr := &Trie{root: root, db: NewDatabase(diskdb)}
+	if empty {
+		tr.root = nil
+	}
+	for i, key := range keys {
+		if err := tr.TryUpdate(key, values[i]); err != nil {
+			return false, fmt.Errorf("invalid proof: %v", err)
+		}
+	}
+	if have := tr.Hash(); have != rootHash {
+		return false, fmt.Errorf("invalid proof, want hash %x, got %x", rootHash, have)
+	}
+	if len(keys) == 0 {
+		return more, nil
+	}
+	return hasRightElement(tr.root, keys[len(keys)-1]), nil
+}
+
+// errEmptyRange is returned by unsetInternal if both edge paths diverge from the
+// trie on the same side of a short node, so there are no entries in between.
+var errEmptyRange = errors.New("empty range")
+
+// proofToPath converts a merkle proof to a trie node path, resolving all nodes
+// along the key from the proof and leaving the rest as hash nodes. If a root is
+// given, the new path is merged into it.
+//
+// The proof is allowed to be a non-existence proof if allowNonExistent is set.
+func proofToPath(rootHash common.Hash, root node, key []byte, proofDb DatabaseReader, allowNonExistent bool) (node, []byte, error) { log.DebugLog()
+	// resolveNode retrieves and resolves a trie node from the proof
+	resolveNode := func(hash common.Hash) (node, error) {
+		buf, _ := proofDb.Get(hash[:])
+		if buf == nil {
+			return nil, fmt.Errorf("proof node (hash %064x) missing", hash)
+		}
+		n, err := decodeNode(hash[:], buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("bad proof node %v", err)
+		}
+		return n, nil
+	}
+	// If the root node is empty, resolve it first. It must be part of the proof.
+	if root == nil {
+		n, err := resolveNode(rootHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		root = n
+	}
+	var (
+		err           error
+		child, parent node
+		keyrest       []byte
+		valnode       []byte
+	)
+	key, parent = keybytesToHex(key), root
+	for {
+		keyrest, child = get(parent, key, false)
+		switch cld := child.(type) {
+		case nil:
+			// The trie doesn't contain the key. If non-existence is allowed, the
+			// resolved nodes are enough to prove the range.
+			if allowNonExistent {
+				return root, nil, nil
+			}
+			return nil, nil, errors.New("the node is not contained in trie")
+		case *shortNode:
+			key, parent = keyrest, child // Already resolved
+			continue
+		case *fullNode:
+			key, parent = keyrest, child // Already resolved
+			continue
+		case hashNode:
+			child, err = resolveNode(common.BytesToHash(cld))
+			if err != nil {
+				return nil, nil, err
+			}
+		case valueNode:
+			valnode = cld
+		}
+		// Link the parent and child
+		switch pnode := parent.(type) {
+		case *shortNode:
+			pnode.Val = child
+		case *fullNode:
+			pnode.Children[key[0]] = child
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", pnode, pnode))
+		}
+		if len(valnode) > 0 {
+			return root, valnode, nil // The whole path is resolved
+		}
+		key, parent = keyrest, child
+	}
+}
+
+// unsetInternal removes all internal node references (hash nodes and embedded
+// nodes) between the two edge paths of a trie constructed by proofToPath. The
+// visited nodes are marked dirty, since their content might be modified. The
+// returned flag reports whether the entire trie was removed.
+//
+// The edge keys are expected to be distinct, with right larger than left.
+func unsetInternal(n node, left []byte, right []byte) (bool, error) { log.DebugLog()
+	left, right = keybytesToHex(left), keybytesToHex(right)
+
+	// Step down to the fork point. It's either a short node not matching one of the
+	// paths, or a full node where the paths diverge or end.
+	var (
+		pos    = 0
+		parent node
+
+		// fork indicators, 0 means no fork, -1 means the path is less, 1 greater
+		shortForkLeft, shortForkRight int
+	)
+findFork:
+	for {
+		switch rn := (n).(type) {
+		case *shortNode:
+			rn.flags = nodeFlag{dirty: true}
+
+			// If either the left or the right path doesn't match the short node,
+			// stop here and the fork point is the short node.
+			if len(left)-pos < len(rn.Key) {
+				shortForkLeft = bytes.Compare(left[pos:], rn.Key)
+			} else {
+				shortForkLeft = bytes.Compare(left[pos:pos+len(rn.Key)], rn.Key)
+			}
+			if len(right)-pos < len(rn.Key) {
+				shortForkRight = bytes.Compare(right[pos:], rn.Key)
+			} else {
+				shortForkRight = bytes.Compare(right[pos:pos+len(rn.Key)], rn.Key)
+			}
+			if shortForkLeft != 0 || shortForkRight != 0 {
+				break findFork
+			}
+			parent = n
+			n, pos = rn.Val, pos+len(rn.Key)
+		case *fullNode:
+			rn.flags = nodeFlag{dirty: true}
+
+			// If either child on the paths is missing or they differ, stop here
+			// and the fork point is the full node.
+			leftnode, rightnode := rn.Children[left[pos]], rn.Children[right[pos]]
+			if leftnode == nil || rightnode == nil || leftnode != rightnode {
+				break findFork
+			}
+			parent = n
+			n, pos = rn.Children[left[pos]], pos+1
+		default:
+			return false, fmt.Errorf("invalid node on edge paths: %T", n)
+		}
+	}
+	switch rn := n.(type) {
+	case *shortNode:
+		// There are five possible scenarios:
+		// - both paths are less than the short node => no valid range
+		// - both paths are greater than the short node => no valid range
+		// - left path is less and right is greater => unset the short node entirely
+		// - left path points into the short node, but right is greater
+		// - right path points into the short node, but left is less
+		if shortForkLeft == -1 && shortForkRight == -1 {
+			return false, errEmptyRange
+		}
+		if shortForkLeft == 1 && shortForkRight == 1 {
+			return false, errEmptyRange
+		}
+		if shortForkLeft != 0 && shortForkRight != 0 {
+			// The fork point is the root node, unset the entire trie
+			if parent == nil {
+				return true, nil
+			}
+			return false, unsetChild(parent, left[pos-1])
+		}
+		// Only one path points to a non-existent key
+		if shortForkRight != 0 {
+			if _, ok := rn.Val.(valueNode); ok {
+				if parent == nil {
+					return true, nil
+				}
+				return false, unsetChild(parent, left[pos-1])
+			}
+			return false, unset(rn, rn.Val, left[pos:], len(rn.Key), false)
+		}
+		if shortForkLeft != 0 {
+			if _, ok := rn.Val.(valueNode); ok {
+				if parent == nil {
+					return true, nil
+				}
+				return false, unsetChild(parent, right[pos-1])
+			}
+			return false, unset(rn, rn.Val, right[pos:], len(rn.Key), true)
+		}
+		return false, nil
+	case *fullNode:
+		// Unset all internal nodes of the fork point
+		for i := left[pos] + 1; i < right[pos]; i++ {
+			rn.Children[i] = nil
+		}
+		if err := unset(rn, rn.Children[left[pos]], left[pos:], 1, false); err != nil {
+			return false, err
+		}
+		if err := unset(rn, rn.Children[right[pos]], right[pos:], 1, true); err != nil {
+			return false, err
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid fork point: %T", n)
+	}
+}
+
+// unset removes all internal node references on one side of an edge path, the
+// left side if removeLeft is set, the right side otherwise. If the path doesn't
+// exist in the trie, the branch where it diverges is removed if it's within the
+// range, or kept (with its hash cached) if it's outside.
+func unset(parent node, child node, key []byte, pos int, removeLeft bool) error { log.DebugLog()
+	switch cld := child.(type) {
+	case *fullNode:
+		if removeLeft {
+			for i := 0; i < int(key[pos]); i++ {
+				cld.Children[i] = nil
+			}
+		} else {
+			for i := key[pos] + 1; i < 16; i++ {
+				cld.Children[i] = nil
+			}
+		}
+		cld.flags = nodeFlag{dirty: true}
+		return unset(cld, cld.Children[key[pos]], key, pos+1, removeLeft)
+	case *shortNode:
+		if len(key[pos:]) < len(cld.Key) || !bytes.Equal(cld.Key, key[pos:pos+len(cld.Key)]) {
+			// Found the fork point, it's a non-existent branch. Unset it if it's
+			// inside the range, the parent must be a full node.
+			if removeLeft {
+				if bytes.Compare(cld.Key, key[pos:]) < 0 {
+					return unsetChild(parent, key[pos-1])
+				}
+			} else {
+				if bytes.Compare(cld.Key, key[pos:]) > 0 {
+					return unsetChild(parent, key[pos-1])
+				}
+			}
+			return nil
+		}
+		if _, ok := cld.Val.(valueNode); ok {
+			return unsetChild(parent, key[pos-1])
+		}
+		cld.flags = nodeFlag{dirty: true}
+		return unset(cld, cld.Val, key, pos+len(cld.Key), removeLeft)
+	case nil:
+		// A missing child of the fork point full node, non-existent branch
+		return nil
+	default:
+		return fmt.Errorf("invalid node on edge path: %T", child) // hashNode, valueNode
+	}
+}
+
+// unsetChild removes the child of a full node at the given index. Any other node
+// can't have a child unset in a valid trie.
+func unsetChild(parent node, index byte) error { log.DebugLog()
+	fn, ok := parent.(*fullNode)
+	if !ok {
+		return fmt.Errorf("invalid fork point parent: %T", parent)
+	}
+	fn.Children[index] = nil
+	return nil
+}
+
+// hasRightElement reports whether there are any entries right of the given path,
+// which may or may not exist in the trie. The whole path is expected to be
+// resolved already.
+func hasRightElement(node node, key []byte) bool { log.DebugLog()
+	pos, key := 0, keybytesToHex(key)
+	for node != nil {
+		switch rn := node.(type) {
+		case *fullNode:
+			for i := key[pos] + 1; i < 16; i++ {
+				if rn.Children[i] != nil {
+					return true
+				}
+			}
+			node, pos = rn.Children[key[pos]], pos+1
+		case *shortNode:
+			if len(key)-pos < len(rn.Key) || !bytes.Equal(rn.Key, key[pos:pos+len(rn.Key)]) {
+				return bytes.Compare(rn.Key, key[pos:]) > 0
+			}
+			node, pos = rn.Val, pos+len(rn.Key)
+		case valueNode:
+			return false // We have resolved the whole path
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", node, node)) // hashNode
+		}
+	}
+	return false
+}
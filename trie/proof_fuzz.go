@@ -0,0 +1,102 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build gofuzz
+
+package trie
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Fuzz implements a go-fuzz fuzzer method to test the range and multi proofs. The
+// input defines a trie, with every 5 bytes inserting a 4 byte key (padded to 32
+// bytes for long shared paths) and a 1 byte value. The leading 2 bytes select the
+// proven range.
+func Fuzz(data []byte) int { log.DebugLog()
+	if len(data) < 2+5 {
+		return -1
+	}
+	selector, data := data[:2], data[2:]
+
+	// Assemble the trie and its sorted, deduplicated entries
+	trie := new(Trie)
+	entries := make(map[string][]byte)
+	for ; len(data) >= 5; data = data[5:] {
+		key := common.RightPadBytes(data[:4], 32)
+		val := []byte{data[4], 0x01}
+		trie.Update(key, val)
+		entries[string(key)] = val
+	}
+	keys := make([][]byte, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, []byte(key))
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+
+	vals := make([][]byte, len(keys))
+	for i, key := range keys {
+		vals[i] = entries[string(key)]
+	}
+	root := trie.Hash()
+
+	// Prove and verify the selected range
+	start := int(selector[0]) % len(keys)
+	end := start + 1 + int(selector[1])%(len(keys)-start)
+
+	proof, _ := ethdb.NewMemDatabase()
+	if err := trie.ProveRange(keys[start], keys[end-1], proof); err != nil {
+		panic(err)
+	}
+	more, err := VerifyRangeProof(root, keys[start], keys[end-1], keys[start:end], vals[start:end], proof)
+	if err != nil {
+		panic(err)
+	}
+	if more != (end < len(keys)) {
+		panic("continuation mismatch")
+	}
+	// Dropping an entry from the middle of the range must be detected
+	if end-start > 2 {
+		rkeys := append(append([][]byte{}, keys[start:start+1]...), keys[start+2:end]...)
+		rvals := append(append([][]byte{}, vals[start:start+1]...), vals[start+2:end]...)
+		if _, err := VerifyRangeProof(root, keys[start], keys[end-1], rkeys, rvals, proof); err == nil {
+			panic("incomplete range verified")
+		}
+	}
+	// Prove and verify the range boundaries as a multiproof, with a missing key
+	missing := common.RightPadBytes([]byte{selector[0], selector[1], 0xff, 0xff, 0xff}, 32)
+	batch := [][]byte{keys[start], keys[end-1], missing}
+
+	blob, err := trie.ProveMulti(batch)
+	if err != nil {
+		panic(err)
+	}
+	have, err := VerifyMultiProof(root, batch, blob)
+	if err != nil {
+		panic(err)
+	}
+	for i, key := range batch {
+		if !bytes.Equal(have[i], entries[string(key)]) {
+			panic("multiproof value mismatch")
+		}
+	}
+	return 1
+}
@@ -20,12 +20,15 @@ import (
 	"bytes"
 	crand "crypto/rand"
 	mrand "math/rand"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 func init() {
@@ -99,6 +102,347 @@ func mutateByte(b []byte) { log.DebugLog()
 	}
 }
 
+type entrySlice []*kv
+
+func (p entrySlice) Len() int           { log.DebugLog(); return len(p) }
+func (p entrySlice) Less(i, j int) bool { log.DebugLog(); return bytes.Compare(p[i].k, p[j].k) < 0 }
+func (p entrySlice) Swap(i, j int)      { log.DebugLog(); p[i], p[j] = p[j], p[i] }
+
+// randomSortedTrie creates a random trie, returning its entries in key order.
+func randomSortedTrie(n int) (*Trie, entrySlice) { log.DebugLog()
+	trie, vals := randomTrie(n)
+	var entries entrySlice
+	for _, kv := range vals {
+		entries = append(entries, kv)
+	}
+	sort.Sort(entries)
+	return trie, entries
+}
+
+// rangeData splits a slice of entries into the keys and values to verify.
+func rangeData(entries entrySlice) ([][]byte, [][]byte) { log.DebugLog()
+	var keys, vals [][]byte
+	for _, kv := range entries {
+		keys = append(keys, kv.k)
+		vals = append(vals, kv.v)
+	}
+	return keys, vals
+}
+
+// Tests that random ranges of a random trie, with existing edge keys, are proven.
+func TestRangeProof(t *testing.T) { log.DebugLog()
+	trie, entries := randomSortedTrie(4096)
+	root := trie.Hash()
+
+	for i := 0; i < 200; i++ {
+		start := mrand.Intn(len(entries))
+		end := mrand.Intn(len(entries)-start) + start + 1
+
+		proof, _ := ethdb.NewMemDatabase()
+		if err := trie.ProveRange(entries[start].k, entries[end-1].k, proof); err != nil {
+			t.Fatalf("failed to prove range [%d, %d): %v", start, end, err)
+		}
+		keys, vals := rangeData(entries[start:end])
+		more, err := VerifyRangeProof(root, keys[0], keys[len(keys)-1], keys, vals, proof)
+		if err != nil {
+			t.Fatalf("range [%d, %d) verification failed: %v", start, end, err)
+		}
+		if more != (end < len(entries)) {
+			t.Fatalf("range [%d, %d) continuation mismatch: have %v, want %v", start, end, more, end < len(entries))
+		}
+	}
+}
+
+// Tests that random ranges of a random trie are proven with non-existent edge keys.
+func TestRangeProofWithNonExistentEdges(t *testing.T) { log.DebugLog()
+	trie, entries := randomSortedTrie(4096)
+	root := trie.Hash()
+
+	for i := 0; i < 200; i++ {
+		start := mrand.Intn(len(entries))
+		end := mrand.Intn(len(entries)-start) + start + 1
+
+		// Skip the edges overflowing or colliding with the neighbouring entries
+		first := decreaseKey(common.CopyBytes(entries[start].k))
+		if bytes.Compare(first, entries[start].k) > 0 || (start > 0 && bytes.Compare(first, entries[start-1].k) <= 0) {
+			continue
+		}
+		last := increaseKey(common.CopyBytes(entries[end-1].k))
+		if bytes.Compare(last, entries[end-1].k) < 0 || (end < len(entries) && bytes.Compare(last, entries[end].k) >= 0) {
+			continue
+		}
+		proof, _ := ethdb.NewMemDatabase()
+		if err := trie.ProveRange(first, last, proof); err != nil {
+			t.Fatalf("failed to prove range [%d, %d): %v", start, end, err)
+		}
+		keys, vals := rangeData(entries[start:end])
+		more, err := VerifyRangeProof(root, first, last, keys, vals, proof)
+		if err != nil {
+			t.Fatalf("range [%d, %d) verification failed: %v", start, end, err)
+		}
+		if more != (end < len(entries)) {
+			t.Fatalf("range [%d, %d) continuation mismatch: have %v, want %v", start, end, more, end < len(entries))
+		}
+	}
+	// Special case, a range covering the entire trie with edge proofs
+	first, last := common.Hash{}, common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	proof, _ := ethdb.NewMemDatabase()
+	trie.ProveRange(first[:], last[:], proof)
+
+	keys, vals := rangeData(entries)
+	if _, err := VerifyRangeProof(root, first[:], last[:], keys, vals, proof); err != nil {
+		t.Fatalf("full range verification failed: %v", err)
+	}
+}
+
+// Tests that tampered ranges are rejected by the verification.
+func TestBadRangeProof(t *testing.T) { log.DebugLog()
+	trie, entries := randomSortedTrie(4096)
+	root := trie.Hash()
+
+	for i := 0; i < 200; i++ {
+		start := mrand.Intn(len(entries))
+		end := mrand.Intn(len(entries)-start) + start + 1
+
+		proof, _ := ethdb.NewMemDatabase()
+		if err := trie.ProveRange(entries[start].k, entries[end-1].k, proof); err != nil {
+			t.Fatalf("failed to prove range [%d, %d): %v", start, end, err)
+		}
+		keys, vals := rangeData(entries[start:end])
+		first, last := keys[0], keys[len(keys)-1]
+
+		index := mrand.Intn(len(keys))
+		switch mrand.Intn(5) {
+		case 0: // Modified key
+			keys[index] = randBytes(32)
+		case 1: // Modified value
+			vals[index] = randBytes(20)
+		case 2: // Dropped entry
+			keys = append(keys[:index:index], keys[index+1:]...)
+			vals = append(vals[:index:index], vals[index+1:]...)
+		case 3: // Duplicated entry
+			keys = append(keys[:index+1:index+1], keys[index:]...)
+			vals = append(vals[:index+1:index+1], vals[index:]...)
+		case 4: // Deleted entry
+			vals[index] = nil
+		}
+		if _, err := VerifyRangeProof(root, first, last, keys, vals, proof); err == nil {
+			t.Fatalf("tampered range [%d, %d) verified", start, end)
+		}
+	}
+}
+
+// Tests that ranges without any entries are proven, both bounded ones and the
+// ones stretching until the end of the trie.
+func TestEmptyRangeProof(t *testing.T) { log.DebugLog()
+	trie, entries := randomSortedTrie(4096)
+	root := trie.Hash()
+
+	for i := 0; i < 500; i++ {
+		pos := mrand.Intn(len(entries) - 1)
+
+		first := increaseKey(common.CopyBytes(entries[pos].k))
+		last := decreaseKey(common.CopyBytes(entries[pos+1].k))
+		if bytes.Compare(first, last) >= 0 {
+			continue
+		}
+		proof, _ := ethdb.NewMemDatabase()
+		trie.ProveRange(first, last, proof)
+
+		more, err := VerifyRangeProof(root, first, last, nil, nil, proof)
+		if err != nil {
+			t.Fatalf("empty range after #%d verification failed: %v", pos, err)
+		}
+		if !more {
+			t.Fatalf("empty range after #%d reported no continuation", pos)
+		}
+		// Extending the range over an entry must fail
+		proof, _ = ethdb.NewMemDatabase()
+		trie.ProveRange(first, entries[pos+1].k, proof)
+		if _, err := VerifyRangeProof(root, first, entries[pos+1].k, nil, nil, proof); err == nil {
+			t.Fatalf("non-empty range after #%d verified", pos)
+		}
+	}
+	// Nothing after the last entry, but something after any other
+	first := increaseKey(common.CopyBytes(entries[len(entries)-1].k))
+	proof, _ := ethdb.NewMemDatabase()
+	trie.Prove(first, 0, proof)
+	if _, err := VerifyRangeProof(root, first, nil, nil, nil, proof); err != nil {
+		t.Fatalf("empty trailing range verification failed: %v", err)
+	}
+	first = increaseKey(common.CopyBytes(entries[len(entries)-2].k))
+	proof, _ = ethdb.NewMemDatabase()
+	trie.Prove(first, 0, proof)
+	if _, err := VerifyRangeProof(root, first, nil, nil, nil, proof); err == nil {
+		t.Fatal("non-empty trailing range verified")
+	}
+}
+
+// Tests that single entry ranges are proven with a plain merkle proof.
+func TestSingleEntryRangeProof(t *testing.T) { log.DebugLog()
+	trie, entries := randomSortedTrie(4096)
+	root := trie.Hash()
+
+	for i := 0; i < 100; i++ {
+		pos := mrand.Intn(len(entries))
+
+		proof, _ := ethdb.NewMemDatabase()
+		trie.ProveRange(entries[pos].k, entries[pos].k, proof)
+
+		more, err := VerifyRangeProof(root, entries[pos].k, entries[pos].k, [][]byte{entries[pos].k}, [][]byte{entries[pos].v}, proof)
+		if err != nil {
+			t.Fatalf("single entry #%d verification failed: %v", pos, err)
+		}
+		if more != (pos < len(entries)-1) {
+			t.Fatalf("single entry #%d continuation mismatch: have %v, want %v", pos, more, pos < len(entries)-1)
+		}
+		if _, err := VerifyRangeProof(root, entries[pos].k, entries[pos].k, [][]byte{entries[pos].k}, [][]byte{randBytes(20)}, proof); err == nil {
+			t.Fatalf("single entry #%d with bad value verified", pos)
+		}
+	}
+}
+
+// Tests that the entire trie is proven without edge proofs, and that a partial
+// one isn't.
+func TestAllEntriesProof(t *testing.T) { log.DebugLog()
+	trie, entries := randomSortedTrie(4096)
+	root := trie.Hash()
+
+	keys, vals := rangeData(entries)
+	if _, err := VerifyRangeProof(root, nil, nil, keys, vals, nil); err != nil {
+		t.Fatalf("full trie verification failed: %v", err)
+	}
+	if _, err := VerifyRangeProof(root, nil, nil, keys[1:], vals[1:], nil); err == nil {
+		t.Fatal("partial trie verified")
+	}
+}
+
+// Tests that the multiproof of random existing and missing keys proves all their
+// values, and is smaller than the individual proofs.
+func TestMultiProof(t *testing.T) { log.DebugLog()
+	trie, entries := randomSortedTrie(4096)
+	root := trie.Hash()
+
+	for i := 0; i < 50; i++ {
+		var (
+			keys [][]byte
+			vals [][]byte
+			size int
+		)
+		for j := 0; j < 1+mrand.Intn(64); j++ {
+			if mrand.Intn(4) == 0 {
+				keys, vals = append(keys, randBytes(32)), append(vals, nil)
+			} else {
+				kv := entries[mrand.Intn(len(entries))]
+				keys, vals = append(keys, kv.k), append(vals, kv.v)
+			}
+			proof, _ := ethdb.NewMemDatabase()
+			trie.Prove(keys[len(keys)-1], 0, proof)
+			for _, key := range proof.Keys() {
+				node, _ := proof.Get(key)
+				size += len(node)
+			}
+		}
+		proof, err := trie.ProveMulti(keys)
+		if err != nil {
+			t.Fatalf("failed to create multiproof: %v", err)
+		}
+		if len(keys) > 1 && len(proof) >= size {
+			t.Errorf("multiproof not compact: have %d bytes, individual proofs %d", len(proof), size)
+		}
+		have, err := VerifyMultiProof(root, keys, proof)
+		if err != nil {
+			t.Fatalf("multiproof verification failed: %v", err)
+		}
+		for j := range keys {
+			if !bytes.Equal(have[j], vals[j]) {
+				t.Fatalf("value #%d mismatch: have %x, want %x", j, have[j], vals[j])
+			}
+		}
+	}
+	// Everything is missing from an empty trie, without any proof
+	proof, _ := new(Trie).ProveMulti([][]byte{randBytes(32)})
+	if have, err := VerifyMultiProof(emptyRoot, [][]byte{randBytes(32)}, proof); err != nil || have[0] != nil {
+		t.Fatalf("empty trie multiproof mismatch: have %x, %v", have, err)
+	}
+}
+
+// Tests that tampered, non-canonical or partial multiproofs are rejected.
+func TestBadMultiProof(t *testing.T) { log.DebugLog()
+	trie, entries := randomSortedTrie(4096)
+	root := trie.Hash()
+
+	for i := 0; i < 100; i++ {
+		var keys [][]byte
+		for j := 0; j < 2+mrand.Intn(16); j++ {
+			keys = append(keys, entries[mrand.Intn(len(entries))].k)
+		}
+		blob, _ := trie.ProveMulti(keys)
+
+		var nodes [][]byte
+		rlp.DecodeBytes(blob, &nodes)
+
+		index := mrand.Intn(len(nodes))
+		switch mrand.Intn(4) {
+		case 0: // Missing node
+			nodes = append(nodes[:index:index], nodes[index+1:]...)
+		case 1: // Duplicate node
+			nodes = append(nodes, nodes[index])
+		case 2: // Unused node
+			extra, _ := ethdb.NewMemDatabase()
+			trie.Prove(randBytes(32), 0, extra)
+			for _, key := range extra.Keys() {
+				if node, _ := extra.Get(key); !containsNode(nodes, node) {
+					nodes = append(nodes, node)
+				}
+			}
+		case 3: // Modified node
+			node := common.CopyBytes(nodes[index])
+			mutateByte(node)
+			nodes[index] = node
+		}
+		tampered, _ := rlp.EncodeToBytes(nodes)
+		if bytes.Equal(tampered, blob) {
+			continue // All the extra nodes were shared
+		}
+		if _, err := VerifyMultiProof(root, keys, tampered); err == nil {
+			t.Fatalf("tampered multiproof verified")
+		}
+	}
+}
+
+// containsNode checks whether a node is part of a list of proof nodes.
+func containsNode(nodes [][]byte, node []byte) bool { log.DebugLog()
+	for _, n := range nodes {
+		if bytes.Equal(n, node) {
+			return true
+		}
+	}
+	return false
+}
+
+// increaseKey returns the key following the given one, modifying it in place.
+func increaseKey(key []byte) []byte { log.DebugLog()
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i]++
+		if key[i] != 0x0 {
+			break
+		}
+	}
+	return key
+}
+
+// decreaseKey returns the key preceding the given one, modifying it in place.
+func decreaseKey(key []byte) []byte { log.DebugLog()
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i]--
+		if key[i] != 0xff {
+			break
+		}
+	}
+	return key
+}
+
 func BenchmarkProve(b *testing.B) { log.DebugLog()
 	trie, vals := randomTrie(100)
 	var keys []string
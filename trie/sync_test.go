@@ -22,6 +22,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 // makeTestTrie create a sample test trie to test node-wise reconstruction.
@@ -108,8 +109,8 @@ func TestEmptyTrieSync(t *testing.T) { log.DebugLog()
 
 // Tests that given a root hash, a trie can sync iteratively on a single thread,
 // requesting retrieval tasks and returning all of them in one go.
-func TestIterativeTrieSyncIndividual(t *testing.T) { log.DebugLog() testIterativeTrieSync(t, 1) }
-func TestIterativeTrieSyncBatched(t *testing.T)    { log.DebugLog() testIterativeTrieSync(t, 100) }
+func TestIterativeTrieSyncIndividual(t *testing.T) { log.DebugLog(); testIterativeTrieSync(t, 1) }
+func TestIterativeTrieSyncBatched(t *testing.T)    { log.DebugLog(); testIterativeTrieSync(t, 100) }
 
 func testIterativeTrieSync(t *testing.T, batch int) { log.DebugLog()
 	// Create a random trie to copy
@@ -179,8 +180,8 @@ func TestIterativeDelayedTrieSync(t *testing.T) { log.DebugLog()
 // Tests that given a root hash, a trie can sync iteratively on a single thread,
 // requesting retrieval tasks and returning all of them in one go, however in a
 // random order.
-func TestIterativeRandomTrieSyncIndividual(t *testing.T) { log.DebugLog() testIterativeRandomTrieSync(t, 1) }
-func TestIterativeRandomTrieSyncBatched(t *testing.T)    { log.DebugLog() testIterativeRandomTrieSync(t, 100) }
+func TestIterativeRandomTrieSyncIndividual(t *testing.T) { log.DebugLog(); testIterativeRandomTrieSync(t, 1) }
+func TestIterativeRandomTrieSyncBatched(t *testing.T)    { log.DebugLog(); testIterativeRandomTrieSync(t, 100) }
 
 func testIterativeRandomTrieSync(t *testing.T, batch int) { log.DebugLog()
 	// Create a random trie to copy